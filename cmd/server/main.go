@@ -22,6 +22,23 @@ func main() {
 	// create logger
 	logger.Setup(cfg)
 
+	// run a subcommand instead of the server if requested
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := runMigrate(cfg, os.Args[2:]); err != nil {
+				logger.Fatal(fmt.Errorf("migration failed: %w", err))
+			}
+		case "purge":
+			if err := runPurge(); err != nil {
+				logger.Fatal(fmt.Errorf("purge failed: %w", err))
+			}
+		default:
+			logger.Fatal(fmt.Errorf("unknown command: %s", os.Args[1]))
+		}
+		return
+	}
+
 	// build dependencies
 	ctn := container.New()
 
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/database/mysql"
+)
+
+const migrateUsage = "usage: server migrate up | down N | down -all | status | force VERSION"
+
+// runMigrate applies, reverts or inspects the schema migrations embedded in the binary
+func runMigrate(cfg config.Provider, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := mysql.GetWriteConnection(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := mysql.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("no change")
+		}
+
+	case "down":
+		// reverting everything drops all the data, so it has to be asked for explicitly
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+
+		steps := 0 // all
+		if args[1] != "-all" {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errors.New(migrateUsage)
+			}
+		}
+
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("no change")
+		}
+
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("version: %d, dirty: %t\n", status.Version, status.Dirty)
+		for _, migration := range status.Migrations {
+			state := "pending"
+			if migration.Version <= status.Version {
+				state = "applied"
+			}
+			fmt.Printf("%-8s %d_%s\n", state, migration.Version, migration.Name)
+		}
+
+	case "force":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+
+		version, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return errors.New(migrateUsage)
+		}
+
+		if err = migrator.Force(ctx, version); err != nil {
+			return err
+		}
+		fmt.Printf("forced version %d\n", version)
+
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
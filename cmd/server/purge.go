@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/container"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/database/mysql"
+)
+
+// runPurge purges all the users deleted longer than the retention period ago, the same as the background job does,
+// e.g. from a cron job when the job is disabled
+func runPurge() error {
+	ctn := container.New()
+
+	retention := ctn.Get("service-retention").(service.RetentionPort)
+
+	// database connections are defined only when the mysql repository driver is in use
+	if conns, err := ctn.SafeGet("mysql-conns"); err == nil {
+		defer conns.(*mysql.Connections).Read.Close()
+		defer conns.(*mysql.Connections).Write.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	for {
+		more, err := retention.PurgeDeleted(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !more {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type AuditPort interface {
+	List(ctx context.Context, q *audit.Query) (*audit.Page, error)
+}
+
+// auditor records the changes of users, within the transactions of the changes
+type auditor struct {
+	auditRepo    audit.Repository
+	timeProvider domain.TimeProvider
+}
+
+// record stores who has made the change and in which request, it has to be called within the transaction of the change
+func (a *auditor) record(ctx context.Context, action audit.Action, userID domain.ID, changes []audit.Change) error {
+	if changes == nil {
+		changes = []audit.Change{}
+	}
+
+	request := audit.RequestFromContext(ctx)
+
+	err := a.auditRepo.Create(ctx, &audit.Entry{
+		Actor:     actorOf(ctx),
+		Action:    action,
+		UserID:    userID,
+		Changes:   changes,
+		RequestID: request.ID,
+		ClientIP:  request.ClientIP,
+		CreatedAt: a.timeProvider.UtcNow(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed recording audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// actorOf describes the caller in audit entries
+func actorOf(ctx context.Context) audit.Actor {
+	identity, ok := auth.IdentityFromContext(ctx)
+
+	switch {
+	case !ok:
+		return audit.Actor{Type: audit.ActorAnonymous}
+	case identity.IsClient():
+		return audit.Actor{Type: audit.ActorClient, ID: identity.Client}
+	default:
+		return audit.Actor{Type: audit.ActorUser, ID: identity.UserID.String()}
+	}
+}
+
+type auditService struct {
+	auditRepo audit.Repository
+}
+
+var _ AuditPort = (*auditService)(nil)
+
+func NewAuditService(auditRepo audit.Repository) *auditService {
+	return &auditService{
+		auditRepo: auditRepo,
+	}
+}
+
+// List returns the entries matching the query, listing them across all users requires reading the audit of any user
+func (s *auditService) List(ctx context.Context, q *audit.Query) (*audit.Page, error) {
+	if err := authorize(ctx, user.PermissionReadAudit, q.Filter.UserID); err != nil {
+		return nil, err
+	}
+
+	page, err := s.auditRepo.List(ctx, q)
+	if err != nil {
+		err = fmt.Errorf("failed listing audit entries: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return page, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	repoMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/infrastructure/database/mysql"
+)
+
+func TestListAudit(t *testing.T) {
+	id := domain.NewID()
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		filter audit.Filter
+		err    error
+	}{
+		{name: "admin lists all users", ctx: adminCtx()},
+		{name: "support lists a user", ctx: userCtx(domain.NewID(), user.RoleSupport), filter: audit.Filter{UserID: id}},
+		{name: "user lists their own", ctx: userCtx(id, user.RoleSelf), filter: audit.Filter{UserID: id}},
+		{name: "user lists another user", ctx: userCtx(domain.NewID(), user.RoleSelf), filter: audit.Filter{UserID: id}, err: auth.ErrForbidden},
+		{name: "user lists all users", ctx: userCtx(id, user.RoleSelf), err: auth.ErrForbidden},
+		{name: "anonymous", ctx: context.Background(), filter: audit.Filter{UserID: id}, err: auth.ErrUnauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repoMock.AuditRepositoryMock)
+			mockRepo.On("List", mock.Anything, mock.Anything).Return(&audit.Page{}, nil)
+
+			_, err := NewAuditService(mockRepo).List(tt.ctx, &audit.Query{Filter: tt.filter, Limit: 5})
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				mockRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestActorOf(t *testing.T) {
+	id := domain.NewID()
+
+	assert.Equal(t, audit.Actor{Type: audit.ActorClient, ID: "test"}, actorOf(adminCtx()))
+	assert.Equal(t, audit.Actor{Type: audit.ActorUser, ID: id.String()}, actorOf(userCtx(id, user.RoleSelf)))
+	assert.Equal(t, audit.Actor{Type: audit.ActorAnonymous}, actorOf(context.Background()))
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type AuthPort interface {
+	Login(ctx context.Context, email, password, clientIP string) (*auth.Token, error)
+	LoginMFA(ctx context.Context, challenge, code, clientIP string) (*auth.Token, error)
+
+	// Authenticate and AuthenticateMFA verify the user the same way as Login and LoginMFA, failures and MFA included,
+	// but return their identity instead of starting a session, e.g. for the login form of the authorization endpoint
+	Authenticate(ctx context.Context, email, password, clientIP string) (auth.Identity, error)
+	AuthenticateMFA(ctx context.Context, challenge, code, clientIP string) (auth.Identity, error)
+}
+
+type authService struct {
+	userRepo      user.Repository
+	tokenProvider auth.TokenProvider
+	hasher        auth.PasswordHasher
+	tokenRepo     verification.Repository
+	guard         *lockoutGuard
+	mfa           *mfaVerifier
+	sessions      *sessionIssuer
+	timeProvider  domain.TimeProvider
+	challengeTTL  time.Duration
+	dummyHash     string
+}
+
+var _ AuthPort = (*authService)(nil)
+
+func NewAuthService(
+	userRepo user.Repository,
+	tokenProvider auth.TokenProvider,
+	hasher auth.PasswordHasher,
+	lockoutRepo lockout.Repository,
+	lockoutPolicy *lockout.Policy,
+	tokenRepo verification.Repository,
+	mfaRepo mfa.Repository,
+	cipher mfa.Cipher,
+	sessionRepo session.Repository,
+	timeProvider domain.TimeProvider,
+	challengeTTL time.Duration,
+	refreshTTL time.Duration,
+) (*authService, error) {
+	// dummyHash is verified when a user does not exist, so the response time does not reveal registered emails
+	dummyHash, err := hasher.Hash("dummy-password")
+	if err != nil {
+		return nil, fmt.Errorf("failed hashing dummy password: %w", err)
+	}
+
+	return &authService{
+		userRepo:      userRepo,
+		tokenProvider: tokenProvider,
+		hasher:        hasher,
+		tokenRepo:     tokenRepo,
+		guard: &lockoutGuard{
+			lockoutRepo: lockoutRepo,
+			policy:      lockoutPolicy,
+		},
+		mfa: &mfaVerifier{
+			mfaRepo:      mfaRepo,
+			cipher:       cipher,
+			timeProvider: timeProvider,
+		},
+		sessions: &sessionIssuer{
+			sessionRepo:   sessionRepo,
+			tokenProvider: tokenProvider,
+			refreshTTL:    refreshTTL,
+		},
+		timeProvider: timeProvider,
+		challengeTTL: challengeTTL,
+		dummyHash:    dummyHash,
+	}, nil
+}
+
+// Login verifies the password and issues a token. A hash created with an outdated algorithm or cost
+// is replaced on the way, as it is the only moment the password is known.
+// Failed logins are counted per user and per client IP, either is locked out for a while once it reaches its threshold.
+// Users with MFA enabled get an auth.MFAChallenge error instead of the token, see LoginMFA.
+func (s *authService) Login(ctx context.Context, email, password, clientIP string) (*auth.Token, error) {
+	credentials, state, err := s.authenticate(ctx, email, password, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, credentials, state)
+}
+
+func (s *authService) Authenticate(ctx context.Context, email, password, clientIP string) (auth.Identity, error) {
+	credentials, state, err := s.authenticate(ctx, email, password, clientIP)
+	if err != nil {
+		return auth.Identity{}, err
+	}
+
+	s.guard.reset(ctx, credentials.ID, state)
+
+	return auth.Identity{UserID: credentials.ID, Role: credentials.Role}, nil
+}
+
+// authenticate verifies the password and returns the credentials of the user together with their failed logins
+func (s *authService) authenticate(ctx context.Context, email, password, clientIP string) (*user.Credentials, *lockout.State, error) {
+	now := s.timeProvider.UtcNow()
+	ip := lockout.IP(clientIP)
+
+	if _, err := s.guard.check(ctx, ip, now); err != nil {
+		return nil, nil, err
+	}
+
+	credentials, err := s.userRepo.GetCredentialsByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			_, _, _ = s.hasher.Verify(s.dummyHash, password)
+			s.guard.recordFailure(ctx, now, ip)
+
+			return nil, nil, auth.ErrInvalidCredentials
+		}
+
+		err = fmt.Errorf("failed fetching credentials: %w", err)
+		logger.Debug(err)
+
+		return nil, nil, err
+	}
+
+	account := lockout.User(credentials.ID)
+
+	// the password is not verified for a locked account, so guessing it goes on only once the lock is over
+	state, err := s.guard.check(ctx, account, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	match, rehash, err := s.hasher.Verify(credentials.PasswordHash, password)
+	if err != nil {
+		err = fmt.Errorf("failed verifying password: %w", err)
+		logger.Debug(err)
+
+		return nil, nil, err
+	}
+
+	if !match {
+		s.guard.recordFailure(ctx, now, account, ip)
+
+		return nil, nil, auth.ErrInvalidCredentials
+	}
+
+	if rehash {
+		// the user is authenticated anyway, the hash is replaced on the next login
+		if err = s.rehash(ctx, credentials, password); err != nil {
+			logger.Error(fmt.Errorf("failed rehashing password: %w", err))
+		}
+	}
+
+	// the failures are reset only once the code is verified too, so knowing the password does not open the way to guess codes
+	if credentials.MFAEnabled {
+		return nil, nil, s.challenge(ctx, credentials, now)
+	}
+
+	return credentials, state, nil
+}
+
+// LoginMFA completes the login of a user with MFA enabled, exchanging the challenge token and a TOTP or recovery code
+// for an access token. The challenge is single-use, a wrong code requires logging in with the password again
+// and counts as a failed login.
+func (s *authService) LoginMFA(ctx context.Context, challenge, code, clientIP string) (*auth.Token, error) {
+	credentials, state, err := s.authenticateMFA(ctx, challenge, code, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, credentials, state)
+}
+
+func (s *authService) AuthenticateMFA(ctx context.Context, challenge, code, clientIP string) (auth.Identity, error) {
+	credentials, state, err := s.authenticateMFA(ctx, challenge, code, clientIP)
+	if err != nil {
+		return auth.Identity{}, err
+	}
+
+	s.guard.reset(ctx, credentials.ID, state)
+
+	return auth.Identity{UserID: credentials.ID, Role: credentials.Role}, nil
+}
+
+// authenticateMFA consumes the challenge, verifies the code and returns the credentials of the user together with their failed logins
+func (s *authService) authenticateMFA(ctx context.Context, challenge, code, clientIP string) (*user.Credentials, *lockout.State, error) {
+	now := s.timeProvider.UtcNow()
+	ip := lockout.IP(clientIP)
+
+	if _, err := s.guard.check(ctx, ip, now); err != nil {
+		return nil, nil, err
+	}
+
+	token, err := s.tokenRepo.Consume(ctx, verification.Hash(challenge), verification.PurposeMFALogin, now)
+	if err != nil {
+		if errors.Is(err, verification.ErrInvalidToken) {
+			return nil, nil, auth.ErrInvalidToken
+		}
+
+		err = fmt.Errorf("failed consuming mfa challenge: %w", err)
+		logger.Debug(err)
+
+		return nil, nil, err
+	}
+
+	account := lockout.User(token.UserID)
+
+	state, err := s.guard.check(ctx, account, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err = s.mfa.verify(ctx, token.UserID, code); err != nil {
+		// MFA reset in the meantime fails the same way, the next login does not require a code
+		if errors.Is(err, mfa.ErrInvalidCode) || errors.Is(err, mfa.ErrNotEnrolled) {
+			s.guard.recordFailure(ctx, now, account, ip)
+
+			return nil, nil, mfa.ErrInvalidCode
+		}
+
+		err = fmt.Errorf("failed verifying mfa code: %w", err)
+		logger.Debug(err)
+
+		return nil, nil, err
+	}
+
+	credentials, err := s.userRepo.GetCredentialsByUUID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return nil, nil, auth.ErrInvalidToken
+		}
+
+		err = fmt.Errorf("failed fetching credentials: %w", err)
+		logger.Debug(err)
+
+		return nil, nil, err
+	}
+
+	return credentials, state, nil
+}
+
+// challenge stores a single-use token exchanged for an access token together with a code
+func (s *authService) challenge(ctx context.Context, credentials *user.Credentials, now time.Time) error {
+	secret, token, err := verification.New(credentials.ID, verification.PurposeMFALogin, credentials.Email, now.Add(s.challengeTTL))
+	if err != nil {
+		return err
+	}
+
+	if err = s.tokenRepo.Create(ctx, token, now); err != nil {
+		err = fmt.Errorf("failed storing mfa challenge: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	return &auth.MFAChallenge{Token: secret, ExpiresIn: s.challengeTTL}
+}
+
+// issue resets the failures of the authenticated user and issues the access and refresh tokens
+func (s *authService) issue(ctx context.Context, credentials *user.Credentials, state *lockout.State) (*auth.Token, error) {
+	s.guard.reset(ctx, credentials.ID, state)
+
+	// every login starts a new session
+	token, err := s.sessions.issue(ctx, credentials, domain.NewID(), s.timeProvider.UtcNow())
+	if err != nil {
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// rehash replaces the verified hash, unless the password has been changed since it was read.
+// The version of the user is left as it is, the hash is not part of their representation, so their ETag stays valid.
+func (s *authService) rehash(ctx context.Context, credentials *user.Credentials, password string) error {
+	passwordHash, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	err = s.userRepo.ReplacePasswordHash(ctx, credentials.ID, credentials.PasswordHash, passwordHash)
+	if errors.Is(err, user.ErrVersionMismatch) {
+		return nil
+	}
+
+	return err
+}
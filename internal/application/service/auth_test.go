@@ -0,0 +1,505 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	"github.com/wojciechpawlinow/usermanagement/pkg/totp"
+)
+
+const (
+	testClientIP     = "192.0.2.1"
+	testChallengeTTL = 5 * time.Minute
+	testRefreshTTL   = 24 * time.Hour
+)
+
+// testSecret is the decrypted TOTP secret of users with MFA enabled
+var testSecret = []byte("12345678901234567890")
+
+var testLockoutPolicy = &lockout.Policy{
+	UserThreshold: 3,
+	IPThreshold:   10,
+	BaseDelay:     time.Minute,
+	MaxDelay:      time.Hour,
+	Window:        24 * time.Hour,
+}
+
+// newAuthService creates the service with nothing locked out, unless the test sets the state of a subject first
+func newAuthService(t *testing.T, now time.Time) (*authService, *serviceMocks) {
+	m := newServiceMocks(now)
+
+	m.hasher.On("Hash", "dummy-password").Return("dummy-hash", nil).Once()
+	m.sessionRepo.On("Create", mock.Anything, mock.Anything, now).Return(nil)
+
+	authSrv, err := NewAuthService(
+		m.userRepo,
+		m.tokenProvider,
+		m.hasher,
+		m.lockoutRepo,
+		testLockoutPolicy,
+		m.tokenRepo,
+		m.mfaRepo,
+		m.cipher,
+		m.sessionRepo,
+		m.timeProvider,
+		testChallengeTTL,
+		testRefreshTTL,
+	)
+	assert.NoError(t, err)
+
+	return authSrv, m
+}
+
+func TestLogin(t *testing.T) {
+	now := time.Now()
+
+	t.Run("login", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+		m.unlocked()
+
+		userID := domain.NewID()
+		expectedToken := &auth.Token{AccessToken: "token", TokenType: "Bearer", ExpiresIn: time.Minute}
+
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "test@example.com").Return(&user.Credentials{
+			ID:           userID,
+			Email:        "test@example.com",
+			PasswordHash: "hash",
+			Role:         user.RoleSelf,
+		}, nil)
+		m.hasher.On("Verify", "hash", "secure123").Return(true, false, nil)
+		m.tokenProvider.On("Issue", userID, user.RoleSelf).Return(expectedToken, nil)
+
+		token, err := authSrv.Login(context.Background(), "test@example.com", "secure123", testClientIP)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedToken, token)
+		assert.NotEmpty(t, token.RefreshToken)
+		m.userRepo.AssertNotCalled(t, "UpdateBasicFields", mock.Anything, mock.Anything, mock.Anything)
+		m.sessionRepo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(rt *session.RefreshToken) bool {
+			return rt.UserID == userID && rt.Hash == session.Hash(token.RefreshToken) && rt.ExpiresAt.Equal(now.Add(testRefreshTTL))
+		}), now)
+	})
+
+	t.Run("outdated hash is replaced", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+		m.unlocked()
+
+		userID := domain.NewID()
+		credentials := &user.Credentials{ID: userID, PasswordHash: "old-hash", Role: user.RoleSelf}
+
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "test@example.com").Return(credentials, nil)
+		m.hasher.On("Verify", "old-hash", "secure123").Return(true, true, nil)
+		m.hasher.On("Hash", "secure123").Return("new-hash", nil)
+		m.userRepo.On("ReplacePasswordHash", mock.Anything, userID, "old-hash", "new-hash").Return(nil)
+		m.tokenProvider.On("Issue", userID, user.RoleSelf).Return(&auth.Token{}, nil)
+
+		_, err := authSrv.Login(context.Background(), "test@example.com", "secure123", testClientIP)
+		assert.NoError(t, err)
+		m.userRepo.AssertExpectations(t)
+		m.userRepo.AssertNotCalled(t, "IncrementVersion", mock.Anything, mock.Anything, mock.Anything) // the ETag stays valid
+	})
+
+	t.Run("password changed before the rehash", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+		m.unlocked()
+
+		userID := domain.NewID()
+
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "test@example.com").Return(&user.Credentials{ID: userID, PasswordHash: "old-hash"}, nil)
+		m.hasher.On("Verify", "old-hash", "secure123").Return(true, true, nil)
+		m.hasher.On("Hash", "secure123").Return("new-hash", nil)
+		m.userRepo.On("ReplacePasswordHash", mock.Anything, userID, "old-hash", "new-hash").Return(user.ErrVersionMismatch)
+		m.tokenProvider.On("Issue", mock.Anything, mock.Anything).Return(&auth.Token{}, nil)
+
+		_, err := authSrv.Login(context.Background(), "test@example.com", "secure123", testClientIP)
+		assert.NoError(t, err)
+		m.userRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed rehash does not fail the login", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		authSrv, m := newAuthService(t, now)
+		m.unlocked()
+
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "test@example.com").Return(&user.Credentials{ID: domain.NewID(), PasswordHash: "old-hash"}, nil)
+		m.hasher.On("Verify", "old-hash", "secure123").Return(true, true, nil)
+		m.hasher.On("Hash", "secure123").Return("", errors.New("some hasher error"))
+		m.tokenProvider.On("Issue", mock.Anything, mock.Anything).Return(&auth.Token{}, nil)
+
+		_, err := authSrv.Login(context.Background(), "test@example.com", "secure123", testClientIP)
+		assert.NoError(t, err)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+		m.unlocked()
+
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "test@example.com").Return(&user.Credentials{
+			ID:           domain.NewID(),
+			Email:        "test@example.com",
+			PasswordHash: "hash",
+		}, nil)
+		m.hasher.On("Verify", "hash", "wrong-password").Return(false, false, nil)
+		m.lockoutRepo.On("RecordFailure", mock.Anything, mock.Anything, now, testLockoutPolicy.Window).Return(&lockout.State{Failures: 1}, nil)
+
+		token, err := authSrv.Login(context.Background(), "test@example.com", "wrong-password", testClientIP)
+		assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+		assert.Nil(t, token)
+		m.tokenProvider.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+		m.lockoutRepo.AssertNumberOfCalls(t, "RecordFailure", 2) // the user and the client IP
+		m.lockoutRepo.AssertNotCalled(t, "Lock", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+		m.unlocked()
+
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "test@example.com").Return(nil, user.ErrNotFound)
+		m.hasher.On("Verify", "dummy-hash", "secure123").Return(false, false, nil)
+		m.lockoutRepo.On("RecordFailure", mock.Anything, lockout.IP(testClientIP), now, testLockoutPolicy.Window).Return(&lockout.State{Failures: 1}, nil)
+
+		token, err := authSrv.Login(context.Background(), "test@example.com", "secure123", testClientIP)
+		assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+		assert.Nil(t, token)
+		m.hasher.AssertExpectations(t) // the dummy hash is verified to take as long as for existing users
+		m.lockoutRepo.AssertExpectations(t)
+	})
+
+	t.Run("user locked out on reaching the threshold", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		authSrv, m := newAuthService(t, now)
+		m.unlocked()
+
+		userID := domain.NewID()
+
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "test@example.com").Return(&user.Credentials{ID: userID, PasswordHash: "hash"}, nil)
+		m.hasher.On("Verify", "hash", "wrong-password").Return(false, false, nil)
+		m.lockoutRepo.On("RecordFailure", mock.Anything, lockout.User(userID), now, testLockoutPolicy.Window).Return(&lockout.State{Failures: 5}, nil)
+		m.lockoutRepo.On("RecordFailure", mock.Anything, lockout.IP(testClientIP), now, testLockoutPolicy.Window).Return(&lockout.State{Failures: 5}, nil)
+		m.lockoutRepo.On("Lock", mock.Anything, lockout.User(userID), now.Add(4*time.Minute)).Return(nil)
+
+		_, err := authSrv.Login(context.Background(), "test@example.com", "wrong-password", testClientIP)
+		assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+		m.lockoutRepo.AssertExpectations(t)
+		m.lockoutRepo.AssertNotCalled(t, "Lock", mock.Anything, lockout.IP(testClientIP), mock.Anything)
+	})
+
+	t.Run("locked out user", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		authSrv, m := newAuthService(t, now)
+
+		userID := domain.NewID()
+		lockedUntil := now.Add(time.Minute)
+
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "test@example.com").Return(&user.Credentials{ID: userID, PasswordHash: "hash"}, nil)
+		m.lockoutRepo.On("Get", mock.Anything, lockout.User(userID)).Return(&lockout.State{Failures: 3, LockedUntil: &lockedUntil}, nil)
+		m.unlocked()
+
+		token, err := authSrv.Login(context.Background(), "test@example.com", "secure123", testClientIP)
+		assert.ErrorIs(t, err, lockout.ErrLocked)
+		assert.Nil(t, token)
+		m.hasher.AssertNotCalled(t, "Verify", "hash", "secure123")
+		m.lockoutRepo.AssertNotCalled(t, "RecordFailure", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("locked out client IP", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		authSrv, m := newAuthService(t, now)
+
+		lockedUntil := now.Add(time.Minute)
+
+		m.lockoutRepo.On("Get", mock.Anything, lockout.IP(testClientIP)).Return(&lockout.State{Failures: 10, LockedUntil: &lockedUntil}, nil)
+
+		_, err := authSrv.Login(context.Background(), "test@example.com", "secure123", testClientIP)
+		assert.ErrorIs(t, err, lockout.ErrLocked)
+		m.userRepo.AssertNotCalled(t, "GetCredentialsByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("expired lock is reset on login", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+
+		userID := domain.NewID()
+		lockedUntil := now.Add(-time.Second)
+
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "test@example.com").Return(&user.Credentials{ID: userID, PasswordHash: "hash"}, nil)
+		m.lockoutRepo.On("Get", mock.Anything, lockout.User(userID)).Return(&lockout.State{Failures: 3, LockedUntil: &lockedUntil}, nil)
+		m.unlocked()
+		m.hasher.On("Verify", "hash", "secure123").Return(true, false, nil)
+		m.lockoutRepo.On("Reset", mock.Anything, lockout.User(userID)).Return(nil)
+		m.tokenProvider.On("Issue", userID, mock.Anything).Return(&auth.Token{}, nil)
+
+		_, err := authSrv.Login(context.Background(), "test@example.com", "secure123", testClientIP)
+		assert.NoError(t, err)
+		m.lockoutRepo.AssertExpectations(t)
+		m.lockoutRepo.AssertNotCalled(t, "Reset", mock.Anything, lockout.IP(testClientIP))
+	})
+
+	t.Run("user with mfa gets a challenge", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+
+		userID := domain.NewID()
+
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "test@example.com").Return(&user.Credentials{
+			ID:           userID,
+			Email:        "test@example.com",
+			PasswordHash: "hash",
+			MFAEnabled:   true,
+		}, nil)
+		m.lockoutRepo.On("Get", mock.Anything, lockout.User(userID)).Return(&lockout.State{Failures: 2}, nil)
+		m.unlocked()
+		m.hasher.On("Verify", "hash", "secure123").Return(true, false, nil)
+		m.tokenRepo.On("Create", mock.Anything, mock.MatchedBy(func(token *verification.Token) bool {
+			return token.UserID == userID && token.Purpose == verification.PurposeMFALogin && token.ExpiresAt.Equal(now.Add(testChallengeTTL))
+		}), now).Return(nil)
+
+		token, err := authSrv.Login(context.Background(), "test@example.com", "secure123", testClientIP)
+		assert.ErrorIs(t, err, auth.ErrMFARequired)
+		assert.Nil(t, token)
+
+		var challenge *auth.MFAChallenge
+		assert.ErrorAs(t, err, &challenge)
+		assert.NotEmpty(t, challenge.Token)
+		assert.Equal(t, testChallengeTTL, challenge.ExpiresIn)
+
+		m.tokenRepo.AssertExpectations(t)
+		m.tokenProvider.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+		m.lockoutRepo.AssertNotCalled(t, "Reset", mock.Anything, mock.Anything) // failures are reset once the code is verified
+	})
+
+	t.Run("lockout repository error", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		authSrv, m := newAuthService(t, now)
+
+		m.lockoutRepo.On("Get", mock.Anything, mock.Anything).Return(nil, errors.New("some repository error"))
+
+		_, err := authSrv.Login(context.Background(), "test@example.com", "secure123", testClientIP)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed fetching failed logins")
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		authSrv, m := newAuthService(t, now)
+		m.unlocked()
+
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "test@example.com").Return(nil, errors.New("some repository error"))
+
+		token, err := authSrv.Login(context.Background(), "test@example.com", "secure123", testClientIP)
+		assert.Error(t, err)
+		assert.Nil(t, token)
+		assert.Contains(t, err.Error(), "failed fetching credentials")
+	})
+}
+
+func TestLoginMFA(t *testing.T) {
+	now := time.Now()
+	step := totp.Step(now)
+
+	// challenged sets up a valid challenge of a user with MFA enabled
+	challenged := func(m *serviceMocks, userID domain.ID, lastUsedStep int64) {
+		m.tokenRepo.On("Consume", mock.Anything, verification.Hash("challenge"), verification.PurposeMFALogin, now).Return(&verification.Token{UserID: userID}, nil)
+		m.mfaRepo.On("Get", mock.Anything, userID).Return(&mfa.Factor{
+			UserID:          userID,
+			EncryptedSecret: "encrypted",
+			ConfirmedAt:     &now,
+			LastUsedStep:    lastUsedStep,
+		}, nil)
+		m.cipher.On("Decrypt", "encrypted").Return(testSecret, nil)
+	}
+
+	t.Run("login with a code", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+
+		userID := domain.NewID()
+		expectedToken := &auth.Token{AccessToken: "token"}
+
+		challenged(m, userID, step-1)
+		m.lockoutRepo.On("Get", mock.Anything, lockout.User(userID)).Return(&lockout.State{Failures: 2}, nil)
+		m.unlocked()
+		m.mfaRepo.On("UseStep", mock.Anything, userID, step).Return(nil)
+		m.lockoutRepo.On("Reset", mock.Anything, lockout.User(userID)).Return(nil)
+		m.userRepo.On("GetCredentialsByUUID", mock.Anything, userID).Return(&user.Credentials{ID: userID, Role: user.RoleSelf}, nil)
+		m.tokenProvider.On("Issue", userID, user.RoleSelf).Return(expectedToken, nil)
+
+		token, err := authSrv.LoginMFA(context.Background(), "challenge", totp.Code(testSecret, step), testClientIP)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedToken, token)
+		m.mfaRepo.AssertExpectations(t)
+		m.lockoutRepo.AssertExpectations(t)
+	})
+
+	t.Run("login with a recovery code", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+		m.unlocked()
+
+		userID := domain.NewID()
+
+		challenged(m, userID, 0)
+		m.mfaRepo.On("UseRecoveryCode", mock.Anything, userID, mfa.HashRecoveryCode("abcdefgh-ijklmnop"), now).Return(nil)
+		m.userRepo.On("GetCredentialsByUUID", mock.Anything, userID).Return(&user.Credentials{ID: userID, Role: user.RoleSelf}, nil)
+		m.tokenProvider.On("Issue", userID, user.RoleSelf).Return(&auth.Token{}, nil)
+
+		_, err := authSrv.LoginMFA(context.Background(), "challenge", "ABCDEFGH-IJKLMNOP", testClientIP)
+		assert.NoError(t, err)
+		m.mfaRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid challenge", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+		m.unlocked()
+
+		m.tokenRepo.On("Consume", mock.Anything, verification.Hash("challenge"), verification.PurposeMFALogin, now).Return(nil, verification.ErrInvalidToken)
+
+		token, err := authSrv.LoginMFA(context.Background(), "challenge", "123456", testClientIP)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+		assert.Nil(t, token)
+		m.mfaRepo.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	})
+
+	t.Run("wrong code counts as a failed login", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+		m.unlocked()
+
+		userID := domain.NewID()
+
+		challenged(m, userID, 0)
+		m.lockoutRepo.On("RecordFailure", mock.Anything, mock.Anything, now, testLockoutPolicy.Window).Return(&lockout.State{Failures: 1}, nil)
+
+		token, err := authSrv.LoginMFA(context.Background(), "challenge", totp.Code(testSecret, step+10), testClientIP)
+		assert.ErrorIs(t, err, mfa.ErrInvalidCode)
+		assert.Nil(t, token)
+		m.lockoutRepo.AssertNumberOfCalls(t, "RecordFailure", 2) // the user and the client IP
+		m.mfaRepo.AssertNotCalled(t, "UseStep", mock.Anything, mock.Anything, mock.Anything)
+		m.tokenProvider.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+	})
+
+	t.Run("code is not accepted twice", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+		m.unlocked()
+
+		userID := domain.NewID()
+
+		challenged(m, userID, step)
+		m.lockoutRepo.On("RecordFailure", mock.Anything, mock.Anything, now, testLockoutPolicy.Window).Return(&lockout.State{Failures: 1}, nil)
+
+		_, err := authSrv.LoginMFA(context.Background(), "challenge", totp.Code(testSecret, step), testClientIP)
+		assert.ErrorIs(t, err, mfa.ErrInvalidCode)
+		m.mfaRepo.AssertNotCalled(t, "UseStep", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("locked out user", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		authSrv, m := newAuthService(t, now)
+
+		userID := domain.NewID()
+		lockedUntil := now.Add(time.Minute)
+
+		m.tokenRepo.On("Consume", mock.Anything, verification.Hash("challenge"), verification.PurposeMFALogin, now).Return(&verification.Token{UserID: userID}, nil)
+		m.lockoutRepo.On("Get", mock.Anything, lockout.User(userID)).Return(&lockout.State{Failures: 3, LockedUntil: &lockedUntil}, nil)
+		m.unlocked()
+
+		_, err := authSrv.LoginMFA(context.Background(), "challenge", "123456", testClientIP)
+		assert.ErrorIs(t, err, lockout.ErrLocked)
+		m.mfaRepo.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	now := time.Now()
+
+	t.Run("identity without a session", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+
+		userID := domain.NewID()
+
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "test@example.com").Return(&user.Credentials{
+			ID:           userID,
+			Email:        "test@example.com",
+			PasswordHash: "hash",
+			Role:         user.RoleSelf,
+		}, nil)
+		m.lockoutRepo.On("Get", mock.Anything, lockout.User(userID)).Return(&lockout.State{Failures: 2}, nil)
+		m.unlocked()
+		m.lockoutRepo.On("Reset", mock.Anything, lockout.User(userID)).Return(nil)
+		m.hasher.On("Verify", "hash", "secure123").Return(true, false, nil)
+
+		identity, err := authSrv.Authenticate(context.Background(), "test@example.com", "secure123", testClientIP)
+		assert.NoError(t, err)
+		assert.Equal(t, auth.Identity{UserID: userID, Role: user.RoleSelf}, identity)
+		m.lockoutRepo.AssertExpectations(t)
+		m.tokenProvider.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+		m.sessionRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		authSrv, m := newAuthService(t, now)
+		m.unlocked()
+
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "test@example.com").Return(&user.Credentials{
+			ID:           domain.NewID(),
+			Email:        "test@example.com",
+			PasswordHash: "hash",
+		}, nil)
+		m.hasher.On("Verify", "hash", "wrong-password").Return(false, false, nil)
+		m.lockoutRepo.On("RecordFailure", mock.Anything, mock.Anything, now, testLockoutPolicy.Window).Return(&lockout.State{Failures: 1}, nil)
+
+		identity, err := authSrv.Authenticate(context.Background(), "test@example.com", "wrong-password", testClientIP)
+		assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+		assert.Equal(t, auth.Identity{}, identity)
+		m.lockoutRepo.AssertNumberOfCalls(t, "RecordFailure", 2) // the user and the client IP
+	})
+}
+
+func TestLockoutDelay(t *testing.T) {
+	testCases := []struct {
+		kind     lockout.Kind
+		failures int
+		expected time.Duration
+	}{
+		{kind: lockout.KindUser, failures: 2, expected: 0},
+		{kind: lockout.KindUser, failures: 3, expected: time.Minute},
+		{kind: lockout.KindUser, failures: 4, expected: 2 * time.Minute},
+		{kind: lockout.KindUser, failures: 8, expected: 32 * time.Minute},
+		{kind: lockout.KindUser, failures: 9, expected: time.Hour},
+		{kind: lockout.KindUser, failures: 100, expected: time.Hour},
+		{kind: lockout.KindIP, failures: 9, expected: 0},
+		{kind: lockout.KindIP, failures: 10, expected: time.Minute},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, testLockoutPolicy.Delay(tc.kind, tc.failures), "%s after %d failures", tc.kind, tc.failures)
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		policy := &lockout.Policy{BaseDelay: time.Minute, MaxDelay: time.Hour}
+
+		assert.Zero(t, policy.Delay(lockout.KindUser, 1000))
+	})
+}
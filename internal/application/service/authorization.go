@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+// authorize checks the caller's role from the context against the permission required for an operation on the target user.
+// An empty target means the operation does not concern any particular user, e.g. listing.
+func authorize(ctx context.Context, permission user.Permission, target domain.ID) error {
+	identity, ok := auth.IdentityFromContext(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+
+	own := !identity.IsClient() && !target.IsEmpty() && identity.UserID.Equal(target)
+
+	if !identity.Role.Can(permission, own) {
+		return auth.ErrForbidden
+	}
+
+	return nil
+}
+
+// authorizeRank refuses operations on users holding a higher role than the caller, API key clients act as admins
+func authorizeRank(ctx context.Context, target *user.User) error {
+	identity, ok := auth.IdentityFromContext(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+
+	if target.Role.Outranks(identity.Role) {
+		return auth.ErrForbidden
+	}
+
+	return nil
+}
+
+// authorizeRankOf is authorizeRank for a target known by its ID, the target is loaded only when a role may outrank the caller,
+// i.e. not for admins, API key clients or users acting on their own record
+func authorizeRankOf(ctx context.Context, userRepo user.Repository, target domain.ID) error {
+	identity, ok := auth.IdentityFromContext(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+
+	if !user.RoleAdmin.Outranks(identity.Role) || (!identity.IsClient() && identity.UserID.Equal(target)) {
+		return nil
+	}
+
+	u, err := userRepo.GetByUUID(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	return authorizeRank(ctx, u)
+}
+
+// actor describes the caller in log messages
+func actor(ctx context.Context) string {
+	identity, _ := auth.IdentityFromContext(ctx)
+
+	if identity.IsClient() {
+		return "client " + identity.Client
+	}
+
+	return "user " + identity.UserID.String()
+}
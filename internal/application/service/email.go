@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mail"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type EmailPort interface {
+	RequestChange(ctx context.Context, userID, email string) error
+	Verify(ctx context.Context, secret string) error
+}
+
+// EmailVerifier sends a verification of an email address to its owner
+type EmailVerifier interface {
+	SendVerification(ctx context.Context, id domain.ID, email string) error
+}
+
+type emailService struct {
+	userRepo     user.Repository
+	tokenRepo    verification.Repository
+	uow          domain.UnitOfWork
+	tokens       *tokenMailer
+	timeProvider domain.TimeProvider
+	ttl          time.Duration
+	*auditor
+	*eventRaiser
+}
+
+var (
+	_ EmailPort     = (*emailService)(nil)
+	_ EmailVerifier = (*emailService)(nil)
+)
+
+func NewEmailService(
+	userRepo user.Repository,
+	tokenRepo verification.Repository,
+	uow domain.UnitOfWork,
+	auditRepo audit.Repository,
+	outbox event.Outbox,
+	mailer mail.Mailer,
+	timeProvider domain.TimeProvider,
+	ttl time.Duration,
+) *emailService {
+	return &emailService{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		uow:       uow,
+		tokens: &tokenMailer{
+			tokenRepo:    tokenRepo,
+			uow:          uow,
+			mailer:       mailer,
+			timeProvider: timeProvider,
+		},
+		timeProvider: timeProvider,
+		ttl:          ttl,
+		auditor: &auditor{
+			auditRepo:    auditRepo,
+			timeProvider: timeProvider,
+		},
+		eventRaiser: &eventRaiser{
+			outbox:       outbox,
+			timeProvider: timeProvider,
+		},
+	}
+}
+
+// RequestChange sends a verification to the new address, the email is changed only once it is verified.
+// Requesting the current unverified address sends its verification again.
+// Whoever controls the email takes over the account with a password reset, so only the user themselves and admins change it.
+func (s *emailService) RequestChange(ctx context.Context, userID, email string) error {
+	id, err := domain.ParseID(userID)
+	if err != nil {
+		return fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionChangeEmail, id); err != nil {
+		return err
+	}
+
+	u, err := s.userRepo.GetByUUID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err = authorizeRank(ctx, u); err != nil {
+		return err
+	}
+
+	if u.Email == email {
+		if u.EmailVerified {
+			return nil
+		}
+	} else {
+		_, err = s.userRepo.GetCredentialsByEmail(ctx, email)
+		switch {
+		case err == nil:
+			return user.ErrEmailAlreadyExists
+		case !errors.Is(err, user.ErrNotFound):
+			err = fmt.Errorf("failed checking email: %w", err)
+			logger.Debug(err)
+
+			return err
+		}
+	}
+
+	return s.SendVerification(ctx, id, email)
+}
+
+// SendVerification issues a token for the address and mails it there, tokens issued before are revoked
+func (s *emailService) SendVerification(ctx context.Context, id domain.ID, email string) error {
+	err := s.tokens.send(ctx, id, verification.PurposeEmail, email, s.ttl,
+		"Verify your email address",
+		"Verify your email address with the token below",
+	)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return user.ErrNotFound
+		}
+
+		err = fmt.Errorf("failed sending verification: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	return nil
+}
+
+// Verify consumes the token and marks the address it was sent to as the verified email of the user
+func (s *emailService) Verify(ctx context.Context, secret string) error {
+	now := s.timeProvider.UtcNow()
+
+	err := s.uow.WithinTx(ctx, func(ctx context.Context) error {
+		token, err := s.tokenRepo.Consume(ctx, verification.Hash(secret), verification.PurposeEmail, now)
+		if err != nil {
+			return err
+		}
+
+		if err = s.userRepo.IncrementVersion(ctx, token.UserID, nil); err != nil {
+			return err
+		}
+
+		if err = s.userRepo.SetVerifiedEmail(ctx, token.UserID, token.Email); err != nil {
+			return err
+		}
+
+		changes := audit.EmailChanges(token.Email)
+		if err = s.record(ctx, audit.ActionEmailVerified, token.UserID, changes); err != nil {
+			return err
+		}
+
+		if err = s.raise(ctx, token.UserID, event.UserUpdated{Changes: changes}); err != nil {
+			return err
+		}
+
+		// password resets mailed to a previous address must not be usable anymore
+		return s.tokenRepo.Revoke(ctx, token.UserID, verification.PurposePasswordReset)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, verification.ErrInvalidToken), errors.Is(err, user.ErrEmailAlreadyExists):
+			return err
+		case errors.Is(err, user.ErrNotFound):
+			// the user has been deleted in the meantime
+			return verification.ErrInvalidToken
+		}
+
+		err = fmt.Errorf("failed verifying email: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mail"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	domainMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain"
+)
+
+func newEmailService(now time.Time) (*emailService, *serviceMocks) {
+	m := newServiceMocks(now)
+
+	return NewEmailService(m.userRepo, m.tokenRepo, new(domainMock.UnitOfWorkMock), m.auditRepo, m.outbox, m.mailer, m.timeProvider, time.Hour), m
+}
+
+func TestRequestEmailChange(t *testing.T) {
+	t.Run("verification is sent to the new address", func(t *testing.T) {
+		now := time.Now()
+		emailSrv, m := newEmailService(now)
+
+		id := domain.NewID()
+
+		var (
+			token *verification.Token
+			msg   *mail.Message
+		)
+
+		m.userRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id, Email: "old@example.com", EmailVerified: true}, nil)
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "new@example.com").Return(nil, user.ErrNotFound)
+		m.tokenRepo.On("Revoke", mock.Anything, id, verification.PurposeEmail).Return(nil)
+		m.tokenRepo.On("Create", mock.Anything, mock.Anything, now).Run(func(args mock.Arguments) {
+			token = args.Get(1).(*verification.Token)
+		}).Return(nil)
+		m.mailer.On("Send", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			msg = args.Get(1).(*mail.Message)
+		}).Return(nil)
+
+		err := emailSrv.RequestChange(userCtx(id, user.RoleSelf), id.String(), "new@example.com")
+		assert.NoError(t, err)
+
+		assert.Equal(t, id, token.UserID)
+		assert.Equal(t, verification.PurposeEmail, token.Purpose)
+		assert.Equal(t, "new@example.com", token.Email)
+		assert.Equal(t, now.Add(time.Hour), token.ExpiresAt)
+
+		// only the hash of the mailed secret is stored
+		assert.Equal(t, "new@example.com", msg.To)
+		lines := strings.Split(msg.Body, "\n")
+		secret := lines[len(lines)-1]
+		assert.NotEqual(t, secret, token.Hash)
+		assert.Equal(t, verification.Hash(secret), token.Hash)
+	})
+
+	t.Run("email taken by another user", func(t *testing.T) {
+		emailSrv, m := newEmailService(time.Now())
+
+		id := domain.NewID()
+
+		m.userRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id, Email: "old@example.com"}, nil)
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "taken@example.com").Return(&user.Credentials{ID: domain.NewID()}, nil)
+
+		err := emailSrv.RequestChange(adminCtx(), id.String(), "taken@example.com")
+		assert.ErrorIs(t, err, user.ErrEmailAlreadyExists)
+		m.tokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("current unverified email is sent again", func(t *testing.T) {
+		emailSrv, m := newEmailService(time.Now())
+
+		id := domain.NewID()
+
+		m.userRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id, Email: "john@example.com"}, nil)
+		m.tokenRepo.On("Revoke", mock.Anything, id, verification.PurposeEmail).Return(nil)
+		m.tokenRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		m.mailer.On("Send", mock.Anything, mock.MatchedBy(func(msg *mail.Message) bool {
+			return msg.To == "john@example.com"
+		})).Return(nil)
+
+		err := emailSrv.RequestChange(adminCtx(), id.String(), "john@example.com")
+		assert.NoError(t, err)
+		m.mailer.AssertExpectations(t)
+		m.userRepo.AssertNotCalled(t, "GetCredentialsByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("current verified email is not sent again", func(t *testing.T) {
+		emailSrv, m := newEmailService(time.Now())
+
+		id := domain.NewID()
+
+		m.userRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id, Email: "john@example.com", EmailVerified: true}, nil)
+
+		err := emailSrv.RequestChange(adminCtx(), id.String(), "john@example.com")
+		assert.NoError(t, err)
+		m.mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("user can not change email of other users", func(t *testing.T) {
+		emailSrv, m := newEmailService(time.Now())
+
+		err := emailSrv.RequestChange(userCtx(domain.NewID(), user.RoleSelf), domain.NewID().String(), "new@example.com")
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		m.userRepo.AssertNotCalled(t, "GetByUUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("support can not change email of other users", func(t *testing.T) {
+		emailSrv, m := newEmailService(time.Now())
+
+		err := emailSrv.RequestChange(userCtx(domain.NewID(), user.RoleSupport), domain.NewID().String(), "new@example.com")
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		m.userRepo.AssertNotCalled(t, "GetByUUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("support changes own email", func(t *testing.T) {
+		emailSrv, m := newEmailService(time.Now())
+
+		id := domain.NewID()
+
+		m.userRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id, Email: "john@example.com", EmailVerified: true, Role: user.RoleSupport}, nil)
+
+		err := emailSrv.RequestChange(userCtx(id, user.RoleSupport), id.String(), "john@example.com")
+		assert.NoError(t, err)
+	})
+
+	t.Run("email of a higher role is not changed", func(t *testing.T) {
+		emailSrv, m := newEmailService(time.Now())
+
+		id := domain.NewID()
+
+		// e.g. a user demoted after the token was issued
+		m.userRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id, Email: "admin@example.com", Role: user.RoleAdmin}, nil)
+
+		err := emailSrv.RequestChange(userCtx(id, user.RoleSelf), id.String(), "new@example.com")
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		m.tokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("mailer error", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		emailSrv, m := newEmailService(time.Now())
+
+		id := domain.NewID()
+
+		m.userRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id, Email: "john@example.com"}, nil)
+		m.tokenRepo.On("Revoke", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		m.tokenRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		m.mailer.On("Send", mock.Anything, mock.Anything).Return(assert.AnError)
+
+		err := emailSrv.RequestChange(adminCtx(), id.String(), "john@example.com")
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Contains(t, err.Error(), "failed sending verification: failed sending mail")
+	})
+}
+
+func TestVerifyEmail(t *testing.T) {
+	t.Run("verify email", func(t *testing.T) {
+		now := time.Now()
+		emailSrv, m := newEmailService(now)
+
+		id := domain.NewID()
+
+		m.tokenRepo.On("Consume", mock.Anything, verification.Hash("secret"), verification.PurposeEmail, now).Return(&verification.Token{
+			UserID: id,
+			Email:  "new@example.com",
+		}, nil)
+		m.userRepo.On("IncrementVersion", mock.Anything, id, (*int64)(nil)).Return(nil)
+		m.userRepo.On("SetVerifiedEmail", mock.Anything, id, "new@example.com").Return(nil)
+		m.tokenRepo.On("Revoke", mock.Anything, id, verification.PurposePasswordReset).Return(nil)
+
+		err := emailSrv.Verify(context.Background(), "secret")
+		assert.NoError(t, err)
+		m.userRepo.AssertExpectations(t)
+		m.tokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		emailSrv, m := newEmailService(time.Now())
+
+		m.tokenRepo.On("Consume", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, verification.ErrInvalidToken)
+
+		err := emailSrv.Verify(context.Background(), "secret")
+		assert.ErrorIs(t, err, verification.ErrInvalidToken)
+		m.userRepo.AssertNotCalled(t, "SetVerifiedEmail", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("deleted user", func(t *testing.T) {
+		emailSrv, m := newEmailService(time.Now())
+
+		m.tokenRepo.On("Consume", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&verification.Token{UserID: domain.NewID()}, nil)
+		m.userRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(user.ErrNotFound)
+
+		err := emailSrv.Verify(context.Background(), "secret")
+		assert.ErrorIs(t, err, verification.ErrInvalidToken)
+	})
+
+	t.Run("email taken in the meantime", func(t *testing.T) {
+		emailSrv, m := newEmailService(time.Now())
+
+		m.tokenRepo.On("Consume", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&verification.Token{UserID: domain.NewID()}, nil)
+		m.userRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+		m.userRepo.On("SetVerifiedEmail", mock.Anything, mock.Anything, mock.Anything).Return(user.ErrEmailAlreadyExists)
+
+		err := emailSrv.Verify(context.Background(), "secret")
+		assert.ErrorIs(t, err, user.ErrEmailAlreadyExists)
+	})
+}
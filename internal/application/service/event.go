@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+)
+
+// eventRaiser adds the events of changes to the outbox, within the transactions of the changes
+type eventRaiser struct {
+	outbox       event.Outbox
+	timeProvider domain.TimeProvider
+}
+
+// raise adds the event to the outbox, it has to be called within the transaction of the change
+func (r *eventRaiser) raise(ctx context.Context, userID domain.ID, payload event.Payload) error {
+	e, err := event.New(userID, payload, r.timeProvider.UtcNow())
+	if err != nil {
+		return err
+	}
+
+	if err = r.outbox.Add(ctx, e); err != nil {
+		return fmt.Errorf("failed adding %s event to the outbox: %w", e.Type, err)
+	}
+
+	return nil
+}
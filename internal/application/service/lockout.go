@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type LockoutPort interface {
+	GetUserLockout(ctx context.Context, userID string) (*lockout.State, error)
+	UnlockUser(ctx context.Context, userID string) error
+	UnlockIP(ctx context.Context, address string) error
+}
+
+type lockoutService struct {
+	userRepo    user.Repository
+	lockoutRepo lockout.Repository
+}
+
+var _ LockoutPort = (*lockoutService)(nil)
+
+func NewLockoutService(userRepo user.Repository, lockoutRepo lockout.Repository) *lockoutService {
+	return &lockoutService{
+		userRepo:    userRepo,
+		lockoutRepo: lockoutRepo,
+	}
+}
+
+func (s *lockoutService) GetUserLockout(ctx context.Context, userID string) (*lockout.State, error) {
+	id, err := s.lockoutTarget(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.lockoutRepo.Get(ctx, lockout.User(id))
+	if err != nil {
+		err = fmt.Errorf("failed fetching failed logins: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// UnlockUser lifts the lock of the user and forgets their failed logins
+func (s *lockoutService) UnlockUser(ctx context.Context, userID string) error {
+	id, err := s.lockoutTarget(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.unlock(ctx, lockout.User(id))
+}
+
+// UnlockIP lifts the lock of the client address and forgets its failed logins
+func (s *lockoutService) UnlockIP(ctx context.Context, address string) error {
+	if err := authorize(ctx, user.PermissionUnlock, domain.ID{}); err != nil {
+		return err
+	}
+
+	if net.ParseIP(address) == nil {
+		return lockout.ErrInvalidIP
+	}
+
+	return s.unlock(ctx, lockout.IP(address))
+}
+
+// lockoutTarget authorizes the caller and makes sure the user exists
+func (s *lockoutService) lockoutTarget(ctx context.Context, userID string) (domain.ID, error) {
+	id, err := domain.ParseID(userID)
+	if err != nil {
+		return domain.ID{}, fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionUnlock, id); err != nil {
+		return domain.ID{}, err
+	}
+
+	if _, err = s.userRepo.GetByUUID(ctx, id); err != nil {
+		return domain.ID{}, err
+	}
+
+	return id, nil
+}
+
+func (s *lockoutService) unlock(ctx context.Context, subject lockout.Subject) error {
+	if err := s.lockoutRepo.Reset(ctx, subject); err != nil {
+		err = fmt.Errorf("failed unlocking: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("%s %s unlocked by %s", subject.Kind, subject.Value, actor(ctx)))
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+// lockoutGuard counts failed password and code checks per user and per client IP, wherever they are verified
+type lockoutGuard struct {
+	lockoutRepo lockout.Repository
+	policy      *lockout.Policy
+}
+
+// check returns lockout.ErrLocked when the subject is locked out
+func (g *lockoutGuard) check(ctx context.Context, subject lockout.Subject, now time.Time) (*lockout.State, error) {
+	state, err := g.lockoutRepo.Get(ctx, subject)
+	if err != nil {
+		err = fmt.Errorf("failed fetching failed logins: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	if state.LockedAt(now) {
+		logger.Info(fmt.Sprintf("attempt rejected, %s %s is locked out until %s", subject.Kind, subject.Value, state.LockedUntil.Format(time.RFC3339)))
+
+		return nil, lockout.ErrLocked
+	}
+
+	return state, nil
+}
+
+// recordFailure counts a failure of the subjects and locks out the ones over their threshold.
+// It does not fail the attempt, which is rejected anyway.
+func (g *lockoutGuard) recordFailure(ctx context.Context, now time.Time, subjects ...lockout.Subject) {
+	for _, subject := range subjects {
+		state, err := g.lockoutRepo.RecordFailure(ctx, subject, now, g.policy.Window)
+		if err != nil {
+			logger.Error(fmt.Errorf("failed recording failed login: %w", err))
+			continue
+		}
+
+		delay := g.policy.Delay(subject.Kind, state.Failures)
+		if delay == 0 {
+			continue
+		}
+
+		if err = g.lockoutRepo.Lock(ctx, subject, now.Add(delay)); err != nil {
+			logger.Error(fmt.Errorf("failed locking out: %w", err))
+			continue
+		}
+
+		logger.Info(fmt.Sprintf("%s %s locked out for %s after %d failed logins", subject.Kind, subject.Value, delay, state.Failures))
+	}
+}
+
+// reset forgets the failures of the verified user.
+// Failures of the client IP are not forgotten, so one valid account does not open the way to guess others.
+func (g *lockoutGuard) reset(ctx context.Context, userID domain.ID, state *lockout.State) {
+	if state.Failures > 0 || state.LockedUntil != nil {
+		if err := g.lockoutRepo.Reset(ctx, lockout.User(userID)); err != nil {
+			logger.Error(fmt.Errorf("failed resetting failed logins: %w", err))
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	repoMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/infrastructure/database/mysql"
+)
+
+func TestGetUserLockout(t *testing.T) {
+	t.Run("get", func(t *testing.T) {
+		userRepo := new(repoMock.UserRepositoryMock)
+		lockoutRepo := new(repoMock.LockoutRepositoryMock)
+		lockoutSrv := NewLockoutService(userRepo, lockoutRepo)
+
+		userID := domain.NewID()
+		lockedUntil := time.Now().Add(time.Minute)
+		expected := &lockout.State{Subject: lockout.User(userID), Failures: 5, LockedUntil: &lockedUntil}
+
+		userRepo.On("GetByUUID", mock.Anything, userID).Return(&user.User{ID: userID}, nil)
+		lockoutRepo.On("Get", mock.Anything, lockout.User(userID)).Return(expected, nil)
+
+		state, err := lockoutSrv.GetUserLockout(userCtx(domain.NewID(), user.RoleSupport), userID.String())
+		assert.NoError(t, err)
+		assert.Equal(t, expected, state)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		userRepo := new(repoMock.UserRepositoryMock)
+		lockoutRepo := new(repoMock.LockoutRepositoryMock)
+		lockoutSrv := NewLockoutService(userRepo, lockoutRepo)
+
+		userID := domain.NewID()
+
+		userRepo.On("GetByUUID", mock.Anything, userID).Return(nil, user.ErrNotFound)
+
+		_, err := lockoutSrv.GetUserLockout(adminCtx(), userID.String())
+		assert.ErrorIs(t, err, user.ErrNotFound)
+		lockoutRepo.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	})
+
+	t.Run("own lockout can not be read", func(t *testing.T) {
+		lockoutSrv := NewLockoutService(new(repoMock.UserRepositoryMock), new(repoMock.LockoutRepositoryMock))
+
+		userID := domain.NewID()
+
+		_, err := lockoutSrv.GetUserLockout(userCtx(userID, user.RoleSelf), userID.String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+	})
+}
+
+func TestUnlock(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	t.Run("unlock user", func(t *testing.T) {
+		userRepo := new(repoMock.UserRepositoryMock)
+		lockoutRepo := new(repoMock.LockoutRepositoryMock)
+		lockoutSrv := NewLockoutService(userRepo, lockoutRepo)
+
+		userID := domain.NewID()
+
+		userRepo.On("GetByUUID", mock.Anything, userID).Return(&user.User{ID: userID}, nil)
+		lockoutRepo.On("Reset", mock.Anything, lockout.User(userID)).Return(nil)
+
+		assert.NoError(t, lockoutSrv.UnlockUser(adminCtx(), userID.String()))
+		lockoutRepo.AssertExpectations(t)
+	})
+
+	t.Run("unlock user unauthenticated", func(t *testing.T) {
+		lockoutSrv := NewLockoutService(new(repoMock.UserRepositoryMock), new(repoMock.LockoutRepositoryMock))
+
+		err := lockoutSrv.UnlockUser(context.Background(), domain.NewID().String())
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+	})
+
+	t.Run("unlock ip", func(t *testing.T) {
+		lockoutRepo := new(repoMock.LockoutRepositoryMock)
+		lockoutSrv := NewLockoutService(new(repoMock.UserRepositoryMock), lockoutRepo)
+
+		lockoutRepo.On("Reset", mock.Anything, lockout.Subject{Kind: lockout.KindIP, Value: "2001:db8::1"}).Return(nil)
+
+		assert.NoError(t, lockoutSrv.UnlockIP(userCtx(domain.NewID(), user.RoleSupport), "2001:0db8:0000::1"))
+		lockoutRepo.AssertExpectations(t)
+	})
+
+	t.Run("unlock invalid ip", func(t *testing.T) {
+		lockoutRepo := new(repoMock.LockoutRepositoryMock)
+		lockoutSrv := NewLockoutService(new(repoMock.UserRepositoryMock), lockoutRepo)
+
+		err := lockoutSrv.UnlockIP(adminCtx(), "not-an-ip")
+		assert.ErrorIs(t, err, lockout.ErrInvalidIP)
+		lockoutRepo.AssertNotCalled(t, "Reset", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unlock ip forbidden", func(t *testing.T) {
+		lockoutSrv := NewLockoutService(new(repoMock.UserRepositoryMock), new(repoMock.LockoutRepositoryMock))
+
+		err := lockoutSrv.UnlockIP(userCtx(domain.NewID(), user.RoleSelf), "192.0.2.1")
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+	})
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	"github.com/wojciechpawlinow/usermanagement/pkg/totp"
+)
+
+type MFAPort interface {
+	Enroll(ctx context.Context, userID string) (*MFAEnrollment, error)
+	Confirm(ctx context.Context, userID, code string) ([]string, error)
+	Disable(ctx context.Context, userID, code string) error
+	Reset(ctx context.Context, userID string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error)
+}
+
+// MFAEnrollment is what an authenticator app is set up with, shown to the user once
+type MFAEnrollment struct {
+	Secret string
+	URI    string
+}
+
+type mfaService struct {
+	userRepo     user.Repository
+	mfaRepo      mfa.Repository
+	sessionRepo  session.Repository
+	uow          domain.UnitOfWork
+	cipher       mfa.Cipher
+	verifier     *mfaVerifier
+	policy       *user.MFAPolicy
+	timeProvider domain.TimeProvider
+	issuer       string
+}
+
+var _ MFAPort = (*mfaService)(nil)
+
+func NewMFAService(
+	userRepo user.Repository,
+	mfaRepo mfa.Repository,
+	sessionRepo session.Repository,
+	uow domain.UnitOfWork,
+	cipher mfa.Cipher,
+	policy *user.MFAPolicy,
+	timeProvider domain.TimeProvider,
+	issuer string,
+) *mfaService {
+	return &mfaService{
+		userRepo:    userRepo,
+		mfaRepo:     mfaRepo,
+		sessionRepo: sessionRepo,
+		uow:         uow,
+		cipher:      cipher,
+		verifier: &mfaVerifier{
+			mfaRepo:      mfaRepo,
+			cipher:       cipher,
+			timeProvider: timeProvider,
+		},
+		policy:       policy,
+		timeProvider: timeProvider,
+		issuer:       issuer,
+	}
+}
+
+// Enroll generates a new secret, MFA is enabled only once the secret is confirmed with a code.
+// Enrolling again replaces a secret that has not been confirmed yet.
+func (s *mfaService) Enroll(ctx context.Context, userID string) (*MFAEnrollment, error) {
+	id, err := s.authorize(ctx, user.PermissionManageMFA, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := s.userRepo.GetByUUID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	factor, err := s.mfaRepo.Get(ctx, id)
+	switch {
+	case err == nil && factor.Enabled():
+		return nil, mfa.ErrAlreadyEnabled
+	case err != nil && !errors.Is(err, mfa.ErrNotEnrolled):
+		err = fmt.Errorf("failed fetching mfa factor: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	secret, err := mfa.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.cipher.Encrypt(secret)
+	if err != nil {
+		err = fmt.Errorf("failed encrypting secret: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	if err = s.mfaRepo.Enroll(ctx, &mfa.Factor{UserID: id, EncryptedSecret: encrypted}, s.timeProvider.UtcNow()); err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return nil, err
+		}
+
+		err = fmt.Errorf("failed enrolling mfa: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return &MFAEnrollment{
+		Secret: totp.Encoding.EncodeToString(secret),
+		URI:    totp.URI(s.issuer, u.Email, secret),
+	}, nil
+}
+
+// Confirm enables MFA once the user proves the authenticator app works, it returns recovery codes shown to the user once
+func (s *mfaService) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	id, err := s.authorize(ctx, user.PermissionManageMFA, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	factor, err := s.mfaRepo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, mfa.ErrNotEnrolled) {
+			return nil, err
+		}
+
+		err = fmt.Errorf("failed fetching mfa factor: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	if factor.Enabled() {
+		return nil, mfa.ErrAlreadyEnabled
+	}
+
+	step, err := s.verifier.validate(factor, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := mfa.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.uow.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.IncrementVersion(ctx, id, nil); err != nil {
+			return err
+		}
+
+		return s.mfaRepo.Confirm(ctx, id, step, s.timeProvider.UtcNow(), hashes)
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) || errors.Is(err, mfa.ErrNotEnrolled) {
+			return nil, err
+		}
+
+		err = fmt.Errorf("failed confirming mfa: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns MFA off with a current code, unless the role of the user requires it
+func (s *mfaService) Disable(ctx context.Context, userID, code string) error {
+	id, err := s.authorize(ctx, user.PermissionManageMFA, userID)
+	if err != nil {
+		return err
+	}
+
+	u, err := s.userRepo.GetByUUID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if s.policy.Requires(u.Role) {
+		return user.ErrMFARequired
+	}
+
+	if err = s.verifier.verify(ctx, id, code); err != nil {
+		return err
+	}
+
+	return s.delete(ctx, id, false)
+}
+
+// Reset turns MFA of a user off without a code, for users who lost their authenticator app and recovery codes.
+// All sessions of the user are revoked, so they log in again without the second factor.
+func (s *mfaService) Reset(ctx context.Context, userID string) error {
+	id, err := s.authorize(ctx, user.PermissionResetMFA, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.delete(ctx, id, true)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, used or not, with new ones shown to the user once
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	id, err := s.authorize(ctx, user.PermissionManageMFA, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.verifier.verify(ctx, id, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := mfa.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.mfaRepo.ReplaceRecoveryCodes(ctx, id, hashes); err != nil {
+		err = fmt.Errorf("failed replacing recovery codes: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *mfaService) authorize(ctx context.Context, permission user.Permission, userID string) (domain.ID, error) {
+	id, err := domain.ParseID(userID)
+	if err != nil {
+		return domain.ID{}, fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, permission, id); err != nil {
+		return domain.ID{}, err
+	}
+
+	return id, nil
+}
+
+func (s *mfaService) delete(ctx context.Context, id domain.ID, revokeSessions bool) error {
+	err := s.uow.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.IncrementVersion(ctx, id, nil); err != nil {
+			return err
+		}
+
+		if revokeSessions {
+			if err := s.sessionRepo.RevokeUser(ctx, id, s.timeProvider.UtcNow()); err != nil {
+				return err
+			}
+		}
+
+		return s.mfaRepo.Delete(ctx, id)
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return err
+		}
+
+		err = fmt.Errorf("failed disabling mfa: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	return nil
+}
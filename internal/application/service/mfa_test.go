@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/totp"
+	domainMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain"
+)
+
+func newMFAService(now time.Time, policy *user.MFAPolicy) (*mfaService, *serviceMocks) {
+	m := newServiceMocks(now)
+
+	return NewMFAService(m.userRepo, m.mfaRepo, m.sessionRepo, new(domainMock.UnitOfWorkMock), m.cipher, policy, m.timeProvider, "usermanagement"), m
+}
+
+func TestEnrollMFA(t *testing.T) {
+	now := time.Now()
+
+	t.Run("enroll", func(t *testing.T) {
+		mfaSrv, m := newMFAService(now, testMFAPolicy)
+
+		userID := domain.NewID()
+
+		m.userRepo.On("GetByUUID", mock.Anything, userID).Return(&user.User{ID: userID, Email: "test@example.com"}, nil)
+		m.mfaRepo.On("Get", mock.Anything, userID).Return(nil, mfa.ErrNotEnrolled)
+		m.cipher.On("Encrypt", mock.Anything).Return("encrypted", nil)
+		m.mfaRepo.On("Enroll", mock.Anything, &mfa.Factor{UserID: userID, EncryptedSecret: "encrypted"}, now).Return(nil)
+
+		enrollment, err := mfaSrv.Enroll(userCtx(userID, user.RoleSelf), userID.String())
+		assert.NoError(t, err)
+
+		uri, err := url.Parse(enrollment.URI)
+		assert.NoError(t, err)
+		assert.Equal(t, enrollment.Secret, uri.Query().Get("secret"))
+		assert.Equal(t, "/usermanagement:test@example.com", uri.Path)
+
+		// the app is set up with the same secret which is stored encrypted
+		secret, err := totp.Encoding.DecodeString(enrollment.Secret)
+		assert.NoError(t, err)
+		m.cipher.AssertCalled(t, "Encrypt", secret)
+	})
+
+	t.Run("already enabled", func(t *testing.T) {
+		mfaSrv, m := newMFAService(now, testMFAPolicy)
+
+		userID := domain.NewID()
+
+		m.userRepo.On("GetByUUID", mock.Anything, userID).Return(&user.User{ID: userID}, nil)
+		m.enabled(userID, now)
+
+		_, err := mfaSrv.Enroll(userCtx(userID, user.RoleSelf), userID.String())
+		assert.ErrorIs(t, err, mfa.ErrAlreadyEnabled)
+		m.mfaRepo.AssertNotCalled(t, "Enroll", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("someone else's mfa", func(t *testing.T) {
+		mfaSrv, _ := newMFAService(now, testMFAPolicy)
+
+		_, err := mfaSrv.Enroll(userCtx(domain.NewID(), user.RoleAdmin), domain.NewID().String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+	})
+}
+
+func TestConfirmMFA(t *testing.T) {
+	now := time.Now()
+	step := totp.Step(now)
+
+	t.Run("confirm", func(t *testing.T) {
+		mfaSrv, m := newMFAService(now, testMFAPolicy)
+
+		userID := domain.NewID()
+
+		m.mfaRepo.On("Get", mock.Anything, userID).Return(&mfa.Factor{UserID: userID, EncryptedSecret: "encrypted"}, nil)
+		m.cipher.On("Decrypt", "encrypted").Return(testSecret, nil)
+		m.userRepo.On("IncrementVersion", mock.Anything, userID, (*int64)(nil)).Return(nil)
+		m.mfaRepo.On("Confirm", mock.Anything, userID, step, now, mock.MatchedBy(func(hashes []string) bool {
+			return len(hashes) == mfa.RecoveryCodeCount
+		})).Return(nil)
+
+		codes, err := mfaSrv.Confirm(userCtx(userID, user.RoleSelf), userID.String(), totp.Code(testSecret, step))
+		assert.NoError(t, err)
+		assert.Len(t, codes, mfa.RecoveryCodeCount)
+		m.mfaRepo.AssertExpectations(t)
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		mfaSrv, m := newMFAService(now, testMFAPolicy)
+
+		userID := domain.NewID()
+
+		m.mfaRepo.On("Get", mock.Anything, userID).Return(&mfa.Factor{UserID: userID, EncryptedSecret: "encrypted"}, nil)
+		m.cipher.On("Decrypt", "encrypted").Return(testSecret, nil)
+
+		_, err := mfaSrv.Confirm(userCtx(userID, user.RoleSelf), userID.String(), totp.Code(testSecret, step+10))
+		assert.ErrorIs(t, err, mfa.ErrInvalidCode)
+		m.mfaRepo.AssertNotCalled(t, "Confirm", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("not enrolled", func(t *testing.T) {
+		mfaSrv, m := newMFAService(now, testMFAPolicy)
+
+		userID := domain.NewID()
+
+		m.mfaRepo.On("Get", mock.Anything, userID).Return(nil, mfa.ErrNotEnrolled)
+
+		_, err := mfaSrv.Confirm(userCtx(userID, user.RoleSelf), userID.String(), "123456")
+		assert.ErrorIs(t, err, mfa.ErrNotEnrolled)
+	})
+
+	t.Run("already enabled", func(t *testing.T) {
+		mfaSrv, m := newMFAService(now, testMFAPolicy)
+
+		userID := domain.NewID()
+
+		m.enabled(userID, now)
+
+		_, err := mfaSrv.Confirm(userCtx(userID, user.RoleSelf), userID.String(), totp.Code(testSecret, step))
+		assert.ErrorIs(t, err, mfa.ErrAlreadyEnabled)
+	})
+}
+
+func TestDisableMFA(t *testing.T) {
+	now := time.Now()
+	step := totp.Step(now)
+
+	t.Run("disable", func(t *testing.T) {
+		mfaSrv, m := newMFAService(now, testMFAPolicy)
+
+		userID := domain.NewID()
+
+		m.userRepo.On("GetByUUID", mock.Anything, userID).Return(&user.User{ID: userID, Role: user.RoleSelf}, nil)
+		m.enabled(userID, now)
+		m.mfaRepo.On("UseStep", mock.Anything, userID, step).Return(nil)
+		m.userRepo.On("IncrementVersion", mock.Anything, userID, (*int64)(nil)).Return(nil)
+		m.mfaRepo.On("Delete", mock.Anything, userID).Return(nil)
+
+		err := mfaSrv.Disable(userCtx(userID, user.RoleSelf), userID.String(), totp.Code(testSecret, step))
+		assert.NoError(t, err)
+		m.mfaRepo.AssertExpectations(t)
+	})
+
+	t.Run("required by the role", func(t *testing.T) {
+		mfaSrv, m := newMFAService(now, user.NewMFAPolicy([]user.Role{user.RoleAdmin}))
+
+		userID := domain.NewID()
+
+		m.userRepo.On("GetByUUID", mock.Anything, userID).Return(&user.User{ID: userID, Role: user.RoleAdmin}, nil)
+
+		err := mfaSrv.Disable(userCtx(userID, user.RoleAdmin), userID.String(), totp.Code(testSecret, step))
+		assert.ErrorIs(t, err, user.ErrMFARequired)
+		m.mfaRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		mfaSrv, m := newMFAService(now, testMFAPolicy)
+
+		userID := domain.NewID()
+
+		m.userRepo.On("GetByUUID", mock.Anything, userID).Return(&user.User{ID: userID, Role: user.RoleSelf}, nil)
+		m.enabled(userID, now)
+
+		err := mfaSrv.Disable(userCtx(userID, user.RoleSelf), userID.String(), totp.Code(testSecret, step+10))
+		assert.ErrorIs(t, err, mfa.ErrInvalidCode)
+		m.mfaRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+}
+
+func TestResetMFA(t *testing.T) {
+	t.Run("admin resets", func(t *testing.T) {
+		now := time.Now()
+		mfaSrv, m := newMFAService(now, testMFAPolicy)
+
+		userID := domain.NewID()
+
+		m.userRepo.On("IncrementVersion", mock.Anything, userID, (*int64)(nil)).Return(nil)
+		m.sessionRepo.On("RevokeUser", mock.Anything, userID, now).Return(nil)
+		m.mfaRepo.On("Delete", mock.Anything, userID).Return(nil)
+
+		err := mfaSrv.Reset(adminCtx(), userID.String())
+		assert.NoError(t, err)
+		m.mfaRepo.AssertExpectations(t)
+		m.sessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("user can not reset own mfa", func(t *testing.T) {
+		mfaSrv, m := newMFAService(time.Now(), testMFAPolicy)
+
+		userID := domain.NewID()
+
+		err := mfaSrv.Reset(userCtx(userID, user.RoleSelf), userID.String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		m.mfaRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mfaSrv, m := newMFAService(time.Now(), testMFAPolicy)
+
+		m.userRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(user.ErrNotFound)
+
+		err := mfaSrv.Reset(adminCtx(), domain.NewID().String())
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+}
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	now := time.Now()
+
+	t.Run("regenerate with a recovery code", func(t *testing.T) {
+		mfaSrv, m := newMFAService(now, testMFAPolicy)
+
+		userID := domain.NewID()
+
+		m.enabled(userID, now)
+		m.mfaRepo.On("UseRecoveryCode", mock.Anything, userID, mfa.HashRecoveryCode("abcdefgh-ijklmnop"), now).Return(nil)
+		m.mfaRepo.On("ReplaceRecoveryCodes", mock.Anything, userID, mock.MatchedBy(func(hashes []string) bool {
+			return len(hashes) == mfa.RecoveryCodeCount
+		})).Return(nil)
+
+		codes, err := mfaSrv.RegenerateRecoveryCodes(userCtx(userID, user.RoleSelf), userID.String(), "abcdefgh-ijklmnop")
+		assert.NoError(t, err)
+		assert.Len(t, codes, mfa.RecoveryCodeCount)
+		m.mfaRepo.AssertExpectations(t)
+	})
+
+	t.Run("used recovery code", func(t *testing.T) {
+		mfaSrv, m := newMFAService(now, testMFAPolicy)
+
+		userID := domain.NewID()
+
+		m.enabled(userID, now)
+		m.mfaRepo.On("UseRecoveryCode", mock.Anything, userID, mock.Anything, now).Return(mfa.ErrInvalidCode)
+
+		_, err := mfaSrv.RegenerateRecoveryCodes(context.Background(), userID.String(), "abcdefgh-ijklmnop")
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+
+		_, err = mfaSrv.RegenerateRecoveryCodes(userCtx(userID, user.RoleSelf), userID.String(), "abcdefgh-ijklmnop")
+		assert.ErrorIs(t, err, mfa.ErrInvalidCode)
+		m.mfaRepo.AssertNotCalled(t, "ReplaceRecoveryCodes", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
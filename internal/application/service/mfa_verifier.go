@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/pkg/totp"
+)
+
+// codeSkew is the number of time steps a code is accepted for before and after its own one
+const codeSkew = 1
+
+// mfaVerifier checks codes of users with MFA enabled
+type mfaVerifier struct {
+	mfaRepo      mfa.Repository
+	cipher       mfa.Cipher
+	timeProvider domain.TimeProvider
+}
+
+// verify accepts a current TOTP code or an unused recovery code, either of them only once.
+// mfa.ErrNotEnrolled is returned when MFA of the user is not enabled, mfa.ErrInvalidCode for any other code.
+func (v *mfaVerifier) verify(ctx context.Context, userID domain.ID, code string) error {
+	factor, err := v.mfaRepo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !factor.Enabled() {
+		return mfa.ErrNotEnrolled
+	}
+
+	now := v.timeProvider.UtcNow()
+
+	if len(code) != totp.Digits {
+		return v.mfaRepo.UseRecoveryCode(ctx, userID, mfa.HashRecoveryCode(code), now)
+	}
+
+	step, err := v.validate(factor, code)
+	if err != nil {
+		return err
+	}
+
+	// checked here as well to spare the write for a replayed code
+	if step <= factor.LastUsedStep {
+		return mfa.ErrInvalidCode
+	}
+
+	return v.mfaRepo.UseStep(ctx, userID, step)
+}
+
+// validate checks the TOTP code against the secret of the factor and returns its time step
+func (v *mfaVerifier) validate(factor *mfa.Factor, code string) (int64, error) {
+	secret, err := v.cipher.Decrypt(factor.EncryptedSecret)
+	if err != nil {
+		return 0, fmt.Errorf("failed decrypting secret: %w", err)
+	}
+
+	step, ok := totp.Validate(secret, code, v.timeProvider.UtcNow(), codeSkew)
+	if !ok {
+		return 0, mfa.ErrInvalidCode
+	}
+
+	return step, nil
+}
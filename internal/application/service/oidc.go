@@ -0,0 +1,392 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+const (
+	responseTypeCode           = "code"
+	grantTypeAuthorizationCode = "authorization_code"
+	tokenTypeBearer            = "Bearer"
+)
+
+type OIDCPort interface {
+	// Prepare checks the authorization request and returns the client and the scopes the user is asked to grant it
+	Prepare(ctx context.Context, req *AuthorizationRequest) (*oidc.Client, []string, error)
+	Authorize(ctx context.Context, req *AuthorizationRequest) (string, error)
+	Exchange(ctx context.Context, req *TokenRequest) (*auth.Token, error)
+	UserInfo(ctx context.Context, accessToken string) (*oidc.UserClaims, error)
+	PublicKeys(ctx context.Context) ([]oidc.PublicKey, error)
+	RegisterClient(ctx context.Context, name string, redirectURIs []string, public bool) (*oidc.Client, string, error)
+	DeleteClient(ctx context.Context, clientID string) error
+}
+
+// AuthorizationRequest are the parameters of the authorization endpoint
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// TokenRequest are the parameters of the token endpoint, the client secret is empty for public clients
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	ClientID     string
+	ClientSecret string
+	CodeVerifier string
+}
+
+type oidcService struct {
+	userRepo       user.Repository
+	clientRepo     oidc.ClientRepository
+	codeRepo       oidc.CodeRepository
+	signer         oidc.Signer
+	timeProvider   domain.TimeProvider
+	issuer         string
+	codeTTL        time.Duration
+	accessTokenTTL time.Duration
+}
+
+var _ OIDCPort = (*oidcService)(nil)
+
+func NewOIDCService(
+	userRepo user.Repository,
+	clientRepo oidc.ClientRepository,
+	codeRepo oidc.CodeRepository,
+	signer oidc.Signer,
+	timeProvider domain.TimeProvider,
+	issuer string,
+	codeTTL time.Duration,
+	accessTokenTTL time.Duration,
+) *oidcService {
+	return &oidcService{
+		userRepo:       userRepo,
+		clientRepo:     clientRepo,
+		codeRepo:       codeRepo,
+		signer:         signer,
+		timeProvider:   timeProvider,
+		issuer:         issuer,
+		codeTTL:        codeTTL,
+		accessTokenTTL: accessTokenTTL,
+	}
+}
+
+func (s *oidcService) Prepare(ctx context.Context, req *AuthorizationRequest) (*oidc.Client, []string, error) {
+	clientID, err := domain.ParseID(req.ClientID)
+	if err != nil {
+		return nil, nil, oidc.ErrInvalidClient
+	}
+
+	client, err := s.clientRepo.Get(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, oidc.ErrClientNotFound) {
+			return nil, nil, oidc.ErrInvalidClient
+		}
+
+		err = fmt.Errorf("failed fetching client: %w", err)
+		logger.Debug(err)
+
+		return nil, nil, err
+	}
+
+	if !client.AllowsRedirect(req.RedirectURI) {
+		return nil, nil, oidc.ErrInvalidRedirectURI
+	}
+
+	if req.ResponseType != responseTypeCode {
+		return nil, nil, oidc.ErrUnsupportedResponseType
+	}
+
+	scopes, err := oidc.ParseScopes(req.Scope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// PKCE is required from confidential clients as well, it protects against injected codes
+	if req.CodeChallengeMethod != oidc.CodeChallengeMethodS256 || !oidc.ValidCodeChallenge(req.CodeChallenge) {
+		return nil, nil, oidc.ErrInvalidRequest
+	}
+
+	return client, scopes, nil
+}
+
+// Authorize issues an authorization code to the client for the calling user, who has logged in and consented to it.
+// ErrInvalidClient and ErrInvalidRedirectURI mean the redirect URI can not be trusted, any later error is reported to the client through it.
+func (s *oidcService) Authorize(ctx context.Context, req *AuthorizationRequest) (string, error) {
+	client, scopes, err := s.Prepare(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	identity, ok := auth.IdentityFromContext(ctx)
+	if !ok {
+		return "", auth.ErrUnauthenticated
+	}
+
+	// API key clients are not users who could log in anywhere
+	if identity.IsClient() {
+		return "", auth.ErrForbidden
+	}
+
+	now := s.timeProvider.UtcNow()
+
+	code, authorization, err := oidc.NewCode(client.ID, identity.UserID, req.RedirectURI, scopes, req.Nonce, req.CodeChallenge, now.Add(s.codeTTL))
+	if err != nil {
+		return "", err
+	}
+
+	if err = s.codeRepo.Create(ctx, authorization, now); err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return "", auth.ErrUnauthenticated
+		}
+
+		err = fmt.Errorf("failed storing authorization code: %w", err)
+		logger.Debug(err)
+
+		return "", err
+	}
+
+	return code, nil
+}
+
+// Exchange issues an access token and an ID token for the authorization code, which is accepted once.
+// The access token is not the one of the API, it grants the client reading the user info of the granted scopes only.
+func (s *oidcService) Exchange(ctx context.Context, req *TokenRequest) (*auth.Token, error) {
+	if req.GrantType != grantTypeAuthorizationCode {
+		return nil, oidc.ErrUnsupportedGrantType
+	}
+
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, oidc.ErrInvalidRequest
+	}
+
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.timeProvider.UtcNow()
+
+	code, err := s.codeRepo.Use(ctx, oidc.Hash(req.Code), now)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidGrant) {
+			return nil, err
+		}
+
+		err = fmt.Errorf("failed using authorization code: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	if !code.ClientID.Equal(client.ID) || code.RedirectURI != req.RedirectURI || !now.Before(code.ExpiresAt) || !code.VerifyPKCE(req.CodeVerifier) {
+		return nil, oidc.ErrInvalidGrant
+	}
+
+	// the role may have changed since the login
+	u, err := s.userRepo.GetByUUID(ctx, code.UserID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return nil, oidc.ErrInvalidGrant
+		}
+
+		err = fmt.Errorf("failed fetching user: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	token := &auth.Token{TokenType: tokenTypeBearer, ExpiresIn: s.accessTokenTTL}
+
+	token.AccessToken, err = s.signer.Sign(ctx, &oidc.AccessTokenClaims{
+		Issuer:    s.issuer,
+		Subject:   u.ID.String(),
+		Audience:  client.ID.String(),
+		ClientID:  client.ID.String(),
+		Scope:     strings.Join(code.Scopes, " "),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.accessTokenTTL).Unix(),
+	})
+	if err != nil {
+		err = fmt.Errorf("failed signing access token: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	token.IDToken, err = s.signer.Sign(ctx, &oidc.IDTokenClaims{
+		Issuer:     s.issuer,
+		Audience:   client.ID.String(),
+		IssuedAt:   now.Unix(),
+		ExpiresAt:  now.Add(s.accessTokenTTL).Unix(),
+		Nonce:      code.Nonce,
+		UserClaims: oidc.NewUserClaims(u, code.Scopes),
+	})
+	if err != nil {
+		err = fmt.Errorf("failed signing id token: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// UserInfo returns the claims about the user the scopes granted in the access token allow.
+// Only access tokens issued by Exchange are accepted, neither ID tokens nor the tokens of the API.
+func (s *oidcService) UserInfo(ctx context.Context, accessToken string) (*oidc.UserClaims, error) {
+	var claims oidc.AccessTokenClaims
+	if err := s.signer.Verify(ctx, accessToken, &claims); err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, err
+		}
+
+		err = fmt.Errorf("failed verifying access token: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	if claims.Issuer != s.issuer || claims.ClientID == "" || claims.Audience != claims.ClientID || s.timeProvider.UtcNow().Unix() >= claims.ExpiresAt {
+		return nil, auth.ErrInvalidToken
+	}
+
+	clientID, err := domain.ParseID(claims.ClientID)
+	if err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+
+	// tokens of a deleted client are not honored anymore
+	if _, err = s.clientRepo.Get(ctx, clientID); err != nil {
+		if errors.Is(err, oidc.ErrClientNotFound) {
+			return nil, auth.ErrInvalidToken
+		}
+
+		err = fmt.Errorf("failed fetching client: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	id, err := domain.ParseID(claims.Subject)
+	if err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+
+	u, err := s.userRepo.GetByUUID(ctx, id)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return nil, auth.ErrInvalidToken
+		}
+
+		err = fmt.Errorf("failed fetching user: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return oidc.NewUserClaims(u, strings.Fields(claims.Scope)), nil
+}
+
+func (s *oidcService) PublicKeys(ctx context.Context) ([]oidc.PublicKey, error) {
+	keys, err := s.signer.PublicKeys(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed fetching public keys: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// RegisterClient stores a new client, the returned secret of a confidential client is shown once
+func (s *oidcService) RegisterClient(ctx context.Context, name string, redirectURIs []string, public bool) (*oidc.Client, string, error) {
+	if err := authorize(ctx, user.PermissionManageClients, domain.ID{}); err != nil {
+		return nil, "", err
+	}
+
+	client, secret, err := oidc.NewClient(name, redirectURIs, public)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err = s.clientRepo.Create(ctx, client, s.timeProvider.UtcNow()); err != nil {
+		err = fmt.Errorf("failed creating client: %w", err)
+		logger.Debug(err)
+
+		return nil, "", err
+	}
+
+	logger.Info(fmt.Sprintf("client %s registered by %s", client.ID, actor(ctx)))
+
+	return client, secret, nil
+}
+
+func (s *oidcService) DeleteClient(ctx context.Context, clientID string) error {
+	id, err := domain.ParseID(clientID)
+	if err != nil {
+		return fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionManageClients, domain.ID{}); err != nil {
+		return err
+	}
+
+	if err = s.clientRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, oidc.ErrClientNotFound) {
+			return err
+		}
+
+		err = fmt.Errorf("failed deleting client: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("client %s deleted by %s", id, actor(ctx)))
+
+	return nil
+}
+
+// authenticateClient checks the secret of a confidential client, public ones prove themselves with the PKCE verifier only
+func (s *oidcService) authenticateClient(ctx context.Context, clientID, secret string) (*oidc.Client, error) {
+	id, err := domain.ParseID(clientID)
+	if err != nil {
+		return nil, oidc.ErrInvalidClient
+	}
+
+	client, err := s.clientRepo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, oidc.ErrClientNotFound) {
+			return nil, oidc.ErrInvalidClient
+		}
+
+		err = fmt.Errorf("failed fetching client: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	if !client.Public() && !client.VerifySecret(secret) {
+		return nil, oidc.ErrInvalidClient
+	}
+
+	return client, nil
+}
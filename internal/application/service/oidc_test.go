@@ -0,0 +1,496 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+const (
+	testIssuer       = "https://id.example.com"
+	testCodeTTL      = time.Minute
+	testTokenTTL     = 15 * time.Minute
+	testRedirectURI  = "https://app.example.com/callback"
+	testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+func newOIDCService(now time.Time) (*oidcService, *serviceMocks) {
+	m := newServiceMocks(now)
+
+	return NewOIDCService(m.userRepo, m.clientRepo, m.codeRepo, m.signer, m.timeProvider, testIssuer, testCodeTTL, testTokenTTL), m
+}
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func authorizationRequest(clientID domain.ID) *AuthorizationRequest {
+	return &AuthorizationRequest{
+		ResponseType:        "code",
+		ClientID:            clientID.String(),
+		RedirectURI:         testRedirectURI,
+		Scope:               "openid email",
+		Nonce:               "nonce",
+		CodeChallenge:       codeChallenge(testCodeVerifier),
+		CodeChallengeMethod: oidc.CodeChallengeMethodS256,
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	now := time.Now()
+	userID := domain.NewID()
+	client := &oidc.Client{ID: domain.NewID(), Name: "app", RedirectURIs: []string{testRedirectURI}}
+
+	t.Run("issue code", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		var stored *oidc.AuthorizationCode
+
+		m.clientRepo.On("Get", mock.Anything, client.ID).Return(client, nil)
+		m.codeRepo.On("Create", mock.Anything, mock.Anything, now).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*oidc.AuthorizationCode)
+		}).Return(nil)
+
+		code, err := oidcSrv.Authorize(userCtx(userID, user.RoleSelf), authorizationRequest(client.ID))
+		assert.NoError(t, err)
+		assert.NotEmpty(t, code)
+		assert.Equal(t, oidc.Hash(code), stored.Hash)
+		assert.Equal(t, userID, stored.UserID)
+		assert.Equal(t, client.ID, stored.ClientID)
+		assert.Equal(t, []string{oidc.ScopeOpenID, oidc.ScopeEmail}, stored.Scopes)
+		assert.Equal(t, "nonce", stored.Nonce)
+		assert.True(t, stored.ExpiresAt.Equal(now.Add(testCodeTTL)))
+	})
+
+	t.Run("prepare before the login", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		m.clientRepo.On("Get", mock.Anything, client.ID).Return(client, nil)
+
+		prepared, scopes, err := oidcSrv.Prepare(context.Background(), authorizationRequest(client.ID))
+		assert.NoError(t, err)
+		assert.Equal(t, client, prepared)
+		assert.Equal(t, []string{oidc.ScopeOpenID, oidc.ScopeEmail}, scopes)
+		m.codeRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown client", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		m.clientRepo.On("Get", mock.Anything, client.ID).Return(nil, oidc.ErrClientNotFound)
+
+		_, err := oidcSrv.Authorize(userCtx(userID, user.RoleSelf), authorizationRequest(client.ID))
+		assert.ErrorIs(t, err, oidc.ErrInvalidClient)
+	})
+
+	t.Run("unregistered redirect URI", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		req := authorizationRequest(client.ID)
+		req.RedirectURI = "https://app.example.com/callback/other"
+		req.ResponseType = "token" // reported after the redirect URI, which must not be used
+
+		m.clientRepo.On("Get", mock.Anything, client.ID).Return(client, nil)
+
+		_, err := oidcSrv.Authorize(userCtx(userID, user.RoleSelf), req)
+		assert.ErrorIs(t, err, oidc.ErrInvalidRedirectURI)
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			modify func(req *AuthorizationRequest)
+			err    error
+		}{
+			{"implicit flow", func(req *AuthorizationRequest) { req.ResponseType = "token" }, oidc.ErrUnsupportedResponseType},
+			{"missing openid scope", func(req *AuthorizationRequest) { req.Scope = "email" }, oidc.ErrInvalidScope},
+			{"unknown scope", func(req *AuthorizationRequest) { req.Scope = "openid admin" }, oidc.ErrInvalidScope},
+			{"missing challenge", func(req *AuthorizationRequest) { req.CodeChallenge = "" }, oidc.ErrInvalidRequest},
+			{"plain challenge", func(req *AuthorizationRequest) { req.CodeChallengeMethod = "plain" }, oidc.ErrInvalidRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				oidcSrv, m := newOIDCService(now)
+
+				req := authorizationRequest(client.ID)
+				tt.modify(req)
+
+				m.clientRepo.On("Get", mock.Anything, client.ID).Return(client, nil)
+
+				_, err := oidcSrv.Authorize(userCtx(userID, user.RoleSelf), req)
+				assert.ErrorIs(t, err, tt.err)
+				m.codeRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("api key client", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		m.clientRepo.On("Get", mock.Anything, client.ID).Return(client, nil)
+
+		_, err := oidcSrv.Authorize(adminCtx(), authorizationRequest(client.ID))
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+	})
+}
+
+func TestExchange(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	now := time.Now()
+	u := &user.User{ID: domain.NewID(), Email: "john@example.com", EmailVerified: true, FirstName: "John", LastName: "Doe", Role: user.RoleSupport}
+	client := &oidc.Client{ID: domain.NewID(), Name: "app", SecretHash: oidc.Hash("client-secret"), RedirectURIs: []string{testRedirectURI}}
+
+	authorizationCode := func() *oidc.AuthorizationCode {
+		return &oidc.AuthorizationCode{
+			Hash:          oidc.Hash("code"),
+			ClientID:      client.ID,
+			UserID:        u.ID,
+			RedirectURI:   testRedirectURI,
+			Scopes:        []string{oidc.ScopeOpenID, oidc.ScopeEmail},
+			Nonce:         "nonce",
+			CodeChallenge: codeChallenge(testCodeVerifier),
+			ExpiresAt:     now.Add(time.Minute),
+		}
+	}
+
+	tokenRequest := func() *TokenRequest {
+		return &TokenRequest{
+			GrantType:    "authorization_code",
+			Code:         "code",
+			RedirectURI:  testRedirectURI,
+			ClientID:     client.ID.String(),
+			ClientSecret: "client-secret",
+			CodeVerifier: testCodeVerifier,
+		}
+	}
+
+	t.Run("issue tokens", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		m.clientRepo.On("Get", mock.Anything, client.ID).Return(client, nil)
+		m.codeRepo.On("Use", mock.Anything, oidc.Hash("code"), now).Return(authorizationCode(), nil)
+		m.userRepo.On("GetByUUID", mock.Anything, u.ID).Return(u, nil)
+		m.signer.On("Sign", mock.Anything, mock.MatchedBy(func(claims *oidc.AccessTokenClaims) bool {
+			return claims.Issuer == testIssuer &&
+				claims.Subject == u.ID.String() &&
+				claims.Audience == client.ID.String() &&
+				claims.ClientID == client.ID.String() &&
+				claims.Scope == "openid email" &&
+				claims.ExpiresAt == now.Add(testTokenTTL).Unix()
+		})).Return("access-token", nil)
+		m.signer.On("Sign", mock.Anything, mock.MatchedBy(func(claims *oidc.IDTokenClaims) bool {
+			return claims.Issuer == testIssuer &&
+				claims.Audience == client.ID.String() &&
+				claims.Subject == u.ID.String() &&
+				claims.Nonce == "nonce" &&
+				claims.Email == u.Email &&
+				claims.GivenName == "" && // the profile scope has not been granted
+				claims.ExpiresAt == now.Add(testTokenTTL).Unix()
+		})).Return("id-token", nil)
+
+		token, err := oidcSrv.Exchange(context.Background(), tokenRequest())
+		assert.NoError(t, err)
+		assert.Equal(t, "access-token", token.AccessToken)
+		assert.Equal(t, "Bearer", token.TokenType)
+		assert.Equal(t, testTokenTTL, token.ExpiresIn)
+		assert.Equal(t, "id-token", token.IDToken)
+		assert.Empty(t, token.RefreshToken)
+	})
+
+	t.Run("public client", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		public := &oidc.Client{ID: client.ID, Name: "spa", RedirectURIs: client.RedirectURIs}
+		req := tokenRequest()
+		req.ClientSecret = ""
+
+		m.clientRepo.On("Get", mock.Anything, client.ID).Return(public, nil)
+		m.codeRepo.On("Use", mock.Anything, oidc.Hash("code"), now).Return(authorizationCode(), nil)
+		m.userRepo.On("GetByUUID", mock.Anything, u.ID).Return(u, nil)
+		m.signer.On("Sign", mock.Anything, mock.Anything).Return("token", nil)
+
+		_, err := oidcSrv.Exchange(context.Background(), req)
+		assert.NoError(t, err)
+	})
+
+	t.Run("wrong client secret", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		req := tokenRequest()
+		req.ClientSecret = "wrong"
+
+		m.clientRepo.On("Get", mock.Anything, client.ID).Return(client, nil)
+
+		_, err := oidcSrv.Exchange(context.Background(), req)
+		assert.ErrorIs(t, err, oidc.ErrInvalidClient)
+		m.codeRepo.AssertNotCalled(t, "Use", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unsupported grant type", func(t *testing.T) {
+		oidcSrv, _ := newOIDCService(now)
+
+		req := tokenRequest()
+		req.GrantType = "password"
+
+		_, err := oidcSrv.Exchange(context.Background(), req)
+		assert.ErrorIs(t, err, oidc.ErrUnsupportedGrantType)
+	})
+
+	t.Run("used code", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		m.clientRepo.On("Get", mock.Anything, client.ID).Return(client, nil)
+		m.codeRepo.On("Use", mock.Anything, oidc.Hash("code"), now).Return(nil, oidc.ErrInvalidGrant)
+
+		_, err := oidcSrv.Exchange(context.Background(), tokenRequest())
+		assert.ErrorIs(t, err, oidc.ErrInvalidGrant)
+	})
+
+	t.Run("invalid grant", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			modify func(code *oidc.AuthorizationCode, req *TokenRequest)
+		}{
+			{"wrong verifier", func(_ *oidc.AuthorizationCode, req *TokenRequest) { req.CodeVerifier = testCodeVerifier[1:] + "a" }},
+			{"other redirect URI", func(_ *oidc.AuthorizationCode, req *TokenRequest) { req.RedirectURI = "https://app.example.com/" }},
+			{"other client", func(code *oidc.AuthorizationCode, _ *TokenRequest) { code.ClientID = domain.NewID() }},
+			{"expired code", func(code *oidc.AuthorizationCode, _ *TokenRequest) { code.ExpiresAt = now }},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				oidcSrv, m := newOIDCService(now)
+
+				code, req := authorizationCode(), tokenRequest()
+				tt.modify(code, req)
+
+				m.clientRepo.On("Get", mock.Anything, client.ID).Return(client, nil)
+				m.codeRepo.On("Use", mock.Anything, oidc.Hash("code"), now).Return(code, nil)
+
+				_, err := oidcSrv.Exchange(context.Background(), req)
+				assert.ErrorIs(t, err, oidc.ErrInvalidGrant)
+				m.signer.AssertNotCalled(t, "Sign", mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("deleted user", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		m.clientRepo.On("Get", mock.Anything, client.ID).Return(client, nil)
+		m.codeRepo.On("Use", mock.Anything, oidc.Hash("code"), now).Return(authorizationCode(), nil)
+		m.userRepo.On("GetByUUID", mock.Anything, u.ID).Return(nil, user.ErrNotFound)
+
+		_, err := oidcSrv.Exchange(context.Background(), tokenRequest())
+		assert.ErrorIs(t, err, oidc.ErrInvalidGrant)
+	})
+}
+
+func TestUserInfo(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	now := time.Now()
+	u := &user.User{ID: domain.NewID(), Email: "john@example.com", FirstName: "John", LastName: "Doe", PhoneNumber: "+48123456789"}
+	client := &oidc.Client{ID: domain.NewID(), Name: "app", RedirectURIs: []string{testRedirectURI}}
+
+	accessToken := func() oidc.AccessTokenClaims {
+		return oidc.AccessTokenClaims{
+			Issuer:    testIssuer,
+			Subject:   u.ID.String(),
+			Audience:  client.ID.String(),
+			ClientID:  client.ID.String(),
+			Scope:     "openid email",
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(testTokenTTL).Unix(),
+		}
+	}
+
+	verified := func(m *serviceMocks, claims oidc.AccessTokenClaims) {
+		m.signer.On("Verify", mock.Anything, "access-token", mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(2).(*oidc.AccessTokenClaims) = claims
+		}).Return(nil)
+	}
+
+	t.Run("claims of the granted scopes", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		verified(m, accessToken())
+		m.clientRepo.On("Get", mock.Anything, client.ID).Return(client, nil)
+		m.userRepo.On("GetByUUID", mock.Anything, u.ID).Return(u, nil)
+
+		claims, err := oidcSrv.UserInfo(context.Background(), "access-token")
+		assert.NoError(t, err)
+		assert.Equal(t, u.ID.String(), claims.Subject)
+		assert.Equal(t, u.Email, claims.Email)
+		assert.False(t, *claims.EmailVerified)
+		assert.Empty(t, claims.Name)
+		assert.Empty(t, claims.PhoneNumber)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		m.signer.On("Verify", mock.Anything, "access-token", mock.Anything).Return(auth.ErrInvalidToken)
+
+		_, err := oidcSrv.UserInfo(context.Background(), "access-token")
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+		m.userRepo.AssertNotCalled(t, "GetByUUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid claims", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			modify func(claims *oidc.AccessTokenClaims)
+		}{
+			{"id token", func(claims *oidc.AccessTokenClaims) { claims.ClientID, claims.Scope = "", "" }},
+			{"other audience", func(claims *oidc.AccessTokenClaims) { claims.Audience = domain.NewID().String() }},
+			{"foreign issuer", func(claims *oidc.AccessTokenClaims) { claims.Issuer = "https://other.example.com" }},
+			{"expired", func(claims *oidc.AccessTokenClaims) { claims.ExpiresAt = now.Unix() }},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				oidcSrv, m := newOIDCService(now)
+
+				claims := accessToken()
+				tt.modify(&claims)
+				verified(m, claims)
+
+				_, err := oidcSrv.UserInfo(context.Background(), "access-token")
+				assert.ErrorIs(t, err, auth.ErrInvalidToken)
+				m.userRepo.AssertNotCalled(t, "GetByUUID", mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("deleted client", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		verified(m, accessToken())
+		m.clientRepo.On("Get", mock.Anything, client.ID).Return(nil, oidc.ErrClientNotFound)
+
+		_, err := oidcSrv.UserInfo(context.Background(), "access-token")
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("deleted user", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		verified(m, accessToken())
+		m.clientRepo.On("Get", mock.Anything, client.ID).Return(client, nil)
+		m.userRepo.On("GetByUUID", mock.Anything, u.ID).Return(nil, user.ErrNotFound)
+
+		_, err := oidcSrv.UserInfo(context.Background(), "access-token")
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+}
+
+func TestRegisterClient(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	now := time.Now()
+
+	t.Run("confidential client", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		m.clientRepo.On("Create", mock.Anything, mock.Anything, now).Return(nil)
+
+		client, secret, err := oidcSrv.RegisterClient(adminCtx(), "app", []string{testRedirectURI}, false)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, secret)
+		assert.True(t, client.VerifySecret(secret))
+		m.clientRepo.AssertExpectations(t)
+	})
+
+	t.Run("public client", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		m.clientRepo.On("Create", mock.Anything, mock.Anything, now).Return(nil)
+
+		client, secret, err := oidcSrv.RegisterClient(adminCtx(), "spa", []string{testRedirectURI}, true)
+		assert.NoError(t, err)
+		assert.Empty(t, secret)
+		assert.True(t, client.Public())
+	})
+
+	t.Run("invalid redirect URI", func(t *testing.T) {
+		oidcSrv, _ := newOIDCService(now)
+
+		_, _, err := oidcSrv.RegisterClient(adminCtx(), "app", []string{"/callback"}, false)
+		assert.ErrorIs(t, err, oidc.ErrInvalidRedirectURI)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		_, _, err := oidcSrv.RegisterClient(userCtx(domain.NewID(), user.RoleSupport), "app", []string{testRedirectURI}, false)
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		m.clientRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestDeleteClient(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	now := time.Now()
+	clientID := domain.NewID()
+
+	t.Run("delete", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		m.clientRepo.On("Delete", mock.Anything, clientID).Return(nil)
+
+		assert.NoError(t, oidcSrv.DeleteClient(adminCtx(), clientID.String()))
+		m.clientRepo.AssertExpectations(t)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		m.clientRepo.On("Delete", mock.Anything, clientID).Return(oidc.ErrClientNotFound)
+
+		err := oidcSrv.DeleteClient(adminCtx(), clientID.String())
+		assert.ErrorIs(t, err, oidc.ErrClientNotFound)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		m.clientRepo.On("Delete", mock.Anything, clientID).Return(errors.New("db error"))
+
+		err := oidcSrv.DeleteClient(adminCtx(), clientID.String())
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, oidc.ErrClientNotFound)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		oidcSrv, m := newOIDCService(now)
+
+		err := oidcSrv.DeleteClient(userCtx(domain.NewID(), user.RoleSupport), clientID.String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		m.clientRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mail"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type PasswordPort interface {
+	RequestReset(ctx context.Context, email string) error
+	ConfirmReset(ctx context.Context, secret, password string) error
+	Change(ctx context.Context, userID, currentPassword, newPassword, clientIP string) error
+}
+
+type passwordService struct {
+	userRepo     user.Repository
+	tokenRepo    verification.Repository
+	sessionRepo  session.Repository
+	uow          domain.UnitOfWork
+	tokens       *tokenMailer
+	limiter      domain.RateLimiter
+	policy       *user.PasswordPolicy
+	hasher       auth.PasswordHasher
+	guard        *lockoutGuard
+	timeProvider domain.TimeProvider
+	ttl          time.Duration
+	resetDelay   time.Duration
+	*auditor
+}
+
+var _ PasswordPort = (*passwordService)(nil)
+
+func NewPasswordService(
+	userRepo user.Repository,
+	tokenRepo verification.Repository,
+	sessionRepo session.Repository,
+	uow domain.UnitOfWork,
+	auditRepo audit.Repository,
+	mailer mail.Mailer,
+	limiter domain.RateLimiter,
+	policy *user.PasswordPolicy,
+	hasher auth.PasswordHasher,
+	lockoutRepo lockout.Repository,
+	lockoutPolicy *lockout.Policy,
+	timeProvider domain.TimeProvider,
+	ttl time.Duration,
+	resetDelay time.Duration,
+) *passwordService {
+	return &passwordService{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		sessionRepo: sessionRepo,
+		uow:         uow,
+		tokens: &tokenMailer{
+			tokenRepo:    tokenRepo,
+			uow:          uow,
+			mailer:       mailer,
+			timeProvider: timeProvider,
+		},
+		limiter:      limiter,
+		policy:       policy,
+		hasher:       hasher,
+		timeProvider: timeProvider,
+		ttl:          ttl,
+		resetDelay:   resetDelay,
+		guard: &lockoutGuard{
+			lockoutRepo: lockoutRepo,
+			policy:      lockoutPolicy,
+		},
+		auditor: &auditor{
+			auditRepo:    auditRepo,
+			timeProvider: timeProvider,
+		},
+	}
+}
+
+// RequestReset mails a reset token to the user with the email. The outcome and the time it takes are the same whether the email
+// exists or not, so it can not be used to find registered emails. Attempts are limited per email, regardless of whether it exists.
+func (s *passwordService) RequestReset(ctx context.Context, email string) error {
+	if !s.limiter.Allow(strings.ToLower(email)) {
+		return auth.ErrTooManyAttempts
+	}
+
+	// every request is answered after resetDelay at the earliest, which covers storing and mailing the token,
+	// so an existing email takes as long to answer as an unknown one
+	defer wait(ctx, time.NewTimer(s.resetDelay))
+
+	credentials, err := s.userRepo.GetCredentialsByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return nil
+		}
+
+		err = fmt.Errorf("failed fetching credentials: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	err = s.tokens.send(ctx, credentials.ID, verification.PurposePasswordReset, credentials.Email, s.ttl,
+		"Reset your password",
+		"Reset your password with the token below, ignore this message if you have not requested it",
+	)
+	if err != nil {
+		// a failure for an existing email only would reveal it
+		logger.Error(fmt.Errorf("failed sending password reset: %w", err))
+	}
+
+	return nil
+}
+
+// wait blocks until the timer fires, unless the request is cancelled first
+func wait(ctx context.Context, timer *time.Timer) {
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// ConfirmReset consumes the token and replaces the password of the user it was issued to, all of their sessions are revoked.
+// A password rejected by the policy leaves the token unused.
+func (s *passwordService) ConfirmReset(ctx context.Context, secret, password string) error {
+	now := s.timeProvider.UtcNow()
+
+	err := s.uow.WithinTx(ctx, func(ctx context.Context) error {
+		token, err := s.tokenRepo.Consume(ctx, verification.Hash(secret), verification.PurposePasswordReset, now)
+		if err != nil {
+			return err
+		}
+
+		if err = s.policy.Validate(password, token.Email); err != nil {
+			return err
+		}
+
+		passwordHash, err := s.hasher.Hash(password)
+		if err != nil {
+			return err
+		}
+
+		if err = s.userRepo.IncrementVersion(ctx, token.UserID, nil); err != nil {
+			return err
+		}
+
+		changes := []user.Change{user.Set(user.FieldPassword, passwordHash)}
+		if err = s.userRepo.UpdateBasicFields(ctx, token.UserID, changes); err != nil {
+			return err
+		}
+
+		// whoever has logged in with the old password is logged out
+		if err = s.sessionRepo.RevokeUser(ctx, token.UserID, now); err != nil {
+			return err
+		}
+
+		return s.record(ctx, audit.ActionPasswordReset, token.UserID, audit.FieldChanges(changes))
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, verification.ErrInvalidToken), errors.Is(err, user.ErrWeakPassword):
+			return err
+		case errors.Is(err, user.ErrNotFound):
+			// the user has been deleted in the meantime
+			return verification.ErrInvalidToken
+		}
+
+		err = fmt.Errorf("failed resetting password: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	return nil
+}
+
+// Change replaces the password of the user, who has to prove knowing the current one. A wrong one counts as a failed login
+// of the user and the client IP, so it can not be used to guess the password past the lockout.
+// Pending password resets are revoked, so a reset mailed before can not undo the change, and so are all sessions of the user.
+func (s *passwordService) Change(ctx context.Context, userID, currentPassword, newPassword, clientIP string) error {
+	id, err := domain.ParseID(userID)
+	if err != nil {
+		return fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionUpdate, id); err != nil {
+		return err
+	}
+
+	now := s.timeProvider.UtcNow()
+	account, ip := lockout.User(id), lockout.IP(clientIP)
+
+	if _, err = s.guard.check(ctx, ip, now); err != nil {
+		return err
+	}
+
+	state, err := s.guard.check(ctx, account, now)
+	if err != nil {
+		return err
+	}
+
+	credentials, err := s.userRepo.GetCredentialsByUUID(ctx, id)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return user.ErrNotFound
+		}
+
+		err = fmt.Errorf("failed fetching credentials: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	match, _, err := s.hasher.Verify(credentials.PasswordHash, currentPassword)
+	if err != nil {
+		err = fmt.Errorf("failed verifying password: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	if !match {
+		s.guard.recordFailure(ctx, now, account, ip)
+
+		return auth.ErrInvalidCredentials
+	}
+
+	s.guard.reset(ctx, id, state)
+
+	if err = s.policy.Validate(newPassword, credentials.Email); err != nil {
+		return err
+	}
+
+	passwordHash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		err = fmt.Errorf("failed hashing password: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	err = s.uow.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.IncrementVersion(ctx, id, nil); err != nil {
+			return err
+		}
+
+		changes := []user.Change{user.Set(user.FieldPassword, passwordHash)}
+		if err := s.userRepo.UpdateBasicFields(ctx, id, changes); err != nil {
+			return err
+		}
+
+		if err := s.record(ctx, audit.ActionPasswordChanged, id, audit.FieldChanges(changes)); err != nil {
+			return err
+		}
+
+		if err := s.sessionRepo.RevokeUser(ctx, id, now); err != nil {
+			return err
+		}
+
+		return s.tokenRepo.Revoke(ctx, id, verification.PurposePasswordReset)
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return user.ErrNotFound
+		}
+
+		err = fmt.Errorf("failed changing password: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	return nil
+}
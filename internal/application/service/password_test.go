@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mail"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	domainMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain"
+)
+
+func newPasswordService(now time.Time) (*passwordService, *serviceMocks) {
+	m := newServiceMocks(now)
+
+	return NewPasswordService(m.userRepo, m.tokenRepo, m.sessionRepo, new(domainMock.UnitOfWorkMock), m.auditRepo, m.mailer, m.limiter, testPasswordPolicy, m.hasher, m.lockoutRepo, testLockoutPolicy, m.timeProvider, time.Hour, 0), m
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	t.Run("token is mailed to the user", func(t *testing.T) {
+		now := time.Now()
+		passwordSrv, m := newPasswordService(now)
+
+		id := domain.NewID()
+
+		m.limiter.On("Allow", "john@example.com").Return(true)
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "John@example.com").Return(&user.Credentials{ID: id, Email: "john@example.com"}, nil)
+		m.tokenRepo.On("Revoke", mock.Anything, id, verification.PurposePasswordReset).Return(nil)
+		m.tokenRepo.On("Create", mock.Anything, mock.MatchedBy(func(token *verification.Token) bool {
+			return token.UserID == id && token.Purpose == verification.PurposePasswordReset && token.ExpiresAt.Equal(now.Add(time.Hour))
+		}), now).Return(nil)
+		m.mailer.On("Send", mock.Anything, mock.MatchedBy(func(msg *mail.Message) bool {
+			return msg.To == "john@example.com"
+		})).Return(nil)
+
+		err := passwordSrv.RequestReset(context.Background(), "John@example.com")
+		assert.NoError(t, err)
+		m.tokenRepo.AssertExpectations(t)
+		m.mailer.AssertExpectations(t)
+	})
+
+	t.Run("unknown email looks the same", func(t *testing.T) {
+		passwordSrv, m := newPasswordService(time.Now())
+
+		m.limiter.On("Allow", "nobody@example.com").Return(true)
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "nobody@example.com").Return(nil, user.ErrNotFound)
+
+		err := passwordSrv.RequestReset(context.Background(), "nobody@example.com")
+		assert.NoError(t, err)
+		m.mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("failed mail looks the same", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		passwordSrv, m := newPasswordService(time.Now())
+
+		m.limiter.On("Allow", mock.Anything).Return(true)
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, mock.Anything).Return(&user.Credentials{ID: domain.NewID()}, nil)
+		m.tokenRepo.On("Revoke", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		m.tokenRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		m.mailer.On("Send", mock.Anything, mock.Anything).Return(assert.AnError)
+
+		err := passwordSrv.RequestReset(context.Background(), "john@example.com")
+		assert.NoError(t, err)
+	})
+
+	t.Run("answered after the delay whether the email exists or not", func(t *testing.T) {
+		passwordSrv, m := newPasswordService(time.Now())
+		passwordSrv.resetDelay = 50 * time.Millisecond
+
+		m.limiter.On("Allow", mock.Anything).Return(true)
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "john@example.com").Return(&user.Credentials{ID: domain.NewID()}, nil)
+		m.userRepo.On("GetCredentialsByEmail", mock.Anything, "nobody@example.com").Return(nil, user.ErrNotFound)
+		m.tokenRepo.On("Revoke", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		m.tokenRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		m.mailer.On("Send", mock.Anything, mock.Anything).Return(nil)
+
+		for _, email := range []string{"john@example.com", "nobody@example.com"} {
+			started := time.Now()
+
+			err := passwordSrv.RequestReset(context.Background(), email)
+			assert.NoError(t, err)
+			assert.GreaterOrEqual(t, time.Since(started), passwordSrv.resetDelay)
+		}
+
+		m.mailer.AssertNumberOfCalls(t, "Send", 1)
+	})
+
+	t.Run("too many attempts", func(t *testing.T) {
+		passwordSrv, m := newPasswordService(time.Now())
+
+		m.limiter.On("Allow", "john@example.com").Return(false)
+
+		err := passwordSrv.RequestReset(context.Background(), "john@example.com")
+		assert.ErrorIs(t, err, auth.ErrTooManyAttempts)
+		m.userRepo.AssertNotCalled(t, "GetCredentialsByEmail", mock.Anything, mock.Anything)
+	})
+}
+
+func TestConfirmPasswordReset(t *testing.T) {
+	t.Run("password is replaced", func(t *testing.T) {
+		now := time.Now()
+		passwordSrv, m := newPasswordService(now)
+
+		id := domain.NewID()
+
+		m.tokenRepo.On("Consume", mock.Anything, verification.Hash("secret"), verification.PurposePasswordReset, now).Return(&verification.Token{UserID: id}, nil)
+		m.hasher.On("Hash", "new-secure123").Return("new-hash", nil)
+		m.userRepo.On("IncrementVersion", mock.Anything, id, (*int64)(nil)).Return(nil)
+		m.userRepo.On("UpdateBasicFields", mock.Anything, id, []user.Change{user.Set(user.FieldPassword, "new-hash")}).Return(nil)
+		m.sessionRepo.On("RevokeUser", mock.Anything, id, now).Return(nil)
+
+		err := passwordSrv.ConfirmReset(context.Background(), "secret", "new-secure123")
+		assert.NoError(t, err)
+		m.userRepo.AssertExpectations(t)
+		m.sessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("weak password", func(t *testing.T) {
+		passwordSrv, m := newPasswordService(time.Now())
+
+		m.tokenRepo.On("Consume", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&verification.Token{
+			UserID: domain.NewID(),
+			Email:  "john@example.com",
+		}, nil)
+
+		err := passwordSrv.ConfirmReset(context.Background(), "secret", "JOHN@example.com")
+		assert.ErrorIs(t, err, user.ErrWeakPassword)
+		m.userRepo.AssertNotCalled(t, "UpdateBasicFields", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		passwordSrv, m := newPasswordService(time.Now())
+
+		m.tokenRepo.On("Consume", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, verification.ErrInvalidToken)
+
+		err := passwordSrv.ConfirmReset(context.Background(), "secret", "new-secure123")
+		assert.ErrorIs(t, err, verification.ErrInvalidToken)
+		m.userRepo.AssertNotCalled(t, "UpdateBasicFields", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("deleted user", func(t *testing.T) {
+		passwordSrv, m := newPasswordService(time.Now())
+
+		m.tokenRepo.On("Consume", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&verification.Token{UserID: domain.NewID()}, nil)
+		m.hasher.On("Hash", mock.Anything).Return("new-hash", nil)
+		m.userRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(user.ErrNotFound)
+
+		err := passwordSrv.ConfirmReset(context.Background(), "secret", "new-secure123")
+		assert.ErrorIs(t, err, verification.ErrInvalidToken)
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	t.Run("password is changed", func(t *testing.T) {
+		now := time.Now()
+		passwordSrv, m := newPasswordService(now)
+		m.unlocked()
+
+		id := domain.NewID()
+
+		m.userRepo.On("GetCredentialsByUUID", mock.Anything, id).Return(&user.Credentials{ID: id, Email: "john@example.com", PasswordHash: "hash"}, nil)
+		m.hasher.On("Verify", "hash", "secure123").Return(true, false, nil)
+		m.hasher.On("Hash", "new-secure123").Return("new-hash", nil)
+		m.userRepo.On("IncrementVersion", mock.Anything, id, (*int64)(nil)).Return(nil)
+		m.userRepo.On("UpdateBasicFields", mock.Anything, id, []user.Change{user.Set(user.FieldPassword, "new-hash")}).Return(nil)
+		m.tokenRepo.On("Revoke", mock.Anything, id, verification.PurposePasswordReset).Return(nil)
+		m.sessionRepo.On("RevokeUser", mock.Anything, id, now).Return(nil)
+
+		err := passwordSrv.Change(userCtx(id, user.RoleSelf), id.String(), "secure123", "new-secure123", testClientIP)
+		assert.NoError(t, err)
+		m.userRepo.AssertExpectations(t)
+		m.tokenRepo.AssertExpectations(t)
+		m.sessionRepo.AssertExpectations(t)
+		m.auditRepo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(e *audit.Entry) bool {
+			return e.Action == audit.ActionPasswordChanged && e.Actor == audit.Actor{Type: audit.ActorUser, ID: id.String()} &&
+				len(e.Changes) == 1 && *e.Changes[0].Value == audit.Redacted
+		}))
+	})
+
+	t.Run("wrong current password counts as a failed login", func(t *testing.T) {
+		now := time.Now()
+		passwordSrv, m := newPasswordService(now)
+		m.unlocked()
+
+		id := domain.NewID()
+
+		m.userRepo.On("GetCredentialsByUUID", mock.Anything, id).Return(&user.Credentials{ID: id, PasswordHash: "hash"}, nil)
+		m.hasher.On("Verify", "hash", "not-the-current").Return(false, false, nil)
+		m.lockoutRepo.On("RecordFailure", mock.Anything, lockout.User(id), now, testLockoutPolicy.Window).Return(&lockout.State{Failures: 1}, nil)
+		m.lockoutRepo.On("RecordFailure", mock.Anything, lockout.IP(testClientIP), now, testLockoutPolicy.Window).Return(&lockout.State{Failures: 1}, nil)
+
+		err := passwordSrv.Change(userCtx(id, user.RoleSelf), id.String(), "not-the-current", "new-secure123", testClientIP)
+		assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+		m.lockoutRepo.AssertExpectations(t)
+		m.userRepo.AssertNotCalled(t, "UpdateBasicFields", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("user locked out on reaching the threshold", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		now := time.Now()
+		passwordSrv, m := newPasswordService(now)
+		m.unlocked()
+
+		id := domain.NewID()
+
+		m.userRepo.On("GetCredentialsByUUID", mock.Anything, id).Return(&user.Credentials{ID: id, PasswordHash: "hash"}, nil)
+		m.hasher.On("Verify", "hash", "not-the-current").Return(false, false, nil)
+		m.lockoutRepo.On("RecordFailure", mock.Anything, lockout.User(id), now, testLockoutPolicy.Window).Return(&lockout.State{Failures: testLockoutPolicy.UserThreshold}, nil)
+		m.lockoutRepo.On("RecordFailure", mock.Anything, lockout.IP(testClientIP), now, testLockoutPolicy.Window).Return(&lockout.State{Failures: 1}, nil)
+		m.lockoutRepo.On("Lock", mock.Anything, lockout.User(id), now.Add(testLockoutPolicy.BaseDelay)).Return(nil)
+
+		err := passwordSrv.Change(userCtx(id, user.RoleSelf), id.String(), "not-the-current", "new-secure123", testClientIP)
+		assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+		m.lockoutRepo.AssertExpectations(t)
+	})
+
+	t.Run("locked out user", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		now := time.Now()
+		passwordSrv, m := newPasswordService(now)
+
+		id := domain.NewID()
+		lockedUntil := now.Add(time.Minute)
+
+		m.lockoutRepo.On("Get", mock.Anything, lockout.User(id)).Return(&lockout.State{Failures: 3, LockedUntil: &lockedUntil}, nil)
+		m.unlocked()
+
+		err := passwordSrv.Change(userCtx(id, user.RoleSelf), id.String(), "secure123", "new-secure123", testClientIP)
+		assert.ErrorIs(t, err, lockout.ErrLocked)
+		m.hasher.AssertNotCalled(t, "Verify", mock.Anything, mock.Anything)
+	})
+
+	t.Run("locked out client IP", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		now := time.Now()
+		passwordSrv, m := newPasswordService(now)
+
+		id := domain.NewID()
+		lockedUntil := now.Add(time.Minute)
+
+		m.lockoutRepo.On("Get", mock.Anything, lockout.IP(testClientIP)).Return(&lockout.State{Failures: 20, LockedUntil: &lockedUntil}, nil)
+
+		err := passwordSrv.Change(userCtx(id, user.RoleSelf), id.String(), "secure123", "new-secure123", testClientIP)
+		assert.ErrorIs(t, err, lockout.ErrLocked)
+		m.userRepo.AssertNotCalled(t, "GetCredentialsByUUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("failures are reset once the current password is verified", func(t *testing.T) {
+		passwordSrv, m := newPasswordService(time.Now())
+
+		id := domain.NewID()
+
+		m.lockoutRepo.On("Get", mock.Anything, lockout.User(id)).Return(&lockout.State{Failures: 2}, nil)
+		m.unlocked()
+		m.lockoutRepo.On("Reset", mock.Anything, lockout.User(id)).Return(nil)
+		m.userRepo.On("GetCredentialsByUUID", mock.Anything, id).Return(&user.Credentials{ID: id, Email: "john@example.com", PasswordHash: "hash"}, nil)
+		m.hasher.On("Verify", "hash", "secure123").Return(true, false, nil)
+
+		// the new password is rejected, the current one was right nevertheless
+		err := passwordSrv.Change(userCtx(id, user.RoleSelf), id.String(), "secure123", "short", testClientIP)
+		assert.ErrorIs(t, err, user.ErrWeakPassword)
+		m.lockoutRepo.AssertExpectations(t)
+	})
+
+	t.Run("weak password", func(t *testing.T) {
+		policy := user.NewPasswordPolicy(10, []user.CharClass{user.ClassUpper, user.ClassDigit}, []string{"Company2024!"})
+
+		for _, tc := range []struct {
+			name     string
+			password string
+			reason   string
+		}{
+			{"too short", "Short1", "at least 10 characters"},
+			{"too long", "A1" + strings.Repeat("a", 71), "at most 72 bytes"},
+			{"missing class", "lowercase-only-1", "at least one upper character"},
+			{"email", "John1@Example.com", "must not be the email"},
+			{"common", "Password123", "too common"},
+			{"configured deny list", "COMPANY2024!", "too common"},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				passwordSrv, m := newPasswordService(time.Now())
+				passwordSrv.policy = policy
+				m.unlocked()
+
+				id := domain.NewID()
+
+				m.userRepo.On("GetCredentialsByUUID", mock.Anything, id).Return(&user.Credentials{ID: id, Email: "john1@example.com", PasswordHash: "hash"}, nil)
+				m.hasher.On("Verify", "hash", "secure123").Return(true, false, nil)
+
+				err := passwordSrv.Change(userCtx(id, user.RoleSelf), id.String(), "secure123", tc.password, testClientIP)
+				assert.ErrorIs(t, err, user.ErrWeakPassword)
+				assert.Contains(t, err.Error(), tc.reason)
+				m.userRepo.AssertNotCalled(t, "IncrementVersion", mock.Anything, mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("user can not change password of other users", func(t *testing.T) {
+		passwordSrv, m := newPasswordService(time.Now())
+
+		err := passwordSrv.Change(userCtx(domain.NewID(), user.RoleSelf), domain.NewID().String(), "secure123", "new-secure123", testClientIP)
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		m.userRepo.AssertNotCalled(t, "GetCredentialsByUUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		passwordSrv, m := newPasswordService(time.Now())
+		m.unlocked()
+
+		m.userRepo.On("GetCredentialsByUUID", mock.Anything, mock.Anything).Return(nil, user.ErrNotFound)
+
+		err := passwordSrv.Change(adminCtx(), domain.NewID().String(), "secure123", "new-secure123", testClientIP)
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+}
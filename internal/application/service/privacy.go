@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+// exportPageSize is the number of audit entries read at a time while exporting
+const exportPageSize = 100
+
+// PrivacyPort serves data subject requests: exporting everything stored about a user and erasing their personal data
+type PrivacyPort interface {
+	Export(ctx context.Context, userID string) (*UserExport, error)
+
+	// Erase anonymizes the user irreversibly, unlike deleting. The audit trail stays, stripped of the personal data.
+	Erase(ctx context.Context, userID string) error
+}
+
+// UserExport is everything stored about a user, passwords and token hashes left out
+type UserExport struct {
+	ExportedAt   time.Time          `json:"exported_at"`
+	User         *user.User         `json:"user"`
+	AuditEntries []*audit.Entry     `json:"audit_entries"`
+	Sessions     []*session.Session `json:"sessions"`
+}
+
+type privacyService struct {
+	userRepo     user.Repository
+	sessionRepo  session.Repository
+	deliveryRepo webhook.DeliveryRepository
+	uow          domain.UnitOfWork
+	timeProvider domain.TimeProvider
+	*auditor
+	*eventRaiser
+}
+
+var _ PrivacyPort = (*privacyService)(nil)
+
+func NewPrivacyService(
+	userRepo user.Repository,
+	sessionRepo session.Repository,
+	auditRepo audit.Repository,
+	outbox event.Outbox,
+	deliveryRepo webhook.DeliveryRepository,
+	uow domain.UnitOfWork,
+	timeProvider domain.TimeProvider,
+) *privacyService {
+	return &privacyService{
+		userRepo:     userRepo,
+		sessionRepo:  sessionRepo,
+		deliveryRepo: deliveryRepo,
+		uow:          uow,
+		timeProvider: timeProvider,
+		auditor: &auditor{
+			auditRepo:    auditRepo,
+			timeProvider: timeProvider,
+		},
+		eventRaiser: &eventRaiser{
+			outbox:       outbox,
+			timeProvider: timeProvider,
+		},
+	}
+}
+
+func (s *privacyService) Export(ctx context.Context, userID string) (*UserExport, error) {
+	id, err := domain.ParseID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionExport, id); err != nil {
+		return nil, err
+	}
+
+	export, err := s.export(ctx, id)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return nil, err
+		}
+
+		err = fmt.Errorf("failed exporting user: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (s *privacyService) export(ctx context.Context, id domain.ID) (*UserExport, error) {
+	// deleted users keep their data until the purge, so it is exported as well
+	u, err := s.userRepo.GetByUUIDIncludingDeleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &UserExport{
+		ExportedAt:   s.timeProvider.UtcNow(),
+		User:         u,
+		AuditEntries: []*audit.Entry{},
+		Sessions:     []*session.Session{},
+	}
+
+	q := &audit.Query{Filter: audit.Filter{UserID: id}, Limit: exportPageSize}
+
+	for {
+		page, err := s.auditRepo.List(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("failed listing audit entries: %w", err)
+		}
+
+		export.AuditEntries = append(export.AuditEntries, page.Entries...)
+
+		if page.NextCursor == nil {
+			break
+		}
+
+		q.Cursor = page.NextCursor
+	}
+
+	sessions, err := s.sessionRepo.ListByUser(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed listing sessions: %w", err)
+	}
+
+	export.Sessions = append(export.Sessions, sessions...)
+
+	return export, nil
+}
+
+// Erase erases the copies of the personal data kept in the audit log, the outbox and the webhook deliveries
+// in the same transaction as the user, and records the erasure itself afterwards, so its entry is kept intact
+func (s *privacyService) Erase(ctx context.Context, userID string) error {
+	id, err := domain.ParseID(userID)
+	if err != nil {
+		return fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionErase, id); err != nil {
+		return err
+	}
+
+	err = s.uow.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Erase(ctx, id, s.timeProvider.UtcNow()); err != nil {
+			return err
+		}
+
+		if err := s.auditRepo.Erase(ctx, id); err != nil {
+			return fmt.Errorf("failed erasing audit entries: %w", err)
+		}
+
+		if err := s.outbox.Erase(ctx, id); err != nil {
+			return fmt.Errorf("failed erasing events: %w", err)
+		}
+
+		if err := s.deliveryRepo.Erase(ctx, id); err != nil {
+			return fmt.Errorf("failed erasing webhook deliveries: %w", err)
+		}
+
+		if err := s.record(ctx, audit.ActionUserErased, id, nil); err != nil {
+			return err
+		}
+
+		return s.raise(ctx, id, event.UserErased{})
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) || errors.Is(err, user.ErrErased) {
+			return err
+		}
+
+		err = fmt.Errorf("failed erasing user: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("user %s erased by %s", id, actor(ctx)))
+
+	return nil
+}
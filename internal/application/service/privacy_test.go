@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	domainMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain"
+	repoMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/infrastructure/database/mysql"
+)
+
+func TestExport(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	t.Run("export own data", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockSessions := new(repoMock.SessionRepositoryMock)
+		mockAudit := new(repoMock.AuditRepositoryMock)
+		privacySrv := NewPrivacyService(mockRepo, mockSessions, mockAudit, stubOutbox(), nil, new(domainMock.UnitOfWorkMock), stubClock())
+
+		id := domain.NewID()
+		first, second := &audit.Entry{ID: 2, UserID: id}, &audit.Entry{ID: 1, UserID: id}
+		sessions := []*session.Session{{FamilyID: domain.NewID(), CreatedAt: time.Now()}}
+
+		mockRepo.On("GetByUUIDIncludingDeleted", mock.Anything, id).Return(&user.User{ID: id, Email: "test@example.com"}, nil)
+		mockAudit.On("List", mock.Anything, mock.MatchedBy(func(q *audit.Query) bool {
+			return q.Filter.UserID == id && q.Cursor == nil
+		})).Return(&audit.Page{Entries: []*audit.Entry{first}, NextCursor: &audit.Cursor{ID: 2}}, nil).Once()
+		mockAudit.On("List", mock.Anything, mock.MatchedBy(func(q *audit.Query) bool {
+			return q.Filter.UserID == id && q.Cursor != nil
+		})).Return(&audit.Page{Entries: []*audit.Entry{second}}, nil).Once()
+		mockSessions.On("ListByUser", mock.Anything, id).Return(sessions, nil)
+
+		export, err := privacySrv.Export(userCtx(id, user.RoleSelf), id.String())
+		assert.NoError(t, err)
+		assert.Equal(t, "test@example.com", export.User.Email)
+		assert.Equal(t, []*audit.Entry{first, second}, export.AuditEntries)
+		assert.Equal(t, sessions, export.Sessions)
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("export deleted user", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockSessions := new(repoMock.SessionRepositoryMock)
+		mockAudit := new(repoMock.AuditRepositoryMock)
+		privacySrv := NewPrivacyService(mockRepo, mockSessions, mockAudit, stubOutbox(), nil, new(domainMock.UnitOfWorkMock), stubClock())
+
+		id := domain.NewID()
+		deletedAt := time.Now()
+
+		mockRepo.On("GetByUUIDIncludingDeleted", mock.Anything, id).Return(&user.User{ID: id, Email: "test@example.com", DeletedAt: &deletedAt}, nil)
+		mockAudit.On("List", mock.Anything, mock.Anything).Return(&audit.Page{}, nil)
+		mockSessions.On("ListByUser", mock.Anything, id).Return(nil, nil)
+
+		export, err := privacySrv.Export(adminCtx(), id.String())
+		assert.NoError(t, err)
+		assert.Equal(t, "test@example.com", export.User.Email)
+		assert.Equal(t, &deletedAt, export.User.DeletedAt)
+		mockRepo.AssertNotCalled(t, "GetByUUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		privacySrv := NewPrivacyService(mockRepo, nil, nil, nil, nil, new(domainMock.UnitOfWorkMock), stubClock())
+
+		mockRepo.On("GetByUUIDIncludingDeleted", mock.Anything, mock.Anything).Return(nil, user.ErrNotFound)
+
+		_, err := privacySrv.Export(adminCtx(), domain.NewID().String())
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+
+	t.Run("only admins export other users", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		privacySrv := NewPrivacyService(mockRepo, nil, nil, nil, nil, new(domainMock.UnitOfWorkMock), stubClock())
+
+		id := domain.NewID()
+
+		_, err := privacySrv.Export(userCtx(domain.NewID(), user.RoleSelf), id.String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+
+		_, err = privacySrv.Export(userCtx(domain.NewID(), user.RoleSupport), id.String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+
+		mockRepo.AssertNotCalled(t, "GetByUUIDIncludingDeleted", mock.Anything, mock.Anything)
+	})
+}
+
+func TestErase(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	t.Run("erase user", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockAudit := new(repoMock.AuditRepositoryMock)
+		mockOutbox := new(repoMock.OutboxRepositoryMock)
+		mockDeliveries := new(repoMock.DeliveryRepositoryMock)
+		privacySrv := NewPrivacyService(mockRepo, nil, mockAudit, mockOutbox, mockDeliveries, new(domainMock.UnitOfWorkMock), stubClock())
+
+		id := domain.NewID()
+
+		mockRepo.On("Erase", mock.Anything, id, mock.Anything).Return(nil)
+		mockAudit.On("Erase", mock.Anything, id).Return(nil)
+		mockAudit.On("Create", mock.Anything, mock.MatchedBy(func(e *audit.Entry) bool {
+			return e.Action == audit.ActionUserErased && e.UserID == id
+		})).Return(nil)
+		mockOutbox.On("Erase", mock.Anything, id).Return(nil)
+		mockOutbox.On("Add", mock.Anything, mock.MatchedBy(func(e *event.Event) bool {
+			return e.Type == event.TypeUserErased && e.UserID == id
+		})).Return(nil)
+		mockDeliveries.On("Erase", mock.Anything, id).Return(nil)
+
+		err := privacySrv.Erase(adminCtx(), id.String())
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockAudit.AssertExpectations(t)
+		mockOutbox.AssertExpectations(t)
+		mockDeliveries.AssertExpectations(t)
+	})
+
+	t.Run("user erased already", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockAudit := new(repoMock.AuditRepositoryMock)
+		privacySrv := NewPrivacyService(mockRepo, nil, mockAudit, stubOutbox(), nil, new(domainMock.UnitOfWorkMock), stubClock())
+
+		mockRepo.On("Erase", mock.Anything, mock.Anything, mock.Anything).Return(user.ErrErased)
+
+		err := privacySrv.Erase(adminCtx(), domain.NewID().String())
+		assert.ErrorIs(t, err, user.ErrErased)
+		mockAudit.AssertNotCalled(t, "Erase", mock.Anything, mock.Anything)
+	})
+
+	t.Run("failed erasing the audit log fails the erasure", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockAudit := new(repoMock.AuditRepositoryMock)
+		privacySrv := NewPrivacyService(mockRepo, nil, mockAudit, stubOutbox(), nil, new(domainMock.UnitOfWorkMock), stubClock())
+
+		mockRepo.On("Erase", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockAudit.On("Erase", mock.Anything, mock.Anything).Return(errors.New("db error"))
+
+		err := privacySrv.Erase(adminCtx(), domain.NewID().String())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed erasing user")
+	})
+
+	t.Run("only admins erase users", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		privacySrv := NewPrivacyService(mockRepo, nil, nil, nil, nil, new(domainMock.UnitOfWorkMock), stubClock())
+
+		id := domain.NewID()
+
+		err := privacySrv.Erase(userCtx(id, user.RoleSelf), id.String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+
+		err = privacySrv.Erase(userCtx(domain.NewID(), user.RoleSupport), id.String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+
+		mockRepo.AssertNotCalled(t, "Erase", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+// RetentionPort removes deleted users for good once they are past the retention period, restoring them is not possible anymore
+type RetentionPort interface {
+	// PurgeDeleted purges a batch of users, more tells the batch has been full, so more users may be due
+	PurgeDeleted(ctx context.Context) (more bool, err error)
+}
+
+type retentionService struct {
+	userRepo     user.Repository
+	timeProvider domain.TimeProvider
+	retention    time.Duration
+	batchSize    int
+}
+
+var _ RetentionPort = (*retentionService)(nil)
+
+func NewRetentionService(userRepo user.Repository, timeProvider domain.TimeProvider, retention time.Duration, batchSize int) *retentionService {
+	return &retentionService{
+		userRepo:     userRepo,
+		timeProvider: timeProvider,
+		retention:    retention,
+		batchSize:    batchSize,
+	}
+}
+
+func (s *retentionService) PurgeDeleted(ctx context.Context) (bool, error) {
+	deletedBefore := s.timeProvider.UtcNow().Add(-s.retention)
+
+	purged, err := s.userRepo.Purge(ctx, deletedBefore, s.batchSize)
+	if err != nil {
+		err = fmt.Errorf("failed purging deleted users: %w", err)
+		logger.Debug(err)
+
+		return false, err
+	}
+
+	if purged > 0 {
+		logger.Info(fmt.Sprintf("purged %d users deleted before %s", purged, deletedBefore.Format(time.RFC3339)))
+	}
+
+	return purged == s.batchSize, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	domainMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain"
+	repoMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/infrastructure/database/mysql"
+)
+
+func TestPurgeDeleted(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+	deletedBefore := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	clock := new(domainMock.TimeProviderMock)
+	clock.On("UtcNow").Return(now)
+
+	tests := []struct {
+		name         string
+		purged       int
+		repoErr      error
+		expectedMore bool
+	}{
+		{name: "full batch", purged: 10, expectedMore: true},
+		{name: "last batch", purged: 3},
+		{name: "nothing to purge"},
+		{name: "repository error", repoErr: errors.New("db error")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repoMock.UserRepositoryMock)
+			mockRepo.On("Purge", context.Background(), deletedBefore, 10).Return(tt.purged, tt.repoErr)
+
+			more, err := NewRetentionService(mockRepo, clock, 30*24*time.Hour, 10).PurgeDeleted(context.Background())
+			if tt.repoErr != nil {
+				assert.ErrorIs(t, err, tt.repoErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedMore, more)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type SessionPort interface {
+	Refresh(ctx context.Context, refreshToken string) (*auth.Token, error)
+	Logout(ctx context.Context, refreshToken string) error
+	RevokeAll(ctx context.Context, userID string) error
+}
+
+// sessionIssuer issues access tokens together with refresh tokens
+type sessionIssuer struct {
+	sessionRepo   session.Repository
+	tokenProvider auth.TokenProvider
+	refreshTTL    time.Duration
+}
+
+// issue issues an access token and the next refresh token of the family, a login starts a new family
+func (i *sessionIssuer) issue(ctx context.Context, credentials *user.Credentials, familyID domain.ID, now time.Time) (*auth.Token, error) {
+	token, err := i.tokenProvider.Issue(credentials.ID, credentials.Role)
+	if err != nil {
+		return nil, fmt.Errorf("failed issuing token: %w", err)
+	}
+
+	secret, refreshToken, err := session.New(credentials.ID, familyID, now.Add(i.refreshTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = i.sessionRepo.Create(ctx, refreshToken, now); err != nil {
+		return nil, fmt.Errorf("failed storing refresh token: %w", err)
+	}
+
+	token.RefreshToken = secret
+
+	return token, nil
+}
+
+type sessionService struct {
+	userRepo     user.Repository
+	sessionRepo  session.Repository
+	uow          domain.UnitOfWork
+	sessions     *sessionIssuer
+	timeProvider domain.TimeProvider
+}
+
+var _ SessionPort = (*sessionService)(nil)
+
+func NewSessionService(
+	userRepo user.Repository,
+	sessionRepo session.Repository,
+	uow domain.UnitOfWork,
+	tokenProvider auth.TokenProvider,
+	timeProvider domain.TimeProvider,
+	refreshTTL time.Duration,
+) *sessionService {
+	return &sessionService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		uow:         uow,
+		sessions: &sessionIssuer{
+			sessionRepo:   sessionRepo,
+			tokenProvider: tokenProvider,
+			refreshTTL:    refreshTTL,
+		},
+		timeProvider: timeProvider,
+	}
+}
+
+// Refresh exchanges the refresh token for a new access token and the next refresh token, with the current role of the user.
+// A token is exchanged once, presenting a used one again means it has leaked, so the whole family is revoked.
+func (s *sessionService) Refresh(ctx context.Context, refreshToken string) (*auth.Token, error) {
+	now := s.timeProvider.UtcNow()
+
+	t, err := s.sessionRepo.Get(ctx, session.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, session.ErrInvalidToken) {
+			return nil, auth.ErrInvalidToken
+		}
+
+		err = fmt.Errorf("failed fetching refresh token: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	if t.UsedAt != nil {
+		s.revokeReused(ctx, t, now)
+
+		return nil, auth.ErrInvalidToken
+	}
+
+	if !t.Active(now) {
+		return nil, auth.ErrInvalidToken
+	}
+
+	credentials, err := s.userRepo.GetCredentialsByUUID(ctx, t.UserID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return nil, auth.ErrInvalidToken
+		}
+
+		err = fmt.Errorf("failed fetching credentials: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	var token *auth.Token
+
+	// the next token is stored only if this one is still unused
+	err = s.uow.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.sessionRepo.Use(ctx, t.Hash, now); err != nil {
+			return err
+		}
+
+		var err error
+		token, err = s.sessions.issue(ctx, credentials, t.FamilyID, now)
+
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, session.ErrReused):
+			s.revokeReused(ctx, t, now)
+
+			return nil, auth.ErrInvalidToken
+		case errors.Is(err, user.ErrNotFound):
+			return nil, auth.ErrInvalidToken
+		}
+
+		err = fmt.Errorf("failed rotating refresh token: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Logout revokes the family of the refresh token, which ends the session started with its login.
+// The access token stays valid until it expires.
+func (s *sessionService) Logout(ctx context.Context, refreshToken string) error {
+	t, err := s.sessionRepo.Get(ctx, session.Hash(refreshToken))
+	if err != nil {
+		// nothing to end
+		if errors.Is(err, session.ErrInvalidToken) {
+			return nil
+		}
+
+		err = fmt.Errorf("failed fetching refresh token: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	if err = s.sessionRepo.RevokeFamily(ctx, t.FamilyID, s.timeProvider.UtcNow()); err != nil {
+		err = fmt.Errorf("failed revoking session: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	return nil
+}
+
+// RevokeAll ends all sessions of the user, their access tokens stay valid until they expire
+func (s *sessionService) RevokeAll(ctx context.Context, userID string) error {
+	id, err := domain.ParseID(userID)
+	if err != nil {
+		return fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionRevokeSessions, id); err != nil {
+		return err
+	}
+
+	u, err := s.userRepo.GetByUUID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err = authorizeRank(ctx, u); err != nil {
+		return err
+	}
+
+	if err = s.sessionRepo.RevokeUser(ctx, id, s.timeProvider.UtcNow()); err != nil {
+		err = fmt.Errorf("failed revoking sessions: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("sessions of user %s revoked by %s", id, actor(ctx)))
+
+	return nil
+}
+
+// revokeReused revokes the family of a reused token, failing to do so does not change the response
+func (s *sessionService) revokeReused(ctx context.Context, t *session.RefreshToken, now time.Time) {
+	if err := s.sessionRepo.RevokeFamily(ctx, t.FamilyID, now); err != nil {
+		logger.Error(fmt.Errorf("failed revoking reused session: %w", err))
+		return
+	}
+
+	logger.Info(fmt.Sprintf("refresh token of user %s reused, its session has been revoked", t.UserID))
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	domainMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain"
+)
+
+func newSessionService(now time.Time) (*sessionService, *serviceMocks) {
+	m := newServiceMocks(now)
+
+	return NewSessionService(m.userRepo, m.sessionRepo, new(domainMock.UnitOfWorkMock), m.tokenProvider, m.timeProvider, testRefreshTTL), m
+}
+
+func TestRefresh(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	now := time.Now()
+
+	t.Run("rotate", func(t *testing.T) {
+		sessionSrv, m := newSessionService(now)
+
+		userID, familyID := domain.NewID(), domain.NewID()
+		current := &session.RefreshToken{Hash: session.Hash("secret"), UserID: userID, FamilyID: familyID, ExpiresAt: now.Add(time.Hour)}
+		expectedToken := &auth.Token{AccessToken: "token", TokenType: "Bearer", ExpiresIn: time.Minute}
+
+		m.sessionRepo.On("Get", mock.Anything, session.Hash("secret")).Return(current, nil)
+		m.userRepo.On("GetCredentialsByUUID", mock.Anything, userID).Return(&user.Credentials{ID: userID, Role: user.RoleSupport}, nil)
+		m.sessionRepo.On("Use", mock.Anything, current.Hash, now).Return(nil)
+		m.tokenProvider.On("Issue", userID, user.RoleSupport).Return(expectedToken, nil)
+		m.sessionRepo.On("Create", mock.Anything, mock.MatchedBy(func(rt *session.RefreshToken) bool {
+			return rt.UserID == userID && rt.FamilyID == familyID && rt.ExpiresAt.Equal(now.Add(testRefreshTTL))
+		}), now).Return(nil)
+
+		token, err := sessionSrv.Refresh(context.Background(), "secret")
+		assert.NoError(t, err)
+		assert.Equal(t, "token", token.AccessToken)
+		assert.NotEmpty(t, token.RefreshToken)
+		assert.NotEqual(t, "secret", token.RefreshToken)
+		m.sessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		sessionSrv, m := newSessionService(now)
+
+		m.sessionRepo.On("Get", mock.Anything, session.Hash("secret")).Return(nil, session.ErrInvalidToken)
+
+		_, err := sessionSrv.Refresh(context.Background(), "secret")
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		sessionSrv, m := newSessionService(now)
+
+		current := &session.RefreshToken{Hash: session.Hash("secret"), UserID: domain.NewID(), FamilyID: domain.NewID(), ExpiresAt: now.Add(-time.Second)}
+
+		m.sessionRepo.On("Get", mock.Anything, current.Hash).Return(current, nil)
+
+		_, err := sessionSrv.Refresh(context.Background(), "secret")
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+		m.sessionRepo.AssertNotCalled(t, "Use", mock.Anything, mock.Anything, mock.Anything)
+		m.sessionRepo.AssertNotCalled(t, "RevokeFamily", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("revoked token", func(t *testing.T) {
+		sessionSrv, m := newSessionService(now)
+
+		revokedAt := now.Add(-time.Minute)
+		current := &session.RefreshToken{Hash: session.Hash("secret"), UserID: domain.NewID(), FamilyID: domain.NewID(), ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt}
+
+		m.sessionRepo.On("Get", mock.Anything, current.Hash).Return(current, nil)
+
+		_, err := sessionSrv.Refresh(context.Background(), "secret")
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+		m.sessionRepo.AssertNotCalled(t, "Use", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("reused token revokes the family", func(t *testing.T) {
+		sessionSrv, m := newSessionService(now)
+
+		usedAt := now.Add(-time.Minute)
+		current := &session.RefreshToken{Hash: session.Hash("secret"), UserID: domain.NewID(), FamilyID: domain.NewID(), ExpiresAt: now.Add(time.Hour), UsedAt: &usedAt}
+
+		m.sessionRepo.On("Get", mock.Anything, current.Hash).Return(current, nil)
+		m.sessionRepo.On("RevokeFamily", mock.Anything, current.FamilyID, now).Return(nil)
+
+		_, err := sessionSrv.Refresh(context.Background(), "secret")
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+		m.sessionRepo.AssertExpectations(t)
+		m.tokenProvider.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+	})
+
+	t.Run("concurrently used token revokes the family", func(t *testing.T) {
+		sessionSrv, m := newSessionService(now)
+
+		userID := domain.NewID()
+		current := &session.RefreshToken{Hash: session.Hash("secret"), UserID: userID, FamilyID: domain.NewID(), ExpiresAt: now.Add(time.Hour)}
+
+		m.sessionRepo.On("Get", mock.Anything, current.Hash).Return(current, nil)
+		m.userRepo.On("GetCredentialsByUUID", mock.Anything, userID).Return(&user.Credentials{ID: userID, Role: user.RoleSelf}, nil)
+		m.sessionRepo.On("Use", mock.Anything, current.Hash, now).Return(session.ErrReused)
+		m.sessionRepo.On("RevokeFamily", mock.Anything, current.FamilyID, now).Return(nil)
+
+		_, err := sessionSrv.Refresh(context.Background(), "secret")
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+		m.sessionRepo.AssertExpectations(t)
+		m.tokenProvider.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+	})
+
+	t.Run("deleted user", func(t *testing.T) {
+		sessionSrv, m := newSessionService(now)
+
+		userID := domain.NewID()
+		current := &session.RefreshToken{Hash: session.Hash("secret"), UserID: userID, FamilyID: domain.NewID(), ExpiresAt: now.Add(time.Hour)}
+
+		m.sessionRepo.On("Get", mock.Anything, current.Hash).Return(current, nil)
+		m.userRepo.On("GetCredentialsByUUID", mock.Anything, userID).Return(nil, user.ErrNotFound)
+
+		_, err := sessionSrv.Refresh(context.Background(), "secret")
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+		m.sessionRepo.AssertNotCalled(t, "Use", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	now := time.Now()
+
+	t.Run("logout", func(t *testing.T) {
+		sessionSrv, m := newSessionService(now)
+
+		current := &session.RefreshToken{Hash: session.Hash("secret"), UserID: domain.NewID(), FamilyID: domain.NewID(), ExpiresAt: now.Add(time.Hour)}
+
+		m.sessionRepo.On("Get", mock.Anything, current.Hash).Return(current, nil)
+		m.sessionRepo.On("RevokeFamily", mock.Anything, current.FamilyID, now).Return(nil)
+
+		assert.NoError(t, sessionSrv.Logout(context.Background(), "secret"))
+		m.sessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		sessionSrv, m := newSessionService(now)
+
+		m.sessionRepo.On("Get", mock.Anything, session.Hash("secret")).Return(nil, session.ErrInvalidToken)
+
+		assert.NoError(t, sessionSrv.Logout(context.Background(), "secret"))
+		m.sessionRepo.AssertNotCalled(t, "RevokeFamily", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRevokeAll(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	now := time.Now()
+
+	t.Run("revoke by admin", func(t *testing.T) {
+		sessionSrv, m := newSessionService(now)
+
+		userID := domain.NewID()
+
+		m.userRepo.On("GetByUUID", mock.Anything, userID).Return(&user.User{ID: userID}, nil)
+		m.sessionRepo.On("RevokeUser", mock.Anything, userID, now).Return(nil)
+
+		assert.NoError(t, sessionSrv.RevokeAll(adminCtx(), userID.String()))
+		m.sessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("revoke own", func(t *testing.T) {
+		sessionSrv, m := newSessionService(now)
+
+		userID := domain.NewID()
+
+		m.userRepo.On("GetByUUID", mock.Anything, userID).Return(&user.User{ID: userID}, nil)
+		m.sessionRepo.On("RevokeUser", mock.Anything, userID, now).Return(nil)
+
+		assert.NoError(t, sessionSrv.RevokeAll(userCtx(userID, user.RoleSelf), userID.String()))
+		m.sessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("revoke of another user", func(t *testing.T) {
+		sessionSrv, m := newSessionService(now)
+
+		err := sessionSrv.RevokeAll(userCtx(domain.NewID(), user.RoleSupport), domain.NewID().String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		m.sessionRepo.AssertNotCalled(t, "RevokeUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		sessionSrv, m := newSessionService(now)
+
+		userID := domain.NewID()
+
+		m.userRepo.On("GetByUUID", mock.Anything, userID).Return(nil, user.ErrNotFound)
+
+		err := sessionSrv.RevokeAll(adminCtx(), userID.String())
+		assert.ErrorIs(t, err, user.ErrNotFound)
+		m.sessionRepo.AssertNotCalled(t, "RevokeUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
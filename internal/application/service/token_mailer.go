@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mail"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+)
+
+// tokenMailer issues single-use tokens and mails their secrets, tokens issued before for the same purpose are revoked
+type tokenMailer struct {
+	tokenRepo    verification.Repository
+	uow          domain.UnitOfWork
+	mailer       mail.Mailer
+	timeProvider domain.TimeProvider
+}
+
+func (m *tokenMailer) send(ctx context.Context, id domain.ID, purpose verification.Purpose, email string, ttl time.Duration, subject, text string) error {
+	now := m.timeProvider.UtcNow()
+
+	secret, token, err := verification.New(id, purpose, email, now.Add(ttl))
+	if err != nil {
+		return err
+	}
+
+	err = m.uow.WithinTx(ctx, func(ctx context.Context) error {
+		if err := m.tokenRepo.Revoke(ctx, id, purpose); err != nil {
+			return err
+		}
+
+		return m.tokenRepo.Create(ctx, token, now)
+	})
+	if err != nil {
+		return fmt.Errorf("failed storing token: %w", err)
+	}
+
+	msg := &mail.Message{
+		To:      email,
+		Subject: subject,
+		Body:    fmt.Sprintf("%s, it expires at %s.\n\n%s", text, token.ExpiresAt.Format(time.RFC1123), secret),
+	}
+
+	if err = m.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed sending mail: %w", err)
+	}
+
+	return nil
+}
@@ -6,16 +6,27 @@ import (
 	"fmt"
 
 	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
 	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
 	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
 )
 
 type UserPort interface {
 	Create(ctx context.Context, dto *CreateUserDTO) error
-	Update(ctx context.Context, userID string, dto *UpdateUserDTO) error
-	Delete(ctx context.Context, userID string) error
-	Get(ctx context.Context, page, pageSize int) ([]*user.User, error)
+	Update(ctx context.Context, userID string, changes *user.ChangeSet, version *int64) error
+	Delete(ctx context.Context, userID string, version *int64) error
+	Restore(ctx context.Context, userID string) error
+	Get(ctx context.Context, q *user.ListQuery) (*user.Page, error)
 	GetByUUID(ctx context.Context, userID string) (*user.User, error)
+
+	ListAddresses(ctx context.Context, userID string) ([]*user.Address, error)
+	GetAddress(ctx context.Context, userID string, addrType user.AddressType) (*user.Address, error)
+	AddAddress(ctx context.Context, userID string, addr *user.Address, version *int64) error
+	ReplaceAddress(ctx context.Context, userID string, addr *user.Address, version *int64) error
+	DeleteAddress(ctx context.Context, userID string, addrType user.AddressType, version *int64) error
 }
 
 type CreateUserDTO struct {
@@ -25,6 +36,7 @@ type CreateUserDTO struct {
 	FirstName   string
 	LastName    string
 	PhoneNumber string
+	Role        user.Role
 	Addresses   []*CreateUserAddress
 }
 
@@ -37,45 +49,91 @@ type CreateUserAddress struct {
 	Country    string
 }
 
-type UpdateUserDTO struct {
-	Password    *string
-	FirstName   *string
-	LastName    *string
-	PhoneNumber *string
-	Addresses   []*UpdateUserAddress
-}
-
-type UpdateUserAddress struct {
-	Type       int
-	Street     *string
-	City       *string
-	State      *string
-	PostalCode *string
-	Country    *string
-}
-
 type userService struct {
 	userRepo     user.Repository
+	sessionRepo  session.Repository
+	uow          domain.UnitOfWork
 	timeProvider domain.TimeProvider
+	verifier     EmailVerifier
+	policy       *user.PasswordPolicy
+	hasher       auth.PasswordHasher
+	mfaPolicy    *user.MFAPolicy
+	*auditor
+	*eventRaiser
 }
 
 var _ UserPort = (*userService)(nil)
 
-func NewUserService(userRepo user.Repository, timeProvider domain.TimeProvider) *userService {
+func NewUserService(
+	userRepo user.Repository,
+	sessionRepo session.Repository,
+	uow domain.UnitOfWork,
+	auditRepo audit.Repository,
+	outbox event.Outbox,
+	timeProvider domain.TimeProvider,
+	verifier EmailVerifier,
+	policy *user.PasswordPolicy,
+	hasher auth.PasswordHasher,
+	mfaPolicy *user.MFAPolicy,
+) *userService {
 	return &userService{
 		userRepo:     userRepo,
+		sessionRepo:  sessionRepo,
+		uow:          uow,
 		timeProvider: timeProvider,
+		verifier:     verifier,
+		policy:       policy,
+		hasher:       hasher,
+		mfaPolicy:    mfaPolicy,
+		auditor: &auditor{
+			auditRepo:    auditRepo,
+			timeProvider: timeProvider,
+		},
+		eventRaiser: &eventRaiser{
+			outbox:       outbox,
+			timeProvider: timeProvider,
+		},
 	}
 }
 
 func (s *userService) Create(ctx context.Context, dto *CreateUserDTO) error {
+	role := dto.Role
+	if role == "" {
+		role = user.RoleSelf
+	}
+
+	// anyone can register, but only privileged callers can grant roles
+	if role != user.RoleSelf {
+		if err := authorize(ctx, user.PermissionManageRoles, domain.ID{}); err != nil {
+			return err
+		}
+	}
+
+	// a new user has no MFA enabled yet, the role is granted once they enable it
+	if s.mfaPolicy.Requires(role) {
+		return user.ErrMFARequired
+	}
+
+	if err := s.policy.Validate(dto.Password, dto.Email); err != nil {
+		return err
+	}
+
+	passwordHash, err := s.hasher.Hash(dto.Password)
+	if err != nil {
+		err = fmt.Errorf("failed hashing password: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
 	u := &user.User{
 		ID:          dto.ID,
 		Email:       dto.Email,
-		Password:    dto.Password,
+		Password:    passwordHash,
 		FirstName:   dto.FirstName,
 		LastName:    dto.LastName,
 		PhoneNumber: dto.PhoneNumber,
+		Role:        role,
 		Addresses:   make([]*user.Address, 0, len(dto.Addresses)),
 	}
 
@@ -90,7 +148,19 @@ func (s *userService) Create(ctx context.Context, dto *CreateUserDTO) error {
 		})
 	}
 
-	if err := s.userRepo.Create(ctx, u, s.timeProvider.UtcNow()); err != nil {
+	// the user is created together with the audit entry and the event or not at all
+	err = s.uow.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Create(ctx, u, s.timeProvider.UtcNow()); err != nil {
+			return err
+		}
+
+		if err := s.record(ctx, audit.ActionUserCreated, u.ID, audit.UserChanges(u)); err != nil {
+			return err
+		}
+
+		return s.raise(ctx, u.ID, event.UserCreated{User: u})
+	})
+	if err != nil {
 		if errors.Is(err, user.ErrEmailAlreadyExists) {
 			return user.ErrEmailAlreadyExists
 		}
@@ -100,108 +170,180 @@ func (s *userService) Create(ctx context.Context, dto *CreateUserDTO) error {
 		return err
 	}
 
+	// the user is created anyway, the verification can be requested again
+	if err = s.verifier.SendVerification(ctx, u.ID, u.Email); err != nil {
+		logger.Error(err)
+	}
+
 	return nil
 }
 
-func (s *userService) Update(ctx context.Context, userID string, dto *UpdateUserDTO) error {
-	userFields := make(map[string]any)
+// Update applies the change set as a whole, when the version is given it has to match the current one.
+// A role change revokes all sessions of the user, so none started with the previous role outlives the change.
+func (s *userService) Update(ctx context.Context, userID string, changes *user.ChangeSet, version *int64) error {
 	id, err := domain.ParseID(userID)
 	if err != nil {
 		return fmt.Errorf("failed parsing uuid: %w", err)
 	}
 
-	if dto.Password != nil {
-		userFields["password"] = *dto.Password
+	if err = authorize(ctx, user.PermissionUpdate, id); err != nil {
+		return err
 	}
-	if dto.FirstName != nil {
-		userFields["first_name"] = *dto.FirstName
+
+	if err = authorizeRankOf(ctx, s.userRepo, id); err != nil {
+		return err
 	}
-	if dto.LastName != nil {
-		userFields["last_name"] = *dto.LastName
+
+	if changes.Has(user.FieldRole) {
+		if err = authorize(ctx, user.PermissionManageRoles, id); err != nil {
+			return err
+		}
 	}
-	if dto.PhoneNumber != nil {
-		userFields["phone_number"] = *dto.PhoneNumber
+
+	if err = changes.Validate(); err != nil {
+		return err
+	}
+
+	if err = s.checkMFA(ctx, id, changes); err != nil {
+		return err
 	}
 
-	if len(userFields) > 0 {
-		if err = s.userRepo.UpdateBasicFields(ctx, id, userFields); err != nil {
-			err = fmt.Errorf("failed updating user personal data: %w", err)
-			logger.Debug(err)
+	// nothing to change, the version stays the same
+	if changes.IsEmpty() {
+		return nil
+	}
 
+	// the whole update is applied or none of it
+	err = s.uow.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.IncrementVersion(ctx, id, version); err != nil {
 			return err
 		}
-	}
 
-	for _, addr := range dto.Addresses {
-		addrFields := make(map[string]any)
-
-		if addr.Street != nil {
-			addrFields["street"] = *addr.Street
-		}
-		if addr.City != nil {
-			addrFields["city"] = *addr.City
-		}
-		if addr.State != nil {
-			addrFields["state"] = *addr.State
-		}
-		if addr.PostalCode != nil {
-			addrFields["postal_code"] = *addr.PostalCode
-		}
-		if addr.Country != nil {
-			addrFields["country"] = *addr.Country
-		}
-
-		if len(addrFields) > 0 {
-			if err = s.userRepo.UpdateAddress(ctx, id, addr.Type, addrFields); err != nil {
-				if errors.Is(err, user.ErrAddressNotFound) {
-					newAddr := &user.Address{
-						Type: user.AddressType(addr.Type),
-					}
-					if addr.Street != nil {
-						newAddr.Street = *addr.Street
-					}
-					if addr.City != nil {
-						newAddr.City = *addr.City
-					}
-					if addr.State != nil {
-						newAddr.State = *addr.State
-					}
-					if addr.PostalCode != nil {
-						newAddr.PostalCode = *addr.PostalCode
-					}
-					if addr.Country != nil {
-						newAddr.Country = *addr.Country
-					}
-
-					if err = s.userRepo.InsertAddress(ctx, id, newAddr, s.timeProvider.UtcNow()); err != nil {
-						err = fmt.Errorf("failed inserting additional address")
-						logger.Debug(err)
-
-						return err
-					}
-				} else {
-					err = fmt.Errorf("failed updating user address data: %w", err)
-					logger.Debug(err)
-
-					return err
-				}
-
-				return nil
+		if len(changes.User) > 0 {
+			if err := s.userRepo.UpdateBasicFields(ctx, id, changes.User); err != nil {
+				return fmt.Errorf("failed updating user personal data: %w", err)
 			}
 		}
+
+		for _, addr := range changes.Addresses {
+			if err := s.updateAddress(ctx, id, addr); err != nil {
+				return err
+			}
+		}
+
+		if changes.Has(user.FieldRole) {
+			if err := s.sessionRepo.RevokeUser(ctx, id, s.timeProvider.UtcNow()); err != nil {
+				return fmt.Errorf("failed revoking sessions: %w", err)
+			}
+		}
+
+		recorded := audit.ChangeSetChanges(changes)
+		if err := s.record(ctx, audit.ActionUserUpdated, id, recorded); err != nil {
+			return err
+		}
+
+		return s.raise(ctx, id, event.UserUpdated{Changes: recorded})
+	})
+	if err != nil {
+		logger.Debug(err)
+
+		return err
+	}
+
+	return nil
+}
+
+// checkMFA makes sure a role requiring MFA is granted only to a user having MFA enabled
+func (s *userService) checkMFA(ctx context.Context, id domain.ID, changes *user.ChangeSet) error {
+	for _, c := range changes.User {
+		if c.Field != user.FieldRole {
+			continue
+		}
+
+		if role, _ := user.ParseRole(c.Value); !s.mfaPolicy.Requires(role) {
+			return nil
+		}
+
+		u, err := s.userRepo.GetByUUID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if !u.MFAEnabled {
+			return user.ErrMFARequired
+		}
+	}
+
+	return nil
+}
+
+// updateAddress applies the changes of an address, the address is added when the user does not have one of this type
+func (s *userService) updateAddress(ctx context.Context, id domain.ID, addr user.AddressChange) error {
+	if addr.Remove {
+		if err := s.userRepo.DeleteAddress(ctx, id, addr.Type); err != nil {
+			return fmt.Errorf("failed deleting address: %w", err)
+		}
+
+		return nil
+	}
+
+	if len(addr.Changes) == 0 {
+		return nil
+	}
+
+	err := s.userRepo.UpdateAddress(ctx, id, addr.Type, addr.Changes)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, user.ErrAddressNotFound) {
+		return fmt.Errorf("failed updating user address data: %w", err)
+	}
+
+	newAddr, err := addr.NewAddress()
+	if err != nil {
+		return err
+	}
+
+	if err = s.userRepo.InsertAddress(ctx, id, newAddr, s.timeProvider.UtcNow()); err != nil {
+		return fmt.Errorf("failed inserting additional address: %w", err)
 	}
 
 	return nil
 }
 
-func (s *userService) Delete(ctx context.Context, userID string) error {
+// Delete removes the user, when the version is given it has to match the current one
+func (s *userService) Delete(ctx context.Context, userID string, version *int64) error {
 	id, err := domain.ParseID(userID)
 	if err != nil {
 		return fmt.Errorf("failed parsing uuid: %w", err)
 	}
 
-	if err = s.userRepo.Delete(ctx, id); err != nil {
-		if errors.Is(err, user.ErrNotFound) {
+	if err = authorize(ctx, user.PermissionDelete, id); err != nil {
+		return err
+	}
+
+	if err = authorizeRankOf(ctx, s.userRepo, id); err != nil {
+		return err
+	}
+
+	err = s.uow.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.IncrementVersion(ctx, id, version); err != nil {
+			return err
+		}
+
+		if err := s.userRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+
+		if err := s.record(ctx, audit.ActionUserDeleted, id, nil); err != nil {
+			return err
+		}
+
+		return s.raise(ctx, id, event.UserDeleted{})
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) || errors.Is(err, user.ErrVersionMismatch) {
 			return err
 		}
 
@@ -214,8 +356,70 @@ func (s *userService) Delete(ctx context.Context, userID string) error {
 	return nil
 }
 
-func (s *userService) Get(ctx context.Context, page, pageSize int) ([]*user.User, error) {
-	return s.userRepo.Get(ctx, page, pageSize)
+// Restore brings back a deleted user, unless it has been purged in the meantime
+func (s *userService) Restore(ctx context.Context, userID string) error {
+	id, err := domain.ParseID(userID)
+	if err != nil {
+		return fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionRestore, id); err != nil {
+		return err
+	}
+
+	err = s.uow.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Restore(ctx, id); err != nil {
+			return err
+		}
+
+		if err := s.userRepo.IncrementVersion(ctx, id, nil); err != nil {
+			return err
+		}
+
+		u, err := s.userRepo.GetByUUID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.record(ctx, audit.ActionUserRestored, id, nil); err != nil {
+			return err
+		}
+
+		return s.raise(ctx, id, event.UserRestored{User: u})
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) || errors.Is(err, user.ErrNotDeleted) || errors.Is(err, user.ErrErased) {
+			return err
+		}
+
+		err = fmt.Errorf("failed restoring user: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("user %s restored by %s", id, actor(ctx)))
+
+	return nil
+}
+
+func (s *userService) Get(ctx context.Context, q *user.ListQuery) (*user.Page, error) {
+	if err := authorize(ctx, user.PermissionList, domain.ID{}); err != nil {
+		return nil, err
+	}
+
+	if q.IncludeDeleted {
+		if err := authorize(ctx, user.PermissionRestore, domain.ID{}); err != nil {
+			return nil, err
+		}
+	}
+
+	// a cursor issued for a different order would skip or repeat users
+	if q.Cursor != nil && !q.Cursor.Matches(q.OrderBy()) {
+		return nil, user.ErrInvalidCursor
+	}
+
+	return s.userRepo.Get(ctx, q)
 }
 
 func (s *userService) GetByUUID(ctx context.Context, userID string) (*user.User, error) {
@@ -224,5 +428,9 @@ func (s *userService) GetByUUID(ctx context.Context, userID string) (*user.User,
 		return nil, fmt.Errorf("failed parsing uuid: %w", err)
 	}
 
+	if err = authorize(ctx, user.PermissionRead, id); err != nil {
+		return nil, err
+	}
+
 	return s.userRepo.GetByUUID(ctx, id)
 }
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+func (s *userService) ListAddresses(ctx context.Context, userID string) ([]*user.Address, error) {
+	id, err := domain.ParseID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionRead, id); err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.ListAddresses(ctx, id)
+}
+
+func (s *userService) GetAddress(ctx context.Context, userID string, addrType user.AddressType) (*user.Address, error) {
+	addresses, err := s.ListAddresses(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, addr := range addresses {
+		if addr.Type == addrType {
+			return addr, nil
+		}
+	}
+
+	return nil, user.ErrAddressNotFound
+}
+
+// AddAddress adds an address of a type the user does not have yet, when the version is given it has to match the current one
+func (s *userService) AddAddress(ctx context.Context, userID string, addr *user.Address, version *int64) error {
+	changes := audit.AddressChanges(addr.Type, addr.Changes())
+
+	return s.changeAddresses(ctx, userID, version, audit.ActionAddressAdded, changes, event.AddressAdded{Address: addr}, func(ctx context.Context, id domain.ID) error {
+		return s.userRepo.InsertAddress(ctx, id, addr, s.timeProvider.UtcNow())
+	})
+}
+
+// ReplaceAddress replaces all fields of an existing address, when the version is given it has to match the current one
+func (s *userService) ReplaceAddress(ctx context.Context, userID string, addr *user.Address, version *int64) error {
+	changes := audit.AddressChanges(addr.Type, addr.Changes())
+
+	return s.changeAddresses(ctx, userID, version, audit.ActionAddressReplaced, changes, event.UserUpdated{Changes: changes}, func(ctx context.Context, id domain.ID) error {
+		return s.userRepo.UpdateAddress(ctx, id, addr.Type, addr.Changes())
+	})
+}
+
+// DeleteAddress removes an address unless it is the last one, when the version is given it has to match the current one
+func (s *userService) DeleteAddress(ctx context.Context, userID string, addrType user.AddressType, version *int64) error {
+	changes := audit.AddressRemoved(addrType)
+
+	return s.changeAddresses(ctx, userID, version, audit.ActionAddressDeleted, changes, event.UserUpdated{Changes: changes}, func(ctx context.Context, id domain.ID) error {
+		addresses, err := s.userRepo.ListAddresses(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if !slices.ContainsFunc(addresses, func(addr *user.Address) bool { return addr.Type == addrType }) {
+			return user.ErrAddressNotFound
+		}
+
+		if len(addresses) == 1 {
+			return user.ErrLastAddress
+		}
+
+		return s.userRepo.DeleteAddress(ctx, id, addrType)
+	})
+}
+
+// changeAddresses authorizes and runs a change of addresses in a transaction together with the version increment,
+// the audit entry and the event
+func (s *userService) changeAddresses(
+	ctx context.Context,
+	userID string,
+	version *int64,
+	action audit.Action,
+	changes []audit.Change,
+	payload event.Payload,
+	change func(ctx context.Context, id domain.ID) error,
+) error {
+	id, err := domain.ParseID(userID)
+	if err != nil {
+		return fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionUpdate, id); err != nil {
+		return err
+	}
+
+	if err = authorizeRankOf(ctx, s.userRepo, id); err != nil {
+		return err
+	}
+
+	err = s.uow.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.IncrementVersion(ctx, id, version); err != nil {
+			return err
+		}
+
+		if err := change(ctx, id); err != nil {
+			return err
+		}
+
+		if err := s.record(ctx, action, id, changes); err != nil {
+			return err
+		}
+
+		return s.raise(ctx, id, payload)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFound),
+			errors.Is(err, user.ErrVersionMismatch),
+			errors.Is(err, user.ErrAddressNotFound),
+			errors.Is(err, user.ErrAddressAlreadyExists),
+			errors.Is(err, user.ErrLastAddress):
+			return err
+		}
+
+		err = fmt.Errorf("failed changing addresses: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	return nil
+}
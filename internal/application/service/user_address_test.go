@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	domainMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain"
+	repoMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/infrastructure/database/mysql"
+)
+
+func TestListAddresses(t *testing.T) {
+	t.Run("user lists own addresses", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+		addresses := []*user.Address{{Type: 1, City: "New York"}}
+
+		mockRepo.On("ListAddresses", mock.Anything, id).Return(addresses, nil)
+
+		result, err := userSrv.ListAddresses(userCtx(id, user.RoleSelf), id.String())
+		assert.NoError(t, err)
+		assert.Equal(t, addresses, result)
+	})
+
+	t.Run("user can not list addresses of other users", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		_, err := userSrv.ListAddresses(userCtx(domain.NewID(), user.RoleSelf), domain.NewID().String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "ListAddresses", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetAddress(t *testing.T) {
+	t.Run("get address by type", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+		mockRepo.On("ListAddresses", mock.Anything, id).Return([]*user.Address{{Type: 1, City: "New York"}, {Type: 2, City: "Boston"}}, nil)
+
+		result, err := userSrv.GetAddress(adminCtx(), id.String(), 2)
+		assert.NoError(t, err)
+		assert.Equal(t, "Boston", result.City)
+	})
+
+	t.Run("address not found", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+		mockRepo.On("ListAddresses", mock.Anything, id).Return([]*user.Address{{Type: 1, City: "New York"}}, nil)
+
+		_, err := userSrv.GetAddress(adminCtx(), id.String(), 3)
+		assert.ErrorIs(t, err, user.ErrAddressNotFound)
+	})
+}
+
+func TestAddAddress(t *testing.T) {
+	t.Run("add address", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockTimeProvider := new(domainMock.TimeProviderMock)
+		mockOutbox := stubOutbox()
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), mockOutbox, mockTimeProvider, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+		addr := &user.Address{Type: 2, Street: "Side av", City: "Boston", PostalCode: "55010"}
+		now := time.Now()
+
+		mockTimeProvider.On("UtcNow").Return(now)
+		mockRepo.On("IncrementVersion", mock.Anything, id, ptr(int64(3))).Return(nil)
+		mockRepo.On("InsertAddress", mock.Anything, id, addr, now).Return(nil)
+
+		err := userSrv.AddAddress(adminCtx(), id.String(), addr, ptr(int64(3)))
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockOutbox.AssertCalled(t, "Add", mock.Anything, mock.MatchedBy(func(e *event.Event) bool {
+			return e.Type == event.TypeAddressAdded && e.UserID == id && e.OccurredAt == now &&
+				string(e.Data) == `{"address":{"type":2,"street":"Side av","city":"Boston","state":"","postal_code":"55010","country":""}}`
+		}))
+	})
+
+	t.Run("address already exists", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockTimeProvider := new(domainMock.TimeProviderMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), mockTimeProvider, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		mockTimeProvider.On("UtcNow").Return(time.Now())
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+		mockRepo.On("InsertAddress", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(user.ErrAddressAlreadyExists)
+
+		err := userSrv.AddAddress(adminCtx(), domain.NewID().String(), &user.Address{Type: 1}, nil)
+		assert.ErrorIs(t, err, user.ErrAddressAlreadyExists)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockTimeProvider := new(domainMock.TimeProviderMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), mockTimeProvider, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		mockTimeProvider.On("UtcNow").Return(time.Now())
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+		mockRepo.On("InsertAddress", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("some error"))
+
+		err := userSrv.AddAddress(adminCtx(), domain.NewID().String(), &user.Address{Type: 1}, nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed changing addresses")
+	})
+
+	t.Run("user can not add addresses of other users", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		err := userSrv.AddAddress(userCtx(domain.NewID(), user.RoleSelf), domain.NewID().String(), &user.Address{Type: 1}, nil)
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "IncrementVersion", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("support can not add addresses of an admin", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+		mockRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id, Role: user.RoleAdmin}, nil)
+
+		err := userSrv.AddAddress(userCtx(domain.NewID(), user.RoleSupport), id.String(), &user.Address{Type: 1}, nil)
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "IncrementVersion", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestReplaceAddress(t *testing.T) {
+	t.Run("replace address", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+
+		mockRepo.On("IncrementVersion", mock.Anything, id, (*int64)(nil)).Return(nil)
+		mockRepo.On("UpdateAddress", mock.Anything, id, user.AddressType(1), []user.Change{
+			user.Set(user.FieldStreet, "Main av"),
+			user.Set(user.FieldCity, "New York"),
+			user.Clear(user.FieldState),
+			user.Set(user.FieldPostalCode, "55010"),
+			user.Set(user.FieldCountry, "USA"),
+		}).Return(nil)
+
+		err := userSrv.ReplaceAddress(adminCtx(), id.String(), &user.Address{
+			Type:       1,
+			Street:     "Main av",
+			City:       "New York",
+			PostalCode: "55010",
+			Country:    "USA",
+		}, nil)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("address not found", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+		mockRepo.On("UpdateAddress", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(user.ErrAddressNotFound)
+
+		err := userSrv.ReplaceAddress(adminCtx(), domain.NewID().String(), &user.Address{Type: 3}, nil)
+		assert.ErrorIs(t, err, user.ErrAddressNotFound)
+	})
+}
+
+func TestDeleteAddress(t *testing.T) {
+	t.Run("delete address", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+
+		mockRepo.On("IncrementVersion", mock.Anything, id, (*int64)(nil)).Return(nil)
+		mockRepo.On("ListAddresses", mock.Anything, id).Return([]*user.Address{{Type: 1}, {Type: 2}}, nil)
+		mockRepo.On("DeleteAddress", mock.Anything, id, user.AddressType(2)).Return(nil)
+
+		err := userSrv.DeleteAddress(adminCtx(), id.String(), 2, nil)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("last address is kept", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+		mockRepo.On("ListAddresses", mock.Anything, mock.Anything).Return([]*user.Address{{Type: 1}}, nil)
+
+		err := userSrv.DeleteAddress(adminCtx(), domain.NewID().String(), 1, nil)
+		assert.ErrorIs(t, err, user.ErrLastAddress)
+		mockRepo.AssertNotCalled(t, "DeleteAddress", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("address not found", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+		mockRepo.On("ListAddresses", mock.Anything, mock.Anything).Return([]*user.Address{{Type: 1}}, nil)
+
+		err := userSrv.DeleteAddress(adminCtx(), domain.NewID().String(), 3, nil)
+		assert.ErrorIs(t, err, user.ErrAddressNotFound)
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, ptr(int64(1))).Return(user.ErrVersionMismatch)
+
+		err := userSrv.DeleteAddress(adminCtx(), domain.NewID().String(), 1, ptr(int64(1)))
+		assert.ErrorIs(t, err, user.ErrVersionMismatch)
+		mockRepo.AssertNotCalled(t, "ListAddresses", mock.Anything, mock.Anything)
+	})
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,20 +13,45 @@ import (
 
 	"github.com/wojciechpawlinow/usermanagement/internal/config"
 	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
 	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
 	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
 	domainMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain"
+	authMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain/auth"
+	mailMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain/mail"
+	mfaMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain/mfa"
+	oidcMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain/oidc"
 	repoMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/infrastructure/database/mysql"
 )
 
+// verifierMock lives here, as the service mocks import this package
+type verifierMock struct {
+	mock.Mock
+}
+
+func (m *verifierMock) SendVerification(ctx context.Context, id domain.ID, email string) error {
+	args := m.Called(ctx, id, email)
+
+	return args.Error(0)
+}
+
 func TestCreate(t *testing.T) {
 	t.Run("create user", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
+		mockVerifier := new(verifierMock)
 
 		mockTimeProvider := new(domainMock.TimeProviderMock)
 		mockTimeProvider.On("UtcNow").Return(time.Now())
 
-		userSrv := NewUserService(mockRepo, mockTimeProvider)
+		mockHasher := new(authMock.PasswordHasherMock)
+		mockAudit := stubAudit()
+		mockOutbox := stubOutbox()
+
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), mockAudit, mockOutbox, mockTimeProvider, mockVerifier, testPasswordPolicy, mockHasher, testMFAPolicy)
 
 		dto := &CreateUserDTO{
 			ID:          domain.NewID(),
@@ -45,18 +72,54 @@ func TestCreate(t *testing.T) {
 			},
 		}
 
-		mockRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockHasher.On("Hash", "admin123").Return("admin123-hash", nil)
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(u *user.User) bool {
+			return u.Password == "admin123-hash"
+		}), mock.Anything).Return(nil)
+		mockVerifier.On("SendVerification", mock.Anything, dto.ID, "test@example.com").Return(nil)
 
 		err := userSrv.Create(context.Background(), dto)
 		assert.NoError(t, err)
+		mockVerifier.AssertExpectations(t)
+
+		// the registration is anonymous and the password hash is not recorded
+		mockAudit.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(e *audit.Entry) bool {
+			return e.Actor.Type == audit.ActorAnonymous && e.Action == audit.ActionUserCreated && e.UserID == dto.ID &&
+				slices.ContainsFunc(e.Changes, func(c audit.Change) bool { return c.Field == "addresses.1.city" && *c.Value == "New York" }) &&
+				slices.ContainsFunc(e.Changes, func(c audit.Change) bool { return c.Field == "password" && *c.Value == audit.Redacted })
+		}))
+		mockOutbox.AssertCalled(t, "Add", mock.Anything, mock.MatchedBy(func(e *event.Event) bool {
+			return e.Type == event.TypeUserCreated && e.UserID == dto.ID &&
+				strings.Contains(string(e.Data), `"email":"test@example.com"`) && !strings.Contains(string(e.Data), "admin123")
+		}))
 	})
+
+	t.Run("failed verification does not fail the creation", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockVerifier := new(verifierMock)
+
+		mockTimeProvider := new(domainMock.TimeProviderMock)
+		mockTimeProvider.On("UtcNow").Return(time.Now())
+
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), mockTimeProvider, mockVerifier, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		mockRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockVerifier.On("SendVerification", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("some mailer error"))
+
+		err := userSrv.Create(context.Background(), &CreateUserDTO{ID: domain.NewID(), Email: "test@example.com", Password: "admin123"})
+		assert.NoError(t, err)
+	})
+
 	t.Run("email already exists", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
 
 		mockTimeProvider := new(domainMock.TimeProviderMock)
 		mockTimeProvider.On("UtcNow").Return(time.Now())
 
-		userSrv := NewUserService(mockRepo, mockTimeProvider)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), mockTimeProvider, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
 
 		dto := &CreateUserDTO{
 			ID:          domain.NewID(),
@@ -92,7 +155,7 @@ func TestCreate(t *testing.T) {
 		mockTimeProvider := new(domainMock.TimeProviderMock)
 		mockTimeProvider.On("UtcNow").Return(time.Now())
 
-		userSrv := NewUserService(mockRepo, mockTimeProvider)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), mockTimeProvider, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
 
 		dto := &CreateUserDTO{
 			ID:          domain.NewID(),
@@ -119,49 +182,146 @@ func TestCreate(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed creating user")
 	})
+
+	t.Run("granting a role requires admin", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		dto := &CreateUserDTO{
+			ID:    domain.NewID(),
+			Email: "test@example.com",
+			Role:  user.RoleAdmin,
+		}
+
+		err := userSrv.Create(context.Background(), dto)
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+
+		err = userSrv.Create(userCtx(domain.NewID(), user.RoleSupport), dto)
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("admin creates an admin", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockVerifier := new(verifierMock)
+		mockVerifier.On("SendVerification", mock.Anything, mock.Anything, "test@example.com").Return(nil)
+
+		mockTimeProvider := new(domainMock.TimeProviderMock)
+		mockTimeProvider.On("UtcNow").Return(time.Now())
+
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), mockTimeProvider, mockVerifier, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(u *user.User) bool {
+			return u.Role == user.RoleAdmin
+		}), mock.Anything).Return(nil)
+
+		err := userSrv.Create(adminCtx(), &CreateUserDTO{ID: domain.NewID(), Email: "test@example.com", Password: "admin123", Role: user.RoleAdmin})
+		assert.NoError(t, err)
+	})
+
+	t.Run("role requiring mfa is not granted to a new user", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), user.NewMFAPolicy([]user.Role{user.RoleAdmin}))
+
+		err := userSrv.Create(adminCtx(), &CreateUserDTO{ID: domain.NewID(), Email: "test@example.com", Password: "admin123", Role: user.RoleAdmin})
+		assert.ErrorIs(t, err, user.ErrMFARequired)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("registered user gets the self role", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockVerifier := new(verifierMock)
+		mockVerifier.On("SendVerification", mock.Anything, mock.Anything, "test@example.com").Return(nil)
+
+		mockTimeProvider := new(domainMock.TimeProviderMock)
+		mockTimeProvider.On("UtcNow").Return(time.Now())
+
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), mockTimeProvider, mockVerifier, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(u *user.User) bool {
+			return u.Role == user.RoleSelf
+		}), mock.Anything).Return(nil)
+
+		err := userSrv.Create(context.Background(), &CreateUserDTO{ID: domain.NewID(), Email: "test@example.com", Password: "admin123"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("weak password", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		err := userSrv.Create(context.Background(), &CreateUserDTO{ID: domain.NewID(), Email: "test@example.com", Password: "password"})
+		assert.ErrorIs(t, err, user.ErrWeakPassword)
+
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
 func TestUpdate(t *testing.T) {
 	t.Run("update user", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
+		mockAudit := new(repoMock.AuditRepositoryMock)
 		mockTimeProvider := new(domainMock.TimeProviderMock)
-
-		userSrv := NewUserService(mockRepo, mockTimeProvider)
-
-		userID := domain.NewID().String()
-		dto := &UpdateUserDTO{
-			FirstName:   ptr("Test"),
-			LastName:    ptr("Test"),
-			PhoneNumber: ptr("1234567890"),
-			Addresses: []*UpdateUserAddress{
+		now := time.Now()
+		mockTimeProvider.On("UtcNow").Return(now)
+
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), mockAudit, stubOutbox(), mockTimeProvider, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+
+		id := domain.NewID()
+		userID := id.String()
+		changes := &user.ChangeSet{
+			User: []user.Change{
+				user.Set(user.FieldFirstName, "Test"),
+				user.Set(user.FieldLastName, "Test"),
+				user.Clear(user.FieldPhoneNumber),
+			},
+			Addresses: []user.AddressChange{
 				{
-					Type:       1,
-					Street:     ptr("Test"),
-					City:       ptr("New York"),
-					State:      ptr("NY"),
-					PostalCode: ptr(""),
-					Country:    ptr("USA"),
+					Type: 1,
+					Changes: []user.Change{
+						user.Set(user.FieldStreet, "Test"),
+						user.Set(user.FieldCity, "New York"),
+						user.Clear(user.FieldState),
+					},
 				},
 			},
 		}
 
-		mockRepo.On("UpdateBasicFields", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-		mockRepo.On("UpdateAddress", mock.Anything, mock.Anything, 1, mock.Anything).Return(nil)
+		mockRepo.On("UpdateBasicFields", mock.Anything, mock.Anything, changes.User).Return(nil)
+		mockRepo.On("UpdateAddress", mock.Anything, mock.Anything, user.AddressType(1), changes.Addresses[0].Changes).Return(nil)
+		mockAudit.On("Create", mock.Anything, &audit.Entry{
+			Actor:  audit.Actor{Type: audit.ActorClient, ID: "test"},
+			Action: audit.ActionUserUpdated,
+			UserID: id,
+			Changes: []audit.Change{
+				{Field: "first_name", Value: ptr("Test")},
+				{Field: "last_name", Value: ptr("Test")},
+				{Field: "phone_number"},
+				{Field: "addresses.1.street", Value: ptr("Test")},
+				{Field: "addresses.1.city", Value: ptr("New York")},
+				{Field: "addresses.1.state"},
+			},
+			CreatedAt: now,
+		}).Return(nil)
 
-		err := userSrv.Update(context.Background(), userID, dto)
+		err := userSrv.Update(adminCtx(), userID, changes, nil)
 		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockAudit.AssertExpectations(t)
 	})
 
 	t.Run("error parsing userID", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
 		mockTimeProvider := new(domainMock.TimeProviderMock)
 
-		userSrv := NewUserService(mockRepo, mockTimeProvider)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), mockTimeProvider, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
 
 		invalidUserID := "invalid-uuid"
-		dto := &UpdateUserDTO{}
 
-		err := userSrv.Update(context.Background(), invalidUserID, dto)
+		err := userSrv.Update(adminCtx(), invalidUserID, &user.ChangeSet{}, nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed parsing uuid")
 	})
@@ -170,16 +330,17 @@ func TestUpdate(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
 		mockTimeProvider := new(domainMock.TimeProviderMock)
 
-		userSrv := NewUserService(mockRepo, mockTimeProvider)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), mockTimeProvider, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
 
 		userID := domain.NewID().String()
-		dto := &UpdateUserDTO{
-			FirstName: ptr("Test"),
+		changes := &user.ChangeSet{
+			User: []user.Change{user.Set(user.FieldFirstName, "Test")},
 		}
 
 		mockRepo.On("UpdateBasicFields", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("some error"))
 
-		err := userSrv.Update(context.Background(), userID, dto)
+		err := userSrv.Update(adminCtx(), userID, changes, nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed updating user personal data")
 	})
@@ -188,25 +349,19 @@ func TestUpdate(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
 		mockTimeProvider := new(domainMock.TimeProviderMock)
 
-		userSrv := NewUserService(mockRepo, mockTimeProvider)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), mockTimeProvider, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
 
 		userID := domain.NewID().String()
-		dto := &UpdateUserDTO{
-			Addresses: []*UpdateUserAddress{
-				{
-					Type:       1,
-					Street:     ptr("Test"),
-					City:       ptr("New York"),
-					State:      ptr("NY"),
-					PostalCode: ptr(""),
-					Country:    ptr("USA"),
-				},
+		changes := &user.ChangeSet{
+			Addresses: []user.AddressChange{
+				{Type: 1, Changes: []user.Change{user.Set(user.FieldCity, "New York")}},
 			},
 		}
 
-		mockRepo.On("UpdateAddress", mock.Anything, mock.Anything, 1, mock.Anything).Return(errors.New("some error"))
+		mockRepo.On("UpdateAddress", mock.Anything, mock.Anything, user.AddressType(1), mock.Anything).Return(errors.New("some error"))
 
-		err := userSrv.Update(context.Background(), userID, dto)
+		err := userSrv.Update(adminCtx(), userID, changes, nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed updating user address data")
 	})
@@ -217,85 +372,444 @@ func TestUpdate(t *testing.T) {
 
 		mockTimeProvider.On("UtcNow").Return(time.Now())
 
-		userSrv := NewUserService(mockRepo, mockTimeProvider)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), mockTimeProvider, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
 
 		userID := domain.NewID().String()
-		dto := &UpdateUserDTO{
-			Addresses: []*UpdateUserAddress{
+		changes := &user.ChangeSet{
+			Addresses: []user.AddressChange{
 				{
-					Type:       1,
-					Street:     ptr("Test"),
-					City:       ptr("New York"),
-					State:      ptr("NY"),
-					PostalCode: ptr(""),
-					Country:    ptr("USA"),
+					Type: 1,
+					Changes: []user.Change{
+						user.Set(user.FieldStreet, "Test"),
+						user.Set(user.FieldCity, "New York"),
+						user.Set(user.FieldPostalCode, "55010"),
+					},
 				},
 			},
 		}
 
-		mockRepo.On("UpdateAddress", mock.Anything, mock.Anything, 1, mock.Anything).Return(user.ErrAddressNotFound)
+		mockRepo.On("UpdateAddress", mock.Anything, mock.Anything, user.AddressType(1), mock.Anything).Return(user.ErrAddressNotFound)
 		mockRepo.On("InsertAddress", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("insert address error"))
 
-		err := userSrv.Update(context.Background(), userID, dto)
+		err := userSrv.Update(adminCtx(), userID, changes, nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed inserting additional address")
 	})
+
+	t.Run("missing address needs all required fields", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+
+		changes := &user.ChangeSet{
+			Addresses: []user.AddressChange{
+				{Type: 1, Changes: []user.Change{user.Set(user.FieldCity, "New York")}},
+			},
+		}
+
+		mockRepo.On("UpdateAddress", mock.Anything, mock.Anything, user.AddressType(1), mock.Anything).Return(user.ErrAddressNotFound)
+
+		err := userSrv.Update(adminCtx(), domain.NewID().String(), changes, nil)
+		assert.ErrorIs(t, err, user.ErrInvalidChange)
+		mockRepo.AssertNotCalled(t, "InsertAddress", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("remove address", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+
+		id := domain.NewID()
+
+		mockRepo.On("DeleteAddress", mock.Anything, id, user.AddressType(2)).Return(nil)
+
+		err := userSrv.Update(adminCtx(), id.String(), &user.ChangeSet{
+			Addresses: []user.AddressChange{{Type: 2, Remove: true}},
+		}, nil)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid changes", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			changes *user.ChangeSet
+		}{
+			{name: "clear required field", changes: &user.ChangeSet{User: []user.Change{user.Clear(user.FieldLastName)}}},
+			{name: "clear required address field", changes: &user.ChangeSet{Addresses: []user.AddressChange{{Type: 1, Changes: []user.Change{user.Clear(user.FieldCity)}}}}},
+			{name: "address field of a user", changes: &user.ChangeSet{User: []user.Change{user.Set(user.FieldCity, "New York")}}},
+			{name: "unknown role", changes: &user.ChangeSet{User: []user.Change{user.Set(user.FieldRole, "root")}}},
+			{name: "password", changes: &user.ChangeSet{User: []user.Change{user.Set(user.FieldPassword, "hash")}}},
+			{name: "address changed twice", changes: &user.ChangeSet{Addresses: []user.AddressChange{{Type: 1, Remove: true}, {Type: 1, Remove: true}}}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				mockRepo := new(repoMock.UserRepositoryMock)
+				userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+				err := userSrv.Update(adminCtx(), domain.NewID().String(), tt.changes, nil)
+				assert.ErrorIs(t, err, user.ErrInvalidChange)
+				mockRepo.AssertNotCalled(t, "IncrementVersion", mock.Anything, mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("nothing to change", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		err := userSrv.Update(adminCtx(), domain.NewID().String(), &user.ChangeSet{}, ptr(int64(1)))
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "IncrementVersion", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		userID := domain.NewID()
+		version := int64(2)
+
+		mockRepo.On("IncrementVersion", mock.Anything, userID, &version).Return(user.ErrVersionMismatch)
+
+		changes := &user.ChangeSet{User: []user.Change{user.Set(user.FieldFirstName, "Test")}}
+
+		err := userSrv.Update(adminCtx(), userID.String(), changes, &version)
+		assert.ErrorIs(t, err, user.ErrVersionMismatch)
+		mockRepo.AssertNotCalled(t, "UpdateBasicFields", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("all missing addresses are inserted", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockTimeProvider := new(domainMock.TimeProviderMock)
+
+		mockTimeProvider.On("UtcNow").Return(time.Now())
+
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), mockTimeProvider, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+
+		userID := domain.NewID().String()
+		changes := &user.ChangeSet{}
+		for _, street := range []string{"First", "Second", "Third"} {
+			changes.Addresses = append(changes.Addresses, user.AddressChange{
+				Type: user.AddressType(len(changes.Addresses) + 1),
+				Changes: []user.Change{
+					user.Set(user.FieldStreet, street),
+					user.Set(user.FieldCity, "New York"),
+					user.Set(user.FieldPostalCode, "55010"),
+				},
+			})
+		}
+
+		mockRepo.On("UpdateAddress", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(user.ErrAddressNotFound)
+		mockRepo.On("InsertAddress", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		err := userSrv.Update(adminCtx(), userID, changes, nil)
+		assert.NoError(t, err)
+		mockRepo.AssertNumberOfCalls(t, "InsertAddress", 3)
+	})
+
+	t.Run("user updates own record", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+
+		id := domain.NewID()
+		changes := []user.Change{user.Set(user.FieldFirstName, "Test")}
+
+		mockRepo.On("UpdateBasicFields", mock.Anything, id, changes).Return(nil)
+
+		err := userSrv.Update(userCtx(id, user.RoleSelf), id.String(), &user.ChangeSet{User: changes}, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("user can not update other users", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+
+		changes := &user.ChangeSet{User: []user.Change{user.Set(user.FieldFirstName, "Test")}}
+
+		err := userSrv.Update(userCtx(domain.NewID(), user.RoleSelf), domain.NewID().String(), changes, nil)
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "UpdateBasicFields", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("support updates a regular user", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+		changes := []user.Change{user.Set(user.FieldFirstName, "Test")}
+
+		mockRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id, Role: user.RoleSelf}, nil)
+		mockRepo.On("IncrementVersion", mock.Anything, id, (*int64)(nil)).Return(nil)
+		mockRepo.On("UpdateBasicFields", mock.Anything, id, changes).Return(nil)
+
+		err := userSrv.Update(userCtx(domain.NewID(), user.RoleSupport), id.String(), &user.ChangeSet{User: changes}, nil)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("support can not update an admin", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+		mockRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id, Role: user.RoleAdmin}, nil)
+
+		changes := &user.ChangeSet{User: []user.Change{user.Set(user.FieldLastName, "Test")}}
+
+		err := userSrv.Update(userCtx(domain.NewID(), user.RoleSupport), id.String(), changes, nil)
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "IncrementVersion", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "UpdateBasicFields", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("user can not change own role", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+
+		id := domain.NewID()
+		changes := &user.ChangeSet{User: []user.Change{user.Set(user.FieldRole, string(user.RoleAdmin))}}
+
+		err := userSrv.Update(userCtx(id, user.RoleSelf), id.String(), changes, nil)
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+	})
+
+	t.Run("admin changes a role", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockSessions := new(repoMock.SessionRepositoryMock)
+		userSrv := NewUserService(mockRepo, mockSessions, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+		changes := []user.Change{user.Set(user.FieldRole, string(user.RoleSupport))}
+
+		mockRepo.On("IncrementVersion", mock.Anything, id, (*int64)(nil)).Return(nil)
+		mockRepo.On("UpdateBasicFields", mock.Anything, id, changes).Return(nil)
+		mockSessions.On("RevokeUser", mock.Anything, id, mock.Anything).Return(nil)
+
+		err := userSrv.Update(adminCtx(), id.String(), &user.ChangeSet{User: changes}, nil)
+		assert.NoError(t, err)
+		mockSessions.AssertExpectations(t)
+	})
+
+	t.Run("role requiring mfa", func(t *testing.T) {
+		mfaPolicy := user.NewMFAPolicy([]user.Role{user.RoleAdmin})
+		changes := []user.Change{user.Set(user.FieldRole, string(user.RoleAdmin))}
+
+		t.Run("user without mfa", func(t *testing.T) {
+			mockRepo := new(repoMock.UserRepositoryMock)
+			userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), mfaPolicy)
+
+			id := domain.NewID()
+			mockRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id}, nil)
+
+			err := userSrv.Update(adminCtx(), id.String(), &user.ChangeSet{User: changes}, nil)
+			assert.ErrorIs(t, err, user.ErrMFARequired)
+			mockRepo.AssertNotCalled(t, "IncrementVersion", mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		t.Run("user with mfa", func(t *testing.T) {
+			mockRepo := new(repoMock.UserRepositoryMock)
+			mockSessions := new(repoMock.SessionRepositoryMock)
+			userSrv := NewUserService(mockRepo, mockSessions, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), mfaPolicy)
+
+			id := domain.NewID()
+			mockRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id, MFAEnabled: true}, nil)
+			mockRepo.On("IncrementVersion", mock.Anything, id, (*int64)(nil)).Return(nil)
+			mockRepo.On("UpdateBasicFields", mock.Anything, id, changes).Return(nil)
+			mockSessions.On("RevokeUser", mock.Anything, id, mock.Anything).Return(nil)
+
+			err := userSrv.Update(adminCtx(), id.String(), &user.ChangeSet{User: changes}, nil)
+			assert.NoError(t, err)
+		})
+	})
 }
 
 func TestDelete(t *testing.T) {
 	t.Run("delete user", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
-		userSrv := NewUserService(mockRepo, new(domainMock.TimeProviderMock))
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
 
 		userID := domain.NewID().String()
 
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
 		mockRepo.On("Delete", mock.Anything, mock.Anything).Return(nil)
 
-		err := userSrv.Delete(context.Background(), userID)
+		err := userSrv.Delete(adminCtx(), userID, nil)
 		assert.NoError(t, err)
 	})
 
 	t.Run("error parsing userID", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
-		userSrv := NewUserService(mockRepo, new(domainMock.TimeProviderMock))
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
 
 		invalidUserID := "sdasdasd31231"
 
-		err := userSrv.Delete(context.Background(), invalidUserID)
+		err := userSrv.Delete(adminCtx(), invalidUserID, nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed parsing uuid")
 	})
 
+	t.Run("failed event fails the deletion", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockOutbox := new(repoMock.OutboxRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), mockOutbox, stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+		mockRepo.On("Delete", mock.Anything, mock.Anything).Return(nil)
+		mockOutbox.On("Add", mock.Anything, mock.MatchedBy(func(e *event.Event) bool {
+			return e.Type == event.TypeUserDeleted
+		})).Return(errors.New("db error"))
+
+		err := userSrv.Delete(adminCtx(), domain.NewID().String(), nil)
+		assert.Error(t, err)
+		mockOutbox.AssertExpectations(t)
+	})
+
+	t.Run("failed audit fails the deletion", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockAudit := new(repoMock.AuditRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), mockAudit, stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
+		mockRepo.On("Delete", mock.Anything, mock.Anything).Return(nil)
+		mockAudit.On("Create", mock.Anything, mock.MatchedBy(func(e *audit.Entry) bool {
+			return e.Action == audit.ActionUserDeleted
+		})).Return(errors.New("db error"))
+
+		err := userSrv.Delete(adminCtx(), domain.NewID().String(), nil)
+		assert.Error(t, err)
+		mockAudit.AssertExpectations(t)
+	})
+
 	t.Run("user not found", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
-		userSrv := NewUserService(mockRepo, new(domainMock.TimeProviderMock))
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
 
 		userID := domain.NewID().String()
 
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
 		mockRepo.On("Delete", mock.Anything, mock.Anything).Return(user.ErrNotFound)
 
-		err := userSrv.Delete(context.Background(), userID)
+		err := userSrv.Delete(adminCtx(), userID, nil)
 		assert.ErrorIs(t, err, user.ErrNotFound)
 	})
 
 	t.Run("repository error", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
-		userSrv := NewUserService(mockRepo, new(domainMock.TimeProviderMock))
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
 
 		userID := domain.NewID().String()
 
+		mockRepo.On("IncrementVersion", mock.Anything, mock.Anything, (*int64)(nil)).Return(nil)
 		mockRepo.On("Delete", mock.Anything, mock.Anything).Return(errors.New("some repository error"))
 
-		err := userSrv.Delete(context.Background(), userID)
+		err := userSrv.Delete(adminCtx(), userID, nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed deleting user")
 	})
+
+	t.Run("only admins delete users", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+
+		err := userSrv.Delete(userCtx(id, user.RoleSelf), id.String(), nil)
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+
+		err = userSrv.Delete(userCtx(domain.NewID(), user.RoleSupport), id.String(), nil)
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRestore(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	t.Run("restore user", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockOutbox := new(repoMock.OutboxRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), mockOutbox, stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+
+		mockRepo.On("Restore", mock.Anything, id).Return(nil)
+		mockRepo.On("IncrementVersion", mock.Anything, id, (*int64)(nil)).Return(nil)
+		mockRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id, Email: "test@example.com"}, nil)
+		mockOutbox.On("Add", mock.Anything, mock.MatchedBy(func(e *event.Event) bool {
+			return e.Type == event.TypeUserRestored && e.UserID == id
+		})).Return(nil)
+
+		err := userSrv.Restore(adminCtx(), id.String())
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockOutbox.AssertExpectations(t)
+	})
+
+	t.Run("user not deleted", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		mockRepo.On("Restore", mock.Anything, mock.Anything).Return(user.ErrNotDeleted)
+
+		err := userSrv.Restore(adminCtx(), domain.NewID().String())
+		assert.ErrorIs(t, err, user.ErrNotDeleted)
+		mockRepo.AssertNotCalled(t, "IncrementVersion", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("failed audit fails the restore", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		mockAudit := new(repoMock.AuditRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), mockAudit, stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+
+		mockRepo.On("Restore", mock.Anything, id).Return(nil)
+		mockRepo.On("IncrementVersion", mock.Anything, id, (*int64)(nil)).Return(nil)
+		mockRepo.On("GetByUUID", mock.Anything, id).Return(&user.User{ID: id}, nil)
+		mockAudit.On("Create", mock.Anything, mock.MatchedBy(func(e *audit.Entry) bool {
+			return e.Action == audit.ActionUserRestored
+		})).Return(errors.New("db error"))
+
+		err := userSrv.Restore(adminCtx(), id.String())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed restoring user")
+	})
+
+	t.Run("only admins restore users", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), stubClock(), nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		id := domain.NewID()
+
+		err := userSrv.Restore(userCtx(id, user.RoleSelf), id.String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+
+		err = userSrv.Restore(userCtx(domain.NewID(), user.RoleSupport), id.String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+
+		mockRepo.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+	})
 }
 
 func TestGet(t *testing.T) {
 	t.Run("get user", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
-		userSrv := NewUserService(mockRepo, nil)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), nil, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
 
 		expectedUsers := []*user.User{
 			{
@@ -314,30 +828,70 @@ func TestGet(t *testing.T) {
 			},
 		}
 
-		mockRepo.On("Get", mock.Anything, 1, 2).Return(expectedUsers, nil)
+		mockRepo.On("Get", mock.Anything, &user.ListQuery{Limit: 2}).Return(&user.Page{Users: expectedUsers}, nil)
 
-		users, err := userSrv.Get(context.Background(), 1, 2)
+		page, err := userSrv.Get(adminCtx(), &user.ListQuery{Limit: 2})
 		assert.NoError(t, err)
-		assert.Equal(t, expectedUsers, users)
+		assert.Equal(t, expectedUsers, page.Users)
 	})
 
 	t.Run("repository error", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
-		userSrv := NewUserService(mockRepo, nil)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), nil, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
 
-		mockRepo.On("Get", mock.Anything, 1, 2).Return(nil, errors.New("some repository error"))
+		mockRepo.On("Get", mock.Anything, &user.ListQuery{Limit: 2}).Return(nil, errors.New("some repository error"))
 
-		users, err := userSrv.Get(context.Background(), 1, 2)
+		users, err := userSrv.Get(adminCtx(), &user.ListQuery{Limit: 2})
 		assert.Error(t, err)
 		assert.Nil(t, users)
 		assert.Contains(t, err.Error(), "some repository error")
 	})
+
+	t.Run("cursor issued for a different order", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), nil, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		cursor := &user.Cursor{Sort: []user.Sort{{Field: user.SortByCreatedAt}}, Values: []string{"2024-01-01T00:00:00Z"}, ID: 1}
+
+		page, err := userSrv.Get(adminCtx(), &user.ListQuery{
+			Sort:   []user.Sort{{Field: user.SortByLastName}},
+			Cursor: cursor,
+			Limit:  2,
+		})
+		assert.ErrorIs(t, err, user.ErrInvalidCursor)
+		assert.Nil(t, page)
+		mockRepo.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	})
+
+	t.Run("only admins list deleted users", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), nil, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		q := &user.ListQuery{Limit: 2, IncludeDeleted: true}
+		mockRepo.On("Get", mock.Anything, q).Return(&user.Page{}, nil)
+
+		_, err := userSrv.Get(adminCtx(), q)
+		assert.NoError(t, err)
+
+		_, err = userSrv.Get(userCtx(domain.NewID(), user.RoleSupport), q)
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		mockRepo.AssertNumberOfCalls(t, "Get", 1)
+	})
+
+	t.Run("regular users can not list users", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), nil, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		users, err := userSrv.Get(userCtx(domain.NewID(), user.RoleSelf), &user.ListQuery{Limit: 2})
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		assert.Nil(t, users)
+	})
 }
 
 func TestGetByUUID(t *testing.T) {
 	t.Run("get by uuid", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
-		userSrv := NewUserService(mockRepo, nil)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), nil, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
 
 		userID := domain.NewID()
 		expectedUser := &user.User{
@@ -350,18 +904,18 @@ func TestGetByUUID(t *testing.T) {
 
 		mockRepo.On("GetByUUID", mock.Anything, userID).Return(expectedUser, nil)
 
-		resultUser, err := userSrv.GetByUUID(context.Background(), userID.String())
+		resultUser, err := userSrv.GetByUUID(adminCtx(), userID.String())
 		assert.NoError(t, err)
 		assert.Equal(t, expectedUser, resultUser)
 	})
 
 	t.Run("error parsing userID", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
-		userSrv := NewUserService(mockRepo, nil)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), nil, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
 
 		invalidUserID := "invalid-uuid"
 
-		resultUser, err := userSrv.GetByUUID(context.Background(), invalidUserID)
+		resultUser, err := userSrv.GetByUUID(adminCtx(), invalidUserID)
 		assert.Error(t, err)
 		assert.Nil(t, resultUser)
 		assert.Contains(t, err.Error(), "failed parsing uuid")
@@ -369,32 +923,169 @@ func TestGetByUUID(t *testing.T) {
 
 	t.Run("user not found", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
-		userSrv := NewUserService(mockRepo, nil)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), nil, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
 
 		userID := domain.NewID()
 
 		mockRepo.On("GetByUUID", mock.Anything, userID).Return(nil, user.ErrNotFound)
 
-		resultUser, err := userSrv.GetByUUID(context.Background(), userID.String())
+		resultUser, err := userSrv.GetByUUID(adminCtx(), userID.String())
 		assert.ErrorIs(t, err, user.ErrNotFound)
 		assert.Nil(t, resultUser)
 	})
 
 	t.Run("repository error", func(t *testing.T) {
 		mockRepo := new(repoMock.UserRepositoryMock)
-		userSrv := NewUserService(mockRepo, nil)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), nil, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
 
 		userID := domain.NewID()
 
 		mockRepo.On("GetByUUID", mock.Anything, userID).Return(nil, errors.New("some repository error"))
 
-		resultUser, err := userSrv.GetByUUID(context.Background(), userID.String())
+		resultUser, err := userSrv.GetByUUID(adminCtx(), userID.String())
 		assert.Error(t, err)
 		assert.Nil(t, resultUser)
 		assert.Contains(t, err.Error(), "some repository error")
 	})
+
+	t.Run("user gets own record", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), nil, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		userID := domain.NewID()
+		expectedUser := &user.User{ID: userID}
+
+		mockRepo.On("GetByUUID", mock.Anything, userID).Return(expectedUser, nil)
+
+		resultUser, err := userSrv.GetByUUID(userCtx(userID, user.RoleSelf), userID.String())
+		assert.NoError(t, err)
+		assert.Equal(t, expectedUser, resultUser)
+	})
+
+	t.Run("user can not get other users", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), nil, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		resultUser, err := userSrv.GetByUUID(userCtx(domain.NewID(), user.RoleSelf), domain.NewID().String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+		assert.Nil(t, resultUser)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		mockRepo := new(repoMock.UserRepositoryMock)
+		userSrv := NewUserService(mockRepo, nil, new(domainMock.UnitOfWorkMock), stubAudit(), stubOutbox(), nil, nil, testPasswordPolicy, stubHasher(), testMFAPolicy)
+
+		resultUser, err := userSrv.GetByUUID(context.Background(), domain.NewID().String())
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+		assert.Nil(t, resultUser)
+	})
+}
+
+var testPasswordPolicy = user.NewPasswordPolicy(8, nil, nil)
+
+var testMFAPolicy = user.NewMFAPolicy(nil)
+
+// serviceMocks are the dependencies services are created with in tests, each test sets up the ones its service uses
+type serviceMocks struct {
+	userRepo      *repoMock.UserRepositoryMock
+	tokenRepo     *repoMock.VerificationRepositoryMock
+	sessionRepo   *repoMock.SessionRepositoryMock
+	lockoutRepo   *repoMock.LockoutRepositoryMock
+	mfaRepo       *repoMock.MFARepositoryMock
+	clientRepo    *repoMock.ClientRepositoryMock
+	codeRepo      *repoMock.AuthorizationCodeRepositoryMock
+	auditRepo     *repoMock.AuditRepositoryMock
+	outbox        *repoMock.OutboxRepositoryMock
+	mailer        *mailMock.MailerMock
+	limiter       *domainMock.RateLimiterMock
+	hasher        *authMock.PasswordHasherMock
+	tokenProvider *authMock.TokenProviderMock
+	cipher        *mfaMock.CipherMock
+	signer        *oidcMock.SignerMock
+	timeProvider  *domainMock.TimeProviderMock
+}
+
+// newServiceMocks creates the mocks with the audit log and the outbox accepting anything and the time fixed at now
+func newServiceMocks(now time.Time) *serviceMocks {
+	m := &serviceMocks{
+		userRepo:      new(repoMock.UserRepositoryMock),
+		tokenRepo:     new(repoMock.VerificationRepositoryMock),
+		sessionRepo:   new(repoMock.SessionRepositoryMock),
+		lockoutRepo:   new(repoMock.LockoutRepositoryMock),
+		mfaRepo:       new(repoMock.MFARepositoryMock),
+		clientRepo:    new(repoMock.ClientRepositoryMock),
+		codeRepo:      new(repoMock.AuthorizationCodeRepositoryMock),
+		auditRepo:     stubAudit(),
+		outbox:        stubOutbox(),
+		mailer:        new(mailMock.MailerMock),
+		limiter:       new(domainMock.RateLimiterMock),
+		hasher:        new(authMock.PasswordHasherMock),
+		tokenProvider: new(authMock.TokenProviderMock),
+		cipher:        new(mfaMock.CipherMock),
+		signer:        new(oidcMock.SignerMock),
+		timeProvider:  new(domainMock.TimeProviderMock),
+	}
+
+	m.timeProvider.On("UtcNow").Return(now)
+
+	return m
+}
+
+// unlocked makes the lockout repository report no failures for subjects without a state set before
+func (m *serviceMocks) unlocked() {
+	m.lockoutRepo.On("Get", mock.Anything, mock.Anything).Return(&lockout.State{}, nil)
+}
+
+// enabled sets up a confirmed factor of the user with testSecret
+func (m *serviceMocks) enabled(userID domain.ID, confirmedAt time.Time) {
+	m.mfaRepo.On("Get", mock.Anything, userID).Return(&mfa.Factor{
+		UserID:          userID,
+		EncryptedSecret: "encrypted",
+		ConfirmedAt:     &confirmedAt,
+	}, nil)
+	m.cipher.On("Decrypt", "encrypted").Return(testSecret, nil)
+}
+
+// stubHasher hashes every password into the same value
+func stubHasher() *authMock.PasswordHasherMock {
+	m := new(authMock.PasswordHasherMock)
+	m.On("Hash", mock.Anything).Return("hash", nil)
+
+	return m
+}
+
+// stubClock is a time provider for tests not asserting on time
+func stubClock() *domainMock.TimeProviderMock {
+	m := new(domainMock.TimeProviderMock)
+	m.On("UtcNow").Return(time.Now())
+
+	return m
+}
+
+// stubAudit accepts any audit entry
+func stubAudit() *repoMock.AuditRepositoryMock {
+	m := new(repoMock.AuditRepositoryMock)
+	m.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	return m
+}
+
+// stubOutbox accepts any event
+func stubOutbox() *repoMock.OutboxRepositoryMock {
+	m := new(repoMock.OutboxRepositoryMock)
+	m.On("Add", mock.Anything, mock.Anything).Return(nil)
+
+	return m
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func adminCtx() context.Context {
+	return auth.WithIdentity(context.Background(), auth.Identity{Client: "test", Role: user.RoleAdmin})
 }
 
-func ptr(s string) *string {
-	return &s
+func userCtx(id domain.ID, role user.Role) context.Context {
+	return auth.WithIdentity(context.Background(), auth.Identity{UserID: id, Role: role})
 }
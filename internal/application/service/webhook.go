@@ -0,0 +1,425 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type WebhookPort interface {
+	Create(ctx context.Context, req *WebhookRequest) (*webhook.Subscription, string, error)
+	Get(ctx context.Context, subscriptionID string) (*webhook.Subscription, error)
+	List(ctx context.Context) ([]*webhook.Subscription, error)
+	Update(ctx context.Context, subscriptionID string, req *WebhookRequest) (*webhook.Subscription, error)
+	Delete(ctx context.Context, subscriptionID string) error
+	ListDeliveries(ctx context.Context, q *webhook.DeliveryQuery) (*webhook.DeliveryPage, error)
+	Redeliver(ctx context.Context, subscriptionID, deliveryID string) error
+}
+
+// WebhookRequest describes a subscription, an empty secret is generated on creation and left unchanged on update
+type WebhookRequest struct {
+	URL        string
+	EventTypes []string
+	Secret     string
+}
+
+type webhookService struct {
+	subscriptionRepo webhook.SubscriptionRepository
+	deliveryRepo     webhook.DeliveryRepository
+	uow              domain.UnitOfWork
+	cipher           webhook.Cipher
+	timeProvider     domain.TimeProvider
+
+	// allowPrivateNetworks lets subscriptions point at loopback and private addresses, for local setups only
+	allowPrivateNetworks bool
+}
+
+var _ WebhookPort = (*webhookService)(nil)
+
+func NewWebhookService(
+	subscriptionRepo webhook.SubscriptionRepository,
+	deliveryRepo webhook.DeliveryRepository,
+	uow domain.UnitOfWork,
+	cipher webhook.Cipher,
+	timeProvider domain.TimeProvider,
+	allowPrivateNetworks bool,
+) *webhookService {
+	return &webhookService{
+		subscriptionRepo:     subscriptionRepo,
+		deliveryRepo:         deliveryRepo,
+		uow:                  uow,
+		cipher:               cipher,
+		timeProvider:         timeProvider,
+		allowPrivateNetworks: allowPrivateNetworks,
+	}
+}
+
+// Create stores a new subscription, the returned secret is generated when none has been chosen and shown once
+func (s *webhookService) Create(ctx context.Context, req *WebhookRequest) (*webhook.Subscription, string, error) {
+	if err := authorize(ctx, user.PermissionManageWebhooks, domain.ID{}); err != nil {
+		return nil, "", err
+	}
+
+	sub := &webhook.Subscription{ID: domain.NewID()}
+
+	secret, err := s.apply(sub, req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sub.CreatedAt = s.timeProvider.UtcNow()
+	sub.UpdatedAt = sub.CreatedAt
+
+	if err = s.subscriptionRepo.Create(ctx, sub); err != nil {
+		err = fmt.Errorf("failed creating subscription: %w", err)
+		logger.Debug(err)
+
+		return nil, "", err
+	}
+
+	logger.Info(fmt.Sprintf("webhook subscription %s created by %s", sub.ID, actor(ctx)))
+
+	return sub, secret, nil
+}
+
+func (s *webhookService) Get(ctx context.Context, subscriptionID string) (*webhook.Subscription, error) {
+	id, err := domain.ParseID(subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionManageWebhooks, domain.ID{}); err != nil {
+		return nil, err
+	}
+
+	return s.get(ctx, id)
+}
+
+func (s *webhookService) List(ctx context.Context) ([]*webhook.Subscription, error) {
+	if err := authorize(ctx, user.PermissionManageWebhooks, domain.ID{}); err != nil {
+		return nil, err
+	}
+
+	subs, err := s.subscriptionRepo.List(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed listing subscriptions: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+// Update replaces the URL and the event types, and the secret when a new one is given
+func (s *webhookService) Update(ctx context.Context, subscriptionID string, req *WebhookRequest) (*webhook.Subscription, error) {
+	id, err := domain.ParseID(subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionManageWebhooks, domain.ID{}); err != nil {
+		return nil, err
+	}
+
+	sub, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.apply(sub, req); err != nil {
+		return nil, err
+	}
+
+	sub.UpdatedAt = s.timeProvider.UtcNow()
+
+	if err = s.subscriptionRepo.Update(ctx, sub); err != nil {
+		if errors.Is(err, webhook.ErrSubscriptionNotFound) {
+			return nil, err
+		}
+
+		err = fmt.Errorf("failed updating subscription: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	logger.Info(fmt.Sprintf("webhook subscription %s updated by %s", sub.ID, actor(ctx)))
+
+	return sub, nil
+}
+
+// Delete removes the subscription, its pending deliveries are not sent anymore
+func (s *webhookService) Delete(ctx context.Context, subscriptionID string) error {
+	id, err := domain.ParseID(subscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionManageWebhooks, domain.ID{}); err != nil {
+		return err
+	}
+
+	if err = s.subscriptionRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, webhook.ErrSubscriptionNotFound) {
+			return err
+		}
+
+		err = fmt.Errorf("failed deleting subscription: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("webhook subscription %s deleted by %s", id, actor(ctx)))
+
+	return nil
+}
+
+// ListDeliveries returns the delivery history of the subscription, the newest first
+func (s *webhookService) ListDeliveries(ctx context.Context, q *webhook.DeliveryQuery) (*webhook.DeliveryPage, error) {
+	if err := authorize(ctx, user.PermissionManageWebhooks, domain.ID{}); err != nil {
+		return nil, err
+	}
+
+	// an empty page would not tell an unknown subscription apart from one without deliveries
+	if _, err := s.get(ctx, q.SubscriptionID); err != nil {
+		return nil, err
+	}
+
+	page, err := s.deliveryRepo.List(ctx, q)
+	if err != nil {
+		err = fmt.Errorf("failed listing deliveries: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// Redeliver gives a dead delivery another round of attempts, e.g. once the subscriber is fixed
+func (s *webhookService) Redeliver(ctx context.Context, subscriptionID, deliveryID string) error {
+	subID, err := domain.ParseID(subscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	id, err := domain.ParseID(deliveryID)
+	if err != nil {
+		return fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = authorize(ctx, user.PermissionManageWebhooks, domain.ID{}); err != nil {
+		return err
+	}
+
+	err = s.uow.WithinTx(ctx, func(ctx context.Context) error {
+		d, err := s.deliveryRepo.Get(ctx, subID, id)
+		if err != nil {
+			return err
+		}
+
+		if err = d.Redeliver(s.timeProvider.UtcNow()); err != nil {
+			return err
+		}
+
+		return s.deliveryRepo.Update(ctx, d)
+	})
+	if err != nil {
+		if errors.Is(err, webhook.ErrDeliveryNotFound) || errors.Is(err, webhook.ErrNotDead) {
+			return err
+		}
+
+		err = fmt.Errorf("failed redelivering: %w", err)
+		logger.Debug(err)
+
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("webhook delivery %s redelivered by %s", id, actor(ctx)))
+
+	return nil
+}
+
+func (s *webhookService) get(ctx context.Context, id domain.ID) (*webhook.Subscription, error) {
+	sub, err := s.subscriptionRepo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, webhook.ErrSubscriptionNotFound) {
+			return nil, err
+		}
+
+		err = fmt.Errorf("failed fetching subscription: %w", err)
+		logger.Debug(err)
+
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// apply validates the request and sets it on the subscription, it returns the secret generated for a new subscription
+func (s *webhookService) apply(sub *webhook.Subscription, req *WebhookRequest) (string, error) {
+	if err := webhook.ValidateURL(req.URL, s.allowPrivateNetworks); err != nil {
+		return "", err
+	}
+
+	types, err := webhook.ParseEventTypes(req.EventTypes)
+	if err != nil {
+		return "", err
+	}
+
+	secret := req.Secret
+
+	switch {
+	case secret != "":
+		if err = webhook.ValidateSecret(secret); err != nil {
+			return "", err
+		}
+	case sub.EncryptedSecret == "":
+		if secret, err = webhook.NewSecret(); err != nil {
+			return "", err
+		}
+	}
+
+	if secret != "" {
+		if sub.EncryptedSecret, err = s.cipher.Encrypt([]byte(secret)); err != nil {
+			err = fmt.Errorf("failed encrypting secret: %w", err)
+			logger.Debug(err)
+
+			return "", err
+		}
+	}
+
+	sub.URL = req.URL
+	sub.EventTypes = types
+
+	return secret, nil
+}
+
+// WebhookDispatcherPort turns published events into deliveries and sends them in the background
+type WebhookDispatcherPort interface {
+	event.Publisher
+
+	// DeliverDue attempts a batch of due deliveries, more tells the batch has been full, so more deliveries may be due
+	DeliverDue(ctx context.Context) (more bool, err error)
+}
+
+type webhookDispatcher struct {
+	subscriptionRepo webhook.SubscriptionRepository
+	deliveryRepo     webhook.DeliveryRepository
+	cipher           webhook.Cipher
+	sender           webhook.Sender
+	policy           *webhook.RetryPolicy
+	timeProvider     domain.TimeProvider
+	batchSize        int
+	lease            time.Duration
+}
+
+var _ WebhookDispatcherPort = (*webhookDispatcher)(nil)
+
+// NewWebhookDispatcher creates a dispatcher claiming batches of due deliveries for the lease,
+// which must be longer than sending a whole batch may take, or the deliveries left are sent by another dispatcher too.
+func NewWebhookDispatcher(
+	subscriptionRepo webhook.SubscriptionRepository,
+	deliveryRepo webhook.DeliveryRepository,
+	cipher webhook.Cipher,
+	sender webhook.Sender,
+	policy *webhook.RetryPolicy,
+	timeProvider domain.TimeProvider,
+	batchSize int,
+	lease time.Duration,
+) *webhookDispatcher {
+	return &webhookDispatcher{
+		subscriptionRepo: subscriptionRepo,
+		deliveryRepo:     deliveryRepo,
+		cipher:           cipher,
+		sender:           sender,
+		policy:           policy,
+		timeProvider:     timeProvider,
+		batchSize:        batchSize,
+		lease:            lease,
+	}
+}
+
+// Publish creates a delivery of the event for every subscription of its type, the deliveries are sent later by DeliverDue.
+// It is called by the outbox relay outside of a transaction, an event published again gets its deliveries created again.
+func (d *webhookDispatcher) Publish(ctx context.Context, e *event.Event) error {
+	subs, err := d.subscriptionRepo.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed listing subscriptions: %w", err)
+	}
+
+	now := d.timeProvider.UtcNow()
+
+	for _, sub := range subs {
+		if !sub.Matches(e.Type) {
+			continue
+		}
+
+		if err = d.deliveryRepo.Create(ctx, webhook.NewDelivery(sub.ID, e, now)); err != nil {
+			return fmt.Errorf("failed creating delivery: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeliverDue sends the due deliveries one by one, a failed one is scheduled for a retry or dead when it has no attempts left.
+// The deliveries are claimed in a transaction of their own and sent outside of any, each outcome is recorded on its own.
+// A delivery is sent at least once, it is sent again once the lease ends when recording the outcome fails.
+func (d *webhookDispatcher) DeliverDue(ctx context.Context) (more bool, err error) {
+	now := d.timeProvider.UtcNow()
+
+	deliveries, err := d.deliveryRepo.Claim(ctx, now, now.Add(d.lease), d.batchSize)
+	if err != nil {
+		return false, fmt.Errorf("failed claiming deliveries: %w", err)
+	}
+
+	subs := make(map[domain.ID]*webhook.Subscription)
+
+	for _, delivery := range deliveries {
+		sub, ok := subs[delivery.SubscriptionID]
+		if !ok {
+			if sub, err = d.subscriptionRepo.Get(ctx, delivery.SubscriptionID); err != nil {
+				// deleted since the claim, together with its deliveries
+				if errors.Is(err, webhook.ErrSubscriptionNotFound) {
+					continue
+				}
+
+				return false, fmt.Errorf("failed delivering webhooks: %w", err)
+			}
+			subs[sub.ID] = sub
+		}
+
+		if err = d.deliver(ctx, sub, delivery); err != nil {
+			return false, fmt.Errorf("failed delivering webhooks: %w", err)
+		}
+	}
+
+	return len(deliveries) == d.batchSize, nil
+}
+
+func (d *webhookDispatcher) deliver(ctx context.Context, sub *webhook.Subscription, delivery *webhook.Delivery) error {
+	secret, err := d.cipher.Decrypt(sub.EncryptedSecret)
+	if err != nil {
+		return fmt.Errorf("failed decrypting secret: %w", err)
+	}
+
+	statusCode, sendErr := d.sender.Send(ctx, sub.URL, secret, delivery)
+	if sendErr != nil {
+		logger.Debug(fmt.Errorf("failed sending delivery %s: %w", delivery.ID, sendErr))
+		delivery.Failed(d.timeProvider.UtcNow(), statusCode, sendErr.Error(), d.policy)
+	} else {
+		delivery.Succeeded(d.timeProvider.UtcNow(), statusCode)
+	}
+
+	return d.deliveryRepo.Update(ctx, delivery)
+}
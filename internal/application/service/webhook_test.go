@@ -0,0 +1,456 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	domainMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain"
+	webhookMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain/webhook"
+	repoMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/infrastructure/database/mysql"
+)
+
+// stubWebhookCipher encrypts any secret
+func stubWebhookCipher() *webhookMock.CipherMock {
+	m := new(webhookMock.CipherMock)
+	m.On("Encrypt", mock.Anything).Return("encrypted", nil)
+
+	return m
+}
+
+func TestCreateWebhook(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		req          *WebhookRequest
+		allowPrivate bool
+		secret       string // the expected one, empty when it is generated
+		err          error
+	}{
+		{
+			name: "generated secret",
+			ctx:  adminCtx(),
+			req:  &WebhookRequest{URL: "https://crm.example.com/hooks", EventTypes: []string{"user.created"}},
+		},
+		{
+			name:   "chosen secret",
+			ctx:    adminCtx(),
+			req:    &WebhookRequest{URL: "https://crm.example.com/hooks", EventTypes: []string{"user.updated"}, Secret: "0123456789abcdef"},
+			secret: "0123456789abcdef",
+		},
+		{
+			name:         "localhost when private networks are allowed",
+			ctx:          adminCtx(),
+			req:          &WebhookRequest{URL: "http://localhost:9000", EventTypes: []string{"user.created"}},
+			allowPrivate: true,
+		},
+		{
+			name: "localhost",
+			ctx:  adminCtx(),
+			req:  &WebhookRequest{URL: "http://localhost:9000", EventTypes: []string{"user.created"}},
+			err:  webhook.ErrPrivateURL,
+		},
+		{
+			name: "loopback address",
+			ctx:  adminCtx(),
+			req:  &WebhookRequest{URL: "http://127.0.0.1:9000", EventTypes: []string{"user.created"}},
+			err:  webhook.ErrPrivateURL,
+		},
+		{
+			name: "link-local address",
+			ctx:  adminCtx(),
+			req:  &WebhookRequest{URL: "http://169.254.169.254/latest/meta-data", EventTypes: []string{"user.created"}},
+			err:  webhook.ErrPrivateURL,
+		},
+		{
+			name: "private address",
+			ctx:  adminCtx(),
+			req:  &WebhookRequest{URL: "https://10.0.0.5/hooks", EventTypes: []string{"user.created"}},
+			err:  webhook.ErrPrivateURL,
+		},
+		{
+			name: "ipv4-mapped ipv6 loopback",
+			ctx:  adminCtx(),
+			req:  &WebhookRequest{URL: "http://[::ffff:127.0.0.1]:9000", EventTypes: []string{"user.created"}},
+			err:  webhook.ErrPrivateURL,
+		},
+		{
+			name: "unspecified address",
+			ctx:  adminCtx(),
+			req:  &WebhookRequest{URL: "http://0.0.0.0:9000", EventTypes: []string{"user.created"}},
+			err:  webhook.ErrPrivateURL,
+		},
+		{
+			name: "relative url",
+			ctx:  adminCtx(),
+			req:  &WebhookRequest{URL: "/hooks", EventTypes: []string{"user.created"}},
+			err:  webhook.ErrInvalidURL,
+		},
+		{
+			name: "unsupported scheme",
+			ctx:  adminCtx(),
+			req:  &WebhookRequest{URL: "ftp://crm.example.com", EventTypes: []string{"user.created"}},
+			err:  webhook.ErrInvalidURL,
+		},
+		{
+			name: "unknown event type",
+			ctx:  adminCtx(),
+			req:  &WebhookRequest{URL: "https://crm.example.com/hooks", EventTypes: []string{"user.archived"}},
+			err:  event.ErrInvalidType,
+		},
+		{
+			name: "no event types",
+			ctx:  adminCtx(),
+			req:  &WebhookRequest{URL: "https://crm.example.com/hooks"},
+			err:  webhook.ErrNoEventTypes,
+		},
+		{
+			name: "short secret",
+			ctx:  adminCtx(),
+			req:  &WebhookRequest{URL: "https://crm.example.com/hooks", EventTypes: []string{"user.created"}, Secret: "secret"},
+			err:  webhook.ErrInvalidSecret,
+		},
+		{
+			name: "support",
+			ctx:  userCtx(domain.NewID(), user.RoleSupport),
+			req:  &WebhookRequest{URL: "https://crm.example.com/hooks", EventTypes: []string{"user.created"}},
+			err:  auth.ErrForbidden,
+		},
+		{
+			name: "anonymous",
+			ctx:  context.Background(),
+			req:  &WebhookRequest{URL: "https://crm.example.com/hooks", EventTypes: []string{"user.created"}},
+			err:  auth.ErrUnauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSubs := new(repoMock.SubscriptionRepositoryMock)
+			mockSubs.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+			mockCipher := stubWebhookCipher()
+
+			s := NewWebhookService(mockSubs, new(repoMock.DeliveryRepositoryMock), new(domainMock.UnitOfWorkMock), mockCipher, stubClock(), tt.allowPrivate)
+
+			sub, secret, err := s.Create(tt.ctx, tt.req)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				mockSubs.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.req.URL, sub.URL)
+			assert.Equal(t, "encrypted", sub.EncryptedSecret)
+			mockSubs.AssertCalled(t, "Create", mock.Anything, sub)
+			mockCipher.AssertCalled(t, "Encrypt", []byte(secret))
+
+			if tt.secret != "" {
+				assert.Equal(t, tt.secret, secret)
+			} else {
+				assert.GreaterOrEqual(t, len(secret), webhook.MinSecretLength)
+			}
+		})
+	}
+
+	t.Run("event types are deduplicated", func(t *testing.T) {
+		mockSubs := new(repoMock.SubscriptionRepositoryMock)
+		mockSubs.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		s := NewWebhookService(mockSubs, new(repoMock.DeliveryRepositoryMock), new(domainMock.UnitOfWorkMock), stubWebhookCipher(), stubClock(), false)
+
+		sub, _, err := s.Create(adminCtx(), &WebhookRequest{
+			URL:        "https://crm.example.com/hooks",
+			EventTypes: []string{"user.created", "user.created", "user.deleted"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []event.Type{event.TypeUserCreated, event.TypeUserDeleted}, sub.EventTypes)
+	})
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	stored := func() *webhook.Subscription {
+		return &webhook.Subscription{
+			ID:              domain.NewID(),
+			URL:             "https://crm.example.com/hooks",
+			EventTypes:      []event.Type{event.TypeUserCreated},
+			EncryptedSecret: "current",
+		}
+	}
+
+	t.Run("secret is kept", func(t *testing.T) {
+		sub := stored()
+
+		mockSubs := new(repoMock.SubscriptionRepositoryMock)
+		mockSubs.On("Get", mock.Anything, sub.ID).Return(sub, nil)
+		mockSubs.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		s := NewWebhookService(mockSubs, new(repoMock.DeliveryRepositoryMock), new(domainMock.UnitOfWorkMock), stubWebhookCipher(), stubClock(), false)
+
+		updated, err := s.Update(adminCtx(), sub.ID.String(), &WebhookRequest{URL: "https://crm.example.com/v2", EventTypes: []string{"user.updated"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "https://crm.example.com/v2", updated.URL)
+		assert.Equal(t, []event.Type{event.TypeUserUpdated}, updated.EventTypes)
+		assert.Equal(t, "current", updated.EncryptedSecret)
+		mockSubs.AssertCalled(t, "Update", mock.Anything, updated)
+	})
+
+	t.Run("secret is rotated", func(t *testing.T) {
+		sub := stored()
+
+		mockSubs := new(repoMock.SubscriptionRepositoryMock)
+		mockSubs.On("Get", mock.Anything, sub.ID).Return(sub, nil)
+		mockSubs.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		mockCipher := new(webhookMock.CipherMock)
+		mockCipher.On("Encrypt", []byte("a brand new secret")).Return("rotated", nil)
+
+		s := NewWebhookService(mockSubs, new(repoMock.DeliveryRepositoryMock), new(domainMock.UnitOfWorkMock), mockCipher, stubClock(), false)
+
+		updated, err := s.Update(adminCtx(), sub.ID.String(), &WebhookRequest{URL: sub.URL, EventTypes: []string{"user.created"}, Secret: "a brand new secret"})
+		assert.NoError(t, err)
+		assert.Equal(t, "rotated", updated.EncryptedSecret)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockSubs := new(repoMock.SubscriptionRepositoryMock)
+		mockSubs.On("Get", mock.Anything, mock.Anything).Return(nil, webhook.ErrSubscriptionNotFound)
+
+		s := NewWebhookService(mockSubs, new(repoMock.DeliveryRepositoryMock), new(domainMock.UnitOfWorkMock), stubWebhookCipher(), stubClock(), false)
+
+		_, err := s.Update(adminCtx(), domain.NewID().String(), &WebhookRequest{URL: "https://crm.example.com", EventTypes: []string{"user.created"}})
+		assert.ErrorIs(t, err, webhook.ErrSubscriptionNotFound)
+		mockSubs.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRedeliver(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	clock := new(domainMock.TimeProviderMock)
+	clock.On("UtcNow").Return(now)
+
+	newDelivery := func(status webhook.Status) *webhook.Delivery {
+		return &webhook.Delivery{ID: domain.NewID(), SubscriptionID: domain.NewID(), Status: status, Attempts: 5}
+	}
+
+	t.Run("dead delivery", func(t *testing.T) {
+		d := newDelivery(webhook.StatusDead)
+
+		mockDeliveries := new(repoMock.DeliveryRepositoryMock)
+		mockDeliveries.On("Get", mock.Anything, d.SubscriptionID, d.ID).Return(d, nil)
+		mockDeliveries.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		s := NewWebhookService(new(repoMock.SubscriptionRepositoryMock), mockDeliveries, new(domainMock.UnitOfWorkMock), stubWebhookCipher(), clock, false)
+
+		assert.NoError(t, s.Redeliver(adminCtx(), d.SubscriptionID.String(), d.ID.String()))
+		mockDeliveries.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(d *webhook.Delivery) bool {
+			return d.Status == webhook.StatusPending && d.Attempts == 0 && d.NextAttemptAt.Equal(now)
+		}))
+	})
+
+	t.Run("delivery which is not dead", func(t *testing.T) {
+		d := newDelivery(webhook.StatusSucceeded)
+
+		mockDeliveries := new(repoMock.DeliveryRepositoryMock)
+		mockDeliveries.On("Get", mock.Anything, d.SubscriptionID, d.ID).Return(d, nil)
+
+		s := NewWebhookService(new(repoMock.SubscriptionRepositoryMock), mockDeliveries, new(domainMock.UnitOfWorkMock), stubWebhookCipher(), clock, false)
+
+		assert.ErrorIs(t, s.Redeliver(adminCtx(), d.SubscriptionID.String(), d.ID.String()), webhook.ErrNotDead)
+		mockDeliveries.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		s := NewWebhookService(new(repoMock.SubscriptionRepositoryMock), new(repoMock.DeliveryRepositoryMock), new(domainMock.UnitOfWorkMock), stubWebhookCipher(), clock, false)
+
+		err := s.Redeliver(userCtx(domain.NewID(), user.RoleSelf), domain.NewID().String(), domain.NewID().String())
+		assert.ErrorIs(t, err, auth.ErrForbidden)
+	})
+}
+
+func TestListDeliveries(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	t.Run("unknown subscription", func(t *testing.T) {
+		mockSubs := new(repoMock.SubscriptionRepositoryMock)
+		mockSubs.On("Get", mock.Anything, mock.Anything).Return(nil, webhook.ErrSubscriptionNotFound)
+
+		mockDeliveries := new(repoMock.DeliveryRepositoryMock)
+
+		s := NewWebhookService(mockSubs, mockDeliveries, new(domainMock.UnitOfWorkMock), stubWebhookCipher(), stubClock(), false)
+
+		_, err := s.ListDeliveries(adminCtx(), &webhook.DeliveryQuery{SubscriptionID: domain.NewID(), Limit: 10})
+		assert.ErrorIs(t, err, webhook.ErrSubscriptionNotFound)
+		mockDeliveries.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+	})
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	policy := &webhook.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+
+	clock := new(domainMock.TimeProviderMock)
+	clock.On("UtcNow").Return(now)
+
+	t.Run("events are delivered to matching subscriptions", func(t *testing.T) {
+		created := &webhook.Subscription{ID: domain.NewID(), EventTypes: []event.Type{event.TypeUserCreated}}
+		deleted := &webhook.Subscription{ID: domain.NewID(), EventTypes: []event.Type{event.TypeUserDeleted, event.TypeUserCreated}}
+		updated := &webhook.Subscription{ID: domain.NewID(), EventTypes: []event.Type{event.TypeUserUpdated}}
+
+		mockSubs := new(repoMock.SubscriptionRepositoryMock)
+		mockSubs.On("List", mock.Anything).Return([]*webhook.Subscription{created, deleted, updated}, nil)
+
+		mockDeliveries := new(repoMock.DeliveryRepositoryMock)
+		mockDeliveries.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		e, err := event.New(domain.NewID(), event.UserCreated{}, now)
+		assert.NoError(t, err)
+
+		d := NewWebhookDispatcher(mockSubs, mockDeliveries, new(webhookMock.CipherMock), new(webhookMock.SenderMock), policy, clock, 10, time.Minute)
+		assert.NoError(t, d.Publish(context.Background(), e))
+
+		mockDeliveries.AssertNumberOfCalls(t, "Create", 2)
+		for _, sub := range []*webhook.Subscription{created, deleted} {
+			mockDeliveries.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(d *webhook.Delivery) bool {
+				return d.SubscriptionID == sub.ID && d.Event == e && d.Status == webhook.StatusPending && d.NextAttemptAt.Equal(now)
+			}))
+		}
+	})
+
+	t.Run("due deliveries are sent", func(t *testing.T) {
+		sub := &webhook.Subscription{ID: domain.NewID(), URL: "https://crm.example.com/hooks", EncryptedSecret: "enc"}
+
+		e, err := event.New(domain.NewID(), event.UserDeleted{}, now)
+		assert.NoError(t, err)
+
+		succeeding := webhook.NewDelivery(sub.ID, e, now)
+		failing := webhook.NewDelivery(sub.ID, e, now)
+		dying := webhook.NewDelivery(sub.ID, e, now)
+		dying.Attempts = 2
+
+		mockSubs := new(repoMock.SubscriptionRepositoryMock)
+		mockSubs.On("Get", mock.Anything, sub.ID).Return(sub, nil)
+
+		mockDeliveries := new(repoMock.DeliveryRepositoryMock)
+		mockDeliveries.On("Claim", mock.Anything, now, now.Add(time.Minute), 3).Return([]*webhook.Delivery{succeeding, failing, dying}, nil)
+		mockDeliveries.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		mockCipher := new(webhookMock.CipherMock)
+		mockCipher.On("Decrypt", "enc").Return([]byte("secret"), nil)
+
+		mockSender := new(webhookMock.SenderMock)
+		mockSender.On("Send", mock.Anything, sub.URL, []byte("secret"), succeeding).Return(200, nil)
+		mockSender.On("Send", mock.Anything, sub.URL, []byte("secret"), failing).Return(503, errors.New("webhook responded with 503"))
+		mockSender.On("Send", mock.Anything, sub.URL, []byte("secret"), dying).Return(0, errors.New("connection refused"))
+
+		d := NewWebhookDispatcher(mockSubs, mockDeliveries, mockCipher, mockSender, policy, clock, 3, time.Minute)
+
+		more, err := d.DeliverDue(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, more)
+
+		assert.Equal(t, webhook.StatusSucceeded, succeeding.Status)
+		assert.Nil(t, succeeding.NextAttemptAt)
+
+		assert.Equal(t, webhook.StatusPending, failing.Status)
+		assert.Equal(t, 1, failing.Attempts)
+		assert.Equal(t, 503, failing.LastStatusCode)
+		assert.Equal(t, "webhook responded with 503", failing.LastError)
+		assert.Equal(t, now.Add(time.Minute), *failing.NextAttemptAt)
+
+		assert.Equal(t, webhook.StatusDead, dying.Status)
+		assert.Equal(t, 3, dying.Attempts)
+		assert.Nil(t, dying.NextAttemptAt)
+
+		mockDeliveries.AssertNumberOfCalls(t, "Update", 3)
+
+		// the subscription is fetched once per batch
+		mockSubs.AssertNumberOfCalls(t, "Get", 1)
+	})
+
+	t.Run("deliveries of a deleted subscription are skipped", func(t *testing.T) {
+		deleted, sub := domain.NewID(), &webhook.Subscription{ID: domain.NewID(), URL: "https://crm.example.com/hooks", EncryptedSecret: "enc"}
+
+		e, err := event.New(domain.NewID(), event.UserDeleted{}, now)
+		assert.NoError(t, err)
+
+		skipped, sent := webhook.NewDelivery(deleted, e, now), webhook.NewDelivery(sub.ID, e, now)
+
+		mockSubs := new(repoMock.SubscriptionRepositoryMock)
+		mockSubs.On("Get", mock.Anything, deleted).Return(nil, webhook.ErrSubscriptionNotFound)
+		mockSubs.On("Get", mock.Anything, sub.ID).Return(sub, nil)
+
+		mockDeliveries := new(repoMock.DeliveryRepositoryMock)
+		mockDeliveries.On("Claim", mock.Anything, now, now.Add(time.Minute), 10).Return([]*webhook.Delivery{skipped, sent}, nil)
+		mockDeliveries.On("Update", mock.Anything, sent).Return(nil)
+
+		mockCipher := new(webhookMock.CipherMock)
+		mockCipher.On("Decrypt", "enc").Return([]byte("secret"), nil)
+
+		mockSender := new(webhookMock.SenderMock)
+		mockSender.On("Send", mock.Anything, sub.URL, []byte("secret"), sent).Return(200, nil)
+
+		d := NewWebhookDispatcher(mockSubs, mockDeliveries, mockCipher, mockSender, policy, clock, 10, time.Minute)
+
+		more, err := d.DeliverDue(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, more)
+		mockSender.AssertNumberOfCalls(t, "Send", 1)
+		mockDeliveries.AssertNumberOfCalls(t, "Update", 1)
+	})
+
+	t.Run("nothing due", func(t *testing.T) {
+		mockDeliveries := new(repoMock.DeliveryRepositoryMock)
+		mockDeliveries.On("Claim", mock.Anything, now, now.Add(time.Minute), 10).Return(nil, nil)
+
+		d := NewWebhookDispatcher(new(repoMock.SubscriptionRepositoryMock), mockDeliveries, new(webhookMock.CipherMock), new(webhookMock.SenderMock), policy, clock, 10, time.Minute)
+
+		more, err := d.DeliverDue(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, more)
+	})
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := &webhook.RetryPolicy{MaxAttempts: 5, BaseDelay: time.Minute, MaxDelay: 5 * time.Minute}
+
+	for attempts, expected := range []time.Duration{1: time.Minute, 2: 2 * time.Minute, 3: 4 * time.Minute, 4: 5 * time.Minute} {
+		if attempts == 0 {
+			continue
+		}
+
+		delay, ok := policy.Delay(attempts)
+		assert.True(t, ok)
+		assert.Equal(t, expected, delay, "after %d attempts", attempts)
+	}
+
+	_, ok := policy.Delay(5)
+	assert.False(t, ok)
+}
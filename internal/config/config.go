@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/spf13/viper"
@@ -29,6 +30,75 @@ func defaultLoadConfig() *Config {
 	v.SetDefault("LOG_LEVEL", "debug")
 	v.SetDefault("GIN_MODE", "release")
 
+	v.SetDefault("REPOSITORY_DRIVER", "mysql") // mysql|memory
+	v.SetDefault("PAGINATION_MAX_SIZE", 100)
+	v.SetDefault("HTTP_REQUIRE_IF_MATCH", "false") // true rejects PUT, PATCH and DELETE /users/:id without If-Match
+	v.SetDefault("HTTP_TRUSTED_PROXIES", "")       // comma separated addresses or CIDRs allowed to set X-Forwarded-For
+
+	v.SetDefault("AUTH_TOKEN_SECRET", "change-me") // non production approach
+	v.SetDefault("AUTH_TOKEN_ISSUER", "usermanagement")
+	v.SetDefault("AUTH_TOKEN_TTL_MINUTES", 15)
+	v.SetDefault("AUTH_REFRESH_TOKEN_TTL_HOURS", 720)
+	v.SetDefault("AUTH_API_KEYS", "") // comma separated list of name:key pairs
+	v.SetDefault("AUTH_PROTECTED_ROUTES", "GET /users,GET /users/:id,PUT /users/:id,PATCH /users/:id,DELETE /users/:id,POST /users/:id/restore,* /users/:id/addresses,* /users/:id/addresses/:type,POST /users/:id/email-change,POST /users/:id/password,* /users/:id/lockout,DELETE /lockouts/ip/:ip,* /users/:id/mfa,POST /users/:id/mfa/confirm,POST /users/:id/mfa/disable,POST /users/:id/mfa/recovery-codes,DELETE /users/:id/sessions,POST /oauth/clients,DELETE /oauth/clients/:id,GET /users/:id/audit,GET /audit,GET /users/:id/export,POST /users/:id/erase,* /webhooks,* /webhooks/:id,GET /webhooks/:id/deliveries,POST /webhooks/:id/deliveries/:delivery_id/redeliver")
+
+	v.SetDefault("EMAIL_VERIFICATION_TTL_MINUTES", 1440)
+	v.SetDefault("PASSWORD_RESET_TTL_MINUTES", 30)
+	v.SetDefault("PASSWORD_RESET_RATE_LIMIT", 3) // reset requests per email within the window, counted per instance
+	v.SetDefault("PASSWORD_RESET_RATE_WINDOW_MINUTES", 60)
+	v.SetDefault("PASSWORD_RESET_DELAY_MS", 500) // minimum time to answer a reset request, longer than mailing the token takes
+	v.SetDefault("MAILER_DRIVER", "log")         // log|file, both meant for local development only
+	v.SetDefault("MAILER_FILE_PATH", "mail.log")
+	v.SetDefault("MAILER_FROM", "no-reply@usermanagement.local")
+
+	v.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	v.SetDefault("PASSWORD_REQUIRED_CLASSES", "")       // comma separated list of lower|upper|digit|symbol
+	v.SetDefault("PASSWORD_DENY_LIST", "")              // comma separated passwords denied on top of the built-in common ones
+	v.SetDefault("PASSWORD_HASH_ALGORITHM", "argon2id") // argon2id|bcrypt, hashes of the other one are replaced on login
+	v.SetDefault("PASSWORD_BCRYPT_COST", 10)
+	v.SetDefault("PASSWORD_ARGON2_MEMORY_KIB", 19456)
+	v.SetDefault("PASSWORD_ARGON2_ITERATIONS", 2)
+	v.SetDefault("PASSWORD_ARGON2_PARALLELISM", 1)
+
+	v.SetDefault("LOCKOUT_USER_THRESHOLD", 5) // consecutive failed logins locking a user out, 0 disables it
+	v.SetDefault("LOCKOUT_IP_THRESHOLD", 20)  // consecutive failed logins locking a client IP out, 0 disables it
+	v.SetDefault("LOCKOUT_BASE_DELAY_SECONDS", 60)
+	v.SetDefault("LOCKOUT_MAX_DELAY_MINUTES", 60)
+	v.SetDefault("LOCKOUT_WINDOW_MINUTES", 1440) // failures older than that are forgotten
+
+	v.SetDefault("MFA_ENCRYPTION_KEY", "abVUH1X/2MiW8/JFXW+R4pBllVzzZk3PzX/GOBbkOwU=") // base64 encoded 32 bytes, non production approach
+	v.SetDefault("MFA_ISSUER", "usermanagement")
+	v.SetDefault("MFA_LOGIN_TTL_MINUTES", 5)
+	v.SetDefault("MFA_REQUIRED_ROLES", "") // comma separated list of roles granted only to users with MFA enabled
+
+	v.SetDefault("OIDC_ISSUER", "http://localhost:8080") // public base URL of the service, the endpoints are advertised under it
+	v.SetDefault("OIDC_CODE_TTL_SECONDS", 60)
+	v.SetDefault("OIDC_ACCESS_TOKEN_TTL_MINUTES", 15)                                       // of the access tokens issued to clients, shorter than the key rotation
+	v.SetDefault("OIDC_SIGNING_ALGORITHM", "RS256")                                         // RS256|EdDSA
+	v.SetDefault("OIDC_KEY_ROTATION_HOURS", 720)                                            // a new signing key is generated after that, the previous one is published for as long
+	v.SetDefault("OIDC_KEY_ENCRYPTION_KEY", "eHqyoHMEEIk60SQ0qNMRCC72wB3v3+vsWxwJpWOpk/I=") // base64 encoded 32 bytes, non production approach
+
+	v.SetDefault("EVENTS_PUBLISHER", "stdout") // stdout|webhook
+	v.SetDefault("EVENTS_WEBHOOK_URL", "")
+	v.SetDefault("EVENTS_WEBHOOK_TIMEOUT_SECONDS", 5)
+	v.SetDefault("EVENTS_RELAY_INTERVAL_SECONDS", 1)
+	v.SetDefault("EVENTS_RELAY_BATCH_SIZE", 100)
+	v.SetDefault("EVENTS_RELAY_LEASE_SECONDS", 600) // longer than publishing a batch may take, i.e. EVENTS_RELAY_BATCH_SIZE times EVENTS_WEBHOOK_TIMEOUT_SECONDS
+
+	v.SetDefault("WEBHOOK_ENCRYPTION_KEY", "DAi/3MC3tAc6FHASpGc6zZ+HvQP3lW2l0/iGFwzdmzA=") // base64 encoded 32 bytes, non production approach
+	v.SetDefault("WEBHOOK_TIMEOUT_SECONDS", 5)
+	v.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8) // a delivery is dead letter after that
+	v.SetDefault("WEBHOOK_RETRY_BASE_SECONDS", 10)
+	v.SetDefault("WEBHOOK_RETRY_MAX_MINUTES", 60)
+	v.SetDefault("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "false") // true lets subscriptions reach loopback and private addresses, for local setups only
+	v.SetDefault("WEBHOOK_DISPATCH_INTERVAL_SECONDS", 1)
+	v.SetDefault("WEBHOOK_DISPATCH_BATCH_SIZE", 50)
+	v.SetDefault("WEBHOOK_DISPATCH_LEASE_SECONDS", 300) // longer than sending a batch may take, i.e. WEBHOOK_DISPATCH_BATCH_SIZE times WEBHOOK_TIMEOUT_SECONDS
+
+	v.SetDefault("USERS_RETENTION_DAYS", 30)         // deleted users are purged after that, until then they can be restored
+	v.SetDefault("USERS_PURGE_INTERVAL_MINUTES", 60) // 0 disables the background purge, e.g. when the purge command is run by cron
+	v.SetDefault("USERS_PURGE_BATCH_SIZE", 100)
+
 	v.SetDefault("DB_READ_USER", "user")     // non production approach
 	v.SetDefault("DB_READ_PASSWORD", "pass") // non production approach
 	v.SetDefault("DB_READ_HOST", "mysql")
@@ -71,3 +141,16 @@ func Load() *Config {
 
 	return defaultConfig
 }
+
+// SplitList splits a comma separated config value, skipping empty elements
+func SplitList(value string) []string {
+	var list []string
+
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			list = append(list, element)
+		}
+	}
+
+	return list
+}
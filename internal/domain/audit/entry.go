@@ -0,0 +1,179 @@
+package audit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+// fieldEmail names the email in entries, it is not a field of change sets as it changes only once verified
+const fieldEmail = "email"
+
+// Redacted replaces the values of secrets, e.g. password hashes, the entry tells only that they have changed
+const Redacted = "[redacted]"
+
+// Erased replaces the values of erased users' personal data, the entries keep telling which fields have changed
+const Erased = "[erased]"
+
+// Action is a kind of change of a user
+type Action string
+
+const (
+	ActionUserCreated     Action = "user.created"
+	ActionUserUpdated     Action = "user.updated"
+	ActionUserDeleted     Action = "user.deleted"
+	ActionUserRestored    Action = "user.restored"
+	ActionUserErased      Action = "user.erased"
+	ActionAddressAdded    Action = "address.added"
+	ActionAddressReplaced Action = "address.replaced"
+	ActionAddressDeleted  Action = "address.deleted"
+	ActionPasswordChanged Action = "password.changed"
+	ActionPasswordReset   Action = "password.reset"
+	ActionEmailVerified   Action = "email.verified"
+)
+
+// ParseAction converts a raw value into a known action
+func ParseAction(value string) (Action, error) {
+	action := Action(value)
+
+	switch action {
+	case ActionUserCreated, ActionUserUpdated, ActionUserDeleted, ActionUserRestored, ActionUserErased,
+		ActionAddressAdded, ActionAddressReplaced, ActionAddressDeleted,
+		ActionPasswordChanged, ActionPasswordReset, ActionEmailVerified:
+		return action, nil
+	default:
+		return "", ErrInvalidAction
+	}
+}
+
+type ActorType string
+
+const (
+	ActorUser   ActorType = "user"
+	ActorClient ActorType = "client"
+
+	// ActorAnonymous is an unauthenticated caller, e.g. someone registering or holding a mailed token
+	ActorAnonymous ActorType = "anonymous"
+)
+
+// Actor is the caller who has made the change
+type Actor struct {
+	Type ActorType `json:"type"`
+	ID   string    `json:"id,omitempty"` // the UUID of a user or the name of an API key client
+}
+
+// Change is the new value of a field, addresses are named after their type, e.g. "addresses.1.city"
+type Change struct {
+	Field string  `json:"field"`
+	Value *string `json:"value"` // nil when the field is cleared or the address is removed
+}
+
+// Entry records who has changed which user and how. The user is referenced by the UUID only,
+// so the entries outlive the user.
+type Entry struct {
+	ID        int64     `json:"-"` // storage id, orders the entries
+	Actor     Actor     `json:"actor"`
+	Action    Action    `json:"action"`
+	UserID    domain.ID `json:"user_id"`
+	Changes   []Change  `json:"changes"`
+	RequestID string    `json:"request_id,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserChanges lists the fields of a created user, the password redacted
+func UserChanges(u *user.User) []Change {
+	changes := []Change{
+		set(fieldEmail, u.Email),
+		set(string(user.FieldPassword), Redacted),
+		set(string(user.FieldFirstName), u.FirstName),
+		set(string(user.FieldLastName), u.LastName),
+		set(string(user.FieldPhoneNumber), u.PhoneNumber),
+		set(string(user.FieldRole), string(u.Role)),
+	}
+
+	for _, addr := range u.Addresses {
+		changes = append(changes, AddressChanges(addr.Type, addr.Changes())...)
+	}
+
+	return changes
+}
+
+// ChangeSetChanges lists the changes of a change set
+func ChangeSetChanges(cs *user.ChangeSet) []Change {
+	changes := FieldChanges(cs.User)
+
+	for _, addr := range cs.Addresses {
+		if addr.Remove {
+			changes = append(changes, AddressRemoved(addr.Type)...)
+			continue
+		}
+
+		changes = append(changes, AddressChanges(addr.Type, addr.Changes)...)
+	}
+
+	return changes
+}
+
+// EmailChanges tells the email has been changed to the verified address
+func EmailChanges(email string) []Change {
+	return []Change{set(fieldEmail, email)}
+}
+
+// FieldChanges lists changes of user fields, passwords redacted
+func FieldChanges(changes []user.Change) []Change {
+	return convert("", changes)
+}
+
+// AddressChanges lists changes of the address of the type
+func AddressChanges(addrType user.AddressType, changes []user.Change) []Change {
+	return convert(addressField(addrType)+".", changes)
+}
+
+// AddressRemoved tells the address of the type has been removed
+func AddressRemoved(addrType user.AddressType) []Change {
+	return []Change{{Field: addressField(addrType)}}
+}
+
+// EraseChanges replaces the values of the changes with Erased. Roles are not personal data and stay,
+// as do cleared and redacted values.
+func EraseChanges(changes []Change) []Change {
+	erased := make([]Change, 0, len(changes))
+
+	for _, c := range changes {
+		if c.Value != nil && c.Field != string(user.FieldRole) && *c.Value != Redacted {
+			c = set(c.Field, Erased)
+		}
+
+		erased = append(erased, c)
+	}
+
+	return erased
+}
+
+func convert(prefix string, changes []user.Change) []Change {
+	converted := make([]Change, 0, len(changes))
+
+	for _, c := range changes {
+		switch {
+		case c.Field == user.FieldPassword:
+			converted = append(converted, set(prefix+string(c.Field), Redacted))
+		case c.Clear:
+			converted = append(converted, Change{Field: prefix + string(c.Field)})
+		default:
+			converted = append(converted, set(prefix+string(c.Field), c.Value))
+		}
+	}
+
+	return converted
+}
+
+func addressField(addrType user.AddressType) string {
+	return fmt.Sprintf("addresses.%d", addrType)
+}
+
+func set(field, value string) Change {
+	return Change{Field: field, Value: &value}
+}
@@ -0,0 +1,8 @@
+package audit
+
+import "errors"
+
+var (
+	ErrInvalidAction = errors.New("invalid action")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"encoding/base64"
+	"strconv"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+// Filter narrows down listed entries, empty fields are ignored
+type Filter struct {
+	UserID  domain.ID
+	ActorID string
+	Action  Action
+	From    *time.Time // inclusive
+	To      *time.Time // exclusive
+}
+
+// Query lists the entries newest first, paginated with a cursor
+type Query struct {
+	Filter Filter
+	Cursor *Cursor // nil for the first page
+	Limit  int
+}
+
+// Page is a single page of listed entries
+type Page struct {
+	Entries    []*Entry
+	NextCursor *Cursor // nil on the last page
+}
+
+// Cursor points at the last entry of a page, the next page starts right after it
+type Cursor struct {
+	ID int64
+}
+
+// Encode returns the cursor as a URL safe token
+func (c *Cursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.ID, 10)))
+}
+
+// DecodeCursor parses a token returned by Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id < 1 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{ID: id}, nil
+}
@@ -0,0 +1,18 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+type Repository interface {
+	// Create stores the entry, it has to be called in the same transaction as the change it records
+	Create(ctx context.Context, e *Entry) error
+
+	List(ctx context.Context, q *Query) (*Page, error)
+
+	// Erase removes the personal data of the user from the entries: the values of the changes of the user
+	// and the client IPs of the entries about or made by the user. The entries themselves stay.
+	Erase(ctx context.Context, userID domain.ID) error
+}
@@ -0,0 +1,23 @@
+package audit
+
+import "context"
+
+// Request describes the request a change has been made in, recorded together with the change
+type Request struct {
+	ID       string
+	ClientIP string
+}
+
+type requestKey struct{}
+
+// WithRequest returns a copy of the context carrying the request
+func WithRequest(ctx context.Context, r Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, r)
+}
+
+// RequestFromContext returns the request, an empty one when the change is not made in a request
+func RequestFromContext(ctx context.Context) Request {
+	r, _ := ctx.Value(requestKey{}).(Request)
+
+	return r
+}
@@ -0,0 +1,13 @@
+package auth
+
+import "errors"
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrInvalidAPIKey      = errors.New("invalid api key")
+	ErrUnauthenticated    = errors.New("missing credentials")
+	ErrForbidden          = errors.New("forbidden")
+	ErrTooManyAttempts    = errors.New("too many attempts")
+	ErrMFARequired        = errors.New("mfa required")
+)
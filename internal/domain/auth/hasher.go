@@ -0,0 +1,10 @@
+package auth
+
+// PasswordHasher hashes passwords with the preferred algorithm and verifies hashes of every supported one
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+
+	// Verify tells whether the password matches the hash and whether the hash should be replaced,
+	// as it uses an algorithm or cost other than the preferred ones
+	Verify(hash, password string) (match bool, rehash bool, err error)
+}
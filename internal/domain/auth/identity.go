@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+// Identity describes the authenticated caller, either a user holding an access token or a client holding an API key
+type Identity struct {
+	UserID domain.ID
+	Client string
+	Role   user.Role
+}
+
+type identityKey struct{}
+
+// IsClient tells whether the caller is a machine client authenticated with an API key rather than a user
+func (i Identity) IsClient() bool {
+	return i.UserID.IsEmpty()
+}
+
+// WithIdentity returns a copy of the context carrying the caller's identity
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the caller's identity, if the request has been authenticated
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+
+	return identity, ok
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+// Token is an access token issued to an authenticated user, together with a refresh token exchanged for the next one.
+// Tokens issued to OpenID Connect clients carry an ID token instead of a refresh token.
+type Token struct {
+	AccessToken  string
+	TokenType    string
+	ExpiresIn    time.Duration
+	RefreshToken string
+	IDToken      string
+}
+
+// MFAChallenge is returned instead of a token to users with MFA enabled, it wraps ErrMFARequired.
+// The challenge token is exchanged together with a code for an access token.
+type MFAChallenge struct {
+	Token     string
+	ExpiresIn time.Duration
+}
+
+func (c *MFAChallenge) Error() string {
+	return ErrMFARequired.Error()
+}
+
+func (c *MFAChallenge) Unwrap() error {
+	return ErrMFARequired
+}
+
+// Claims are the verified contents of an access token
+type Claims struct {
+	Subject   domain.ID
+	Role      user.Role
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// TokenProvider issues and verifies signed access tokens
+type TokenProvider interface {
+	Issue(subject domain.ID, role user.Role) (*Token, error)
+	Verify(accessToken string) (*Claims, error)
+}
@@ -0,0 +1,5 @@
+package event
+
+import "errors"
+
+var ErrInvalidType = errors.New("invalid event type")
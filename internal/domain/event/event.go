@@ -0,0 +1,98 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+// Type is a kind of change other services react to
+type Type string
+
+const (
+	TypeUserCreated  Type = "user.created"
+	TypeUserUpdated  Type = "user.updated"
+	TypeAddressAdded Type = "address.added"
+	TypeUserDeleted  Type = "user.deleted"
+	TypeUserRestored Type = "user.restored"
+	TypeUserErased   Type = "user.erased"
+)
+
+// ParseType converts a raw value into a known type
+func ParseType(value string) (Type, error) {
+	t := Type(value)
+
+	switch t {
+	case TypeUserCreated, TypeUserUpdated, TypeAddressAdded, TypeUserDeleted, TypeUserRestored, TypeUserErased:
+		return t, nil
+	default:
+		return "", ErrInvalidType
+	}
+}
+
+// Event is a change of a user, published at least once, so consumers deduplicate events by the ID
+type Event struct {
+	ID         domain.ID       `json:"id"`
+	Type       Type            `json:"type"`
+	UserID     domain.ID       `json:"user_id"`
+	Data       json.RawMessage `json:"data"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// Payload is the data of an event of a particular type
+type Payload interface {
+	Type() Type
+}
+
+// UserCreated carries the created user, without the password
+type UserCreated struct {
+	User *user.User `json:"user"`
+}
+
+// UserUpdated carries the new values of the changed fields, secrets redacted
+type UserUpdated struct {
+	Changes []audit.Change `json:"changes"`
+}
+
+// AddressAdded carries the address added to the user
+type AddressAdded struct {
+	Address *user.Address `json:"address"`
+}
+
+// UserDeleted tells the user has been deleted
+type UserDeleted struct{}
+
+// UserRestored carries the restored user, the same as UserCreated
+type UserRestored struct {
+	User *user.User `json:"user"`
+}
+
+// UserErased tells the personal data of the user has been erased, consumers are expected to erase their copies
+type UserErased struct{}
+
+func (UserCreated) Type() Type  { return TypeUserCreated }
+func (UserUpdated) Type() Type  { return TypeUserUpdated }
+func (AddressAdded) Type() Type { return TypeAddressAdded }
+func (UserDeleted) Type() Type  { return TypeUserDeleted }
+func (UserRestored) Type() Type { return TypeUserRestored }
+func (UserErased) Type() Type   { return TypeUserErased }
+
+// New creates an event of the payload's type with a new ID
+func New(userID domain.ID, payload Payload, occurredAt time.Time) (*Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed encoding %s event: %w", payload.Type(), err)
+	}
+
+	return &Event{
+		ID:         domain.NewID(),
+		Type:       payload.Type(),
+		UserID:     userID,
+		Data:       data,
+		OccurredAt: occurredAt,
+	}, nil
+}
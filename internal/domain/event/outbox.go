@@ -0,0 +1,30 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+// Outbox keeps the events until they are published. Events are added within the transaction of the change
+// raising them, so an event is published only when its change is committed, and it is never lost when it is.
+type Outbox interface {
+	Add(ctx context.Context, e *Event) error
+
+	// Claim returns the oldest unpublished events and leases them until leaseUntil, so concurrent relays do not publish
+	// the same events. It returns none while any of them is leased, the events are published in order by one relay at a time.
+	// Events neither marked published nor released are claimed again once the lease ends.
+	Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*Event, error)
+	MarkPublished(ctx context.Context, ids []domain.ID, publishedAt time.Time) error
+	// Release ends the lease of the events, so those not published are claimed again right away
+	Release(ctx context.Context, ids []domain.ID) error
+
+	// Erase empties the data of the events of the user, published or not, as it may carry personal data
+	Erase(ctx context.Context, userID domain.ID) error
+}
+
+// Publisher delivers events to other services, implementations are picked by configuration
+type Publisher interface {
+	Publish(ctx context.Context, e *Event) error
+}
@@ -0,0 +1,6 @@
+package domain
+
+// RateLimiter tells whether another attempt identified by the key is allowed now, an allowed attempt is counted
+type RateLimiter interface {
+	Allow(key string) bool
+}
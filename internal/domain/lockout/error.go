@@ -0,0 +1,8 @@
+package lockout
+
+import "errors"
+
+var (
+	ErrLocked    = errors.New("temporarily locked out after too many failed logins")
+	ErrInvalidIP = errors.New("invalid ip address")
+)
@@ -0,0 +1,79 @@
+package lockout
+
+import (
+	"net"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+// Kind tells what failed logins are counted for
+type Kind string
+
+const (
+	KindUser Kind = "user"
+	KindIP   Kind = "ip"
+)
+
+// Subject is a user or a client address whose failed logins are counted
+type Subject struct {
+	Kind  Kind
+	Value string
+}
+
+func User(id domain.ID) Subject {
+	return Subject{Kind: KindUser, Value: id.String()}
+}
+
+// IP normalizes the address, so different notations of one address are counted together
+func IP(address string) Subject {
+	if ip := net.ParseIP(address); ip != nil {
+		address = ip.String()
+	}
+
+	return Subject{Kind: KindIP, Value: address}
+}
+
+// State is the record of recent failed logins of a subject
+type State struct {
+	Subject       Subject    `json:"-"`
+	Failures      int        `json:"failures"`
+	LastFailureAt *time.Time `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
+
+// LockedAt tells whether logins of the subject are rejected at the given time
+func (s *State) LockedAt(now time.Time) bool {
+	return s.LockedUntil != nil && now.Before(*s.LockedUntil)
+}
+
+// Policy decides when and for how long subjects are locked out
+type Policy struct {
+	UserThreshold int
+	IPThreshold   int
+	BaseDelay     time.Duration
+	MaxDelay      time.Duration
+
+	// Window is the time after the last failure the failures are forgotten in
+	Window time.Duration
+}
+
+// Delay returns for how long the subject is locked out after the given number of consecutive failures, zero when it is not.
+// Reaching the threshold locks it for the base delay, which doubles with every further failure up to the max delay.
+func (p *Policy) Delay(kind Kind, failures int) time.Duration {
+	threshold := p.UserThreshold
+	if kind == KindIP {
+		threshold = p.IPThreshold
+	}
+
+	if threshold <= 0 || failures < threshold {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := threshold; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, p.MaxDelay)
+}
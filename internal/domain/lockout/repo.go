@@ -0,0 +1,20 @@
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// Get returns the state of the subject, a state without failures when none have been recorded
+	Get(ctx context.Context, subject Subject) (*State, error)
+
+	// RecordFailure counts a failed login and returns the updated state,
+	// failures are counted from scratch when the last one is older than the window
+	RecordFailure(ctx context.Context, subject Subject, at time.Time, window time.Duration) (*State, error)
+
+	Lock(ctx context.Context, subject Subject, until time.Time) error
+
+	// Reset forgets the failures of the subject and lifts its lock
+	Reset(ctx context.Context, subject Subject) error
+}
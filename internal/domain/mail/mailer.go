@@ -0,0 +1,14 @@
+package mail
+
+import "context"
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages to users, implementations are picked by configuration
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
@@ -0,0 +1,7 @@
+package mfa
+
+// Cipher encrypts secrets before they are stored
+type Cipher interface {
+	Encrypt(plaintext []byte) (string, error)
+	Decrypt(ciphertext string) ([]byte, error)
+}
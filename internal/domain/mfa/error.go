@@ -0,0 +1,9 @@
+package mfa
+
+import "errors"
+
+var (
+	ErrNotEnrolled    = errors.New("mfa not enrolled")
+	ErrAlreadyEnabled = errors.New("mfa already enabled")
+	ErrInvalidCode    = errors.New("invalid code")
+)
@@ -0,0 +1,75 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+const (
+	// secretSize is the number of random bytes of a TOTP secret, the size of an HMAC-SHA1 key recommended by RFC 4226
+	secretSize = 20
+
+	// RecoveryCodeCount is the number of recovery codes a user gets at once
+	RecoveryCodeCount = 10
+
+	// recoveryCodeSize is the number of random bytes of a recovery code, encoded as 16 characters
+	recoveryCodeSize = 10
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Factor is the TOTP secret of a user, MFA is enabled once the user confirms it with a first code
+type Factor struct {
+	UserID          domain.ID
+	EncryptedSecret string
+	ConfirmedAt     *time.Time
+	LastUsedStep    int64
+}
+
+func (f *Factor) Enabled() bool {
+	return f.ConfirmedAt != nil
+}
+
+// NewSecret generates a random TOTP secret
+func NewSecret() ([]byte, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed generating secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// NewRecoveryCodes generates single-use codes replacing a TOTP code when the authenticator is lost,
+// the codes are shown to the user once and only their hashes are stored
+func NewRecoveryCodes() (codes []string, hashes []string, err error) {
+	for range RecoveryCodeCount {
+		b := make([]byte, recoveryCodeSize)
+		if _, err = rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed generating recovery code: %w", err)
+		}
+
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		code = code[:8] + "-" + code[8:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode returns the form a recovery code is stored and looked up in, it ignores the case and dashes
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,32 @@
+package mfa
+
+import (
+	"context"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+type Repository interface {
+	// Get returns the factor of the user, ErrNotEnrolled when the user has not started an enrollment
+	Get(ctx context.Context, userID domain.ID) (*Factor, error)
+
+	// Enroll stores a pending factor, replacing the previous pending one
+	Enroll(ctx context.Context, f *Factor, createdAt time.Time) error
+
+	// Confirm enables the factor with the step of the code it has been confirmed with, and stores the recovery code hashes
+	Confirm(ctx context.Context, userID domain.ID, step int64, confirmedAt time.Time, recoveryCodeHashes []string) error
+
+	// UseStep records the step of a used code, ErrInvalidCode is returned when it is not newer than the last used one,
+	// so every code is accepted once
+	UseStep(ctx context.Context, userID domain.ID, step int64) error
+
+	// UseRecoveryCode marks the code as used, ErrInvalidCode is returned when the user has no unused code with the hash
+	UseRecoveryCode(ctx context.Context, userID domain.ID, hash string, usedAt time.Time) error
+
+	// ReplaceRecoveryCodes deletes all recovery codes of the user and stores the new ones
+	ReplaceRecoveryCodes(ctx context.Context, userID domain.ID, hashes []string) error
+
+	// Delete removes the factor along with the recovery codes, which disables MFA of the user
+	Delete(ctx context.Context, userID domain.ID) error
+}
@@ -0,0 +1,7 @@
+package oidc
+
+// Cipher encrypts private signing keys before they are stored
+type Cipher interface {
+	Encrypt(plaintext []byte) (string, error)
+	Decrypt(ciphertext string) ([]byte, error)
+}
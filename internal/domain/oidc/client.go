@@ -0,0 +1,93 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+// secretSize is the number of random bytes of a secret
+const secretSize = 32
+
+// Client is an application registered to delegate the login of its users, only the hash of its secret is stored
+type Client struct {
+	ID           domain.ID
+	Name         string
+	SecretHash   string // empty for public clients, e.g. single page or mobile apps, which can not keep a secret
+	RedirectURIs []string
+}
+
+// NewClient validates the redirect URIs and generates the secret of a confidential client, the secret itself is shown once
+func NewClient(name string, redirectURIs []string, public bool) (*Client, string, error) {
+	if len(redirectURIs) == 0 {
+		return nil, "", ErrInvalidRedirectURI
+	}
+
+	for _, redirectURI := range redirectURIs {
+		u, err := url.Parse(redirectURI)
+		if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+			return nil, "", ErrInvalidRedirectURI
+		}
+	}
+
+	c := &Client{
+		ID:           domain.NewID(),
+		Name:         name,
+		RedirectURIs: redirectURIs,
+	}
+
+	if public {
+		return c, "", nil
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	c.SecretHash = Hash(secret)
+
+	return c, secret, nil
+}
+
+// Public tells whether the client authenticates with PKCE only
+func (c *Client) Public() bool {
+	return c.SecretHash == ""
+}
+
+// AllowsRedirect tells whether the URI is one of the registered ones, compared exactly as partial matches enable open redirects
+func (c *Client) AllowsRedirect(redirectURI string) bool {
+	return slices.Contains(c.RedirectURIs, redirectURI)
+}
+
+// VerifySecret tells whether the secret is the one of a confidential client
+func (c *Client) VerifySecret(secret string) bool {
+	if c.Public() {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(Hash(secret)), []byte(c.SecretHash)) == 1
+}
+
+// Hash returns the form a secret is stored and looked up in
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed generating secret: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,78 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+// CodeChallengeMethodS256 is the only PKCE method accepted, the plain one would expose the verifier
+const CodeChallengeMethodS256 = "S256"
+
+// AuthorizationCode is the single-use proof of a login handed to a client through the redirect URI.
+// It is exchanged for tokens by the client holding the PKCE verifier of the challenge, only the hash of the code is stored.
+type AuthorizationCode struct {
+	Hash          string
+	ClientID      domain.ID
+	UserID        domain.ID
+	RedirectURI   string
+	Scopes        []string
+	Nonce         string
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+// NewCode generates a random code and the authorization to be stored for it, the code itself is only given to the client
+func NewCode(clientID, userID domain.ID, redirectURI string, scopes []string, nonce, codeChallenge string, expiresAt time.Time) (string, *AuthorizationCode, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return secret, &AuthorizationCode{
+		Hash:          Hash(secret),
+		ClientID:      clientID,
+		UserID:        userID,
+		RedirectURI:   redirectURI,
+		Scopes:        scopes,
+		Nonce:         nonce,
+		CodeChallenge: codeChallenge,
+		ExpiresAt:     expiresAt,
+	}, nil
+}
+
+// VerifyPKCE tells whether the verifier is the one the S256 challenge of the code was derived from
+func (c *AuthorizationCode) VerifyPKCE(verifier string) bool {
+	if !validPKCEValue(verifier) {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(c.CodeChallenge)) == 1
+}
+
+// ValidCodeChallenge tells whether the challenge has the form of an S256 one
+func ValidCodeChallenge(challenge string) bool {
+	return validPKCEValue(challenge)
+}
+
+// validPKCEValue checks the length and the characters of verifiers and challenges, as defined by RFC 7636
+func validPKCEValue(value string) bool {
+	if len(value) < 43 || len(value) > 128 {
+		return false
+	}
+
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.', r == '_', r == '~':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,14 @@
+package oidc
+
+import "errors"
+
+var (
+	ErrClientNotFound          = errors.New("client not found")
+	ErrInvalidClient           = errors.New("invalid client")
+	ErrInvalidRedirectURI      = errors.New("invalid redirect uri")
+	ErrInvalidRequest          = errors.New("invalid request")
+	ErrInvalidScope            = errors.New("invalid scope")
+	ErrInvalidGrant            = errors.New("invalid grant")
+	ErrUnsupportedResponseType = errors.New("unsupported response type")
+	ErrUnsupportedGrantType    = errors.New("unsupported grant type")
+)
@@ -0,0 +1,31 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"time"
+)
+
+// SigningKey is a key pair ID tokens are signed with, the private key is stored encrypted
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey string // encrypted PKCS #8 DER
+	PublicKey  []byte // PKIX DER
+	CreatedAt  time.Time
+}
+
+// PublicKey is a key published for clients to verify ID tokens with
+type PublicKey struct {
+	ID  string
+	Key crypto.PublicKey
+}
+
+// Signer signs ID and access tokens with the current key, keys are rotated and published for as long as tokens signed with them are valid
+type Signer interface {
+	Sign(ctx context.Context, claims any) (string, error)
+	PublicKeys(ctx context.Context) ([]PublicKey, error)
+
+	// Verify checks the signature against the published key of the token and decodes the claims into dst
+	Verify(ctx context.Context, token string, dst any) error
+}
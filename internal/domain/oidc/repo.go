@@ -0,0 +1,34 @@
+package oidc
+
+import (
+	"context"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+type ClientRepository interface {
+	Create(ctx context.Context, c *Client, createdAt time.Time) error
+
+	// Get returns the client, ErrClientNotFound when there is no such client
+	Get(ctx context.Context, id domain.ID) (*Client, error)
+
+	// Delete removes the client along with its unused codes, ErrClientNotFound is returned when there is no such client
+	Delete(ctx context.Context, id domain.ID) error
+}
+
+type CodeRepository interface {
+	// Create stores the code, user.ErrNotFound is returned when the user does not exist
+	Create(ctx context.Context, c *AuthorizationCode, createdAt time.Time) error
+
+	// Use marks the code as used and returns it, including an expired one. ErrInvalidGrant is returned when there is no unused
+	// code with the hash of an existing user, so concurrent exchanges of one code let only the first of them through.
+	Use(ctx context.Context, hash string, usedAt time.Time) (*AuthorizationCode, error)
+}
+
+type KeyRepository interface {
+	Create(ctx context.Context, k *SigningKey) error
+
+	// List returns the keys created after the time, the newest first
+	List(ctx context.Context, since time.Time) ([]*SigningKey, error)
+}
@@ -0,0 +1,95 @@
+package oidc
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+	ScopePhone   = "phone"
+)
+
+// Scopes are the supported scopes, each but openid adds claims about the user to the ID token
+var Scopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopePhone}
+
+// ParseScopes splits the space separated scopes, which must include openid
+func ParseScopes(value string) ([]string, error) {
+	var scopes []string
+
+	for _, scope := range strings.Fields(value) {
+		if !slices.Contains(Scopes, scope) {
+			return nil, ErrInvalidScope
+		}
+
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if !slices.Contains(scopes, ScopeOpenID) {
+		return nil, ErrInvalidScope
+	}
+
+	return scopes, nil
+}
+
+// UserClaims are the standard claims about the user
+type UserClaims struct {
+	Subject       string `json:"sub"`
+	Name          string `json:"name,omitempty"`
+	GivenName     string `json:"given_name,omitempty"`
+	FamilyName    string `json:"family_name,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	PhoneNumber   string `json:"phone_number,omitempty"`
+}
+
+// NewUserClaims returns the claims the scopes grant access to
+func NewUserClaims(u *user.User, scopes []string) *UserClaims {
+	claims := &UserClaims{Subject: u.ID.String()}
+
+	if slices.Contains(scopes, ScopeProfile) {
+		claims.Name = strings.TrimSpace(u.FirstName + " " + u.LastName)
+		claims.GivenName = u.FirstName
+		claims.FamilyName = u.LastName
+	}
+
+	if slices.Contains(scopes, ScopeEmail) {
+		verified := u.EmailVerified
+		claims.Email = u.Email
+		claims.EmailVerified = &verified
+	}
+
+	if slices.Contains(scopes, ScopePhone) {
+		claims.PhoneNumber = u.PhoneNumber
+	}
+
+	return claims
+}
+
+// IDTokenClaims are the contents of an ID token, which tells the client who has logged in
+type IDTokenClaims struct {
+	Issuer    string `json:"iss"`
+	Audience  string `json:"aud"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Nonce     string `json:"nonce,omitempty"`
+	*UserClaims
+}
+
+// AccessTokenClaims are the contents of an access token issued to a client, which grants reading the user info of the scopes.
+// It is signed like an ID token, the client ID tells the two apart, and is not accepted by the API.
+type AccessTokenClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	ClientID  string `json:"client_id"`
+	Scope     string `json:"scope"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
@@ -0,0 +1,8 @@
+package session
+
+import "errors"
+
+var (
+	ErrInvalidToken = errors.New("invalid or expired refresh token")
+	ErrReused       = errors.New("refresh token reused")
+)
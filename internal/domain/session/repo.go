@@ -0,0 +1,29 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+type Repository interface {
+	Create(ctx context.Context, t *RefreshToken, createdAt time.Time) error
+
+	// Get returns the token with the hash, used and revoked ones included. ErrInvalidToken is returned
+	// when there is no such token of an existing user.
+	Get(ctx context.Context, hash string) (*RefreshToken, error)
+
+	// Use marks the token as used, ErrReused is returned when it has been used already,
+	// so concurrent exchanges of one token let only the first of them through
+	Use(ctx context.Context, hash string, usedAt time.Time) error
+
+	// RevokeFamily revokes all tokens rotated from the same login
+	RevokeFamily(ctx context.Context, familyID domain.ID, revokedAt time.Time) error
+
+	// ListByUser returns the sessions of the user, expired and revoked ones included, the oldest first
+	ListByUser(ctx context.Context, userID domain.ID) ([]*Session, error)
+
+	// RevokeUser revokes all tokens of the user, which ends all of their sessions
+	RevokeUser(ctx context.Context, userID domain.ID, revokedAt time.Time) error
+}
@@ -0,0 +1,64 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+// secretSize is the number of random bytes of a secret
+const secretSize = 32
+
+// RefreshToken is exchanged once for a new access token and the next refresh token of the same family.
+// A family is the chain of tokens rotated from one login, only the hash of a secret is stored.
+type RefreshToken struct {
+	Hash      string
+	UserID    domain.ID
+	FamilyID  domain.ID
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+// Session is the family of tokens of one login as shown to the user, without the hashes
+type Session struct {
+	FamilyID   domain.ID  `json:"family_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"` // the last token exchange
+	ExpiresAt  time.Time  `json:"expires_at"`             // of the latest token
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active tells whether the token can still be exchanged, used tokens are not active either
+func (t *RefreshToken) Active(now time.Time) bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// New generates a random secret and the token of the family to be stored for it, the secret itself is only given to the client
+func New(userID, familyID domain.ID, expiresAt time.Time) (string, *RefreshToken, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("failed generating secret: %w", err)
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(b)
+
+	return secret, &RefreshToken{
+		Hash:      Hash(secret),
+		UserID:    userID,
+		FamilyID:  familyID,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Hash returns the form a secret is stored and looked up in
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
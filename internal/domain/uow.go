@@ -0,0 +1,11 @@
+package domain
+
+import "context"
+
+// UnitOfWork groups repository calls into a single transaction
+type UnitOfWork interface {
+	// WithinTx runs fn in a transaction carried by the context passed to it, repositories called with that context
+	// take part in the transaction. It is committed when fn returns nil and rolled back otherwise.
+	// A call made within a running transaction joins it.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
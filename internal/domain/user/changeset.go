@@ -0,0 +1,235 @@
+package user
+
+import (
+	"slices"
+)
+
+// Field is a changeable attribute of a user or of an address, named after its storage column
+type Field string
+
+const (
+	FieldPassword    Field = "password"
+	FieldFirstName   Field = "first_name"
+	FieldLastName    Field = "last_name"
+	FieldPhoneNumber Field = "phone_number"
+	FieldRole        Field = "role"
+
+	FieldStreet     Field = "street"
+	FieldCity       Field = "city"
+	FieldState      Field = "state"
+	FieldPostalCode Field = "postal_code"
+	FieldCountry    Field = "country"
+)
+
+var (
+	// the password is changed only through its own flow, proving the current one or a reset token
+	userFields    = []Field{FieldFirstName, FieldLastName, FieldPhoneNumber, FieldRole}
+	addressFields = []Field{FieldStreet, FieldCity, FieldState, FieldPostalCode, FieldCountry}
+
+	// optionalFields are the only ones that can be cleared
+	optionalFields = []Field{FieldPhoneNumber, FieldState, FieldCountry}
+
+	requiredAddressFields = []Field{FieldStreet, FieldCity, FieldPostalCode}
+)
+
+// Change either sets a field to the value or clears it
+type Change struct {
+	Field Field
+	Value string
+	Clear bool
+}
+
+func Set(field Field, value string) Change {
+	return Change{Field: field, Value: value}
+}
+
+func Clear(field Field) Change {
+	return Change{Field: field, Clear: true}
+}
+
+// AddressChange changes the address of the given type, an address the user does not have yet is added,
+// so all its required fields have to be set
+type AddressChange struct {
+	Type    AddressType
+	Changes []Change
+	Remove  bool
+}
+
+// ChangeSet is a set of changes applied to a user at once
+type ChangeSet struct {
+	User      []Change
+	Addresses []AddressChange
+}
+
+func (cs *ChangeSet) IsEmpty() bool {
+	return len(cs.User) == 0 && len(cs.Addresses) == 0
+}
+
+// Has tells whether the user field is changed
+func (cs *ChangeSet) Has(field Field) bool {
+	return slices.ContainsFunc(cs.User, func(c Change) bool { return c.Field == field })
+}
+
+// Validate checks that only known fields are changed, required fields are never cleared
+// and each address is changed at most once
+func (cs *ChangeSet) Validate() error {
+	if err := validateChanges(cs.User, userFields); err != nil {
+		return err
+	}
+
+	for _, c := range cs.User {
+		if c.Field == FieldRole {
+			if _, err := ParseRole(c.Value); err != nil {
+				return ErrInvalidChange
+			}
+		}
+	}
+
+	seen := make(map[AddressType]struct{})
+	for _, addr := range cs.Addresses {
+		if _, ok := seen[addr.Type]; ok {
+			return ErrInvalidChange
+		}
+		seen[addr.Type] = struct{}{}
+
+		if addr.Remove && len(addr.Changes) > 0 {
+			return ErrInvalidChange
+		}
+
+		if err := validateChanges(addr.Changes, addressFields); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewAddress builds an address out of changes of a missing one
+func (c AddressChange) NewAddress() (*Address, error) {
+	addr := &Address{Type: c.Type}
+
+	set := make(map[Field]struct{})
+	for _, change := range c.Changes {
+		if change.Clear {
+			continue
+		}
+		set[change.Field] = struct{}{}
+
+		switch change.Field {
+		case FieldStreet:
+			addr.Street = change.Value
+		case FieldCity:
+			addr.City = change.Value
+		case FieldState:
+			addr.State = change.Value
+		case FieldPostalCode:
+			addr.PostalCode = change.Value
+		case FieldCountry:
+			addr.Country = change.Value
+		}
+	}
+
+	for _, field := range requiredAddressFields {
+		if _, ok := set[field]; !ok {
+			return nil, ErrInvalidChange
+		}
+	}
+
+	return addr, nil
+}
+
+// Changes returns the changes replacing all fields of an address of the same type with this one
+func (a *Address) Changes() []Change {
+	return []Change{
+		Set(FieldStreet, a.Street),
+		Set(FieldCity, a.City),
+		setOrClear(FieldState, a.State),
+		Set(FieldPostalCode, a.PostalCode),
+		setOrClear(FieldCountry, a.Country),
+	}
+}
+
+func validateChanges(changes []Change, allowed []Field) error {
+	seen := make(map[Field]struct{})
+
+	for _, c := range changes {
+		if !slices.Contains(allowed, c.Field) {
+			return ErrInvalidChange
+		}
+
+		if _, ok := seen[c.Field]; ok {
+			return ErrInvalidChange
+		}
+		seen[c.Field] = struct{}{}
+
+		if c.Clear && !slices.Contains(optionalFields, c.Field) {
+			return ErrInvalidChange
+		}
+	}
+
+	return nil
+}
+
+// Diff returns the changes turning the current user into the target one, empty optional values clear the fields.
+// Passwords are never read, so they are not compared and an empty role of the target keeps the current one.
+func Diff(current, target *User) *ChangeSet {
+	cs := &ChangeSet{}
+
+	cs.User = diffField(cs.User, FieldFirstName, current.FirstName, target.FirstName)
+	cs.User = diffField(cs.User, FieldLastName, current.LastName, target.LastName)
+	cs.User = diffField(cs.User, FieldPhoneNumber, current.PhoneNumber, target.PhoneNumber)
+
+	if target.Role != "" {
+		cs.User = diffField(cs.User, FieldRole, string(current.Role), string(target.Role))
+	}
+
+	currentAddresses := make(map[AddressType]*Address, len(current.Addresses))
+	for _, addr := range current.Addresses {
+		currentAddresses[addr.Type] = addr
+	}
+
+	targetTypes := make(map[AddressType]struct{}, len(target.Addresses))
+	for _, addr := range target.Addresses {
+		targetTypes[addr.Type] = struct{}{}
+
+		existing, ok := currentAddresses[addr.Type]
+		if !ok {
+			existing = &Address{}
+		}
+
+		var changes []Change
+		changes = diffField(changes, FieldStreet, existing.Street, addr.Street)
+		changes = diffField(changes, FieldCity, existing.City, addr.City)
+		changes = diffField(changes, FieldState, existing.State, addr.State)
+		changes = diffField(changes, FieldPostalCode, existing.PostalCode, addr.PostalCode)
+		changes = diffField(changes, FieldCountry, existing.Country, addr.Country)
+
+		if len(changes) > 0 {
+			cs.Addresses = append(cs.Addresses, AddressChange{Type: addr.Type, Changes: changes})
+		}
+	}
+
+	for _, addr := range current.Addresses {
+		if _, ok := targetTypes[addr.Type]; !ok {
+			cs.Addresses = append(cs.Addresses, AddressChange{Type: addr.Type, Remove: true})
+		}
+	}
+
+	return cs
+}
+
+func diffField(changes []Change, field Field, current, target string) []Change {
+	if current == target {
+		return changes
+	}
+
+	return append(changes, setOrClear(field, target))
+}
+
+func setOrClear(field Field, value string) Change {
+	if value == "" {
+		return Clear(field)
+	}
+
+	return Set(field, value)
+}
@@ -0,0 +1,42 @@
+package user
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+)
+
+// Cursor points at the last user of a page, the next page starts right after it.
+// It is opaque to the clients, so its content can change without breaking them.
+type Cursor struct {
+	Sort   []Sort   `json:"s"`
+	Values []string `json:"v"` // sort field values of the last user, in the Sort order
+	ID     int64    `json:"i"` // storage id of the last user, breaks ties between equal values
+}
+
+// Encode returns the cursor as a URL safe token
+func (c *Cursor) Encode() string {
+	b, _ := json.Marshal(c) // cannot fail for this struct
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Matches tells whether the cursor has been issued for the given order
+func (c *Cursor) Matches(sorts []Sort) bool {
+	return slices.Equal(c.Sort, sorts) && len(c.Values) == len(sorts)
+}
+
+// DecodeCursor parses a token returned by Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err = json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,11 @@
+package user
+
+import "github.com/wojciechpawlinow/usermanagement/internal/domain"
+
+// erasedDomain is reserved, so nothing is ever mailed to an erased user
+const erasedDomain = "erased.invalid"
+
+// ErasedEmail replaces the email of an erased user, it is derived from the UUID to keep the emails unique
+func ErasedEmail(id domain.ID) string {
+	return id.String() + "@" + erasedDomain
+}
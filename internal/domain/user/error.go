@@ -6,5 +6,15 @@ var (
 	ErrEmailAlreadyExists   = errors.New("email already exists")
 	ErrAddressAlreadyExists = errors.New("address of this type already exists")
 	ErrNotFound             = errors.New("user not found")
+	ErrNotDeleted           = errors.New("user is not deleted")
+	ErrErased               = errors.New("user has been erased")
 	ErrAddressNotFound      = errors.New("address not found")
+	ErrInvalidRole          = errors.New("invalid role")
+	ErrInvalidSort          = errors.New("invalid sort")
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrVersionMismatch      = errors.New("user has been modified in the meantime")
+	ErrInvalidChange        = errors.New("invalid change")
+	ErrLastAddress          = errors.New("user must have at least one address")
+	ErrWeakPassword         = errors.New("password does not meet the policy")
+	ErrMFARequired          = errors.New("mfa is required for the role")
 )
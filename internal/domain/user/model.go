@@ -1,17 +1,24 @@
 package user
 
 import (
+	"time"
+
 	"github.com/wojciechpawlinow/usermanagement/internal/domain"
 )
 
 type User struct {
-	ID          domain.ID  `json:"id"`
-	Email       string     `json:"email"`
-	Password    string     `json:"-"`
-	FirstName   string     `json:"first_name"`
-	LastName    string     `json:"last_name"`
-	PhoneNumber string     `json:"phone_number"`
-	Addresses   []*Address `json:"addresses"`
+	ID            domain.ID  `json:"id"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	MFAEnabled    bool       `json:"mfa_enabled"`
+	Password      string     `json:"-"`
+	FirstName     string     `json:"first_name"`
+	LastName      string     `json:"last_name"`
+	PhoneNumber   string     `json:"phone_number"`
+	Role          Role       `json:"role"`
+	Addresses     []*Address `json:"addresses"`
+	Version       int64      `json:"-"`                    // incremented on every change, exposed as ETag
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"` // set only on deleted users, listed on request
 }
 
 type AddressType int
@@ -30,3 +37,12 @@ type Address struct {
 	PostalCode string      `json:"postal_code"`
 	Country    string      `json:"country"`
 }
+
+// Credentials are the data required to authenticate a user, never to be exposed outside the application
+type Credentials struct {
+	ID           domain.ID
+	Email        string
+	PasswordHash string
+	Role         Role
+	MFAEnabled   bool
+}
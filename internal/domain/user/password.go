@@ -0,0 +1,97 @@
+package user
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxPasswordBytes keeps passwords within what every supported hashing algorithm accepts, bcrypt takes at most 72 bytes
+const maxPasswordBytes = 72
+
+// CharClass is a kind of characters a password can be required to contain
+type CharClass string
+
+const (
+	ClassLower  CharClass = "lower"
+	ClassUpper  CharClass = "upper"
+	ClassDigit  CharClass = "digit"
+	ClassSymbol CharClass = "symbol"
+)
+
+var classMatchers = map[CharClass]func(r rune) bool{
+	ClassLower: unicode.IsLower,
+	ClassUpper: unicode.IsUpper,
+	ClassDigit: unicode.IsDigit,
+	ClassSymbol: func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+	},
+}
+
+//go:embed common_passwords.txt
+var commonPasswords string
+
+// ParseCharClass converts a raw value into a known character class
+func ParseCharClass(value string) (CharClass, error) {
+	class := CharClass(value)
+	if _, ok := classMatchers[class]; !ok {
+		return "", fmt.Errorf("unknown character class %q", value)
+	}
+
+	return class, nil
+}
+
+// PasswordPolicy decides which passwords users are allowed to choose
+type PasswordPolicy struct {
+	minLength int
+	classes   []CharClass
+	denyList  map[string]struct{}
+}
+
+// NewPasswordPolicy creates a policy denying the given passwords on top of the built-in list of common ones,
+// the deny list is matched case-insensitively
+func NewPasswordPolicy(minLength int, classes []CharClass, denyList []string) *PasswordPolicy {
+	p := &PasswordPolicy{
+		minLength: minLength,
+		classes:   classes,
+		denyList:  make(map[string]struct{}),
+	}
+
+	for _, password := range append(strings.Split(commonPasswords, "\n"), denyList...) {
+		if password = strings.TrimSpace(password); password != "" {
+			p.denyList[strings.ToLower(password)] = struct{}{}
+		}
+	}
+
+	return p
+}
+
+// Validate checks the password chosen by the owner of the email, the returned error wraps ErrWeakPassword
+// and tells which rule is broken
+func (p *PasswordPolicy) Validate(password, email string) error {
+	if utf8.RuneCountInString(password) < p.minLength {
+		return fmt.Errorf("%w: must be at least %d characters long", ErrWeakPassword, p.minLength)
+	}
+
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("%w: must be at most %d bytes long", ErrWeakPassword, maxPasswordBytes)
+	}
+
+	for _, class := range p.classes {
+		if !strings.ContainsFunc(password, classMatchers[class]) {
+			return fmt.Errorf("%w: must contain at least one %s character", ErrWeakPassword, class)
+		}
+	}
+
+	if strings.EqualFold(password, email) {
+		return fmt.Errorf("%w: must not be the email", ErrWeakPassword)
+	}
+
+	if _, ok := p.denyList[strings.ToLower(password)]; ok {
+		return fmt.Errorf("%w: too common", ErrWeakPassword)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package user
+
+import (
+	"strings"
+)
+
+// Filter narrows down listed users, empty fields are ignored.
+// Address conditions must be all met by the same address.
+type Filter struct {
+	Email       string // exact match
+	EmailPrefix string
+	FirstName   string // contains
+	LastName    string // contains
+	PhoneNumber string // exact match
+	Country     string // exact match
+	City        string // exact match
+	AddressType *AddressType
+}
+
+// HasAddressConditions tells whether users have to be matched by their addresses
+func (f Filter) HasAddressConditions() bool {
+	return f.Country != "" || f.City != "" || f.AddressType != nil
+}
+
+type SortField string
+
+const (
+	SortByEmail     SortField = "email"
+	SortByFirstName SortField = "first_name"
+	SortByLastName  SortField = "last_name"
+	SortByCreatedAt SortField = "created_at"
+)
+
+type Sort struct {
+	Field SortField `json:"f"`
+	Desc  bool      `json:"d,omitempty"`
+}
+
+// ListQuery describes which users to list and in which order, the default order is the creation order.
+// Users are paginated with a cursor, so pages stay consistent when users are created or deleted in the meantime.
+type ListQuery struct {
+	Filter    Filter
+	Sort      []Sort
+	Cursor    *Cursor // nil for the first page
+	Limit     int
+	WithTotal bool
+
+	// IncludeDeleted lists soft deleted users as well, the ones not purged yet
+	IncludeDeleted bool
+}
+
+// OrderBy returns the effective order, the storage id is always the final tie breaker
+func (q *ListQuery) OrderBy() []Sort {
+	if len(q.Sort) == 0 {
+		return []Sort{{Field: SortByCreatedAt}}
+	}
+
+	return q.Sort
+}
+
+// Page is a single page of listed users
+type Page struct {
+	Users      []*User
+	NextCursor *Cursor // nil on the last page
+	Total      *int    // set only when requested, the number of all users matching the filter
+}
+
+// ParseSort parses a comma separated list of fields, a field prefixed with "-" is sorted in descending order,
+// e.g. "last_name,-created_at"
+func ParseSort(value string) ([]Sort, error) {
+	var sorts []Sort
+
+	seen := make(map[SortField]struct{})
+
+	for _, element := range strings.Split(value, ",") {
+		element = strings.TrimSpace(element)
+		if element == "" {
+			continue
+		}
+
+		s := Sort{}
+		if strings.HasPrefix(element, "-") {
+			s.Desc = true
+			element = element[1:]
+		}
+
+		s.Field = SortField(element)
+		switch s.Field {
+		case SortByEmail, SortByFirstName, SortByLastName, SortByCreatedAt:
+		default:
+			return nil, ErrInvalidSort
+		}
+
+		if _, ok := seen[s.Field]; ok {
+			return nil, ErrInvalidSort
+		}
+		seen[s.Field] = struct{}{}
+
+		sorts = append(sorts, s)
+	}
+
+	return sorts, nil
+}
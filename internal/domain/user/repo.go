@@ -9,10 +9,43 @@ import (
 
 type Repository interface {
 	Create(ctx context.Context, u *User, createdAt time.Time) error
-	UpdateBasicFields(ctx context.Context, id domain.ID, fields map[string]any) error
-	UpdateAddress(ctx context.Context, id domain.ID, addrType int, fields map[string]any) error
+	UpdateBasicFields(ctx context.Context, id domain.ID, changes []Change) error
+	UpdateAddress(ctx context.Context, id domain.ID, addrType AddressType, changes []Change) error
 	InsertAddress(ctx context.Context, id domain.ID, addr *Address, createdAt time.Time) error
+	DeleteAddress(ctx context.Context, id domain.ID, addrType AddressType) error
+	ListAddresses(ctx context.Context, id domain.ID) ([]*Address, error)
 	Delete(ctx context.Context, id domain.ID) error
+
+	// Restore brings back a soft deleted user together with the addresses deleted with them,
+	// ErrNotDeleted is returned when the user has not been deleted and ErrErased when they have been erased
+	Restore(ctx context.Context, id domain.ID) error
+
+	// Erase anonymizes the user for good, deleted or not. The row and its UUID stay, so references to the user
+	// keep working, while the personal data and everything the user has logged in or verified with is removed.
+	// The user ends up deleted, ErrErased is returned when they have been erased already.
+	Erase(ctx context.Context, id domain.ID, erasedAt time.Time) error
+
+	// Purge removes users deleted before the given time for good, together with all their data except the audit log,
+	// at most limit users at a time. It returns the number of purged users.
+	// Erased users are never purged, so references to them keep working.
+	Purge(ctx context.Context, deletedBefore time.Time, limit int) (int, error)
 	GetByUUID(ctx context.Context, id domain.ID) (*User, error)
-	Get(ctx context.Context, page, pageSize int) ([]*User, error)
+
+	// GetByUUIDIncludingDeleted returns the user even when they have been deleted or erased, with DeletedAt set then
+	GetByUUIDIncludingDeleted(ctx context.Context, id domain.ID) (*User, error)
+	Get(ctx context.Context, q *ListQuery) (*Page, error)
+	GetCredentialsByEmail(ctx context.Context, email string) (*Credentials, error)
+	GetCredentialsByUUID(ctx context.Context, id domain.ID) (*Credentials, error)
+
+	// SetVerifiedEmail changes the email of a user and marks it as verified, ErrEmailAlreadyExists is returned
+	// when another user has it
+	SetVerifiedEmail(ctx context.Context, id domain.ID, email string) error
+
+	// ReplacePasswordHash replaces the password hash of a user as long as it still is the current one, without bumping the version,
+	// e.g. to rehash a verified password. ErrVersionMismatch is returned when the hash has been changed or the user deleted.
+	ReplacePasswordHash(ctx context.Context, id domain.ID, current, replacement string) error
+
+	// IncrementVersion bumps the version of a user, it has to be called in the same transaction as every change of the user.
+	// When the expected version is given and differs from the current one, ErrVersionMismatch is returned.
+	IncrementVersion(ctx context.Context, id domain.ID, expected *int64) error
 }
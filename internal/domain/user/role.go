@@ -0,0 +1,137 @@
+package user
+
+import "slices"
+
+// Role defines what a user is allowed to do with user records
+type Role string
+
+const (
+	RoleAdmin   Role = "admin"
+	RoleSupport Role = "support"
+	RoleSelf    Role = "self"
+)
+
+// Permission is an operation on user records subject to authorization
+type Permission int
+
+const (
+	PermissionRead Permission = iota
+	PermissionList
+	PermissionUpdate
+	PermissionDelete
+	PermissionManageRoles
+	PermissionUnlock
+	PermissionManageMFA
+	PermissionResetMFA
+	PermissionRevokeSessions
+	PermissionManageClients
+	PermissionReadAudit
+	PermissionManageWebhooks
+	PermissionRestore // list and restore deleted users
+	PermissionExport  // export everything stored about a user
+	PermissionErase   // anonymize users for good
+	PermissionChangeEmail
+)
+
+type scope int
+
+const (
+	scopeOwn scope = iota + 1
+	scopeAny
+)
+
+var rolePermissions = map[Role]map[Permission]scope{
+	RoleAdmin: {
+		PermissionRead:           scopeAny,
+		PermissionList:           scopeAny,
+		PermissionUpdate:         scopeAny,
+		PermissionDelete:         scopeAny,
+		PermissionManageRoles:    scopeAny,
+		PermissionUnlock:         scopeAny,
+		PermissionManageMFA:      scopeOwn,
+		PermissionResetMFA:       scopeAny,
+		PermissionRevokeSessions: scopeAny,
+		PermissionManageClients:  scopeAny,
+		PermissionReadAudit:      scopeAny,
+		PermissionManageWebhooks: scopeAny,
+		PermissionRestore:        scopeAny,
+		PermissionExport:         scopeAny,
+		PermissionErase:          scopeAny,
+		PermissionChangeEmail:    scopeAny,
+	},
+	RoleSupport: {
+		PermissionRead:           scopeAny,
+		PermissionList:           scopeAny,
+		PermissionUpdate:         scopeAny,
+		PermissionUnlock:         scopeAny,
+		PermissionManageMFA:      scopeOwn,
+		PermissionRevokeSessions: scopeOwn,
+		PermissionReadAudit:      scopeAny,
+		PermissionChangeEmail:    scopeOwn,
+	},
+	RoleSelf: {
+		PermissionRead:           scopeOwn,
+		PermissionUpdate:         scopeOwn,
+		PermissionManageMFA:      scopeOwn,
+		PermissionRevokeSessions: scopeOwn,
+		PermissionReadAudit:      scopeOwn,
+		PermissionExport:         scopeOwn,
+		PermissionChangeEmail:    scopeOwn,
+	},
+}
+
+// roleRanks orders the roles by their permissions, nobody acts on the account of a user ranked higher
+var roleRanks = map[Role]int{
+	RoleSelf:    1,
+	RoleSupport: 2,
+	RoleAdmin:   3,
+}
+
+// ParseRole converts a raw value into a known role
+func ParseRole(value string) (Role, error) {
+	role := Role(value)
+	if !role.IsValid() {
+		return "", ErrInvalidRole
+	}
+
+	return role, nil
+}
+
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+
+	return ok
+}
+
+// Can tells whether the role grants the permission, own says whether the caller acts on their own record
+func (r Role) Can(p Permission, own bool) bool {
+	switch rolePermissions[r][p] {
+	case scopeAny:
+		return true
+	case scopeOwn:
+		return own
+	default:
+		return false
+	}
+}
+
+// Outranks tells whether the role is ranked higher than the other one
+func (r Role) Outranks(other Role) bool {
+	return roleRanks[r] > roleRanks[other]
+}
+
+// MFAPolicy tells which roles are granted only to users with MFA enabled
+type MFAPolicy struct {
+	requiredRoles []Role
+}
+
+func NewMFAPolicy(requiredRoles []Role) *MFAPolicy {
+	return &MFAPolicy{
+		requiredRoles: requiredRoles,
+	}
+}
+
+// Requires tells whether users of the role must have MFA enabled
+func (p *MFAPolicy) Requires(role Role) bool {
+	return slices.Contains(p.requiredRoles, role)
+}
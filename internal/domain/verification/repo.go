@@ -0,0 +1,19 @@
+package verification
+
+import (
+	"context"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+type Repository interface {
+	Create(ctx context.Context, t *Token, createdAt time.Time) error
+
+	// Consume marks the token as used and returns it. ErrInvalidToken is returned when there is no such token
+	// for the purpose, or it has been used or has expired by now.
+	Consume(ctx context.Context, hash string, purpose Purpose, now time.Time) (*Token, error)
+
+	// Revoke invalidates all unused tokens of the user issued for the purpose
+	Revoke(ctx context.Context, userID domain.ID, purpose Purpose) error
+}
@@ -0,0 +1,58 @@
+package verification
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+// Purpose tells what a token proves, a token is accepted only for the purpose it was issued for
+type Purpose string
+
+const (
+	PurposeEmail         Purpose = "email"
+	PurposePasswordReset Purpose = "password_reset"
+	PurposeMFALogin      Purpose = "mfa_login"
+)
+
+// secretSize is the number of random bytes of a secret
+const secretSize = 32
+
+// Token is a single-use and time-limited proof sent to a user, only the hash of its secret is stored
+type Token struct {
+	Hash      string
+	UserID    domain.ID
+	Purpose   Purpose
+	Email     string // the address the secret was sent to
+	ExpiresAt time.Time
+}
+
+// New generates a random secret and the token to be stored for it, the secret itself is only sent to the user
+func New(userID domain.ID, purpose Purpose, email string, expiresAt time.Time) (string, *Token, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("failed generating secret: %w", err)
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(b)
+
+	return secret, &Token{
+		Hash:      Hash(secret),
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Hash returns the form a secret is stored and looked up in
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
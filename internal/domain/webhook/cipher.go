@@ -0,0 +1,7 @@
+package webhook
+
+// Cipher encrypts the secrets of subscriptions before they are stored, they are needed in plain to sign the deliveries
+type Cipher interface {
+	Encrypt(plaintext []byte) (string, error)
+	Decrypt(ciphertext string) ([]byte, error)
+}
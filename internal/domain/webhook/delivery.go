@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+)
+
+// maxErrorLength keeps the stored error of an attempt short, e.g. when a response is echoed in it
+const maxErrorLength = 1024
+
+// Status is the stage of a delivery
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusSucceeded Status = "succeeded"
+
+	// StatusDead is a delivery which has failed all its attempts, it is kept until it is redelivered
+	StatusDead Status = "dead"
+)
+
+// ParseStatus converts a raw value into a known status
+func ParseStatus(value string) (Status, error) {
+	status := Status(value)
+
+	switch status {
+	case StatusPending, StatusSucceeded, StatusDead:
+		return status, nil
+	default:
+		return "", ErrInvalidStatus
+	}
+}
+
+// Delivery is an event sent to a subscriber, attempted until it succeeds or the retry policy gives up
+type Delivery struct {
+	ID             domain.ID    `json:"id"`
+	Seq            int64        `json:"-"` // storage id, orders the deliveries
+	SubscriptionID domain.ID    `json:"subscription_id"`
+	Event          *event.Event `json:"event"`
+	Status         Status       `json:"status"`
+	Attempts       int          `json:"attempts"`
+	NextAttemptAt  *time.Time   `json:"next_attempt_at"` // nil once the delivery has succeeded or is dead
+	LastAttemptAt  *time.Time   `json:"last_attempt_at"`
+	LastStatusCode int          `json:"last_status_code,omitempty"` // zero when no response has been received
+	LastError      string       `json:"last_error,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// NewDelivery creates a delivery of the event, attempted right away
+func NewDelivery(subscriptionID domain.ID, e *event.Event, now time.Time) *Delivery {
+	return &Delivery{
+		ID:             domain.NewID(),
+		SubscriptionID: subscriptionID,
+		Event:          e,
+		Status:         StatusPending,
+		NextAttemptAt:  &now,
+		CreatedAt:      now,
+	}
+}
+
+// Succeeded records a successful attempt
+func (d *Delivery) Succeeded(at time.Time, statusCode int) {
+	d.attempted(at, statusCode, "")
+	d.Status = StatusSucceeded
+	d.NextAttemptAt = nil
+}
+
+// Failed records a failed attempt and schedules the next one, the delivery is dead when the policy gives up
+func (d *Delivery) Failed(at time.Time, statusCode int, reason string, policy *RetryPolicy) {
+	d.attempted(at, statusCode, reason)
+
+	delay, ok := policy.Delay(d.Attempts)
+	if !ok {
+		d.Status = StatusDead
+		d.NextAttemptAt = nil
+
+		return
+	}
+
+	next := at.Add(delay)
+	d.NextAttemptAt = &next
+}
+
+// Redeliver schedules a dead delivery for another round of attempts
+func (d *Delivery) Redeliver(now time.Time) error {
+	if d.Status != StatusDead {
+		return ErrNotDead
+	}
+
+	d.Status = StatusPending
+	d.Attempts = 0
+	d.NextAttemptAt = &now
+
+	return nil
+}
+
+func (d *Delivery) attempted(at time.Time, statusCode int, reason string) {
+	if len(reason) > maxErrorLength {
+		reason = reason[:maxErrorLength]
+	}
+
+	d.Attempts++
+	d.LastAttemptAt = &at
+	d.LastStatusCode = statusCode
+	d.LastError = reason
+}
+
+// RetryPolicy decides when failed deliveries are attempted again
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Delay returns how long to wait after the given number of failed attempts, false when no attempts are left.
+// The base delay doubles with every further attempt up to the max delay.
+func (p *RetryPolicy) Delay(attempts int) (time.Duration, bool) {
+	if attempts >= p.MaxAttempts {
+		return 0, false
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, p.MaxDelay), true
+}
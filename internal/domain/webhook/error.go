@@ -0,0 +1,15 @@
+package webhook
+
+import "errors"
+
+var (
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	ErrDeliveryNotFound     = errors.New("delivery not found")
+	ErrInvalidURL           = errors.New("invalid url")
+	ErrPrivateURL           = errors.New("url of a private network")
+	ErrNoEventTypes         = errors.New("no event types")
+	ErrInvalidSecret        = errors.New("invalid secret")
+	ErrInvalidStatus        = errors.New("invalid status")
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrNotDead              = errors.New("delivery is not dead")
+)
@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"encoding/base64"
+	"strconv"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+// DeliveryQuery lists the deliveries of a subscription newest first, paginated with a cursor
+type DeliveryQuery struct {
+	SubscriptionID domain.ID
+	Status         Status  // empty for all
+	Cursor         *Cursor // nil for the first page
+	Limit          int
+}
+
+// DeliveryPage is a single page of listed deliveries
+type DeliveryPage struct {
+	Deliveries []*Delivery
+	NextCursor *Cursor // nil on the last page
+}
+
+// Cursor points at the last delivery of a page, the next page starts right after it
+type Cursor struct {
+	Seq int64
+}
+
+// Encode returns the cursor as a URL safe token
+func (c *Cursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.Seq, 10)))
+}
+
+// DecodeCursor parses a token returned by Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	seq, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || seq < 1 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{Seq: seq}, nil
+}
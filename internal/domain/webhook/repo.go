@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+type SubscriptionRepository interface {
+	Create(ctx context.Context, s *Subscription) error
+
+	// Get returns the subscription, ErrSubscriptionNotFound when there is no such subscription
+	Get(ctx context.Context, id domain.ID) (*Subscription, error)
+
+	// List returns all subscriptions, the oldest first
+	List(ctx context.Context) ([]*Subscription, error)
+
+	// Update stores the URL, the event types and the secret, ErrSubscriptionNotFound is returned when there is no such subscription
+	Update(ctx context.Context, s *Subscription) error
+
+	// Delete removes the subscription along with its deliveries, ErrSubscriptionNotFound is returned when there is no such subscription
+	Delete(ctx context.Context, id domain.ID) error
+}
+
+type DeliveryRepository interface {
+	Create(ctx context.Context, d *Delivery) error
+
+	// Get returns the delivery of the subscription, ErrDeliveryNotFound when there is no such delivery
+	Get(ctx context.Context, subscriptionID, id domain.ID) (*Delivery, error)
+
+	// Claim returns the pending deliveries whose next attempt is due, the longest waiting first, and postpones their
+	// next attempt until the lease ends, so concurrent dispatchers do not send them twice. A delivery whose outcome
+	// has not been recorded by then, e.g. because the dispatcher has crashed, is due again.
+	Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*Delivery, error)
+
+	// Update stores the outcome of an attempt
+	Update(ctx context.Context, d *Delivery) error
+
+	List(ctx context.Context, q *DeliveryQuery) (*DeliveryPage, error)
+
+	// Erase empties the event data of the deliveries of the user's events, pending ones are sent without it
+	Erase(ctx context.Context, userID domain.ID) error
+}
+
+// Sender posts a delivery to the URL, signed with the secret. It returns the status code of the response,
+// zero when there has been none, and an error when the delivery has failed, including a response other than 2xx.
+type Sender interface {
+	Send(ctx context.Context, url string, secret []byte, d *Delivery) (int, error)
+}
@@ -0,0 +1,27 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// SignaturePrefix names the algorithm in the signature header, so it can be changed without breaking subscribers
+const SignaturePrefix = "sha256="
+
+// Sign returns the HMAC-SHA256 of the timestamp and the body joined with a dot. Signing the timestamp
+// lets subscribers reject replayed deliveries, the body alone could be sent again at any time.
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return SignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify tells whether the signature is the one of the timestamp and the body, compared in constant time
+func Verify(secret []byte, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
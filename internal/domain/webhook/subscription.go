@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+)
+
+const (
+	// secretSize is the number of random bytes of a generated secret
+	secretSize = 32
+
+	// MinSecretLength keeps secrets chosen by the subscriber from being guessed
+	MinSecretLength = 16
+)
+
+// Subscription sends the events of the chosen types to the URL of a subscriber
+type Subscription struct {
+	ID              domain.ID    `json:"id"`
+	URL             string       `json:"url"`
+	EventTypes      []event.Type `json:"event_types"`
+	EncryptedSecret string       `json:"-"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+}
+
+// ValidateURL accepts absolute http and https URLs only, unless private networks are allowed the host can not be
+// localhost or an address IsPublicAddr rejects; names resolving to such addresses are refused by the sender when dialing
+func ValidateURL(rawURL string, allowPrivate bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Fragment != "" {
+		return ErrInvalidURL
+	}
+
+	if allowPrivate {
+		return nil
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateURL
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil && !IsPublicAddr(addr) {
+		return ErrPrivateURL
+	}
+
+	return nil
+}
+
+// IsPublicAddr reports whether the address is not loopback, link-local, private or unspecified
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsPrivate() &&
+		!addr.IsUnspecified()
+}
+
+// ParseEventTypes converts raw values into known event types, duplicates are dropped
+func ParseEventTypes(values []string) ([]event.Type, error) {
+	if len(values) == 0 {
+		return nil, ErrNoEventTypes
+	}
+
+	types := make([]event.Type, 0, len(values))
+
+	for _, value := range values {
+		t, err := event.ParseType(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+
+	return types, nil
+}
+
+// Matches tells whether the subscriber wants events of the type
+func (s *Subscription) Matches(t event.Type) bool {
+	return slices.Contains(s.EventTypes, t)
+}
+
+// ValidateSecret checks a secret chosen by the subscriber
+func ValidateSecret(secret string) error {
+	if len(secret) < MinSecretLength {
+		return ErrInvalidSecret
+	}
+
+	return nil
+}
+
+// NewSecret generates a secret for a subscriber who has not chosen one
+func NewSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed generating secret: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
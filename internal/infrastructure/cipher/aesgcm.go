@@ -0,0 +1,76 @@
+package cipher
+
+import (
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+)
+
+// KeySize is the size of the AES-256 key
+const KeySize = 32
+
+var ErrMalformed = errors.New("malformed ciphertext")
+
+type aesGCM struct {
+	aead gocipher.AEAD
+}
+
+var (
+	_ mfa.Cipher     = (*aesGCM)(nil)
+	_ oidc.Cipher    = (*aesGCM)(nil)
+	_ webhook.Cipher = (*aesGCM)(nil)
+)
+
+// NewAESGCM creates a cipher encrypting with AES-256 in GCM mode, which also detects tampered ciphertexts
+func NewAESGCM(key []byte) (*aesGCM, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes long", KeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := gocipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGCM{
+		aead: aead,
+	}, nil
+}
+
+// Encrypt returns the random nonce followed by the sealed plaintext, base64 encoded
+func (c *aesGCM) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed generating nonce: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(c.aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+func (c *aesGCM) Decrypt(ciphertext string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+
+	plaintext, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed decrypting: %w", err)
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,66 @@
+package cipher
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAESGCM(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, KeySize)
+
+	t.Run("round trip", func(t *testing.T) {
+		c, err := NewAESGCM(key)
+		assert.NoError(t, err)
+
+		ciphertext, err := c.Encrypt([]byte("secret"))
+		assert.NoError(t, err)
+		assert.NotContains(t, ciphertext, "secret")
+
+		other, err := c.Encrypt([]byte("secret"))
+		assert.NoError(t, err)
+		assert.NotEqual(t, ciphertext, other) // every encryption uses a new nonce
+
+		plaintext, err := c.Decrypt(ciphertext)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("secret"), plaintext)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		c, _ := NewAESGCM(key)
+		other, _ := NewAESGCM(bytes.Repeat([]byte{2}, KeySize))
+
+		ciphertext, _ := c.Encrypt([]byte("secret"))
+
+		_, err := other.Decrypt(ciphertext)
+		assert.Error(t, err)
+	})
+
+	t.Run("tampered ciphertext", func(t *testing.T) {
+		c, _ := NewAESGCM(key)
+
+		ciphertext, _ := c.Encrypt([]byte("secret"))
+		sealed, _ := base64.StdEncoding.DecodeString(ciphertext)
+		sealed[len(sealed)-1] ^= 1
+
+		_, err := c.Decrypt(base64.StdEncoding.EncodeToString(sealed))
+		assert.Error(t, err)
+	})
+
+	t.Run("malformed ciphertext", func(t *testing.T) {
+		c, _ := NewAESGCM(key)
+
+		_, err := c.Decrypt("not base64!")
+		assert.ErrorIs(t, err, ErrMalformed)
+
+		_, err = c.Decrypt("c2hvcnQ=")
+		assert.ErrorIs(t, err, ErrMalformed)
+	})
+
+	t.Run("invalid key size", func(t *testing.T) {
+		_, err := NewAESGCM([]byte("short"))
+		assert.Error(t, err)
+	})
+}
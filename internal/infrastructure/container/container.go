@@ -1,46 +1,227 @@
 package container
 
 import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/go-playground/validator/v10"
 	"github.com/sarulabs/di"
 
 	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
 	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mail"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
 	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/cipher"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/database/memory"
 	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/database/mysql"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/hasher"
 	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/httpserver/handlers"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/httpserver/middleware"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/mailer"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/outbox"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/publisher"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/token"
 	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
-	"github.com/wojciechpawlinow/usermanagement/pkg/time"
+	"github.com/wojciechpawlinow/usermanagement/pkg/ratelimit"
+	timeutil "github.com/wojciechpawlinow/usermanagement/pkg/time"
+	"github.com/wojciechpawlinow/usermanagement/pkg/worker"
+)
+
+const (
+	DriverMySQL  = "mysql"
+	DriverMemory = "memory"
+
+	MailerLog  = "log"
+	MailerFile = "file"
+
+	PublisherStdout  = "stdout"
+	PublisherWebhook = "webhook"
 )
 
 func New() di.Container {
 	builder, _ := di.NewBuilder()
 
+	switch driver := config.Load().GetString("REPOSITORY_DRIVER"); driver {
+	case DriverMySQL:
+		addMySQLDefs(builder)
+	case DriverMemory:
+		addMemoryDefs(builder)
+	default:
+		logger.Fatal(fmt.Errorf("unsupported repository driver: %s", driver))
+	}
+
 	if err := builder.Add(di.Def{
-		Name: "mysql-conns",
+		Name: "service-user",
 		Build: func(ctn di.Container) (interface{}, error) {
-			return mysql.GetConnections(config.Load())
+			return service.NewUserService(
+				ctn.Get("repo-user").(user.Repository),
+				ctn.Get("repo-session").(session.Repository),
+				ctn.Get("unit-of-work").(domain.UnitOfWork),
+				ctn.Get("repo-audit").(audit.Repository),
+				ctn.Get("repo-outbox").(event.Outbox),
+				timeutil.NewTimeService(),
+				ctn.Get("service-email").(service.EmailVerifier),
+				ctn.Get("password-policy").(*user.PasswordPolicy),
+				ctn.Get("password-hasher").(auth.PasswordHasher),
+				ctn.Get("mfa-policy").(*user.MFAPolicy),
+			), nil
 		},
 	}); err != nil {
-		logger.Fatal(err) // crucial functionality, we can't continue without it
+		logger.Error(err)
 	}
 
 	if err := builder.Add(di.Def{
-		Name: "repo-user",
+		Name: "password-hasher",
 		Build: func(ctn di.Container) (interface{}, error) {
-			conns := ctn.Get("mysql-conns").(*mysql.Connections)
-			return mysql.NewUserRepository(conns.Read, conns.Write), nil
+			cfg := config.Load()
+
+			bcrypt, err := hasher.NewBcrypt(cfg.GetInt("PASSWORD_BCRYPT_COST"))
+			if err != nil {
+				return nil, err
+			}
+
+			argon2id, err := hasher.NewArgon2id(hasher.Argon2idParams{
+				Memory:      uint32(cfg.GetInt("PASSWORD_ARGON2_MEMORY_KIB")),
+				Iterations:  uint32(cfg.GetInt("PASSWORD_ARGON2_ITERATIONS")),
+				Parallelism: uint8(cfg.GetInt("PASSWORD_ARGON2_PARALLELISM")),
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			switch algorithm := cfg.GetString("PASSWORD_HASH_ALGORITHM"); algorithm {
+			case hasher.AlgorithmArgon2id:
+				return hasher.New(argon2id, bcrypt), nil
+			case hasher.AlgorithmBcrypt:
+				return hasher.New(bcrypt, argon2id), nil
+			default:
+				return nil, fmt.Errorf("unsupported password hash algorithm: %s", algorithm)
+			}
 		},
 	}); err != nil {
 		logger.Error(err)
 	}
 
 	if err := builder.Add(di.Def{
-		Name: "service-user",
+		Name: "password-policy",
 		Build: func(ctn di.Container) (interface{}, error) {
-			return service.NewUserService(
+			cfg := config.Load()
+
+			var classes []user.CharClass
+			for _, value := range config.SplitList(cfg.GetString("PASSWORD_REQUIRED_CLASSES")) {
+				class, err := user.ParseCharClass(value)
+				if err != nil {
+					return nil, err
+				}
+				classes = append(classes, class)
+			}
+
+			return user.NewPasswordPolicy(
+				cfg.GetInt("PASSWORD_MIN_LENGTH"),
+				classes,
+				config.SplitList(cfg.GetString("PASSWORD_DENY_LIST")),
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "mailer",
+		Build: func(ctn di.Container) (interface{}, error) {
+			cfg := config.Load()
+
+			switch driver := cfg.GetString("MAILER_DRIVER"); driver {
+			case MailerLog:
+				return mailer.NewLogMailer(cfg.GetString("MAILER_FROM")), nil
+			case MailerFile:
+				return mailer.NewFileMailer(cfg.GetString("MAILER_FILE_PATH"), cfg.GetString("MAILER_FROM"), timeutil.NewTimeService()), nil
+			default:
+				return nil, fmt.Errorf("unsupported mailer driver: %s", driver)
+			}
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "service-email",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return service.NewEmailService(
+				ctn.Get("repo-user").(user.Repository),
+				ctn.Get("repo-verification").(verification.Repository),
+				ctn.Get("unit-of-work").(domain.UnitOfWork),
+				ctn.Get("repo-audit").(audit.Repository),
+				ctn.Get("repo-outbox").(event.Outbox),
+				ctn.Get("mailer").(mail.Mailer),
+				timeutil.NewTimeService(),
+				time.Duration(config.Load().GetInt("EMAIL_VERIFICATION_TTL_MINUTES"))*time.Minute,
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "http-email",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return handlers.NewEmailHTTPHandler(
+				validator.New(),
+				ctn.Get("service-email").(service.EmailPort),
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "service-password",
+		Build: func(ctn di.Container) (interface{}, error) {
+			cfg := config.Load()
+
+			return service.NewPasswordService(
 				ctn.Get("repo-user").(user.Repository),
-				time.NewTimeService(),
+				ctn.Get("repo-verification").(verification.Repository),
+				ctn.Get("repo-session").(session.Repository),
+				ctn.Get("unit-of-work").(domain.UnitOfWork),
+				ctn.Get("repo-audit").(audit.Repository),
+				ctn.Get("mailer").(mail.Mailer),
+				ratelimit.New(
+					cfg.GetInt("PASSWORD_RESET_RATE_LIMIT"),
+					time.Duration(cfg.GetInt("PASSWORD_RESET_RATE_WINDOW_MINUTES"))*time.Minute,
+					timeutil.NewTimeService(),
+				),
+				ctn.Get("password-policy").(*user.PasswordPolicy),
+				ctn.Get("password-hasher").(auth.PasswordHasher),
+				ctn.Get("repo-lockout").(lockout.Repository),
+				ctn.Get("lockout-policy").(*lockout.Policy),
+				timeutil.NewTimeService(),
+				time.Duration(cfg.GetInt("PASSWORD_RESET_TTL_MINUTES"))*time.Minute,
+				time.Duration(cfg.GetInt("PASSWORD_RESET_DELAY_MS"))*time.Millisecond,
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "http-password",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return handlers.NewPasswordHTTPHandler(
+				validator.New(),
+				ctn.Get("service-password").(service.PasswordPort),
 			), nil
 		},
 	}); err != nil {
@@ -53,11 +234,751 @@ func New() di.Container {
 			return handlers.NewUserHTTPHandler(
 				validator.New(),
 				ctn.Get("service-user").(service.UserPort),
+				config.Load().GetInt("PAGINATION_MAX_SIZE"),
+				config.Load().GetString("HTTP_REQUIRE_IF_MATCH") == "true",
 			), nil
 		},
 	}); err != nil {
 		logger.Error(err)
 	}
 
-	return builder.Build()
+	if err := builder.Add(di.Def{
+		Name: "token-provider",
+		Build: func(ctn di.Container) (interface{}, error) {
+			cfg := config.Load()
+
+			return token.NewJWTProvider(
+				cfg.GetString("AUTH_TOKEN_SECRET"),
+				cfg.GetString("AUTH_TOKEN_ISSUER"),
+				time.Duration(cfg.GetInt("AUTH_TOKEN_TTL_MINUTES"))*time.Minute,
+				timeutil.NewTimeService(),
+			)
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "service-auth",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return service.NewAuthService(
+				ctn.Get("repo-user").(user.Repository),
+				ctn.Get("token-provider").(auth.TokenProvider),
+				ctn.Get("password-hasher").(auth.PasswordHasher),
+				ctn.Get("repo-lockout").(lockout.Repository),
+				ctn.Get("lockout-policy").(*lockout.Policy),
+				ctn.Get("repo-verification").(verification.Repository),
+				ctn.Get("repo-mfa").(mfa.Repository),
+				ctn.Get("mfa-cipher").(mfa.Cipher),
+				ctn.Get("repo-session").(session.Repository),
+				timeutil.NewTimeService(),
+				time.Duration(config.Load().GetInt("MFA_LOGIN_TTL_MINUTES"))*time.Minute,
+				time.Duration(config.Load().GetInt("AUTH_REFRESH_TOKEN_TTL_HOURS"))*time.Hour,
+			)
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "service-session",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return service.NewSessionService(
+				ctn.Get("repo-user").(user.Repository),
+				ctn.Get("repo-session").(session.Repository),
+				ctn.Get("unit-of-work").(domain.UnitOfWork),
+				ctn.Get("token-provider").(auth.TokenProvider),
+				timeutil.NewTimeService(),
+				time.Duration(config.Load().GetInt("AUTH_REFRESH_TOKEN_TTL_HOURS"))*time.Hour,
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "http-session",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return handlers.NewSessionHTTPHandler(
+				validator.New(),
+				ctn.Get("service-session").(service.SessionPort),
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "mfa-cipher",
+		Build: func(ctn di.Container) (interface{}, error) {
+			key, err := base64.StdEncoding.DecodeString(config.Load().GetString("MFA_ENCRYPTION_KEY"))
+			if err != nil {
+				return nil, fmt.Errorf("failed decoding mfa encryption key: %w", err)
+			}
+
+			return cipher.NewAESGCM(key)
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "mfa-policy",
+		Build: func(ctn di.Container) (interface{}, error) {
+			var roles []user.Role
+			for _, value := range config.SplitList(config.Load().GetString("MFA_REQUIRED_ROLES")) {
+				role, err := user.ParseRole(value)
+				if err != nil {
+					return nil, err
+				}
+				roles = append(roles, role)
+			}
+
+			return user.NewMFAPolicy(roles), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "service-mfa",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return service.NewMFAService(
+				ctn.Get("repo-user").(user.Repository),
+				ctn.Get("repo-mfa").(mfa.Repository),
+				ctn.Get("repo-session").(session.Repository),
+				ctn.Get("unit-of-work").(domain.UnitOfWork),
+				ctn.Get("mfa-cipher").(mfa.Cipher),
+				ctn.Get("mfa-policy").(*user.MFAPolicy),
+				timeutil.NewTimeService(),
+				config.Load().GetString("MFA_ISSUER"),
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "http-mfa",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return handlers.NewMFAHTTPHandler(
+				validator.New(),
+				ctn.Get("service-mfa").(service.MFAPort),
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "oidc-cipher",
+		Build: func(ctn di.Container) (interface{}, error) {
+			key, err := base64.StdEncoding.DecodeString(config.Load().GetString("OIDC_KEY_ENCRYPTION_KEY"))
+			if err != nil {
+				return nil, fmt.Errorf("failed decoding oidc key encryption key: %w", err)
+			}
+
+			return cipher.NewAESGCM(key)
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "oidc-signer",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return token.NewKeyRing(
+				ctn.Get("repo-oidc-key").(oidc.KeyRepository),
+				ctn.Get("oidc-cipher").(oidc.Cipher),
+				config.Load().GetString("OIDC_SIGNING_ALGORITHM"),
+				time.Duration(config.Load().GetInt("OIDC_KEY_ROTATION_HOURS"))*time.Hour,
+				timeutil.NewTimeService(),
+			)
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "service-oidc",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return service.NewOIDCService(
+				ctn.Get("repo-user").(user.Repository),
+				ctn.Get("repo-oidc-client").(oidc.ClientRepository),
+				ctn.Get("repo-oidc-code").(oidc.CodeRepository),
+				ctn.Get("oidc-signer").(oidc.Signer),
+				timeutil.NewTimeService(),
+				config.Load().GetString("OIDC_ISSUER"),
+				time.Duration(config.Load().GetInt("OIDC_CODE_TTL_SECONDS"))*time.Second,
+				time.Duration(config.Load().GetInt("OIDC_ACCESS_TOKEN_TTL_MINUTES"))*time.Minute,
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "http-oauth",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return handlers.NewOAuthHTTPHandler(
+				validator.New(),
+				ctn.Get("service-oidc").(service.OIDCPort),
+				ctn.Get("service-auth").(service.AuthPort),
+				config.Load().GetString("OIDC_ISSUER"),
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "service-retention",
+		Build: func(ctn di.Container) (interface{}, error) {
+			cfg := config.Load()
+
+			return service.NewRetentionService(
+				ctn.Get("repo-user").(user.Repository),
+				timeutil.NewTimeService(),
+				time.Duration(cfg.GetInt("USERS_RETENTION_DAYS"))*24*time.Hour,
+				cfg.GetInt("USERS_PURGE_BATCH_SIZE"),
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "worker-retention",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return worker.New(
+				"deleted users purge",
+				time.Duration(config.Load().GetInt("USERS_PURGE_INTERVAL_MINUTES"))*time.Minute,
+				ctn.Get("service-retention").(service.RetentionPort).PurgeDeleted,
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "service-audit",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return service.NewAuditService(ctn.Get("repo-audit").(audit.Repository)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "http-audit",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return handlers.NewAuditHTTPHandler(
+				validator.New(),
+				ctn.Get("service-audit").(service.AuditPort),
+				config.Load().GetInt("PAGINATION_MAX_SIZE"),
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "service-privacy",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return service.NewPrivacyService(
+				ctn.Get("repo-user").(user.Repository),
+				ctn.Get("repo-session").(session.Repository),
+				ctn.Get("repo-audit").(audit.Repository),
+				ctn.Get("repo-outbox").(event.Outbox),
+				ctn.Get("repo-webhook-delivery").(webhook.DeliveryRepository),
+				ctn.Get("unit-of-work").(domain.UnitOfWork),
+				timeutil.NewTimeService(),
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "http-privacy",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return handlers.NewPrivacyHTTPHandler(ctn.Get("service-privacy").(service.PrivacyPort)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "event-publisher",
+		Build: func(ctn di.Container) (interface{}, error) {
+			cfg := config.Load()
+
+			var configured event.Publisher
+			switch driver := cfg.GetString("EVENTS_PUBLISHER"); driver {
+			case PublisherStdout:
+				configured = publisher.NewStdoutPublisher(os.Stdout)
+			case PublisherWebhook:
+				p, err := publisher.NewWebhookPublisher(
+					cfg.GetString("EVENTS_WEBHOOK_URL"),
+					time.Duration(cfg.GetInt("EVENTS_WEBHOOK_TIMEOUT_SECONDS"))*time.Second,
+				)
+				if err != nil {
+					return nil, err
+				}
+				configured = p
+			default:
+				return nil, fmt.Errorf("unsupported events publisher: %s", driver)
+			}
+
+			// webhook subscriptions receive every event next to the configured publisher
+			return publisher.NewMultiPublisher(configured, ctn.Get("webhook-dispatcher").(event.Publisher)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "outbox-relay",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return outbox.NewRelay(
+				ctn.Get("repo-outbox").(event.Outbox),
+				ctn.Get("event-publisher").(event.Publisher),
+				ctn.Get("unit-of-work").(domain.UnitOfWork),
+				timeutil.NewTimeService(),
+				config.Load().GetInt("EVENTS_RELAY_BATCH_SIZE"),
+				time.Duration(config.Load().GetInt("EVENTS_RELAY_LEASE_SECONDS"))*time.Second,
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "worker-outbox",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return worker.New(
+				"outbox relay",
+				time.Duration(config.Load().GetInt("EVENTS_RELAY_INTERVAL_SECONDS"))*time.Second,
+				ctn.Get("outbox-relay").(*outbox.Relay).PublishPending,
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "webhook-cipher",
+		Build: func(ctn di.Container) (interface{}, error) {
+			key, err := base64.StdEncoding.DecodeString(config.Load().GetString("WEBHOOK_ENCRYPTION_KEY"))
+			if err != nil {
+				return nil, fmt.Errorf("failed decoding webhook encryption key: %w", err)
+			}
+
+			return cipher.NewAESGCM(key)
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "service-webhook",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return service.NewWebhookService(
+				ctn.Get("repo-webhook-subscription").(webhook.SubscriptionRepository),
+				ctn.Get("repo-webhook-delivery").(webhook.DeliveryRepository),
+				ctn.Get("unit-of-work").(domain.UnitOfWork),
+				ctn.Get("webhook-cipher").(webhook.Cipher),
+				timeutil.NewTimeService(),
+				config.Load().GetString("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true",
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "webhook-dispatcher",
+		Build: func(ctn di.Container) (interface{}, error) {
+			cfg := config.Load()
+
+			return service.NewWebhookDispatcher(
+				ctn.Get("repo-webhook-subscription").(webhook.SubscriptionRepository),
+				ctn.Get("repo-webhook-delivery").(webhook.DeliveryRepository),
+				ctn.Get("webhook-cipher").(webhook.Cipher),
+				publisher.NewSignedSender(
+					time.Duration(cfg.GetInt("WEBHOOK_TIMEOUT_SECONDS"))*time.Second,
+					cfg.GetString("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true",
+					timeutil.NewTimeService(),
+				),
+				&webhook.RetryPolicy{
+					MaxAttempts: cfg.GetInt("WEBHOOK_MAX_ATTEMPTS"),
+					BaseDelay:   time.Duration(cfg.GetInt("WEBHOOK_RETRY_BASE_SECONDS")) * time.Second,
+					MaxDelay:    time.Duration(cfg.GetInt("WEBHOOK_RETRY_MAX_MINUTES")) * time.Minute,
+				},
+				timeutil.NewTimeService(),
+				cfg.GetInt("WEBHOOK_DISPATCH_BATCH_SIZE"),
+				time.Duration(cfg.GetInt("WEBHOOK_DISPATCH_LEASE_SECONDS"))*time.Second,
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "worker-webhooks",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return worker.New(
+				"webhook dispatcher",
+				time.Duration(config.Load().GetInt("WEBHOOK_DISPATCH_INTERVAL_SECONDS"))*time.Second,
+				ctn.Get("webhook-dispatcher").(service.WebhookDispatcherPort).DeliverDue,
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "http-webhook",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return handlers.NewWebhookHTTPHandler(
+				validator.New(),
+				ctn.Get("service-webhook").(service.WebhookPort),
+				config.Load().GetInt("PAGINATION_MAX_SIZE"),
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "lockout-policy",
+		Build: func(ctn di.Container) (interface{}, error) {
+			cfg := config.Load()
+
+			return &lockout.Policy{
+				UserThreshold: cfg.GetInt("LOCKOUT_USER_THRESHOLD"),
+				IPThreshold:   cfg.GetInt("LOCKOUT_IP_THRESHOLD"),
+				BaseDelay:     time.Duration(cfg.GetInt("LOCKOUT_BASE_DELAY_SECONDS")) * time.Second,
+				MaxDelay:      time.Duration(cfg.GetInt("LOCKOUT_MAX_DELAY_MINUTES")) * time.Minute,
+				Window:        time.Duration(cfg.GetInt("LOCKOUT_WINDOW_MINUTES")) * time.Minute,
+			}, nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "service-lockout",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return service.NewLockoutService(
+				ctn.Get("repo-user").(user.Repository),
+				ctn.Get("repo-lockout").(lockout.Repository),
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "http-lockout",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return handlers.NewLockoutHTTPHandler(
+				ctn.Get("service-lockout").(service.LockoutPort),
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "http-auth",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return handlers.NewAuthHTTPHandler(
+				validator.New(),
+				ctn.Get("service-auth").(service.AuthPort),
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "middleware-auth",
+		Build: func(ctn di.Container) (interface{}, error) {
+			cfg := config.Load()
+
+			return middleware.NewAuthenticator(
+				ctn.Get("token-provider").(auth.TokenProvider),
+				config.SplitList(cfg.GetString("AUTH_API_KEYS")),
+				config.SplitList(cfg.GetString("AUTH_PROTECTED_ROUTES")),
+				// OAuth clients and OIDC access tokens are not the API's credentials
+				[]string{"POST /oauth/token", "GET /oauth/userinfo"},
+			), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	return builder.Build()
+}
+
+func addMySQLDefs(builder *di.Builder) {
+	if err := builder.Add(di.Def{
+		Name: "mysql-conns",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return mysql.GetConnections(config.Load())
+		},
+	}); err != nil {
+		logger.Fatal(err) // crucial functionality, we can't continue without it
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-user",
+		Build: func(ctn di.Container) (interface{}, error) {
+			conns := ctn.Get("mysql-conns").(*mysql.Connections)
+			return mysql.NewUserRepository(conns.Read, conns.Write), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-verification",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return mysql.NewVerificationRepository(ctn.Get("mysql-conns").(*mysql.Connections).Write), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-lockout",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return mysql.NewLockoutRepository(ctn.Get("mysql-conns").(*mysql.Connections).Write), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-mfa",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return mysql.NewMFARepository(ctn.Get("mysql-conns").(*mysql.Connections).Write), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-session",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return mysql.NewSessionRepository(ctn.Get("mysql-conns").(*mysql.Connections).Write), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-oidc-client",
+		Build: func(ctn di.Container) (interface{}, error) {
+			conns := ctn.Get("mysql-conns").(*mysql.Connections)
+			return mysql.NewClientRepository(conns.Read, conns.Write), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-oidc-code",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return mysql.NewAuthorizationCodeRepository(ctn.Get("mysql-conns").(*mysql.Connections).Write), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-oidc-key",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return mysql.NewSigningKeyRepository(ctn.Get("mysql-conns").(*mysql.Connections).Write), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-audit",
+		Build: func(ctn di.Container) (interface{}, error) {
+			conns := ctn.Get("mysql-conns").(*mysql.Connections)
+			return mysql.NewAuditRepository(conns.Read, conns.Write), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-outbox",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return mysql.NewOutboxRepository(ctn.Get("mysql-conns").(*mysql.Connections).Write), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-webhook-subscription",
+		Build: func(ctn di.Container) (interface{}, error) {
+			conns := ctn.Get("mysql-conns").(*mysql.Connections)
+			return mysql.NewSubscriptionRepository(conns.Read, conns.Write), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-webhook-delivery",
+		Build: func(ctn di.Container) (interface{}, error) {
+			conns := ctn.Get("mysql-conns").(*mysql.Connections)
+			return mysql.NewDeliveryRepository(conns.Read, conns.Write), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "unit-of-work",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return mysql.NewUnitOfWork(ctn.Get("mysql-conns").(*mysql.Connections).Write), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+}
+
+func addMemoryDefs(builder *di.Builder) {
+	if err := builder.Add(di.Def{
+		Name: "memory-db",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewDatabase(), nil
+		},
+	}); err != nil {
+		logger.Fatal(err) // crucial functionality, we can't continue without it
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-user",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewUserRepository(ctn.Get("memory-db").(*memory.Database)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-verification",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewVerificationRepository(ctn.Get("memory-db").(*memory.Database)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-lockout",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewLockoutRepository(ctn.Get("memory-db").(*memory.Database)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-mfa",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewMFARepository(ctn.Get("memory-db").(*memory.Database)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-session",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewSessionRepository(ctn.Get("memory-db").(*memory.Database)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-oidc-client",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewClientRepository(ctn.Get("memory-db").(*memory.Database)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-oidc-code",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewAuthorizationCodeRepository(ctn.Get("memory-db").(*memory.Database)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-oidc-key",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewSigningKeyRepository(ctn.Get("memory-db").(*memory.Database)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-audit",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewAuditRepository(ctn.Get("memory-db").(*memory.Database)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-outbox",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewOutboxRepository(ctn.Get("memory-db").(*memory.Database)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-webhook-subscription",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewSubscriptionRepository(ctn.Get("memory-db").(*memory.Database)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "repo-webhook-delivery",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewDeliveryRepository(ctn.Get("memory-db").(*memory.Database)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
+
+	if err := builder.Add(di.Def{
+		Name: "unit-of-work",
+		Build: func(ctn di.Container) (interface{}, error) {
+			return memory.NewUnitOfWork(ctn.Get("memory-db").(*memory.Database)), nil
+		},
+	}); err != nil {
+		logger.Error(err)
+	}
 }
@@ -0,0 +1,394 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+)
+
+// Database is a process local storage mimicking the MySQL schema, meant for local development and tests
+type Database struct {
+	mu            sync.RWMutex
+	users         []*userRow
+	byUUID        map[string]*userRow
+	byEmail       map[string]*userRow
+	userSeq       int64
+	addresses     []*addressRow
+	tokens        []*tokenRow
+	attempts      map[lockout.Subject]*attemptRow
+	factors       []*factorRow
+	codes         []*recoveryCodeRow
+	refreshTokens []*refreshTokenRow
+	clients       []*clientRow
+	authCodes     []*authCodeRow
+	signingKeys   []*signingKeyRow
+	auditEntries  []*auditRow
+	auditSeq      int64
+	events        []*eventRow
+	subscriptions []*subscriptionRow
+	deliveries    []*deliveryRow
+	deliverySeq   int64
+}
+
+type userRow struct {
+	id            int64
+	uuid          string
+	email         string
+	emailVerified bool
+	mfaEnabled    bool
+	password      string
+	firstName     string
+	lastName      string
+	phoneNumber   string
+	role          user.Role
+	version       int64
+	createdAt     time.Time
+	deletedAt     *time.Time
+	erasedAt      *time.Time
+}
+
+type addressRow struct {
+	userID     int64
+	addrType   user.AddressType
+	street     string
+	city       string
+	state      string
+	postalCode string
+	country    string
+	createdAt  time.Time
+	deletedAt  *time.Time
+}
+
+type tokenRow struct {
+	userID    int64
+	purpose   verification.Purpose
+	hash      string
+	email     string
+	expiresAt time.Time
+	usedAt    *time.Time
+	createdAt time.Time
+}
+
+type factorRow struct {
+	userID       int64
+	secret       string
+	confirmedAt  *time.Time
+	lastUsedStep int64
+	createdAt    time.Time
+}
+
+type recoveryCodeRow struct {
+	userID int64
+	hash   string
+	usedAt *time.Time
+}
+
+type refreshTokenRow struct {
+	userID    int64
+	familyID  string
+	hash      string
+	expiresAt time.Time
+	usedAt    *time.Time
+	revokedAt *time.Time
+	createdAt time.Time
+}
+
+type clientRow struct {
+	clientID     string
+	name         string
+	secretHash   string
+	redirectURIs []string
+	createdAt    time.Time
+}
+
+type authCodeRow struct {
+	userID        int64
+	clientID      string
+	hash          string
+	redirectURI   string
+	scopes        []string
+	nonce         string
+	codeChallenge string
+	expiresAt     time.Time
+	usedAt        *time.Time
+	createdAt     time.Time
+}
+
+type signingKeyRow struct {
+	keyID      string
+	algorithm  string
+	privateKey string
+	publicKey  []byte
+	createdAt  time.Time
+}
+
+type auditRow struct {
+	id        int64
+	userUUID  string
+	actor     audit.Actor
+	action    audit.Action
+	changes   []audit.Change
+	requestID string
+	clientIP  string
+	createdAt time.Time
+}
+
+type eventRow struct {
+	event        event.Event
+	publishedAt  *time.Time
+	claimedUntil *time.Time
+}
+
+type subscriptionRow struct {
+	subscription webhook.Subscription
+}
+
+type deliveryRow struct {
+	delivery webhook.Delivery
+	event    event.Event
+}
+
+type attemptRow struct {
+	failures      int
+	lastFailureAt *time.Time
+	lockedUntil   *time.Time
+}
+
+// NewDatabase creates an empty in-memory database
+func NewDatabase() *Database {
+	return &Database{
+		byUUID:   make(map[string]*userRow),
+		byEmail:  make(map[string]*userRow),
+		attempts: make(map[lockout.Subject]*attemptRow),
+	}
+}
+
+type snapshot struct {
+	users         []userRow
+	userSeq       int64
+	addresses     []addressRow
+	tokens        []tokenRow
+	attempts      map[lockout.Subject]attemptRow
+	factors       []factorRow
+	codes         []recoveryCodeRow
+	refreshTokens []refreshTokenRow
+	clients       []clientRow
+	authCodes     []authCodeRow
+	signingKeys   []signingKeyRow
+	auditEntries  []auditRow
+	auditSeq      int64
+	events        []eventRow
+	subscriptions []subscriptionRow
+	deliveries    []deliveryRow
+	deliverySeq   int64
+}
+
+// lock takes the write lock unless the context carries a transaction of this database, which already holds it
+func (db *Database) lock(ctx context.Context) (unlock func()) {
+	if db.inTx(ctx) {
+		return func() {}
+	}
+
+	db.mu.Lock()
+
+	return db.mu.Unlock
+}
+
+// rlock takes the read lock unless the context carries a transaction of this database, which already holds the write lock
+func (db *Database) rlock(ctx context.Context) (unlock func()) {
+	if db.inTx(ctx) {
+		return func() {}
+	}
+
+	db.mu.RLock()
+
+	return db.mu.RUnlock
+}
+
+func (db *Database) inTx(ctx context.Context) bool {
+	tx, _ := ctx.Value(txKey{}).(*Database)
+
+	return tx == db
+}
+
+// snapshot copies all rows, as they are modified in place. The caller must hold the write lock.
+func (db *Database) snapshot() *snapshot {
+	s := &snapshot{
+		users:         make([]userRow, 0, len(db.users)),
+		userSeq:       db.userSeq,
+		addresses:     make([]addressRow, 0, len(db.addresses)),
+		tokens:        make([]tokenRow, 0, len(db.tokens)),
+		attempts:      make(map[lockout.Subject]attemptRow, len(db.attempts)),
+		factors:       make([]factorRow, 0, len(db.factors)),
+		codes:         make([]recoveryCodeRow, 0, len(db.codes)),
+		refreshTokens: make([]refreshTokenRow, 0, len(db.refreshTokens)),
+		clients:       make([]clientRow, 0, len(db.clients)),
+		authCodes:     make([]authCodeRow, 0, len(db.authCodes)),
+		signingKeys:   make([]signingKeyRow, 0, len(db.signingKeys)),
+		auditEntries:  make([]auditRow, 0, len(db.auditEntries)),
+		auditSeq:      db.auditSeq,
+		events:        make([]eventRow, 0, len(db.events)),
+		subscriptions: make([]subscriptionRow, 0, len(db.subscriptions)),
+		deliveries:    make([]deliveryRow, 0, len(db.deliveries)),
+		deliverySeq:   db.deliverySeq,
+	}
+
+	for _, row := range db.users {
+		s.users = append(s.users, *row)
+	}
+
+	for _, row := range db.addresses {
+		s.addresses = append(s.addresses, *row)
+	}
+
+	for _, row := range db.tokens {
+		s.tokens = append(s.tokens, *row)
+	}
+
+	for subject, row := range db.attempts {
+		s.attempts[subject] = *row
+	}
+
+	for _, row := range db.factors {
+		s.factors = append(s.factors, *row)
+	}
+
+	for _, row := range db.codes {
+		s.codes = append(s.codes, *row)
+	}
+
+	for _, row := range db.refreshTokens {
+		s.refreshTokens = append(s.refreshTokens, *row)
+	}
+
+	for _, row := range db.clients {
+		s.clients = append(s.clients, *row)
+	}
+
+	for _, row := range db.authCodes {
+		s.authCodes = append(s.authCodes, *row)
+	}
+
+	for _, row := range db.signingKeys {
+		s.signingKeys = append(s.signingKeys, *row)
+	}
+
+	for _, row := range db.auditEntries {
+		s.auditEntries = append(s.auditEntries, *row)
+	}
+
+	for _, row := range db.events {
+		s.events = append(s.events, *row)
+	}
+
+	for _, row := range db.subscriptions {
+		s.subscriptions = append(s.subscriptions, *row)
+	}
+
+	for _, row := range db.deliveries {
+		s.deliveries = append(s.deliveries, *row)
+	}
+
+	return s
+}
+
+// restore brings back the rows from the snapshot. The caller must hold the write lock.
+func (db *Database) restore(s *snapshot) {
+	db.users = make([]*userRow, 0, len(s.users))
+	db.byUUID = make(map[string]*userRow, len(s.users))
+	db.byEmail = make(map[string]*userRow, len(s.users))
+	db.userSeq = s.userSeq
+	db.addresses = make([]*addressRow, 0, len(s.addresses))
+	db.tokens = make([]*tokenRow, 0, len(s.tokens))
+	db.attempts = make(map[lockout.Subject]*attemptRow, len(s.attempts))
+	db.factors = make([]*factorRow, 0, len(s.factors))
+	db.codes = make([]*recoveryCodeRow, 0, len(s.codes))
+	db.refreshTokens = make([]*refreshTokenRow, 0, len(s.refreshTokens))
+	db.clients = make([]*clientRow, 0, len(s.clients))
+	db.authCodes = make([]*authCodeRow, 0, len(s.authCodes))
+	db.signingKeys = make([]*signingKeyRow, 0, len(s.signingKeys))
+	db.auditEntries = make([]*auditRow, 0, len(s.auditEntries))
+	db.auditSeq = s.auditSeq
+	db.events = make([]*eventRow, 0, len(s.events))
+	db.subscriptions = make([]*subscriptionRow, 0, len(s.subscriptions))
+	db.deliveries = make([]*deliveryRow, 0, len(s.deliveries))
+	db.deliverySeq = s.deliverySeq
+
+	for i := range s.users {
+		row := s.users[i]
+		db.users = append(db.users, &row)
+		db.byUUID[row.uuid] = &row
+		db.byEmail[row.email] = &row
+	}
+
+	for i := range s.addresses {
+		row := s.addresses[i]
+		db.addresses = append(db.addresses, &row)
+	}
+
+	for i := range s.tokens {
+		row := s.tokens[i]
+		db.tokens = append(db.tokens, &row)
+	}
+
+	for subject, row := range s.attempts {
+		db.attempts[subject] = &row
+	}
+
+	for i := range s.factors {
+		row := s.factors[i]
+		db.factors = append(db.factors, &row)
+	}
+
+	for i := range s.codes {
+		row := s.codes[i]
+		db.codes = append(db.codes, &row)
+	}
+
+	for i := range s.refreshTokens {
+		row := s.refreshTokens[i]
+		db.refreshTokens = append(db.refreshTokens, &row)
+	}
+
+	for i := range s.clients {
+		row := s.clients[i]
+		db.clients = append(db.clients, &row)
+	}
+
+	for i := range s.authCodes {
+		row := s.authCodes[i]
+		db.authCodes = append(db.authCodes, &row)
+	}
+
+	for i := range s.signingKeys {
+		row := s.signingKeys[i]
+		db.signingKeys = append(db.signingKeys, &row)
+	}
+
+	for i := range s.auditEntries {
+		row := s.auditEntries[i]
+		db.auditEntries = append(db.auditEntries, &row)
+	}
+
+	for i := range s.events {
+		row := s.events[i]
+		db.events = append(db.events, &row)
+	}
+
+	for i := range s.subscriptions {
+		row := s.subscriptions[i]
+		db.subscriptions = append(db.subscriptions, &row)
+	}
+
+	for i := range s.deliveries {
+		row := s.deliveries[i]
+		db.deliveries = append(db.deliveries, &row)
+	}
+}
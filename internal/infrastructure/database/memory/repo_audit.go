@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+)
+
+type auditRepository struct {
+	db *Database
+}
+
+var _ audit.Repository = (*auditRepository)(nil)
+
+func NewAuditRepository(db *Database) *auditRepository {
+	return &auditRepository{
+		db: db,
+	}
+}
+
+func (r *auditRepository) Create(ctx context.Context, e *audit.Entry) error {
+	defer r.db.lock(ctx)()
+
+	r.db.auditSeq++
+	e.ID = r.db.auditSeq
+
+	r.db.auditEntries = append(r.db.auditEntries, &auditRow{
+		id:        e.ID,
+		userUUID:  e.UserID.String(),
+		actor:     e.Actor,
+		action:    e.Action,
+		changes:   slices.Clone(e.Changes),
+		requestID: e.RequestID,
+		clientIP:  e.ClientIP,
+		createdAt: e.CreatedAt,
+	})
+
+	return nil
+}
+
+func (r *auditRepository) List(ctx context.Context, q *audit.Query) (*audit.Page, error) {
+	defer r.db.rlock(ctx)()
+
+	page := &audit.Page{}
+
+	// the entries are appended in the order of their ids, so they are walked backwards to list the newest first
+	for i := len(r.db.auditEntries) - 1; i >= 0; i-- {
+		row := r.db.auditEntries[i]
+
+		if !matchesAudit(row, q) {
+			continue
+		}
+
+		// one entry more than requested tells whether there is a next page
+		if len(page.Entries) == q.Limit {
+			page.NextCursor = &audit.Cursor{ID: page.Entries[len(page.Entries)-1].ID}
+			break
+		}
+
+		userID, _ := domain.ParseID(row.userUUID)
+
+		page.Entries = append(page.Entries, &audit.Entry{
+			ID:        row.id,
+			Actor:     row.actor,
+			Action:    row.action,
+			UserID:    userID,
+			Changes:   slices.Clone(row.changes),
+			RequestID: row.requestID,
+			ClientIP:  row.clientIP,
+			CreatedAt: row.createdAt,
+		})
+	}
+
+	return page, nil
+}
+
+func (r *auditRepository) Erase(ctx context.Context, userID domain.ID) error {
+	defer r.db.lock(ctx)()
+
+	for _, row := range r.db.auditEntries {
+		if row.userUUID == userID.String() {
+			row.changes = audit.EraseChanges(row.changes)
+		}
+
+		if row.userUUID == userID.String() || row.actor.ID == userID.String() {
+			row.clientIP = ""
+		}
+	}
+
+	return nil
+}
+
+func matchesAudit(row *auditRow, q *audit.Query) bool {
+	f := q.Filter
+
+	switch {
+	case q.Cursor != nil && row.id >= q.Cursor.ID,
+		!f.UserID.IsEmpty() && row.userUUID != f.UserID.String(),
+		f.ActorID != "" && row.actor.ID != f.ActorID,
+		f.Action != "" && row.action != f.Action,
+		f.From != nil && row.createdAt.Before(*f.From),
+		f.To != nil && !row.createdAt.Before(*f.To):
+		return false
+	default:
+		return true
+	}
+}
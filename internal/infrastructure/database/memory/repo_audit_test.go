@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+)
+
+func TestAuditEntries(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	first, second := domain.NewID(), domain.NewID()
+
+	newRepo := func(t *testing.T) *auditRepository {
+		repo := NewAuditRepository(NewDatabase())
+
+		entries := []*audit.Entry{
+			{UserID: first, Actor: audit.Actor{Type: audit.ActorAnonymous}, Action: audit.ActionUserCreated, CreatedAt: now},
+			{UserID: first, Actor: audit.Actor{Type: audit.ActorClient, ID: "backoffice"}, Action: audit.ActionUserUpdated, CreatedAt: now.Add(time.Hour)},
+			{UserID: second, Actor: audit.Actor{Type: audit.ActorClient, ID: "backoffice"}, Action: audit.ActionUserCreated, CreatedAt: now.Add(2 * time.Hour)},
+			{UserID: first, Actor: audit.Actor{Type: audit.ActorUser, ID: first.String()}, Action: audit.ActionUserDeleted, CreatedAt: now.Add(3 * time.Hour)},
+		}
+
+		for _, e := range entries {
+			assert.NoError(t, repo.Create(context.Background(), e))
+		}
+
+		return repo
+	}
+
+	actions := func(page *audit.Page) []audit.Action {
+		var result []audit.Action
+		for _, e := range page.Entries {
+			result = append(result, e.Action)
+		}
+
+		return result
+	}
+
+	t.Run("newest first with cursor", func(t *testing.T) {
+		repo := newRepo(t)
+
+		page, err := repo.List(context.Background(), &audit.Query{Filter: audit.Filter{UserID: first}, Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []audit.Action{audit.ActionUserDeleted, audit.ActionUserUpdated}, actions(page))
+		assert.NotNil(t, page.NextCursor)
+
+		page, err = repo.List(context.Background(), &audit.Query{Filter: audit.Filter{UserID: first}, Cursor: page.NextCursor, Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []audit.Action{audit.ActionUserCreated}, actions(page))
+		assert.Nil(t, page.NextCursor)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		repo := newRepo(t)
+		from, to := now.Add(time.Hour), now.Add(3*time.Hour)
+
+		page, err := repo.List(context.Background(), &audit.Query{Filter: audit.Filter{ActorID: "backoffice"}, Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, page.Entries, 2)
+
+		page, err = repo.List(context.Background(), &audit.Query{Filter: audit.Filter{Action: audit.ActionUserCreated}, Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, page.Entries, 2)
+
+		page, err = repo.List(context.Background(), &audit.Query{Filter: audit.Filter{From: &from, To: &to}, Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []audit.Action{audit.ActionUserCreated, audit.ActionUserUpdated}, actions(page))
+		assert.Equal(t, second, page.Entries[0].UserID)
+	})
+
+	t.Run("erase", func(t *testing.T) {
+		repo := NewAuditRepository(NewDatabase())
+
+		name, role := "John", "admin"
+		entries := []*audit.Entry{
+			{
+				UserID:    first,
+				Actor:     audit.Actor{Type: audit.ActorAnonymous},
+				Action:    audit.ActionUserUpdated,
+				Changes:   []audit.Change{{Field: "first_name", Value: &name}, {Field: "role", Value: &role}, {Field: "phone_number"}},
+				ClientIP:  "192.0.2.1",
+				CreatedAt: now,
+			},
+			{UserID: second, Actor: audit.Actor{Type: audit.ActorUser, ID: first.String()}, Action: audit.ActionUserUpdated, ClientIP: "192.0.2.1", CreatedAt: now},
+			{UserID: second, Actor: audit.Actor{Type: audit.ActorUser, ID: second.String()}, Action: audit.ActionUserUpdated, ClientIP: "192.0.2.2", CreatedAt: now},
+		}
+
+		for _, e := range entries {
+			assert.NoError(t, repo.Create(context.Background(), e))
+		}
+
+		assert.NoError(t, repo.Erase(context.Background(), first))
+
+		page, err := repo.List(context.Background(), &audit.Query{Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, page.Entries, 3)
+
+		erased := audit.Erased
+		assert.Equal(t, []audit.Change{{Field: "first_name", Value: &erased}, {Field: "role", Value: &role}, {Field: "phone_number"}}, page.Entries[2].Changes)
+		assert.Empty(t, page.Entries[2].ClientIP)
+		assert.Empty(t, page.Entries[1].ClientIP)
+		assert.Equal(t, "192.0.2.2", page.Entries[0].ClientIP)
+	})
+
+	t.Run("rolled back with the transaction", func(t *testing.T) {
+		db := NewDatabase()
+		repo := NewAuditRepository(db)
+
+		err := NewUnitOfWork(db).WithinTx(context.Background(), func(ctx context.Context) error {
+			if err := repo.Create(ctx, &audit.Entry{UserID: first, Action: audit.ActionUserUpdated, CreatedAt: now}); err != nil {
+				return err
+			}
+
+			return errors.New("failure")
+		})
+		assert.Error(t, err)
+
+		page, err := repo.List(context.Background(), &audit.Query{Limit: 10})
+		assert.NoError(t, err)
+		assert.Empty(t, page.Entries)
+	})
+}
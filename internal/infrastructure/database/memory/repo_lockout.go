@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+)
+
+type lockoutRepository struct {
+	db *Database
+}
+
+var _ lockout.Repository = (*lockoutRepository)(nil)
+
+func NewLockoutRepository(db *Database) *lockoutRepository {
+	return &lockoutRepository{
+		db: db,
+	}
+}
+
+func (r *lockoutRepository) Get(ctx context.Context, subject lockout.Subject) (*lockout.State, error) {
+	defer r.db.rlock(ctx)()
+
+	return state(subject, r.db.attempts[subject]), nil
+}
+
+func (r *lockoutRepository) RecordFailure(ctx context.Context, subject lockout.Subject, at time.Time, window time.Duration) (*lockout.State, error) {
+	defer r.db.lock(ctx)()
+
+	row := r.attempt(subject)
+
+	if row.lastFailureAt == nil || row.lastFailureAt.Before(at.Add(-window)) {
+		row.failures = 1
+	} else {
+		row.failures++
+	}
+	row.lastFailureAt = &at
+
+	return state(subject, row), nil
+}
+
+func (r *lockoutRepository) Lock(ctx context.Context, subject lockout.Subject, until time.Time) error {
+	defer r.db.lock(ctx)()
+
+	r.attempt(subject).lockedUntil = &until
+
+	return nil
+}
+
+func (r *lockoutRepository) Reset(ctx context.Context, subject lockout.Subject) error {
+	defer r.db.lock(ctx)()
+
+	delete(r.db.attempts, subject)
+
+	return nil
+}
+
+// attempt returns the row of the subject, creating it when missing. The caller must hold the write lock.
+func (r *lockoutRepository) attempt(subject lockout.Subject) *attemptRow {
+	row, ok := r.db.attempts[subject]
+	if !ok {
+		row = &attemptRow{}
+		r.db.attempts[subject] = row
+	}
+
+	return row
+}
+
+func state(subject lockout.Subject, row *attemptRow) *lockout.State {
+	s := &lockout.State{Subject: subject}
+	if row == nil {
+		return s
+	}
+
+	s.Failures = row.failures
+
+	if row.lastFailureAt != nil {
+		lastFailureAt := *row.lastFailureAt
+		s.LastFailureAt = &lastFailureAt
+	}
+
+	if row.lockedUntil != nil {
+		lockedUntil := *row.lockedUntil
+		s.LockedUntil = &lockedUntil
+	}
+
+	return s
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+)
+
+func TestLoginAttempts(t *testing.T) {
+	now := time.Now()
+	subject := lockout.IP("192.0.2.1")
+
+	t.Run("no failures recorded", func(t *testing.T) {
+		repo := NewLockoutRepository(NewDatabase())
+
+		state, err := repo.Get(context.Background(), subject)
+		assert.NoError(t, err)
+		assert.Equal(t, subject, state.Subject)
+		assert.Zero(t, state.Failures)
+		assert.Nil(t, state.LockedUntil)
+	})
+
+	t.Run("failures are counted within the window", func(t *testing.T) {
+		repo := NewLockoutRepository(NewDatabase())
+
+		_, err := repo.RecordFailure(context.Background(), subject, now, time.Hour)
+		assert.NoError(t, err)
+
+		state, err := repo.RecordFailure(context.Background(), subject, now.Add(time.Minute), time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, state.Failures)
+		assert.Equal(t, now.Add(time.Minute), *state.LastFailureAt)
+
+		state, err = repo.RecordFailure(context.Background(), subject, now.Add(2*time.Hour), time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, state.Failures)
+	})
+
+	t.Run("subjects are counted separately", func(t *testing.T) {
+		repo := NewLockoutRepository(NewDatabase())
+
+		_, err := repo.RecordFailure(context.Background(), subject, now, time.Hour)
+		assert.NoError(t, err)
+
+		state, err := repo.Get(context.Background(), lockout.Subject{Kind: lockout.KindUser, Value: subject.Value})
+		assert.NoError(t, err)
+		assert.Zero(t, state.Failures)
+	})
+
+	t.Run("lock and reset", func(t *testing.T) {
+		repo := NewLockoutRepository(NewDatabase())
+
+		_, err := repo.RecordFailure(context.Background(), subject, now, time.Hour)
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Lock(context.Background(), subject, now.Add(time.Minute)))
+
+		state, err := repo.Get(context.Background(), subject)
+		assert.NoError(t, err)
+		assert.True(t, state.LockedAt(now))
+		assert.False(t, state.LockedAt(now.Add(time.Minute)))
+
+		assert.NoError(t, repo.Reset(context.Background(), subject))
+
+		state, err = repo.Get(context.Background(), subject)
+		assert.NoError(t, err)
+		assert.Zero(t, state.Failures)
+		assert.False(t, state.LockedAt(now))
+	})
+
+	t.Run("rolled back with the transaction", func(t *testing.T) {
+		db := NewDatabase()
+		repo := NewLockoutRepository(db)
+
+		err := NewUnitOfWork(db).WithinTx(context.Background(), func(ctx context.Context) error {
+			if _, err := repo.RecordFailure(ctx, subject, now, time.Hour); err != nil {
+				return err
+			}
+
+			return assert.AnError
+		})
+		assert.ErrorIs(t, err, assert.AnError)
+
+		state, err := repo.Get(context.Background(), subject)
+		assert.NoError(t, err)
+		assert.Zero(t, state.Failures)
+	})
+}
@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+type mfaRepository struct {
+	db *Database
+}
+
+var _ mfa.Repository = (*mfaRepository)(nil)
+
+func NewMFARepository(db *Database) *mfaRepository {
+	return &mfaRepository{
+		db: db,
+	}
+}
+
+func (r *mfaRepository) Get(ctx context.Context, userID domain.ID) (*mfa.Factor, error) {
+	defer r.db.rlock(ctx)()
+
+	_, factor := r.factor(userID)
+	if factor == nil {
+		return nil, mfa.ErrNotEnrolled
+	}
+
+	return &mfa.Factor{
+		UserID:          userID,
+		EncryptedSecret: factor.secret,
+		ConfirmedAt:     factor.confirmedAt,
+		LastUsedStep:    factor.lastUsedStep,
+	}, nil
+}
+
+func (r *mfaRepository) Enroll(ctx context.Context, f *mfa.Factor, createdAt time.Time) error {
+	defer r.db.lock(ctx)()
+
+	owner, ok := r.db.byUUID[f.UserID.String()]
+	if !ok || owner.deletedAt != nil {
+		return user.ErrNotFound
+	}
+
+	r.delete(owner.id)
+
+	r.db.factors = append(r.db.factors, &factorRow{
+		userID:    owner.id,
+		secret:    f.EncryptedSecret,
+		createdAt: createdAt,
+	})
+
+	return nil
+}
+
+func (r *mfaRepository) Confirm(ctx context.Context, userID domain.ID, step int64, confirmedAt time.Time, recoveryCodeHashes []string) error {
+	defer r.db.lock(ctx)()
+
+	owner, factor := r.factor(userID)
+	if factor == nil {
+		return mfa.ErrNotEnrolled
+	}
+
+	factor.confirmedAt = &confirmedAt
+	factor.lastUsedStep = step
+	owner.mfaEnabled = true
+
+	r.replaceCodes(owner.id, recoveryCodeHashes)
+
+	return nil
+}
+
+func (r *mfaRepository) UseStep(ctx context.Context, userID domain.ID, step int64) error {
+	defer r.db.lock(ctx)()
+
+	_, factor := r.factor(userID)
+	if factor == nil || factor.lastUsedStep >= step {
+		return mfa.ErrInvalidCode
+	}
+
+	factor.lastUsedStep = step
+
+	return nil
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID domain.ID, hash string, usedAt time.Time) error {
+	defer r.db.lock(ctx)()
+
+	owner, ok := r.db.byUUID[userID.String()]
+	if !ok {
+		return mfa.ErrInvalidCode
+	}
+
+	for _, code := range r.db.codes {
+		if code.userID == owner.id && code.hash == hash && code.usedAt == nil {
+			code.usedAt = &usedAt
+			return nil
+		}
+	}
+
+	return mfa.ErrInvalidCode
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID domain.ID, hashes []string) error {
+	defer r.db.lock(ctx)()
+
+	owner, ok := r.db.byUUID[userID.String()]
+	if !ok {
+		return user.ErrNotFound
+	}
+
+	r.replaceCodes(owner.id, hashes)
+
+	return nil
+}
+
+func (r *mfaRepository) Delete(ctx context.Context, userID domain.ID) error {
+	defer r.db.lock(ctx)()
+
+	owner, ok := r.db.byUUID[userID.String()]
+	if !ok {
+		return nil
+	}
+
+	r.delete(owner.id)
+	owner.mfaEnabled = false
+
+	return nil
+}
+
+// factor returns the not deleted owner and their factor, the caller must hold the lock
+func (r *mfaRepository) factor(userID domain.ID) (*userRow, *factorRow) {
+	owner, ok := r.db.byUUID[userID.String()]
+	if !ok || owner.deletedAt != nil {
+		return nil, nil
+	}
+
+	for _, row := range r.db.factors {
+		if row.userID == owner.id {
+			return owner, row
+		}
+	}
+
+	return owner, nil
+}
+
+// delete removes the factor and the recovery codes of the user, the caller must hold the write lock
+func (r *mfaRepository) delete(id int64) {
+	factors := r.db.factors[:0]
+	for _, row := range r.db.factors {
+		if row.userID != id {
+			factors = append(factors, row)
+		}
+	}
+	r.db.factors = factors
+
+	r.replaceCodes(id, nil)
+}
+
+// replaceCodes deletes the recovery codes of the user and adds the new ones, the caller must hold the write lock
+func (r *mfaRepository) replaceCodes(id int64, hashes []string) {
+	codes := r.db.codes[:0]
+	for _, row := range r.db.codes {
+		if row.userID != id {
+			codes = append(codes, row)
+		}
+	}
+
+	for _, hash := range hashes {
+		codes = append(codes, &recoveryCodeRow{userID: id, hash: hash})
+	}
+
+	r.db.codes = codes
+}
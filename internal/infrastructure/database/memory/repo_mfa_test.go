@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+func TestMFAFactors(t *testing.T) {
+	now := time.Now()
+
+	t.Run("enroll and confirm", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		userRepo := NewUserRepository(db)
+		assert.NoError(t, userRepo.Create(context.Background(), u, now))
+
+		repo := NewMFARepository(db)
+
+		_, err := repo.Get(context.Background(), u.ID)
+		assert.ErrorIs(t, err, mfa.ErrNotEnrolled)
+
+		assert.NoError(t, repo.Enroll(context.Background(), &mfa.Factor{UserID: u.ID, EncryptedSecret: "first"}, now))
+		assert.NoError(t, repo.Enroll(context.Background(), &mfa.Factor{UserID: u.ID, EncryptedSecret: "second"}, now))
+
+		factor, err := repo.Get(context.Background(), u.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "second", factor.EncryptedSecret)
+		assert.False(t, factor.Enabled())
+
+		assert.NoError(t, repo.Confirm(context.Background(), u.ID, 100, now, []string{"hash"}))
+
+		factor, err = repo.Get(context.Background(), u.ID)
+		assert.NoError(t, err)
+		assert.True(t, factor.Enabled())
+		assert.Equal(t, int64(100), factor.LastUsedStep)
+
+		result, err := userRepo.GetByUUID(context.Background(), u.ID)
+		assert.NoError(t, err)
+		assert.True(t, result.MFAEnabled)
+	})
+
+	t.Run("enroll missing user", func(t *testing.T) {
+		repo := NewMFARepository(NewDatabase())
+
+		err := repo.Enroll(context.Background(), &mfa.Factor{UserID: domain.NewID(), EncryptedSecret: "secret"}, now)
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+
+	t.Run("step is used once", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		assert.NoError(t, NewUserRepository(db).Create(context.Background(), u, now))
+
+		repo := NewMFARepository(db)
+		assert.NoError(t, repo.Enroll(context.Background(), &mfa.Factor{UserID: u.ID, EncryptedSecret: "secret"}, now))
+		assert.NoError(t, repo.Confirm(context.Background(), u.ID, 100, now, nil))
+
+		assert.ErrorIs(t, repo.UseStep(context.Background(), u.ID, 100), mfa.ErrInvalidCode)
+		assert.NoError(t, repo.UseStep(context.Background(), u.ID, 101))
+		assert.ErrorIs(t, repo.UseStep(context.Background(), u.ID, 101), mfa.ErrInvalidCode)
+	})
+
+	t.Run("recovery code is used once", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		assert.NoError(t, NewUserRepository(db).Create(context.Background(), u, now))
+
+		repo := NewMFARepository(db)
+		assert.NoError(t, repo.Enroll(context.Background(), &mfa.Factor{UserID: u.ID, EncryptedSecret: "secret"}, now))
+		assert.NoError(t, repo.Confirm(context.Background(), u.ID, 100, now, []string{"first", "second"}))
+
+		assert.NoError(t, repo.UseRecoveryCode(context.Background(), u.ID, "first", now))
+		assert.ErrorIs(t, repo.UseRecoveryCode(context.Background(), u.ID, "first", now), mfa.ErrInvalidCode)
+
+		assert.NoError(t, repo.ReplaceRecoveryCodes(context.Background(), u.ID, []string{"third"}))
+		assert.ErrorIs(t, repo.UseRecoveryCode(context.Background(), u.ID, "second", now), mfa.ErrInvalidCode)
+		assert.NoError(t, repo.UseRecoveryCode(context.Background(), u.ID, "third", now))
+	})
+
+	t.Run("delete disables mfa", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		userRepo := NewUserRepository(db)
+		assert.NoError(t, userRepo.Create(context.Background(), u, now))
+
+		repo := NewMFARepository(db)
+		assert.NoError(t, repo.Enroll(context.Background(), &mfa.Factor{UserID: u.ID, EncryptedSecret: "secret"}, now))
+		assert.NoError(t, repo.Confirm(context.Background(), u.ID, 100, now, []string{"hash"}))
+		assert.NoError(t, repo.Delete(context.Background(), u.ID))
+
+		_, err := repo.Get(context.Background(), u.ID)
+		assert.ErrorIs(t, err, mfa.ErrNotEnrolled)
+		assert.ErrorIs(t, repo.UseRecoveryCode(context.Background(), u.ID, "hash", now), mfa.ErrInvalidCode)
+
+		credentials, err := userRepo.GetCredentialsByUUID(context.Background(), u.ID)
+		assert.NoError(t, err)
+		assert.False(t, credentials.MFAEnabled)
+	})
+}
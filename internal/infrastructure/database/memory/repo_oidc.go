@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+type clientRepository struct {
+	db *Database
+}
+
+var _ oidc.ClientRepository = (*clientRepository)(nil)
+
+func NewClientRepository(db *Database) *clientRepository {
+	return &clientRepository{
+		db: db,
+	}
+}
+
+func (r *clientRepository) Create(ctx context.Context, c *oidc.Client, createdAt time.Time) error {
+	defer r.db.lock(ctx)()
+
+	r.db.clients = append(r.db.clients, &clientRow{
+		clientID:     c.ID.String(),
+		name:         c.Name,
+		secretHash:   c.SecretHash,
+		redirectURIs: slices.Clone(c.RedirectURIs),
+		createdAt:    createdAt,
+	})
+
+	return nil
+}
+
+func (r *clientRepository) Get(ctx context.Context, id domain.ID) (*oidc.Client, error) {
+	defer r.db.rlock(ctx)()
+
+	for _, row := range r.db.clients {
+		if row.clientID == id.String() {
+			return &oidc.Client{
+				ID:           id,
+				Name:         row.name,
+				SecretHash:   row.secretHash,
+				RedirectURIs: slices.Clone(row.redirectURIs),
+			}, nil
+		}
+	}
+
+	return nil, oidc.ErrClientNotFound
+}
+
+func (r *clientRepository) Delete(ctx context.Context, id domain.ID) error {
+	defer r.db.lock(ctx)()
+
+	before := len(r.db.clients)
+
+	r.db.clients = slices.DeleteFunc(r.db.clients, func(row *clientRow) bool {
+		return row.clientID == id.String()
+	})
+
+	if len(r.db.clients) == before {
+		return oidc.ErrClientNotFound
+	}
+
+	r.db.authCodes = slices.DeleteFunc(r.db.authCodes, func(row *authCodeRow) bool {
+		return row.clientID == id.String() && row.usedAt == nil
+	})
+
+	return nil
+}
+
+type authorizationCodeRepository struct {
+	db *Database
+}
+
+var _ oidc.CodeRepository = (*authorizationCodeRepository)(nil)
+
+func NewAuthorizationCodeRepository(db *Database) *authorizationCodeRepository {
+	return &authorizationCodeRepository{
+		db: db,
+	}
+}
+
+func (r *authorizationCodeRepository) Create(ctx context.Context, c *oidc.AuthorizationCode, createdAt time.Time) error {
+	defer r.db.lock(ctx)()
+
+	row, ok := r.db.byUUID[c.UserID.String()]
+	if !ok || row.deletedAt != nil {
+		return user.ErrNotFound
+	}
+
+	r.db.authCodes = append(r.db.authCodes, &authCodeRow{
+		userID:        row.id,
+		clientID:      c.ClientID.String(),
+		hash:          c.Hash,
+		redirectURI:   c.RedirectURI,
+		scopes:        slices.Clone(c.Scopes),
+		nonce:         c.Nonce,
+		codeChallenge: c.CodeChallenge,
+		expiresAt:     c.ExpiresAt,
+		createdAt:     createdAt,
+	})
+
+	return nil
+}
+
+func (r *authorizationCodeRepository) Use(ctx context.Context, hash string, usedAt time.Time) (*oidc.AuthorizationCode, error) {
+	defer r.db.lock(ctx)()
+
+	for _, row := range r.db.authCodes {
+		if row.hash != hash || row.usedAt != nil {
+			continue
+		}
+
+		for _, owner := range r.db.users {
+			if owner.id != row.userID || owner.deletedAt != nil {
+				continue
+			}
+
+			row.usedAt = &usedAt
+
+			userID, _ := domain.ParseID(owner.uuid)
+			clientID, _ := domain.ParseID(row.clientID)
+
+			return &oidc.AuthorizationCode{
+				Hash:          row.hash,
+				ClientID:      clientID,
+				UserID:        userID,
+				RedirectURI:   row.redirectURI,
+				Scopes:        slices.Clone(row.scopes),
+				Nonce:         row.nonce,
+				CodeChallenge: row.codeChallenge,
+				ExpiresAt:     row.expiresAt,
+			}, nil
+		}
+	}
+
+	return nil, oidc.ErrInvalidGrant
+}
+
+type signingKeyRepository struct {
+	db *Database
+}
+
+var _ oidc.KeyRepository = (*signingKeyRepository)(nil)
+
+func NewSigningKeyRepository(db *Database) *signingKeyRepository {
+	return &signingKeyRepository{
+		db: db,
+	}
+}
+
+func (r *signingKeyRepository) Create(ctx context.Context, k *oidc.SigningKey) error {
+	defer r.db.lock(ctx)()
+
+	r.db.signingKeys = append(r.db.signingKeys, &signingKeyRow{
+		keyID:      k.ID,
+		algorithm:  k.Algorithm,
+		privateKey: k.PrivateKey,
+		publicKey:  slices.Clone(k.PublicKey),
+		createdAt:  k.CreatedAt,
+	})
+
+	return nil
+}
+
+func (r *signingKeyRepository) List(ctx context.Context, since time.Time) ([]*oidc.SigningKey, error) {
+	defer r.db.rlock(ctx)()
+
+	var keys []*oidc.SigningKey
+
+	for _, row := range r.db.signingKeys {
+		if !row.createdAt.After(since) {
+			continue
+		}
+
+		keys = append(keys, &oidc.SigningKey{
+			ID:         row.keyID,
+			Algorithm:  row.algorithm,
+			PrivateKey: row.privateKey,
+			PublicKey:  slices.Clone(row.publicKey),
+			CreatedAt:  row.createdAt,
+		})
+	}
+
+	slices.SortStableFunc(keys, func(a, b *oidc.SigningKey) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return keys, nil
+}
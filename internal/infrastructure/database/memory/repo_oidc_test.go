@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+)
+
+func TestClients(t *testing.T) {
+	now := time.Now()
+
+	t.Run("create, get and delete", func(t *testing.T) {
+		db := NewDatabase()
+		repo := NewClientRepository(db)
+
+		client, _, err := oidc.NewClient("app", []string{"https://app.example.com/callback"}, false)
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Create(context.Background(), client, now))
+
+		result, err := repo.Get(context.Background(), client.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, client, result)
+
+		assert.NoError(t, repo.Delete(context.Background(), client.ID))
+
+		_, err = repo.Get(context.Background(), client.ID)
+		assert.ErrorIs(t, err, oidc.ErrClientNotFound)
+		assert.ErrorIs(t, repo.Delete(context.Background(), client.ID), oidc.ErrClientNotFound)
+	})
+
+	t.Run("deleting a client deletes its codes", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		assert.NoError(t, NewUserRepository(db).Create(context.Background(), u, now))
+
+		client, _, _ := oidc.NewClient("app", []string{"https://app.example.com/callback"}, true)
+		assert.NoError(t, NewClientRepository(db).Create(context.Background(), client, now))
+
+		codeRepo := NewAuthorizationCodeRepository(db)
+
+		_, code, _ := oidc.NewCode(client.ID, u.ID, "https://app.example.com/callback", []string{oidc.ScopeOpenID}, "", "challenge", now.Add(time.Minute))
+		assert.NoError(t, codeRepo.Create(context.Background(), code, now))
+		assert.NoError(t, NewClientRepository(db).Delete(context.Background(), client.ID))
+
+		_, err := codeRepo.Use(context.Background(), code.Hash, now)
+		assert.ErrorIs(t, err, oidc.ErrInvalidGrant)
+	})
+}
+
+func TestAuthorizationCodes(t *testing.T) {
+	now := time.Now()
+
+	t.Run("code is used once", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		assert.NoError(t, NewUserRepository(db).Create(context.Background(), u, now))
+
+		repo := NewAuthorizationCodeRepository(db)
+
+		_, code, err := oidc.NewCode(domain.NewID(), u.ID, "https://app.example.com/callback", []string{oidc.ScopeOpenID, oidc.ScopeEmail}, "nonce", "challenge", now.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Create(context.Background(), code, now))
+
+		result, err := repo.Use(context.Background(), code.Hash, now)
+		assert.NoError(t, err)
+		assert.Equal(t, code, result)
+
+		_, err = repo.Use(context.Background(), code.Hash, now)
+		assert.ErrorIs(t, err, oidc.ErrInvalidGrant)
+	})
+
+	t.Run("code of a deleted user", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		assert.NoError(t, NewUserRepository(db).Create(context.Background(), u, now))
+
+		repo := NewAuthorizationCodeRepository(db)
+
+		_, code, _ := oidc.NewCode(domain.NewID(), u.ID, "https://app.example.com/callback", []string{oidc.ScopeOpenID}, "", "challenge", now.Add(time.Minute))
+		assert.NoError(t, repo.Create(context.Background(), code, now))
+		assert.NoError(t, NewUserRepository(db).Delete(context.Background(), u.ID))
+
+		_, err := repo.Use(context.Background(), code.Hash, now)
+		assert.ErrorIs(t, err, oidc.ErrInvalidGrant)
+	})
+}
+
+func TestSigningKeys(t *testing.T) {
+	now := time.Now()
+	repo := NewSigningKeyRepository(NewDatabase())
+
+	for i, createdAt := range []time.Time{now.Add(-2 * time.Hour), now.Add(-time.Minute), now} {
+		assert.NoError(t, repo.Create(context.Background(), &oidc.SigningKey{ID: string(rune('a' + i)), CreatedAt: createdAt}))
+	}
+
+	keys, err := repo.List(context.Background(), now.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "c", keys[0].ID) // the newest first
+	assert.Equal(t, "b", keys[1].ID)
+}
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+)
+
+type outboxRepository struct {
+	db *Database
+}
+
+var _ event.Outbox = (*outboxRepository)(nil)
+
+func NewOutboxRepository(db *Database) *outboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+func (r *outboxRepository) Add(ctx context.Context, e *event.Event) error {
+	defer r.db.lock(ctx)()
+
+	row := &eventRow{event: *e}
+	row.event.Data = slices.Clone(e.Data)
+
+	r.db.events = append(r.db.events, row)
+
+	return nil
+}
+
+// Claim leases the oldest unpublished events, a transaction of this database holds the lock of the whole database,
+// so the events are never being claimed by another relay at the same time
+func (r *outboxRepository) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*event.Event, error) {
+	defer r.db.lock(ctx)()
+
+	var rows []*eventRow
+
+	for _, row := range r.db.events {
+		if len(rows) == limit {
+			break
+		}
+
+		if row.publishedAt != nil {
+			continue
+		}
+
+		// another relay is publishing the oldest events, the following ones wait for it
+		if row.claimedUntil != nil && row.claimedUntil.After(now) {
+			return nil, nil
+		}
+
+		rows = append(rows, row)
+	}
+
+	events := make([]*event.Event, 0, len(rows))
+
+	for _, row := range rows {
+		claimedUntil := leaseUntil
+		row.claimedUntil = &claimedUntil
+
+		e := row.event
+		e.Data = slices.Clone(row.event.Data)
+		events = append(events, &e)
+	}
+
+	return events, nil
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, ids []domain.ID, publishedAt time.Time) error {
+	defer r.db.lock(ctx)()
+
+	for _, row := range r.db.events {
+		if row.publishedAt == nil && slices.Contains(ids, row.event.ID) {
+			row.publishedAt = &publishedAt
+		}
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) Release(ctx context.Context, ids []domain.ID) error {
+	defer r.db.lock(ctx)()
+
+	for _, row := range r.db.events {
+		if slices.Contains(ids, row.event.ID) {
+			row.claimedUntil = nil
+		}
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) Erase(ctx context.Context, userID domain.ID) error {
+	defer r.db.lock(ctx)()
+
+	for _, row := range r.db.events {
+		if row.event.UserID == userID {
+			row.event.Data = json.RawMessage("{}")
+		}
+	}
+
+	return nil
+}
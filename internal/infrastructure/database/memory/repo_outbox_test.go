@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+)
+
+func TestOutbox(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	userID := domain.NewID()
+
+	newEvent := func(t *testing.T, payload event.Payload) *event.Event {
+		e, err := event.New(userID, payload, now)
+		assert.NoError(t, err)
+
+		return e
+	}
+
+	t.Run("claim claimed events", func(t *testing.T) {
+		repo := NewOutboxRepository(NewDatabase())
+
+		created, updated, deleted := newEvent(t, event.UserCreated{}), newEvent(t, event.UserUpdated{}), newEvent(t, event.UserDeleted{})
+		for _, e := range []*event.Event{created, updated, deleted} {
+			assert.NoError(t, repo.Add(context.Background(), e))
+		}
+
+		leaseUntil := now.Add(time.Minute)
+
+		claimed, err := repo.Claim(context.Background(), now, leaseUntil, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []*event.Event{created, updated}, claimed)
+
+		// the following events wait until the claimed ones are published or released
+		claimed, err = repo.Claim(context.Background(), now, leaseUntil, 2)
+		assert.NoError(t, err)
+		assert.Empty(t, claimed)
+
+		assert.NoError(t, repo.MarkPublished(context.Background(), []domain.ID{created.ID}, now))
+		assert.NoError(t, repo.Release(context.Background(), []domain.ID{updated.ID}))
+
+		claimed, err = repo.Claim(context.Background(), now, leaseUntil, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []*event.Event{updated, deleted}, claimed)
+
+		// unless the lease ends first
+		claimed, err = repo.Claim(context.Background(), leaseUntil, leaseUntil.Add(time.Minute), 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []*event.Event{updated, deleted}, claimed)
+	})
+
+	t.Run("erase the data of the user's events", func(t *testing.T) {
+		repo := NewOutboxRepository(NewDatabase())
+
+		other, err := event.New(domain.NewID(), event.UserCreated{}, now)
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Add(context.Background(), newEvent(t, event.UserCreated{})))
+		assert.NoError(t, repo.Add(context.Background(), other))
+
+		assert.NoError(t, repo.Erase(context.Background(), userID))
+
+		claimed, err := repo.Claim(context.Background(), now, now, 10)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{}`, string(claimed[0].Data))
+		assert.JSONEq(t, `{"user":null}`, string(claimed[1].Data))
+	})
+
+	t.Run("rolled back with the transaction", func(t *testing.T) {
+		db := NewDatabase()
+		repo := NewOutboxRepository(db)
+
+		err := NewUnitOfWork(db).WithinTx(context.Background(), func(ctx context.Context) error {
+			if err := repo.Add(ctx, newEvent(t, event.UserDeleted{})); err != nil {
+				return err
+			}
+
+			return errors.New("failure")
+		})
+		assert.Error(t, err)
+
+		claimed, err := repo.Claim(context.Background(), now, now, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, claimed)
+	})
+}
@@ -0,0 +1,172 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+type sessionRepository struct {
+	db *Database
+}
+
+var _ session.Repository = (*sessionRepository)(nil)
+
+func NewSessionRepository(db *Database) *sessionRepository {
+	return &sessionRepository{
+		db: db,
+	}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, t *session.RefreshToken, createdAt time.Time) error {
+	defer r.db.lock(ctx)()
+
+	row, ok := r.db.byUUID[t.UserID.String()]
+	if !ok || row.deletedAt != nil {
+		return user.ErrNotFound
+	}
+
+	r.db.refreshTokens = append(r.db.refreshTokens, &refreshTokenRow{
+		userID:    row.id,
+		familyID:  t.FamilyID.String(),
+		hash:      t.Hash,
+		expiresAt: t.ExpiresAt,
+		createdAt: createdAt,
+	})
+
+	return nil
+}
+
+func (r *sessionRepository) Get(ctx context.Context, hash string) (*session.RefreshToken, error) {
+	defer r.db.rlock(ctx)()
+
+	row := r.token(hash)
+	if row == nil {
+		return nil, session.ErrInvalidToken
+	}
+
+	var owner *userRow
+	for _, u := range r.db.users {
+		if u.id == row.userID && u.deletedAt == nil {
+			owner = u
+		}
+	}
+
+	if owner == nil {
+		return nil, session.ErrInvalidToken
+	}
+
+	userID, _ := domain.ParseID(owner.uuid)
+	familyID, _ := domain.ParseID(row.familyID)
+
+	return &session.RefreshToken{
+		Hash:      row.hash,
+		UserID:    userID,
+		FamilyID:  familyID,
+		ExpiresAt: row.expiresAt,
+		UsedAt:    row.usedAt,
+		RevokedAt: row.revokedAt,
+	}, nil
+}
+
+func (r *sessionRepository) Use(ctx context.Context, hash string, usedAt time.Time) error {
+	defer r.db.lock(ctx)()
+
+	row := r.token(hash)
+	if row == nil || row.usedAt != nil {
+		return session.ErrReused
+	}
+
+	row.usedAt = &usedAt
+
+	return nil
+}
+
+func (r *sessionRepository) RevokeFamily(ctx context.Context, familyID domain.ID, revokedAt time.Time) error {
+	defer r.db.lock(ctx)()
+
+	for _, row := range r.db.refreshTokens {
+		if row.familyID == familyID.String() && row.revokedAt == nil {
+			row.revokedAt = &revokedAt
+		}
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) ListByUser(ctx context.Context, userID domain.ID) ([]*session.Session, error) {
+	defer r.db.rlock(ctx)()
+
+	owner, ok := r.db.byUUID[userID.String()]
+	if !ok {
+		return nil, nil
+	}
+
+	var sessions []*session.Session
+
+	// the tokens are appended as they are created, so the first token of a family is the login
+	families := make(map[string]*session.Session)
+
+	for _, row := range r.db.refreshTokens {
+		if row.userID != owner.id {
+			continue
+		}
+
+		s, ok := families[row.familyID]
+		if !ok {
+			familyID, _ := domain.ParseID(row.familyID)
+
+			s = &session.Session{FamilyID: familyID, CreatedAt: row.createdAt}
+			families[row.familyID] = s
+			sessions = append(sessions, s)
+		}
+
+		s.LastUsedAt = latest(s.LastUsedAt, row.usedAt)
+		s.RevokedAt = latest(s.RevokedAt, row.revokedAt)
+
+		if row.expiresAt.After(s.ExpiresAt) {
+			s.ExpiresAt = row.expiresAt
+		}
+	}
+
+	return sessions, nil
+}
+
+func (r *sessionRepository) RevokeUser(ctx context.Context, userID domain.ID, revokedAt time.Time) error {
+	defer r.db.lock(ctx)()
+
+	owner, ok := r.db.byUUID[userID.String()]
+	if !ok {
+		return nil
+	}
+
+	for _, row := range r.db.refreshTokens {
+		if row.userID == owner.id && row.revokedAt == nil {
+			row.revokedAt = &revokedAt
+		}
+	}
+
+	return nil
+}
+
+// token returns the token with the hash, the caller must hold the lock
+func (r *sessionRepository) token(hash string) *refreshTokenRow {
+	for _, row := range r.db.refreshTokens {
+		if row.hash == hash {
+			return row
+		}
+	}
+
+	return nil
+}
+
+func latest(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+
+	return a
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+func TestRefreshTokens(t *testing.T) {
+	now := time.Now()
+
+	t.Run("token is used once", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		assert.NoError(t, NewUserRepository(db).Create(context.Background(), u, now))
+
+		repo := NewSessionRepository(db)
+		familyID := domain.NewID()
+
+		_, token, err := session.New(u.ID, familyID, now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Create(context.Background(), token, now))
+
+		result, err := repo.Get(context.Background(), token.Hash)
+		assert.NoError(t, err)
+		assert.Equal(t, u.ID, result.UserID)
+		assert.Equal(t, familyID, result.FamilyID)
+		assert.True(t, result.Active(now))
+
+		assert.NoError(t, repo.Use(context.Background(), token.Hash, now))
+		assert.ErrorIs(t, repo.Use(context.Background(), token.Hash, now), session.ErrReused)
+
+		result, err = repo.Get(context.Background(), token.Hash)
+		assert.NoError(t, err)
+		assert.False(t, result.Active(now))
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		_, err := NewSessionRepository(NewDatabase()).Get(context.Background(), session.Hash("unknown"))
+		assert.ErrorIs(t, err, session.ErrInvalidToken)
+	})
+
+	t.Run("create for missing user", func(t *testing.T) {
+		_, token, err := session.New(domain.NewID(), domain.NewID(), now.Add(time.Hour))
+		assert.NoError(t, err)
+
+		err = NewSessionRepository(NewDatabase()).Create(context.Background(), token, now)
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+
+	t.Run("revoke family", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		assert.NoError(t, NewUserRepository(db).Create(context.Background(), u, now))
+
+		repo := NewSessionRepository(db)
+
+		_, first, _ := session.New(u.ID, domain.NewID(), now.Add(time.Hour))
+		_, other, _ := session.New(u.ID, domain.NewID(), now.Add(time.Hour))
+		assert.NoError(t, repo.Create(context.Background(), first, now))
+		assert.NoError(t, repo.Create(context.Background(), other, now))
+
+		assert.NoError(t, repo.RevokeFamily(context.Background(), first.FamilyID, now))
+
+		result, err := repo.Get(context.Background(), first.Hash)
+		assert.NoError(t, err)
+		assert.NotNil(t, result.RevokedAt)
+
+		result, err = repo.Get(context.Background(), other.Hash)
+		assert.NoError(t, err)
+		assert.Nil(t, result.RevokedAt)
+	})
+
+	t.Run("list by user", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		assert.NoError(t, NewUserRepository(db).Create(context.Background(), u, now))
+
+		repo := NewSessionRepository(db)
+		familyID := domain.NewID()
+
+		_, first, _ := session.New(u.ID, familyID, now.Add(time.Hour))
+		_, rotated, _ := session.New(u.ID, familyID, now.Add(2*time.Hour))
+		_, other, _ := session.New(u.ID, domain.NewID(), now.Add(3*time.Hour))
+		assert.NoError(t, repo.Create(context.Background(), first, now))
+		assert.NoError(t, repo.Use(context.Background(), first.Hash, now.Add(time.Minute)))
+		assert.NoError(t, repo.Create(context.Background(), rotated, now.Add(time.Minute)))
+		assert.NoError(t, repo.Create(context.Background(), other, now.Add(time.Minute)))
+		assert.NoError(t, repo.RevokeFamily(context.Background(), other.FamilyID, now.Add(2*time.Minute)))
+
+		sessions, err := repo.ListByUser(context.Background(), u.ID)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 2)
+
+		assert.Equal(t, familyID, sessions[0].FamilyID)
+		assert.Equal(t, now, sessions[0].CreatedAt)
+		assert.Equal(t, now.Add(time.Minute), *sessions[0].LastUsedAt)
+		assert.Equal(t, now.Add(2*time.Hour), sessions[0].ExpiresAt)
+		assert.Nil(t, sessions[0].RevokedAt)
+
+		assert.Equal(t, other.FamilyID, sessions[1].FamilyID)
+		assert.Nil(t, sessions[1].LastUsedAt)
+		assert.Equal(t, now.Add(2*time.Minute), *sessions[1].RevokedAt)
+	})
+
+	t.Run("deleting the user revokes the tokens", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		userRepo := NewUserRepository(db)
+		assert.NoError(t, userRepo.Create(context.Background(), u, now))
+
+		repo := NewSessionRepository(db)
+
+		_, token, _ := session.New(u.ID, domain.NewID(), now.Add(time.Hour))
+		assert.NoError(t, repo.Create(context.Background(), token, now))
+		assert.NoError(t, userRepo.Delete(context.Background(), u.ID))
+
+		_, err := repo.Get(context.Background(), token.Hash)
+		assert.ErrorIs(t, err, session.ErrInvalidToken)
+		assert.NotNil(t, db.refreshTokens[0].revokedAt)
+	})
+}
@@ -0,0 +1,701 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+type userRepository struct {
+	db *Database
+}
+
+var _ user.Repository = (*userRepository)(nil)
+
+func NewUserRepository(db *Database) *userRepository {
+	return &userRepository{
+		db: db,
+	}
+}
+
+func (r *userRepository) Create(ctx context.Context, u *user.User, createdAt time.Time) error {
+	defer r.db.lock(ctx)()
+
+	// unique indexes do not take soft deletes into account, the same as in MySQL
+	if _, ok := r.db.byEmail[u.Email]; ok {
+		return user.ErrEmailAlreadyExists
+	}
+
+	typeIsPresent := make(map[user.AddressType]struct{}, len(u.Addresses))
+	for _, addr := range u.Addresses {
+		if _, ok := typeIsPresent[addr.Type]; ok {
+			return user.ErrAddressAlreadyExists
+		}
+		typeIsPresent[addr.Type] = struct{}{}
+	}
+
+	r.db.userSeq++
+	row := &userRow{
+		id:          r.db.userSeq,
+		uuid:        u.ID.String(),
+		email:       u.Email,
+		password:    u.Password,
+		firstName:   u.FirstName,
+		lastName:    u.LastName,
+		phoneNumber: u.PhoneNumber,
+		role:        u.Role,
+		version:     1,
+		createdAt:   createdAt,
+	}
+
+	r.db.users = append(r.db.users, row)
+	r.db.byUUID[row.uuid] = row
+	r.db.byEmail[row.email] = row
+
+	for _, addr := range u.Addresses {
+		r.db.addresses = append(r.db.addresses, &addressRow{
+			userID:     row.id,
+			addrType:   addr.Type,
+			street:     addr.Street,
+			city:       addr.City,
+			state:      addr.State,
+			postalCode: addr.PostalCode,
+			country:    addr.Country,
+			createdAt:  createdAt,
+		})
+	}
+
+	return nil
+}
+
+func (r *userRepository) UpdateBasicFields(ctx context.Context, id domain.ID, changes []user.Change) error {
+	defer r.db.lock(ctx)()
+
+	row := r.activeUser(id)
+	if row == nil {
+		return user.ErrNotFound
+	}
+
+	// validate all the columns first to not leave the row partially updated
+	for _, c := range changes {
+		switch c.Field {
+		case user.FieldPassword, user.FieldFirstName, user.FieldLastName, user.FieldPhoneNumber, user.FieldRole:
+		default:
+			return fmt.Errorf("failed updating users: unknown column %s", c.Field)
+		}
+	}
+
+	for _, c := range changes {
+		switch c.Field {
+		case user.FieldPassword:
+			row.password = c.Value
+		case user.FieldFirstName:
+			row.firstName = c.Value
+		case user.FieldLastName:
+			row.lastName = c.Value
+		case user.FieldPhoneNumber:
+			row.phoneNumber = c.Value
+		case user.FieldRole:
+			row.role = user.Role(c.Value)
+		}
+	}
+
+	return nil
+}
+
+func (r *userRepository) UpdateAddress(ctx context.Context, id domain.ID, addrType user.AddressType, changes []user.Change) error {
+	defer r.db.lock(ctx)()
+
+	row := r.activeUser(id)
+	if row == nil {
+		return user.ErrAddressNotFound
+	}
+
+	addr := r.address(row.id, addrType)
+	if addr == nil {
+		return user.ErrAddressNotFound
+	}
+
+	for _, c := range changes {
+		switch c.Field {
+		case user.FieldStreet, user.FieldCity, user.FieldState, user.FieldPostalCode, user.FieldCountry:
+		default:
+			return fmt.Errorf("failed updating address: unknown column %s", c.Field)
+		}
+	}
+
+	for _, c := range changes {
+		switch c.Field {
+		case user.FieldStreet:
+			addr.street = c.Value
+		case user.FieldCity:
+			addr.city = c.Value
+		case user.FieldState:
+			addr.state = c.Value
+		case user.FieldPostalCode:
+			addr.postalCode = c.Value
+		case user.FieldCountry:
+			addr.country = c.Value
+		}
+	}
+
+	return nil
+}
+
+func (r *userRepository) InsertAddress(ctx context.Context, id domain.ID, addr *user.Address, createdAt time.Time) error {
+	defer r.db.lock(ctx)()
+
+	row := r.activeUser(id)
+	if row == nil {
+		return user.ErrNotFound
+	}
+
+	if r.address(row.id, addr.Type) != nil {
+		return user.ErrAddressAlreadyExists
+	}
+
+	r.db.addresses = append(r.db.addresses, &addressRow{
+		userID:     row.id,
+		addrType:   addr.Type,
+		street:     addr.Street,
+		city:       addr.City,
+		state:      addr.State,
+		postalCode: addr.PostalCode,
+		country:    addr.Country,
+		createdAt:  createdAt,
+	})
+
+	return nil
+}
+
+// DeleteAddress removes the address for good, so an address of the same type can be added again
+func (r *userRepository) DeleteAddress(ctx context.Context, id domain.ID, addrType user.AddressType) error {
+	defer r.db.lock(ctx)()
+
+	row := r.activeUser(id)
+	if row == nil {
+		return user.ErrNotFound
+	}
+
+	for i, addr := range r.db.addresses {
+		if addr.userID == row.id && addr.addrType == addrType && addr.deletedAt == nil {
+			r.db.addresses = append(r.db.addresses[:i], r.db.addresses[i+1:]...)
+			return nil
+		}
+	}
+
+	return user.ErrAddressNotFound
+}
+
+func (r *userRepository) ListAddresses(ctx context.Context, id domain.ID) ([]*user.Address, error) {
+	defer r.db.rlock(ctx)()
+
+	row := r.activeUser(id)
+	if row == nil {
+		return nil, user.ErrNotFound
+	}
+
+	return r.toDomain(row).Addresses, nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id domain.ID) error {
+	defer r.db.lock(ctx)()
+
+	row, ok := r.db.byUUID[id.String()]
+	if !ok {
+		return user.ErrNotFound
+	}
+
+	ts := time.Now()
+
+	row.deletedAt = &ts
+	for _, addr := range r.db.addresses {
+		if addr.userID == row.id {
+			addr.deletedAt = &ts
+		}
+	}
+
+	for _, token := range r.db.refreshTokens {
+		if token.userID == row.id && token.revokedAt == nil {
+			token.revokedAt = &ts
+		}
+	}
+
+	return nil
+}
+
+func (r *userRepository) Restore(ctx context.Context, id domain.ID) error {
+	defer r.db.lock(ctx)()
+
+	row, ok := r.db.byUUID[id.String()]
+	if !ok {
+		return user.ErrNotFound
+	}
+
+	if row.erasedAt != nil {
+		return user.ErrErased
+	}
+
+	if row.deletedAt == nil {
+		return user.ErrNotDeleted
+	}
+
+	for _, addr := range r.db.addresses {
+		if addr.userID == row.id && deletedTogether(addr, row) {
+			addr.deletedAt = nil
+		}
+	}
+
+	// revoked sessions stay revoked, the user has to log in again
+	row.deletedAt = nil
+
+	return nil
+}
+
+func (r *userRepository) Purge(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	defer r.db.lock(ctx)()
+
+	var deleted []*userRow
+	for _, row := range r.db.users {
+		if row.deletedAt != nil && row.deletedAt.Before(deletedBefore) && row.erasedAt == nil {
+			deleted = append(deleted, row)
+		}
+	}
+
+	sort.SliceStable(deleted, func(i, j int) bool {
+		return deleted[i].deletedAt.Before(*deleted[j].deletedAt)
+	})
+
+	if len(deleted) > limit {
+		deleted = deleted[:limit]
+	}
+
+	purged := make(map[int64]struct{}, len(deleted))
+	for _, row := range deleted {
+		purged[row.id] = struct{}{}
+
+		delete(r.db.byUUID, row.uuid)
+		delete(r.db.byEmail, row.email)
+		delete(r.db.attempts, lockout.Subject{Kind: lockout.KindUser, Value: row.uuid})
+	}
+
+	// the same rows as the MySQL foreign keys cascade to
+	r.db.users = slices.DeleteFunc(r.db.users, func(row *userRow) bool { return isPurged(purged, row.id) })
+	r.db.addresses = slices.DeleteFunc(r.db.addresses, func(row *addressRow) bool { return isPurged(purged, row.userID) })
+	r.db.tokens = slices.DeleteFunc(r.db.tokens, func(row *tokenRow) bool { return isPurged(purged, row.userID) })
+	r.db.factors = slices.DeleteFunc(r.db.factors, func(row *factorRow) bool { return isPurged(purged, row.userID) })
+	r.db.codes = slices.DeleteFunc(r.db.codes, func(row *recoveryCodeRow) bool { return isPurged(purged, row.userID) })
+	r.db.refreshTokens = slices.DeleteFunc(r.db.refreshTokens, func(row *refreshTokenRow) bool { return isPurged(purged, row.userID) })
+	r.db.authCodes = slices.DeleteFunc(r.db.authCodes, func(row *authCodeRow) bool { return isPurged(purged, row.userID) })
+
+	return len(deleted), nil
+}
+
+func (r *userRepository) Erase(ctx context.Context, id domain.ID, erasedAt time.Time) error {
+	defer r.db.lock(ctx)()
+
+	row, ok := r.db.byUUID[id.String()]
+	if !ok {
+		return user.ErrNotFound
+	}
+
+	if row.erasedAt != nil {
+		return user.ErrErased
+	}
+
+	delete(r.db.byEmail, row.email)
+	delete(r.db.attempts, lockout.Subject{Kind: lockout.KindUser, Value: row.uuid})
+
+	row.email = user.ErasedEmail(id)
+	row.emailVerified = false
+	row.password = ""
+	row.mfaEnabled = false
+	row.firstName = ""
+	row.lastName = ""
+	row.phoneNumber = ""
+	row.version++
+	row.erasedAt = &erasedAt
+
+	if row.deletedAt == nil {
+		row.deletedAt = &erasedAt
+	}
+
+	r.db.byEmail[row.email] = row
+
+	// the same rows as Purge removes, the user row stays
+	erased := map[int64]struct{}{row.id: {}}
+	r.db.addresses = slices.DeleteFunc(r.db.addresses, func(row *addressRow) bool { return isPurged(erased, row.userID) })
+	r.db.tokens = slices.DeleteFunc(r.db.tokens, func(row *tokenRow) bool { return isPurged(erased, row.userID) })
+	r.db.factors = slices.DeleteFunc(r.db.factors, func(row *factorRow) bool { return isPurged(erased, row.userID) })
+	r.db.codes = slices.DeleteFunc(r.db.codes, func(row *recoveryCodeRow) bool { return isPurged(erased, row.userID) })
+	r.db.refreshTokens = slices.DeleteFunc(r.db.refreshTokens, func(row *refreshTokenRow) bool { return isPurged(erased, row.userID) })
+	r.db.authCodes = slices.DeleteFunc(r.db.authCodes, func(row *authCodeRow) bool { return isPurged(erased, row.userID) })
+
+	return nil
+}
+
+func isPurged(purged map[int64]struct{}, userID int64) bool {
+	_, ok := purged[userID]
+
+	return ok
+}
+
+// deletedTogether tells whether the address has been deleted with its user, the only way addresses are soft deleted
+func deletedTogether(addr *addressRow, row *userRow) bool {
+	return addr.deletedAt == nil || (row.deletedAt != nil && addr.deletedAt.Equal(*row.deletedAt))
+}
+
+func (r *userRepository) ReplacePasswordHash(ctx context.Context, id domain.ID, current, replacement string) error {
+	defer r.db.lock(ctx)()
+
+	row := r.activeUser(id)
+	if row == nil || row.password != current {
+		return user.ErrVersionMismatch
+	}
+
+	row.password = replacement
+
+	return nil
+}
+
+func (r *userRepository) IncrementVersion(ctx context.Context, id domain.ID, expected *int64) error {
+	defer r.db.lock(ctx)()
+
+	row := r.activeUser(id)
+	if row == nil {
+		return user.ErrNotFound
+	}
+
+	if expected != nil && *expected != row.version {
+		return user.ErrVersionMismatch
+	}
+
+	row.version++
+
+	return nil
+}
+
+func (r *userRepository) GetByUUID(ctx context.Context, id domain.ID) (*user.User, error) {
+	defer r.db.rlock(ctx)()
+
+	row := r.activeUser(id)
+	if row == nil {
+		return nil, user.ErrNotFound
+	}
+
+	return r.toDomain(row), nil
+}
+
+func (r *userRepository) GetByUUIDIncludingDeleted(ctx context.Context, id domain.ID) (*user.User, error) {
+	defer r.db.rlock(ctx)()
+
+	row, ok := r.db.byUUID[id.String()]
+	if !ok {
+		return nil, user.ErrNotFound
+	}
+
+	return r.toDomain(row), nil
+}
+
+func (r *userRepository) Get(ctx context.Context, q *user.ListQuery) (*user.Page, error) {
+	defer r.db.rlock(ctx)()
+
+	orderBy := q.OrderBy()
+
+	var rows []*userRow
+
+	for _, row := range r.db.users {
+		if (row.deletedAt != nil && !q.IncludeDeleted) || !r.matches(row, q.Filter) {
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	page := &user.Page{}
+
+	if q.WithTotal {
+		total := len(rows)
+		page.Total = &total
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return lessUser(rows[i], rows[j], orderBy)
+	})
+
+	if q.Cursor != nil {
+		after, err := cursorRow(orderBy, q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		start := sort.Search(len(rows), func(i int) bool {
+			return lessUser(after, rows[i], orderBy)
+		})
+		rows = rows[start:]
+	}
+
+	for i, row := range rows {
+		if i == q.Limit {
+			page.NextCursor = userCursor(orderBy, rows[i-1])
+			break
+		}
+
+		page.Users = append(page.Users, r.toDomain(row))
+	}
+
+	return page, nil
+}
+
+func (r *userRepository) GetCredentialsByEmail(ctx context.Context, email string) (*user.Credentials, error) {
+	defer r.db.rlock(ctx)()
+
+	row, ok := r.db.byEmail[email]
+	if !ok || row.deletedAt != nil {
+		return nil, user.ErrNotFound
+	}
+
+	return credentials(row)
+}
+
+func (r *userRepository) GetCredentialsByUUID(ctx context.Context, id domain.ID) (*user.Credentials, error) {
+	defer r.db.rlock(ctx)()
+
+	row := r.activeUser(id)
+	if row == nil {
+		return nil, user.ErrNotFound
+	}
+
+	return credentials(row)
+}
+
+func credentials(row *userRow) (*user.Credentials, error) {
+	userID, err := domain.ParseID(row.uuid)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	return &user.Credentials{
+		ID:           userID,
+		Email:        row.email,
+		PasswordHash: row.password,
+		Role:         row.role,
+		MFAEnabled:   row.mfaEnabled,
+	}, nil
+}
+
+func (r *userRepository) SetVerifiedEmail(ctx context.Context, id domain.ID, email string) error {
+	defer r.db.lock(ctx)()
+
+	row := r.activeUser(id)
+	if row == nil {
+		return user.ErrNotFound
+	}
+
+	if other, ok := r.db.byEmail[email]; ok && other != row {
+		return user.ErrEmailAlreadyExists
+	}
+
+	delete(r.db.byEmail, row.email)
+	row.email = email
+	row.emailVerified = true
+	r.db.byEmail[row.email] = row
+
+	return nil
+}
+
+// activeUser returns a not deleted user row, the caller must hold the lock
+func (r *userRepository) activeUser(id domain.ID) *userRow {
+	row, ok := r.db.byUUID[id.String()]
+	if !ok || row.deletedAt != nil {
+		return nil
+	}
+
+	return row
+}
+
+// address returns an address of a given type regardless of its soft delete state, the same as the MySQL unique key does
+func (r *userRepository) address(userID int64, addrType user.AddressType) *addressRow {
+	for _, addr := range r.db.addresses {
+		if addr.userID == userID && addr.addrType == addrType {
+			return addr
+		}
+	}
+
+	return nil
+}
+
+func (r *userRepository) toDomain(row *userRow) *user.User {
+	var domainAddresses []*user.Address
+
+	for _, addr := range r.db.addresses {
+		if addr.userID != row.id || !deletedTogether(addr, row) {
+			continue
+		}
+
+		domainAddresses = append(domainAddresses, &user.Address{
+			Type:       addr.addrType,
+			Street:     addr.street,
+			City:       addr.city,
+			State:      addr.state,
+			PostalCode: addr.postalCode,
+			Country:    addr.country,
+		})
+	}
+
+	userID, _ := domain.ParseID(row.uuid)
+
+	var deletedAt *time.Time
+	if row.deletedAt != nil {
+		at := *row.deletedAt
+		deletedAt = &at
+	}
+
+	return &user.User{
+		ID:            userID,
+		Email:         row.email,
+		EmailVerified: row.emailVerified,
+		MFAEnabled:    row.mfaEnabled,
+		Password:      "", // Password is not retrieved
+		FirstName:     row.firstName,
+		LastName:      row.lastName,
+		PhoneNumber:   row.phoneNumber,
+		Role:          row.role,
+		Addresses:     domainAddresses,
+		Version:       row.version,
+		DeletedAt:     deletedAt,
+	}
+}
+
+// matches mimics the MySQL filtering with its case-insensitive collation
+func (r *userRepository) matches(row *userRow, f user.Filter) bool {
+	switch {
+	case f.Email != "" && !strings.EqualFold(row.email, f.Email),
+		f.EmailPrefix != "" && !strings.HasPrefix(strings.ToLower(row.email), strings.ToLower(f.EmailPrefix)),
+		f.FirstName != "" && !strings.Contains(strings.ToLower(row.firstName), strings.ToLower(f.FirstName)),
+		f.LastName != "" && !strings.Contains(strings.ToLower(row.lastName), strings.ToLower(f.LastName)),
+		f.PhoneNumber != "" && row.phoneNumber != f.PhoneNumber:
+		return false
+	}
+
+	if !f.HasAddressConditions() {
+		return true
+	}
+
+	for _, addr := range r.db.addresses {
+		if addr.userID != row.id || !deletedTogether(addr, row) {
+			continue
+		}
+
+		if f.Country != "" && !strings.EqualFold(addr.country, f.Country) {
+			continue
+		}
+
+		if f.City != "" && !strings.EqualFold(addr.city, f.City) {
+			continue
+		}
+
+		if f.AddressType != nil && addr.addrType != *f.AddressType {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// lessUser orders rows by the given fields falling back to the id, the same as the MySQL repository does
+func lessUser(a, b *userRow, sorts []user.Sort) bool {
+	for _, s := range sorts {
+		var cmp int
+
+		switch s.Field {
+		case user.SortByEmail:
+			cmp = strings.Compare(strings.ToLower(a.email), strings.ToLower(b.email))
+		case user.SortByFirstName:
+			cmp = strings.Compare(strings.ToLower(a.firstName), strings.ToLower(b.firstName))
+		case user.SortByLastName:
+			cmp = strings.Compare(strings.ToLower(a.lastName), strings.ToLower(b.lastName))
+		case user.SortByCreatedAt:
+			cmp = a.createdAt.Compare(b.createdAt)
+		}
+
+		if cmp == 0 {
+			continue
+		}
+
+		if s.Desc {
+			return cmp > 0
+		}
+
+		return cmp < 0
+	}
+
+	return a.id < b.id
+}
+
+// userCursor points at the given row in the given order
+func userCursor(sorts []user.Sort, row *userRow) *user.Cursor {
+	c := &user.Cursor{
+		Sort:   sorts,
+		Values: make([]string, 0, len(sorts)),
+		ID:     row.id,
+	}
+
+	for _, s := range sorts {
+		var value string
+
+		switch s.Field {
+		case user.SortByEmail:
+			value = row.email
+		case user.SortByFirstName:
+			value = row.firstName
+		case user.SortByLastName:
+			value = row.lastName
+		case user.SortByCreatedAt:
+			value = row.createdAt.Format(time.RFC3339Nano)
+		}
+
+		c.Values = append(c.Values, value)
+	}
+
+	return c
+}
+
+// cursorRow turns the cursor back into a row, so it can be compared with the stored ones
+func cursorRow(sorts []user.Sort, c *user.Cursor) (*userRow, error) {
+	if !c.Matches(sorts) {
+		return nil, user.ErrInvalidCursor
+	}
+
+	row := &userRow{id: c.ID}
+
+	for i, s := range sorts {
+		switch s.Field {
+		case user.SortByEmail:
+			row.email = c.Values[i]
+		case user.SortByFirstName:
+			row.firstName = c.Values[i]
+		case user.SortByLastName:
+			row.lastName = c.Values[i]
+		case user.SortByCreatedAt:
+			createdAt, err := time.Parse(time.RFC3339Nano, c.Values[i])
+			if err != nil {
+				return nil, user.ErrInvalidCursor
+			}
+			row.createdAt = createdAt
+		default:
+			return nil, user.ErrInvalidCursor
+		}
+	}
+
+	return row, nil
+}
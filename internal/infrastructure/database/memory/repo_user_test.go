@@ -0,0 +1,671 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+func newTestUser(email string) *user.User {
+	return &user.User{
+		ID:          domain.NewID(),
+		Email:       email,
+		Password:    "hash",
+		FirstName:   "Test",
+		LastName:    "Test",
+		PhoneNumber: "1234567890",
+		Addresses: []*user.Address{
+			{
+				Type:       user.HomeAddress,
+				Street:     "Main av",
+				City:       "New York",
+				State:      "NY",
+				PostalCode: "55010",
+				Country:    "USA",
+			},
+		},
+	}
+}
+
+func TestCreate(t *testing.T) {
+	t.Run("create and get user", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+
+		err := repo.Create(context.Background(), u, time.Now())
+		assert.NoError(t, err)
+
+		result, err := repo.GetByUUID(context.Background(), u.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, u.Email, result.Email)
+		assert.Empty(t, result.Password)
+		assert.Equal(t, u.Addresses, result.Addresses)
+	})
+
+	t.Run("email already exists", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+
+		err := repo.Create(context.Background(), newTestUser("test@example.com"), time.Now())
+		assert.NoError(t, err)
+
+		err = repo.Create(context.Background(), newTestUser("test@example.com"), time.Now())
+		assert.ErrorIs(t, err, user.ErrEmailAlreadyExists)
+	})
+
+	t.Run("email of a deleted user still exists", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+		assert.NoError(t, repo.Delete(context.Background(), u.ID))
+
+		err := repo.Create(context.Background(), newTestUser("test@example.com"), time.Now())
+		assert.ErrorIs(t, err, user.ErrEmailAlreadyExists)
+	})
+
+	t.Run("duplicated address type", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		u.Addresses = append(u.Addresses, &user.Address{Type: user.HomeAddress, Street: "Other"})
+
+		err := repo.Create(context.Background(), u, time.Now())
+		assert.ErrorIs(t, err, user.ErrAddressAlreadyExists)
+
+		_, err = repo.GetByUUID(context.Background(), u.ID)
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	t.Run("update basic fields", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		err := repo.UpdateBasicFields(context.Background(), u.ID, []user.Change{user.Set(user.FieldFirstName, "New"), user.Set(user.FieldPhoneNumber, "987654321")})
+		assert.NoError(t, err)
+
+		result, _ := repo.GetByUUID(context.Background(), u.ID)
+		assert.Equal(t, "New", result.FirstName)
+		assert.Equal(t, "987654321", result.PhoneNumber)
+		assert.Equal(t, "Test", result.LastName)
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		err := repo.UpdateBasicFields(context.Background(), u.ID, []user.Change{user.Set(user.FieldFirstName, "New"), user.Set("email", "x@example.com")})
+		assert.Error(t, err)
+
+		result, _ := repo.GetByUUID(context.Background(), u.ID)
+		assert.Equal(t, "Test", result.FirstName)
+	})
+
+	t.Run("update basic fields of not existing user", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+
+		err := repo.UpdateBasicFields(context.Background(), domain.NewID(), []user.Change{user.Set(user.FieldFirstName, "New")})
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+
+	t.Run("update address", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		err := repo.UpdateAddress(context.Background(), u.ID, user.HomeAddress, []user.Change{user.Set(user.FieldCity, "Warszawa")})
+		assert.NoError(t, err)
+
+		result, _ := repo.GetByUUID(context.Background(), u.ID)
+		assert.Equal(t, "Warszawa", result.Addresses[0].City)
+	})
+
+	t.Run("address not found", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		err := repo.UpdateAddress(context.Background(), u.ID, user.BillingAddress, []user.Change{user.Set(user.FieldCity, "Warszawa")})
+		assert.ErrorIs(t, err, user.ErrAddressNotFound)
+	})
+
+	t.Run("insert address", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		err := repo.InsertAddress(context.Background(), u.ID, &user.Address{Type: user.BillingAddress, City: "Warszawa"}, time.Now())
+		assert.NoError(t, err)
+
+		err = repo.InsertAddress(context.Background(), u.ID, &user.Address{Type: user.BillingAddress, City: "Warszawa"}, time.Now())
+		assert.ErrorIs(t, err, user.ErrAddressAlreadyExists)
+
+		result, _ := repo.GetByUUID(context.Background(), u.ID)
+		assert.Len(t, result.Addresses, 2)
+	})
+
+	t.Run("clear fields", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		err := repo.UpdateBasicFields(context.Background(), u.ID, []user.Change{user.Clear(user.FieldPhoneNumber)})
+		assert.NoError(t, err)
+
+		err = repo.UpdateAddress(context.Background(), u.ID, user.HomeAddress, []user.Change{user.Clear(user.FieldState)})
+		assert.NoError(t, err)
+
+		result, _ := repo.GetByUUID(context.Background(), u.ID)
+		assert.Empty(t, result.PhoneNumber)
+		assert.Empty(t, result.Addresses[0].State)
+	})
+
+	t.Run("delete address", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		assert.NoError(t, repo.DeleteAddress(context.Background(), u.ID, user.HomeAddress))
+
+		err := repo.DeleteAddress(context.Background(), u.ID, user.HomeAddress)
+		assert.ErrorIs(t, err, user.ErrAddressNotFound)
+
+		result, _ := repo.GetByUUID(context.Background(), u.ID)
+		assert.Empty(t, result.Addresses)
+
+		// the type is free to be used again
+		err = repo.InsertAddress(context.Background(), u.ID, &user.Address{Type: user.HomeAddress, City: "Warszawa"}, time.Now())
+		assert.NoError(t, err)
+	})
+}
+
+func TestListAddresses(t *testing.T) {
+	t.Run("list addresses", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		addresses, err := repo.ListAddresses(context.Background(), u.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, u.Addresses, addresses)
+	})
+
+	t.Run("deleted user", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+		assert.NoError(t, repo.Delete(context.Background(), u.ID))
+
+		_, err := repo.ListAddresses(context.Background(), u.ID)
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+}
+
+func TestSetVerifiedEmail(t *testing.T) {
+	t.Run("change and verify email", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("old@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		assert.NoError(t, repo.SetVerifiedEmail(context.Background(), u.ID, "new@example.com"))
+
+		result, err := repo.GetByUUID(context.Background(), u.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "new@example.com", result.Email)
+		assert.True(t, result.EmailVerified)
+
+		_, err = repo.GetCredentialsByEmail(context.Background(), "old@example.com")
+		assert.ErrorIs(t, err, user.ErrNotFound)
+
+		// the old email is free again
+		assert.NoError(t, repo.Create(context.Background(), newTestUser("old@example.com"), time.Now()))
+	})
+
+	t.Run("email of another user", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("old@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+		assert.NoError(t, repo.Create(context.Background(), newTestUser("taken@example.com"), time.Now()))
+
+		err := repo.SetVerifiedEmail(context.Background(), u.ID, "taken@example.com")
+		assert.ErrorIs(t, err, user.ErrEmailAlreadyExists)
+	})
+}
+
+func TestGetCredentialsByUUID(t *testing.T) {
+	t.Run("get credentials", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		credentials, err := repo.GetCredentialsByUUID(context.Background(), u.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, u.ID, credentials.ID)
+		assert.Equal(t, "test@example.com", credentials.Email)
+		assert.Equal(t, "hash", credentials.PasswordHash)
+	})
+
+	t.Run("deleted user", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+		assert.NoError(t, repo.Delete(context.Background(), u.ID))
+
+		_, err := repo.GetCredentialsByUUID(context.Background(), u.ID)
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Run("soft delete user", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		assert.NoError(t, repo.Delete(context.Background(), u.ID))
+
+		_, err := repo.GetByUUID(context.Background(), u.ID)
+		assert.ErrorIs(t, err, user.ErrNotFound)
+
+		err = repo.UpdateBasicFields(context.Background(), u.ID, []user.Change{user.Set(user.FieldFirstName, "New")})
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+
+		err := repo.Delete(context.Background(), domain.NewID())
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+}
+
+func TestRestore(t *testing.T) {
+	t.Run("restore user with addresses", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+		assert.NoError(t, repo.Delete(context.Background(), u.ID))
+
+		assert.NoError(t, repo.Restore(context.Background(), u.ID))
+
+		result, err := repo.GetByUUID(context.Background(), u.ID)
+		assert.NoError(t, err)
+		assert.Nil(t, result.DeletedAt)
+		assert.Equal(t, u.Addresses, result.Addresses)
+	})
+
+	t.Run("user not deleted", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		err := repo.Restore(context.Background(), u.ID)
+		assert.ErrorIs(t, err, user.ErrNotDeleted)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+
+		err := repo.Restore(context.Background(), domain.NewID())
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+}
+
+func TestErase(t *testing.T) {
+	t.Run("erase personal data", func(t *testing.T) {
+		db := NewDatabase()
+		repo := NewUserRepository(db)
+		sessionRepo := NewSessionRepository(db)
+
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		_, token, err := session.New(u.ID, domain.NewID(), time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.NoError(t, sessionRepo.Create(context.Background(), token, time.Now()))
+
+		assert.NoError(t, repo.Erase(context.Background(), u.ID, time.Now()))
+
+		page, err := repo.Get(context.Background(), &user.ListQuery{Limit: 10, IncludeDeleted: true})
+		assert.NoError(t, err)
+		assert.Len(t, page.Users, 1)
+
+		erased := page.Users[0]
+		assert.Equal(t, u.ID, erased.ID)
+		assert.Equal(t, user.ErasedEmail(u.ID), erased.Email)
+		assert.Empty(t, erased.FirstName)
+		assert.Empty(t, erased.LastName)
+		assert.Empty(t, erased.PhoneNumber)
+		assert.Empty(t, erased.Addresses)
+		assert.NotNil(t, erased.DeletedAt)
+
+		_, err = repo.GetCredentialsByEmail(context.Background(), "test@example.com")
+		assert.ErrorIs(t, err, user.ErrNotFound)
+
+		sessions, err := sessionRepo.ListByUser(context.Background(), u.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, sessions)
+
+		// the email is free again
+		assert.NoError(t, repo.Create(context.Background(), newTestUser("test@example.com"), time.Now()))
+	})
+
+	t.Run("erase deleted user", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+		assert.NoError(t, repo.Delete(context.Background(), u.ID))
+
+		assert.NoError(t, repo.Erase(context.Background(), u.ID, time.Now()))
+		assert.ErrorIs(t, repo.Restore(context.Background(), u.ID), user.ErrErased)
+	})
+
+	t.Run("user erased already", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+		assert.NoError(t, repo.Erase(context.Background(), u.ID, time.Now()))
+
+		err := repo.Erase(context.Background(), u.ID, time.Now())
+		assert.ErrorIs(t, err, user.ErrErased)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+
+		err := repo.Erase(context.Background(), domain.NewID(), time.Now())
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+}
+
+func TestGetIncludingDeleted(t *testing.T) {
+	repo := NewUserRepository(NewDatabase())
+
+	active := newTestUser("active@example.com")
+	deleted := newTestUser("deleted@example.com")
+	assert.NoError(t, repo.Create(context.Background(), active, time.Now()))
+	assert.NoError(t, repo.Create(context.Background(), deleted, time.Now()))
+	assert.NoError(t, repo.Delete(context.Background(), deleted.ID))
+
+	page, err := repo.Get(context.Background(), &user.ListQuery{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, page.Users, 1)
+
+	page, err = repo.Get(context.Background(), &user.ListQuery{Filter: user.Filter{City: "New York"}, Limit: 10, IncludeDeleted: true})
+	assert.NoError(t, err)
+	assert.Len(t, page.Users, 2)
+	assert.Nil(t, page.Users[0].DeletedAt)
+	assert.NotNil(t, page.Users[1].DeletedAt)
+	assert.Equal(t, deleted.Addresses, page.Users[1].Addresses)
+}
+
+func TestGetByUUIDIncludingDeleted(t *testing.T) {
+	repo := NewUserRepository(NewDatabase())
+
+	u := newTestUser("deleted@example.com")
+	assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+	assert.NoError(t, repo.Delete(context.Background(), u.ID))
+
+	_, err := repo.GetByUUID(context.Background(), u.ID)
+	assert.ErrorIs(t, err, user.ErrNotFound)
+
+	deleted, err := repo.GetByUUIDIncludingDeleted(context.Background(), u.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, u.Email, deleted.Email)
+	assert.Equal(t, u.Addresses, deleted.Addresses)
+	assert.NotNil(t, deleted.DeletedAt)
+
+	_, err = repo.GetByUUIDIncludingDeleted(context.Background(), domain.NewID())
+	assert.ErrorIs(t, err, user.ErrNotFound)
+}
+
+func TestPurge(t *testing.T) {
+	t.Run("purge users deleted before", func(t *testing.T) {
+		db := NewDatabase()
+		repo := NewUserRepository(db)
+		lockoutRepo := NewLockoutRepository(db)
+
+		active := newTestUser("active@example.com")
+		deleted := newTestUser("deleted@example.com")
+		assert.NoError(t, repo.Create(context.Background(), active, time.Now()))
+		assert.NoError(t, repo.Create(context.Background(), deleted, time.Now()))
+		_, err := lockoutRepo.RecordFailure(context.Background(), lockout.User(deleted.ID), time.Now(), time.Hour)
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Delete(context.Background(), deleted.ID))
+
+		purged, err := repo.Purge(context.Background(), time.Now().Add(-time.Hour), 10)
+		assert.NoError(t, err)
+		assert.Zero(t, purged)
+
+		purged, err = repo.Purge(context.Background(), time.Now().Add(time.Hour), 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, purged)
+
+		err = repo.Restore(context.Background(), deleted.ID)
+		assert.ErrorIs(t, err, user.ErrNotFound)
+
+		state, err := lockoutRepo.Get(context.Background(), lockout.User(deleted.ID))
+		assert.NoError(t, err)
+		assert.Zero(t, state.Failures)
+
+		_, err = repo.GetByUUID(context.Background(), active.ID)
+		assert.NoError(t, err)
+
+		// the email is free again
+		assert.NoError(t, repo.Create(context.Background(), newTestUser("deleted@example.com"), time.Now()))
+	})
+
+	t.Run("at most limit users", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+
+		for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+			u := newTestUser(email)
+			assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+			assert.NoError(t, repo.Delete(context.Background(), u.ID))
+		}
+
+		purged, err := repo.Purge(context.Background(), time.Now().Add(time.Hour), 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, purged)
+
+		purged, err = repo.Purge(context.Background(), time.Now().Add(time.Hour), 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, purged)
+	})
+
+	t.Run("erased users are kept", func(t *testing.T) {
+		repo := NewUserRepository(NewDatabase())
+
+		u := newTestUser("erased@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+		assert.NoError(t, repo.Erase(context.Background(), u.ID, time.Now()))
+
+		purged, err := repo.Purge(context.Background(), time.Now().Add(time.Hour), 10)
+		assert.NoError(t, err)
+		assert.Zero(t, purged)
+
+		assert.ErrorIs(t, repo.Restore(context.Background(), u.ID), user.ErrErased)
+	})
+}
+
+func TestGet(t *testing.T) {
+	repo := NewUserRepository(NewDatabase())
+
+	base := time.Now()
+
+	var ids []domain.ID
+	for i, email := range []string{"test1@example.com", "test2@example.com", "test3@example.com", "test4@example.com"} {
+		u := newTestUser(email)
+		assert.NoError(t, repo.Create(context.Background(), u, base.Add(time.Duration(i)*time.Second)))
+		ids = append(ids, u.ID)
+	}
+	assert.NoError(t, repo.Delete(context.Background(), ids[1]))
+
+	first, err := repo.Get(context.Background(), &user.ListQuery{Limit: 2, WithTotal: true})
+
+	t.Run("first page", func(t *testing.T) {
+		assert.NoError(t, err)
+		assert.Len(t, first.Users, 2)
+		assert.Equal(t, ids[0], first.Users[0].ID)
+		assert.Equal(t, ids[2], first.Users[1].ID)
+		assert.Equal(t, 3, *first.Total)
+		assert.NotNil(t, first.NextCursor)
+	})
+
+	t.Run("last page not shifted by changes in between", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(context.Background(), ids[0]))
+
+		page, err := repo.Get(context.Background(), &user.ListQuery{Cursor: first.NextCursor, Limit: 2})
+		assert.NoError(t, err)
+		assert.Len(t, page.Users, 1)
+		assert.Equal(t, ids[3], page.Users[0].ID)
+		assert.Nil(t, page.NextCursor)
+		assert.Nil(t, page.Total)
+	})
+
+	t.Run("descending order", func(t *testing.T) {
+		sorts := []user.Sort{{Field: user.SortByCreatedAt, Desc: true}}
+
+		page, err := repo.Get(context.Background(), &user.ListQuery{Sort: sorts, Limit: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, ids[3], page.Users[0].ID)
+
+		page, err = repo.Get(context.Background(), &user.ListQuery{Sort: sorts, Cursor: page.NextCursor, Limit: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, ids[2], page.Users[0].ID)
+		assert.Nil(t, page.NextCursor)
+	})
+
+	t.Run("cursor issued for a different order", func(t *testing.T) {
+		_, err := repo.Get(context.Background(), &user.ListQuery{Sort: []user.Sort{{Field: user.SortByEmail}}, Cursor: first.NextCursor, Limit: 2})
+		assert.ErrorIs(t, err, user.ErrInvalidCursor)
+	})
+}
+
+func TestGetFiltered(t *testing.T) {
+	repo := NewUserRepository(NewDatabase())
+
+	base := time.Now()
+
+	john := newTestUser("john.smith@example.com")
+	john.FirstName, john.LastName = "John", "Smith"
+	assert.NoError(t, repo.Create(context.Background(), john, base))
+
+	jane := newTestUser("jane.doe@example.org")
+	jane.FirstName, jane.LastName = "Jane", "Doe"
+	jane.Addresses[0].Type = user.WorkAddress
+	jane.Addresses[0].City = "Berlin"
+	jane.Addresses[0].Country = "Germany"
+	assert.NoError(t, repo.Create(context.Background(), jane, base.Add(time.Second)))
+
+	adam := newTestUser("adam_smith@example.com")
+	adam.FirstName, adam.LastName = "Adam", "Smithson"
+	assert.NoError(t, repo.Create(context.Background(), adam, base.Add(2*time.Second)))
+
+	workType := user.WorkAddress
+
+	tests := []struct {
+		name     string
+		query    user.ListQuery
+		expected []domain.ID
+	}{
+		{
+			name:     "email exact match",
+			query:    user.ListQuery{Filter: user.Filter{Email: "JANE.DOE@example.org"}},
+			expected: []domain.ID{jane.ID},
+		},
+		{
+			name:     "email prefix",
+			query:    user.ListQuery{Filter: user.Filter{EmailPrefix: "j"}},
+			expected: []domain.ID{john.ID, jane.ID},
+		},
+		{
+			name:     "last name contains",
+			query:    user.ListQuery{Filter: user.Filter{LastName: "smith"}},
+			expected: []domain.ID{john.ID, adam.ID},
+		},
+		{
+			name:     "address conditions",
+			query:    user.ListQuery{Filter: user.Filter{Country: "germany", City: "Berlin", AddressType: &workType}},
+			expected: []domain.ID{jane.ID},
+		},
+		{
+			name:     "address conditions not met by the same address",
+			query:    user.ListQuery{Filter: user.Filter{Country: "USA", AddressType: &workType}},
+			expected: nil,
+		},
+		{
+			name:     "sort by last name",
+			query:    user.ListQuery{Sort: []user.Sort{{Field: user.SortByLastName}}},
+			expected: []domain.ID{jane.ID, john.ID, adam.ID},
+		},
+		{
+			name:     "sort by creation time descending",
+			query:    user.ListQuery{Sort: []user.Sort{{Field: user.SortByCreatedAt, Desc: true}}},
+			expected: []domain.ID{adam.ID, jane.ID, john.ID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Limit = 10
+
+			page, err := repo.Get(context.Background(), &tt.query)
+			assert.NoError(t, err)
+
+			var ids []domain.ID
+			for _, u := range page.Users {
+				ids = append(ids, u.ID)
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
+
+func TestIncrementVersion(t *testing.T) {
+	repo := NewUserRepository(NewDatabase())
+	u := newTestUser("test@example.com")
+	assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+	result, err := repo.GetByUUID(context.Background(), u.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.Version)
+
+	stale := int64(1)
+	assert.NoError(t, repo.IncrementVersion(context.Background(), u.ID, &stale))
+	assert.ErrorIs(t, repo.IncrementVersion(context.Background(), u.ID, &stale), user.ErrVersionMismatch)
+	assert.NoError(t, repo.IncrementVersion(context.Background(), u.ID, nil))
+
+	result, err = repo.GetByUUID(context.Background(), u.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), result.Version)
+
+	assert.ErrorIs(t, repo.IncrementVersion(context.Background(), domain.NewID(), nil), user.ErrNotFound)
+}
+
+func TestReplacePasswordHash(t *testing.T) {
+	repo := NewUserRepository(NewDatabase())
+	u := newTestUser("test@example.com")
+	assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+	assert.NoError(t, repo.ReplacePasswordHash(context.Background(), u.ID, "hash", "new-hash"))
+	assert.ErrorIs(t, repo.ReplacePasswordHash(context.Background(), u.ID, "hash", "other-hash"), user.ErrVersionMismatch)
+	assert.ErrorIs(t, repo.ReplacePasswordHash(context.Background(), domain.NewID(), "hash", "new-hash"), user.ErrVersionMismatch)
+
+	credentials, err := repo.GetCredentialsByUUID(context.Background(), u.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "new-hash", credentials.PasswordHash)
+
+	result, err := repo.GetByUUID(context.Background(), u.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.Version)
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+)
+
+type verificationRepository struct {
+	db *Database
+}
+
+var _ verification.Repository = (*verificationRepository)(nil)
+
+func NewVerificationRepository(db *Database) *verificationRepository {
+	return &verificationRepository{
+		db: db,
+	}
+}
+
+func (r *verificationRepository) Create(ctx context.Context, t *verification.Token, createdAt time.Time) error {
+	defer r.db.lock(ctx)()
+
+	row, ok := r.db.byUUID[t.UserID.String()]
+	if !ok || row.deletedAt != nil {
+		return user.ErrNotFound
+	}
+
+	r.db.tokens = append(r.db.tokens, &tokenRow{
+		userID:    row.id,
+		purpose:   t.Purpose,
+		hash:      t.Hash,
+		email:     t.Email,
+		expiresAt: t.ExpiresAt,
+		createdAt: createdAt,
+	})
+
+	return nil
+}
+
+func (r *verificationRepository) Consume(ctx context.Context, hash string, purpose verification.Purpose, now time.Time) (*verification.Token, error) {
+	defer r.db.lock(ctx)()
+
+	for _, row := range r.db.tokens {
+		if row.hash != hash || row.purpose != purpose || row.usedAt != nil {
+			continue
+		}
+
+		owner := r.owner(row.userID)
+		if owner == nil || !now.Before(row.expiresAt) {
+			return nil, verification.ErrInvalidToken
+		}
+
+		row.usedAt = &now
+
+		userID, _ := domain.ParseID(owner.uuid)
+
+		return &verification.Token{
+			Hash:      row.hash,
+			UserID:    userID,
+			Purpose:   row.purpose,
+			Email:     row.email,
+			ExpiresAt: row.expiresAt,
+		}, nil
+	}
+
+	return nil, verification.ErrInvalidToken
+}
+
+// Revoke deletes the unused tokens, used ones are kept as a trace of past verifications
+func (r *verificationRepository) Revoke(ctx context.Context, userID domain.ID, purpose verification.Purpose) error {
+	defer r.db.lock(ctx)()
+
+	row, ok := r.db.byUUID[userID.String()]
+	if !ok {
+		return nil
+	}
+
+	tokens := r.db.tokens[:0]
+	for _, t := range r.db.tokens {
+		if t.userID == row.id && t.purpose == purpose && t.usedAt == nil {
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	r.db.tokens = tokens
+
+	return nil
+}
+
+// owner returns the not deleted user the token was issued to, the caller must hold the lock
+func (r *verificationRepository) owner(id int64) *userRow {
+	for _, row := range r.db.users {
+		if row.id == id && row.deletedAt == nil {
+			return row
+		}
+	}
+
+	return nil
+}
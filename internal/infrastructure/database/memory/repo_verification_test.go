@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+)
+
+func TestVerificationTokens(t *testing.T) {
+	now := time.Now()
+
+	t.Run("token is consumed once", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		assert.NoError(t, NewUserRepository(db).Create(context.Background(), u, now))
+
+		repo := NewVerificationRepository(db)
+
+		secret, token, err := verification.New(u.ID, verification.PurposeEmail, "new@example.com", now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Create(context.Background(), token, now))
+
+		result, err := repo.Consume(context.Background(), verification.Hash(secret), verification.PurposeEmail, now)
+		assert.NoError(t, err)
+		assert.Equal(t, u.ID, result.UserID)
+		assert.Equal(t, "new@example.com", result.Email)
+
+		_, err = repo.Consume(context.Background(), verification.Hash(secret), verification.PurposeEmail, now)
+		assert.ErrorIs(t, err, verification.ErrInvalidToken)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		assert.NoError(t, NewUserRepository(db).Create(context.Background(), u, now))
+
+		repo := NewVerificationRepository(db)
+
+		secret, token, err := verification.New(u.ID, verification.PurposeEmail, u.Email, now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Create(context.Background(), token, now))
+
+		_, err = repo.Consume(context.Background(), verification.Hash(secret), verification.PurposeEmail, now.Add(time.Hour))
+		assert.ErrorIs(t, err, verification.ErrInvalidToken)
+	})
+
+	t.Run("token of another purpose", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		assert.NoError(t, NewUserRepository(db).Create(context.Background(), u, now))
+
+		repo := NewVerificationRepository(db)
+
+		secret, token, err := verification.New(u.ID, verification.PurposeEmail, u.Email, now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Create(context.Background(), token, now))
+
+		_, err = repo.Consume(context.Background(), verification.Hash(secret), verification.Purpose("other"), now)
+		assert.ErrorIs(t, err, verification.ErrInvalidToken)
+	})
+
+	t.Run("revoked token", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		assert.NoError(t, NewUserRepository(db).Create(context.Background(), u, now))
+
+		repo := NewVerificationRepository(db)
+
+		secret, token, err := verification.New(u.ID, verification.PurposeEmail, u.Email, now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Create(context.Background(), token, now))
+		assert.NoError(t, repo.Revoke(context.Background(), u.ID, verification.PurposeEmail))
+
+		_, err = repo.Consume(context.Background(), verification.Hash(secret), verification.PurposeEmail, now)
+		assert.ErrorIs(t, err, verification.ErrInvalidToken)
+	})
+
+	t.Run("token of a deleted user", func(t *testing.T) {
+		db := NewDatabase()
+		userRepo := NewUserRepository(db)
+		u := newTestUser("test@example.com")
+		assert.NoError(t, userRepo.Create(context.Background(), u, now))
+
+		repo := NewVerificationRepository(db)
+
+		secret, token, err := verification.New(u.ID, verification.PurposeEmail, u.Email, now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Create(context.Background(), token, now))
+		assert.NoError(t, userRepo.Delete(context.Background(), u.ID))
+
+		_, err = repo.Consume(context.Background(), verification.Hash(secret), verification.PurposeEmail, now)
+		assert.ErrorIs(t, err, verification.ErrInvalidToken)
+	})
+
+	t.Run("consumed token is restored on rollback", func(t *testing.T) {
+		db := NewDatabase()
+		u := newTestUser("test@example.com")
+		assert.NoError(t, NewUserRepository(db).Create(context.Background(), u, now))
+
+		repo := NewVerificationRepository(db)
+
+		secret, token, err := verification.New(u.ID, verification.PurposeEmail, u.Email, now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Create(context.Background(), token, now))
+
+		err = NewUnitOfWork(db).WithinTx(context.Background(), func(ctx context.Context) error {
+			if _, err := repo.Consume(ctx, verification.Hash(secret), verification.PurposeEmail, now); err != nil {
+				return err
+			}
+
+			return assert.AnError
+		})
+		assert.ErrorIs(t, err, assert.AnError)
+
+		_, err = repo.Consume(context.Background(), verification.Hash(secret), verification.PurposeEmail, now)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,251 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+)
+
+type subscriptionRepository struct {
+	db *Database
+}
+
+var _ webhook.SubscriptionRepository = (*subscriptionRepository)(nil)
+
+func NewSubscriptionRepository(db *Database) *subscriptionRepository {
+	return &subscriptionRepository{
+		db: db,
+	}
+}
+
+func (r *subscriptionRepository) Create(ctx context.Context, s *webhook.Subscription) error {
+	defer r.db.lock(ctx)()
+
+	row := &subscriptionRow{subscription: *s}
+	row.subscription.EventTypes = slices.Clone(s.EventTypes)
+
+	r.db.subscriptions = append(r.db.subscriptions, row)
+
+	return nil
+}
+
+func (r *subscriptionRepository) Get(ctx context.Context, id domain.ID) (*webhook.Subscription, error) {
+	defer r.db.rlock(ctx)()
+
+	row := r.db.findSubscription(id)
+	if row == nil {
+		return nil, webhook.ErrSubscriptionNotFound
+	}
+
+	return row.toSubscription(), nil
+}
+
+func (r *subscriptionRepository) List(ctx context.Context) ([]*webhook.Subscription, error) {
+	defer r.db.rlock(ctx)()
+
+	subs := make([]*webhook.Subscription, 0, len(r.db.subscriptions))
+	for _, row := range r.db.subscriptions {
+		subs = append(subs, row.toSubscription())
+	}
+
+	return subs, nil
+}
+
+func (r *subscriptionRepository) Update(ctx context.Context, s *webhook.Subscription) error {
+	defer r.db.lock(ctx)()
+
+	row := r.db.findSubscription(s.ID)
+	if row == nil {
+		return webhook.ErrSubscriptionNotFound
+	}
+
+	row.subscription.URL = s.URL
+	row.subscription.EventTypes = slices.Clone(s.EventTypes)
+	row.subscription.EncryptedSecret = s.EncryptedSecret
+	row.subscription.UpdatedAt = s.UpdatedAt
+
+	return nil
+}
+
+func (r *subscriptionRepository) Delete(ctx context.Context, id domain.ID) error {
+	defer r.db.lock(ctx)()
+
+	before := len(r.db.subscriptions)
+
+	r.db.subscriptions = slices.DeleteFunc(r.db.subscriptions, func(row *subscriptionRow) bool {
+		return row.subscription.ID == id
+	})
+
+	if len(r.db.subscriptions) == before {
+		return webhook.ErrSubscriptionNotFound
+	}
+
+	r.db.deliveries = slices.DeleteFunc(r.db.deliveries, func(row *deliveryRow) bool {
+		return row.delivery.SubscriptionID == id
+	})
+
+	return nil
+}
+
+// findSubscription returns the row of the subscription, nil when there is none. The caller must hold a lock.
+func (db *Database) findSubscription(id domain.ID) *subscriptionRow {
+	for _, row := range db.subscriptions {
+		if row.subscription.ID == id {
+			return row
+		}
+	}
+
+	return nil
+}
+
+func (row *subscriptionRow) toSubscription() *webhook.Subscription {
+	s := row.subscription
+	s.EventTypes = slices.Clone(row.subscription.EventTypes)
+
+	return &s
+}
+
+type deliveryRepository struct {
+	db *Database
+}
+
+var _ webhook.DeliveryRepository = (*deliveryRepository)(nil)
+
+func NewDeliveryRepository(db *Database) *deliveryRepository {
+	return &deliveryRepository{
+		db: db,
+	}
+}
+
+func (r *deliveryRepository) Create(ctx context.Context, d *webhook.Delivery) error {
+	defer r.db.lock(ctx)()
+
+	// the subscription is referenced by a foreign key in MySQL
+	if r.db.findSubscription(d.SubscriptionID) == nil {
+		return webhook.ErrSubscriptionNotFound
+	}
+
+	r.db.deliverySeq++
+	d.Seq = r.db.deliverySeq
+
+	row := &deliveryRow{delivery: *d, event: *d.Event}
+	row.delivery.Event = nil
+	row.event.Data = slices.Clone(d.Event.Data)
+
+	r.db.deliveries = append(r.db.deliveries, row)
+
+	return nil
+}
+
+func (r *deliveryRepository) Get(ctx context.Context, subscriptionID, id domain.ID) (*webhook.Delivery, error) {
+	defer r.db.rlock(ctx)()
+
+	for _, row := range r.db.deliveries {
+		if row.delivery.ID == id && row.delivery.SubscriptionID == subscriptionID {
+			return row.toDelivery(), nil
+		}
+	}
+
+	return nil, webhook.ErrDeliveryNotFound
+}
+
+func (r *deliveryRepository) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*webhook.Delivery, error) {
+	defer r.db.lock(ctx)()
+
+	var due []*deliveryRow
+
+	for _, row := range r.db.deliveries {
+		if row.delivery.Status == webhook.StatusPending && !row.delivery.NextAttemptAt.After(now) {
+			due = append(due, row)
+		}
+	}
+
+	slices.SortStableFunc(due, func(a, b *deliveryRow) int {
+		return a.delivery.NextAttemptAt.Compare(*b.delivery.NextAttemptAt)
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*webhook.Delivery, 0, len(due))
+
+	for _, row := range due {
+		until := leaseUntil
+		row.delivery.NextAttemptAt = &until
+		claimed = append(claimed, row.toDelivery())
+	}
+
+	return claimed, nil
+}
+
+func (r *deliveryRepository) Update(ctx context.Context, d *webhook.Delivery) error {
+	defer r.db.lock(ctx)()
+
+	for _, row := range r.db.deliveries {
+		if row.delivery.ID == d.ID {
+			row.delivery.Status = d.Status
+			row.delivery.Attempts = d.Attempts
+			row.delivery.NextAttemptAt = d.NextAttemptAt
+			row.delivery.LastAttemptAt = d.LastAttemptAt
+			row.delivery.LastStatusCode = d.LastStatusCode
+			row.delivery.LastError = d.LastError
+
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (r *deliveryRepository) List(ctx context.Context, q *webhook.DeliveryQuery) (*webhook.DeliveryPage, error) {
+	defer r.db.rlock(ctx)()
+
+	page := &webhook.DeliveryPage{}
+
+	// the deliveries are appended in the order of their seqs, so they are walked backwards to list the newest first
+	for i := len(r.db.deliveries) - 1; i >= 0; i-- {
+		row := r.db.deliveries[i]
+
+		if row.delivery.SubscriptionID != q.SubscriptionID ||
+			(q.Status != "" && row.delivery.Status != q.Status) ||
+			(q.Cursor != nil && row.delivery.Seq >= q.Cursor.Seq) {
+			continue
+		}
+
+		// one delivery more than requested tells whether there is a next page
+		if len(page.Deliveries) == q.Limit {
+			page.NextCursor = &webhook.Cursor{Seq: page.Deliveries[len(page.Deliveries)-1].Seq}
+			break
+		}
+
+		page.Deliveries = append(page.Deliveries, row.toDelivery())
+	}
+
+	return page, nil
+}
+
+func (r *deliveryRepository) Erase(ctx context.Context, userID domain.ID) error {
+	defer r.db.lock(ctx)()
+
+	for _, row := range r.db.deliveries {
+		if row.event.UserID == userID {
+			row.event.Data = json.RawMessage("{}")
+		}
+	}
+
+	return nil
+}
+
+func (row *deliveryRow) toDelivery() *webhook.Delivery {
+	d := row.delivery
+	e := row.event
+	e.Data = slices.Clone(row.event.Data)
+	d.Event = &e
+
+	return &d
+}
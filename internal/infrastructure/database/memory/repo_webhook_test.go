@@ -0,0 +1,180 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+)
+
+func newTestSubscription(t *testing.T, db *Database, now time.Time) *webhook.Subscription {
+	s := &webhook.Subscription{
+		ID:              domain.NewID(),
+		URL:             "https://crm.example.com/hooks",
+		EventTypes:      []event.Type{event.TypeUserCreated, event.TypeUserDeleted},
+		EncryptedSecret: "encrypted",
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	assert.NoError(t, NewSubscriptionRepository(db).Create(context.Background(), s))
+
+	return s
+}
+
+func newTestDelivery(t *testing.T, db *Database, subscriptionID domain.ID, at time.Time) *webhook.Delivery {
+	e, err := event.New(domain.NewID(), event.UserDeleted{}, at)
+	assert.NoError(t, err)
+
+	d := webhook.NewDelivery(subscriptionID, e, at)
+	assert.NoError(t, NewDeliveryRepository(db).Create(context.Background(), d))
+
+	return d
+}
+
+func TestSubscriptionRepository(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("create, update and get", func(t *testing.T) {
+		db := NewDatabase()
+		repo := NewSubscriptionRepository(db)
+		s := newTestSubscription(t, db, now)
+
+		s.URL = "https://crm.example.com/v2/hooks"
+		s.EventTypes = []event.Type{event.TypeUserUpdated}
+		s.UpdatedAt = now.Add(time.Hour)
+		assert.NoError(t, repo.Update(context.Background(), s))
+
+		stored, err := repo.Get(context.Background(), s.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, s, stored)
+
+		subs, err := repo.List(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []*webhook.Subscription{s}, subs)
+	})
+
+	t.Run("unknown subscription", func(t *testing.T) {
+		repo := NewSubscriptionRepository(NewDatabase())
+
+		_, err := repo.Get(context.Background(), domain.NewID())
+		assert.ErrorIs(t, err, webhook.ErrSubscriptionNotFound)
+		assert.ErrorIs(t, repo.Update(context.Background(), &webhook.Subscription{ID: domain.NewID()}), webhook.ErrSubscriptionNotFound)
+		assert.ErrorIs(t, repo.Delete(context.Background(), domain.NewID()), webhook.ErrSubscriptionNotFound)
+	})
+
+	t.Run("deleting removes the deliveries", func(t *testing.T) {
+		db := NewDatabase()
+		s := newTestSubscription(t, db, now)
+		other := newTestSubscription(t, db, now)
+		newTestDelivery(t, db, s.ID, now)
+		kept := newTestDelivery(t, db, other.ID, now)
+
+		assert.NoError(t, NewSubscriptionRepository(db).Delete(context.Background(), s.ID))
+
+		due, err := NewDeliveryRepository(db).Claim(context.Background(), now, now.Add(time.Minute), 10)
+		assert.NoError(t, err)
+		assert.Len(t, due, 1)
+		assert.Equal(t, kept.ID, due[0].ID)
+	})
+}
+
+func TestDeliveryRepository(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	policy := &webhook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Minute, MaxDelay: time.Hour}
+
+	t.Run("claim due deliveries", func(t *testing.T) {
+		db := NewDatabase()
+		repo := NewDeliveryRepository(db)
+		s := newTestSubscription(t, db, now)
+
+		retried := newTestDelivery(t, db, s.ID, now)
+		fresh := newTestDelivery(t, db, s.ID, now.Add(30*time.Second))
+		succeeded := newTestDelivery(t, db, s.ID, now)
+
+		retried.Failed(now, 500, "webhook responded with 500", policy)
+		assert.NoError(t, repo.Update(context.Background(), retried))
+
+		succeeded.Succeeded(now, 200)
+		assert.NoError(t, repo.Update(context.Background(), succeeded))
+
+		stored, err := repo.Get(context.Background(), s.ID, retried.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, retried, stored)
+
+		leaseUntil := now.Add(6 * time.Minute)
+
+		due, err := repo.Claim(context.Background(), now.Add(time.Minute), leaseUntil, 1)
+		assert.NoError(t, err)
+		assert.Len(t, due, 1)
+		assert.Equal(t, fresh.ID, due[0].ID)
+		assert.Equal(t, leaseUntil, *due[0].NextAttemptAt)
+
+		// the claimed delivery is not due again until the lease ends
+		due, err = repo.Claim(context.Background(), now.Add(time.Minute), leaseUntil, 10)
+		assert.NoError(t, err)
+		assert.Len(t, due, 1)
+		assert.Equal(t, retried.ID, due[0].ID)
+
+		due, err = repo.Claim(context.Background(), now.Add(5*time.Minute), leaseUntil, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, due)
+
+		due, err = repo.Claim(context.Background(), leaseUntil, leaseUntil.Add(5*time.Minute), 10)
+		assert.NoError(t, err)
+		assert.Len(t, due, 2)
+	})
+
+	t.Run("unknown delivery", func(t *testing.T) {
+		db := NewDatabase()
+		s := newTestSubscription(t, db, now)
+		d := newTestDelivery(t, db, s.ID, now)
+
+		_, err := NewDeliveryRepository(db).Get(context.Background(), domain.NewID(), d.ID)
+		assert.ErrorIs(t, err, webhook.ErrDeliveryNotFound)
+	})
+
+	t.Run("delivery of unknown subscription", func(t *testing.T) {
+		e, err := event.New(domain.NewID(), event.UserDeleted{}, now)
+		assert.NoError(t, err)
+
+		err = NewDeliveryRepository(NewDatabase()).Create(context.Background(), webhook.NewDelivery(domain.NewID(), e, now))
+		assert.ErrorIs(t, err, webhook.ErrSubscriptionNotFound)
+	})
+
+	t.Run("list newest first", func(t *testing.T) {
+		db := NewDatabase()
+		repo := NewDeliveryRepository(db)
+		s := newTestSubscription(t, db, now)
+		other := newTestSubscription(t, db, now)
+
+		first := newTestDelivery(t, db, s.ID, now)
+		newTestDelivery(t, db, other.ID, now)
+		second := newTestDelivery(t, db, s.ID, now)
+		third := newTestDelivery(t, db, s.ID, now)
+
+		first.Failed(now, 0, "connection refused", policy)
+		first.Failed(now, 0, "connection refused", policy)
+		assert.Equal(t, webhook.StatusDead, first.Status)
+		assert.NoError(t, repo.Update(context.Background(), first))
+
+		page, err := repo.List(context.Background(), &webhook.DeliveryQuery{SubscriptionID: s.ID, Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []*webhook.Delivery{third, second}, page.Deliveries)
+		assert.NotNil(t, page.NextCursor)
+
+		page, err = repo.List(context.Background(), &webhook.DeliveryQuery{SubscriptionID: s.ID, Cursor: page.NextCursor, Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []*webhook.Delivery{first}, page.Deliveries)
+		assert.Nil(t, page.NextCursor)
+
+		page, err = repo.List(context.Background(), &webhook.DeliveryQuery{SubscriptionID: s.ID, Status: webhook.StatusDead, Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []*webhook.Delivery{first}, page.Deliveries)
+	})
+}
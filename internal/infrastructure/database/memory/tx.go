@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+type txKey struct{}
+
+// unitOfWork holds the database lock for the whole transaction and restores a snapshot taken at its start on failure
+type unitOfWork struct {
+	db *Database
+}
+
+var _ domain.UnitOfWork = (*unitOfWork)(nil)
+
+func NewUnitOfWork(db *Database) *unitOfWork {
+	return &unitOfWork{
+		db: db,
+	}
+}
+
+func (u *unitOfWork) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if u.db.inTx(ctx) {
+		return fn(ctx)
+	}
+
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	s := u.db.snapshot()
+
+	defer func() {
+		if p := recover(); p != nil {
+			u.db.restore(s)
+			panic(p)
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txKey{}, u.db)); err != nil {
+		u.db.restore(s)
+	}
+
+	return err
+}
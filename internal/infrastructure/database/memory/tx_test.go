@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+func TestWithinTx(t *testing.T) {
+	t.Run("commit", func(t *testing.T) {
+		db := NewDatabase()
+		repo := NewUserRepository(db)
+		u := newTestUser("test@example.com")
+
+		err := NewUnitOfWork(db).WithinTx(context.Background(), func(ctx context.Context) error {
+			return repo.Create(ctx, u, time.Now())
+		})
+		assert.NoError(t, err)
+
+		_, err = repo.GetByUUID(context.Background(), u.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		db := NewDatabase()
+		repo := NewUserRepository(db)
+		u := newTestUser("test@example.com")
+		assert.NoError(t, repo.Create(context.Background(), u, time.Now()))
+
+		created := newTestUser("created@example.com")
+		failure := errors.New("failure")
+
+		err := NewUnitOfWork(db).WithinTx(context.Background(), func(ctx context.Context) error {
+			if err := repo.UpdateBasicFields(ctx, u.ID, []user.Change{user.Set(user.FieldFirstName, "Changed")}); err != nil {
+				return err
+			}
+
+			if err := repo.Create(ctx, created, time.Now()); err != nil {
+				return err
+			}
+
+			// reads within the transaction see its changes
+			result, err := repo.GetByUUID(ctx, u.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, "Changed", result.FirstName)
+
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+
+		result, err := repo.GetByUUID(context.Background(), u.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Test", result.FirstName)
+
+		_, err = repo.GetByUUID(context.Background(), created.ID)
+		assert.ErrorIs(t, err, user.ErrNotFound)
+
+		// the email of the rolled back user is free again
+		assert.NoError(t, repo.Create(context.Background(), newTestUser("created@example.com"), time.Now()))
+	})
+
+	t.Run("nested call joins the transaction", func(t *testing.T) {
+		db := NewDatabase()
+		repo := NewUserRepository(db)
+		uow := NewUnitOfWork(db)
+		u := newTestUser("test@example.com")
+
+		err := uow.WithinTx(context.Background(), func(ctx context.Context) error {
+			if err := uow.WithinTx(ctx, func(ctx context.Context) error {
+				return repo.Create(ctx, u, time.Now())
+			}); err != nil {
+				return err
+			}
+
+			return repo.Delete(ctx, domain.NewID())
+		})
+		assert.ErrorIs(t, err, user.ErrNotFound)
+
+		_, err = repo.GetByUUID(context.Background(), u.ID)
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+}
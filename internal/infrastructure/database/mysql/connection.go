@@ -44,6 +44,20 @@ func GetConnections(c config.Provider) (*Connections, error) {
 		return nil, fmt.Errorf("failed to initialize read DB: %w", err)
 	}
 
+	writeDB, err := GetWriteConnection(c)
+	if err != nil {
+		readDB.Close()
+		return nil, err
+	}
+
+	return &Connections{
+		Read:  readDB,
+		Write: writeDB,
+	}, nil
+}
+
+// GetWriteConnection initializes a single connection pool to the primary database, e.g. for schema migrations.
+func GetWriteConnection(c config.Provider) (*sql.DB, error) {
 	writeDB, err := initConn(dbConfig{
 		User:            c.GetString("DB_WRITE_USER"),
 		Password:        c.GetString("DB_WRITE_PASSWORD"),
@@ -59,10 +73,7 @@ func GetConnections(c config.Provider) (*Connections, error) {
 		return nil, fmt.Errorf("failed to initialize write DB: %w", err)
 	}
 
-	return &Connections{
-		Read:  readDB,
-		Write: writeDB,
-	}, nil
+	return writeDB, nil
 }
 
 func initConn(c dbConfig) (*sql.DB, error) {
@@ -5,12 +5,18 @@ import (
 )
 
 type DbUser struct {
-	ID          null.Int    `db:"id" json:"id"`
-	UUID        null.String `db:"uuid" json:"uuid"`
-	Email       null.String `db:"email" json:"email"`
-	FirstName   null.String `db:"first_name" json:"first_name"`
-	LastName    null.String `db:"last_name" json:"last_name"`
-	PhoneNumber null.String `db:"phone_number" json:"phone_number"`
+	ID            null.Int    `db:"id" json:"id"`
+	UUID          null.String `db:"uuid" json:"uuid"`
+	Email         null.String `db:"email" json:"email"`
+	EmailVerified null.Bool   `db:"email_verified" json:"email_verified"`
+	MFAEnabled    null.Bool   `db:"mfa_enabled" json:"mfa_enabled"`
+	FirstName     null.String `db:"first_name" json:"first_name"`
+	LastName      null.String `db:"last_name" json:"last_name"`
+	PhoneNumber   null.String `db:"phone_number" json:"phone_number"`
+	Role          null.String `db:"role" json:"role"`
+	CreatedAt     null.Time   `db:"created_at" json:"created_at"`
+	Version       null.Int    `db:"version" json:"version"`
+	DeletedAt     null.Time   `db:"deleted_at" json:"deleted_at"`
 }
 
 type DbAddress struct {
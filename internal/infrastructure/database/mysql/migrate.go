@@ -0,0 +1,310 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// ErrDirty means a previous migration failed halfway and the schema has to be fixed manually before forcing a version
+var ErrDirty = errors.New("database is dirty")
+
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version    uint64
+	Dirty      bool
+	Migrations []*Migration
+}
+
+// Migrator applies the embedded SQL migrations.
+// It keeps the state in the same schema_migrations table as golang-migrate, so both tools can be used interchangeably.
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationsFS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies all pending migrations and returns the applied ones
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	version, err := m.currentVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []*Migration
+
+	for _, migration := range m.migrations {
+		if migration.Version <= version {
+			continue
+		}
+
+		if err = m.run(ctx, migration.Up, migration.Version, migration.Version); err != nil {
+			return applied, fmt.Errorf("failed applying migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+// Down reverts the given number of applied migrations, all of them if steps is not positive
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	version, err := m.currentVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []*Migration
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version > version {
+			continue
+		}
+
+		if steps > 0 && len(reverted) == steps {
+			break
+		}
+
+		var previous uint64
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+
+		if err = m.run(ctx, migration.Down, migration.Version, previous); err != nil {
+			return reverted, fmt.Errorf("failed reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		reverted = append(reverted, migration)
+	}
+
+	return reverted, nil
+}
+
+// Status returns the current schema version together with all known migrations
+func (m *Migrator) Status(ctx context.Context) (*MigrationStatus, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	version, dirty, err := m.readVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MigrationStatus{
+		Version:    version,
+		Dirty:      dirty,
+		Migrations: m.migrations,
+	}, nil
+}
+
+// Force sets the schema version and clears the dirty flag without running any migration
+func (m *Migrator) Force(ctx context.Context, version uint64) error {
+	if err := m.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	return m.setVersion(ctx, version, false)
+}
+
+func (m *Migrator) currentVersion(ctx context.Context) (uint64, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+
+	version, dirty, err := m.readVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d, fix the schema and force a version", ErrDirty, version)
+	}
+
+	return version, nil
+}
+
+// run executes a migration script, the version stays dirty if any of the statements fail as MySQL DDLs are not transactional
+func (m *Migrator) run(ctx context.Context, script string, dirtyVersion, targetVersion uint64) error {
+	if err := m.setVersion(ctx, dirtyVersion, true); err != nil {
+		return err
+	}
+
+	for _, statement := range splitStatements(script) {
+		if _, err := m.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return m.setVersion(ctx, targetVersion, false)
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	query := "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)"
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed creating schema_migrations table: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Migrator) readVersion(ctx context.Context) (uint64, bool, error) {
+	var (
+		version uint64
+		dirty   bool
+	)
+
+	err := m.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, fmt.Errorf("failed reading schema version: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+func (m *Migrator) setVersion(ctx context.Context, version uint64, dirty bool) error {
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return fmt.Errorf("failed resetting schema version: %w", err)
+	}
+
+	// no row means no migration applied, the same as golang-migrate does
+	if version > 0 {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)", version, dirty); err != nil {
+			return fmt.Errorf("failed setting schema version: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func loadMigrations(fsys fs.FS) ([]*Migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*Migration)
+
+	for _, file := range files {
+		name := strings.TrimPrefix(file, "migrations/")
+
+		matches := migrationFileRegexp.FindStringSubmatch(name)
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", name)
+		}
+
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %s", name)
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		}
+
+		if matches[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// splitStatements splits a script on semicolons outside of quotes, so it can run without the multiStatements DSN option
+func splitStatements(script string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		quote      rune
+	)
+
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for _, r := range script {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == ';':
+			flush()
+			continue
+		}
+
+		current.WriteRune(r)
+	}
+
+	flush()
+
+	return statements
+}
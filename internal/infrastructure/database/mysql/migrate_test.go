@@ -0,0 +1,80 @@
+package mysql
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("embedded migrations", func(t *testing.T) {
+		migrations, err := loadMigrations(migrationsFS)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, migrations)
+
+		for i, migration := range migrations {
+			assert.Equal(t, uint64(i+1), migration.Version, "migration versions must be consecutive")
+			assert.NotEmpty(t, migration.Up)
+			assert.NotEmpty(t, migration.Down)
+		}
+	})
+
+	t.Run("sorted by version", func(t *testing.T) {
+		migrations, err := loadMigrations(fstest.MapFS{
+			"migrations/000010_b.up.sql":   {Data: []byte("b")},
+			"migrations/000010_b.down.sql": {Data: []byte("b")},
+			"migrations/000002_a.up.sql":   {Data: []byte("a")},
+			"migrations/000002_a.down.sql": {Data: []byte("a")},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, migrations, 2)
+		assert.Equal(t, uint64(2), migrations[0].Version)
+		assert.Equal(t, "a", migrations[0].Name)
+		assert.Equal(t, uint64(10), migrations[1].Version)
+	})
+
+	t.Run("missing down migration", func(t *testing.T) {
+		_, err := loadMigrations(fstest.MapFS{
+			"migrations/000001_a.up.sql": {Data: []byte("a")},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid file name", func(t *testing.T) {
+		_, err := loadMigrations(fstest.MapFS{
+			"migrations/create_users.sql": {Data: []byte("a")},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected []string
+	}{
+		{
+			name:     "single statement without semicolon",
+			script:   "DROP TABLE users",
+			expected: []string{"DROP TABLE users"},
+		},
+		{
+			name:     "multiple statements and empty ones",
+			script:   "ALTER TABLE a ADD COLUMN b INT;\n\nCREATE TABLE c (id INT);\n;\n",
+			expected: []string{"ALTER TABLE a ADD COLUMN b INT", "CREATE TABLE c (id INT)"},
+		},
+		{
+			name:     "semicolons in quotes",
+			script:   "INSERT INTO a VALUES ('x;y', \"z;\");UPDATE `a;b` SET c = 1;",
+			expected: []string{"INSERT INTO a VALUES ('x;y', \"z;\")", "UPDATE `a;b` SET c = 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, splitStatements(tt.script))
+		})
+	}
+}
@@ -0,0 +1,4 @@
+DROP INDEX idx_addresses_country_city ON addresses;
+DROP INDEX idx_users_created_at ON users;
+DROP INDEX idx_users_first_name ON users;
+DROP INDEX idx_users_last_name ON users;
@@ -0,0 +1,4 @@
+CREATE INDEX idx_users_last_name ON users (last_name);
+CREATE INDEX idx_users_first_name ON users (first_name);
+CREATE INDEX idx_users_created_at ON users (created_at);
+CREATE INDEX idx_addresses_country_city ON addresses (country, city);
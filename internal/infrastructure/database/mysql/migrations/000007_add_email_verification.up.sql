@@ -0,0 +1,15 @@
+ALTER TABLE users
+ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE verification_tokens (
+   id BIGINT AUTO_INCREMENT PRIMARY KEY,
+   user_id BIGINT NOT NULL,
+   purpose VARCHAR(32) NOT NULL,
+   token_hash CHAR(64) NOT NULL UNIQUE,
+   email VARCHAR(255) NOT NULL,
+   expires_at DATETIME NOT NULL,
+   used_at DATETIME NULL DEFAULT NULL,
+   created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   INDEX idx_verification_tokens_user_purpose (user_id, purpose),
+   FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+CREATE TABLE login_attempts (
+   id BIGINT AUTO_INCREMENT PRIMARY KEY,
+   kind VARCHAR(16) NOT NULL,
+   value VARCHAR(64) NOT NULL,
+   failures INT NOT NULL DEFAULT 0,
+   last_failure_at DATETIME NULL DEFAULT NULL,
+   locked_until DATETIME NULL DEFAULT NULL,
+   UNIQUE INDEX idx_login_attempts_subject (kind, value)
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS mfa_factors;
+
+ALTER TABLE users
+DROP COLUMN mfa_enabled;
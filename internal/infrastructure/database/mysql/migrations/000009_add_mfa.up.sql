@@ -0,0 +1,21 @@
+ALTER TABLE users
+ADD COLUMN mfa_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE mfa_factors (
+   id BIGINT AUTO_INCREMENT PRIMARY KEY,
+   user_id BIGINT NOT NULL UNIQUE,
+   secret VARCHAR(255) NOT NULL,
+   confirmed_at DATETIME NULL DEFAULT NULL,
+   last_used_step BIGINT NOT NULL DEFAULT 0,
+   created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE mfa_recovery_codes (
+   id BIGINT AUTO_INCREMENT PRIMARY KEY,
+   user_id BIGINT NOT NULL,
+   code_hash CHAR(64) NOT NULL,
+   used_at DATETIME NULL DEFAULT NULL,
+   INDEX idx_mfa_recovery_codes_user (user_id),
+   FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,13 @@
+CREATE TABLE refresh_tokens (
+   id BIGINT AUTO_INCREMENT PRIMARY KEY,
+   user_id BIGINT NOT NULL,
+   family_id CHAR(36) NOT NULL,
+   token_hash CHAR(64) NOT NULL UNIQUE,
+   expires_at DATETIME NOT NULL,
+   used_at DATETIME NULL DEFAULT NULL,
+   revoked_at DATETIME NULL DEFAULT NULL,
+   created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   INDEX idx_refresh_tokens_family (family_id),
+   INDEX idx_refresh_tokens_user (user_id),
+   FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,34 @@
+CREATE TABLE oauth_clients (
+   id BIGINT AUTO_INCREMENT PRIMARY KEY,
+   client_id CHAR(36) NOT NULL UNIQUE,
+   name VARCHAR(255) NOT NULL,
+   secret_hash CHAR(64) NULL DEFAULT NULL,
+   redirect_uris TEXT NOT NULL,
+   created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE oauth_authorization_codes (
+   id BIGINT AUTO_INCREMENT PRIMARY KEY,
+   user_id BIGINT NOT NULL,
+   client_id CHAR(36) NOT NULL,
+   code_hash CHAR(64) NOT NULL UNIQUE,
+   redirect_uri TEXT NOT NULL,
+   scope VARCHAR(255) NOT NULL,
+   nonce VARCHAR(255) NOT NULL DEFAULT '',
+   code_challenge VARCHAR(128) NOT NULL,
+   expires_at DATETIME NOT NULL,
+   used_at DATETIME NULL DEFAULT NULL,
+   created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   INDEX idx_oauth_authorization_codes_client (client_id),
+   FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE oauth_signing_keys (
+   id BIGINT AUTO_INCREMENT PRIMARY KEY,
+   key_id CHAR(36) NOT NULL UNIQUE,
+   algorithm VARCHAR(16) NOT NULL,
+   private_key TEXT NOT NULL,
+   public_key BLOB NOT NULL,
+   created_at DATETIME NOT NULL,
+   INDEX idx_oauth_signing_keys_created_at (created_at)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE audit_log (
+   id BIGINT AUTO_INCREMENT PRIMARY KEY,
+   user_uuid CHAR(36) NOT NULL,
+   actor_type VARCHAR(16) NOT NULL,
+   actor_id VARCHAR(255) NOT NULL DEFAULT '',
+   action VARCHAR(32) NOT NULL,
+   changes TEXT NOT NULL,
+   request_id VARCHAR(128) NULL DEFAULT NULL,
+   client_ip VARCHAR(45) NULL DEFAULT NULL,
+   created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   INDEX idx_audit_log_user (user_uuid, id),
+   INDEX idx_audit_log_actor (actor_id, id),
+   INDEX idx_audit_log_created_at (created_at)
+);
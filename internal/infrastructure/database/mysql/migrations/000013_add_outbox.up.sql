@@ -0,0 +1,11 @@
+CREATE TABLE outbox_events (
+   id BIGINT AUTO_INCREMENT PRIMARY KEY,
+   event_id CHAR(36) NOT NULL UNIQUE,
+   type VARCHAR(32) NOT NULL,
+   user_uuid CHAR(36) NOT NULL,
+   payload TEXT NOT NULL,
+   occurred_at DATETIME NOT NULL,
+   published_at DATETIME NULL DEFAULT NULL,
+   created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   INDEX idx_outbox_events_published_at (published_at, id)
+);
@@ -0,0 +1,30 @@
+CREATE TABLE webhook_subscriptions (
+   id BIGINT AUTO_INCREMENT PRIMARY KEY,
+   uuid CHAR(36) NOT NULL UNIQUE,
+   url VARCHAR(2048) NOT NULL,
+   event_types TEXT NOT NULL,
+   secret TEXT NOT NULL,
+   created_at DATETIME NOT NULL,
+   updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+   id BIGINT AUTO_INCREMENT PRIMARY KEY,
+   uuid CHAR(36) NOT NULL UNIQUE,
+   subscription_id BIGINT NOT NULL,
+   event_id CHAR(36) NOT NULL,
+   event_type VARCHAR(32) NOT NULL,
+   user_uuid CHAR(36) NOT NULL,
+   payload TEXT NOT NULL,
+   occurred_at DATETIME NOT NULL,
+   status VARCHAR(16) NOT NULL,
+   attempts INT NOT NULL DEFAULT 0,
+   next_attempt_at DATETIME NULL DEFAULT NULL,
+   last_attempt_at DATETIME NULL DEFAULT NULL,
+   last_status_code INT NULL DEFAULT NULL,
+   last_error VARCHAR(1024) NULL DEFAULT NULL,
+   created_at DATETIME NOT NULL,
+   INDEX idx_webhook_deliveries_due (status, next_attempt_at),
+   INDEX idx_webhook_deliveries_subscription (subscription_id, id),
+   FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id) ON DELETE CASCADE
+);
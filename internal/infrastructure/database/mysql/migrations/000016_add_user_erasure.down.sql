@@ -0,0 +1,5 @@
+DROP INDEX idx_webhook_deliveries_user ON webhook_deliveries;
+DROP INDEX idx_outbox_events_user ON outbox_events;
+
+ALTER TABLE users
+DROP COLUMN erased_at;
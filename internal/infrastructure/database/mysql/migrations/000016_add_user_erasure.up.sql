@@ -0,0 +1,5 @@
+ALTER TABLE users
+ADD COLUMN erased_at DATETIME NULL DEFAULT NULL;
+
+CREATE INDEX idx_outbox_events_user ON outbox_events (user_uuid);
+CREATE INDEX idx_webhook_deliveries_user ON webhook_deliveries (user_uuid);
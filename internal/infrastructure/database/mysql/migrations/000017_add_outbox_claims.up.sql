@@ -0,0 +1,2 @@
+ALTER TABLE outbox_events
+ADD COLUMN claimed_until DATETIME NULL DEFAULT NULL;
@@ -0,0 +1,239 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/database/mysql/entity"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+// sortColumns whitelists the columns users can be ordered by, nothing coming from a request ends up in the query text
+var sortColumns = map[user.SortField]string{
+	user.SortByEmail:     "email",
+	user.SortByFirstName: "first_name",
+	user.SortByLastName:  "last_name",
+	user.SortByCreatedAt: "created_at",
+}
+
+// userColumns and addressColumns whitelist the changeable columns
+var (
+	userColumns = map[user.Field]string{
+		user.FieldPassword:    "password",
+		user.FieldFirstName:   "first_name",
+		user.FieldLastName:    "last_name",
+		user.FieldPhoneNumber: "phone_number",
+		user.FieldRole:        "role",
+	}
+	addressColumns = map[user.Field]string{
+		user.FieldStreet:     "street",
+		user.FieldCity:       "city",
+		user.FieldState:      "state",
+		user.FieldPostalCode: "postal_code",
+		user.FieldCountry:    "country",
+	}
+)
+
+// buildSet returns the SET clause with placeholders and its arguments, cleared fields become NULL
+func buildSet(changes []user.Change, columns map[user.Field]string) (string, []any, error) {
+	assignments := make([]string, 0, len(changes))
+	args := make([]any, 0, len(changes))
+
+	for _, c := range changes {
+		column, ok := columns[c.Field]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown column %s", c.Field)
+		}
+
+		assignments = append(assignments, column+" = ?")
+		if c.Clear {
+			args = append(args, nil)
+		} else {
+			args = append(args, c.Value)
+		}
+	}
+
+	return strings.Join(assignments, ", "), args, nil
+}
+
+// buildUserFilter returns the WHERE clause with placeholders and its arguments
+func buildUserFilter(f user.Filter, includeDeleted bool) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+
+	if !includeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	if f.Email != "" {
+		conditions = append(conditions, "email = ?")
+		args = append(args, f.Email)
+	}
+
+	if f.EmailPrefix != "" {
+		conditions = append(conditions, "email LIKE ?")
+		args = append(args, escapeLike(f.EmailPrefix)+"%")
+	}
+
+	if f.FirstName != "" {
+		conditions = append(conditions, "first_name LIKE ?")
+		args = append(args, "%"+escapeLike(f.FirstName)+"%")
+	}
+
+	if f.LastName != "" {
+		conditions = append(conditions, "last_name LIKE ?")
+		args = append(args, "%"+escapeLike(f.LastName)+"%")
+	}
+
+	if f.PhoneNumber != "" {
+		conditions = append(conditions, "phone_number = ?")
+		args = append(args, f.PhoneNumber)
+	}
+
+	if f.HasAddressConditions() {
+		addressConditions := []string{"a.user_id = users.id", "a.deleted_at IS NULL"}
+		if includeDeleted {
+			// addresses of a deleted user are deleted at the same time as the user
+			addressConditions[1] = "(a.deleted_at IS NULL OR a.deleted_at = users.deleted_at)"
+		}
+
+		if f.Country != "" {
+			addressConditions = append(addressConditions, "a.country = ?")
+			args = append(args, f.Country)
+		}
+
+		if f.City != "" {
+			addressConditions = append(addressConditions, "a.city = ?")
+			args = append(args, f.City)
+		}
+
+		if f.AddressType != nil {
+			addressConditions = append(addressConditions, "a.type = ?")
+			args = append(args, int(*f.AddressType))
+		}
+
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM addresses a WHERE "+strings.Join(addressConditions, " AND ")+")")
+	}
+
+	if len(conditions) == 0 {
+		return "TRUE", args
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// buildUserOrder returns the ORDER BY clause, id is always the last column to keep pages stable
+func buildUserOrder(sorts []user.Sort) string {
+	columns := make([]string, 0, len(sorts)+1)
+
+	for _, s := range sorts {
+		column, ok := sortColumns[s.Field]
+		if !ok {
+			continue
+		}
+
+		if s.Desc {
+			column += " DESC"
+		}
+
+		columns = append(columns, column)
+	}
+
+	return strings.Join(append(columns, "id"), ", ")
+}
+
+// buildUserSeek returns the condition selecting users placed after the cursor in the given order,
+// e.g. for "last_name, -created_at": (last_name > ?) OR (last_name = ? AND created_at < ?) OR (last_name = ? AND created_at = ? AND id > ?)
+func buildUserSeek(sorts []user.Sort, c *user.Cursor) (string, []any, error) {
+	if !c.Matches(sorts) {
+		return "", nil, user.ErrInvalidCursor
+	}
+
+	columns := make([]string, 0, len(sorts)+1)
+	values := make([]any, 0, len(sorts)+1)
+
+	for i, s := range sorts {
+		column, ok := sortColumns[s.Field]
+		if !ok {
+			return "", nil, user.ErrInvalidCursor
+		}
+
+		var value any = c.Values[i]
+		if s.Field == user.SortByCreatedAt {
+			createdAt, err := time.Parse(time.RFC3339Nano, c.Values[i])
+			if err != nil {
+				return "", nil, user.ErrInvalidCursor
+			}
+			value = createdAt
+		}
+
+		columns = append(columns, column)
+		values = append(values, value)
+	}
+
+	columns = append(columns, "id")
+	values = append(values, c.ID)
+
+	var (
+		alternatives []string
+		args         []any
+	)
+
+	for i := range columns {
+		conditions := make([]string, 0, i+1)
+
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, columns[j]+" = ?")
+			args = append(args, values[j])
+		}
+
+		operator := " > ?"
+		if i < len(sorts) && sorts[i].Desc {
+			operator = " < ?"
+		}
+
+		conditions = append(conditions, columns[i]+operator)
+		args = append(args, values[i])
+
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", args, nil
+}
+
+// userCursor points at the given user in the given order
+func userCursor(sorts []user.Sort, u entity.DbUser) *user.Cursor {
+	c := &user.Cursor{
+		Sort:   sorts,
+		Values: make([]string, 0, len(sorts)),
+		ID:     u.ID.Int64,
+	}
+
+	for _, s := range sorts {
+		var value string
+
+		switch s.Field {
+		case user.SortByEmail:
+			value = u.Email.String
+		case user.SortByFirstName:
+			value = u.FirstName.String
+		case user.SortByLastName:
+			value = u.LastName.String
+		case user.SortByCreatedAt:
+			value = u.CreatedAt.Time.Format(time.RFC3339Nano)
+		}
+
+		c.Values = append(c.Values, value)
+	}
+
+	return c
+}
+
+// escapeLike escapes the LIKE wildcards, so they are matched literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,99 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+func TestBuildSet(t *testing.T) {
+	t.Run("set and clear", func(t *testing.T) {
+		set, args, err := buildSet([]user.Change{
+			user.Set(user.FieldFirstName, "John"),
+			user.Clear(user.FieldPhoneNumber),
+		}, userColumns)
+		assert.NoError(t, err)
+		assert.Equal(t, "first_name = ?, phone_number = ?", set)
+		assert.Equal(t, []any{"John", nil}, args)
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		_, _, err := buildSet([]user.Change{user.Set(user.FieldCity, "Warsaw")}, userColumns)
+		assert.Error(t, err)
+	})
+}
+
+func TestBuildUserFilter(t *testing.T) {
+	t.Run("no filter", func(t *testing.T) {
+		where, args := buildUserFilter(user.Filter{}, false)
+		assert.Equal(t, "deleted_at IS NULL", where)
+		assert.Empty(t, args)
+	})
+
+	t.Run("wildcards are escaped", func(t *testing.T) {
+		where, args := buildUserFilter(user.Filter{EmailPrefix: "john_%", LastName: `a\b`}, false)
+		assert.Equal(t, "deleted_at IS NULL AND email LIKE ? AND last_name LIKE ?", where)
+		assert.Equal(t, []any{`john\_\%%`, `%a\\b%`}, args)
+	})
+
+	t.Run("address conditions", func(t *testing.T) {
+		addrType := user.HomeAddress
+
+		where, args := buildUserFilter(user.Filter{Country: "USA", AddressType: &addrType}, false)
+		assert.Equal(t, "deleted_at IS NULL AND EXISTS (SELECT 1 FROM addresses a WHERE a.user_id = users.id AND a.deleted_at IS NULL AND a.country = ? AND a.type = ?)", where)
+		assert.Equal(t, []any{"USA", 1}, args)
+	})
+	t.Run("including deleted users", func(t *testing.T) {
+		where, args := buildUserFilter(user.Filter{}, true)
+		assert.Equal(t, "TRUE", where)
+		assert.Empty(t, args)
+
+		where, args = buildUserFilter(user.Filter{City: "Warsaw"}, true)
+		assert.Equal(t, "EXISTS (SELECT 1 FROM addresses a WHERE a.user_id = users.id AND (a.deleted_at IS NULL OR a.deleted_at = users.deleted_at) AND a.city = ?)", where)
+		assert.Equal(t, []any{"Warsaw"}, args)
+	})
+}
+
+func TestBuildUserOrder(t *testing.T) {
+	assert.Equal(t, "id", buildUserOrder(nil))
+	assert.Equal(t, "last_name, created_at DESC, id", buildUserOrder([]user.Sort{
+		{Field: user.SortByLastName},
+		{Field: user.SortByCreatedAt, Desc: true},
+	}))
+}
+
+func TestBuildUserSeek(t *testing.T) {
+	t.Run("default order", func(t *testing.T) {
+		sorts := []user.Sort{{Field: user.SortByCreatedAt}}
+		cursor := &user.Cursor{Sort: sorts, Values: []string{"2024-01-02T03:04:05Z"}, ID: 7}
+
+		seek, args, err := buildUserSeek(sorts, cursor)
+		assert.NoError(t, err)
+		assert.Equal(t, "((created_at > ?) OR (created_at = ? AND id > ?))", seek)
+		createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		assert.Equal(t, []any{createdAt, createdAt, int64(7)}, args)
+	})
+
+	t.Run("mixed directions", func(t *testing.T) {
+		sorts := []user.Sort{{Field: user.SortByLastName}, {Field: user.SortByEmail, Desc: true}}
+		cursor := &user.Cursor{Sort: sorts, Values: []string{"Smith", "john@example.com"}, ID: 7}
+
+		seek, args, err := buildUserSeek(sorts, cursor)
+		assert.NoError(t, err)
+		assert.Equal(t, "((last_name > ?) OR (last_name = ? AND email < ?) OR (last_name = ? AND email = ? AND id > ?))", seek)
+		assert.Equal(t, []any{"Smith", "Smith", "john@example.com", "Smith", "john@example.com", int64(7)}, args)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		sorts := []user.Sort{{Field: user.SortByCreatedAt}}
+
+		_, _, err := buildUserSeek(sorts, &user.Cursor{Sort: sorts, Values: []string{"yesterday"}, ID: 7})
+		assert.ErrorIs(t, err, user.ErrInvalidCursor)
+
+		_, _, err = buildUserSeek(sorts, &user.Cursor{Sort: []user.Sort{{Field: user.SortByEmail}}, Values: []string{"a"}, ID: 7})
+		assert.ErrorIs(t, err, user.ErrInvalidCursor)
+	})
+}
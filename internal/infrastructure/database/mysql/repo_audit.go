@@ -0,0 +1,211 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+)
+
+type auditRepository struct {
+	dbRead  *sql.DB
+	dbWrite *sql.DB
+}
+
+var _ audit.Repository = (*auditRepository)(nil)
+
+// NewAuditRepository keeps the entries apart from the users table, referencing users by UUID,
+// so the entries outlive the users
+func NewAuditRepository(dbRead, dbWrite *sql.DB) *auditRepository {
+	return &auditRepository{
+		dbRead:  dbRead,
+		dbWrite: dbWrite,
+	}
+}
+
+func (r *auditRepository) Create(ctx context.Context, e *audit.Entry) error {
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return fmt.Errorf("failed encoding audit changes: %w", err)
+	}
+
+	query := `
+		INSERT INTO audit_log (user_uuid, actor_type, actor_id, action, changes, request_id, client_ip, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := conn(ctx, r.dbWrite).ExecContext(ctx, query,
+		e.UserID.String(),
+		e.Actor.Type,
+		e.Actor.ID,
+		e.Action,
+		changes,
+		sql.NullString{String: e.RequestID, Valid: e.RequestID != ""},
+		sql.NullString{String: e.ClientIP, Valid: e.ClientIP != ""},
+		e.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed creating audit entry: %w", err)
+	}
+
+	if e.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed reading audit entry id: %w", err)
+	}
+
+	return nil
+}
+
+func (r *auditRepository) List(ctx context.Context, q *audit.Query) (*audit.Page, error) {
+	where, args := buildAuditFilter(q.Filter)
+
+	if q.Cursor != nil {
+		where = append(where, "id < ?")
+		args = append(args, q.Cursor.ID)
+	}
+
+	// one entry more than requested tells whether there is a next page
+	query := "SELECT id, user_uuid, actor_type, actor_id, action, changes, request_id, client_ip, created_at FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+
+	args = append(args, q.Limit+1)
+
+	rows, err := conn(ctx, r.dbRead).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed querying audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	page := &audit.Page{}
+
+	for rows.Next() {
+		var (
+			e         audit.Entry
+			userUUID  string
+			changes   []byte
+			requestID sql.NullString
+			clientIP  sql.NullString
+		)
+
+		err = rows.Scan(&e.ID, &userUUID, &e.Actor.Type, &e.Actor.ID, &e.Action, &changes, &requestID, &clientIP, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed scanning audit entry: %w", err)
+		}
+
+		if e.UserID, err = domain.ParseID(userUUID); err != nil {
+			return nil, fmt.Errorf("failed parsing uuid: %w", err)
+		}
+
+		if err = json.Unmarshal(changes, &e.Changes); err != nil {
+			return nil, fmt.Errorf("failed decoding audit changes: %w", err)
+		}
+
+		e.RequestID = requestID.String
+		e.ClientIP = clientIP.String
+
+		page.Entries = append(page.Entries, &e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating audit entries: %w", err)
+	}
+
+	if len(page.Entries) > q.Limit {
+		page.Entries = page.Entries[:q.Limit]
+		page.NextCursor = &audit.Cursor{ID: page.Entries[len(page.Entries)-1].ID}
+	}
+
+	return page, nil
+}
+
+// Erase rewrites the changes entry by entry, as the values are stored within JSON documents
+func (r *auditRepository) Erase(ctx context.Context, userID domain.ID) error {
+	return withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		tx := conn(ctx, r.dbWrite)
+
+		rows, err := tx.QueryContext(ctx, "SELECT id, changes FROM audit_log WHERE user_uuid = ? FOR UPDATE", userID.String())
+		if err != nil {
+			return fmt.Errorf("failed querying audit entries: %w", err)
+		}
+		defer rows.Close()
+
+		erased := make(map[int64][]byte)
+
+		for rows.Next() {
+			var (
+				id      int64
+				changes []byte
+				decoded []audit.Change
+			)
+
+			if err = rows.Scan(&id, &changes); err != nil {
+				return fmt.Errorf("failed scanning audit entry: %w", err)
+			}
+
+			if err = json.Unmarshal(changes, &decoded); err != nil {
+				return fmt.Errorf("failed decoding audit changes: %w", err)
+			}
+
+			if erased[id], err = json.Marshal(audit.EraseChanges(decoded)); err != nil {
+				return fmt.Errorf("failed encoding audit changes: %w", err)
+			}
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("failed iterating audit entries: %w", err)
+		}
+
+		for id, changes := range erased {
+			if _, err = tx.ExecContext(ctx, "UPDATE audit_log SET changes = ? WHERE id = ?", changes, id); err != nil {
+				return fmt.Errorf("failed erasing audit changes: %w", err)
+			}
+		}
+
+		queryIPs := "UPDATE audit_log SET client_ip = NULL WHERE user_uuid = ? OR actor_id = ?"
+		if _, err = tx.ExecContext(ctx, queryIPs, userID.String(), userID.String()); err != nil {
+			return fmt.Errorf("failed erasing audit client ips: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func buildAuditFilter(f audit.Filter) ([]string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+
+	if !f.UserID.IsEmpty() {
+		where = append(where, "user_uuid = ?")
+		args = append(args, f.UserID.String())
+	}
+
+	if f.ActorID != "" {
+		where = append(where, "actor_id = ?")
+		args = append(args, f.ActorID)
+	}
+
+	if f.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, f.Action)
+	}
+
+	if f.From != nil {
+		where = append(where, "created_at >= ?")
+		args = append(args, *f.From)
+	}
+
+	if f.To != nil {
+		where = append(where, "created_at < ?")
+		args = append(args, *f.To)
+	}
+
+	return where, args
+}
@@ -0,0 +1,106 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+)
+
+type lockoutRepository struct {
+	dbWrite *sql.DB
+}
+
+var _ lockout.Repository = (*lockoutRepository)(nil)
+
+// NewLockoutRepository works on the write pool only, as a replica lagging behind would let attempts through a lock
+func NewLockoutRepository(dbWrite *sql.DB) *lockoutRepository {
+	return &lockoutRepository{
+		dbWrite: dbWrite,
+	}
+}
+
+func (r *lockoutRepository) Get(ctx context.Context, subject lockout.Subject) (*lockout.State, error) {
+	query := `
+		SELECT failures, last_failure_at, locked_until
+		FROM login_attempts
+		WHERE kind = ? AND value = ?
+	`
+
+	state := &lockout.State{Subject: subject}
+
+	var lastFailureAt, lockedUntil sql.NullTime
+
+	err := conn(ctx, r.dbWrite).QueryRowContext(ctx, query, subject.Kind, subject.Value).Scan(&state.Failures, &lastFailureAt, &lockedUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return state, nil
+		}
+
+		return nil, fmt.Errorf("failed querying login attempts: %w", err)
+	}
+
+	if lastFailureAt.Valid {
+		state.LastFailureAt = &lastFailureAt.Time
+	}
+
+	if lockedUntil.Valid {
+		state.LockedUntil = &lockedUntil.Time
+	}
+
+	return state, nil
+}
+
+func (r *lockoutRepository) RecordFailure(ctx context.Context, subject lockout.Subject, at time.Time, window time.Duration) (*lockout.State, error) {
+	var state *lockout.State
+
+	err := withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		// the failures are compared with the previous last_failure_at, as assignments are applied left to right
+		query := `
+			INSERT INTO login_attempts (kind, value, failures, last_failure_at)
+			VALUES (?, ?, 1, ?)
+			ON DUPLICATE KEY UPDATE
+				failures = IF(last_failure_at IS NULL OR last_failure_at < ?, 1, failures + 1),
+				last_failure_at = VALUES(last_failure_at)
+		`
+
+		if _, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, subject.Kind, subject.Value, at, at.Add(-window)); err != nil {
+			return fmt.Errorf("failed recording login failure: %w", err)
+		}
+
+		var err error
+		state, err = r.Get(ctx, subject)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (r *lockoutRepository) Lock(ctx context.Context, subject lockout.Subject, until time.Time) error {
+	query := `
+		INSERT INTO login_attempts (kind, value, locked_until)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE locked_until = VALUES(locked_until)
+	`
+
+	if _, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, subject.Kind, subject.Value, until); err != nil {
+		return fmt.Errorf("failed locking out: %w", err)
+	}
+
+	return nil
+}
+
+func (r *lockoutRepository) Reset(ctx context.Context, subject lockout.Subject) error {
+	if _, err := conn(ctx, r.dbWrite).ExecContext(ctx, "DELETE FROM login_attempts WHERE kind = ? AND value = ?", subject.Kind, subject.Value); err != nil {
+		return fmt.Errorf("failed resetting login attempts: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+type mfaRepository struct {
+	dbWrite *sql.DB
+}
+
+var _ mfa.Repository = (*mfaRepository)(nil)
+
+// NewMFARepository works on the write pool only, as a used code read from a lagging replica would be accepted again
+func NewMFARepository(dbWrite *sql.DB) *mfaRepository {
+	return &mfaRepository{
+		dbWrite: dbWrite,
+	}
+}
+
+func (r *mfaRepository) Get(ctx context.Context, userID domain.ID) (*mfa.Factor, error) {
+	query := `
+		SELECT f.secret, f.confirmed_at, f.last_used_step
+		FROM mfa_factors f
+		JOIN users u ON u.id = f.user_id AND u.deleted_at IS NULL
+		WHERE u.uuid = ?
+	`
+
+	f := &mfa.Factor{UserID: userID}
+
+	var confirmedAt sql.NullTime
+
+	err := conn(ctx, r.dbWrite).QueryRowContext(ctx, query, userID.String()).Scan(&f.EncryptedSecret, &confirmedAt, &f.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, mfa.ErrNotEnrolled
+		}
+
+		return nil, fmt.Errorf("failed querying mfa factor: %w", err)
+	}
+
+	if confirmedAt.Valid {
+		f.ConfirmedAt = &confirmedAt.Time
+	}
+
+	return f, nil
+}
+
+func (r *mfaRepository) Enroll(ctx context.Context, f *mfa.Factor, createdAt time.Time) error {
+	return withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		if err := r.delete(ctx, f.UserID); err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO mfa_factors (user_id, secret, created_at)
+			VALUES ((SELECT id FROM users WHERE uuid = ? AND deleted_at IS NULL), ?, ?)
+		`
+
+		_, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, f.UserID.String(), f.EncryptedSecret, createdAt)
+		if err != nil {
+			// the subquery yields NULL for a missing user, which the NOT NULL column rejects
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && mysqlErr.Number == columnCannotBeNull {
+				return user.ErrNotFound
+			}
+
+			return fmt.Errorf("failed enrolling mfa factor: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *mfaRepository) Confirm(ctx context.Context, userID domain.ID, step int64, confirmedAt time.Time, recoveryCodeHashes []string) error {
+	return withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		tx := conn(ctx, r.dbWrite)
+
+		query := `
+			UPDATE mfa_factors
+			SET confirmed_at = ?, last_used_step = ?
+			WHERE user_id = (SELECT id FROM users WHERE uuid = ? AND deleted_at IS NULL)
+		`
+
+		result, err := tx.ExecContext(ctx, query, confirmedAt, step, userID.String())
+		if err != nil {
+			return fmt.Errorf("failed confirming mfa factor: %w", err)
+		}
+
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return mfa.ErrNotEnrolled
+		}
+
+		if _, err = tx.ExecContext(ctx, "UPDATE users SET mfa_enabled = TRUE WHERE uuid = ?", userID.String()); err != nil {
+			return fmt.Errorf("failed enabling mfa: %w", err)
+		}
+
+		return r.replaceCodes(ctx, userID, recoveryCodeHashes)
+	})
+}
+
+func (r *mfaRepository) UseStep(ctx context.Context, userID domain.ID, step int64) error {
+	// the condition makes concurrent uses of one code accept only the first of them
+	query := `
+		UPDATE mfa_factors
+		SET last_used_step = ?
+		WHERE user_id = (SELECT id FROM users WHERE uuid = ? AND deleted_at IS NULL) AND last_used_step < ?
+	`
+
+	result, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, step, userID.String(), step)
+	if err != nil {
+		return fmt.Errorf("failed using mfa code: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return mfa.ErrInvalidCode
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID domain.ID, hash string, usedAt time.Time) error {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = ?
+		WHERE user_id = (SELECT id FROM users WHERE uuid = ? AND deleted_at IS NULL) AND code_hash = ? AND used_at IS NULL
+		LIMIT 1
+	`
+
+	result, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, usedAt, userID.String(), hash)
+	if err != nil {
+		return fmt.Errorf("failed using recovery code: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return mfa.ErrInvalidCode
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID domain.ID, hashes []string) error {
+	return withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		return r.replaceCodes(ctx, userID, hashes)
+	})
+}
+
+func (r *mfaRepository) Delete(ctx context.Context, userID domain.ID) error {
+	return withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		if err := r.delete(ctx, userID); err != nil {
+			return err
+		}
+
+		if _, err := conn(ctx, r.dbWrite).ExecContext(ctx, "UPDATE users SET mfa_enabled = FALSE WHERE uuid = ?", userID.String()); err != nil {
+			return fmt.Errorf("failed disabling mfa: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *mfaRepository) delete(ctx context.Context, userID domain.ID) error {
+	tx := conn(ctx, r.dbWrite)
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mfa_factors WHERE user_id = (SELECT id FROM users WHERE uuid = ?)", userID.String()); err != nil {
+		return fmt.Errorf("failed deleting mfa factor: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = (SELECT id FROM users WHERE uuid = ?)", userID.String()); err != nil {
+		return fmt.Errorf("failed deleting recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+// replaceCodes has to be called within a transaction
+func (r *mfaRepository) replaceCodes(ctx context.Context, userID domain.ID, hashes []string) error {
+	tx := conn(ctx, r.dbWrite)
+
+	var id int64
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM users WHERE uuid = ? AND deleted_at IS NULL", userID.String()).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user.ErrNotFound
+		}
+
+		return fmt.Errorf("failed querying user: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = ?", id); err != nil {
+		return fmt.Errorf("failed deleting recovery codes: %w", err)
+	}
+
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, 0, len(hashes))
+	args := make([]any, 0, 2*len(hashes))
+
+	for _, hash := range hashes {
+		placeholders = append(placeholders, "(?, ?)")
+		args = append(args, id, hash)
+	}
+
+	query := "INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES " + strings.Join(placeholders, ", ")
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed storing recovery codes: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,247 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+type clientRepository struct {
+	dbRead  *sql.DB
+	dbWrite *sql.DB
+}
+
+var _ oidc.ClientRepository = (*clientRepository)(nil)
+
+func NewClientRepository(dbRead, dbWrite *sql.DB) *clientRepository {
+	return &clientRepository{
+		dbRead:  dbRead,
+		dbWrite: dbWrite,
+	}
+}
+
+func (r *clientRepository) Create(ctx context.Context, c *oidc.Client, createdAt time.Time) error {
+	redirectURIs, err := json.Marshal(c.RedirectURIs)
+	if err != nil {
+		return fmt.Errorf("failed encoding redirect uris: %w", err)
+	}
+
+	query := `
+		INSERT INTO oauth_clients (client_id, name, secret_hash, redirect_uris, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	secretHash := sql.NullString{String: c.SecretHash, Valid: !c.Public()}
+
+	if _, err = conn(ctx, r.dbWrite).ExecContext(ctx, query, c.ID.String(), c.Name, secretHash, string(redirectURIs), createdAt); err != nil {
+		return fmt.Errorf("failed creating client: %w", err)
+	}
+
+	return nil
+}
+
+func (r *clientRepository) Get(ctx context.Context, id domain.ID) (*oidc.Client, error) {
+	query := "SELECT name, secret_hash, redirect_uris FROM oauth_clients WHERE client_id = ?"
+
+	var (
+		secretHash   sql.NullString
+		redirectURIs string
+	)
+
+	c := &oidc.Client{ID: id}
+
+	err := conn(ctx, r.dbRead).QueryRowContext(ctx, query, id.String()).Scan(&c.Name, &secretHash, &redirectURIs)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, oidc.ErrClientNotFound
+		}
+
+		return nil, fmt.Errorf("failed querying client: %w", err)
+	}
+
+	if err = json.Unmarshal([]byte(redirectURIs), &c.RedirectURIs); err != nil {
+		return nil, fmt.Errorf("failed decoding redirect uris: %w", err)
+	}
+
+	c.SecretHash = secretHash.String
+
+	return c, nil
+}
+
+func (r *clientRepository) Delete(ctx context.Context, id domain.ID) error {
+	return withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		tx := conn(ctx, r.dbWrite)
+
+		result, err := tx.ExecContext(ctx, "DELETE FROM oauth_clients WHERE client_id = ?", id.String())
+		if err != nil {
+			return fmt.Errorf("failed deleting client: %w", err)
+		}
+
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return oidc.ErrClientNotFound
+		}
+
+		if _, err = tx.ExecContext(ctx, "DELETE FROM oauth_authorization_codes WHERE client_id = ? AND used_at IS NULL", id.String()); err != nil {
+			return fmt.Errorf("failed deleting authorization codes: %w", err)
+		}
+
+		return nil
+	})
+}
+
+type authorizationCodeRepository struct {
+	dbWrite *sql.DB
+}
+
+var _ oidc.CodeRepository = (*authorizationCodeRepository)(nil)
+
+// NewAuthorizationCodeRepository works on the write pool only, as a used code read from a lagging replica would be accepted again
+func NewAuthorizationCodeRepository(dbWrite *sql.DB) *authorizationCodeRepository {
+	return &authorizationCodeRepository{
+		dbWrite: dbWrite,
+	}
+}
+
+func (r *authorizationCodeRepository) Create(ctx context.Context, c *oidc.AuthorizationCode, createdAt time.Time) error {
+	query := `
+		INSERT INTO oauth_authorization_codes (user_id, client_id, code_hash, redirect_uri, scope, nonce, code_challenge, expires_at, created_at)
+		VALUES ((SELECT id FROM users WHERE uuid = ? AND deleted_at IS NULL), ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.dbWrite).ExecContext(ctx, query,
+		c.UserID.String(), c.ClientID.String(), c.Hash, c.RedirectURI, strings.Join(c.Scopes, " "), c.Nonce, c.CodeChallenge, c.ExpiresAt, createdAt)
+	if err != nil {
+		// the subquery yields NULL for a missing user, which the NOT NULL column rejects
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == columnCannotBeNull {
+			return user.ErrNotFound
+		}
+
+		return fmt.Errorf("failed creating authorization code: %w", err)
+	}
+
+	return nil
+}
+
+func (r *authorizationCodeRepository) Use(ctx context.Context, hash string, usedAt time.Time) (*oidc.AuthorizationCode, error) {
+	var c *oidc.AuthorizationCode
+
+	err := withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		tx := conn(ctx, r.dbWrite)
+
+		// the condition makes concurrent uses of one code accept only the first of them
+		result, err := tx.ExecContext(ctx, "UPDATE oauth_authorization_codes SET used_at = ? WHERE code_hash = ? AND used_at IS NULL", usedAt, hash)
+		if err != nil {
+			return fmt.Errorf("failed using authorization code: %w", err)
+		}
+
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return oidc.ErrInvalidGrant
+		}
+
+		query := `
+			SELECT u.uuid, c.client_id, c.redirect_uri, c.scope, c.nonce, c.code_challenge, c.expires_at
+			FROM oauth_authorization_codes c
+			JOIN users u ON u.id = c.user_id AND u.deleted_at IS NULL
+			WHERE c.code_hash = ?
+		`
+
+		var userUUID, clientUUID, scope string
+
+		c = &oidc.AuthorizationCode{Hash: hash}
+
+		err = tx.QueryRowContext(ctx, query, hash).Scan(&userUUID, &clientUUID, &c.RedirectURI, &scope, &c.Nonce, &c.CodeChallenge, &c.ExpiresAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return oidc.ErrInvalidGrant
+			}
+
+			return fmt.Errorf("failed querying authorization code: %w", err)
+		}
+
+		if c.UserID, err = domain.ParseID(userUUID); err != nil {
+			return fmt.Errorf("failed parsing uuid: %w", err)
+		}
+
+		if c.ClientID, err = domain.ParseID(clientUUID); err != nil {
+			return fmt.Errorf("failed parsing client uuid: %w", err)
+		}
+
+		c.Scopes = strings.Fields(scope)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+type signingKeyRepository struct {
+	dbWrite *sql.DB
+}
+
+var _ oidc.KeyRepository = (*signingKeyRepository)(nil)
+
+// NewSigningKeyRepository works on the write pool only, so a key generated by another instance is found right away
+func NewSigningKeyRepository(dbWrite *sql.DB) *signingKeyRepository {
+	return &signingKeyRepository{
+		dbWrite: dbWrite,
+	}
+}
+
+func (r *signingKeyRepository) Create(ctx context.Context, k *oidc.SigningKey) error {
+	query := `
+		INSERT INTO oauth_signing_keys (key_id, algorithm, private_key, public_key, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	if _, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, k.ID, k.Algorithm, k.PrivateKey, k.PublicKey, k.CreatedAt); err != nil {
+		return fmt.Errorf("failed creating signing key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *signingKeyRepository) List(ctx context.Context, since time.Time) ([]*oidc.SigningKey, error) {
+	query := `
+		SELECT key_id, algorithm, private_key, public_key, created_at
+		FROM oauth_signing_keys
+		WHERE created_at > ?
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := conn(ctx, r.dbWrite).QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed querying signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*oidc.SigningKey
+
+	for rows.Next() {
+		k := &oidc.SigningKey{}
+		if err = rows.Scan(&k.ID, &k.Algorithm, &k.PrivateKey, &k.PublicKey, &k.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed scanning signing key: %w", err)
+		}
+
+		keys = append(keys, k)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating signing keys: %w", err)
+	}
+
+	return keys, nil
+}
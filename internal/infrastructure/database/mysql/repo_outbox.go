@@ -0,0 +1,172 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+)
+
+type outboxRepository struct {
+	dbWrite *sql.DB
+}
+
+var _ event.Outbox = (*outboxRepository)(nil)
+
+// NewOutboxRepository works on the write pool only, as the pending events are claimed by the relay
+func NewOutboxRepository(dbWrite *sql.DB) *outboxRepository {
+	return &outboxRepository{
+		dbWrite: dbWrite,
+	}
+}
+
+func (r *outboxRepository) Add(ctx context.Context, e *event.Event) error {
+	query := `
+		INSERT INTO outbox_events (event_id, type, user_uuid, payload, occurred_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, e.ID.String(), e.Type, e.UserID.String(), []byte(e.Data), e.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("failed adding event: %w", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*event.Event, error) {
+	var events []*event.Event
+
+	err := withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		// the events being claimed by another relay are waited for rather than skipped, the following ones must not be published first
+		query := `
+			SELECT event_id, type, user_uuid, payload, occurred_at, claimed_until FROM outbox_events
+			WHERE published_at IS NULL
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE
+		`
+
+		rows, err := conn(ctx, r.dbWrite).QueryContext(ctx, query, limit)
+		if err != nil {
+			return fmt.Errorf("failed querying pending events: %w", err)
+		}
+		defer rows.Close()
+
+		leased := false
+
+		for rows.Next() {
+			var (
+				e            event.Event
+				eventUUID    string
+				userUUID     string
+				payload      []byte
+				claimedUntil sql.NullTime
+			)
+
+			if err = rows.Scan(&eventUUID, &e.Type, &userUUID, &payload, &e.OccurredAt, &claimedUntil); err != nil {
+				return fmt.Errorf("failed scanning event: %w", err)
+			}
+
+			if e.ID, err = domain.ParseID(eventUUID); err != nil {
+				return fmt.Errorf("failed parsing event uuid: %w", err)
+			}
+
+			if e.UserID, err = domain.ParseID(userUUID); err != nil {
+				return fmt.Errorf("failed parsing uuid: %w", err)
+			}
+
+			if claimedUntil.Valid && claimedUntil.Time.After(now) {
+				leased = true
+			}
+
+			e.Data = payload
+			events = append(events, &e)
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("failed iterating events: %w", err)
+		}
+
+		// another relay is publishing the oldest events, the following ones wait for it
+		if leased || len(events) == 0 {
+			events = nil
+			return nil
+		}
+
+		args := make([]any, 0, len(events)+1)
+		args = append(args, leaseUntil)
+
+		for _, e := range events {
+			args = append(args, e.ID.String())
+		}
+
+		placeholders := strings.Repeat("?, ", len(events)-1) + "?"
+
+		if _, err = conn(ctx, r.dbWrite).ExecContext(ctx, "UPDATE outbox_events SET claimed_until = ? WHERE event_id IN ("+placeholders+")", args...); err != nil {
+			return fmt.Errorf("failed claiming events: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, ids []domain.ID, publishedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, publishedAt)
+
+	for _, id := range ids {
+		args = append(args, id.String())
+	}
+
+	placeholders := strings.Repeat("?, ", len(ids)-1) + "?"
+	query := "UPDATE outbox_events SET published_at = ? WHERE event_id IN (" + placeholders + ")"
+
+	if _, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed marking events published: %w", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) Release(ctx context.Context, ids []domain.ID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(ids))
+
+	for _, id := range ids {
+		args = append(args, id.String())
+	}
+
+	placeholders := strings.Repeat("?, ", len(ids)-1) + "?"
+	query := "UPDATE outbox_events SET claimed_until = NULL WHERE event_id IN (" + placeholders + ")"
+
+	if _, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed releasing events: %w", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) Erase(ctx context.Context, userID domain.ID) error {
+	if _, err := conn(ctx, r.dbWrite).ExecContext(ctx, "UPDATE outbox_events SET payload = '{}' WHERE user_uuid = ?", userID.String()); err != nil {
+		return fmt.Errorf("failed erasing events: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+type sessionRepository struct {
+	dbWrite *sql.DB
+}
+
+var _ session.Repository = (*sessionRepository)(nil)
+
+// NewSessionRepository works on the write pool only, as a used token read from a lagging replica would be accepted again
+func NewSessionRepository(dbWrite *sql.DB) *sessionRepository {
+	return &sessionRepository{
+		dbWrite: dbWrite,
+	}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, t *session.RefreshToken, createdAt time.Time) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at)
+		VALUES ((SELECT id FROM users WHERE uuid = ? AND deleted_at IS NULL), ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, t.UserID.String(), t.FamilyID.String(), t.Hash, t.ExpiresAt, createdAt)
+	if err != nil {
+		// the subquery yields NULL for a missing user, which the NOT NULL column rejects
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == columnCannotBeNull {
+			return user.ErrNotFound
+		}
+
+		return fmt.Errorf("failed creating refresh token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) Get(ctx context.Context, hash string) (*session.RefreshToken, error) {
+	query := `
+		SELECT u.uuid, t.family_id, t.expires_at, t.used_at, t.revoked_at
+		FROM refresh_tokens t
+		JOIN users u ON u.id = t.user_id AND u.deleted_at IS NULL
+		WHERE t.token_hash = ?
+	`
+
+	var (
+		userUUID   string
+		familyUUID string
+		usedAt     sql.NullTime
+		revokedAt  sql.NullTime
+	)
+
+	t := &session.RefreshToken{Hash: hash}
+
+	err := conn(ctx, r.dbWrite).QueryRowContext(ctx, query, hash).Scan(&userUUID, &familyUUID, &t.ExpiresAt, &usedAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, session.ErrInvalidToken
+		}
+
+		return nil, fmt.Errorf("failed querying refresh token: %w", err)
+	}
+
+	if t.UserID, err = domain.ParseID(userUUID); err != nil {
+		return nil, fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if t.FamilyID, err = domain.ParseID(familyUUID); err != nil {
+		return nil, fmt.Errorf("failed parsing family uuid: %w", err)
+	}
+
+	if usedAt.Valid {
+		t.UsedAt = &usedAt.Time
+	}
+
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+
+	return t, nil
+}
+
+func (r *sessionRepository) Use(ctx context.Context, hash string, usedAt time.Time) error {
+	// the condition makes concurrent uses of one token accept only the first of them
+	result, err := conn(ctx, r.dbWrite).ExecContext(ctx, "UPDATE refresh_tokens SET used_at = ? WHERE token_hash = ? AND used_at IS NULL", usedAt, hash)
+	if err != nil {
+		return fmt.Errorf("failed using refresh token: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return session.ErrReused
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) RevokeFamily(ctx context.Context, familyID domain.ID, revokedAt time.Time) error {
+	query := "UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL"
+
+	if _, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, revokedAt, familyID.String()); err != nil {
+		return fmt.Errorf("failed revoking refresh tokens: %w", err)
+	}
+
+	return nil
+}
+
+// ListByUser folds the tokens of every family into one session, deleted users included, as the sessions are part of their data
+func (r *sessionRepository) ListByUser(ctx context.Context, userID domain.ID) ([]*session.Session, error) {
+	query := `
+		SELECT family_id, MIN(created_at), MAX(used_at), MAX(expires_at), MAX(revoked_at)
+		FROM refresh_tokens
+		WHERE user_id = (SELECT id FROM users WHERE uuid = ?)
+		GROUP BY family_id
+		ORDER BY MIN(created_at)
+	`
+
+	rows, err := conn(ctx, r.dbWrite).QueryContext(ctx, query, userID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed querying sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*session.Session
+
+	for rows.Next() {
+		var (
+			s          session.Session
+			familyUUID string
+			lastUsedAt sql.NullTime
+			revokedAt  sql.NullTime
+		)
+
+		if err = rows.Scan(&familyUUID, &s.CreatedAt, &lastUsedAt, &s.ExpiresAt, &revokedAt); err != nil {
+			return nil, fmt.Errorf("failed scanning session: %w", err)
+		}
+
+		if s.FamilyID, err = domain.ParseID(familyUUID); err != nil {
+			return nil, fmt.Errorf("failed parsing family uuid: %w", err)
+		}
+
+		if lastUsedAt.Valid {
+			s.LastUsedAt = &lastUsedAt.Time
+		}
+
+		if revokedAt.Valid {
+			s.RevokedAt = &revokedAt.Time
+		}
+
+		sessions = append(sessions, &s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+func (r *sessionRepository) RevokeUser(ctx context.Context, userID domain.ID, revokedAt time.Time) error {
+	query := `
+		UPDATE refresh_tokens SET revoked_at = ?
+		WHERE user_id = (SELECT id FROM users WHERE uuid = ?) AND revoked_at IS NULL
+	`
+
+	if _, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, revokedAt, userID.String()); err != nil {
+		return fmt.Errorf("failed revoking refresh tokens: %w", err)
+	}
+
+	return nil
+}
@@ -5,17 +5,20 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
 
 	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
 	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
 	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/database/mysql/entity"
 )
 
 const (
-	duplicatedEntry = 1062
+	duplicatedEntry    = 1062
+	columnCannotBeNull = 1048
 )
 
 type userRepository struct {
@@ -33,23 +36,20 @@ func NewUserRepository(dbRead, dbWrite *sql.DB) *userRepository {
 }
 
 func (r *userRepository) Create(ctx context.Context, u *user.User, createdAt time.Time) error {
-	tx, err := r.dbWrite.BeginTx(ctx, &sql.TxOptions{})
-	if err != nil {
-		return err
-	}
+	return withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		return r.create(ctx, u, createdAt)
+	})
+}
 
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		}
-	}()
+func (r *userRepository) create(ctx context.Context, u *user.User, createdAt time.Time) error {
+	tx := conn(ctx, r.dbWrite)
 
 	queryUser := `
-		INSERT INTO users (uuid, email, password, created_at, updated_at, first_name, last_name, phone_number)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO users (uuid, email, password, created_at, updated_at, first_name, last_name, phone_number, role)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := r.dbWrite.ExecContext(ctx, queryUser, u.ID.String(), u.Email, u.Password, createdAt, nil, u.FirstName, u.LastName, u.PhoneNumber)
+	result, err := tx.ExecContext(ctx, queryUser, u.ID.String(), u.Email, u.Password, createdAt, nil, u.FirstName, u.LastName, u.PhoneNumber, u.Role)
 	if err != nil {
 		var mysqlErr *mysql.MySQLError
 		if errors.As(err, &mysqlErr) {
@@ -84,14 +84,14 @@ func (r *userRepository) Create(ctx context.Context, u *user.User, createdAt tim
 		}
 	}
 
-	return tx.Commit()
+	return nil
 }
 
-func (r *userRepository) UpdateBasicFields(ctx context.Context, id domain.ID, fields map[string]any) error {
+func (r *userRepository) UpdateBasicFields(ctx context.Context, id domain.ID, changes []user.Change) error {
 	existsQuery := "SELECT COUNT(1) FROM users WHERE uuid = ? AND deleted_at IS NULL "
 
 	var exists int
-	if err := r.dbRead.QueryRowContext(ctx, existsQuery, id.String()).Scan(&exists); err != nil {
+	if err := conn(ctx, r.dbRead).QueryRowContext(ctx, existsQuery, id.String()).Scan(&exists); err != nil {
 		return fmt.Errorf("failed checking if user exists: %w", err)
 	}
 
@@ -99,55 +99,39 @@ func (r *userRepository) UpdateBasicFields(ctx context.Context, id domain.ID, fi
 		return user.ErrNotFound
 	}
 
-	queryUser := "UPDATE users SET "
-	var args []any
-	i := 1
-
-	for key, value := range fields {
-		queryUser += key + " = ?"
-		if i < len(fields) {
-			queryUser += ", "
-		}
-		args = append(args, value)
-		i++
+	set, args, err := buildSet(changes, userColumns)
+	if err != nil {
+		return fmt.Errorf("failed updating users: %w", err)
 	}
 
-	queryUser += " WHERE uuid = ?"
+	queryUser := "UPDATE users SET " + set + " WHERE uuid = ?"
 	args = append(args, id.String())
 
-	if _, err := r.dbWrite.ExecContext(ctx, queryUser, args...); err != nil {
+	if _, err = conn(ctx, r.dbWrite).ExecContext(ctx, queryUser, args...); err != nil {
 		return fmt.Errorf("failed updating users: %w", err)
 	}
 
 	return nil
 }
 
-func (r *userRepository) UpdateAddress(ctx context.Context, id domain.ID, addrType int, fields map[string]any) error {
+func (r *userRepository) UpdateAddress(ctx context.Context, id domain.ID, addrType user.AddressType, changes []user.Change) error {
 	existsQuery := "SELECT COUNT(1) FROM addresses WHERE user_id = (SELECT id FROM users WHERE uuid = ? AND deleted_at IS NULL) AND type = ?"
 
 	var exists int
-	_ = r.dbRead.QueryRowContext(ctx, existsQuery, id.String(), addrType).Scan(&exists)
+	_ = conn(ctx, r.dbRead).QueryRowContext(ctx, existsQuery, id.String(), addrType).Scan(&exists)
 	if exists == 0 {
 		return user.ErrAddressNotFound
 	}
 
-	queryAddress := "UPDATE addresses SET "
-	var args []any
-	i := 1
-
-	for key, value := range fields {
-		queryAddress += key + " = ?"
-		if i < len(fields) {
-			queryAddress += ", "
-		}
-		args = append(args, value)
-		i++
+	set, args, err := buildSet(changes, addressColumns)
+	if err != nil {
+		return fmt.Errorf("failed updating address: %w", err)
 	}
 
-	queryAddress += " WHERE user_id = (SELECT id FROM users WHERE uuid = ?) AND type = ?"
+	queryAddress := "UPDATE addresses SET " + set + " WHERE user_id = (SELECT id FROM users WHERE uuid = ?) AND type = ?"
 	args = append(args, id.String(), addrType)
 
-	if _, err := r.dbWrite.ExecContext(ctx, queryAddress, args...); err != nil {
+	if _, err = conn(ctx, r.dbWrite).ExecContext(ctx, queryAddress, args...); err != nil {
 		return fmt.Errorf("failed updating address: %w", err)
 	}
 
@@ -160,7 +144,7 @@ func (r *userRepository) InsertAddress(ctx context.Context, id domain.ID, addr *
 		VALUES ((SELECT id FROM users WHERE uuid = ? AND deleted_at IS NULL ), ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.dbWrite.ExecContext(ctx, query,
+	_, err := conn(ctx, r.dbWrite).ExecContext(ctx, query,
 		id.String(),
 		addr.Type,
 		addr.Street,
@@ -171,52 +155,333 @@ func (r *userRepository) InsertAddress(ctx context.Context, id domain.ID, addr *
 		createdAt,
 		nil,
 	)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) {
+			if mysqlErr.Number == duplicatedEntry {
+				return user.ErrAddressAlreadyExists
+			}
+		}
 
-	return err
+		return err
+	}
+
+	return nil
 }
 
-func (r *userRepository) Delete(ctx context.Context, id domain.ID) error {
-	tx, err := r.dbWrite.BeginTx(ctx, &sql.TxOptions{})
+// DeleteAddress removes the address for good, so an address of the same type can be added again
+func (r *userRepository) DeleteAddress(ctx context.Context, id domain.ID, addrType user.AddressType) error {
+	query := "DELETE FROM addresses WHERE user_id = (SELECT id FROM users WHERE uuid = ? AND deleted_at IS NULL) AND type = ? AND deleted_at IS NULL"
+
+	result, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, id.String(), addrType)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed deleting address: %w", err)
 	}
 
-	defer func() {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed deleting address: %w", err)
+	}
+
+	if affected == 0 {
+		return user.ErrAddressNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) ListAddresses(ctx context.Context, id domain.ID) ([]*user.Address, error) {
+	queryID := "SELECT id FROM users WHERE uuid = ? AND deleted_at IS NULL"
+
+	var userID int64
+	if err := conn(ctx, r.dbRead).QueryRowContext(ctx, queryID, id.String()).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, user.ErrNotFound
+		}
+
+		return nil, fmt.Errorf("failed querying user: %w", err)
+	}
+
+	addresses, err := r.addressesByUserIDs(ctx, []int64{userID})
+	if err != nil {
+		return nil, err
+	}
+
+	return addresses[userID], nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id domain.ID) error {
+	return withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		tx := conn(ctx, r.dbWrite)
+
+		queryID := "SELECT id FROM users WHERE uuid = ?"
+
+		var userID int
+		_ = tx.QueryRowContext(ctx, queryID, id.String()).Scan(&userID)
+		if userID == 0 {
+			return user.ErrNotFound
+		}
+
+		ts := time.Now()
+
+		queryUser := "UPDATE users SET deleted_at = ? WHERE id = ? LIMIT 1"
+		if _, err := tx.ExecContext(ctx, queryUser, ts, userID); err != nil {
+			return fmt.Errorf("failed deleting user: %w", err)
+		}
+
+		queryAddresses := "UPDATE addresses SET deleted_at = ? WHERE user_id = ?"
+		if _, err := tx.ExecContext(ctx, queryAddresses, ts, userID); err != nil {
+			return fmt.Errorf("failed deleting addresses: %w", err)
+		}
+
+		querySessions := "UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL"
+		if _, err := tx.ExecContext(ctx, querySessions, ts, userID); err != nil {
+			return fmt.Errorf("failed revoking sessions: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *userRepository) Restore(ctx context.Context, id domain.ID) error {
+	return withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		tx := conn(ctx, r.dbWrite)
+
+		var (
+			userID    int64
+			deletedAt sql.NullTime
+			erasedAt  sql.NullTime
+		)
+		err := tx.QueryRowContext(ctx, "SELECT id, deleted_at, erased_at FROM users WHERE uuid = ? FOR UPDATE", id.String()).Scan(&userID, &deletedAt, &erasedAt)
 		if err != nil {
-			tx.Rollback()
+			if errors.Is(err, sql.ErrNoRows) {
+				return user.ErrNotFound
+			}
+			return fmt.Errorf("failed locking user: %w", err)
 		}
-	}()
 
-	queryID := "SELECT id FROM users WHERE uuid = ?"
+		if erasedAt.Valid {
+			return user.ErrErased
+		}
 
-	var userID int
-	_ = r.dbRead.QueryRowContext(ctx, queryID, id.String()).Scan(&userID)
-	if userID == 0 {
-		return user.ErrNotFound
+		if !deletedAt.Valid {
+			return user.ErrNotDeleted
+		}
+
+		if _, err = tx.ExecContext(ctx, "UPDATE users SET deleted_at = NULL WHERE id = ?", userID); err != nil {
+			return fmt.Errorf("failed restoring user: %w", err)
+		}
+
+		// revoked sessions stay revoked, the user has to log in again
+		queryAddresses := "UPDATE addresses SET deleted_at = NULL WHERE user_id = ? AND deleted_at = ?"
+		if _, err = tx.ExecContext(ctx, queryAddresses, userID, deletedAt.Time); err != nil {
+			return fmt.Errorf("failed restoring addresses: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Erase removes the same rows as the foreign keys cascade to on a purge, but keeps the user row, so the UUID
+// stays taken and references to it keep pointing at the same user
+func (r *userRepository) Erase(ctx context.Context, id domain.ID, erasedAt time.Time) error {
+	return withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		tx := conn(ctx, r.dbWrite)
+
+		var (
+			userID int64
+			erased sql.NullTime
+		)
+		err := tx.QueryRowContext(ctx, "SELECT id, erased_at FROM users WHERE uuid = ? FOR UPDATE", id.String()).Scan(&userID, &erased)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return user.ErrNotFound
+			}
+			return fmt.Errorf("failed locking user: %w", err)
+		}
+
+		if erased.Valid {
+			return user.ErrErased
+		}
+
+		queryUser := `
+			UPDATE users SET
+				email = ?, email_verified = FALSE, password = '', mfa_enabled = FALSE,
+				first_name = '', last_name = '', phone_number = NULL, version = version + 1,
+				deleted_at = COALESCE(deleted_at, ?), erased_at = ?
+			WHERE id = ?
+		`
+		if _, err = tx.ExecContext(ctx, queryUser, user.ErasedEmail(id), erasedAt, erasedAt, userID); err != nil {
+			return fmt.Errorf("failed erasing user: %w", err)
+		}
+
+		for _, table := range []string{
+			"addresses",
+			"verification_tokens",
+			"mfa_factors",
+			"mfa_recovery_codes",
+			"refresh_tokens",
+			"oauth_authorization_codes",
+		} {
+			if _, err = tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE user_id = ?", userID); err != nil {
+				return fmt.Errorf("failed erasing %s: %w", table, err)
+			}
+		}
+
+		queryAttempts := "DELETE FROM login_attempts WHERE kind = ? AND value = ?"
+		if _, err = tx.ExecContext(ctx, queryAttempts, lockout.KindUser, id.String()); err != nil {
+			return fmt.Errorf("failed erasing login attempts: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Purge relies on the foreign keys to remove addresses, tokens, factors and codes of the users,
+// failed logins are not linked to users, so they are removed explicitly
+func (r *userRepository) Purge(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	var purged int
+
+	err := withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		tx := conn(ctx, r.dbWrite)
+
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, uuid FROM users
+			WHERE deleted_at IS NOT NULL AND deleted_at < ? AND erased_at IS NULL
+			ORDER BY deleted_at LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		`, deletedBefore, limit)
+		if err != nil {
+			return fmt.Errorf("failed querying deleted users: %w", err)
+		}
+		defer rows.Close()
+
+		var (
+			ids   []any
+			uuids []any
+		)
+
+		for rows.Next() {
+			var (
+				userID int64
+				uuid   string
+			)
+			if err = rows.Scan(&userID, &uuid); err != nil {
+				return fmt.Errorf("failed scanning deleted users: %w", err)
+			}
+
+			ids = append(ids, userID)
+			uuids = append(uuids, uuid)
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("failed iterating deleted users: %w", err)
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		placeholders := strings.Repeat("?, ", len(ids)-1) + "?"
+
+		queryAttempts := "DELETE FROM login_attempts WHERE kind = ? AND value IN (" + placeholders + ")"
+		if _, err = tx.ExecContext(ctx, queryAttempts, append([]any{lockout.KindUser}, uuids...)...); err != nil {
+			return fmt.Errorf("failed purging login attempts: %w", err)
+		}
+
+		result, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id IN ("+placeholders+")", ids...)
+		if err != nil {
+			return fmt.Errorf("failed purging users: %w", err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed purging users: %w", err)
+		}
+
+		purged = int(affected)
+
+		return nil
+	})
+
+	return purged, err
+}
+
+// SetVerifiedEmail does not check whether the user exists, as it is called after IncrementVersion which locks the row
+func (r *userRepository) SetVerifiedEmail(ctx context.Context, id domain.ID, email string) error {
+	query := "UPDATE users SET email = ?, email_verified = TRUE WHERE uuid = ? AND deleted_at IS NULL"
+
+	if _, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, email, id.String()); err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) {
+			if mysqlErr.Number == duplicatedEntry {
+				return user.ErrEmailAlreadyExists
+			}
+		}
+
+		return fmt.Errorf("failed verifying email: %w", err)
+	}
+
+	return nil
+}
+
+func (r *userRepository) ReplacePasswordHash(ctx context.Context, id domain.ID, current, replacement string) error {
+	// the condition takes the place of the row lock, a hash changed since it was read is not overwritten
+	query := "UPDATE users SET password = ? WHERE uuid = ? AND password = ? AND deleted_at IS NULL"
+
+	result, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, replacement, id.String(), current)
+	if err != nil {
+		return fmt.Errorf("failed replacing password hash: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return user.ErrVersionMismatch
 	}
 
-	ts := time.Now()
+	return nil
+}
+
+func (r *userRepository) IncrementVersion(ctx context.Context, id domain.ID, expected *int64) error {
+	tx := conn(ctx, r.dbWrite)
+
+	// the row stays locked until the end of the transaction, so concurrent changes are applied one after another
+	var version int64
+	if err := tx.QueryRowContext(ctx, "SELECT version FROM users WHERE uuid = ? AND deleted_at IS NULL FOR UPDATE", id.String()).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user.ErrNotFound
+		}
+		return fmt.Errorf("failed locking user: %w", err)
+	}
 
-	queryUser := "UPDATE users SET deleted_at = ? WHERE id = ? LIMIT 1"
-	if _, err = r.dbWrite.ExecContext(ctx, queryUser, ts, userID); err != nil {
-		return fmt.Errorf("failed deleting user: %w", err)
+	if expected != nil && *expected != version {
+		return user.ErrVersionMismatch
 	}
 
-	queryAddresses := "UPDATE addresses SET deleted_at = ? WHERE user_id = ?"
-	if _, err = r.dbWrite.ExecContext(ctx, queryAddresses, ts, userID); err != nil {
-		return fmt.Errorf("failed deleting addresses: %w", err)
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET version = version + 1 WHERE uuid = ?", id.String()); err != nil {
+		return fmt.Errorf("failed incrementing user version: %w", err)
 	}
 
-	return tx.Commit()
+	return nil
 }
 
 func (r *userRepository) GetByUUID(ctx context.Context, id domain.ID) (*user.User, error) {
+	return r.getByUUID(ctx, id, false)
+}
+
+func (r *userRepository) GetByUUIDIncludingDeleted(ctx context.Context, id domain.ID) (*user.User, error) {
+	return r.getByUUID(ctx, id, true)
+}
+
+func (r *userRepository) getByUUID(ctx context.Context, id domain.ID, includeDeleted bool) (*user.User, error) {
 	var dbUser entity.DbUser
 
-	queryUser := "SELECT id, uuid, email, first_name, last_name, phone_number FROM users WHERE uuid = ? AND deleted_at IS NULL"
+	queryUser := "SELECT id, uuid, email, email_verified, mfa_enabled, first_name, last_name, phone_number, role, version, deleted_at FROM users WHERE uuid = ?"
+	if !includeDeleted {
+		queryUser += " AND deleted_at IS NULL"
+	}
 
-	row := r.dbRead.QueryRowContext(ctx, queryUser, id.String())
-	err := row.Scan(&dbUser.ID, &dbUser.UUID, &dbUser.Email, &dbUser.FirstName, &dbUser.LastName, &dbUser.PhoneNumber)
+	row := conn(ctx, r.dbRead).QueryRowContext(ctx, queryUser, id.String())
+	err := row.Scan(&dbUser.ID, &dbUser.UUID, &dbUser.Email, &dbUser.EmailVerified, &dbUser.MFAEnabled, &dbUser.FirstName, &dbUser.LastName, &dbUser.PhoneNumber, &dbUser.Role, &dbUser.Version, &dbUser.DeletedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, user.ErrNotFound
@@ -224,105 +489,218 @@ func (r *userRepository) GetByUUID(ctx context.Context, id domain.ID) (*user.Use
 		return nil, fmt.Errorf("failed querying user: %w", err)
 	}
 
-	var domainAddresses []*user.Address
-
-	queryAddresses := "SELECT type, street, city, state, postal_code, country FROM addresses WHERE user_id = ? AND deleted_at IS NULL"
-	rows, err := r.dbRead.QueryContext(ctx, queryAddresses, dbUser.ID)
+	addresses, err := r.addressesByUserIDs(ctx, []int64{dbUser.ID.Int64})
 	if err != nil {
-		return nil, fmt.Errorf("failed querying addresses: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var dbAddress entity.DbAddress
-		if err := rows.Scan(&dbAddress.Type, &dbAddress.Street, &dbAddress.City, &dbAddress.State, &dbAddress.PostalCode, &dbAddress.Country); err != nil {
-			return nil, fmt.Errorf("failed scanning addresses: %w", err)
+	userID, _ := domain.ParseID(dbUser.UUID.String)
+	domainUser := &user.User{
+		ID:            userID,
+		Email:         dbUser.Email.String,
+		EmailVerified: dbUser.EmailVerified.Bool,
+		MFAEnabled:    dbUser.MFAEnabled.Bool,
+		Password:      "", // Password is not retrieved
+		FirstName:     dbUser.FirstName.String,
+		LastName:      dbUser.LastName.String,
+		PhoneNumber:   dbUser.PhoneNumber.String,
+		Role:          user.Role(dbUser.Role.String),
+		Addresses:     addresses[dbUser.ID.Int64],
+		Version:       dbUser.Version.Int64,
+		DeletedAt:     dbUser.DeletedAt.Ptr(),
+	}
+
+	return domainUser, nil
+}
+
+func (r *userRepository) Get(ctx context.Context, q *user.ListQuery) (*user.Page, error) {
+	orderBy := q.OrderBy()
+
+	page := &user.Page{}
+
+	where, args := buildUserFilter(q.Filter, q.IncludeDeleted)
+
+	if q.WithTotal {
+		var total int
+		if err := conn(ctx, r.dbRead).QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE "+where, args...).Scan(&total); err != nil {
+			return nil, fmt.Errorf("failed counting users: %w", err)
 		}
+		page.Total = &total
+	}
 
-		domainAddress := &user.Address{
-			Type:       user.AddressType(dbAddress.Type.Int64),
-			Street:     dbAddress.Street.String,
-			City:       dbAddress.City.String,
-			State:      dbAddress.State.String,
-			PostalCode: dbAddress.PostalCode.String,
-			Country:    dbAddress.Country.String,
+	if q.Cursor != nil {
+		seek, seekArgs, err := buildUserSeek(orderBy, q.Cursor)
+		if err != nil {
+			return nil, err
 		}
 
-		domainAddresses = append(domainAddresses, domainAddress)
+		where += " AND " + seek
+		args = append(args, seekArgs...)
 	}
 
-	userID, _ := domain.ParseID(dbUser.UUID.String)
-	domainUser := &user.User{
-		ID:          userID,
-		Email:       dbUser.Email.String,
-		Password:    "", // Password is not retrieved
-		FirstName:   dbUser.FirstName.String,
-		LastName:    dbUser.LastName.String,
-		PhoneNumber: dbUser.PhoneNumber.String,
-		Addresses:   domainAddresses,
+	// one user more than requested tells whether there is a next page
+	queryUsers := "SELECT id, uuid, email, email_verified, mfa_enabled, first_name, last_name, phone_number, role, created_at, version, deleted_at FROM users WHERE " + where +
+		" ORDER BY " + buildUserOrder(orderBy) + " LIMIT ?"
+
+	args = append(args, q.Limit+1)
+
+	dbUsers, err := r.queryUsers(ctx, queryUsers, args...)
+	if err != nil {
+		return nil, err
 	}
 
-	return domainUser, nil
-}
+	if len(dbUsers) > q.Limit {
+		dbUsers = dbUsers[:q.Limit]
+		page.NextCursor = userCursor(orderBy, dbUsers[len(dbUsers)-1])
+	}
 
-func (r *userRepository) Get(ctx context.Context, page, pageSize int) ([]*user.User, error) {
-	offset := (page - 1) * pageSize
+	ids := make([]int64, 0, len(dbUsers))
+	for _, dbUser := range dbUsers {
+		ids = append(ids, dbUser.ID.Int64)
+	}
+
+	addresses, err := r.addressesByUserIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
 
-	var domainUsers []*user.User
+	for _, dbUser := range dbUsers {
+		userID, _ := domain.ParseID(dbUser.UUID.String)
+		domainUser := &user.User{
+			ID:            userID,
+			Email:         dbUser.Email.String,
+			EmailVerified: dbUser.EmailVerified.Bool,
+			MFAEnabled:    dbUser.MFAEnabled.Bool,
+			Password:      "",
+			FirstName:     dbUser.FirstName.String,
+			LastName:      dbUser.LastName.String,
+			PhoneNumber:   dbUser.PhoneNumber.String,
+			Role:          user.Role(dbUser.Role.String),
+			Addresses:     addresses[dbUser.ID.Int64],
+			Version:       dbUser.Version.Int64,
+			DeletedAt:     dbUser.DeletedAt.Ptr(),
+		}
 
-	queryUsers := "SELECT id, uuid, email, first_name, last_name, phone_number FROM users WHERE deleted_at IS NULL LIMIT ? OFFSET ?"
+		page.Users = append(page.Users, domainUser)
+	}
 
-	rowsUsers, err := r.dbRead.QueryContext(ctx, queryUsers, pageSize, offset)
+	return page, nil
+}
+
+func (r *userRepository) queryUsers(ctx context.Context, query string, args ...any) ([]entity.DbUser, error) {
+	rows, err := conn(ctx, r.dbRead).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed querying users: %w", err)
 	}
+	defer rows.Close()
+
+	var dbUsers []entity.DbUser
 
-	for rowsUsers.Next() {
+	for rows.Next() {
 		var dbUser entity.DbUser
-		if err = rowsUsers.Scan(&dbUser.ID, &dbUser.UUID, &dbUser.Email, &dbUser.FirstName, &dbUser.LastName, &dbUser.PhoneNumber); err != nil {
+		if err = rows.Scan(&dbUser.ID, &dbUser.UUID, &dbUser.Email, &dbUser.EmailVerified, &dbUser.MFAEnabled, &dbUser.FirstName, &dbUser.LastName, &dbUser.PhoneNumber, &dbUser.Role, &dbUser.CreatedAt, &dbUser.Version, &dbUser.DeletedAt); err != nil {
 			return nil, fmt.Errorf("failed scanning users: %w", err)
 		}
 
-		var domainAddresses []*user.Address
+		dbUsers = append(dbUsers, dbUser)
+	}
 
-		queryAddresses := "SELECT type, street, city, state, postal_code, country FROM addresses WHERE user_id = ? AND deleted_at IS NULL "
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating users: %w", err)
+	}
 
-		rowsAddresses, err := r.dbRead.QueryContext(ctx, queryAddresses, dbUser.ID.Int64)
-		if err != nil {
-			return nil, fmt.Errorf("failed querying addresses: %w", err)
-		}
+	return dbUsers, nil
+}
 
-		for rowsAddresses.Next() {
-			var dbAddress entity.DbAddress
-			if err = rowsAddresses.Scan(&dbAddress.Type, &dbAddress.Street, &dbAddress.City, &dbAddress.State, &dbAddress.PostalCode, &dbAddress.Country); err != nil {
-				return nil, fmt.Errorf("failed scanning addresses: %w", err)
-			}
+// addressesByUserIDs loads addresses of all the given users in a single query
+func (r *userRepository) addressesByUserIDs(ctx context.Context, ids []int64) (map[int64][]*user.Address, error) {
+	addresses := make(map[int64][]*user.Address, len(ids))
 
-			domainAddress := &user.Address{
-				Type:       user.AddressType(dbAddress.Type.Int64),
-				Street:     dbAddress.Street.String,
-				City:       dbAddress.City.String,
-				State:      dbAddress.State.String,
-				PostalCode: dbAddress.PostalCode.String,
-				Country:    dbAddress.Country.String,
-			}
+	if len(ids) == 0 {
+		return addresses, nil
+	}
+
+	placeholders := strings.Repeat("?, ", len(ids)-1) + "?"
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	// addresses of a deleted user are deleted at the same time as the user, so they are listed together with the user
+	queryAddresses := `
+		SELECT a.user_id, a.type, a.street, a.city, a.state, a.postal_code, a.country FROM addresses a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.user_id IN (` + placeholders + `) AND (a.deleted_at IS NULL OR a.deleted_at = u.deleted_at)
+		ORDER BY a.user_id, a.id
+	`
+
+	rows, err := conn(ctx, r.dbRead).QueryContext(ctx, queryAddresses, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed querying addresses: %w", err)
+	}
+	defer rows.Close()
 
-			domainAddresses = append(domainAddresses, domainAddress)
+	for rows.Next() {
+		var (
+			userID    int64
+			dbAddress entity.DbAddress
+		)
+		if err = rows.Scan(&userID, &dbAddress.Type, &dbAddress.Street, &dbAddress.City, &dbAddress.State, &dbAddress.PostalCode, &dbAddress.Country); err != nil {
+			return nil, fmt.Errorf("failed scanning addresses: %w", err)
 		}
 
-		userID, _ := domain.ParseID(dbUser.UUID.String)
-		domainUser := &user.User{
-			ID:          userID,
-			Email:       dbUser.Email.String,
-			Password:    "",
-			FirstName:   dbUser.FirstName.String,
-			LastName:    dbUser.LastName.String,
-			PhoneNumber: dbUser.PhoneNumber.String,
-			Addresses:   domainAddresses,
+		addresses[userID] = append(addresses[userID], &user.Address{
+			Type:       user.AddressType(dbAddress.Type.Int64),
+			Street:     dbAddress.Street.String,
+			City:       dbAddress.City.String,
+			State:      dbAddress.State.String,
+			PostalCode: dbAddress.PostalCode.String,
+			Country:    dbAddress.Country.String,
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating addresses: %w", err)
+	}
+
+	return addresses, nil
+}
+
+func (r *userRepository) GetCredentialsByEmail(ctx context.Context, email string) (*user.Credentials, error) {
+	return r.credentials(ctx, r.dbRead, "email = ?", email)
+}
+
+// GetCredentialsByUUID reads from the write pool, the password is about to be checked before it is changed
+func (r *userRepository) GetCredentialsByUUID(ctx context.Context, id domain.ID) (*user.Credentials, error) {
+	return r.credentials(ctx, r.dbWrite, "uuid = ?", id.String())
+}
+
+func (r *userRepository) credentials(ctx context.Context, db *sql.DB, condition string, arg any) (*user.Credentials, error) {
+	var (
+		dbUser       entity.DbUser
+		passwordHash string
+	)
+
+	query := "SELECT uuid, email, password, role, mfa_enabled FROM users WHERE " + condition + " AND deleted_at IS NULL"
+
+	err := conn(ctx, db).QueryRowContext(ctx, query, arg).Scan(&dbUser.UUID, &dbUser.Email, &passwordHash, &dbUser.Role, &dbUser.MFAEnabled)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, user.ErrNotFound
 		}
+		return nil, fmt.Errorf("failed querying credentials: %w", err)
+	}
 
-		domainUsers = append(domainUsers, domainUser)
+	userID, err := domain.ParseID(dbUser.UUID.String)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing uuid: %w", err)
 	}
 
-	return domainUsers, nil
+	return &user.Credentials{
+		ID:           userID,
+		Email:        dbUser.Email.String,
+		PasswordHash: passwordHash,
+		Role:         user.Role(dbUser.Role.String),
+		MFAEnabled:   dbUser.MFAEnabled.Bool,
+	}, nil
 }
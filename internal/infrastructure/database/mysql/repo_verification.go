@@ -0,0 +1,122 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+)
+
+type verificationRepository struct {
+	dbWrite *sql.DB
+}
+
+var _ verification.Repository = (*verificationRepository)(nil)
+
+// NewVerificationRepository works on the write pool only, as tokens are read right before they are consumed
+func NewVerificationRepository(dbWrite *sql.DB) *verificationRepository {
+	return &verificationRepository{
+		dbWrite: dbWrite,
+	}
+}
+
+func (r *verificationRepository) Create(ctx context.Context, t *verification.Token, createdAt time.Time) error {
+	query := `
+		INSERT INTO verification_tokens (user_id, purpose, token_hash, email, expires_at, created_at)
+		VALUES ((SELECT id FROM users WHERE uuid = ? AND deleted_at IS NULL), ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, t.UserID.String(), t.Purpose, t.Hash, t.Email, t.ExpiresAt, createdAt)
+	if err != nil {
+		// the subquery yields NULL for a missing user, which the NOT NULL column rejects
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == columnCannotBeNull {
+			return user.ErrNotFound
+		}
+
+		return fmt.Errorf("failed creating verification token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *verificationRepository) Consume(ctx context.Context, hash string, purpose verification.Purpose, now time.Time) (*verification.Token, error) {
+	var t *verification.Token
+
+	err := withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		tx := conn(ctx, r.dbWrite)
+
+		query := `
+			SELECT t.id, u.uuid, t.email, t.expires_at
+			FROM verification_tokens t
+			JOIN users u ON u.id = t.user_id AND u.deleted_at IS NULL
+			WHERE t.token_hash = ? AND t.purpose = ? AND t.used_at IS NULL
+			FOR UPDATE
+		`
+
+		var (
+			tokenID   int64
+			userUUID  string
+			email     string
+			expiresAt time.Time
+		)
+
+		err := tx.QueryRowContext(ctx, query, hash, purpose).Scan(&tokenID, &userUUID, &email, &expiresAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return verification.ErrInvalidToken
+			}
+
+			return fmt.Errorf("failed querying verification token: %w", err)
+		}
+
+		if !now.Before(expiresAt) {
+			return verification.ErrInvalidToken
+		}
+
+		if _, err = tx.ExecContext(ctx, "UPDATE verification_tokens SET used_at = ? WHERE id = ?", now, tokenID); err != nil {
+			return fmt.Errorf("failed consuming verification token: %w", err)
+		}
+
+		userID, err := domain.ParseID(userUUID)
+		if err != nil {
+			return fmt.Errorf("failed parsing uuid: %w", err)
+		}
+
+		t = &verification.Token{
+			Hash:      hash,
+			UserID:    userID,
+			Purpose:   purpose,
+			Email:     email,
+			ExpiresAt: expiresAt,
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Revoke deletes the unused tokens, used ones are kept as a trace of past verifications
+func (r *verificationRepository) Revoke(ctx context.Context, userID domain.ID, purpose verification.Purpose) error {
+	query := `
+		DELETE FROM verification_tokens
+		WHERE user_id = (SELECT id FROM users WHERE uuid = ?) AND purpose = ? AND used_at IS NULL
+	`
+
+	if _, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, userID.String(), purpose); err != nil {
+		return fmt.Errorf("failed revoking verification tokens: %w", err)
+	}
+
+	return nil
+}
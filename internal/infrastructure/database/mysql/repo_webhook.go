@@ -0,0 +1,408 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+)
+
+type subscriptionRepository struct {
+	dbRead  *sql.DB
+	dbWrite *sql.DB
+}
+
+var _ webhook.SubscriptionRepository = (*subscriptionRepository)(nil)
+
+func NewSubscriptionRepository(dbRead, dbWrite *sql.DB) *subscriptionRepository {
+	return &subscriptionRepository{
+		dbRead:  dbRead,
+		dbWrite: dbWrite,
+	}
+}
+
+func (r *subscriptionRepository) Create(ctx context.Context, s *webhook.Subscription) error {
+	eventTypes, err := json.Marshal(s.EventTypes)
+	if err != nil {
+		return fmt.Errorf("failed encoding event types: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_subscriptions (uuid, url, event_types, secret, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = conn(ctx, r.dbWrite).ExecContext(ctx, query, s.ID.String(), s.URL, string(eventTypes), s.EncryptedSecret, s.CreatedAt, s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed creating subscription: %w", err)
+	}
+
+	return nil
+}
+
+func (r *subscriptionRepository) Get(ctx context.Context, id domain.ID) (*webhook.Subscription, error) {
+	query := "SELECT uuid, url, event_types, secret, created_at, updated_at FROM webhook_subscriptions WHERE uuid = ?"
+
+	s, err := scanSubscription(conn(ctx, r.dbRead).QueryRowContext(ctx, query, id.String()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, webhook.ErrSubscriptionNotFound
+		}
+
+		return nil, fmt.Errorf("failed querying subscription: %w", err)
+	}
+
+	return s, nil
+}
+
+func (r *subscriptionRepository) List(ctx context.Context) ([]*webhook.Subscription, error) {
+	query := "SELECT uuid, url, event_types, secret, created_at, updated_at FROM webhook_subscriptions ORDER BY id"
+
+	rows, err := conn(ctx, r.dbRead).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed querying subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []*webhook.Subscription
+
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed scanning subscription: %w", err)
+		}
+
+		subs = append(subs, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+func (r *subscriptionRepository) Update(ctx context.Context, s *webhook.Subscription) error {
+	eventTypes, err := json.Marshal(s.EventTypes)
+	if err != nil {
+		return fmt.Errorf("failed encoding event types: %w", err)
+	}
+
+	query := "UPDATE webhook_subscriptions SET url = ?, event_types = ?, secret = ?, updated_at = ? WHERE uuid = ?"
+
+	result, err := conn(ctx, r.dbWrite).ExecContext(ctx, query, s.URL, string(eventTypes), s.EncryptedSecret, s.UpdatedAt, s.ID.String())
+	if err != nil {
+		return fmt.Errorf("failed updating subscription: %w", err)
+	}
+
+	// a write of the same values is not counted as affected, so the subscription is looked up then
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		_, err = r.Get(ctx, s.ID)
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes the deliveries of the subscription through the foreign key
+func (r *subscriptionRepository) Delete(ctx context.Context, id domain.ID) error {
+	result, err := conn(ctx, r.dbWrite).ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE uuid = ?", id.String())
+	if err != nil {
+		return fmt.Errorf("failed deleting subscription: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return webhook.ErrSubscriptionNotFound
+	}
+
+	return nil
+}
+
+func scanSubscription(row interface{ Scan(dest ...any) error }) (*webhook.Subscription, error) {
+	var (
+		s          webhook.Subscription
+		uuid       string
+		eventTypes string
+	)
+
+	if err := row.Scan(&uuid, &s.URL, &eventTypes, &s.EncryptedSecret, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if s.ID, err = domain.ParseID(uuid); err != nil {
+		return nil, fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if err = json.Unmarshal([]byte(eventTypes), &s.EventTypes); err != nil {
+		return nil, fmt.Errorf("failed decoding event types: %w", err)
+	}
+
+	return &s, nil
+}
+
+type deliveryRepository struct {
+	dbRead  *sql.DB
+	dbWrite *sql.DB
+}
+
+var _ webhook.DeliveryRepository = (*deliveryRepository)(nil)
+
+func NewDeliveryRepository(dbRead, dbWrite *sql.DB) *deliveryRepository {
+	return &deliveryRepository{
+		dbRead:  dbRead,
+		dbWrite: dbWrite,
+	}
+}
+
+const deliveryColumns = `
+	d.id, d.uuid, s.uuid, d.event_id, d.event_type, d.user_uuid, d.payload, d.occurred_at,
+	d.status, d.attempts, d.next_attempt_at, d.last_attempt_at, d.last_status_code, d.last_error, d.created_at
+`
+
+func (r *deliveryRepository) Create(ctx context.Context, d *webhook.Delivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (uuid, subscription_id, event_id, event_type, user_uuid, payload, occurred_at, status, next_attempt_at, created_at)
+		VALUES (?, (SELECT id FROM webhook_subscriptions WHERE uuid = ?), ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := conn(ctx, r.dbWrite).ExecContext(ctx, query,
+		d.ID.String(),
+		d.SubscriptionID.String(),
+		d.Event.ID.String(),
+		d.Event.Type,
+		d.Event.UserID.String(),
+		[]byte(d.Event.Data),
+		d.Event.OccurredAt,
+		d.Status,
+		d.NextAttemptAt,
+		d.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed creating delivery: %w", err)
+	}
+
+	if d.Seq, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed reading delivery id: %w", err)
+	}
+
+	return nil
+}
+
+func (r *deliveryRepository) Get(ctx context.Context, subscriptionID, id domain.ID) (*webhook.Delivery, error) {
+	query := "SELECT " + deliveryColumns + `
+		FROM webhook_deliveries d JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE d.uuid = ? AND s.uuid = ?
+	`
+
+	d, err := scanDelivery(conn(ctx, r.dbRead).QueryRowContext(ctx, query, id.String(), subscriptionID.String()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, webhook.ErrDeliveryNotFound
+		}
+
+		return nil, fmt.Errorf("failed querying delivery: %w", err)
+	}
+
+	return d, nil
+}
+
+// Claim locks the due deliveries only for as long as it takes to postpone them, they are sent after the transaction has ended
+func (r *deliveryRepository) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*webhook.Delivery, error) {
+	var deliveries []*webhook.Delivery
+
+	err := withinTx(ctx, r.dbWrite, func(ctx context.Context) error {
+		// the deliveries locked by another dispatcher are skipped rather than waited for
+		query := "SELECT " + deliveryColumns + `
+			FROM webhook_deliveries d JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = ? AND d.next_attempt_at <= ?
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT ?
+			FOR UPDATE OF d SKIP LOCKED
+		`
+
+		var err error
+
+		if deliveries, err = r.query(ctx, r.dbWrite, query, webhook.StatusPending, now, limit); err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		args := make([]any, 0, len(deliveries)+1)
+		args = append(args, leaseUntil)
+
+		for _, d := range deliveries {
+			args = append(args, d.Seq)
+			d.NextAttemptAt = &leaseUntil
+		}
+
+		placeholders := strings.Repeat("?, ", len(deliveries)-1) + "?"
+
+		if _, err = conn(ctx, r.dbWrite).ExecContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN ("+placeholders+")", args...); err != nil {
+			return fmt.Errorf("failed claiming deliveries: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *deliveryRepository) Update(ctx context.Context, d *webhook.Delivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_attempt_at = ?, last_status_code = ?, last_error = ?
+		WHERE uuid = ?
+	`
+
+	_, err := conn(ctx, r.dbWrite).ExecContext(ctx, query,
+		d.Status,
+		d.Attempts,
+		d.NextAttemptAt,
+		d.LastAttemptAt,
+		sql.NullInt64{Int64: int64(d.LastStatusCode), Valid: d.LastStatusCode != 0},
+		sql.NullString{String: d.LastError, Valid: d.LastError != ""},
+		d.ID.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed updating delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (r *deliveryRepository) List(ctx context.Context, q *webhook.DeliveryQuery) (*webhook.DeliveryPage, error) {
+	where := []string{"s.uuid = ?"}
+	args := []any{q.SubscriptionID.String()}
+
+	if q.Status != "" {
+		where = append(where, "d.status = ?")
+		args = append(args, q.Status)
+	}
+
+	if q.Cursor != nil {
+		where = append(where, "d.id < ?")
+		args = append(args, q.Cursor.Seq)
+	}
+
+	// one delivery more than requested tells whether there is a next page
+	query := "SELECT " + deliveryColumns + `
+		FROM webhook_deliveries d JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY d.id DESC
+		LIMIT ?
+	`
+
+	args = append(args, q.Limit+1)
+
+	deliveries, err := r.query(ctx, r.dbRead, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &webhook.DeliveryPage{Deliveries: deliveries}
+
+	if len(page.Deliveries) > q.Limit {
+		page.Deliveries = page.Deliveries[:q.Limit]
+		page.NextCursor = &webhook.Cursor{Seq: page.Deliveries[len(page.Deliveries)-1].Seq}
+	}
+
+	return page, nil
+}
+
+func (r *deliveryRepository) Erase(ctx context.Context, userID domain.ID) error {
+	if _, err := conn(ctx, r.dbWrite).ExecContext(ctx, "UPDATE webhook_deliveries SET payload = '{}' WHERE user_uuid = ?", userID.String()); err != nil {
+		return fmt.Errorf("failed erasing deliveries: %w", err)
+	}
+
+	return nil
+}
+
+func (r *deliveryRepository) query(ctx context.Context, db *sql.DB, query string, args ...any) ([]*webhook.Delivery, error) {
+	rows, err := conn(ctx, db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed querying deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*webhook.Delivery
+
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed scanning delivery: %w", err)
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func scanDelivery(row interface{ Scan(dest ...any) error }) (*webhook.Delivery, error) {
+	var (
+		d                webhook.Delivery
+		e                event.Event
+		uuid             string
+		subscriptionUUID string
+		eventUUID        string
+		userUUID         string
+		payload          []byte
+		nextAttemptAt    sql.NullTime
+		lastAttemptAt    sql.NullTime
+		lastStatusCode   sql.NullInt64
+		lastError        sql.NullString
+	)
+
+	err := row.Scan(
+		&d.Seq, &uuid, &subscriptionUUID, &eventUUID, &e.Type, &userUUID, &payload, &e.OccurredAt,
+		&d.Status, &d.Attempts, &nextAttemptAt, &lastAttemptAt, &lastStatusCode, &lastError, &d.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.ID, err = domain.ParseID(uuid); err != nil {
+		return nil, fmt.Errorf("failed parsing delivery uuid: %w", err)
+	}
+
+	if d.SubscriptionID, err = domain.ParseID(subscriptionUUID); err != nil {
+		return nil, fmt.Errorf("failed parsing subscription uuid: %w", err)
+	}
+
+	if e.ID, err = domain.ParseID(eventUUID); err != nil {
+		return nil, fmt.Errorf("failed parsing event uuid: %w", err)
+	}
+
+	if e.UserID, err = domain.ParseID(userUUID); err != nil {
+		return nil, fmt.Errorf("failed parsing uuid: %w", err)
+	}
+
+	if nextAttemptAt.Valid {
+		d.NextAttemptAt = &nextAttemptAt.Time
+	}
+
+	if lastAttemptAt.Valid {
+		d.LastAttemptAt = &lastAttemptAt.Time
+	}
+
+	e.Data = payload
+	d.Event = &e
+	d.LastStatusCode = int(lastStatusCode.Int64)
+	d.LastError = lastError.String
+
+	return &d, nil
+}
@@ -0,0 +1,122 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2idPrefix = "$argon2id$"
+	saltLength     = 16
+	keyLength      = 32
+)
+
+var errMalformedArgon2id = errors.New("malformed argon2id hash")
+
+// Argon2idParams are the costs of argon2id, memory is given in KiB
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+type argon2idAlgorithm struct {
+	params Argon2idParams
+}
+
+var _ Algorithm = (*argon2idAlgorithm)(nil)
+
+func NewArgon2id(params Argon2idParams) (*argon2idAlgorithm, error) {
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return nil, errors.New("argon2id memory, iterations and parallelism must be positive")
+	}
+
+	return &argon2idAlgorithm{
+		params: params,
+	}, nil
+}
+
+// Hash encodes the hash in the PHC string format, e.g. $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+func (a *argon2idAlgorithm) Hash(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed generating salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.params.Iterations, a.params.Memory, a.params.Parallelism, keyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		a.params.Memory,
+		a.params.Iterations,
+		a.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a *argon2idAlgorithm) Verify(hash, password string) (bool, error) {
+	version, params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	if version != argon2.Version {
+		return false, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+func (a *argon2idAlgorithm) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+func (a *argon2idAlgorithm) Outdated(hash string) bool {
+	version, params, salt, key, err := decodeArgon2id(hash)
+
+	return err != nil || version != argon2.Version || params != a.params || len(salt) != saltLength || len(key) != keyLength
+}
+
+func decodeArgon2id(hash string) (int, Argon2idParams, []byte, []byte, error) {
+	var (
+		version int
+		params  Argon2idParams
+	)
+
+	// the leading separator leaves an empty first part
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return 0, params, nil, nil, errMalformedArgon2id
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return 0, params, nil, nil, errMalformedArgon2id
+	}
+
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil || params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return 0, params, nil, nil, errMalformedArgon2id
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return 0, params, nil, nil, errMalformedArgon2id
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return 0, params, nil, nil, errMalformedArgon2id
+	}
+
+	return version, params, salt, key, nil
+}
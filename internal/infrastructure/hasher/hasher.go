@@ -0,0 +1,61 @@
+package hasher
+
+import (
+	"errors"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Algorithm is a single hashing scheme, its hashes are recognized by their format
+type Algorithm interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) (bool, error)
+	Recognizes(hash string) bool
+
+	// Outdated tells whether the hash has been created with parameters other than the current ones
+	Outdated(hash string) bool
+}
+
+type passwordHasher struct {
+	preferred  Algorithm
+	algorithms []Algorithm
+}
+
+var _ auth.PasswordHasher = (*passwordHasher)(nil)
+
+// New creates a hasher hashing with the preferred algorithm, hashes of the supported ones are still verified
+// and reported to be rehashed
+func New(preferred Algorithm, supported ...Algorithm) *passwordHasher {
+	return &passwordHasher{
+		preferred:  preferred,
+		algorithms: append([]Algorithm{preferred}, supported...),
+	}
+}
+
+func (h *passwordHasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+func (h *passwordHasher) Verify(hash, password string) (bool, bool, error) {
+	for _, algorithm := range h.algorithms {
+		if !algorithm.Recognizes(hash) {
+			continue
+		}
+
+		match, err := algorithm.Verify(hash, password)
+		if err != nil || !match {
+			return false, false, err
+		}
+
+		return true, algorithm != h.preferred || algorithm.Outdated(hash), nil
+	}
+
+	return false, false, ErrUnknownHash
+}
@@ -0,0 +1,122 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2idParams = Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestHasher(t *testing.T) {
+	bcryptAlgorithm, err := NewBcrypt(bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	argon2idAlgorithm, err := NewArgon2id(testArgon2idParams)
+	assert.NoError(t, err)
+
+	t.Run("hash and verify", func(t *testing.T) {
+		for name, h := range map[string]*passwordHasher{
+			AlgorithmBcrypt:   New(bcryptAlgorithm, argon2idAlgorithm),
+			AlgorithmArgon2id: New(argon2idAlgorithm, bcryptAlgorithm),
+		} {
+			t.Run(name, func(t *testing.T) {
+				hash, err := h.Hash("secure123")
+				assert.NoError(t, err)
+				assert.NotContains(t, hash, "secure123")
+
+				match, rehash, err := h.Verify(hash, "secure123")
+				assert.NoError(t, err)
+				assert.True(t, match)
+				assert.False(t, rehash)
+
+				match, _, err = h.Verify(hash, "wrong-password")
+				assert.NoError(t, err)
+				assert.False(t, match)
+			})
+		}
+	})
+
+	t.Run("argon2id hashes are salted", func(t *testing.T) {
+		first, err := argon2idAlgorithm.Hash("secure123")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(first, "$argon2id$v=19$m=64,t=1,p=1$"))
+
+		second, err := argon2idAlgorithm.Hash("secure123")
+		assert.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("hash of another algorithm is rehashed", func(t *testing.T) {
+		hash, err := bcryptAlgorithm.Hash("secure123")
+		assert.NoError(t, err)
+
+		match, rehash, err := New(argon2idAlgorithm, bcryptAlgorithm).Verify(hash, "secure123")
+		assert.NoError(t, err)
+		assert.True(t, match)
+		assert.True(t, rehash)
+	})
+
+	t.Run("hash with an outdated cost is rehashed", func(t *testing.T) {
+		stronger, err := NewArgon2id(Argon2idParams{Memory: 128, Iterations: 1, Parallelism: 1})
+		assert.NoError(t, err)
+
+		hash, err := argon2idAlgorithm.Hash("secure123")
+		assert.NoError(t, err)
+
+		match, rehash, err := New(stronger).Verify(hash, "secure123")
+		assert.NoError(t, err)
+		assert.True(t, match)
+		assert.True(t, rehash)
+
+		costlier, err := NewBcrypt(bcrypt.MinCost + 1)
+		assert.NoError(t, err)
+
+		hash, err = bcryptAlgorithm.Hash("secure123")
+		assert.NoError(t, err)
+
+		match, rehash, err = New(costlier).Verify(hash, "secure123")
+		assert.NoError(t, err)
+		assert.True(t, match)
+		assert.True(t, rehash)
+	})
+
+	t.Run("wrong password is not rehashed", func(t *testing.T) {
+		hash, err := bcryptAlgorithm.Hash("secure123")
+		assert.NoError(t, err)
+
+		match, rehash, err := New(argon2idAlgorithm, bcryptAlgorithm).Verify(hash, "wrong-password")
+		assert.NoError(t, err)
+		assert.False(t, match)
+		assert.False(t, rehash)
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		hash, err := bcryptAlgorithm.Hash("secure123")
+		assert.NoError(t, err)
+
+		_, _, err = New(argon2idAlgorithm).Verify(hash, "secure123")
+		assert.ErrorIs(t, err, ErrUnknownHash)
+	})
+
+	t.Run("malformed argon2id hash", func(t *testing.T) {
+		for _, hash := range []string{
+			"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+			"$argon2id$v=19$m=64,t=1,p=0$c2FsdA$a2V5",
+			"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$!!!",
+		} {
+			_, _, err := New(argon2idAlgorithm).Verify(hash, "secure123")
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("invalid costs", func(t *testing.T) {
+		_, err := NewBcrypt(bcrypt.MaxCost + 1)
+		assert.Error(t, err)
+
+		_, err = NewArgon2id(Argon2idParams{Memory: 64, Iterations: 0, Parallelism: 1})
+		assert.Error(t, err)
+	})
+}
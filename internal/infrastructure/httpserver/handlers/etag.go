@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var errInvalidETag = errors.New("invalid entity tag")
+
+// etag formats a resource version as a strong entity tag
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseETag reads the version from a single strong entity tag, as If-Match requires the strong comparison
+func parseETag(tag string) (int64, error) {
+	tag = strings.TrimSpace(tag)
+	if len(tag) < 3 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, errInvalidETag
+	}
+
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil {
+		return 0, errInvalidETag
+	}
+
+	return version, nil
+}
+
+// noneMatch tells whether If-None-Match does not match the current entity tag, using the weak comparison
+func noneMatch(header, current string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+// addressRequest replaces an address of the type given in the path
+type addressRequest struct {
+	Street     string `json:"street" binding:"required" validate:"required,min=1,max=255"`
+	City       string `json:"city" binding:"required" validate:"required,min=1,max=100"`
+	State      string `json:"state" binding:"omitempty" validate:"omitempty,min=1,max=100"`
+	PostalCode string `json:"postal_code" binding:"required" validate:"required,min=1,max=20,alphanum"`
+	Country    string `json:"country" binding:"omitempty" validate:"omitempty,min=1,max=100,alpha"`
+}
+
+func (h *UserHTTPHandler) ListAddresses(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	addresses, err := h.userService.ListAddresses(c.Request.Context(), userID)
+	if err != nil {
+		respondAddressError(c, err)
+		return
+	}
+
+	if addresses == nil {
+		addresses = []*user.Address{}
+	}
+
+	c.JSON(http.StatusOK, addresses)
+}
+
+func (h *UserHTTPHandler) GetAddress(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	addrType, ok := addressType(c)
+	if !ok {
+		return
+	}
+
+	addr, err := h.userService.GetAddress(c.Request.Context(), userID, addrType)
+	if err != nil {
+		respondAddressError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, addr)
+}
+
+func (h *UserHTTPHandler) CreateAddress(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	version, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
+	var req addressDocument
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	addr := &user.Address{
+		Type:       user.AddressType(req.Type),
+		Street:     req.Street,
+		City:       req.City,
+		State:      req.State,
+		PostalCode: req.PostalCode,
+		Country:    req.Country,
+	}
+
+	if err := h.userService.AddAddress(c.Request.Context(), userID, addr, version); err != nil {
+		respondAddressError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, addr)
+}
+
+func (h *UserHTTPHandler) ReplaceAddress(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	addrType, ok := addressType(c)
+	if !ok {
+		return
+	}
+
+	version, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
+	var req addressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	addr := &user.Address{
+		Type:       addrType,
+		Street:     req.Street,
+		City:       req.City,
+		State:      req.State,
+		PostalCode: req.PostalCode,
+		Country:    req.Country,
+	}
+
+	if err := h.userService.ReplaceAddress(c.Request.Context(), userID, addr, version); err != nil {
+		respondAddressError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+func (h *UserHTTPHandler) DeleteAddress(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	addrType, ok := addressType(c)
+	if !ok {
+		return
+	}
+
+	version, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.DeleteAddress(c.Request.Context(), userID, addrType, version); err != nil {
+		respondAddressError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// addressType reads the address type from the path, it responds with an error and returns false when it is invalid
+func addressType(c *gin.Context) (user.AddressType, bool) {
+	value, err := strconv.Atoi(c.Param("type"))
+	if err != nil || value < 1 || value > 3 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address type"})
+		return 0, false
+	}
+
+	return user.AddressType(value), true
+}
+
+func respondAddressError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, user.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, user.ErrAddressNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "address not found"})
+	case errors.Is(err, user.ErrAddressAlreadyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "address of this type already exists"})
+	case errors.Is(err, user.ErrLastAddress):
+		c.JSON(http.StatusConflict, gin.H{"error": "user must have at least one address"})
+	case errors.Is(err, user.ErrVersionMismatch):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "precondition failed"})
+	case errors.Is(err, auth.ErrUnauthenticated):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+	case errors.Is(err, auth.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	default:
+		logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+	}
+}
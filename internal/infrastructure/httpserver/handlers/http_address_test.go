@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	serviceMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/applicaion/service"
+)
+
+func newAddressRouter(s *serviceMock.UserServiceMock) *gin.Engine {
+	userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/users/:id/addresses", userHandler.ListAddresses)
+	router.POST("/users/:id/addresses", userHandler.CreateAddress)
+	router.GET("/users/:id/addresses/:type", userHandler.GetAddress)
+	router.PUT("/users/:id/addresses/:type", userHandler.ReplaceAddress)
+	router.DELETE("/users/:id/addresses/:type", userHandler.DeleteAddress)
+
+	return router
+}
+
+func TestListAddresses(t *testing.T) {
+	t.Run("list addresses", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		s := new(serviceMock.UserServiceMock)
+		s.On("ListAddresses", mock.Anything, userID).Return([]*user.Address{
+			{Type: 1, Street: "Main av", City: "New York", PostalCode: "55010"},
+		}, nil)
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s/addresses", userID), nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		newAddressRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `[{"type":1,"street":"Main av","city":"New York","state":"","postal_code":"55010","country":""}]`, recorder.Body.String())
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		s := new(serviceMock.UserServiceMock)
+		s.On("ListAddresses", mock.Anything, userID).Return(nil, user.ErrNotFound)
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s/addresses", userID), nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		newAddressRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, `{"error":"user not found"}`, recorder.Body.String())
+	})
+}
+
+func TestGetAddress(t *testing.T) {
+	tests := []struct {
+		name         string
+		addrType     string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "get address",
+			addrType:     "2",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"type":2,"street":"Side av","city":"Boston","state":"","postal_code":"55010","country":""}`,
+		},
+		{
+			name:         "address not found",
+			addrType:     "2",
+			serviceErr:   user.ErrAddressNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"address not found"}`,
+		},
+		{
+			name:         "invalid address type",
+			addrType:     "home",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid address type"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New().String()
+
+			s := new(serviceMock.UserServiceMock)
+			if tt.serviceErr != nil {
+				s.On("GetAddress", mock.Anything, userID, user.AddressType(2)).Return(nil, tt.serviceErr)
+			} else {
+				s.On("GetAddress", mock.Anything, userID, user.AddressType(2)).Return(&user.Address{
+					Type: 2, Street: "Side av", City: "Boston", PostalCode: "55010",
+				}, nil)
+			}
+
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s/addresses/%s", userID, tt.addrType), nil)
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			newAddressRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestCreateAddress(t *testing.T) {
+	reqBody := `{"type": 2, "street": "Side av", "city": "Boston", "postal_code": "55010"}`
+	addr := &user.Address{Type: 2, Street: "Side av", City: "Boston", PostalCode: "55010"}
+
+	t.Run("create address", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		s := new(serviceMock.UserServiceMock)
+		s.On("AddAddress", mock.Anything, userID, addr, ptr(int64(3))).Return(nil)
+
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/addresses", userID), strings.NewReader(reqBody))
+		assert.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"3"`)
+
+		recorder := httptest.NewRecorder()
+		newAddressRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Equal(t, `{"type":2,"street":"Side av","city":"Boston","state":"","postal_code":"55010","country":""}`, recorder.Body.String())
+	})
+
+	t.Run("address already exists", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		s := new(serviceMock.UserServiceMock)
+		s.On("AddAddress", mock.Anything, userID, addr, (*int64)(nil)).Return(user.ErrAddressAlreadyExists)
+
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/addresses", userID), strings.NewReader(reqBody))
+		assert.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		newAddressRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		assert.Equal(t, `{"error":"address of this type already exists"}`, recorder.Body.String())
+	})
+
+	t.Run("validation error", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		s := new(serviceMock.UserServiceMock)
+
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/addresses", userID), strings.NewReader(`{"type": 4, "street": "Side av", "city": "Boston", "postal_code": "55010"}`))
+		assert.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		newAddressRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		s.AssertNotCalled(t, "AddAddress", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestReplaceAddress(t *testing.T) {
+	t.Run("replace address", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		s := new(serviceMock.UserServiceMock)
+		s.On("ReplaceAddress", mock.Anything, userID, &user.Address{
+			Type: 1, Street: "Main av", City: "New York", PostalCode: "55010", Country: "USA",
+		}, (*int64)(nil)).Return(nil)
+
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/users/%s/addresses/1", userID),
+			strings.NewReader(`{"street": "Main av", "city": "New York", "postal_code": "55010", "country": "USA"}`))
+		assert.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		newAddressRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"ok"`, recorder.Body.String())
+	})
+
+	t.Run("address not found", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		s := new(serviceMock.UserServiceMock)
+		s.On("ReplaceAddress", mock.Anything, userID, mock.Anything, (*int64)(nil)).Return(user.ErrAddressNotFound)
+
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/users/%s/addresses/3", userID),
+			strings.NewReader(`{"street": "Main av", "city": "New York", "postal_code": "55010"}`))
+		assert.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		newAddressRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, `{"error":"address not found"}`, recorder.Body.String())
+	})
+}
+
+func TestDeleteAddress(t *testing.T) {
+	tests := []struct {
+		name         string
+		ifMatch      string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "delete address",
+			expectedCode: http.StatusOK,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:         "last address",
+			serviceErr:   user.ErrLastAddress,
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"error":"user must have at least one address"}`,
+		},
+		{
+			name:         "version mismatch",
+			ifMatch:      `"2"`,
+			serviceErr:   user.ErrVersionMismatch,
+			expectedCode: http.StatusPreconditionFailed,
+			expectedBody: `{"error":"precondition failed"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New().String()
+
+			s := new(serviceMock.UserServiceMock)
+			s.On("DeleteAddress", mock.Anything, userID, user.AddressType(1), mock.Anything).Return(tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s/addresses/1", userID), nil)
+			assert.NoError(t, err)
+
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			recorder := httptest.NewRecorder()
+			newAddressRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
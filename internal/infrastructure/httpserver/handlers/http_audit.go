@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type AuditHTTPHandler struct {
+	validator    *validator.Validate
+	auditService service.AuditPort
+	maxPageSize  int
+}
+
+type listAuditRequest struct {
+	UserID  string `form:"user_id" validate:"omitempty,uuid"`
+	ActorID string `form:"actor_id" validate:"omitempty,max=255"`
+	Action  string `form:"action"`
+	From    string `form:"from"`
+	To      string `form:"to"`
+	Cursor  string `form:"cursor"`
+}
+
+type listAuditResponse struct {
+	Data       []*audit.Entry `json:"data"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Links      listUsersLinks `json:"links"`
+}
+
+func NewAuditHTTPHandler(v *validator.Validate, auditService service.AuditPort, maxPageSize int) *AuditHTTPHandler {
+	return &AuditHTTPHandler{
+		validator:    v,
+		auditService: auditService,
+		maxPageSize:  maxPageSize,
+	}
+}
+
+// ListUser lists the audit entries of the user, the user_id param is ignored
+func (h *AuditHTTPHandler) ListUser(c *gin.Context) {
+	userID, err := domain.ParseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	h.list(c, &userID)
+}
+
+// List lists the audit entries of all users, optionally narrowed down to one with the user_id param
+func (h *AuditHTTPHandler) List(c *gin.Context) {
+	h.list(c, nil)
+}
+
+func (h *AuditHTTPHandler) list(c *gin.Context, userID *domain.ID) {
+	size := c.DefaultQuery("size", strconv.Itoa(defaultPageSize))
+	iSize, err := strconv.Atoi(size)
+	if err != nil || iSize < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid size param"})
+		return
+	}
+	if iSize > h.maxPageSize {
+		iSize = h.maxPageSize
+	}
+
+	var req listAuditRequest
+	if err = c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err = h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	q, ok := buildAuditQuery(c, &req, iSize)
+	if !ok {
+		return
+	}
+
+	if userID != nil {
+		q.Filter.UserID = *userID
+	}
+
+	page, err := h.auditService.List(c.Request.Context(), q)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrUnauthenticated):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+		case errors.Is(err, auth.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	resp := listAuditResponse{
+		Data: page.Entries,
+		Links: listUsersLinks{
+			Self: c.Request.URL.RequestURI(),
+		},
+	}
+
+	if resp.Data == nil {
+		resp.Data = []*audit.Entry{}
+	}
+
+	if page.NextCursor != nil {
+		resp.NextCursor = page.NextCursor.Encode()
+
+		params := c.Request.URL.Query()
+		params.Set("cursor", resp.NextCursor)
+		resp.Links.Next = c.Request.URL.Path + "?" + params.Encode()
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// buildAuditQuery parses the params of the request, it responds with an error and returns false when they are invalid
+func buildAuditQuery(c *gin.Context, req *listAuditRequest, limit int) (*audit.Query, bool) {
+	q := &audit.Query{
+		Filter: audit.Filter{
+			ActorID: req.ActorID,
+		},
+		Limit: limit,
+	}
+
+	var err error
+
+	if req.UserID != "" {
+		if q.Filter.UserID, err = domain.ParseID(req.UserID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id param"})
+			return nil, false
+		}
+	}
+
+	if req.Action != "" {
+		if q.Filter.Action, err = audit.ParseAction(req.Action); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid action param"})
+			return nil, false
+		}
+	}
+
+	if q.Filter.From, err = parseTimeParam(req.From); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from param, expected RFC 3339"})
+		return nil, false
+	}
+
+	if q.Filter.To, err = parseTimeParam(req.To); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to param, expected RFC 3339"})
+		return nil, false
+	}
+
+	if req.Cursor != "" {
+		if q.Cursor, err = audit.DecodeCursor(req.Cursor); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor param"})
+			return nil, false
+		}
+	}
+
+	return q, true
+}
+
+// parseTimeParam parses an optional RFC 3339 time, an empty value yields nil
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	t = t.UTC()
+
+	return &t, nil
+}
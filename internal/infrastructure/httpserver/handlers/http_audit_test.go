@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	serviceMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/applicaion/service"
+)
+
+func newAuditRouter(s *serviceMock.AuditServiceMock) *gin.Engine {
+	auditHandler := NewAuditHTTPHandler(validator.New(), s, 10)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/users/:id/audit", auditHandler.ListUser)
+	router.GET("/audit", auditHandler.List)
+
+	return router
+}
+
+func TestListAudit(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	userID := domain.NewID()
+	otherID := domain.NewID()
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	entry := &audit.Entry{
+		ID:        7,
+		Actor:     audit.Actor{Type: audit.ActorClient, ID: "crm"},
+		Action:    audit.ActionUserUpdated,
+		UserID:    userID,
+		Changes:   []audit.Change{{Field: "first_name", Value: ptr("John")}, {Field: "phone_number"}},
+		RequestID: "req-1",
+		ClientIP:  "192.0.2.1",
+		CreatedAt: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name          string
+		url           string
+		expectedQuery *audit.Query
+		page          *audit.Page
+		serviceErr    error
+		expectedCode  int
+		expectedBody  string
+	}{
+		{
+			name:          "user entries",
+			url:           "/users/" + userID.String() + "/audit?user_id=" + otherID.String(),
+			expectedQuery: &audit.Query{Filter: audit.Filter{UserID: userID}, Limit: defaultPageSize},
+			page:          &audit.Page{Entries: []*audit.Entry{entry}, NextCursor: &audit.Cursor{ID: 7}},
+			expectedCode:  http.StatusOK,
+			expectedBody: `{"data":[{"actor":{"type":"client","id":"crm"},"action":"user.updated","user_id":"` + userID.String() + `",` +
+				`"changes":[{"field":"first_name","value":"John"},{"field":"phone_number","value":null}],` +
+				`"request_id":"req-1","client_ip":"192.0.2.1","created_at":"2024-05-02T10:00:00Z"}],"next_cursor":"Nw",` +
+				`"links":{"self":"/users/` + userID.String() + `/audit?user_id=` + otherID.String() + `",` +
+				`"next":"/users/` + userID.String() + `/audit?cursor=Nw\u0026user_id=` + otherID.String() + `"}}`,
+		},
+		{
+			name: "all entries with filters",
+			url:  "/audit?user_id=" + otherID.String() + "&actor_id=crm&action=user.deleted&from=2024-05-01T02:00:00%2B02:00&size=50&cursor=Nw",
+			expectedQuery: &audit.Query{
+				Filter: audit.Filter{UserID: otherID, ActorID: "crm", Action: audit.ActionUserDeleted, From: &from},
+				Cursor: &audit.Cursor{ID: 7},
+				Limit:  10,
+			},
+			page:         &audit.Page{},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":[],"links":{"self":"/audit?user_id=` + otherID.String() + `\u0026actor_id=crm\u0026action=user.deleted` +
+				`\u0026from=2024-05-01T02:00:00%2B02:00\u0026size=50\u0026cursor=Nw"}}`,
+		},
+		{
+			name:         "invalid user ID",
+			url:          "/users/123/audit",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid user ID"}`,
+		},
+		{
+			name:         "invalid action",
+			url:          "/audit?action=user.renamed",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid action param"}`,
+		},
+		{
+			name:         "invalid time",
+			url:          "/audit?to=yesterday",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid to param, expected RFC 3339"}`,
+		},
+		{
+			name:         "invalid cursor",
+			url:          "/audit?cursor=abc",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid cursor param"}`,
+		},
+		{
+			name:         "invalid size",
+			url:          "/audit?size=0",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid size param"}`,
+		},
+		{
+			name:          "forbidden",
+			url:           "/audit",
+			expectedQuery: &audit.Query{Limit: defaultPageSize},
+			serviceErr:    auth.ErrForbidden,
+			expectedCode:  http.StatusForbidden,
+			expectedBody:  `{"error":"forbidden"}`,
+		},
+		{
+			name:          "internal error",
+			url:           "/audit",
+			expectedQuery: &audit.Query{Limit: defaultPageSize},
+			serviceErr:    errors.New("db down"),
+			expectedCode:  http.StatusInternalServerError,
+			expectedBody:  `{"error":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(serviceMock.AuditServiceMock)
+			if tt.expectedQuery != nil {
+				mockService.On("List", mock.Anything, tt.expectedQuery).Return(tt.page, tt.serviceErr)
+			}
+
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			newAuditRouter(mockService).ServeHTTP(w, req)
+
+			body, _ := io.ReadAll(w.Body)
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, string(body))
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type AuthHTTPHandler struct {
+	validator   *validator.Validate
+	authService service.AuthPort
+}
+
+type loginRequest struct {
+	Email    string `json:"email" binding:"required" validate:"email"`
+	Password string `json:"password" binding:"required" validate:"required"`
+}
+
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required" validate:"required,max=255"`
+	Code     string `json:"code" binding:"required" validate:"required,max=32"`
+}
+
+// mfaChallengeResponse asks the client to complete the login with a code, see LoginMFA
+type mfaChallengeResponse struct {
+	MFAToken  string `json:"mfa_token"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+func NewAuthHTTPHandler(v *validator.Validate, authService service.AuthPort) *AuthHTTPHandler {
+	return &AuthHTTPHandler{
+		validator:   v,
+		authService: authService,
+	}
+}
+
+func (h *AuthHTTPHandler) Login(c *gin.Context) {
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.authService.Login(c.Request.Context(), req.Email, req.Password, c.ClientIP())
+	if err != nil {
+		var challenge *auth.MFAChallenge
+
+		switch {
+		case errors.As(err, &challenge):
+			c.JSON(http.StatusAccepted, mfaChallengeResponse{
+				MFAToken:  challenge.Token,
+				ExpiresIn: int(challenge.ExpiresIn.Seconds()),
+			})
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		case errors.Is(err, lockout.ErrLocked):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed logins, try again later"})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	respondToken(c, token)
+}
+
+// LoginMFA completes the login of a user with MFA enabled
+func (h *AuthHTTPHandler) LoginMFA(c *gin.Context) {
+	var req loginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.authService.LoginMFA(c.Request.Context(), req.MFAToken, req.Code, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired mfa token"})
+		case errors.Is(err, mfa.ErrInvalidCode):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		case errors.Is(err, lockout.ErrLocked):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed logins, try again later"})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	respondToken(c, token)
+}
+
+func respondToken(c *gin.Context, token *auth.Token) {
+	c.JSON(http.StatusOK, tokenResponse{
+		AccessToken:  token.AccessToken,
+		TokenType:    token.TokenType,
+		ExpiresIn:    int(token.ExpiresIn.Seconds()),
+		RefreshToken: token.RefreshToken,
+		IDToken:      token.IDToken,
+	})
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	serviceMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/applicaion/service"
+)
+
+func TestLogin(t *testing.T) {
+	t.Run("login", func(t *testing.T) {
+		reqBody := `{"email": "test@example.com", "password": "secure123"}`
+
+		s := new(serviceMock.AuthServiceMock)
+		s.On("Login", mock.Anything, "test@example.com", "secure123", "192.0.2.1").Return(&auth.Token{
+			AccessToken: "token",
+			TokenType:   "Bearer",
+			ExpiresIn:   15 * time.Minute,
+		}, nil)
+
+		authHandler := NewAuthHTTPHandler(validator.New(), s)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/auth/login", authHandler.Login)
+
+		req, err := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(reqBody)))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "192.0.2.1:1234"
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `{"access_token":"token","token_type":"Bearer","expires_in":900}`, recorder.Body.String())
+	})
+
+	t.Run("validation error", func(t *testing.T) {
+		reqBody := `{"email": "not-an-email", "password": "secure123"}`
+
+		s := new(serviceMock.AuthServiceMock)
+		authHandler := NewAuthHTTPHandler(validator.New(), s)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/auth/login", authHandler.Login)
+
+		req, err := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(reqBody)))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		reqBody := `{"email": "test@example.com", "password": "wrong-password"}`
+
+		s := new(serviceMock.AuthServiceMock)
+		s.On("Login", mock.Anything, "test@example.com", "wrong-password", mock.Anything).Return(nil, auth.ErrInvalidCredentials)
+
+		authHandler := NewAuthHTTPHandler(validator.New(), s)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/auth/login", authHandler.Login)
+
+		req, err := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(reqBody)))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Equal(t, `{"error":"invalid credentials"}`, recorder.Body.String())
+	})
+
+	t.Run("mfa required", func(t *testing.T) {
+		reqBody := `{"email": "test@example.com", "password": "secure123"}`
+
+		s := new(serviceMock.AuthServiceMock)
+		s.On("Login", mock.Anything, "test@example.com", "secure123", mock.Anything).Return(nil, &auth.MFAChallenge{Token: "challenge", ExpiresIn: 5 * time.Minute})
+
+		authHandler := NewAuthHTTPHandler(validator.New(), s)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/auth/login", authHandler.Login)
+
+		req, err := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(reqBody)))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusAccepted, recorder.Code)
+		assert.Equal(t, `{"mfa_token":"challenge","expires_in":300}`, recorder.Body.String())
+	})
+
+	t.Run("locked out", func(t *testing.T) {
+		reqBody := `{"email": "test@example.com", "password": "secure123"}`
+
+		s := new(serviceMock.AuthServiceMock)
+		s.On("Login", mock.Anything, "test@example.com", "secure123", mock.Anything).Return(nil, lockout.ErrLocked)
+
+		authHandler := NewAuthHTTPHandler(validator.New(), s)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/auth/login", authHandler.Login)
+
+		req, err := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(reqBody)))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		assert.Equal(t, `{"error":"too many failed logins, try again later"}`, recorder.Body.String())
+	})
+
+	t.Run("internal server error", func(t *testing.T) {
+		cfg := config.Load()
+		logger.Setup(cfg)
+
+		reqBody := `{"email": "test@example.com", "password": "secure123"}`
+
+		s := new(serviceMock.AuthServiceMock)
+		s.On("Login", mock.Anything, "test@example.com", "secure123", mock.Anything).Return(nil, errors.New("internal error"))
+
+		authHandler := NewAuthHTTPHandler(validator.New(), s)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/auth/login", authHandler.Login)
+
+		req, err := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(reqBody)))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.Equal(t, `{"error":"internal server error"}`, recorder.Body.String())
+	})
+}
+
+func TestLoginMFA(t *testing.T) {
+	tests := []struct {
+		name         string
+		reqBody      string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "login",
+			reqBody:      `{"mfa_token": "challenge", "code": "123456"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"access_token":"token","token_type":"Bearer","expires_in":900}`,
+		},
+		{
+			name:         "missing code",
+			reqBody:      `{"mfa_token": "challenge"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid mfa token",
+			reqBody:      `{"mfa_token": "challenge", "code": "123456"}`,
+			serviceErr:   auth.ErrInvalidToken,
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"invalid or expired mfa token"}`,
+		},
+		{
+			name:         "invalid code",
+			reqBody:      `{"mfa_token": "challenge", "code": "123456"}`,
+			serviceErr:   mfa.ErrInvalidCode,
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"invalid code"}`,
+		},
+		{
+			name:         "locked out",
+			reqBody:      `{"mfa_token": "challenge", "code": "123456"}`,
+			serviceErr:   lockout.ErrLocked,
+			expectedCode: http.StatusTooManyRequests,
+			expectedBody: `{"error":"too many failed logins, try again later"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.AuthServiceMock)
+			if tt.serviceErr != nil {
+				s.On("LoginMFA", mock.Anything, "challenge", "123456", "192.0.2.1").Return(nil, tt.serviceErr)
+			} else {
+				s.On("LoginMFA", mock.Anything, "challenge", "123456", "192.0.2.1").Return(&auth.Token{
+					AccessToken: "token",
+					TokenType:   "Bearer",
+					ExpiresIn:   15 * time.Minute,
+				}, nil)
+			}
+
+			authHandler := NewAuthHTTPHandler(validator.New(), s)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.POST("/auth/login/mfa", authHandler.LoginMFA)
+
+			req, err := http.NewRequest(http.MethodPost, "/auth/login/mfa", io.NopCloser(strings.NewReader(tt.reqBody)))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.RemoteAddr = "192.0.2.1:1234"
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type EmailHTTPHandler struct {
+	validator    *validator.Validate
+	emailService service.EmailPort
+}
+
+type emailChangeRequest struct {
+	Email string `json:"email" binding:"required" validate:"email"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required" validate:"required,max=255"`
+}
+
+func NewEmailHTTPHandler(v *validator.Validate, emailService service.EmailPort) *EmailHTTPHandler {
+	return &EmailHTTPHandler{
+		validator:    v,
+		emailService: emailService,
+	}
+}
+
+// RequestEmailChange sends a verification to the new address, the email is changed once the token from it is verified
+func (h *EmailHTTPHandler) RequestEmailChange(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req emailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.emailService.RequestChange(c.Request.Context(), userID, req.Email); err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, user.ErrEmailAlreadyExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "email already exists"})
+		case errors.Is(err, auth.ErrUnauthenticated):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+		case errors.Is(err, auth.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, "ok")
+}
+
+// VerifyEmail is public, the token itself proves the ownership of the address
+func (h *EmailHTTPHandler) VerifyEmail(c *gin.Context) {
+	var req verifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.emailService.Verify(c.Request.Context(), req.Token); err != nil {
+		switch {
+		case errors.Is(err, verification.ErrInvalidToken):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired token"})
+		case errors.Is(err, user.ErrEmailAlreadyExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "email already exists"})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+	serviceMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/applicaion/service"
+)
+
+func newEmailRouter(s *serviceMock.EmailServiceMock) *gin.Engine {
+	emailHandler := NewEmailHTTPHandler(validator.New(), s)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/users/:id/email-change", emailHandler.RequestEmailChange)
+	router.POST("/auth/verify-email", emailHandler.VerifyEmail)
+
+	return router
+}
+
+func TestRequestEmailChange(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "verification sent",
+			body:         `{"email": "new@example.com"}`,
+			expectedCode: http.StatusAccepted,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:         "email already exists",
+			body:         `{"email": "new@example.com"}`,
+			serviceErr:   user.ErrEmailAlreadyExists,
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"error":"email already exists"}`,
+		},
+		{
+			name:         "forbidden",
+			body:         `{"email": "new@example.com"}`,
+			serviceErr:   auth.ErrForbidden,
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"forbidden"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New().String()
+
+			s := new(serviceMock.EmailServiceMock)
+			s.On("RequestChange", mock.Anything, userID, "new@example.com").Return(tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/email-change", userID), strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			newEmailRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+
+	t.Run("invalid email", func(t *testing.T) {
+		s := new(serviceMock.EmailServiceMock)
+
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/email-change", uuid.New()), strings.NewReader(`{"email": "not-an-email"}`))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		newEmailRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		s.AssertNotCalled(t, "RequestChange", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestVerifyEmail(t *testing.T) {
+	tests := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "email verified",
+			expectedCode: http.StatusOK,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:         "invalid token",
+			serviceErr:   verification.ErrInvalidToken,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid or expired token"}`,
+		},
+		{
+			name:         "email taken in the meantime",
+			serviceErr:   user.ErrEmailAlreadyExists,
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"error":"email already exists"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.EmailServiceMock)
+			s.On("Verify", mock.Anything, "secret").Return(tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodPost, "/auth/verify-email", strings.NewReader(`{"token": "secret"}`))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			newEmailRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type LockoutHTTPHandler struct {
+	lockoutService service.LockoutPort
+}
+
+func NewLockoutHTTPHandler(lockoutService service.LockoutPort) *LockoutHTTPHandler {
+	return &LockoutHTTPHandler{
+		lockoutService: lockoutService,
+	}
+}
+
+func (h *LockoutHTTPHandler) GetUserLockout(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	state, err := h.lockoutService.GetUserLockout(c.Request.Context(), userID)
+	if err != nil {
+		respondLockoutError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+func (h *LockoutHTTPHandler) UnlockUser(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	if err := h.lockoutService.UnlockUser(c.Request.Context(), userID); err != nil {
+		respondLockoutError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+func (h *LockoutHTTPHandler) UnlockIP(c *gin.Context) {
+	if err := h.lockoutService.UnlockIP(c.Request.Context(), c.Param("ip")); err != nil {
+		respondLockoutError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+func respondLockoutError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, user.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, lockout.ErrInvalidIP):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IP address"})
+	case errors.Is(err, auth.ErrUnauthenticated):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+	case errors.Is(err, auth.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	default:
+		logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	serviceMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/applicaion/service"
+)
+
+func newLockoutRouter(s *serviceMock.LockoutServiceMock) *gin.Engine {
+	lockoutHandler := NewLockoutHTTPHandler(s)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/users/:id/lockout", lockoutHandler.GetUserLockout)
+	router.DELETE("/users/:id/lockout", lockoutHandler.UnlockUser)
+	router.DELETE("/lockouts/ip/:ip", lockoutHandler.UnlockIP)
+
+	return router
+}
+
+func TestGetUserLockout(t *testing.T) {
+	t.Run("get", func(t *testing.T) {
+		userID := uuid.New().String()
+		lastFailureAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		lockedUntil := lastFailureAt.Add(time.Minute)
+
+		s := new(serviceMock.LockoutServiceMock)
+		s.On("GetUserLockout", mock.Anything, userID).Return(&lockout.State{Failures: 5, LastFailureAt: &lastFailureAt, LockedUntil: &lockedUntil}, nil)
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s/lockout", userID), nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		newLockoutRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `{"failures":5,"last_failure_at":"2024-01-02T03:04:05Z","locked_until":"2024-01-02T03:05:05Z"}`, recorder.Body.String())
+	})
+
+	t.Run("invalid user id", func(t *testing.T) {
+		s := new(serviceMock.LockoutServiceMock)
+
+		req, err := http.NewRequest(http.MethodGet, "/users/invalid/lockout", nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		newLockoutRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		s.AssertNotCalled(t, "GetUserLockout", mock.Anything, mock.Anything)
+	})
+}
+
+func TestUnlockUser(t *testing.T) {
+	tests := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "unlocked",
+			expectedCode: http.StatusOK,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:         "user not found",
+			serviceErr:   user.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"user not found"}`,
+		},
+		{
+			name:         "unauthenticated",
+			serviceErr:   auth.ErrUnauthenticated,
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"missing credentials"}`,
+		},
+		{
+			name:         "forbidden",
+			serviceErr:   auth.ErrForbidden,
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"forbidden"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New().String()
+
+			s := new(serviceMock.LockoutServiceMock)
+			s.On("UnlockUser", mock.Anything, userID).Return(tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s/lockout", userID), nil)
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			newLockoutRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestUnlockIP(t *testing.T) {
+	tests := []struct {
+		name         string
+		ip           string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "unlocked",
+			ip:           "192.0.2.1",
+			expectedCode: http.StatusOK,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:         "ipv6",
+			ip:           "2001:db8::1",
+			expectedCode: http.StatusOK,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:         "invalid ip",
+			ip:           "not-an-ip",
+			serviceErr:   lockout.ErrInvalidIP,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid IP address"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.LockoutServiceMock)
+			s.On("UnlockIP", mock.Anything, tt.ip).Return(tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodDelete, "/lockouts/ip/"+tt.ip, nil)
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			newLockoutRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	serviceMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/applicaion/service"
+)
+
+func newMFARouter(s *serviceMock.MFAServiceMock) *gin.Engine {
+	mfaHandler := NewMFAHTTPHandler(validator.New(), s)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/users/:id/mfa", mfaHandler.Enroll)
+	router.POST("/users/:id/mfa/confirm", mfaHandler.Confirm)
+	router.POST("/users/:id/mfa/disable", mfaHandler.Disable)
+	router.POST("/users/:id/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+	router.DELETE("/users/:id/mfa", mfaHandler.Reset)
+
+	return router
+}
+
+func TestEnrollMFA(t *testing.T) {
+	t.Run("enroll", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		s := new(serviceMock.MFAServiceMock)
+		s.On("Enroll", mock.Anything, userID).Return(&service.MFAEnrollment{Secret: "SECRET", URI: "otpauth://totp/x"}, nil)
+
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/mfa", userID), nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		newMFARouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `{"secret":"SECRET","otpauth_uri":"otpauth://totp/x"}`, recorder.Body.String())
+	})
+
+	t.Run("already enabled", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		s := new(serviceMock.MFAServiceMock)
+		s.On("Enroll", mock.Anything, userID).Return(nil, mfa.ErrAlreadyEnabled)
+
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/mfa", userID), nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		newMFARouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		assert.Equal(t, `{"error":"mfa already enabled"}`, recorder.Body.String())
+	})
+
+	t.Run("invalid user id", func(t *testing.T) {
+		s := new(serviceMock.MFAServiceMock)
+
+		req, err := http.NewRequest(http.MethodPost, "/users/invalid/mfa", nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		newMFARouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		s.AssertNotCalled(t, "Enroll", mock.Anything, mock.Anything)
+	})
+}
+
+func TestConfirmMFA(t *testing.T) {
+	tests := []struct {
+		name         string
+		reqBody      string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "confirm",
+			reqBody:      `{"code": "123456"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"recovery_codes":["first","second"]}`,
+		},
+		{
+			name:         "missing code",
+			reqBody:      `{}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "not enrolled",
+			reqBody:      `{"code": "123456"}`,
+			serviceErr:   mfa.ErrNotEnrolled,
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"error":"mfa not enrolled"}`,
+		},
+		{
+			name:         "invalid code",
+			reqBody:      `{"code": "123456"}`,
+			serviceErr:   mfa.ErrInvalidCode,
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"invalid code"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New().String()
+
+			s := new(serviceMock.MFAServiceMock)
+			if tt.serviceErr != nil {
+				s.On("Confirm", mock.Anything, userID, "123456").Return(nil, tt.serviceErr)
+			} else {
+				s.On("Confirm", mock.Anything, userID, "123456").Return([]string{"first", "second"}, nil)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/mfa/confirm", userID), io.NopCloser(strings.NewReader(tt.reqBody)))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			newMFARouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestDisableMFA(t *testing.T) {
+	tests := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "disabled",
+			expectedCode: http.StatusOK,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:         "required by the role",
+			serviceErr:   user.ErrMFARequired,
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"error":"mfa is required for the role"}`,
+		},
+		{
+			name:         "forbidden",
+			serviceErr:   auth.ErrForbidden,
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"forbidden"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New().String()
+
+			s := new(serviceMock.MFAServiceMock)
+			s.On("Disable", mock.Anything, userID, "123456").Return(tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/mfa/disable", userID), io.NopCloser(strings.NewReader(`{"code": "123456"}`)))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			newMFARouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	userID := uuid.New().String()
+
+	s := new(serviceMock.MFAServiceMock)
+	s.On("RegenerateRecoveryCodes", mock.Anything, userID, "abcdefgh-ijklmnop").Return([]string{"first"}, nil)
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/mfa/recovery-codes", userID), io.NopCloser(strings.NewReader(`{"code": "abcdefgh-ijklmnop"}`)))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	newMFARouter(s).ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"recovery_codes":["first"]}`, recorder.Body.String())
+}
+
+func TestResetMFA(t *testing.T) {
+	tests := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "reset",
+			expectedCode: http.StatusOK,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:         "user not found",
+			serviceErr:   user.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"user not found"}`,
+		},
+		{
+			name:         "unauthenticated",
+			serviceErr:   auth.ErrUnauthenticated,
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"missing credentials"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New().String()
+
+			s := new(serviceMock.MFAServiceMock)
+			s.On("Reset", mock.Anything, userID).Return(tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s/mfa", userID), nil)
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			newMFARouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
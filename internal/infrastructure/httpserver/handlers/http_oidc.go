@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/jwt"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+// OAuthHTTPHandler serves the OpenID Connect provider endpoints, errors of the OAuth flow are reported with the codes of RFC 6749
+type OAuthHTTPHandler struct {
+	validator   *validator.Validate
+	oidcService service.OIDCPort
+	authService service.AuthPort
+	issuer      string
+}
+
+//go:embed templates/authorize.html
+var loginHTML string
+
+var loginTemplate = template.Must(template.New("authorize").Parse(loginHTML))
+
+// loginPage is rendered by the authorization endpoint, Params keep the authorization request between the page and its form
+type loginPage struct {
+	ClientName string
+	Scopes     []string
+	Params     map[string]string
+	Email      string
+	MFAToken   string
+	Error      string
+}
+
+type registerClientRequest struct {
+	Name         string   `json:"name" binding:"required" validate:"required,max=255"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required" validate:"required,min=1,max=10,dive,required,max=2048"`
+	Public       bool     `json:"public"`
+}
+
+type clientResponse struct {
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+}
+
+type discoveryResponse struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+func NewOAuthHTTPHandler(v *validator.Validate, oidcService service.OIDCPort, authService service.AuthPort, issuer string) *OAuthHTTPHandler {
+	return &OAuthHTTPHandler{
+		validator:   v,
+		oidcService: oidcService,
+		authService: authService,
+		issuer:      strings.TrimSuffix(issuer, "/"),
+	}
+}
+
+func (h *OAuthHTTPHandler) Discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, discoveryResponse{
+		Issuer:                            h.issuer,
+		AuthorizationEndpoint:             h.issuer + "/oauth/authorize",
+		TokenEndpoint:                     h.issuer + "/oauth/token",
+		UserInfoEndpoint:                  h.issuer + "/oauth/userinfo",
+		JWKSURI:                           h.issuer + "/oauth/jwks",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{jwt.AlgRS256, jwt.AlgEdDSA},
+		ScopesSupported:                   oidc.Scopes,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{oidc.CodeChallengeMethodS256},
+		ClaimsSupported:                   []string{"sub", "name", "given_name", "family_name", "email", "email_verified", "phone_number"},
+	})
+}
+
+func (h *OAuthHTTPHandler) JWKS(c *gin.Context) {
+	keys, err := h.oidcService.PublicKeys(c.Request.Context())
+	if err != nil {
+		logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		return
+	}
+
+	set := jwt.JWKS{Keys: make([]jwt.JWK, 0, len(keys))}
+
+	for _, key := range keys {
+		jwk, err := jwt.NewJWK(key.ID, key.Key)
+		if err != nil {
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+			return
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	c.JSON(http.StatusOK, set)
+}
+
+// Authorize shows the login and consent page, which posts back to Consent
+func (h *OAuthHTTPHandler) Authorize(c *gin.Context) {
+	req := authorizationRequest(c)
+
+	client, scopes, err := h.oidcService.Prepare(c.Request.Context(), req)
+	if err != nil {
+		respondAuthorizeError(c, req.RedirectURI, err)
+		return
+	}
+
+	renderLogin(c, http.StatusOK, newLoginPage(c, client, scopes))
+}
+
+// Consent logs the user in with the posted credentials and redirects them back to the client with an authorization code,
+// unless they deny it. Users with MFA enabled are asked for their code on a second page, as the login endpoint does.
+func (h *OAuthHTTPHandler) Consent(c *gin.Context) {
+	req := authorizationRequest(c)
+
+	client, scopes, err := h.oidcService.Prepare(c.Request.Context(), req)
+	if err != nil {
+		respondAuthorizeError(c, req.RedirectURI, err)
+		return
+	}
+
+	if c.PostForm("decision") != "allow" {
+		redirectBack(c, req.RedirectURI, "error", "access_denied")
+		return
+	}
+
+	var identity auth.Identity
+
+	if challenge := c.PostForm("mfa_token"); challenge != "" {
+		identity, err = h.authService.AuthenticateMFA(c.Request.Context(), challenge, c.PostForm("code"), c.ClientIP())
+	} else {
+		identity, err = h.authService.Authenticate(c.Request.Context(), c.PostForm("email"), c.PostForm("password"), c.ClientIP())
+	}
+
+	if err != nil {
+		page := newLoginPage(c, client, scopes)
+
+		var challenge *auth.MFAChallenge
+
+		switch {
+		case errors.As(err, &challenge):
+			page.MFAToken = challenge.Token
+			renderLogin(c, http.StatusOK, page)
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			page.Error = "Invalid email or password."
+			renderLogin(c, http.StatusUnauthorized, page)
+		case errors.Is(err, mfa.ErrInvalidCode):
+			page.MFAToken = c.PostForm("mfa_token")
+			page.Error = "Invalid authentication code."
+			renderLogin(c, http.StatusUnauthorized, page)
+		case errors.Is(err, auth.ErrInvalidToken):
+			page.Error = "The sign in has expired, please try again."
+			renderLogin(c, http.StatusUnauthorized, page)
+		case errors.Is(err, lockout.ErrLocked):
+			page.Error = "Too many failed sign in attempts, try again later."
+			renderLogin(c, http.StatusTooManyRequests, page)
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	code, err := h.oidcService.Authorize(auth.WithIdentity(c.Request.Context(), identity), req)
+	if err != nil {
+		respondAuthorizeError(c, req.RedirectURI, err)
+		return
+	}
+
+	redirectBack(c, req.RedirectURI, "code", code)
+}
+
+// Token exchanges an authorization code, clients authenticate with HTTP Basic or with the form parameters
+func (h *OAuthHTTPHandler) Token(c *gin.Context) {
+	req := &service.TokenRequest{
+		GrantType:    c.PostForm("grant_type"),
+		Code:         c.PostForm("code"),
+		RedirectURI:  c.PostForm("redirect_uri"),
+		ClientID:     c.PostForm("client_id"),
+		ClientSecret: c.PostForm("client_secret"),
+		CodeVerifier: c.PostForm("code_verifier"),
+	}
+
+	if clientID, secret, ok := c.Request.BasicAuth(); ok {
+		// both are form encoded before being put in the header
+		req.ClientID, _ = url.QueryUnescape(clientID)
+		req.ClientSecret, _ = url.QueryUnescape(secret)
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	token, err := h.oidcService.Exchange(c.Request.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, oidc.ErrInvalidClient):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		case errors.Is(err, oidc.ErrInvalidGrant):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+		case errors.Is(err, oidc.ErrUnsupportedGrantType):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		case errors.Is(err, oidc.ErrInvalidRequest):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	respondToken(c, token)
+}
+
+// UserInfo accepts the access tokens issued to clients by the token endpoint, errors are reported as RFC 6750 describes
+func (h *OAuthHTTPHandler) UserInfo(c *gin.Context) {
+	accessToken, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found || accessToken == "" {
+		c.Header("WWW-Authenticate", `Bearer realm="usermanagement"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+		return
+	}
+
+	claims, err := h.oidcService.UserInfo(c.Request.Context(), accessToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			c.Header("WWW-Authenticate", `Bearer realm="usermanagement", error="invalid_token"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+
+		logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		return
+	}
+
+	c.JSON(http.StatusOK, claims)
+}
+
+func (h *OAuthHTTPHandler) RegisterClient(c *gin.Context) {
+	var req registerClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, secret, err := h.oidcService.RegisterClient(c.Request.Context(), req.Name, req.RedirectURIs, req.Public)
+	if err != nil {
+		respondClientError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, clientResponse{
+		ClientID:     client.ID.String(),
+		ClientSecret: secret,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+	})
+}
+
+func (h *OAuthHTTPHandler) DeleteClient(c *gin.Context) {
+	clientID := c.Param("id")
+	if _, err := uuid.Parse(clientID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID"})
+		return
+	}
+
+	if err := h.oidcService.DeleteClient(c.Request.Context(), clientID); err != nil {
+		respondClientError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// authorizationParams are passed from the page to the form, which posts them back along with the credentials
+var authorizationParams = []string{
+	"response_type", "client_id", "redirect_uri", "scope", "nonce", "code_challenge", "code_challenge_method", "state",
+}
+
+// authorizationRequest reads the parameters from the query of the page or from the posted form
+func authorizationRequest(c *gin.Context) *service.AuthorizationRequest {
+	return &service.AuthorizationRequest{
+		ResponseType:        authorizationParam(c, "response_type"),
+		ClientID:            authorizationParam(c, "client_id"),
+		RedirectURI:         authorizationParam(c, "redirect_uri"),
+		Scope:               authorizationParam(c, "scope"),
+		Nonce:               authorizationParam(c, "nonce"),
+		CodeChallenge:       authorizationParam(c, "code_challenge"),
+		CodeChallengeMethod: authorizationParam(c, "code_challenge_method"),
+	}
+}
+
+func authorizationParam(c *gin.Context, name string) string {
+	if c.Request.Method == http.MethodPost {
+		return c.PostForm(name)
+	}
+
+	return c.Query(name)
+}
+
+func newLoginPage(c *gin.Context, client *oidc.Client, scopes []string) loginPage {
+	params := make(map[string]string, len(authorizationParams))
+	for _, name := range authorizationParams {
+		if value := authorizationParam(c, name); value != "" {
+			params[name] = value
+		}
+	}
+
+	return loginPage{
+		ClientName: client.Name,
+		Scopes:     scopes,
+		Params:     params,
+		Email:      c.PostForm("email"),
+	}
+}
+
+func renderLogin(c *gin.Context, status int, page loginPage) {
+	var body bytes.Buffer
+	if err := loginTemplate.Execute(&body, page); err != nil {
+		logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		return
+	}
+
+	// the page asks for credentials, it must not be framed by other sites nor cached
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "default-src 'none'; form-action 'self'; frame-ancestors 'none'")
+	c.Header("Cache-Control", "no-store")
+	c.Data(status, "text/html; charset=utf-8", body.Bytes())
+}
+
+func respondAuthorizeError(c *gin.Context, redirectURI string, err error) {
+	switch {
+	// the client can not be told about these, as its redirect URI is unknown
+	case errors.Is(err, oidc.ErrInvalidClient):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "unknown client"})
+	case errors.Is(err, oidc.ErrInvalidRedirectURI):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "redirect_uri is not registered for the client"})
+	case errors.Is(err, oidc.ErrUnsupportedResponseType):
+		redirectBack(c, redirectURI, "error", "unsupported_response_type")
+	case errors.Is(err, oidc.ErrInvalidScope):
+		redirectBack(c, redirectURI, "error", "invalid_scope")
+	case errors.Is(err, oidc.ErrInvalidRequest):
+		redirectBack(c, redirectURI, "error", "invalid_request")
+	case errors.Is(err, auth.ErrUnauthenticated):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+	case errors.Is(err, auth.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	default:
+		logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+	}
+}
+
+// redirectBack sends the user agent to the redirect URI of the client, which has been checked to be a registered one
+func redirectBack(c *gin.Context, redirectURI, key, value string) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		return
+	}
+
+	query := u.Query()
+	query.Set(key, value)
+
+	if state := authorizationParam(c, "state"); state != "" {
+		query.Set("state", state)
+	}
+
+	u.RawQuery = query.Encode()
+
+	c.Redirect(http.StatusFound, u.String())
+}
+
+func respondClientError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, oidc.ErrClientNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "client not found"})
+	case errors.Is(err, oidc.ErrInvalidRedirectURI):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid redirect uri"})
+	case errors.Is(err, user.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, auth.ErrUnauthenticated):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+	case errors.Is(err, auth.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	default:
+		logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+	}
+}
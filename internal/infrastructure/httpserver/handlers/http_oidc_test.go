@@ -0,0 +1,590 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/jwt"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	serviceMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/applicaion/service"
+)
+
+const authorizeQuery = "/oauth/authorize?response_type=code&client_id=c&redirect_uri=https%3A%2F%2Fapp.example.com%2Fcallback%3Ftab%3D1&state=xyz"
+
+var authorizeRequest = &service.AuthorizationRequest{
+	ResponseType: "code",
+	ClientID:     "c",
+	RedirectURI:  "https://app.example.com/callback?tab=1",
+}
+
+// authorizeForm holds the parameters the login page posts back, as they are in authorizeQuery
+func authorizeForm() url.Values {
+	return url.Values{
+		"response_type": {"code"},
+		"client_id":     {"c"},
+		"redirect_uri":  {"https://app.example.com/callback?tab=1"},
+		"state":         {"xyz"},
+	}
+}
+
+func newOAuthRouter(s *serviceMock.OIDCServiceMock) *gin.Engine {
+	return newOAuthRouterWithLogin(s, new(serviceMock.AuthServiceMock))
+}
+
+func newOAuthRouterWithLogin(s *serviceMock.OIDCServiceMock, a *serviceMock.AuthServiceMock) *gin.Engine {
+	oauthHandler := NewOAuthHTTPHandler(validator.New(), s, a, "https://id.example.com/")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/.well-known/openid-configuration", oauthHandler.Discovery)
+	router.GET("/oauth/jwks", oauthHandler.JWKS)
+	router.GET("/oauth/authorize", oauthHandler.Authorize)
+	router.POST("/oauth/authorize", oauthHandler.Consent)
+	router.POST("/oauth/token", oauthHandler.Token)
+	router.GET("/oauth/userinfo", oauthHandler.UserInfo)
+	router.POST("/oauth/clients", oauthHandler.RegisterClient)
+	router.DELETE("/oauth/clients/:id", oauthHandler.DeleteClient)
+
+	return router
+}
+
+func TestDiscovery(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/.well-known/openid-configuration", nil)
+	assert.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	newOAuthRouter(new(serviceMock.OIDCServiceMock)).ServeHTTP(recorder, req)
+
+	var document discoveryResponse
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &document))
+	assert.Equal(t, "https://id.example.com", document.Issuer)
+	assert.Equal(t, "https://id.example.com/oauth/token", document.TokenEndpoint)
+	assert.Equal(t, "https://id.example.com/oauth/jwks", document.JWKSURI)
+	assert.Equal(t, []string{"S256"}, document.CodeChallengeMethodsSupported)
+}
+
+func TestJWKS(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	pub, _, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	t.Run("keys", func(t *testing.T) {
+		s := new(serviceMock.OIDCServiceMock)
+		s.On("PublicKeys", mock.Anything).Return([]oidc.PublicKey{{ID: "kid", Key: pub}}, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/oauth/jwks", nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		newOAuthRouter(s).ServeHTTP(recorder, req)
+
+		var set jwt.JWKS
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &set))
+		assert.Len(t, set.Keys, 1)
+		assert.Equal(t, "kid", set.Keys[0].KeyID)
+		assert.Equal(t, jwt.AlgEdDSA, set.Keys[0].Algorithm)
+	})
+
+	t.Run("internal error", func(t *testing.T) {
+		s := new(serviceMock.OIDCServiceMock)
+		s.On("PublicKeys", mock.Anything).Return(nil, errors.New("db down"))
+
+		req, err := http.NewRequest(http.MethodGet, "/oauth/jwks", nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		newOAuthRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	})
+}
+
+func TestAuthorize(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	client := &oidc.Client{ID: domain.NewID(), Name: "billing <app>"}
+
+	tests := []struct {
+		name             string
+		serviceErr       error
+		expectedCode     int
+		expectedLocation string
+		expectedBody     string
+	}{
+		{
+			name:         "login page",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:             "error reported to the client",
+			serviceErr:       oidc.ErrInvalidScope,
+			expectedCode:     http.StatusFound,
+			expectedLocation: "https://app.example.com/callback?error=invalid_scope&state=xyz&tab=1",
+		},
+		{
+			name:         "unknown client",
+			serviceErr:   oidc.ErrInvalidClient,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid_request","error_description":"unknown client"}`,
+		},
+		{
+			name:         "unregistered redirect URI",
+			serviceErr:   oidc.ErrInvalidRedirectURI,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid_request","error_description":"redirect_uri is not registered for the client"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.OIDCServiceMock)
+			if tt.serviceErr != nil {
+				s.On("Prepare", mock.Anything, authorizeRequest).Return(nil, nil, tt.serviceErr)
+			} else {
+				s.On("Prepare", mock.Anything, authorizeRequest).Return(client, []string{oidc.ScopeOpenID, oidc.ScopeEmail}, nil)
+			}
+
+			req, err := http.NewRequest(http.MethodGet, authorizeQuery, nil)
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			newOAuthRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedLocation, recorder.Header().Get("Location"))
+			s.AssertNotCalled(t, "Authorize", mock.Anything, mock.Anything)
+
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, recorder.Body.String())
+			}
+
+			if tt.expectedCode == http.StatusOK {
+				body := recorder.Body.String()
+				assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+				assert.Equal(t, "DENY", recorder.Header().Get("X-Frame-Options"))
+				assert.Contains(t, body, "Sign in to billing &lt;app&gt;")
+				assert.Contains(t, body, "<li>email</li>")
+				assert.Contains(t, body, `<input type="hidden" name="redirect_uri" value="https://app.example.com/callback?tab=1">`)
+				assert.Contains(t, body, `<input type="hidden" name="state" value="xyz">`)
+				assert.Contains(t, body, `name="password"`)
+			}
+		})
+	}
+}
+
+func TestConsent(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	client := &oidc.Client{ID: domain.NewID(), Name: "billing"}
+	identity := auth.Identity{UserID: domain.NewID(), Role: user.RoleSelf}
+
+	tests := []struct {
+		name             string
+		form             url.Values
+		setup            func(a *serviceMock.AuthServiceMock)
+		authorized       bool
+		expectedCode     int
+		expectedLocation string
+		expectedBody     string
+	}{
+		{
+			name: "code",
+			form: url.Values{"email": {"test@example.com"}, "password": {"secure123"}, "decision": {"allow"}},
+			setup: func(a *serviceMock.AuthServiceMock) {
+				a.On("Authenticate", mock.Anything, "test@example.com", "secure123", mock.Anything).Return(identity, nil)
+			},
+			authorized:       true,
+			expectedCode:     http.StatusFound,
+			expectedLocation: "https://app.example.com/callback?code=abc&state=xyz&tab=1",
+		},
+		{
+			name:             "denied",
+			form:             url.Values{"decision": {"deny"}},
+			expectedCode:     http.StatusFound,
+			expectedLocation: "https://app.example.com/callback?error=access_denied&state=xyz&tab=1",
+		},
+		{
+			name: "wrong password",
+			form: url.Values{"email": {"test@example.com"}, "password": {"wrong"}, "decision": {"allow"}},
+			setup: func(a *serviceMock.AuthServiceMock) {
+				a.On("Authenticate", mock.Anything, "test@example.com", "wrong", mock.Anything).Return(auth.Identity{}, auth.ErrInvalidCredentials)
+			},
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: "Invalid email or password.",
+		},
+		{
+			name: "locked out",
+			form: url.Values{"email": {"test@example.com"}, "password": {"secure123"}, "decision": {"allow"}},
+			setup: func(a *serviceMock.AuthServiceMock) {
+				a.On("Authenticate", mock.Anything, "test@example.com", "secure123", mock.Anything).Return(auth.Identity{}, lockout.ErrLocked)
+			},
+			expectedCode: http.StatusTooManyRequests,
+			expectedBody: "Too many failed sign in attempts",
+		},
+		{
+			name: "user with mfa is asked for the code",
+			form: url.Values{"email": {"test@example.com"}, "password": {"secure123"}, "decision": {"allow"}},
+			setup: func(a *serviceMock.AuthServiceMock) {
+				a.On("Authenticate", mock.Anything, "test@example.com", "secure123", mock.Anything).Return(auth.Identity{}, &auth.MFAChallenge{Token: "challenge"})
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `<input type="hidden" name="mfa_token" value="challenge">`,
+		},
+		{
+			name: "code with mfa",
+			form: url.Values{"mfa_token": {"challenge"}, "code": {"123456"}, "decision": {"allow"}},
+			setup: func(a *serviceMock.AuthServiceMock) {
+				a.On("AuthenticateMFA", mock.Anything, "challenge", "123456", mock.Anything).Return(identity, nil)
+			},
+			authorized:       true,
+			expectedCode:     http.StatusFound,
+			expectedLocation: "https://app.example.com/callback?code=abc&state=xyz&tab=1",
+		},
+		{
+			name: "wrong mfa code",
+			form: url.Values{"mfa_token": {"challenge"}, "code": {"000000"}, "decision": {"allow"}},
+			setup: func(a *serviceMock.AuthServiceMock) {
+				a.On("AuthenticateMFA", mock.Anything, "challenge", "000000", mock.Anything).Return(auth.Identity{}, mfa.ErrInvalidCode)
+			},
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: "Invalid authentication code.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.OIDCServiceMock)
+			s.On("Prepare", mock.Anything, authorizeRequest).Return(client, []string{oidc.ScopeOpenID}, nil)
+			s.On("Authorize", mock.MatchedBy(func(ctx context.Context) bool {
+				authorized, ok := auth.IdentityFromContext(ctx)
+				return ok && authorized == identity
+			}), authorizeRequest).Return("abc", nil)
+
+			a := new(serviceMock.AuthServiceMock)
+			if tt.setup != nil {
+				tt.setup(a)
+			}
+
+			form := authorizeForm()
+			for key, values := range tt.form {
+				form[key] = values
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(form.Encode()))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			recorder := httptest.NewRecorder()
+			newOAuthRouterWithLogin(s, a).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedLocation, recorder.Header().Get("Location"))
+			assert.Contains(t, recorder.Body.String(), tt.expectedBody)
+			a.AssertExpectations(t)
+
+			if tt.authorized {
+				s.AssertNumberOfCalls(t, "Authorize", 1)
+			} else {
+				s.AssertNotCalled(t, "Authorize", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestToken(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {"abc"},
+		"redirect_uri":  {"https://app.example.com/callback"},
+		"code_verifier": {"verifier"},
+	}
+
+	tests := []struct {
+		name         string
+		basicAuth    bool
+		token        *auth.Token
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "tokens",
+			basicAuth:    true,
+			token:        &auth.Token{AccessToken: "token", TokenType: "Bearer", ExpiresIn: 15 * time.Minute, IDToken: "id"},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"access_token":"token","token_type":"Bearer","expires_in":900,"id_token":"id"}`,
+		},
+		{
+			name:         "invalid client",
+			basicAuth:    true,
+			serviceErr:   oidc.ErrInvalidClient,
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"invalid_client"}`,
+		},
+		{
+			name:         "invalid grant",
+			serviceErr:   oidc.ErrInvalidGrant,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid_grant"}`,
+		},
+		{
+			name:         "internal error",
+			serviceErr:   errors.New("db down"),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"error":"server_error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := &service.TokenRequest{
+				GrantType:    "authorization_code",
+				Code:         "abc",
+				RedirectURI:  "https://app.example.com/callback",
+				ClientID:     "client",
+				CodeVerifier: "verifier",
+			}
+
+			body := url.Values{}
+			for k, v := range form {
+				body[k] = v
+			}
+
+			if tt.basicAuth {
+				expected.ClientSecret = "s3cret/+"
+			} else {
+				body.Set("client_id", "client")
+			}
+
+			s := new(serviceMock.OIDCServiceMock)
+			s.On("Exchange", mock.Anything, expected).Return(tt.token, tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodPost, "/oauth/token", io.NopCloser(strings.NewReader(body.Encode())))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			if tt.basicAuth {
+				req.SetBasicAuth("client", url.QueryEscape("s3cret/+"))
+			}
+
+			recorder := httptest.NewRecorder()
+			newOAuthRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+			assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestUserInfo(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	tests := []struct {
+		name          string
+		authorization string
+		claims        *oidc.UserClaims
+		serviceErr    error
+		expectedCode  int
+		expectedBody  string
+		expectedError string
+	}{
+		{
+			name:          "claims",
+			authorization: "Bearer access-token",
+			claims:        &oidc.UserClaims{Subject: "sub", Email: "john@example.com"},
+			expectedCode:  http.StatusOK,
+			expectedBody:  `{"sub":"sub","email":"john@example.com"}`,
+		},
+		{
+			name:          "missing token",
+			expectedCode:  http.StatusUnauthorized,
+			expectedBody:  `{"error":"missing credentials"}`,
+			expectedError: `Bearer realm="usermanagement"`,
+		},
+		{
+			name:          "invalid token",
+			authorization: "Bearer access-token",
+			serviceErr:    auth.ErrInvalidToken,
+			expectedCode:  http.StatusUnauthorized,
+			expectedBody:  `{"error":"invalid token"}`,
+			expectedError: `Bearer realm="usermanagement", error="invalid_token"`,
+		},
+		{
+			name:          "service error",
+			authorization: "Bearer access-token",
+			serviceErr:    errors.New("db error"),
+			expectedCode:  http.StatusInternalServerError,
+			expectedBody:  `{"error":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.OIDCServiceMock)
+			s.On("UserInfo", mock.Anything, "access-token").Return(tt.claims, tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodGet, "/oauth/userinfo", nil)
+			assert.NoError(t, err)
+
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			recorder := httptest.NewRecorder()
+			newOAuthRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+			assert.Equal(t, tt.expectedError, recorder.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestRegisterClient(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	client := &oidc.Client{ID: domain.NewID(), Name: "app", RedirectURIs: []string{"https://app.example.com/callback"}}
+
+	tests := []struct {
+		name         string
+		reqBody      string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "register",
+			reqBody:      `{"name": "app", "redirect_uris": ["https://app.example.com/callback"]}`,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"client_id":"` + client.ID.String() + `","client_secret":"secret","name":"app","redirect_uris":["https://app.example.com/callback"]}`,
+		},
+		{
+			name:         "missing redirect URIs",
+			reqBody:      `{"name": "app", "redirect_uris": []}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid redirect URI",
+			reqBody:      `{"name": "app", "redirect_uris": ["https://app.example.com/callback"]}`,
+			serviceErr:   oidc.ErrInvalidRedirectURI,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid redirect uri"}`,
+		},
+		{
+			name:         "forbidden",
+			reqBody:      `{"name": "app", "redirect_uris": ["https://app.example.com/callback"]}`,
+			serviceErr:   auth.ErrForbidden,
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"forbidden"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.OIDCServiceMock)
+			if tt.serviceErr != nil {
+				s.On("RegisterClient", mock.Anything, "app", client.RedirectURIs, false).Return(nil, "", tt.serviceErr)
+			} else {
+				s.On("RegisterClient", mock.Anything, "app", client.RedirectURIs, false).Return(client, "secret", nil)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "/oauth/clients", io.NopCloser(strings.NewReader(tt.reqBody)))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			newOAuthRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeleteClient(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	clientID := domain.NewID().String()
+
+	tests := []struct {
+		name         string
+		clientID     string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "delete",
+			clientID:     clientID,
+			expectedCode: http.StatusOK,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:         "invalid ID",
+			clientID:     "abc",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid client ID"}`,
+		},
+		{
+			name:         "not found",
+			clientID:     clientID,
+			serviceErr:   oidc.ErrClientNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"client not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.OIDCServiceMock)
+			s.On("DeleteClient", mock.Anything, clientID).Return(tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodDelete, "/oauth/clients/"+tt.clientID, nil)
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			newOAuthRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
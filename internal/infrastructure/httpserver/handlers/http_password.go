@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type PasswordHTTPHandler struct {
+	validator       *validator.Validate
+	passwordService service.PasswordPort
+}
+
+type passwordResetRequest struct {
+	Email string `json:"email" binding:"required" validate:"email"`
+}
+
+type passwordResetConfirmRequest struct {
+	Token    string `json:"token" binding:"required" validate:"required,max=255"`
+	Password string `json:"password" binding:"required"`
+}
+
+type passwordChangeRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+func NewPasswordHTTPHandler(v *validator.Validate, passwordService service.PasswordPort) *PasswordHTTPHandler {
+	return &PasswordHTTPHandler{
+		validator:       v,
+		passwordService: passwordService,
+	}
+}
+
+// RequestPasswordReset responds the same whether the email is registered or not
+func (h *PasswordHTTPHandler) RequestPasswordReset(c *gin.Context) {
+	var req passwordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.passwordService.RequestReset(c.Request.Context(), req.Email); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrTooManyAttempts):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many attempts, try again later"})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, "ok")
+}
+
+func (h *PasswordHTTPHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req passwordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.passwordService.ConfirmReset(c.Request.Context(), req.Token, req.Password); err != nil {
+		switch {
+		case errors.Is(err, verification.ErrInvalidToken):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired token"})
+		case errors.Is(err, user.ErrWeakPassword):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+func (h *PasswordHTTPHandler) ChangePassword(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req passwordChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.passwordService.Change(c.Request.Context(), userID, req.CurrentPassword, req.NewPassword, c.ClientIP()); err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid current password"})
+		case errors.Is(err, lockout.ErrLocked):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed logins, try again later"})
+		case errors.Is(err, user.ErrWeakPassword):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, auth.ErrUnauthenticated):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+		case errors.Is(err, auth.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+	serviceMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/applicaion/service"
+)
+
+func newPasswordRouter(s *serviceMock.PasswordServiceMock) *gin.Engine {
+	passwordHandler := NewPasswordHTTPHandler(validator.New(), s)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/password-reset/request", passwordHandler.RequestPasswordReset)
+	router.POST("/auth/password-reset/confirm", passwordHandler.ConfirmPasswordReset)
+	router.POST("/users/:id/password", passwordHandler.ChangePassword)
+
+	return router
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "reset requested",
+			body:         `{"email": "john@example.com"}`,
+			expectedCode: http.StatusAccepted,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:         "too many attempts",
+			body:         `{"email": "john@example.com"}`,
+			serviceErr:   auth.ErrTooManyAttempts,
+			expectedCode: http.StatusTooManyRequests,
+			expectedBody: `{"error":"too many attempts, try again later"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.PasswordServiceMock)
+			s.On("RequestReset", mock.Anything, "john@example.com").Return(tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodPost, "/auth/password-reset/request", strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			newPasswordRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+
+	t.Run("invalid email", func(t *testing.T) {
+		s := new(serviceMock.PasswordServiceMock)
+
+		req, err := http.NewRequest(http.MethodPost, "/auth/password-reset/request", strings.NewReader(`{"email": "john"}`))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		newPasswordRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		s.AssertNotCalled(t, "RequestReset", mock.Anything, mock.Anything)
+	})
+}
+
+func TestConfirmPasswordReset(t *testing.T) {
+	t.Run("password is reset", func(t *testing.T) {
+		s := new(serviceMock.PasswordServiceMock)
+		s.On("ConfirmReset", mock.Anything, "secret", "new-password").Return(nil)
+
+		req, err := http.NewRequest(http.MethodPost, "/auth/password-reset/confirm", strings.NewReader(`{"token": "secret", "password": "new-password"}`))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		newPasswordRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"ok"`, recorder.Body.String())
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		s := new(serviceMock.PasswordServiceMock)
+		s.On("ConfirmReset", mock.Anything, "secret", mock.Anything).Return(verification.ErrInvalidToken)
+
+		req, err := http.NewRequest(http.MethodPost, "/auth/password-reset/confirm", strings.NewReader(`{"token": "secret", "password": "new-password"}`))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		newPasswordRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, `{"error":"invalid or expired token"}`, recorder.Body.String())
+	})
+
+	t.Run("weak password", func(t *testing.T) {
+		s := new(serviceMock.PasswordServiceMock)
+		s.On("ConfirmReset", mock.Anything, "secret", "short").Return(fmt.Errorf("%w: must be at least 8 characters long", user.ErrWeakPassword))
+
+		req, err := http.NewRequest(http.MethodPost, "/auth/password-reset/confirm", strings.NewReader(`{"token": "secret", "password": "short"}`))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		newPasswordRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+		assert.Equal(t, `{"error":"password does not meet the policy: must be at least 8 characters long"}`, recorder.Body.String())
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	userID := uuid.New().String()
+
+	tests := []struct {
+		name         string
+		userID       string
+		body         string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "password is changed",
+			userID:       userID,
+			body:         `{"current_password": "secure123", "new_password": "new-secure123"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:         "invalid user ID",
+			userID:       "not-a-uuid",
+			body:         `{"current_password": "secure123", "new_password": "new-secure123"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid user ID"}`,
+		},
+		{
+			name:         "missing current password",
+			userID:       userID,
+			body:         `{"new_password": "new-secure123"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "wrong current password",
+			userID:       userID,
+			body:         `{"current_password": "wrong", "new_password": "new-secure123"}`,
+			serviceErr:   auth.ErrInvalidCredentials,
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"invalid current password"}`,
+		},
+		{
+			name:         "locked out",
+			userID:       userID,
+			body:         `{"current_password": "wrong", "new_password": "new-secure123"}`,
+			serviceErr:   lockout.ErrLocked,
+			expectedCode: http.StatusTooManyRequests,
+			expectedBody: `{"error":"too many failed logins, try again later"}`,
+		},
+		{
+			name:         "weak password",
+			userID:       userID,
+			body:         `{"current_password": "secure123", "new_password": "password"}`,
+			serviceErr:   fmt.Errorf("%w: too common", user.ErrWeakPassword),
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"password does not meet the policy: too common"}`,
+		},
+		{
+			name:         "user not found",
+			userID:       userID,
+			body:         `{"current_password": "secure123", "new_password": "new-secure123"}`,
+			serviceErr:   user.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"user not found"}`,
+		},
+		{
+			name:         "forbidden",
+			userID:       userID,
+			body:         `{"current_password": "secure123", "new_password": "new-secure123"}`,
+			serviceErr:   auth.ErrForbidden,
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"forbidden"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.PasswordServiceMock)
+			s.On("Change", mock.Anything, tt.userID, mock.Anything, mock.Anything, mock.Anything).Return(tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/password", tt.userID), strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			newPasswordRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type PrivacyHTTPHandler struct {
+	privacyService service.PrivacyPort
+}
+
+func NewPrivacyHTTPHandler(privacyService service.PrivacyPort) *PrivacyHTTPHandler {
+	return &PrivacyHTTPHandler{
+		privacyService: privacyService,
+	}
+}
+
+// ExportUser responds with the export as a JSON file to download
+func (h *PrivacyHTTPHandler) ExportUser(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	export, err := h.privacyService.Export(c.Request.Context(), userID)
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s.json"`, userID))
+	c.JSON(http.StatusOK, export)
+}
+
+func (h *PrivacyHTTPHandler) EraseUser(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	if err := h.privacyService.Erase(c.Request.Context(), userID); err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+func respondPrivacyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, user.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, user.ErrErased):
+		c.JSON(http.StatusConflict, gin.H{"error": "user has been erased"})
+	case errors.Is(err, auth.ErrUnauthenticated):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+	case errors.Is(err, auth.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	default:
+		logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	serviceMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/applicaion/service"
+)
+
+func newPrivacyRouter(s *serviceMock.PrivacyServiceMock) *gin.Engine {
+	privacyHandler := NewPrivacyHTTPHandler(s)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/users/:id/export", privacyHandler.ExportUser)
+	router.POST("/users/:id/erase", privacyHandler.EraseUser)
+
+	return router
+}
+
+func TestExportUser(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	userID := domain.NewID()
+	familyID := domain.NewID()
+	at := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+
+	export := &service.UserExport{
+		ExportedAt: at,
+		User:       &user.User{ID: userID, Email: "john@example.com", FirstName: "John", LastName: "Doe", Role: user.RoleSelf, Addresses: []*user.Address{}},
+		AuditEntries: []*audit.Entry{
+			{Actor: audit.Actor{Type: audit.ActorAnonymous}, Action: audit.ActionUserCreated, UserID: userID, Changes: []audit.Change{}, CreatedAt: at},
+		},
+		Sessions: []*session.Session{{FamilyID: familyID, CreatedAt: at, ExpiresAt: at.Add(time.Hour)}},
+	}
+
+	tests := []struct {
+		name         string
+		userID       string
+		export       *service.UserExport
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "export user",
+			userID:       userID.String(),
+			export:       export,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"exported_at":"2024-05-02T10:00:00Z",` +
+				`"user":{"id":"` + userID.String() + `","email":"john@example.com","email_verified":false,"mfa_enabled":false,` +
+				`"first_name":"John","last_name":"Doe","phone_number":"","role":"self","addresses":[]},` +
+				`"audit_entries":[{"actor":{"type":"anonymous"},"action":"user.created","user_id":"` + userID.String() + `",` +
+				`"changes":[],"created_at":"2024-05-02T10:00:00Z"}],` +
+				`"sessions":[{"family_id":"` + familyID.String() + `","created_at":"2024-05-02T10:00:00Z","expires_at":"2024-05-02T11:00:00Z"}]}`,
+		},
+		{name: "invalid user ID", userID: "asdasda23423", expectedCode: http.StatusBadRequest, expectedBody: `{"error":"invalid user ID"}`},
+		{name: "user not found", userID: userID.String(), serviceErr: user.ErrNotFound, expectedCode: http.StatusNotFound, expectedBody: `{"error":"user not found"}`},
+		{name: "forbidden", userID: userID.String(), serviceErr: auth.ErrForbidden, expectedCode: http.StatusForbidden, expectedBody: `{"error":"forbidden"}`},
+		{name: "internal error", userID: userID.String(), serviceErr: errors.New("db error"), expectedCode: http.StatusInternalServerError, expectedBody: `{"error":"internal server error"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.PrivacyServiceMock)
+			s.On("Export", mock.Anything, tt.userID).Return(tt.export, tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodGet, "/users/"+tt.userID+"/export", nil)
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			newPrivacyRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, `attachment; filename="user-`+tt.userID+`.json"`, recorder.Header().Get("Content-Disposition"))
+			}
+		})
+	}
+}
+
+func TestEraseUser(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	userID := domain.NewID().String()
+
+	tests := []struct {
+		name         string
+		userID       string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "erase user", userID: userID, expectedCode: http.StatusOK, expectedBody: `"ok"`},
+		{name: "invalid user ID", userID: "asdasda23423", expectedCode: http.StatusBadRequest, expectedBody: `{"error":"invalid user ID"}`},
+		{name: "user not found", userID: userID, serviceErr: user.ErrNotFound, expectedCode: http.StatusNotFound, expectedBody: `{"error":"user not found"}`},
+		{name: "user erased already", userID: userID, serviceErr: user.ErrErased, expectedCode: http.StatusConflict, expectedBody: `{"error":"user has been erased"}`},
+		{name: "forbidden", userID: userID, serviceErr: auth.ErrForbidden, expectedCode: http.StatusForbidden, expectedBody: `{"error":"forbidden"}`},
+		{name: "internal error", userID: userID, serviceErr: errors.New("db error"), expectedCode: http.StatusInternalServerError, expectedBody: `{"error":"internal server error"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.PrivacyServiceMock)
+			s.On("Erase", mock.Anything, tt.userID).Return(tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/"+tt.userID+"/erase", nil)
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			newPrivacyRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type SessionHTTPHandler struct {
+	validator      *validator.Validate
+	sessionService service.SessionPort
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" validate:"required,max=255"`
+}
+
+func NewSessionHTTPHandler(v *validator.Validate, sessionService service.SessionPort) *SessionHTTPHandler {
+	return &SessionHTTPHandler{
+		validator:      v,
+		sessionService: sessionService,
+	}
+}
+
+func (h *SessionHTTPHandler) Refresh(c *gin.Context) {
+	req, ok := h.bindRefreshToken(c)
+	if !ok {
+		return
+	}
+
+	token, err := h.sessionService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired refresh token"})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	respondToken(c, token)
+}
+
+func (h *SessionHTTPHandler) Logout(c *gin.Context) {
+	req, ok := h.bindRefreshToken(c)
+	if !ok {
+		return
+	}
+
+	if err := h.sessionService.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+		logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+func (h *SessionHTTPHandler) RevokeAll(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	if err := h.sessionService.RevokeAll(c.Request.Context(), userID); err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, auth.ErrUnauthenticated):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+		case errors.Is(err, auth.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// bindRefreshToken responds by itself when the request is invalid
+func (h *SessionHTTPHandler) bindRefreshToken(c *gin.Context) (*refreshTokenRequest, bool) {
+	var req refreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	return &req, true
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	serviceMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/applicaion/service"
+)
+
+func newSessionRouter(s *serviceMock.SessionServiceMock) *gin.Engine {
+	sessionHandler := NewSessionHTTPHandler(validator.New(), s)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/refresh", sessionHandler.Refresh)
+	router.POST("/auth/logout", sessionHandler.Logout)
+	router.DELETE("/users/:id/sessions", sessionHandler.RevokeAll)
+
+	return router
+}
+
+func TestRefresh(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	tests := []struct {
+		name         string
+		reqBody      string
+		token        *auth.Token
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "refresh",
+			reqBody:      `{"refresh_token": "secret"}`,
+			token:        &auth.Token{AccessToken: "token", TokenType: "Bearer", ExpiresIn: 15 * time.Minute, RefreshToken: "next"},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"access_token":"token","token_type":"Bearer","expires_in":900,"refresh_token":"next"}`,
+		},
+		{
+			name:         "invalid token",
+			reqBody:      `{"refresh_token": "secret"}`,
+			serviceErr:   auth.ErrInvalidToken,
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"invalid or expired refresh token"}`,
+		},
+		{
+			name:         "internal error",
+			reqBody:      `{"refresh_token": "secret"}`,
+			serviceErr:   errors.New("db down"),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"error":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.SessionServiceMock)
+			s.On("Refresh", mock.Anything, "secret").Return(tt.token, tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodPost, "/auth/refresh", io.NopCloser(strings.NewReader(tt.reqBody)))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			newSessionRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+
+	t.Run("missing token", func(t *testing.T) {
+		s := new(serviceMock.SessionServiceMock)
+
+		req, err := http.NewRequest(http.MethodPost, "/auth/refresh", io.NopCloser(strings.NewReader(`{}`)))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		newSessionRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		s.AssertNotCalled(t, "Refresh", mock.Anything, mock.Anything)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	s := new(serviceMock.SessionServiceMock)
+	s.On("Logout", mock.Anything, "secret").Return(nil)
+
+	req, err := http.NewRequest(http.MethodPost, "/auth/logout", io.NopCloser(strings.NewReader(`{"refresh_token": "secret"}`)))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	newSessionRouter(s).ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `"ok"`, recorder.Body.String())
+	s.AssertExpectations(t)
+}
+
+func TestRevokeSessions(t *testing.T) {
+	tests := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "revoked",
+			expectedCode: http.StatusOK,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:         "user not found",
+			serviceErr:   user.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"user not found"}`,
+		},
+		{
+			name:         "unauthenticated",
+			serviceErr:   auth.ErrUnauthenticated,
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"missing credentials"}`,
+		},
+		{
+			name:         "forbidden",
+			serviceErr:   auth.ErrForbidden,
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"forbidden"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New().String()
+
+			s := new(serviceMock.SessionServiceMock)
+			s.On("RevokeAll", mock.Anything, userID).Return(tt.serviceErr)
+
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s/sessions", userID), nil)
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			newSessionRouter(s).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+
+	t.Run("invalid user id", func(t *testing.T) {
+		s := new(serviceMock.SessionServiceMock)
+
+		req, err := http.NewRequest(http.MethodDelete, "/users/invalid/sessions", nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		newSessionRouter(s).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		s.AssertNotCalled(t, "RevokeAll", mock.Anything, mock.Anything)
+	})
+}
@@ -1,32 +1,44 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 
 	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
 	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
 	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/jsonpatch"
 	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
 )
 
+const defaultPageSize = 5
+
+var errDuplicateAddressType = errors.New("duplicate address type")
+
 type UserHTTPHandler struct {
-	validator   *validator.Validate
-	userService service.UserPort
+	validator      *validator.Validate
+	userService    service.UserPort
+	maxPageSize    int
+	requireIfMatch bool
 }
 
 type createUserRequest struct {
 	Email       string                      `json:"email" binding:"required,email" validate:"email"`
-	Password    string                      `json:"password" binding:"required" validate:"min=8"`
+	Password    string                      `json:"password" binding:"required"`
 	FirstName   string                      `json:"first_name" binding:"required" validate:"required,min=1,max=50"`
 	LastName    string                      `json:"last_name" binding:"required" validate:"required,min=1,max=50"`
 	PhoneNumber string                      `json:"phone_number" binding:"required" validate:"omitempty,min=9,max=15,numeric"`
+	Role        string                      `json:"role" binding:"omitempty" validate:"omitempty,oneof=admin support self"`
 	Addresses   []*createUserAddressRequest `json:"addresses" binding:"required" validate:"required,min=1,dive"`
 }
 
@@ -39,27 +51,60 @@ type createUserAddressRequest struct {
 	Country    string `json:"country" binding:"required" validate:"omitempty,min=1,max=100,alpha"`
 }
 
-type updateUserRequest struct {
-	Password    *string                     `json:"password" binding:"omitempty" validate:"omitempty,min=8"`
-	FirstName   *string                     `json:"first_name" binding:"omitempty" validate:"omitempty,min=1,max=50"`
-	LastName    *string                     `json:"last_name" binding:"omitempty" validate:"omitempty,min=1,max=50"`
-	PhoneNumber *string                     `json:"phone_number" binding:"omitempty" validate:"omitempty,min=9,max=15,numeric"`
-	Addresses   []*updateUserAddressRequest `json:"addresses" binding:"omitempty" validate:"omitempty,dive"`
+// userDocument is the representation of a user replaced by PUT and modified by PATCH,
+// missing optional members are cleared while a missing role is left unchanged.
+// The password is not a part of it, it is changed with POST /users/:id/password.
+type userDocument struct {
+	FirstName   string             `json:"first_name" binding:"required" validate:"required,min=1,max=50"`
+	LastName    string             `json:"last_name" binding:"required" validate:"required,min=1,max=50"`
+	PhoneNumber string             `json:"phone_number,omitempty" binding:"omitempty" validate:"omitempty,min=9,max=15,numeric"`
+	Role        string             `json:"role,omitempty" binding:"omitempty" validate:"omitempty,oneof=admin support self"`
+	Addresses   []*addressDocument `json:"addresses" binding:"required" validate:"required,min=1,dive"`
+}
+
+type addressDocument struct {
+	Type       int    `json:"type" binding:"required" validate:"required,oneof=1 2 3"`
+	Street     string `json:"street" binding:"required" validate:"required,min=1,max=255"`
+	City       string `json:"city" binding:"required" validate:"required,min=1,max=100"`
+	State      string `json:"state,omitempty" binding:"omitempty" validate:"omitempty,min=1,max=100"`
+	PostalCode string `json:"postal_code" binding:"required" validate:"required,min=1,max=20,alphanum"`
+	Country    string `json:"country,omitempty" binding:"omitempty" validate:"omitempty,min=1,max=100,alpha"`
+}
+
+type listUsersRequest struct {
+	Email       string `form:"email" validate:"omitempty,max=255"`
+	EmailPrefix string `form:"email_prefix" validate:"omitempty,max=255"`
+	FirstName   string `form:"first_name" validate:"omitempty,max=50"`
+	LastName    string `form:"last_name" validate:"omitempty,max=50"`
+	PhoneNumber string `form:"phone_number" validate:"omitempty,max=15,numeric"`
+	Country     string `form:"country" validate:"omitempty,max=100"`
+	City        string `form:"city" validate:"omitempty,max=100"`
+	AddressType int    `form:"address_type" validate:"omitempty,oneof=1 2 3"`
+	Sort        string `form:"sort"`
+	Cursor      string `form:"cursor"`
+	Total       bool   `form:"total"`
+
+	IncludeDeleted bool `form:"include_deleted"`
+}
+
+type listUsersResponse struct {
+	Data       []*user.User   `json:"data"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Total      *int           `json:"total,omitempty"`
+	Links      listUsersLinks `json:"links"`
 }
 
-type updateUserAddressRequest struct {
-	Type       int     `json:"type" binding:"required" validate:"required,oneof=1 2 3"`
-	Street     *string `json:"street" binding:"omitempty" validate:"omitempty,min=1,max=255"`
-	City       *string `json:"city" binding:"omitempty" validate:"omitempty,min=1,max=100"`
-	State      *string `json:"state" binding:"omitempty" validate:"omitempty,min=1,max=100"`
-	PostalCode *string `json:"postal_code" binding:"omitempty" validate:"omitempty,min=1,max=20,alphanum"`
-	Country    *string `json:"country" binding:"omitempty" validate:"omitempty,min=1,max=100,alpha"`
+type listUsersLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
 }
 
-func NewUserHTTPHandler(v *validator.Validate, userService service.UserPort) *UserHTTPHandler {
+func NewUserHTTPHandler(v *validator.Validate, userService service.UserPort, maxPageSize int, requireIfMatch bool) *UserHTTPHandler {
 	return &UserHTTPHandler{
-		validator:   v,
-		userService: userService,
+		validator:      v,
+		userService:    userService,
+		maxPageSize:    maxPageSize,
+		requireIfMatch: requireIfMatch,
 	}
 }
 
@@ -75,21 +120,16 @@ func (h *UserHTTPHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := hashPassword(req.Password)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed hashing password"})
-		return
-	}
-
 	userID := domain.NewID()
 
 	createUserDTO := &service.CreateUserDTO{
 		ID:          userID,
 		Email:       req.Email,
-		Password:    hashedPassword,
+		Password:    req.Password,
 		FirstName:   req.FirstName,
 		LastName:    req.LastName,
 		PhoneNumber: req.PhoneNumber,
+		Role:        user.Role(req.Role),
 		Addresses:   make([]*service.CreateUserAddress, 0, len(req.Addresses)),
 	}
 
@@ -108,10 +148,18 @@ func (h *UserHTTPHandler) CreateUser(c *gin.Context) {
 		typeIsPresent[user.AddressType(addr.Type)] = struct{}{}
 	}
 
-	if err = h.userService.Create(c.Request.Context(), createUserDTO); err != nil {
+	if err := h.userService.Create(c.Request.Context(), createUserDTO); err != nil {
 		switch {
 		case errors.Is(err, user.ErrEmailAlreadyExists):
 			c.JSON(http.StatusConflict, gin.H{"error": "email already exists"})
+		case errors.Is(err, user.ErrWeakPassword):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, user.ErrMFARequired):
+			c.JSON(http.StatusConflict, gin.H{"error": "mfa is required for the role"})
+		case errors.Is(err, auth.ErrUnauthenticated):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+		case errors.Is(err, auth.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 		default:
 			logger.Error(err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
@@ -122,6 +170,7 @@ func (h *UserHTTPHandler) CreateUser(c *gin.Context) {
 	c.JSON(http.StatusCreated, map[string]string{"uuid": userID.String()})
 }
 
+// UpdateUser replaces the user with the request body
 func (h *UserHTTPHandler) UpdateUser(c *gin.Context) {
 	userID := c.Param("id")
 	if _, err := uuid.Parse(userID); err != nil {
@@ -129,53 +178,125 @@ func (h *UserHTTPHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	var req updateUserRequest
+	version, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
+	var req userDocument
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.validator.Struct(req); err != nil {
+	if err := h.validateDocument(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	updateUserDTO := &service.UpdateUserDTO{
-		FirstName:   req.FirstName,
-		LastName:    req.LastName,
-		PhoneNumber: req.PhoneNumber,
-		Addresses:   make([]*service.UpdateUserAddress, 0, len(req.Addresses)),
+	current, ok := h.currentUser(c, userID, version)
+	if !ok {
+		return
 	}
 
-	if req.Password != nil {
-		hashedPassword, err := hashPassword(*req.Password)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed hashing password"})
-			return
-		}
+	h.update(c, current, &req, version != nil)
+}
 
-		updateUserDTO.Password = &hashedPassword
+// PatchUser modifies the user with a JSON Merge Patch or a JSON Patch, depending on the content type
+func (h *UserHTTPHandler) PatchUser(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
 	}
 
-	typeIsPresent := make(map[user.AddressType]struct{})
-	for _, addr := range req.Addresses {
-		if _, ok := typeIsPresent[user.AddressType(addr.Type)]; !ok {
-			updateUserDTO.Addresses = append(updateUserDTO.Addresses, &service.UpdateUserAddress{
-				Type:       addr.Type,
-				Street:     addr.Street,
-				City:       addr.City,
-				State:      addr.State,
-				PostalCode: addr.PostalCode,
-				Country:    addr.Country,
-			})
+	var apply func(doc, patch []byte) ([]byte, error)
+	switch c.ContentType() {
+	case "application/merge-patch+json":
+		apply = jsonpatch.MergePatch
+	case "application/json-patch+json":
+		apply = jsonpatch.Apply
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "use application/merge-patch+json or application/json-patch+json"})
+		return
+	}
+
+	version, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed reading request body"})
+		return
+	}
+
+	current, ok := h.currentUser(c, userID, version)
+	if !ok {
+		return
+	}
+
+	doc, err := json.Marshal(newUserDocument(current))
+	if err != nil {
+		logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		return
+	}
+
+	patched, err := apply(doc, patch)
+	if err != nil {
+		switch {
+		case errors.Is(err, jsonpatch.ErrInvalidPatch):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, jsonpatch.ErrConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
 		}
-		typeIsPresent[user.AddressType(addr.Type)] = struct{}{}
+		return
 	}
 
-	if err := h.userService.Update(c.Request.Context(), userID, updateUserDTO); err != nil {
+	// the patched document must still be a valid user, read-only members like the email can not be patched in
+	var req userDocument
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&req); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err = h.validateDocument(&req); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.update(c, current, &req, version != nil)
+}
+
+func (h *UserHTTPHandler) DeleteUser(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	version, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.Delete(c.Request.Context(), userID, version); err != nil {
 		switch {
 		case errors.Is(err, user.ErrNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, user.ErrVersionMismatch):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "precondition failed"})
+		case errors.Is(err, auth.ErrUnauthenticated):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+		case errors.Is(err, auth.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 		default:
 			logger.Error(err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
@@ -186,17 +307,26 @@ func (h *UserHTTPHandler) UpdateUser(c *gin.Context) {
 	c.JSON(http.StatusOK, "ok")
 }
 
-func (h *UserHTTPHandler) DeleteUser(c *gin.Context) {
+// RestoreUser brings back a deleted user, which is possible until the user is purged
+func (h *UserHTTPHandler) RestoreUser(c *gin.Context) {
 	userID := c.Param("id")
 	if _, err := uuid.Parse(userID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
 		return
 	}
 
-	if err := h.userService.Delete(c.Request.Context(), userID); err != nil {
+	if err := h.userService.Restore(c.Request.Context(), userID); err != nil {
 		switch {
 		case errors.Is(err, user.ErrNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, user.ErrNotDeleted):
+			c.JSON(http.StatusConflict, gin.H{"error": "user is not deleted"})
+		case errors.Is(err, user.ErrErased):
+			c.JSON(http.StatusConflict, gin.H{"error": "user has been erased"})
+		case errors.Is(err, auth.ErrUnauthenticated):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+		case errors.Is(err, auth.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 		default:
 			logger.Error(err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
@@ -219,6 +349,10 @@ func (h *UserHTTPHandler) GetUser(c *gin.Context) {
 		switch {
 		case errors.Is(err, user.ErrNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, auth.ErrUnauthenticated):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+		case errors.Is(err, auth.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 		default:
 			logger.Error(err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
@@ -226,38 +360,262 @@ func (h *UserHTTPHandler) GetUser(c *gin.Context) {
 		return
 	}
 
+	tag := etag(domainUser.Version)
+	c.Header("ETag", tag)
+
+	if header := c.GetHeader("If-None-Match"); header != "" && !noneMatch(header, tag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	c.JSON(http.StatusOK, domainUser)
 }
 
 func (h *UserHTTPHandler) Get(c *gin.Context) {
-	page := c.DefaultQuery("page", "1")
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page param"})
+	if _, ok := c.GetQuery("page"); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page param is not supported, use cursor"})
 		return
 	}
-	size := c.DefaultQuery("size", "5")
+
+	size := c.DefaultQuery("size", strconv.Itoa(defaultPageSize))
 	iSize, err := strconv.Atoi(size)
-	if err != nil {
+	if err != nil || iSize < 1 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid size param"})
 		return
 	}
+	if iSize > h.maxPageSize {
+		iSize = h.maxPageSize
+	}
+
+	var req listUsersRequest
+	if err = c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err = h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	domainUsers, err := h.userService.Get(c.Request.Context(), iPage, iSize)
+	sorts, err := user.ParseSort(req.Sort)
 	if err != nil {
-		logger.Error(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort param"})
 		return
 	}
 
-	c.JSON(http.StatusOK, domainUsers)
+	var cursor *user.Cursor
+	if req.Cursor != "" {
+		if cursor, err = user.DecodeCursor(req.Cursor); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor param"})
+			return
+		}
+	}
+
+	q := &user.ListQuery{
+		Filter: user.Filter{
+			Email:       req.Email,
+			EmailPrefix: req.EmailPrefix,
+			FirstName:   req.FirstName,
+			LastName:    req.LastName,
+			PhoneNumber: req.PhoneNumber,
+			Country:     req.Country,
+			City:        req.City,
+		},
+		Sort:      sorts,
+		Cursor:    cursor,
+		Limit:     iSize,
+		WithTotal: req.Total,
+
+		IncludeDeleted: req.IncludeDeleted,
+	}
+
+	if req.AddressType != 0 {
+		addrType := user.AddressType(req.AddressType)
+		q.Filter.AddressType = &addrType
+	}
+
+	page, err := h.userService.Get(c.Request.Context(), q)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor param"})
+		case errors.Is(err, auth.ErrUnauthenticated):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+		case errors.Is(err, auth.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	resp := listUsersResponse{
+		Data:  page.Users,
+		Total: page.Total,
+		Links: listUsersLinks{
+			Self: c.Request.URL.RequestURI(),
+		},
+	}
+
+	if resp.Data == nil {
+		resp.Data = []*user.User{}
+	}
+
+	if page.NextCursor != nil {
+		resp.NextCursor = page.NextCursor.Encode()
+
+		params := c.Request.URL.Query()
+		params.Set("cursor", resp.NextCursor)
+		resp.Links.Next = c.Request.URL.Path + "?" + params.Encode()
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
-func hashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// currentUser loads the user to be changed, checking the version expected by If-Match.
+// It responds with an error and returns false when the request can not go on.
+func (h *UserHTTPHandler) currentUser(c *gin.Context, userID string, version *int64) (*user.User, bool) {
+	current, err := h.userService.GetByUUID(c.Request.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, auth.ErrUnauthenticated):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+		case errors.Is(err, auth.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return nil, false
+	}
+
+	if version != nil && *version != current.Version {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "precondition failed"})
+		return nil, false
+	}
+
+	return current, true
+}
+
+// update turns the user into the one described by the document. The changes are computed against the loaded user,
+// so they are applied only if nobody changed the user in the meantime.
+func (h *UserHTTPHandler) update(c *gin.Context, current *user.User, doc *userDocument, conditional bool) {
+	changes := user.Diff(current, doc.toUser())
+
+	if err := h.userService.Update(c.Request.Context(), current.ID.String(), changes, &current.Version); err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, user.ErrVersionMismatch) && conditional:
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "precondition failed"})
+		case errors.Is(err, user.ErrVersionMismatch):
+			c.JSON(http.StatusConflict, gin.H{"error": "user has been modified concurrently, retry"})
+		case errors.Is(err, user.ErrInvalidChange):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid change"})
+		case errors.Is(err, user.ErrMFARequired):
+			c.JSON(http.StatusConflict, gin.H{"error": "mfa is required for the role"})
+		case errors.Is(err, auth.ErrUnauthenticated):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+		case errors.Is(err, auth.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		default:
+			logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+func (h *UserHTTPHandler) validateDocument(doc *userDocument) error {
+	if err := h.validator.Struct(doc); err != nil {
+		return err
+	}
+
+	typeIsPresent := make(map[int]struct{})
+	for _, addr := range doc.Addresses {
+		if _, ok := typeIsPresent[addr.Type]; ok {
+			return errDuplicateAddressType
+		}
+		typeIsPresent[addr.Type] = struct{}{}
+	}
+
+	return nil
+}
+
+func newUserDocument(u *user.User) *userDocument {
+	doc := &userDocument{
+		FirstName:   u.FirstName,
+		LastName:    u.LastName,
+		PhoneNumber: u.PhoneNumber,
+		Role:        string(u.Role),
+		Addresses:   make([]*addressDocument, 0, len(u.Addresses)),
+	}
+
+	for _, addr := range u.Addresses {
+		doc.Addresses = append(doc.Addresses, &addressDocument{
+			Type:       int(addr.Type),
+			Street:     addr.Street,
+			City:       addr.City,
+			State:      addr.State,
+			PostalCode: addr.PostalCode,
+			Country:    addr.Country,
+		})
+	}
+
+	return doc
+}
+
+func (doc *userDocument) toUser() *user.User {
+	u := &user.User{
+		FirstName:   doc.FirstName,
+		LastName:    doc.LastName,
+		PhoneNumber: doc.PhoneNumber,
+		Role:        user.Role(doc.Role),
+		Addresses:   make([]*user.Address, 0, len(doc.Addresses)),
+	}
+
+	for _, addr := range doc.Addresses {
+		u.Addresses = append(u.Addresses, &user.Address{
+			Type:       user.AddressType(addr.Type),
+			Street:     addr.Street,
+			City:       addr.City,
+			State:      addr.State,
+			PostalCode: addr.PostalCode,
+			Country:    addr.Country,
+		})
+	}
+
+	return u
+}
+
+// ifMatch returns the version expected by the If-Match header, nil when any version is accepted.
+// It responds with an error and returns false when the request can not go on.
+func (h *UserHTTPHandler) ifMatch(c *gin.Context) (*int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+
+	switch header {
+	case "":
+		if h.requireIfMatch {
+			c.JSON(http.StatusPreconditionRequired, gin.H{"error": "missing If-Match header"})
+			return nil, false
+		}
+		return nil, true
+	case "*":
+		return nil, true
+	}
+
+	version, err := parseETag(header)
 	if err != nil {
-		return "", err
+		// neither a weak tag nor a list of tags can match a single version
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "precondition failed"})
+		return nil, false
 	}
 
-	return string(hashedPassword), nil
+	return &version, true
 }
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -19,6 +20,7 @@ import (
 	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
 	"github.com/wojciechpawlinow/usermanagement/internal/config"
 	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
 	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
 	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
 	serviceMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/applicaion/service"
@@ -53,7 +55,7 @@ func TestGetUserByUUID(t *testing.T) {
 		s := new(serviceMock.UserServiceMock)
 		s.On("GetByUUID", mock.Anything, userID.String()).Return(expectedUser, nil)
 
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -72,7 +74,7 @@ func TestGetUserByUUID(t *testing.T) {
 
 	t.Run("fail invalid request", func(t *testing.T) {
 		s := new(serviceMock.UserServiceMock)
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -96,7 +98,7 @@ func TestGetUserByUUID(t *testing.T) {
 		s := new(serviceMock.UserServiceMock)
 		s.On("GetByUUID", mock.Anything, userID).Return(nil, errors.New("internal error"))
 
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -112,6 +114,43 @@ func TestGetUserByUUID(t *testing.T) {
 		assert.Equal(t, `{"error":"internal server error"}`, recorder.Body.String())
 	})
 
+	t.Run("etag and not modified", func(t *testing.T) {
+		userID := domain.NewID()
+
+		s := new(serviceMock.UserServiceMock)
+		s.On("GetByUUID", mock.Anything, userID.String()).Return(&user.User{ID: userID, Version: 3}, nil)
+
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/users/:id", userHandler.GetUser)
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s", userID.String()), nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"3"`, recorder.Header().Get("ETag"))
+
+		req.Header.Set("If-None-Match", `"2", W/"3"`)
+
+		recorder = httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusNotModified, recorder.Code)
+		assert.Equal(t, `"3"`, recorder.Header().Get("ETag"))
+		assert.Empty(t, recorder.Body.String())
+
+		req.Header.Set("If-None-Match", `"2"`)
+
+		recorder = httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
 }
 
 func TestGetUsers(t *testing.T) {
@@ -171,28 +210,110 @@ func TestGetUsers(t *testing.T) {
 		}
 
 		s := new(serviceMock.UserServiceMock)
-		s.On("Get", mock.Anything, 1, 3).Return(expectedUsers, nil)
+		nextCursor := &user.Cursor{Sort: []user.Sort{{Field: user.SortByCreatedAt}}, Values: []string{"2024-01-01T00:00:00Z"}, ID: 3}
+		total := 10
+
+		s.On("Get", mock.Anything, &user.ListQuery{Limit: 3, WithTotal: true}).Return(&user.Page{
+			Users:      expectedUsers,
+			NextCursor: nextCursor,
+			Total:      &total,
+		}, nil)
+
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/users", userHandler.Get)
+
+		req, err := http.NewRequest(http.MethodGet, "/users?size=3&total=true", nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		respJson, _ := json.Marshal(listUsersResponse{
+			Data:       expectedUsers,
+			NextCursor: nextCursor.Encode(),
+			Total:      &total,
+			Links: listUsersLinks{
+				Self: "/users?size=3&total=true",
+				Next: "/users?cursor=" + nextCursor.Encode() + "&size=3&total=true",
+			},
+		})
+		assert.Equal(t, string(respJson), recorder.Body.String())
+	})
+
+	t.Run("including deleted users", func(t *testing.T) {
+		deletedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		deleted := &user.User{ID: domain.NewID(), Email: "test1@example.com", DeletedAt: &deletedAt}
+
+		s := new(serviceMock.UserServiceMock)
+		s.On("Get", mock.Anything, &user.ListQuery{Limit: defaultPageSize, IncludeDeleted: true}).Return(&user.Page{
+			Users: []*user.User{deleted},
+		}, nil)
+
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/users", userHandler.Get)
+
+		req, err := http.NewRequest(http.MethodGet, "/users?include_deleted=true", nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"deleted_at":"2024-05-01T12:00:00Z"`)
+		s.AssertExpectations(t)
+	})
+
+	t.Run("next page", func(t *testing.T) {
+		cursor := &user.Cursor{Sort: []user.Sort{{Field: user.SortByCreatedAt}}, Values: []string{"2024-01-01T00:00:00Z"}, ID: 3}
+
+		s := new(serviceMock.UserServiceMock)
+		s.On("Get", mock.Anything, &user.ListQuery{Cursor: cursor, Limit: 100}).Return(&user.Page{}, nil)
 
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
 		router.GET("/users", userHandler.Get)
 
-		req, err := http.NewRequest(http.MethodGet, "/users?size=3&page=1", nil)
+		req, err := http.NewRequest(http.MethodGet, "/users?size=1000000&cursor="+cursor.Encode(), nil)
 		assert.NoError(t, err)
 
 		recorder := httptest.NewRecorder()
 		router.ServeHTTP(recorder, req)
 
 		assert.Equal(t, http.StatusOK, recorder.Code)
-		usersJson, _ := json.Marshal(expectedUsers)
-		assert.Equal(t, string(usersJson), recorder.Body.String())
+		assert.Equal(t, `{"data":[],"links":{"self":"/users?size=1000000\u0026cursor=`+cursor.Encode()+`"}}`, recorder.Body.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("fail invalid cursor", func(t *testing.T) {
+		s := new(serviceMock.UserServiceMock)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/users", userHandler.Get)
+
+		req, err := http.NewRequest(http.MethodGet, "/users?cursor=not-a-cursor", nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, `{"error":"invalid cursor param"}`, recorder.Body.String())
 	})
 
 	t.Run("fail invalid request", func(t *testing.T) {
 		s := new(serviceMock.UserServiceMock)
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -207,6 +328,75 @@ func TestGetUsers(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, recorder.Code)
 	})
 
+	t.Run("filters and sort", func(t *testing.T) {
+		addrType := user.AddressType(2)
+
+		s := new(serviceMock.UserServiceMock)
+		s.On("Get", mock.Anything, &user.ListQuery{
+			Filter: user.Filter{
+				EmailPrefix: "john",
+				LastName:    "smith",
+				Country:     "USA",
+				AddressType: &addrType,
+			},
+			Sort:  []user.Sort{{Field: user.SortByLastName}, {Field: user.SortByCreatedAt, Desc: true}},
+			Limit: 5,
+		}).Return(&user.Page{}, nil)
+
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/users", userHandler.Get)
+
+		req, err := http.NewRequest(http.MethodGet, "/users?email_prefix=john&last_name=smith&country=USA&address_type=2&sort=last_name,-created_at", nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		s.AssertExpectations(t)
+	})
+
+	t.Run("fail invalid sort", func(t *testing.T) {
+		s := new(serviceMock.UserServiceMock)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/users", userHandler.Get)
+
+		req, err := http.NewRequest(http.MethodGet, "/users?sort=password", nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, `{"error":"invalid sort param"}`, recorder.Body.String())
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		s := new(serviceMock.UserServiceMock)
+		s.On("Get", mock.Anything, &user.ListQuery{Limit: 5}).Return(nil, auth.ErrForbidden)
+
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/users", userHandler.Get)
+
+		req, err := http.NewRequest(http.MethodGet, "/users", nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Equal(t, `{"error":"forbidden"}`, recorder.Body.String())
+	})
+
 	// more use cases ...
 }
 
@@ -215,9 +405,9 @@ func TestDeleteUser(t *testing.T) {
 		userID := uuid.New().String()
 
 		s := new(serviceMock.UserServiceMock)
-		s.On("Delete", mock.Anything, userID).Return(nil)
+		s.On("Delete", mock.Anything, userID, (*int64)(nil)).Return(nil)
 
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -236,7 +426,7 @@ func TestDeleteUser(t *testing.T) {
 	t.Run("invalid user ID", func(t *testing.T) {
 		s := new(serviceMock.UserServiceMock)
 
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -256,9 +446,9 @@ func TestDeleteUser(t *testing.T) {
 		userID := uuid.New().String()
 
 		s := new(serviceMock.UserServiceMock)
-		s.On("Delete", mock.Anything, userID).Return(user.ErrNotFound)
+		s.On("Delete", mock.Anything, userID, (*int64)(nil)).Return(user.ErrNotFound)
 
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -278,9 +468,9 @@ func TestDeleteUser(t *testing.T) {
 		userID := uuid.New().String()
 
 		s := new(serviceMock.UserServiceMock)
-		s.On("Delete", mock.Anything, userID).Return(errors.New("internal error"))
+		s.On("Delete", mock.Anything, userID, (*int64)(nil)).Return(errors.New("internal error"))
 
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -295,46 +485,210 @@ func TestDeleteUser(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
 		assert.Equal(t, `{"error":"internal server error"}`, recorder.Body.String())
 	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		s := new(serviceMock.UserServiceMock)
+		s.On("Delete", mock.Anything, userID, (*int64)(nil)).Return(auth.ErrForbidden)
+
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.DELETE("/users/:id", userHandler.DeleteUser)
+
+		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s", userID), nil)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Equal(t, `{"error":"forbidden"}`, recorder.Body.String())
+	})
+}
+
+func TestRestoreUser(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	userID := uuid.New().String()
+
+	tests := []struct {
+		name         string
+		userID       string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "restore user", userID: userID, expectedCode: http.StatusOK, expectedBody: `"ok"`},
+		{name: "invalid user ID", userID: "asdasda23423", expectedCode: http.StatusBadRequest, expectedBody: `{"error":"invalid user ID"}`},
+		{name: "user not found", userID: userID, serviceErr: user.ErrNotFound, expectedCode: http.StatusNotFound, expectedBody: `{"error":"user not found"}`},
+		{name: "user not deleted", userID: userID, serviceErr: user.ErrNotDeleted, expectedCode: http.StatusConflict, expectedBody: `{"error":"user is not deleted"}`},
+		{name: "user erased", userID: userID, serviceErr: user.ErrErased, expectedCode: http.StatusConflict, expectedBody: `{"error":"user has been erased"}`},
+		{name: "forbidden", userID: userID, serviceErr: auth.ErrForbidden, expectedCode: http.StatusForbidden, expectedBody: `{"error":"forbidden"}`},
+		{name: "internal error", userID: userID, serviceErr: errors.New("db error"), expectedCode: http.StatusInternalServerError, expectedBody: `{"error":"internal server error"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(serviceMock.UserServiceMock)
+			s.On("Restore", mock.Anything, tt.userID).Return(tt.serviceErr)
+
+			userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.POST("/users/:id/restore", userHandler.RestoreUser)
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/restore", tt.userID), nil)
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
+
+// storedUser is the user PUT and PATCH requests are applied to
+func storedUser(userID string) *user.User {
+	id, _ := domain.ParseID(userID)
+
+	return &user.User{
+		ID:          id,
+		Email:       "john@example.com",
+		FirstName:   "John",
+		LastName:    "Doe",
+		PhoneNumber: "1234567890",
+		Role:        user.RoleSelf,
+		Addresses: []*user.Address{
+			{Type: 1, Street: "Main av", City: "New York", State: "NY", PostalCode: "55010", Country: "USA"},
+			{Type: 2, Street: "Side av", City: "New York", PostalCode: "55010"},
+		},
+		Version: 3,
+	}
+}
+
+const replaceUserBody = `{
+	"first_name": "Test",
+	"last_name": "Doe",
+	"addresses": [
+		{
+			"type": 1,
+			"street": "Test",
+			"city": "New York",
+			"postal_code": "55010",
+			"country": "USA"
+		}
+	]
+}`
+
+func TestUpdateUserPreconditions(t *testing.T) {
+	tests := []struct {
+		name           string
+		requireIfMatch bool
+		ifMatch        string
+		serviceErr     error
+		expectedCode   int
+		expectedBody   string
+	}{
+		{
+			name:           "missing If-Match when required",
+			requireIfMatch: true,
+			expectedCode:   http.StatusPreconditionRequired,
+			expectedBody:   `{"error":"missing If-Match header"}`,
+		},
+		{
+			name:         "weak entity tag never matches",
+			ifMatch:      `W/"3"`,
+			expectedCode: http.StatusPreconditionFailed,
+			expectedBody: `{"error":"precondition failed"}`,
+		},
+		{
+			name:         "stale entity tag",
+			ifMatch:      `"2"`,
+			expectedCode: http.StatusPreconditionFailed,
+			expectedBody: `{"error":"precondition failed"}`,
+		},
+		{
+			name:           "version changed in the meantime",
+			requireIfMatch: true,
+			ifMatch:        `"3"`,
+			serviceErr:     user.ErrVersionMismatch,
+			expectedCode:   http.StatusPreconditionFailed,
+			expectedBody:   `{"error":"precondition failed"}`,
+		},
+		{
+			name:         "version changed in the meantime without If-Match",
+			serviceErr:   user.ErrVersionMismatch,
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"error":"user has been modified concurrently, retry"}`,
+		},
+		{
+			name:           "version matches",
+			requireIfMatch: true,
+			ifMatch:        `"3"`,
+			expectedCode:   http.StatusOK,
+			expectedBody:   `"ok"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New().String()
+
+			s := new(serviceMock.UserServiceMock)
+			s.On("GetByUUID", mock.Anything, userID).Return(storedUser(userID), nil)
+			s.On("Update", mock.Anything, userID, mock.Anything, ptr(int64(3))).Return(tt.serviceErr)
+
+			userHandler := NewUserHTTPHandler(validator.New(), s, 100, tt.requireIfMatch)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.PUT("/users/:id", userHandler.UpdateUser)
+
+			req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/users/%s", userID), strings.NewReader(replaceUserBody))
+			assert.NoError(t, err)
+
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
 }
 
 func TestUpdateUser(t *testing.T) {
 	t.Run("update user", func(t *testing.T) {
 		userID := uuid.New().String()
 
-		reqBody := `{
-			"first_name": "Test",
-			"last_name": "Test",
-			"phone_number": "1234567890",
-			"addresses": [
-				{
-					"type": 1,
-					"street": "Test",
-					"city": "New York",
-					"state": "NY",
-					"country": "USA"
-				}
-			]
-		}`
-
-		updateUserDTO := &service.UpdateUserDTO{
-			FirstName:   ptr("Test"),
-			LastName:    ptr("Test"),
-			PhoneNumber: ptr("1234567890"),
-			Addresses: []*service.UpdateUserAddress{
-				{
-					Type:    1,
-					Street:  ptr("Test"),
-					City:    ptr("New York"),
-					State:   ptr("NY"),
-					Country: ptr("USA"),
-				},
+		// PUT replaces the whole user, so omitted members are cleared and omitted addresses removed
+		changes := &user.ChangeSet{
+			User: []user.Change{
+				user.Set(user.FieldFirstName, "Test"),
+				user.Clear(user.FieldPhoneNumber),
+			},
+			Addresses: []user.AddressChange{
+				{Type: 1, Changes: []user.Change{user.Set(user.FieldStreet, "Test"), user.Clear(user.FieldState)}},
+				{Type: 2, Remove: true},
 			},
 		}
 
 		s := new(serviceMock.UserServiceMock)
-		s.On("Update", mock.Anything, userID, updateUserDTO).Return(nil)
+		s.On("GetByUUID", mock.Anything, userID).Return(storedUser(userID), nil)
+		s.On("Update", mock.Anything, userID, changes, ptr(int64(3))).Return(nil)
 
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -344,7 +698,7 @@ func TestUpdateUser(t *testing.T) {
 		assert.NoError(t, err)
 
 		req.Header.Set("Content-Type", "application/json")
-		req.Body = io.NopCloser(strings.NewReader(reqBody))
+		req.Body = io.NopCloser(strings.NewReader(replaceUserBody))
 
 		recorder := httptest.NewRecorder()
 
@@ -352,17 +706,13 @@ func TestUpdateUser(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, recorder.Code)
 		assert.Equal(t, `"ok"`, recorder.Body.String())
+		s.AssertExpectations(t)
 	})
 
 	t.Run("invalid user ID", func(t *testing.T) {
-		reqBody := `{
-			"first_name": "John",
-			"last_name": "Doe"
-		}`
-
 		s := new(serviceMock.UserServiceMock)
 
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -372,7 +722,7 @@ func TestUpdateUser(t *testing.T) {
 		assert.NoError(t, err)
 
 		req.Header.Set("Content-Type", "application/json")
-		req.Body = io.NopCloser(strings.NewReader(reqBody))
+		req.Body = io.NopCloser(strings.NewReader(replaceUserBody))
 
 		recorder := httptest.NewRecorder()
 		router.ServeHTTP(recorder, req)
@@ -391,7 +741,7 @@ func TestUpdateUser(t *testing.T) {
 
 		s := new(serviceMock.UserServiceMock)
 
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -409,8 +759,9 @@ func TestUpdateUser(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, recorder.Code)
 	})
 
-	t.Run("user not found", func(t *testing.T) {
+	t.Run("partial user is rejected", func(t *testing.T) {
 		userID := uuid.New().String()
+
 		reqBody := `{
 			"first_name": "Test",
 			"last_name": "Test"
@@ -418,9 +769,32 @@ func TestUpdateUser(t *testing.T) {
 
 		s := new(serviceMock.UserServiceMock)
 
-		s.On("Update", mock.Anything, userID, mock.Anything).Return(user.ErrNotFound)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.PUT("/users/:id", userHandler.UpdateUser)
+
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/users/%s", userID), strings.NewReader(reqBody))
+		assert.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		s.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		s := new(serviceMock.UserServiceMock)
+
+		s.On("GetByUUID", mock.Anything, userID).Return(nil, user.ErrNotFound)
 
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -430,7 +804,7 @@ func TestUpdateUser(t *testing.T) {
 		assert.NoError(t, err)
 
 		req.Header.Set("Content-Type", "application/json")
-		req.Body = io.NopCloser(strings.NewReader(reqBody))
+		req.Body = io.NopCloser(strings.NewReader(replaceUserBody))
 
 		recorder := httptest.NewRecorder()
 		router.ServeHTTP(recorder, req)
@@ -440,6 +814,148 @@ func TestUpdateUser(t *testing.T) {
 	})
 }
 
+func TestPatchUser(t *testing.T) {
+	tests := []struct {
+		name            string
+		contentType     string
+		body            string
+		expectedChanges *user.ChangeSet
+		expectedCode    int
+		expectedBody    string
+	}{
+		{
+			name:        "merge patch",
+			contentType: "application/merge-patch+json",
+			body:        `{"phone_number": null, "role": "support", "addresses": [{"type": 2, "street": "Side av", "city": "Boston", "postal_code": "55010"}]}`,
+			expectedChanges: &user.ChangeSet{
+				User: []user.Change{user.Clear(user.FieldPhoneNumber), user.Set(user.FieldRole, "support")},
+				Addresses: []user.AddressChange{
+					{Type: 2, Changes: []user.Change{user.Set(user.FieldCity, "Boston")}},
+					{Type: 1, Remove: true},
+				},
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:        "json patch",
+			contentType: "application/json-patch+json; charset=utf-8",
+			body: `[
+				{"op": "test", "path": "/first_name", "value": "John"},
+				{"op": "replace", "path": "/last_name", "value": "Smith"},
+				{"op": "remove", "path": "/addresses/1"},
+				{"op": "remove", "path": "/addresses/0/state"}
+			]`,
+			expectedChanges: &user.ChangeSet{
+				User: []user.Change{user.Set(user.FieldLastName, "Smith")},
+				Addresses: []user.AddressChange{
+					{Type: 1, Changes: []user.Change{user.Clear(user.FieldState)}},
+					{Type: 2, Remove: true},
+				},
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `"ok"`,
+		},
+		{
+			name:         "unsupported media type",
+			contentType:  "application/json",
+			body:         `{"first_name": "Test"}`,
+			expectedCode: http.StatusUnsupportedMediaType,
+			expectedBody: `{"error":"use application/merge-patch+json or application/json-patch+json"}`,
+		},
+		{
+			name:         "malformed patch",
+			contentType:  "application/json-patch+json",
+			body:         `{"op": "remove", "path": "/phone_number"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "failed test",
+			contentType:  "application/json-patch+json",
+			body:         `[{"op": "test", "path": "/first_name", "value": "Jane"}, {"op": "remove", "path": "/phone_number"}]`,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "read-only member",
+			contentType:  "application/merge-patch+json",
+			body:         `{"email": "other@example.com"}`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "required member removed",
+			contentType:  "application/json-patch+json",
+			body:         `[{"op": "remove", "path": "/first_name"}]`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "duplicate address type",
+			contentType:  "application/json-patch+json",
+			body:         `[{"op": "replace", "path": "/addresses/1/type", "value": 1}]`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"duplicate address type"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New().String()
+
+			s := new(serviceMock.UserServiceMock)
+			s.On("GetByUUID", mock.Anything, userID).Return(storedUser(userID), nil)
+			s.On("Update", mock.Anything, userID, mock.Anything, ptr(int64(3))).Return(nil)
+
+			userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.PATCH("/users/:id", userHandler.PatchUser)
+
+			req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%s", userID), strings.NewReader(tt.body))
+			assert.NoError(t, err)
+
+			req.Header.Set("Content-Type", tt.contentType)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, recorder.Body.String())
+			}
+
+			if tt.expectedChanges != nil {
+				s.AssertCalled(t, "Update", mock.Anything, userID, tt.expectedChanges, ptr(int64(3)))
+			} else {
+				s.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+
+	t.Run("password can not be patched", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		s := new(serviceMock.UserServiceMock)
+		s.On("GetByUUID", mock.Anything, userID).Return(storedUser(userID), nil)
+
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.PATCH("/users/:id", userHandler.PatchUser)
+
+		req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%s", userID), strings.NewReader(`{"password": "securePassword123"}`))
+		assert.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+		s.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
 func TestCreateUser(t *testing.T) {
 	t.Run("successful creation", func(t *testing.T) {
 		reqBody := `{
@@ -463,7 +979,7 @@ func TestCreateUser(t *testing.T) {
 		s := new(serviceMock.UserServiceMock)
 		s.On("Create", mock.Anything, mock.Anything).Return(nil)
 
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -493,7 +1009,7 @@ func TestCreateUser(t *testing.T) {
 		}`
 
 		s := new(serviceMock.UserServiceMock)
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -533,7 +1049,7 @@ func TestCreateUser(t *testing.T) {
 		s := new(serviceMock.UserServiceMock)
 		s.On("Create", mock.Anything, mock.Anything).Return(user.ErrEmailAlreadyExists)
 
-		userHandler := NewUserHTTPHandler(validator.New(), s)
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
 
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
@@ -551,4 +1067,44 @@ func TestCreateUser(t *testing.T) {
 		assert.Equal(t, http.StatusConflict, recorder.Code)
 		assert.Equal(t, `{"error":"email already exists"}`, recorder.Body.String())
 	})
+
+	t.Run("weak password", func(t *testing.T) {
+		reqBody := `{
+			"email": "test@example.com",
+			"password": "password",
+			"first_name": "Test",
+			"last_name": "Test",
+			"phone_number": "1234567890",
+			"addresses": [
+				{
+					"type": 1,
+					"street": "Test",
+					"city": "New York",
+					"postal_code": "55010"
+				}
+			]
+		}`
+
+		s := new(serviceMock.UserServiceMock)
+		s.On("Create", mock.Anything, mock.MatchedBy(func(dto *service.CreateUserDTO) bool {
+			return dto.Password == "password"
+		})).Return(fmt.Errorf("%w: too common", user.ErrWeakPassword))
+
+		userHandler := NewUserHTTPHandler(validator.New(), s, 100, false)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/users", userHandler.CreateUser)
+
+		req, err := http.NewRequest(http.MethodPost, "/users", strings.NewReader(reqBody))
+		assert.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+		assert.Equal(t, `{"error":"password does not meet the policy: too common"}`, recorder.Body.String())
+	})
 }
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type WebhookHTTPHandler struct {
+	validator      *validator.Validate
+	webhookService service.WebhookPort
+	maxPageSize    int
+}
+
+type webhookRequest struct {
+	URL        string   `json:"url" binding:"required" validate:"required,max=2048"`
+	EventTypes []string `json:"event_types" binding:"required" validate:"required,min=1,max=10,dive,required"`
+	Secret     string   `json:"secret" validate:"omitempty,max=255"`
+}
+
+// createWebhookResponse shows the secret once, it is never returned again
+type createWebhookResponse struct {
+	*webhook.Subscription
+	Secret string `json:"secret"`
+}
+
+type listWebhooksResponse struct {
+	Data []*webhook.Subscription `json:"data"`
+}
+
+type listDeliveriesResponse struct {
+	Data       []*webhook.Delivery `json:"data"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+	Links      listUsersLinks      `json:"links"`
+}
+
+func NewWebhookHTTPHandler(v *validator.Validate, webhookService service.WebhookPort, maxPageSize int) *WebhookHTTPHandler {
+	return &WebhookHTTPHandler{
+		validator:      v,
+		webhookService: webhookService,
+		maxPageSize:    maxPageSize,
+	}
+}
+
+func (h *WebhookHTTPHandler) Create(c *gin.Context) {
+	req, ok := h.bind(c)
+	if !ok {
+		return
+	}
+
+	sub, secret, err := h.webhookService.Create(c.Request.Context(), req)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, createWebhookResponse{Subscription: sub, Secret: secret})
+}
+
+func (h *WebhookHTTPHandler) List(c *gin.Context) {
+	subs, err := h.webhookService.List(c.Request.Context())
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	if subs == nil {
+		subs = []*webhook.Subscription{}
+	}
+
+	c.JSON(http.StatusOK, listWebhooksResponse{Data: subs})
+}
+
+func (h *WebhookHTTPHandler) Get(c *gin.Context) {
+	id, ok := webhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.webhookService.Get(c.Request.Context(), id)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// Update replaces the URL and the event types, the secret is rotated only when a new one is given
+func (h *WebhookHTTPHandler) Update(c *gin.Context) {
+	id, ok := webhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	req, ok := h.bind(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.webhookService.Update(c.Request.Context(), id, req)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *WebhookHTTPHandler) Delete(c *gin.Context) {
+	id, ok := webhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.Delete(c.Request.Context(), id); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// ListDeliveries lists the delivery history of the subscription, optionally narrowed down to a status
+func (h *WebhookHTTPHandler) ListDeliveries(c *gin.Context) {
+	subscriptionID, err := domain.ParseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return
+	}
+
+	size := c.DefaultQuery("size", strconv.Itoa(defaultPageSize))
+	iSize, err := strconv.Atoi(size)
+	if err != nil || iSize < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid size param"})
+		return
+	}
+	if iSize > h.maxPageSize {
+		iSize = h.maxPageSize
+	}
+
+	q := &webhook.DeliveryQuery{SubscriptionID: subscriptionID, Limit: iSize}
+
+	if status := c.Query("status"); status != "" {
+		if q.Status, err = webhook.ParseStatus(status); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status param"})
+			return
+		}
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		if q.Cursor, err = webhook.DecodeCursor(cursor); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor param"})
+			return
+		}
+	}
+
+	page, err := h.webhookService.ListDeliveries(c.Request.Context(), q)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	resp := listDeliveriesResponse{
+		Data: page.Deliveries,
+		Links: listUsersLinks{
+			Self: c.Request.URL.RequestURI(),
+		},
+	}
+
+	if resp.Data == nil {
+		resp.Data = []*webhook.Delivery{}
+	}
+
+	if page.NextCursor != nil {
+		resp.NextCursor = page.NextCursor.Encode()
+
+		params := c.Request.URL.Query()
+		params.Set("cursor", resp.NextCursor)
+		resp.Links.Next = c.Request.URL.Path + "?" + params.Encode()
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Redeliver gives a dead delivery another round of attempts
+func (h *WebhookHTTPHandler) Redeliver(c *gin.Context) {
+	id, ok := webhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	deliveryID := c.Param("delivery_id")
+	if _, err := domain.ParseID(deliveryID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery ID"})
+		return
+	}
+
+	if err := h.webhookService.Redeliver(c.Request.Context(), id, deliveryID); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// bind parses and validates the body, it responds with an error and returns false when it is invalid
+func (h *WebhookHTTPHandler) bind(c *gin.Context) (*service.WebhookRequest, bool) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	return &service.WebhookRequest{
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     req.Secret,
+	}, true
+}
+
+func webhookIDParam(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if _, err := domain.ParseID(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return "", false
+	}
+
+	return id, true
+}
+
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+	case errors.Is(err, webhook.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+	case errors.Is(err, webhook.ErrNotDead):
+		c.JSON(http.StatusConflict, gin.H{"error": "only dead deliveries can be redelivered"})
+	case errors.Is(err, webhook.ErrInvalidURL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid url, expected an absolute http or https url"})
+	case errors.Is(err, webhook.ErrPrivateURL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url of localhost or a private network address is not allowed"})
+	case errors.Is(err, webhook.ErrNoEventTypes), errors.Is(err, event.ErrInvalidType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event types"})
+	case errors.Is(err, webhook.ErrInvalidSecret):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "secret must be at least " + strconv.Itoa(webhook.MinSecretLength) + " characters long"})
+	case errors.Is(err, auth.ErrUnauthenticated):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+	case errors.Is(err, auth.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	default:
+		logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"}) // do not leak the actual error reason
+	}
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	serviceMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/applicaion/service"
+)
+
+func newWebhookRouter(s *serviceMock.WebhookServiceMock) *gin.Engine {
+	webhookHandler := NewWebhookHTTPHandler(validator.New(), s, 10)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhooks", webhookHandler.Create)
+	router.GET("/webhooks", webhookHandler.List)
+	router.GET("/webhooks/:id", webhookHandler.Get)
+	router.PUT("/webhooks/:id", webhookHandler.Update)
+	router.DELETE("/webhooks/:id", webhookHandler.Delete)
+	router.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+	router.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+
+	return router
+}
+
+func TestCreateWebhook(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	id := domain.NewID()
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	sub := &webhook.Subscription{
+		ID:              id,
+		URL:             "https://crm.example.com/hooks",
+		EventTypes:      []event.Type{event.TypeUserCreated},
+		EncryptedSecret: "encrypted",
+		CreatedAt:       createdAt,
+		UpdatedAt:       createdAt,
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedReq  *service.WebhookRequest
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "created",
+			body:         `{"url":"https://crm.example.com/hooks","event_types":["user.created"]}`,
+			expectedReq:  &service.WebhookRequest{URL: "https://crm.example.com/hooks", EventTypes: []string{"user.created"}},
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"id":"` + id.String() + `","url":"https://crm.example.com/hooks","event_types":["user.created"],` +
+				`"created_at":"2024-05-01T12:00:00Z","updated_at":"2024-05-01T12:00:00Z","secret":"generated"}`,
+		},
+		{
+			name:         "missing event types",
+			body:         `{"url":"https://crm.example.com/hooks"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid url",
+			body:         `{"url":"crm.example.com","event_types":["user.created"]}`,
+			expectedReq:  &service.WebhookRequest{URL: "crm.example.com", EventTypes: []string{"user.created"}},
+			serviceErr:   webhook.ErrInvalidURL,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid url, expected an absolute http or https url"}`,
+		},
+		{
+			name:         "private url",
+			body:         `{"url":"http://10.0.0.5/hooks","event_types":["user.created"]}`,
+			expectedReq:  &service.WebhookRequest{URL: "http://10.0.0.5/hooks", EventTypes: []string{"user.created"}},
+			serviceErr:   webhook.ErrPrivateURL,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"url of localhost or a private network address is not allowed"}`,
+		},
+		{
+			name:         "unknown event type",
+			body:         `{"url":"https://crm.example.com/hooks","event_types":["user.archived"]}`,
+			expectedReq:  &service.WebhookRequest{URL: "https://crm.example.com/hooks", EventTypes: []string{"user.archived"}},
+			serviceErr:   event.ErrInvalidType,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid event types"}`,
+		},
+		{
+			name:         "short secret",
+			body:         `{"url":"https://crm.example.com/hooks","event_types":["user.created"],"secret":"short"}`,
+			expectedReq:  &service.WebhookRequest{URL: "https://crm.example.com/hooks", EventTypes: []string{"user.created"}, Secret: "short"},
+			serviceErr:   webhook.ErrInvalidSecret,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"secret must be at least 16 characters long"}`,
+		},
+		{
+			name:         "forbidden",
+			body:         `{"url":"https://crm.example.com/hooks","event_types":["user.created"]}`,
+			expectedReq:  &service.WebhookRequest{URL: "https://crm.example.com/hooks", EventTypes: []string{"user.created"}},
+			serviceErr:   auth.ErrForbidden,
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"forbidden"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(serviceMock.WebhookServiceMock)
+			if tt.serviceErr != nil {
+				mockService.On("Create", mock.Anything, tt.expectedReq).Return(nil, "", tt.serviceErr)
+			} else {
+				mockService.On("Create", mock.Anything, tt.expectedReq).Return(sub, "generated", nil)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(tt.body))
+			newWebhookRouter(mockService).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedReq == nil {
+				mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+
+			body, _ := io.ReadAll(w.Body)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetWebhook(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	id := domain.NewID()
+
+	t.Run("secret is not returned", func(t *testing.T) {
+		mockService := new(serviceMock.WebhookServiceMock)
+		mockService.On("Get", mock.Anything, id.String()).Return(&webhook.Subscription{
+			ID:              id,
+			URL:             "https://crm.example.com/hooks",
+			EventTypes:      []event.Type{event.TypeUserDeleted},
+			EncryptedSecret: "encrypted",
+		}, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/webhooks/"+id.String(), nil)
+		newWebhookRouter(mockService).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "secret")
+		assert.NotContains(t, w.Body.String(), "encrypted")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockService := new(serviceMock.WebhookServiceMock)
+		mockService.On("Get", mock.Anything, id.String()).Return(nil, webhook.ErrSubscriptionNotFound)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/webhooks/"+id.String(), nil)
+		newWebhookRouter(mockService).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.JSONEq(t, `{"error":"webhook not found"}`, w.Body.String())
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		mockService := new(serviceMock.WebhookServiceMock)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/webhooks/123", nil)
+		newWebhookRouter(mockService).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	})
+}
+
+func TestListDeliveries(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	subscriptionID := domain.NewID()
+	deliveryID := domain.NewID()
+	eventID := domain.NewID()
+	userID := domain.NewID()
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	next := at.Add(time.Minute)
+
+	delivery := &webhook.Delivery{
+		ID:             deliveryID,
+		Seq:            3,
+		SubscriptionID: subscriptionID,
+		Event: &event.Event{
+			ID:         eventID,
+			Type:       event.TypeUserDeleted,
+			UserID:     userID,
+			Data:       []byte(`{}`),
+			OccurredAt: at,
+		},
+		Status:         webhook.StatusPending,
+		Attempts:       1,
+		NextAttemptAt:  &next,
+		LastAttemptAt:  &at,
+		LastStatusCode: 503,
+		LastError:      "webhook responded with 503",
+		CreatedAt:      at,
+	}
+
+	tests := []struct {
+		name          string
+		url           string
+		expectedQuery *webhook.DeliveryQuery
+		page          *webhook.DeliveryPage
+		serviceErr    error
+		expectedCode  int
+		expectedBody  string
+	}{
+		{
+			name:          "history",
+			url:           "/webhooks/" + subscriptionID.String() + "/deliveries?status=pending",
+			expectedQuery: &webhook.DeliveryQuery{SubscriptionID: subscriptionID, Status: webhook.StatusPending, Limit: defaultPageSize},
+			page:          &webhook.DeliveryPage{Deliveries: []*webhook.Delivery{delivery}, NextCursor: &webhook.Cursor{Seq: 3}},
+			expectedCode:  http.StatusOK,
+			expectedBody: `{"data":[{"id":"` + deliveryID.String() + `","subscription_id":"` + subscriptionID.String() + `",` +
+				`"event":{"id":"` + eventID.String() + `","type":"user.deleted","user_id":"` + userID.String() + `","data":{},"occurred_at":"2024-05-01T12:00:00Z"},` +
+				`"status":"pending","attempts":1,"next_attempt_at":"2024-05-01T12:01:00Z","last_attempt_at":"2024-05-01T12:00:00Z",` +
+				`"last_status_code":503,"last_error":"webhook responded with 503","created_at":"2024-05-01T12:00:00Z"}],"next_cursor":"Mw",` +
+				`"links":{"self":"/webhooks/` + subscriptionID.String() + `/deliveries?status=pending",` +
+				`"next":"/webhooks/` + subscriptionID.String() + `/deliveries?cursor=Mw&status=pending"}}`,
+		},
+		{
+			name:          "last page",
+			url:           "/webhooks/" + subscriptionID.String() + "/deliveries?cursor=Mw&size=50",
+			expectedQuery: &webhook.DeliveryQuery{SubscriptionID: subscriptionID, Cursor: &webhook.Cursor{Seq: 3}, Limit: 10},
+			page:          &webhook.DeliveryPage{},
+			expectedCode:  http.StatusOK,
+			expectedBody:  `{"data":[],"links":{"self":"/webhooks/` + subscriptionID.String() + `/deliveries?cursor=Mw&size=50"}}`,
+		},
+		{
+			name:         "invalid status",
+			url:          "/webhooks/" + subscriptionID.String() + "/deliveries?status=failed",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid status param"}`,
+		},
+		{
+			name:         "invalid cursor",
+			url:          "/webhooks/" + subscriptionID.String() + "/deliveries?cursor=abc",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid cursor param"}`,
+		},
+		{
+			name:          "unknown subscription",
+			url:           "/webhooks/" + subscriptionID.String() + "/deliveries",
+			expectedQuery: &webhook.DeliveryQuery{SubscriptionID: subscriptionID, Limit: defaultPageSize},
+			serviceErr:    webhook.ErrSubscriptionNotFound,
+			expectedCode:  http.StatusNotFound,
+			expectedBody:  `{"error":"webhook not found"}`,
+		},
+		{
+			name:          "internal error",
+			url:           "/webhooks/" + subscriptionID.String() + "/deliveries",
+			expectedQuery: &webhook.DeliveryQuery{SubscriptionID: subscriptionID, Limit: defaultPageSize},
+			serviceErr:    errors.New("connection refused"),
+			expectedCode:  http.StatusInternalServerError,
+			expectedBody:  `{"error":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(serviceMock.WebhookServiceMock)
+			if tt.expectedQuery != nil {
+				mockService.On("ListDeliveries", mock.Anything, tt.expectedQuery).Return(tt.page, tt.serviceErr)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			newWebhookRouter(mockService).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			body, _ := io.ReadAll(w.Body)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			if tt.expectedQuery == nil {
+				mockService.AssertNotCalled(t, "ListDeliveries", mock.Anything, mock.Anything)
+				return
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	subscriptionID := domain.NewID().String()
+	deliveryID := domain.NewID().String()
+	url := "/webhooks/" + subscriptionID + "/deliveries/" + deliveryID + "/redeliver"
+
+	tests := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "redelivered", expectedCode: http.StatusOK, expectedBody: `"ok"`},
+		{name: "not dead", serviceErr: webhook.ErrNotDead, expectedCode: http.StatusConflict, expectedBody: `{"error":"only dead deliveries can be redelivered"}`},
+		{name: "not found", serviceErr: webhook.ErrDeliveryNotFound, expectedCode: http.StatusNotFound, expectedBody: `{"error":"delivery not found"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(serviceMock.WebhookServiceMock)
+			mockService.On("Redeliver", mock.Anything, subscriptionID, deliveryID).Return(tt.serviceErr)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, url, nil)
+			newWebhookRouter(mockService).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
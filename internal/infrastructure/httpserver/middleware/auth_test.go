@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	authMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain/auth"
+)
+
+func newTestRouter(a *Authenticator) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(a.Handle)
+
+	identityHandler := func(c *gin.Context) {
+		identity, ok := auth.IdentityFromContext(c.Request.Context())
+		if !ok {
+			c.JSON(http.StatusOK, gin.H{"identity": "anonymous"})
+			return
+		}
+
+		if identity.IsClient() {
+			c.JSON(http.StatusOK, gin.H{"identity": identity.Client})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"identity": identity.UserID.String()})
+	}
+
+	router.GET("/users/:id", identityHandler)
+	router.DELETE("/users/:id", identityHandler)
+	router.POST("/users", identityHandler)
+	router.POST("/oauth/token", identityHandler)
+
+	return router
+}
+
+func TestAuthenticator(t *testing.T) {
+	userID := domain.NewID()
+
+	tokenProvider := new(authMock.TokenProviderMock)
+	tokenProvider.On("Verify", "valid").Return(&auth.Claims{Subject: userID}, nil)
+	tokenProvider.On("Verify", "expired").Return(nil, auth.ErrInvalidToken)
+
+	a := NewAuthenticator(tokenProvider, []string{"crm:secret-key", "bare-key"}, []string{"GET /users/:id", "* /users/:id"}, []string{"POST /oauth/token"})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		headers        map[string]string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "public route",
+			method:         http.MethodPost,
+			path:           "/users",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"identity":"anonymous"}`,
+		},
+		{
+			name:           "api key on a public route",
+			method:         http.MethodPost,
+			path:           "/users",
+			headers:        map[string]string{"X-API-Key": "secret-key"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"identity":"crm"}`,
+		},
+		{
+			name:           "bearer token on a public route",
+			method:         http.MethodPost,
+			path:           "/users",
+			headers:        map[string]string{"Authorization": "Bearer valid"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"identity":"` + userID.String() + `"}`,
+		},
+		{
+			name:           "invalid credentials on a public route",
+			method:         http.MethodPost,
+			path:           "/users",
+			headers:        map[string]string{"Authorization": "Bearer expired"},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"invalid token"}`,
+		},
+		{
+			name:           "route reading its own credentials",
+			method:         http.MethodPost,
+			path:           "/oauth/token",
+			headers:        map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"identity":"anonymous"}`,
+		},
+		{
+			name:           "missing credentials",
+			method:         http.MethodGet,
+			path:           "/users/" + userID.String(),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"missing credentials"}`,
+		},
+		{
+			name:           "valid bearer token",
+			method:         http.MethodGet,
+			path:           "/users/" + userID.String(),
+			headers:        map[string]string{"Authorization": "Bearer valid"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"identity":"` + userID.String() + `"}`,
+		},
+		{
+			name:           "invalid bearer token",
+			method:         http.MethodGet,
+			path:           "/users/" + userID.String(),
+			headers:        map[string]string{"Authorization": "Bearer expired"},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"invalid token"}`,
+		},
+		{
+			name:           "not a bearer scheme",
+			method:         http.MethodGet,
+			path:           "/users/" + userID.String(),
+			headers:        map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"invalid token"}`,
+		},
+		{
+			name:           "valid api key on a wildcard method",
+			method:         http.MethodDelete,
+			path:           "/users/" + userID.String(),
+			headers:        map[string]string{"X-API-Key": "secret-key"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"identity":"crm"}`,
+		},
+		{
+			name:           "api key without a name",
+			method:         http.MethodGet,
+			path:           "/users/" + userID.String(),
+			headers:        map[string]string{"X-API-Key": "bare-key"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"identity":"api-key"}`,
+		},
+		{
+			name:           "invalid api key",
+			method:         http.MethodGet,
+			path:           "/users/" + userID.String(),
+			headers:        map[string]string{"X-API-Key": "unknown"},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"invalid api key"}`,
+		},
+	}
+
+	router := newTestRouter(a)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, nil)
+			assert.NoError(t, err)
+
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// requestIDPattern limits the IDs taken from callers, anything else is replaced so it can not forge log or audit lines
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestMetadata is a gin middleware putting the request ID and the client IP in the request context.
+// The ID is taken from the X-Request-ID header or generated, and it is echoed in the response.
+func RequestMetadata(c *gin.Context) {
+	requestID := c.GetHeader(requestIDHeader)
+	if !requestIDPattern.MatchString(requestID) {
+		requestID = uuid.NewString()
+	}
+
+	c.Header(requestIDHeader, requestID)
+	c.Request = c.Request.WithContext(audit.WithRequest(c.Request.Context(), audit.Request{
+		ID:       requestID,
+		ClientIP: c.ClientIP(),
+	}))
+
+	c.Next()
+}
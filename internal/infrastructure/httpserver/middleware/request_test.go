@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+)
+
+func TestRequestMetadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestMetadata)
+
+	var request audit.Request
+	router.GET("/", func(c *gin.Context) {
+		request = audit.RequestFromContext(c.Request.Context())
+		c.JSON(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		name      string
+		requestID string
+		generated bool
+	}{
+		{name: "taken from the header", requestID: "req-123"},
+		{name: "generated when missing", generated: true},
+		{name: "generated when too long", requestID: strings.Repeat("a", 129), generated: true},
+		{name: "generated when unsafe", requestID: "req\n123", generated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tt.requestID != "" {
+				req.Header.Set(requestIDHeader, tt.requestID)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "192.0.2.1", request.ClientIP)
+			assert.Equal(t, request.ID, w.Header().Get(requestIDHeader))
+
+			if tt.generated {
+				_, err := uuid.Parse(request.ID)
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.requestID, request.ID)
+			}
+		})
+	}
+}
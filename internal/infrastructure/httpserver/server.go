@@ -14,6 +14,9 @@ import (
 	"github.com/wojciechpawlinow/usermanagement/internal/config"
 	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/database/mysql"
 	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/httpserver/handlers"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/httpserver/middleware"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	"github.com/wojciechpawlinow/usermanagement/pkg/worker"
 )
 
 type Server struct {
@@ -22,21 +25,14 @@ type Server struct {
 }
 
 type shutdownDeps struct {
-	conns *mysql.Connections
+	conns   *mysql.Connections
+	workers []*worker.Worker
 }
 
 // Run is a Server constructor that starts the HTTP server in a goroutine and enables routing
 func Run(cfg config.Provider, ctn di.Container, errChan chan error) *Server {
 
-	userHandler := ctn.Get("http-user").(*handlers.UserHTTPHandler)
-
-	// define routes
-	router := gin.Default()
-	router.POST("/users", userHandler.CreateUser)
-	router.PUT("/users/:id", userHandler.UpdateUser)
-	router.DELETE("/users/:id", userHandler.DeleteUser)
-	router.GET("/users/:id", userHandler.GetUser)
-	router.GET("/users", userHandler.Get)
+	router := NewRouter(ctn)
 
 	s := &Server{
 		&http.Server{
@@ -44,9 +40,26 @@ func Run(cfg config.Provider, ctn di.Container, errChan chan error) *Server {
 			Handler:           router,
 			ReadHeaderTimeout: 5 * time.Second,
 		},
-		shutdownDeps{
-			conns: ctn.Get("mysql-conns").(*mysql.Connections),
-		},
+		shutdownDeps{},
+	}
+
+	// database connections are defined only when the mysql repository driver is in use
+	if conns, err := ctn.SafeGet("mysql-conns"); err == nil {
+		s.shutdownDeps.conns = conns.(*mysql.Connections)
+	}
+
+	// events are published and webhooks delivered in the background for as long as the server runs
+	workers := []string{"worker-outbox", "worker-webhooks"}
+
+	// deleted users are purged by the server unless it is left to the purge command
+	if cfg.GetInt("USERS_PURGE_INTERVAL_MINUTES") > 0 {
+		workers = append(workers, "worker-retention")
+	}
+
+	for _, name := range workers {
+		w := ctn.Get(name).(*worker.Worker)
+		w.Start()
+		s.shutdownDeps.workers = append(s.shutdownDeps.workers, w)
 	}
 
 	go func() {
@@ -60,10 +73,95 @@ func Run(cfg config.Provider, ctn di.Container, errChan chan error) *Server {
 	return s
 }
 
+// NewRouter defines routes and middlewares of the HTTP API
+func NewRouter(ctn di.Container) *gin.Engine {
+	userHandler := ctn.Get("http-user").(*handlers.UserHTTPHandler)
+	authHandler := ctn.Get("http-auth").(*handlers.AuthHTTPHandler)
+	emailHandler := ctn.Get("http-email").(*handlers.EmailHTTPHandler)
+	passwordHandler := ctn.Get("http-password").(*handlers.PasswordHTTPHandler)
+	lockoutHandler := ctn.Get("http-lockout").(*handlers.LockoutHTTPHandler)
+	mfaHandler := ctn.Get("http-mfa").(*handlers.MFAHTTPHandler)
+	sessionHandler := ctn.Get("http-session").(*handlers.SessionHTTPHandler)
+	oauthHandler := ctn.Get("http-oauth").(*handlers.OAuthHTTPHandler)
+	auditHandler := ctn.Get("http-audit").(*handlers.AuditHTTPHandler)
+	privacyHandler := ctn.Get("http-privacy").(*handlers.PrivacyHTTPHandler)
+	webhookHandler := ctn.Get("http-webhook").(*handlers.WebhookHTTPHandler)
+	authenticator := ctn.Get("middleware-auth").(*middleware.Authenticator)
+
+	router := gin.Default()
+	router.Use(middleware.RequestMetadata, authenticator.Handle)
+
+	// failed logins are counted per client IP, which must not be taken from headers set by anyone
+	if err := router.SetTrustedProxies(config.SplitList(config.Load().GetString("HTTP_TRUSTED_PROXIES"))); err != nil {
+		logger.Error(err)
+	}
+
+	router.POST("/users", userHandler.CreateUser)
+	router.PUT("/users/:id", userHandler.UpdateUser)
+	router.PATCH("/users/:id", userHandler.PatchUser)
+	router.DELETE("/users/:id", userHandler.DeleteUser)
+	router.POST("/users/:id/restore", userHandler.RestoreUser)
+	router.GET("/users/:id", userHandler.GetUser)
+	router.GET("/users", userHandler.Get)
+	router.GET("/users/:id/addresses", userHandler.ListAddresses)
+	router.POST("/users/:id/addresses", userHandler.CreateAddress)
+	router.GET("/users/:id/addresses/:type", userHandler.GetAddress)
+	router.PUT("/users/:id/addresses/:type", userHandler.ReplaceAddress)
+	router.DELETE("/users/:id/addresses/:type", userHandler.DeleteAddress)
+	router.POST("/users/:id/email-change", emailHandler.RequestEmailChange)
+	router.POST("/users/:id/password", passwordHandler.ChangePassword)
+	router.GET("/users/:id/lockout", lockoutHandler.GetUserLockout)
+	router.DELETE("/users/:id/lockout", lockoutHandler.UnlockUser)
+	router.DELETE("/lockouts/ip/:ip", lockoutHandler.UnlockIP)
+	router.POST("/users/:id/mfa", mfaHandler.Enroll)
+	router.POST("/users/:id/mfa/confirm", mfaHandler.Confirm)
+	router.POST("/users/:id/mfa/disable", mfaHandler.Disable)
+	router.POST("/users/:id/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+	router.DELETE("/users/:id/mfa", mfaHandler.Reset)
+	router.DELETE("/users/:id/sessions", sessionHandler.RevokeAll)
+	router.GET("/users/:id/audit", auditHandler.ListUser)
+	router.GET("/audit", auditHandler.List)
+	router.GET("/users/:id/export", privacyHandler.ExportUser)
+	router.POST("/users/:id/erase", privacyHandler.EraseUser)
+	router.POST("/webhooks", webhookHandler.Create)
+	router.GET("/webhooks", webhookHandler.List)
+	router.GET("/webhooks/:id", webhookHandler.Get)
+	router.PUT("/webhooks/:id", webhookHandler.Update)
+	router.DELETE("/webhooks/:id", webhookHandler.Delete)
+	router.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+	router.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+	router.POST("/auth/login", authHandler.Login)
+	router.POST("/auth/login/mfa", authHandler.LoginMFA)
+	router.POST("/auth/refresh", sessionHandler.Refresh)
+	router.POST("/auth/logout", sessionHandler.Logout)
+	router.POST("/auth/verify-email", emailHandler.VerifyEmail)
+	router.POST("/auth/password-reset/request", passwordHandler.RequestPasswordReset)
+	router.POST("/auth/password-reset/confirm", passwordHandler.ConfirmPasswordReset)
+	router.GET("/.well-known/openid-configuration", oauthHandler.Discovery)
+	router.GET("/oauth/jwks", oauthHandler.JWKS)
+	router.GET("/oauth/authorize", oauthHandler.Authorize)
+	router.POST("/oauth/authorize", oauthHandler.Consent)
+	router.POST("/oauth/token", oauthHandler.Token)
+	router.GET("/oauth/userinfo", oauthHandler.UserInfo)
+	router.POST("/oauth/clients", oauthHandler.RegisterClient)
+	router.DELETE("/oauth/clients/:id", oauthHandler.DeleteClient)
+
+	return router
+}
+
 // Shutdown is a Shutdown function overload
 func (srv *Server) Shutdown(ctx context.Context) error {
-	srv.shutdownDeps.conns.Read.Close()
-	srv.shutdownDeps.conns.Write.Close()
+	// workers finish the batch in progress before the database connections are closed
+	for _, w := range srv.shutdownDeps.workers {
+		if err := w.Stop(ctx); err != nil {
+			logger.Error(err)
+		}
+	}
+
+	if srv.shutdownDeps.conns != nil {
+		srv.shutdownDeps.conns.Read.Close()
+		srv.shutdownDeps.conns.Write.Close()
+	}
 
 	return srv.Server.Shutdown(ctx)
 }
@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mail"
+)
+
+type fileMailer struct {
+	mu           sync.Mutex
+	path         string
+	from         string
+	timeProvider domain.TimeProvider
+}
+
+var _ mail.Mailer = (*fileMailer)(nil)
+
+// NewFileMailer creates a mailer appending messages to a file, meant for local development only
+func NewFileMailer(path, from string, timeProvider domain.TimeProvider) *fileMailer {
+	return &fileMailer{
+		path:         path,
+		from:         from,
+		timeProvider: timeProvider,
+	}
+}
+
+func (m *fileMailer) Send(_ context.Context, msg *mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed opening mail file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "From: %s\nTo: %s\nDate: %s\nSubject: %s\n\n%s\n\n",
+		m.from,
+		msg.To,
+		m.timeProvider.UtcNow().Format(time.RFC1123Z),
+		msg.Subject,
+		msg.Body,
+	)
+	if err != nil {
+		return fmt.Errorf("failed writing mail file: %w", err)
+	}
+
+	return nil
+}
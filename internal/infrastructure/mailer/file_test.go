@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mail"
+	domainMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain"
+)
+
+func TestFileMailer(t *testing.T) {
+	t.Run("messages are appended", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mail.log")
+
+		mockTimeProvider := new(domainMock.TimeProviderMock)
+		mockTimeProvider.On("UtcNow").Return(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+		m := NewFileMailer(path, "no-reply@example.com", mockTimeProvider)
+
+		assert.NoError(t, m.Send(context.Background(), &mail.Message{To: "john@example.com", Subject: "first", Body: "hello"}))
+		assert.NoError(t, m.Send(context.Background(), &mail.Message{To: "jane@example.com", Subject: "second", Body: "bye"}))
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "From: no-reply@example.com\nTo: john@example.com\nDate: Tue, 02 Jan 2024 03:04:05 +0000\nSubject: first\n\nhello\n\n"+
+			"From: no-reply@example.com\nTo: jane@example.com\nDate: Tue, 02 Jan 2024 03:04:05 +0000\nSubject: second\n\nbye\n\n", string(content))
+	})
+
+	t.Run("unwritable path", func(t *testing.T) {
+		m := NewFileMailer(filepath.Join(t.TempDir(), "missing", "mail.log"), "no-reply@example.com", new(domainMock.TimeProviderMock))
+
+		err := m.Send(context.Background(), &mail.Message{To: "john@example.com"})
+		assert.Error(t, err)
+	})
+}
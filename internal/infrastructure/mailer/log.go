@@ -0,0 +1,29 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mail"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+type logMailer struct {
+	from string
+}
+
+var _ mail.Mailer = (*logMailer)(nil)
+
+// NewLogMailer creates a mailer writing messages to the application log, meant for local development only
+// as the messages carry secrets
+func NewLogMailer(from string) *logMailer {
+	return &logMailer{
+		from: from,
+	}
+}
+
+func (m *logMailer) Send(_ context.Context, msg *mail.Message) error {
+	logger.Info(fmt.Sprintf("mail from %s to %s, subject: %s\n%s", m.from, msg.To, msg.Subject, msg.Body))
+
+	return nil
+}
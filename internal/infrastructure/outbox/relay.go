@@ -0,0 +1,87 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+)
+
+// Relay publishes the events of the outbox in the order they have been added, it is run in the background by a worker.
+// An event is published at least once: when marking it published fails, it is published again once the lease ends.
+type Relay struct {
+	outbox       event.Outbox
+	publisher    event.Publisher
+	uow          domain.UnitOfWork
+	timeProvider domain.TimeProvider
+	batchSize    int
+	lease        time.Duration
+}
+
+// NewRelay creates a relay leasing the events it publishes for the lease, which must be longer than publishing a batch may take,
+// or another relay publishes them again in the meantime
+func NewRelay(
+	outbox event.Outbox,
+	publisher event.Publisher,
+	uow domain.UnitOfWork,
+	timeProvider domain.TimeProvider,
+	batchSize int,
+	lease time.Duration,
+) *Relay {
+	return &Relay{
+		outbox:       outbox,
+		publisher:    publisher,
+		uow:          uow,
+		timeProvider: timeProvider,
+		batchSize:    batchSize,
+		lease:        lease,
+	}
+}
+
+// PublishPending publishes a batch of pending events, more tells the batch has been full, so more events may be waiting.
+// The events are claimed in a transaction of their own and published outside of any, so no lock is held while the publisher
+// is waited for. A failed event stops the batch, so the following events wait for it and are not published out of order.
+func (r *Relay) PublishPending(ctx context.Context) (more bool, err error) {
+	now := r.timeProvider.UtcNow()
+
+	events, err := r.outbox.Claim(ctx, now, now.Add(r.lease), r.batchSize)
+	if err != nil {
+		return false, fmt.Errorf("failed relaying events: %w", err)
+	}
+
+	var (
+		published   []domain.ID
+		unpublished []domain.ID
+		publishErr  error
+	)
+
+	for i, e := range events {
+		if publishErr = r.publisher.Publish(ctx, e); publishErr != nil {
+			publishErr = fmt.Errorf("failed publishing event %s: %w", e.ID, publishErr)
+
+			for _, e := range events[i:] {
+				unpublished = append(unpublished, e.ID)
+			}
+
+			break
+		}
+
+		published = append(published, e.ID)
+	}
+
+	// the events left unpublished are released, so they are retried on the next run rather than once the lease ends
+	err = r.uow.WithinTx(ctx, func(ctx context.Context) error {
+		if err := r.outbox.MarkPublished(ctx, published, r.timeProvider.UtcNow()); err != nil {
+			return err
+		}
+
+		return r.outbox.Release(ctx, unpublished)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed relaying events: %w", err)
+	}
+
+	return publishErr == nil && len(published) == r.batchSize, publishErr
+}
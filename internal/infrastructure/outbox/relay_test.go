@@ -0,0 +1,117 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/database/memory"
+	domainMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain"
+	eventMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain/event"
+)
+
+func newTestOutbox(t *testing.T, n int) (*memory.Database, []*event.Event) {
+	db := memory.NewDatabase()
+	repo := memory.NewOutboxRepository(db)
+
+	var events []*event.Event
+	for i := 0; i < n; i++ {
+		e, err := event.New(domain.NewID(), event.UserDeleted{}, time.Now())
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Add(context.Background(), e))
+
+		events = append(events, e)
+	}
+
+	return db, events
+}
+
+func newTestRelay(db *memory.Database, publisher event.Publisher, batchSize int) *Relay {
+	timeProvider := new(domainMock.TimeProviderMock)
+	timeProvider.On("UtcNow").Return(time.Now())
+
+	return NewRelay(memory.NewOutboxRepository(db), publisher, memory.NewUnitOfWork(db), timeProvider, batchSize, time.Minute)
+}
+
+// pending counts the unpublished events, leased or not, claiming them with a lease that has ended already
+func pending(t *testing.T, db *memory.Database) int {
+	events, err := memory.NewOutboxRepository(db).Claim(context.Background(), time.Now().Add(time.Hour), time.Time{}, 100)
+	assert.NoError(t, err)
+
+	return len(events)
+}
+
+func TestRelay(t *testing.T) {
+	t.Run("events are published in order", func(t *testing.T) {
+		db, events := newTestOutbox(t, 3)
+
+		var order []domain.ID
+		publisher := new(eventMock.PublisherMock)
+		publisher.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			order = append(order, args.Get(1).(*event.Event).ID)
+		}).Return(nil)
+
+		more, err := newTestRelay(db, publisher, 2).PublishPending(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, more)
+		assert.Equal(t, 1, pending(t, db))
+
+		more, err = newTestRelay(db, publisher, 2).PublishPending(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, more)
+		assert.Equal(t, 0, pending(t, db))
+
+		assert.Equal(t, []domain.ID{events[0].ID, events[1].ID, events[2].ID}, order)
+	})
+
+	t.Run("failed event holds back the following ones", func(t *testing.T) {
+		db, events := newTestOutbox(t, 3)
+
+		publisher := new(eventMock.PublisherMock)
+		publisher.On("Publish", mock.Anything, events[0]).Return(nil)
+		publisher.On("Publish", mock.Anything, events[1]).Return(errors.New("unavailable"))
+
+		more, err := newTestRelay(db, publisher, 2).PublishPending(context.Background())
+		assert.Error(t, err)
+		assert.False(t, more)
+		assert.Equal(t, 2, pending(t, db))
+		publisher.AssertNotCalled(t, "Publish", mock.Anything, events[2])
+	})
+
+	t.Run("failed event is released for the next run", func(t *testing.T) {
+		db, events := newTestOutbox(t, 2)
+
+		publisher := new(eventMock.PublisherMock)
+		publisher.On("Publish", mock.Anything, events[0]).Return(errors.New("unavailable")).Once()
+
+		_, err := newTestRelay(db, publisher, 2).PublishPending(context.Background())
+		assert.Error(t, err)
+
+		publisher.On("Publish", mock.Anything, mock.Anything).Return(nil)
+
+		more, err := newTestRelay(db, publisher, 2).PublishPending(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, more)
+		publisher.AssertNumberOfCalls(t, "Publish", 3)
+	})
+
+	t.Run("events claimed by another relay are not published", func(t *testing.T) {
+		db, _ := newTestOutbox(t, 2)
+
+		_, err := memory.NewOutboxRepository(db).Claim(context.Background(), time.Now(), time.Now().Add(time.Minute), 1)
+		assert.NoError(t, err)
+
+		publisher := new(eventMock.PublisherMock)
+
+		more, err := newTestRelay(db, publisher, 2).PublishPending(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, more)
+		publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	})
+}
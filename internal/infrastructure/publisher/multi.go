@@ -0,0 +1,31 @@
+package publisher
+
+import (
+	"context"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+)
+
+type multiPublisher struct {
+	publishers []event.Publisher
+}
+
+var _ event.Publisher = (*multiPublisher)(nil)
+
+// NewMultiPublisher creates a publisher passing every event to all the publishers in order, the first failure stops it.
+// The event is then published again to all of them, as events are published at least once anyway.
+func NewMultiPublisher(publishers ...event.Publisher) *multiPublisher {
+	return &multiPublisher{
+		publishers: publishers,
+	}
+}
+
+func (p *multiPublisher) Publish(ctx context.Context, e *event.Event) error {
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
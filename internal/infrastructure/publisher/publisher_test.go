@@ -0,0 +1,176 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+	domainMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain"
+)
+
+func newTestEvent(t *testing.T) *event.Event {
+	e, err := event.New(domain.NewID(), event.UserDeleted{}, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+
+	return e
+}
+
+func TestStdoutPublisher(t *testing.T) {
+	var out bytes.Buffer
+	e := newTestEvent(t)
+
+	p := NewStdoutPublisher(&out)
+	assert.NoError(t, p.Publish(context.Background(), e))
+	assert.NoError(t, p.Publish(context.Background(), e))
+
+	line, _ := json.Marshal(e)
+	assert.Equal(t, string(line)+"\n"+string(line)+"\n", out.String())
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	t.Run("event is posted", func(t *testing.T) {
+		e := newTestEvent(t)
+
+		var received *http.Request
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		p, err := NewWebhookPublisher(server.URL, time.Second)
+		assert.NoError(t, err)
+		assert.NoError(t, p.Publish(context.Background(), e))
+
+		expected, _ := json.Marshal(e)
+		assert.Equal(t, http.MethodPost, received.Method)
+		assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+		assert.Equal(t, e.ID.String(), received.Header.Get(eventIDHeader))
+		assert.Equal(t, "user.deleted", received.Header.Get(eventTypeHeader))
+		assert.JSONEq(t, string(expected), string(body))
+	})
+
+	t.Run("error response fails the publishing", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		p, err := NewWebhookPublisher(server.URL, time.Second)
+		assert.NoError(t, err)
+		assert.ErrorContains(t, p.Publish(context.Background(), newTestEvent(t)), "503")
+	})
+
+	t.Run("missing URL", func(t *testing.T) {
+		_, err := NewWebhookPublisher("", time.Second)
+		assert.Error(t, err)
+	})
+}
+
+func TestMultiPublisher(t *testing.T) {
+	e := newTestEvent(t)
+
+	var first, second bytes.Buffer
+	assert.NoError(t, NewMultiPublisher(NewStdoutPublisher(&first), NewStdoutPublisher(&second)).Publish(context.Background(), e))
+	assert.NotEmpty(t, first.String())
+	assert.Equal(t, first.String(), second.String())
+
+	failing, err := NewWebhookPublisher("http://127.0.0.1:0", time.Second)
+	assert.NoError(t, err)
+
+	var skipped bytes.Buffer
+	assert.Error(t, NewMultiPublisher(failing, NewStdoutPublisher(&skipped)).Publish(context.Background(), e))
+	assert.Empty(t, skipped.String())
+}
+
+func TestSignedSender(t *testing.T) {
+	secret := []byte("0123456789abcdef")
+	now := time.Date(2024, 5, 1, 12, 0, 5, 0, time.UTC)
+
+	timeProvider := new(domainMock.TimeProviderMock)
+	timeProvider.On("UtcNow").Return(now)
+
+	t.Run("delivery is signed", func(t *testing.T) {
+		d := webhook.NewDelivery(domain.NewID(), newTestEvent(t), now)
+
+		var received *http.Request
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		status, err := NewSignedSender(time.Second, true, timeProvider).Send(context.Background(), server.URL, secret, d)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+
+		expected, _ := json.Marshal(d.Event)
+		assert.JSONEq(t, string(expected), string(body))
+		assert.Equal(t, d.ID.String(), received.Header.Get(deliveryIDHeader))
+		assert.Equal(t, d.Event.ID.String(), received.Header.Get(eventIDHeader))
+		assert.Equal(t, "user.deleted", received.Header.Get(eventTypeHeader))
+		assert.Equal(t, "1714564805", received.Header.Get(timestampHeader))
+		assert.True(t, webhook.Verify(secret, now.Unix(), body, received.Header.Get(signatureHeader)))
+		assert.False(t, webhook.Verify([]byte("another secret!!"), now.Unix(), body, received.Header.Get(signatureHeader)))
+		assert.False(t, webhook.Verify(secret, now.Unix()+1, body, received.Header.Get(signatureHeader)))
+	})
+
+	t.Run("error response fails the delivery", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		d := webhook.NewDelivery(domain.NewID(), newTestEvent(t), now)
+
+		status, err := NewSignedSender(time.Second, true, timeProvider).Send(context.Background(), server.URL, secret, d)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusInternalServerError, status)
+	})
+
+	t.Run("unreachable receiver", func(t *testing.T) {
+		d := webhook.NewDelivery(domain.NewID(), newTestEvent(t), now)
+
+		status, err := NewSignedSender(time.Second, true, timeProvider).Send(context.Background(), "http://127.0.0.1:0", secret, d)
+		assert.Error(t, err)
+		assert.Zero(t, status)
+	})
+
+	t.Run("private networks are refused", func(t *testing.T) {
+		called := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		d := webhook.NewDelivery(domain.NewID(), newTestEvent(t), now)
+
+		status, err := NewSignedSender(time.Second, false, timeProvider).Send(context.Background(), server.URL, secret, d)
+		assert.ErrorIs(t, err, webhook.ErrPrivateURL)
+		assert.Zero(t, status)
+		assert.False(t, called)
+	})
+
+	t.Run("names resolving to private networks are refused", func(t *testing.T) {
+		d := webhook.NewDelivery(domain.NewID(), newTestEvent(t), now)
+
+		status, err := NewSignedSender(time.Second, false, timeProvider).Send(context.Background(), "http://localhost:9/hooks", secret, d)
+		assert.ErrorIs(t, err, webhook.ErrPrivateURL)
+		assert.Zero(t, status)
+	})
+}
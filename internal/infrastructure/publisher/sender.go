@@ -0,0 +1,98 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+)
+
+const (
+	deliveryIDHeader = "X-Webhook-ID"
+	timestampHeader  = "X-Webhook-Timestamp"
+	signatureHeader  = "X-Webhook-Signature"
+)
+
+type signedSender struct {
+	client       *http.Client
+	timeProvider domain.TimeProvider
+}
+
+var _ webhook.Sender = (*signedSender)(nil)
+
+// NewSignedSender creates a sender posting the event of a delivery as JSON, signed with HMAC-SHA256 of the subscription's secret,
+// unless private networks are allowed it refuses to connect to addresses webhook.IsPublicAddr rejects
+func NewSignedSender(timeout time.Duration, allowPrivateNetworks bool, timeProvider domain.TimeProvider) *signedSender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateNetworks {
+		// checked on the resolved address of every connection, redirects and names pointing at internal hosts included
+		dialer.Control = refusePrivateAddr
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+
+	return &signedSender{
+		client:       &http.Client{Timeout: timeout, Transport: transport},
+		timeProvider: timeProvider,
+	}
+}
+
+func refusePrivateAddr(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("failed parsing address: %w", err)
+	}
+
+	if !webhook.IsPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", webhook.ErrPrivateURL, addrPort.Addr())
+	}
+
+	return nil
+}
+
+func (s *signedSender) Send(ctx context.Context, url string, secret []byte, d *webhook.Delivery) (int, error) {
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		return 0, fmt.Errorf("failed encoding event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed creating webhook request: %w", err)
+	}
+
+	timestamp := s.timeProvider.UtcNow().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(deliveryIDHeader, d.ID.String())
+	req.Header.Set(eventIDHeader, d.Event.ID.String())
+	req.Header.Set(eventTypeHeader, string(d.Event.Type))
+	req.Header.Set(timestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(signatureHeader, webhook.Sign(secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed posting delivery: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// the body is drained so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,41 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+)
+
+type stdoutPublisher struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+var _ event.Publisher = (*stdoutPublisher)(nil)
+
+// NewStdoutPublisher creates a publisher writing events as JSON lines, meant for local development only
+func NewStdoutPublisher(out io.Writer) *stdoutPublisher {
+	return &stdoutPublisher{
+		out: out,
+	}
+}
+
+func (p *stdoutPublisher) Publish(_ context.Context, e *event.Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed encoding event: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err = fmt.Fprintf(p.out, "%s\n", line); err != nil {
+		return fmt.Errorf("failed writing event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+)
+
+const (
+	eventIDHeader   = "X-Event-ID"
+	eventTypeHeader = "X-Event-Type"
+)
+
+type webhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+var _ event.Publisher = (*webhookPublisher)(nil)
+
+// NewWebhookPublisher creates a publisher posting every event as JSON to the URL, a response other than 2xx fails the publishing
+func NewWebhookPublisher(url string, timeout time.Duration) (*webhookPublisher, error) {
+	if url == "" {
+		return nil, fmt.Errorf("missing webhook URL")
+	}
+
+	return &webhookPublisher{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (p *webhookPublisher) Publish(ctx context.Context, e *event.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed encoding event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed creating webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(eventIDHeader, e.ID.String())
+	req.Header.Set(eventTypeHeader, string(e.Type))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed posting event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// the body is drained so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package token
+
+import (
+	"errors"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/jwt"
+)
+
+const bearer = "Bearer"
+
+type jwtProvider struct {
+	secret       []byte
+	issuer       string
+	ttl          time.Duration
+	timeProvider domain.TimeProvider
+}
+
+type jwtClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Role      string `json:"role"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+var _ auth.TokenProvider = (*jwtProvider)(nil)
+
+// NewJWTProvider creates a token provider issuing HMAC-SHA256 signed JWTs
+func NewJWTProvider(secret, issuer string, ttl time.Duration, timeProvider domain.TimeProvider) (*jwtProvider, error) {
+	if secret == "" {
+		return nil, errors.New("token signing secret must not be empty")
+	}
+
+	return &jwtProvider{
+		secret:       []byte(secret),
+		issuer:       issuer,
+		ttl:          ttl,
+		timeProvider: timeProvider,
+	}, nil
+}
+
+func (p *jwtProvider) Issue(subject domain.ID, role user.Role) (*auth.Token, error) {
+	now := p.timeProvider.UtcNow()
+
+	accessToken, err := jwt.SignHS256(jwtClaims{
+		Issuer:    p.issuer,
+		Subject:   subject.String(),
+		Role:      string(role),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(p.ttl).Unix(),
+	}, p.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth.Token{
+		AccessToken: accessToken,
+		TokenType:   bearer,
+		ExpiresIn:   p.ttl,
+	}, nil
+}
+
+func (p *jwtProvider) Verify(accessToken string) (*auth.Claims, error) {
+	var claims jwtClaims
+	if err := jwt.ParseHS256(accessToken, p.secret, &claims); err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+
+	if claims.Issuer != p.issuer || p.timeProvider.UtcNow().Unix() >= claims.ExpiresAt {
+		return nil, auth.ErrInvalidToken
+	}
+
+	subject, err := domain.ParseID(claims.Subject)
+	if err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+
+	role, err := user.ParseRole(claims.Role)
+	if err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+
+	return &auth.Claims{
+		Subject:   subject,
+		Role:      role,
+		IssuedAt:  time.Unix(claims.IssuedAt, 0).UTC(),
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0).UTC(),
+	}, nil
+}
@@ -0,0 +1,95 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/pkg/jwt"
+	domainMock "github.com/wojciechpawlinow/usermanagement/tests/mocks/domain"
+)
+
+func TestJWTProvider(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("issue and verify", func(t *testing.T) {
+		mockTimeProvider := new(domainMock.TimeProviderMock)
+		mockTimeProvider.On("UtcNow").Return(now)
+
+		p, err := NewJWTProvider("secret", "issuer", 15*time.Minute, mockTimeProvider)
+		assert.NoError(t, err)
+
+		subject := domain.NewID()
+
+		token, err := p.Issue(subject, user.RoleSupport)
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer", token.TokenType)
+		assert.Equal(t, 15*time.Minute, token.ExpiresIn)
+
+		claims, err := p.Verify(token.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, subject, claims.Subject)
+		assert.Equal(t, user.RoleSupport, claims.Role)
+		assert.Equal(t, now, claims.IssuedAt)
+		assert.Equal(t, now.Add(15*time.Minute), claims.ExpiresAt)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		issueTime := new(domainMock.TimeProviderMock)
+		issueTime.On("UtcNow").Return(now)
+		verifyTime := new(domainMock.TimeProviderMock)
+		verifyTime.On("UtcNow").Return(now.Add(16 * time.Minute))
+
+		issuer, _ := NewJWTProvider("secret", "issuer", 15*time.Minute, issueTime)
+		verifier, _ := NewJWTProvider("secret", "issuer", 15*time.Minute, verifyTime)
+
+		token, err := issuer.Issue(domain.NewID(), user.RoleSelf)
+		assert.NoError(t, err)
+
+		_, err = verifier.Verify(token.AccessToken)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("foreign issuer", func(t *testing.T) {
+		mockTimeProvider := new(domainMock.TimeProviderMock)
+		mockTimeProvider.On("UtcNow").Return(now)
+
+		issuer, _ := NewJWTProvider("secret", "other", 15*time.Minute, mockTimeProvider)
+		verifier, _ := NewJWTProvider("secret", "issuer", 15*time.Minute, mockTimeProvider)
+
+		token, err := issuer.Issue(domain.NewID(), user.RoleSelf)
+		assert.NoError(t, err)
+
+		_, err = verifier.Verify(token.AccessToken)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("oidc access token", func(t *testing.T) {
+		mockTimeProvider := new(domainMock.TimeProviderMock)
+		mockTimeProvider.On("UtcNow").Return(now)
+
+		p, _ := NewJWTProvider("secret", "issuer", 15*time.Minute, mockTimeProvider)
+
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		assert.NoError(t, err)
+
+		// the tokens issued to OIDC clients are signed with the keys of the key ring and must not be accepted by the API
+		token, err := jwt.Sign(&oidc.AccessTokenClaims{Issuer: "issuer", Subject: domain.NewID().String(), ExpiresAt: now.Add(time.Minute).Unix()}, "kid", key)
+		assert.NoError(t, err)
+
+		_, err = p.Verify(token)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("empty secret", func(t *testing.T) {
+		_, err := NewJWTProvider("", "issuer", 15*time.Minute, new(domainMock.TimeProviderMock))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,216 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+	"github.com/wojciechpawlinow/usermanagement/pkg/jwt"
+)
+
+const rsaKeyBits = 2048
+
+type keyRing struct {
+	keyRepo      oidc.KeyRepository
+	cipher       oidc.Cipher
+	algorithm    string
+	rotation     time.Duration
+	timeProvider domain.TimeProvider
+
+	mu      sync.Mutex
+	signers map[string]crypto.Signer // decrypted private keys by key ID
+}
+
+var _ oidc.Signer = (*keyRing)(nil)
+
+// NewKeyRing creates a signer generating a new key of the algorithm, RS256 or EdDSA, once the current one is older than the rotation.
+// A replaced key is published for one more rotation, so tokens signed with it must expire sooner than that.
+func NewKeyRing(keyRepo oidc.KeyRepository, cipher oidc.Cipher, algorithm string, rotation time.Duration, timeProvider domain.TimeProvider) (*keyRing, error) {
+	if algorithm != jwt.AlgRS256 && algorithm != jwt.AlgEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	if rotation <= 0 {
+		return nil, errors.New("signing key rotation must be positive")
+	}
+
+	return &keyRing{
+		keyRepo:      keyRepo,
+		cipher:       cipher,
+		algorithm:    algorithm,
+		rotation:     rotation,
+		timeProvider: timeProvider,
+		signers:      make(map[string]crypto.Signer),
+	}, nil
+}
+
+func (r *keyRing) Sign(ctx context.Context, claims any) (string, error) {
+	keyID, signer, err := r.current(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return jwt.Sign(claims, keyID, signer)
+}
+
+func (r *keyRing) PublicKeys(ctx context.Context) ([]oidc.PublicKey, error) {
+	keys, err := r.keyRepo.List(ctx, r.timeProvider.UtcNow().Add(-2*r.rotation))
+	if err != nil {
+		return nil, fmt.Errorf("failed listing signing keys: %w", err)
+	}
+
+	publicKeys := make([]oidc.PublicKey, 0, len(keys))
+
+	for _, k := range keys {
+		key, err := x509.ParsePKIXPublicKey(k.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing public key %s: %w", k.ID, err)
+		}
+
+		publicKeys = append(publicKeys, oidc.PublicKey{ID: k.ID, Key: key})
+	}
+
+	return publicKeys, nil
+}
+
+// Verify accepts tokens signed with a published key only, anything wrong with the token is ErrInvalidToken
+func (r *keyRing) Verify(ctx context.Context, token string, dst any) error {
+	keyID, err := jwt.KeyID(token)
+	if err != nil {
+		return auth.ErrInvalidToken
+	}
+
+	keys, err := r.PublicKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if k.ID != keyID {
+			continue
+		}
+
+		if err = jwt.Verify(token, k.Key, dst); err != nil {
+			return auth.ErrInvalidToken
+		}
+
+		return nil
+	}
+
+	return auth.ErrInvalidToken
+}
+
+// current returns the newest key of the algorithm, a new one is generated when it is due to be rotated.
+// Instances generating a key at the same time publish all of them, each signs with the one it has found or generated.
+func (r *keyRing) current(ctx context.Context) (string, crypto.Signer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.timeProvider.UtcNow()
+
+	keys, err := r.keyRepo.List(ctx, now.Add(-r.rotation))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed listing signing keys: %w", err)
+	}
+
+	for _, k := range keys {
+		if k.Algorithm != r.algorithm {
+			continue
+		}
+
+		if signer, ok := r.signers[k.ID]; ok {
+			return k.ID, signer, nil
+		}
+
+		signer, err := r.decrypt(k)
+		if err != nil {
+			return "", nil, err
+		}
+
+		r.signers[k.ID] = signer
+
+		return k.ID, signer, nil
+	}
+
+	k, signer, err := r.generate(now)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err = r.keyRepo.Create(ctx, k); err != nil {
+		return "", nil, fmt.Errorf("failed storing signing key: %w", err)
+	}
+
+	r.signers[k.ID] = signer
+
+	return k.ID, signer, nil
+}
+
+func (r *keyRing) generate(now time.Time) (*oidc.SigningKey, crypto.Signer, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+
+	switch r.algorithm {
+	case jwt.AlgRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed generating signing key: %w", err)
+	}
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed encoding private key: %w", err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed encoding public key: %w", err)
+	}
+
+	encrypted, err := r.cipher.Encrypt(privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed encrypting private key: %w", err)
+	}
+
+	return &oidc.SigningKey{
+		ID:         domain.NewID().String(),
+		Algorithm:  r.algorithm,
+		PrivateKey: encrypted,
+		PublicKey:  publicKey,
+		CreatedAt:  now,
+	}, signer, nil
+}
+
+func (r *keyRing) decrypt(k *oidc.SigningKey) (crypto.Signer, error) {
+	privateKey, err := r.cipher.Decrypt(k.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed decrypting private key %s: %w", k.ID, err)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing private key %s: %w", k.ID, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key %s can not sign", k.ID)
+	}
+
+	return signer, nil
+}
@@ -0,0 +1,131 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/cipher"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/database/memory"
+	"github.com/wojciechpawlinow/usermanagement/pkg/jwt"
+)
+
+// clock is a time provider moved forward by the test
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) UtcNow() time.Time {
+	return c.now
+}
+
+func newKeyRing(t *testing.T, algorithm string, c *clock) *keyRing {
+	aead, err := cipher.NewAESGCM(bytes.Repeat([]byte{1}, 32))
+	assert.NoError(t, err)
+
+	r, err := NewKeyRing(memory.NewSigningKeyRepository(memory.NewDatabase()), aead, algorithm, 24*time.Hour, c)
+	assert.NoError(t, err)
+
+	return r
+}
+
+// verify checks the token against the published key of its key ID
+func verify(t *testing.T, r *keyRing, token string) string {
+	keyID, err := jwt.KeyID(token)
+	assert.NoError(t, err)
+
+	keys, err := r.PublicKeys(context.Background())
+	assert.NoError(t, err)
+
+	for _, k := range keys {
+		if k.ID == keyID {
+			var claims oidc.IDTokenClaims
+			assert.NoError(t, jwt.Verify(token, k.Key, &claims))
+			assert.Equal(t, "issuer", claims.Issuer)
+
+			return keyID
+		}
+	}
+
+	t.Fatalf("key %s is not published", keyID)
+
+	return ""
+}
+
+func TestKeyRing(t *testing.T) {
+	ctx := context.Background()
+	claims := &oidc.IDTokenClaims{Issuer: "issuer", UserClaims: &oidc.UserClaims{Subject: "subject"}}
+
+	for _, algorithm := range []string{jwt.AlgRS256, jwt.AlgEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			c := &clock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+			r := newKeyRing(t, algorithm, c)
+
+			token, err := r.Sign(ctx, claims)
+			assert.NoError(t, err)
+			first := verify(t, r, token)
+
+			// the key is reused until it is due to be rotated
+			c.now = c.now.Add(23 * time.Hour)
+
+			token, err = r.Sign(ctx, claims)
+			assert.NoError(t, err)
+			assert.Equal(t, first, verify(t, r, token))
+
+			c.now = c.now.Add(2 * time.Hour)
+
+			token, err = r.Sign(ctx, claims)
+			assert.NoError(t, err)
+			second := verify(t, r, token)
+			assert.NotEqual(t, first, second)
+
+			// the replaced key is published for one more rotation
+			keys, err := r.PublicKeys(ctx)
+			assert.NoError(t, err)
+			assert.Len(t, keys, 2)
+
+			c.now = c.now.Add(24 * time.Hour)
+
+			keys, err = r.PublicKeys(ctx)
+			assert.NoError(t, err)
+			assert.Len(t, keys, 1)
+			assert.Equal(t, second, keys[0].ID)
+		})
+	}
+
+	t.Run("verify", func(t *testing.T) {
+		c := &clock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+		r := newKeyRing(t, jwt.AlgEdDSA, c)
+
+		token, err := r.Sign(ctx, &oidc.AccessTokenClaims{Issuer: "issuer", Scope: "openid"})
+		assert.NoError(t, err)
+
+		var verified oidc.AccessTokenClaims
+		assert.NoError(t, r.Verify(ctx, token, &verified))
+		assert.Equal(t, "openid", verified.Scope)
+
+		// signed by another instance with its own keys
+		foreign, err := newKeyRing(t, jwt.AlgEdDSA, c).Sign(ctx, claims)
+		assert.NoError(t, err)
+		assert.ErrorIs(t, r.Verify(ctx, foreign, &verified), auth.ErrInvalidToken)
+
+		// the API tokens are signed with the shared secret
+		api, err := jwt.SignHS256(claims, []byte("secret"))
+		assert.NoError(t, err)
+		assert.ErrorIs(t, r.Verify(ctx, api, &verified), auth.ErrInvalidToken)
+
+		// the key is not published anymore
+		c.now = c.now.Add(49 * time.Hour)
+		assert.ErrorIs(t, r.Verify(ctx, token, &verified), auth.ErrInvalidToken)
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, err := NewKeyRing(memory.NewSigningKeyRepository(memory.NewDatabase()), nil, "HS256", time.Hour, &clock{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,359 @@
+// Package jsonpatch applies JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902) documents to JSON documents
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch")
+	ErrConflict     = errors.New("patch can not be applied")
+)
+
+// MergePatch applies a JSON Merge Patch, null members of the patch remove the members of the document
+// and arrays are always replaced as a whole
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("failed decoding document: %w", err)
+	}
+
+	var p any
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+// Apply applies a JSON Patch, the operations are applied in order and the first failing one aborts the whole patch
+func Apply(doc, patch []byte) ([]byte, error) {
+	var target any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("failed decoding document: %w", err)
+	}
+
+	var ops []map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	for i, raw := range ops {
+		op, err := parseOperation(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+		}
+
+		if target, err = op.apply(target); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+type operation struct {
+	op    string
+	path  []string
+	from  []string
+	value any
+}
+
+func parseOperation(raw map[string]json.RawMessage) (*operation, error) {
+	op := &operation{}
+
+	if err := json.Unmarshal(raw["op"], &op.op); err != nil {
+		return nil, errors.New("missing op")
+	}
+
+	var path string
+	if err := json.Unmarshal(raw["path"], &path); err != nil {
+		return nil, errors.New("missing path")
+	}
+
+	var err error
+	if op.path, err = parsePointer(path); err != nil {
+		return nil, err
+	}
+
+	switch op.op {
+	case "add", "replace", "test":
+		value, ok := raw["value"]
+		if !ok {
+			return nil, errors.New("missing value")
+		}
+		if err = json.Unmarshal(value, &op.value); err != nil {
+			return nil, err
+		}
+	case "move", "copy":
+		var from string
+		if err = json.Unmarshal(raw["from"], &from); err != nil {
+			return nil, errors.New("missing from")
+		}
+		if op.from, err = parsePointer(from); err != nil {
+			return nil, err
+		}
+	case "remove":
+	default:
+		return nil, fmt.Errorf("unknown op %q", op.op)
+	}
+
+	return op, nil
+}
+
+func (op *operation) apply(doc any) (any, error) {
+	switch op.op {
+	case "add":
+		return add(doc, op.path, op.value)
+	case "remove":
+		return remove(doc, op.path)
+	case "replace":
+		doc, err := remove(doc, op.path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.path, op.value)
+	case "move":
+		if isProperPrefix(op.from, op.path) {
+			return nil, fmt.Errorf("%w: can not move a value into itself", ErrConflict)
+		}
+		value, err := get(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = remove(doc, op.from); err != nil {
+			return nil, err
+		}
+		return add(doc, op.path, value)
+	case "copy":
+		value, err := get(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.path, deepCopy(value))
+	case "test":
+		value, err := get(doc, op.path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, op.value) {
+			return nil, fmt.Errorf("%w: test failed", ErrConflict)
+		}
+		return doc, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.op)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func get(node any, path []string) (any, error) {
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]any:
+			value, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: missing member %q", ErrConflict, token)
+			}
+			node = value
+		case []any:
+			i, err := index(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("%w: %q does not point into a container", ErrConflict, token)
+		}
+	}
+
+	return node, nil
+}
+
+func add(node any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token, rest := path[0], path[1:]
+
+	switch n := node.(type) {
+	case map[string]any:
+		if len(rest) == 0 {
+			n[token] = value
+			return n, nil
+		}
+
+		child, ok := n[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: missing member %q", ErrConflict, token)
+		}
+
+		child, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = child
+
+		return n, nil
+	case []any:
+		if len(rest) == 0 {
+			i := len(n)
+			if token != "-" {
+				var err error
+				if i, err = index(token, len(n)); err != nil {
+					return nil, err
+				}
+			}
+
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = value
+
+			return n, nil
+		}
+
+		i, err := index(token, len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+
+		if n[i], err = add(n[i], rest, value); err != nil {
+			return nil, err
+		}
+
+		return n, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q does not point into a container", ErrConflict, token)
+}
+
+func remove(node any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: can not remove the whole document", ErrConflict)
+	}
+
+	token, rest := path[0], path[1:]
+
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: missing member %q", ErrConflict, token)
+		}
+
+		if len(rest) == 0 {
+			delete(n, token)
+			return n, nil
+		}
+
+		child, err := remove(child, rest)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = child
+
+		return n, nil
+	case []any:
+		i, err := index(token, len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rest) == 0 {
+			return append(n[:i], n[i+1:]...), nil
+		}
+
+		if n[i], err = remove(n[i], rest); err != nil {
+			return nil, err
+		}
+
+		return n, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q does not point into a container", ErrConflict, token)
+}
+
+// index parses an array index, leading zeros are not allowed
+func index(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrConflict, token)
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrConflict, token)
+	}
+
+	return i, nil
+}
+
+func isProperPrefix(prefix, path []string) bool {
+	if len(prefix) >= len(path) {
+		return false
+	}
+
+	return reflect.DeepEqual(prefix, path[:len(prefix)])
+}
+
+func deepCopy(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for key, element := range v {
+			c[key] = deepCopy(element)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, element := range v {
+			c[i] = deepCopy(element)
+		}
+		return c
+	}
+
+	return value
+}
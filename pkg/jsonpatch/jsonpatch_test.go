@@ -0,0 +1,126 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}{
+		{name: "set member", doc: `{"a":"b"}`, patch: `{"a":"c"}`, expected: `{"a":"c"}`},
+		{name: "add member", doc: `{"a":"b"}`, patch: `{"b":"c"}`, expected: `{"a":"b","b":"c"}`},
+		{name: "remove member", doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, expected: `{"b":"c"}`},
+		{name: "replace array", doc: `{"a":["b","c"]}`, patch: `{"a":["d"]}`, expected: `{"a":["d"]}`},
+		{name: "nested object", doc: `{"a":{"b":"c","d":"e"}}`, patch: `{"a":{"b":null,"f":"g"}}`, expected: `{"a":{"d":"e","f":"g"}}`},
+		{name: "replace document", doc: `{"a":"b"}`, patch: `["c"]`, expected: `["c"]`},
+		{name: "empty patch", doc: `{"a":"b"}`, patch: `{}`, expected: `{"a":"b"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(result))
+		})
+	}
+
+	t.Run("invalid patch", func(t *testing.T) {
+		_, err := MergePatch([]byte(`{}`), []byte(`{`))
+		assert.ErrorIs(t, err, ErrInvalidPatch)
+	})
+}
+
+func TestApply(t *testing.T) {
+	doc := `{"a":"b","list":[1,2,3],"obj":{"x/y":1,"m~n":2}}`
+
+	tests := []struct {
+		name     string
+		patch    string
+		expected string
+	}{
+		{
+			name:     "add member",
+			patch:    `[{"op":"add","path":"/c","value":"d"}]`,
+			expected: `{"a":"b","c":"d","list":[1,2,3],"obj":{"x/y":1,"m~n":2}}`,
+		},
+		{
+			name:     "add to array",
+			patch:    `[{"op":"add","path":"/list/1","value":9},{"op":"add","path":"/list/-","value":10}]`,
+			expected: `{"a":"b","list":[1,9,2,3,10],"obj":{"x/y":1,"m~n":2}}`,
+		},
+		{
+			name:     "remove escaped members",
+			patch:    `[{"op":"remove","path":"/obj/x~1y"},{"op":"remove","path":"/obj/m~0n"}]`,
+			expected: `{"a":"b","list":[1,2,3],"obj":{}}`,
+		},
+		{
+			name:     "remove from array",
+			patch:    `[{"op":"remove","path":"/list/0"}]`,
+			expected: `{"a":"b","list":[2,3],"obj":{"x/y":1,"m~n":2}}`,
+		},
+		{
+			name:     "replace with null",
+			patch:    `[{"op":"replace","path":"/a","value":null}]`,
+			expected: `{"a":null,"list":[1,2,3],"obj":{"x/y":1,"m~n":2}}`,
+		},
+		{
+			name:     "move",
+			patch:    `[{"op":"move","from":"/a","path":"/obj/a"}]`,
+			expected: `{"list":[1,2,3],"obj":{"x/y":1,"m~n":2,"a":"b"}}`,
+		},
+		{
+			name:     "copy",
+			patch:    `[{"op":"copy","from":"/list","path":"/copy"},{"op":"remove","path":"/copy/0"}]`,
+			expected: `{"a":"b","list":[1,2,3],"copy":[2,3],"obj":{"x/y":1,"m~n":2}}`,
+		},
+		{
+			name:     "successful test",
+			patch:    `[{"op":"test","path":"/list","value":[1,2,3]},{"op":"test","path":"/obj/x~1y","value":1.0}]`,
+			expected: doc,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Apply([]byte(doc), []byte(tt.patch))
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(result))
+		})
+	}
+
+	failures := []struct {
+		name     string
+		patch    string
+		expected error
+	}{
+		{name: "not an array", patch: `{"op":"add"}`, expected: ErrInvalidPatch},
+		{name: "unknown op", patch: `[{"op":"merge","path":"/a"}]`, expected: ErrInvalidPatch},
+		{name: "missing value", patch: `[{"op":"add","path":"/a"}]`, expected: ErrInvalidPatch},
+		{name: "invalid pointer", patch: `[{"op":"remove","path":"a"}]`, expected: ErrInvalidPatch},
+		{name: "failed test", patch: `[{"op":"test","path":"/a","value":"c"}]`, expected: ErrConflict},
+		{name: "missing member", patch: `[{"op":"remove","path":"/missing"}]`, expected: ErrConflict},
+		{name: "missing parent", patch: `[{"op":"add","path":"/missing/a","value":1}]`, expected: ErrConflict},
+		{name: "index out of range", patch: `[{"op":"add","path":"/list/4","value":1}]`, expected: ErrConflict},
+		{name: "leading zero index", patch: `[{"op":"remove","path":"/list/01"}]`, expected: ErrConflict},
+		{name: "move into itself", patch: `[{"op":"move","from":"/obj","path":"/obj/a"}]`, expected: ErrConflict},
+	}
+
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Apply([]byte(doc), []byte(tt.patch))
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+
+	t.Run("failing operation aborts the patch", func(t *testing.T) {
+		result, err := Apply([]byte(doc), []byte(`[{"op":"remove","path":"/a"},{"op":"test","path":"/a","value":"b"}]`))
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Nil(t, result)
+	})
+}
@@ -0,0 +1,81 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"math/big"
+)
+
+const (
+	keyTypeRSA = "RSA"
+	keyTypeOKP = "OKP"
+	curve25519 = "Ed25519"
+)
+
+// JWK is a public key in the JSON Web Key format (RFC 7517), published for verifiers of signed tokens
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Modulus   string `json:"n,omitempty"`
+	Exponent  string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKS is a set of public keys
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK describes an RSA or Ed25519 public key used to verify signatures
+func NewJWK(keyID string, key crypto.PublicKey) (JWK, error) {
+	alg, err := Algorithm(key)
+	if err != nil {
+		return JWK{}, err
+	}
+
+	jwk := JWK{KeyID: keyID, Use: "sig", Algorithm: alg}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = keyTypeRSA
+		jwk.Modulus = encode(k.N.Bytes())
+		jwk.Exponent = encode(big.NewInt(int64(k.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = keyTypeOKP
+		jwk.Curve = curve25519
+		jwk.X = encode(k)
+	}
+
+	return jwk, nil
+}
+
+// PublicKey decodes the key, so tokens can be verified with a published set
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch {
+	case k.KeyType == keyTypeRSA:
+		n, err := decode(k.Modulus)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+
+		e, err := decode(k.Exponent)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case k.KeyType == keyTypeOKP && k.Curve == curve25519:
+		x, err := decode(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, ErrMalformed
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, ErrUnsupportedAlg
+	}
+}
@@ -0,0 +1,49 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWK(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	t.Run("rsa", func(t *testing.T) {
+		jwk, err := NewJWK("key-1", rsaKey.Public())
+		assert.NoError(t, err)
+		assert.Equal(t, "RSA", jwk.KeyType)
+		assert.Equal(t, AlgRS256, jwk.Algorithm)
+		assert.Equal(t, "AQAB", jwk.Exponent)
+
+		key, err := jwk.PublicKey()
+		assert.NoError(t, err)
+		assert.True(t, rsaKey.PublicKey.Equal(key))
+	})
+
+	t.Run("ed25519", func(t *testing.T) {
+		jwk, err := NewJWK("key-2", edPublic)
+		assert.NoError(t, err)
+		assert.Equal(t, JWK{KeyType: "OKP", KeyID: "key-2", Use: "sig", Algorithm: AlgEdDSA, Curve: "Ed25519", X: encode(edPublic)}, jwk)
+
+		key, err := jwk.PublicKey()
+		assert.NoError(t, err)
+		assert.True(t, edPublic.Equal(key))
+	})
+
+	t.Run("unsupported key", func(t *testing.T) {
+		_, err := NewJWK("key-3", crypto.PublicKey("not-a-key"))
+		assert.ErrorIs(t, err, ErrUnsupportedAlg)
+
+		_, err = JWK{KeyType: "EC"}.PublicKey()
+		assert.ErrorIs(t, err, ErrUnsupportedAlg)
+	})
+}
@@ -0,0 +1,219 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrMalformed        = errors.New("malformed token")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrUnsupportedAlg   = errors.New("unsupported signing algorithm")
+)
+
+const (
+	algHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// SignHS256 encodes the claims as a compact JWS signed with HMAC-SHA256
+func SignHS256(claims any, secret []byte) (string, error) {
+	h, err := json.Marshal(header{Alg: algHS256, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed encoding claims: %w", err)
+	}
+
+	signingInput := encode(h) + "." + encode(c)
+
+	return signingInput + "." + encode(hs256(signingInput, secret)), nil
+}
+
+// ParseHS256 verifies the HMAC-SHA256 signature of the token and decodes its claims into dst.
+// Validation of the claims themselves (expiry, issuer etc.) is up to the caller.
+func ParseHS256(token string, secret []byte, dst any) error {
+	h, parts, err := split(token)
+	if err != nil {
+		return err
+	}
+
+	if h.Alg != algHS256 {
+		return ErrUnsupportedAlg
+	}
+
+	signature, err := decode(parts[2])
+	if err != nil {
+		return ErrMalformed
+	}
+
+	if !hmac.Equal(signature, hs256(parts[0]+"."+parts[1], secret)) {
+		return ErrInvalidSignature
+	}
+
+	return decodeClaims(parts[1], dst)
+}
+
+// Sign encodes the claims as a compact JWS signed with the private key, with RS256 for RSA keys and EdDSA for Ed25519 keys.
+// The key ID tells verifiers which of the published keys to verify the signature with.
+func Sign(claims any, keyID string, key crypto.Signer) (string, error) {
+	alg, err := Algorithm(key.Public())
+	if err != nil {
+		return "", err
+	}
+
+	h, err := json.Marshal(header{Alg: alg, Typ: "JWT", Kid: keyID})
+	if err != nil {
+		return "", err
+	}
+
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed encoding claims: %w", err)
+	}
+
+	signingInput := encode(h) + "." + encode(c)
+
+	var signature []byte
+
+	switch alg {
+	case AlgRS256:
+		digest := sha256.Sum256([]byte(signingInput))
+		signature, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		signature, err = key.Sign(rand.Reader, []byte(signingInput), crypto.Hash(0))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed signing token: %w", err)
+	}
+
+	return signingInput + "." + encode(signature), nil
+}
+
+// Verify checks the signature of the token against the public key and decodes its claims into dst.
+// Validation of the claims themselves is up to the caller, as with ParseHS256.
+func Verify(token string, key crypto.PublicKey, dst any) error {
+	h, parts, err := split(token)
+	if err != nil {
+		return err
+	}
+
+	alg, err := Algorithm(key)
+	if err != nil {
+		return err
+	}
+
+	// the algorithm comes from the key, so a token can not pick a weaker one
+	if h.Alg != alg {
+		return ErrUnsupportedAlg
+	}
+
+	signature, err := decode(parts[2])
+	if err != nil {
+		return ErrMalformed
+	}
+
+	signingInput := parts[0] + "." + parts[1]
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) != nil {
+			return ErrInvalidSignature
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, []byte(signingInput), signature) {
+			return ErrInvalidSignature
+		}
+	}
+
+	return decodeClaims(parts[1], dst)
+}
+
+// KeyID reads the ID of the signing key from the header without verifying the token, to pick the key to verify it with
+func KeyID(token string) (string, error) {
+	h, _, err := split(token)
+	if err != nil {
+		return "", err
+	}
+
+	return h.Kid, nil
+}
+
+// Algorithm returns the algorithm of signatures made with the private key of the public key
+func Algorithm(key crypto.PublicKey) (string, error) {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return AlgRS256, nil
+	case ed25519.PublicKey:
+		return AlgEdDSA, nil
+	default:
+		return "", ErrUnsupportedAlg
+	}
+}
+
+func split(token string) (header, []string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return header{}, nil, ErrMalformed
+	}
+
+	rawHeader, err := decode(parts[0])
+	if err != nil {
+		return header{}, nil, ErrMalformed
+	}
+
+	var h header
+	if err = json.Unmarshal(rawHeader, &h); err != nil {
+		return header{}, nil, ErrMalformed
+	}
+
+	return h, parts, nil
+}
+
+func decodeClaims(part string, dst any) error {
+	rawClaims, err := decode(part)
+	if err != nil {
+		return ErrMalformed
+	}
+
+	if err = json.Unmarshal(rawClaims, dst); err != nil {
+		return ErrMalformed
+	}
+
+	return nil
+}
+
+func hs256(signingInput string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+
+	return mac.Sum(nil)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,130 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testClaims struct {
+	Subject string `json:"sub"`
+	Expiry  int64  `json:"exp"`
+}
+
+func TestSignAndParseHS256(t *testing.T) {
+	secret := []byte("secret")
+
+	t.Run("round trip", func(t *testing.T) {
+		token, err := SignHS256(testClaims{Subject: "user", Expiry: 123}, secret)
+		assert.NoError(t, err)
+		assert.Len(t, strings.Split(token, "."), 3)
+
+		var claims testClaims
+		err = ParseHS256(token, secret, &claims)
+		assert.NoError(t, err)
+		assert.Equal(t, testClaims{Subject: "user", Expiry: 123}, claims)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		token, _ := SignHS256(testClaims{Subject: "user"}, secret)
+
+		var claims testClaims
+		err := ParseHS256(token, []byte("other"), &claims)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("tampered claims", func(t *testing.T) {
+		token, _ := SignHS256(testClaims{Subject: "user"}, secret)
+		forged, _ := SignHS256(testClaims{Subject: "admin"}, secret)
+
+		parts := strings.Split(token, ".")
+		parts[1] = strings.Split(forged, ".")[1]
+
+		var claims testClaims
+		err := ParseHS256(strings.Join(parts, "."), secret, &claims)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		// {"alg":"none","typ":"JWT"}
+		token := "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.eyJzdWIiOiJ1c2VyIn0."
+
+		var claims testClaims
+		err := ParseHS256(token, secret, &claims)
+		assert.ErrorIs(t, err, ErrUnsupportedAlg)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		var claims testClaims
+		err := ParseHS256("not-a-token", secret, &claims)
+		assert.ErrorIs(t, err, ErrMalformed)
+	})
+}
+
+func TestSignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	for _, key := range []crypto.Signer{rsaKey, edKey} {
+		alg, _ := Algorithm(key.Public())
+
+		t.Run(alg+" round trip", func(t *testing.T) {
+			token, err := Sign(testClaims{Subject: "user", Expiry: 123}, "key-1", key)
+			assert.NoError(t, err)
+
+			kid, err := KeyID(token)
+			assert.NoError(t, err)
+			assert.Equal(t, "key-1", kid)
+
+			var claims testClaims
+			err = Verify(token, key.Public(), &claims)
+			assert.NoError(t, err)
+			assert.Equal(t, testClaims{Subject: "user", Expiry: 123}, claims)
+		})
+
+		t.Run(alg+" tampered claims", func(t *testing.T) {
+			token, _ := Sign(testClaims{Subject: "user"}, "key-1", key)
+			forged, _ := Sign(testClaims{Subject: "admin"}, "key-1", key)
+
+			parts := strings.Split(token, ".")
+			parts[1] = strings.Split(forged, ".")[1]
+
+			var claims testClaims
+			err := Verify(strings.Join(parts, "."), key.Public(), &claims)
+			assert.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+
+	t.Run("other key", func(t *testing.T) {
+		otherKey, _, _ := ed25519.GenerateKey(rand.Reader)
+		token, _ := Sign(testClaims{Subject: "user"}, "key-1", edKey)
+
+		var claims testClaims
+		err := Verify(token, otherKey, &claims)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("algorithm of another key type", func(t *testing.T) {
+		token, _ := Sign(testClaims{Subject: "user"}, "key-1", edKey)
+
+		var claims testClaims
+		err := Verify(token, rsaKey.Public(), &claims)
+		assert.ErrorIs(t, err, ErrUnsupportedAlg)
+	})
+
+	t.Run("hmac token", func(t *testing.T) {
+		token, _ := SignHS256(testClaims{Subject: "user"}, []byte("secret"))
+
+		var claims testClaims
+		err := Verify(token, edKey.Public(), &claims)
+		assert.ErrorIs(t, err, ErrUnsupportedAlg)
+	})
+}
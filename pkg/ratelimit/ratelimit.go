@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+// Limiter allows a number of attempts per key within a sliding window. The state is process local,
+// so every instance of the application counts attempts on its own.
+type Limiter struct {
+	mu           sync.Mutex
+	limit        int
+	window       time.Duration
+	attempts     map[string][]time.Time
+	lastSweep    time.Time
+	timeProvider domain.TimeProvider
+}
+
+var _ domain.RateLimiter = (*Limiter)(nil)
+
+func New(limit int, window time.Duration, timeProvider domain.TimeProvider) *Limiter {
+	return &Limiter{
+		limit:        limit,
+		window:       window,
+		attempts:     make(map[string][]time.Time),
+		timeProvider: timeProvider,
+	}
+}
+
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.timeProvider.UtcNow()
+	since := now.Add(-l.window)
+
+	// keys which are not used anymore would stay forever otherwise
+	if now.Sub(l.lastSweep) > l.window {
+		for k, attempts := range l.attempts {
+			if len(recent(attempts, since)) == 0 {
+				delete(l.attempts, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	attempts := recent(l.attempts[key], since)
+	if len(attempts) >= l.limit {
+		l.attempts[key] = attempts
+		return false
+	}
+
+	l.attempts[key] = append(attempts, now)
+
+	return true
+}
+
+// recent drops the attempts made before the given time, attempts are ordered from the oldest
+func recent(attempts []time.Time, since time.Time) []time.Time {
+	for i, t := range attempts {
+		if t.After(since) {
+			return attempts[i:]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) UtcNow() time.Time {
+	return c.now
+}
+
+func TestLimiter(t *testing.T) {
+	t.Run("attempts over the limit are rejected", func(t *testing.T) {
+		c := &clock{now: time.Now()}
+		l := New(2, time.Minute, c)
+
+		assert.True(t, l.Allow("a"))
+		assert.True(t, l.Allow("a"))
+		assert.False(t, l.Allow("a"))
+		assert.True(t, l.Allow("b")) // keys are counted separately
+	})
+
+	t.Run("window slides", func(t *testing.T) {
+		c := &clock{now: time.Now()}
+		l := New(2, time.Minute, c)
+
+		assert.True(t, l.Allow("a"))
+		c.now = c.now.Add(30 * time.Second)
+		assert.True(t, l.Allow("a"))
+		assert.False(t, l.Allow("a"))
+
+		c.now = c.now.Add(31 * time.Second) // the first attempt is out of the window
+		assert.True(t, l.Allow("a"))
+		assert.False(t, l.Allow("a"))
+	})
+
+	t.Run("rejected attempts are not counted", func(t *testing.T) {
+		c := &clock{now: time.Now()}
+		l := New(1, time.Minute, c)
+
+		assert.True(t, l.Allow("a"))
+		c.now = c.now.Add(59 * time.Second)
+		assert.False(t, l.Allow("a"))
+		c.now = c.now.Add(2 * time.Second)
+		assert.True(t, l.Allow("a"))
+	})
+
+	t.Run("stale keys are swept", func(t *testing.T) {
+		c := &clock{now: time.Now()}
+		l := New(1, time.Minute, c)
+
+		assert.True(t, l.Allow("a"))
+		c.now = c.now.Add(2 * time.Minute)
+		assert.True(t, l.Allow("b"))
+
+		assert.Len(t, l.attempts, 1)
+	})
+}
@@ -0,0 +1,75 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// secret of the RFC 6238 test vectors for HMAC-SHA1
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to six digits
+	testCases := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "287082"},
+		{unix: 1111111109, expected: "081804"},
+		{unix: 1111111111, expected: "050471"},
+		{unix: 1234567890, expected: "005924"},
+		{unix: 2000000000, expected: "279037"},
+		{unix: 20000000000, expected: "353130"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, Code(rfcSecret, Step(time.Unix(tc.unix, 0))), "at %d", tc.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	t.Run("current code", func(t *testing.T) {
+		step, ok := Validate(rfcSecret, "050471", now, 1)
+		assert.True(t, ok)
+		assert.Equal(t, Step(now), step)
+	})
+
+	t.Run("previous code within the skew", func(t *testing.T) {
+		step, ok := Validate(rfcSecret, Code(rfcSecret, Step(now)-1), now, 1)
+		assert.True(t, ok)
+		assert.Equal(t, Step(now)-1, step)
+	})
+
+	t.Run("code outside the skew", func(t *testing.T) {
+		_, ok := Validate(rfcSecret, Code(rfcSecret, Step(now)-2), now, 1)
+		assert.False(t, ok)
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		_, ok := Validate(rfcSecret, "000000", now, 1)
+		assert.False(t, ok)
+	})
+
+	t.Run("wrong length", func(t *testing.T) {
+		_, ok := Validate(rfcSecret, "50471", now, 1)
+		assert.False(t, ok)
+	})
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("usermanagement", "test@example.com", rfcSecret))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/usermanagement:test@example.com", uri.Path)
+	assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri.Query().Get("secret"))
+	assert.Equal(t, "usermanagement", uri.Query().Get("issuer"))
+	assert.Equal(t, "6", uri.Query().Get("digits"))
+	assert.Equal(t, "30", uri.Query().Get("period"))
+}
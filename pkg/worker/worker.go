@@ -0,0 +1,87 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+// Task does a batch of background work, more tells there is more waiting, so it is run again right away
+type Task func(ctx context.Context) (more bool, err error)
+
+// Worker runs a task in the background every interval, as long as it has more work to do
+type Worker struct {
+	name     string
+	interval time.Duration
+	task     Task
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func New(name string, interval time.Duration, task Task) *Worker {
+	return &Worker{
+		name:     name,
+		interval: interval,
+		task:     task,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs the worker in a goroutine until Stop is called
+func (w *Worker) Start() {
+	go w.run()
+}
+
+// Stop waits for the batch in progress to finish, unless the context ends first
+func (w *Worker) Stop(ctx context.Context) error {
+	close(w.stop)
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed stopping %s: %w", w.name, ctx.Err())
+	}
+}
+
+func (w *Worker) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.drain()
+
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain runs the task as long as it has more work to do
+func (w *Worker) drain() {
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		// a batch is not cancelled by Stop, so it is not left half done
+		more, err := w.task(context.Background())
+		if err != nil {
+			logger.Error(fmt.Errorf("%s: %w", w.name, err))
+			return
+		}
+
+		if !more {
+			return
+		}
+	}
+}
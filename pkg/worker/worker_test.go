@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+)
+
+func TestWorker(t *testing.T) {
+	cfg := config.Load()
+	logger.Setup(cfg)
+
+	t.Run("more work is done right away", func(t *testing.T) {
+		var runs atomic.Int32
+
+		w := New("test", time.Hour, func(ctx context.Context) (bool, error) {
+			return runs.Add(1) < 3, nil
+		})
+		w.Start()
+
+		// the interval is never reached, the runs come from the task having more to do
+		assert.Eventually(t, func() bool { return runs.Load() == 3 }, time.Second, 10*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		assert.NoError(t, w.Stop(ctx))
+		assert.Equal(t, int32(3), runs.Load())
+	})
+
+	t.Run("failed task is run again on the next interval", func(t *testing.T) {
+		var runs atomic.Int32
+
+		w := New("test", 10*time.Millisecond, func(ctx context.Context) (bool, error) {
+			runs.Add(1)
+			return true, errors.New("unavailable")
+		})
+		w.Start()
+
+		assert.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, 10*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		assert.NoError(t, w.Stop(ctx))
+	})
+
+	t.Run("stop waits for the batch in progress", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		w := New("test", time.Hour, func(ctx context.Context) (bool, error) {
+			close(started)
+			<-release
+			return false, nil
+		})
+		w.Start()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		assert.Error(t, w.Stop(ctx))
+
+		close(release)
+	})
+}
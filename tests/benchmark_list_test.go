@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/container"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/database/mysql"
+)
+
+// BenchmarkListUsers measures listing pages of users with their addresses against the database,
+// reporting the number of SQL queries next to the latency of a single page
+func BenchmarkListUsers(b *testing.B) {
+	cfg := config.Load()
+	cfg.SetDefault("REPOSITORY_DRIVER", container.DriverMemory)
+	if cfg.GetString("REPOSITORY_DRIVER") != container.DriverMySQL { // query counts are meaningless for the in-memory storage
+		b.Skip("requires REPOSITORY_DRIVER=mysql")
+	}
+
+	dbCfg := gomysql.NewConfig()
+	dbCfg.User = cfg.GetString("DB_READ_USER")
+	dbCfg.Passwd = cfg.GetString("DB_READ_PASSWORD")
+	dbCfg.Net = "tcp"
+	dbCfg.Addr = net.JoinHostPort(cfg.GetString("DB_READ_HOST"), strconv.Itoa(cfg.GetInt("DB_READ_PORT")))
+	dbCfg.DBName = cfg.GetString("DB_READ_NAME")
+	dbCfg.ParseTime = true
+	dbCfg.Loc = time.Local
+
+	connector, err := gomysql.NewConnector(dbCfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var queries atomic.Int64
+
+	db := sql.OpenDB(&countingConnector{Connector: connector, queries: &queries})
+	defer db.Close()
+
+	repo := mysql.NewUserRepository(db, db)
+	ctx := context.Background()
+
+	emailPrefix := fmt.Sprintf("bench-list-%d-", time.Now().UnixNano())
+	b.Cleanup(func() {
+		db.Exec("DELETE FROM users WHERE email LIKE ?", emailPrefix+"%") // addresses are removed by the foreign key
+	})
+
+	createdAt := time.Now()
+	for i := 0; i < 1000; i++ {
+		u := &user.User{
+			ID:          domain.NewID(),
+			Email:       fmt.Sprintf("%s%d@example.com", emailPrefix, i),
+			Password:    "hash",
+			FirstName:   "FirstName",
+			LastName:    "LastName",
+			PhoneNumber: "1234567890",
+			Role:        user.RoleSelf,
+			Addresses: []*user.Address{
+				{Type: user.HomeAddress, Street: "Test avenue", City: "New York", State: "NY", PostalCode: "55010", Country: "USA"},
+				{Type: user.BillingAddress, Street: "Test avenue", City: "New York", State: "NY", PostalCode: "55010", Country: "USA"},
+			},
+		}
+
+		if err = repo.Create(ctx, u, createdAt); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, size := range []int{5, 100, 1000} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			q := &user.ListQuery{
+				Filter: user.Filter{EmailPrefix: emailPrefix},
+				Limit:  size,
+			}
+
+			queries.Store(0)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				page, err := repo.Get(ctx, q)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(page.Users) != size {
+					b.Fatalf("expected %d users, got %d", size, len(page.Users))
+				}
+			}
+
+			b.ReportMetric(float64(queries.Load())/float64(b.N), "queries/op")
+		})
+	}
+}
+
+// countingConnector counts the queries and statements sent through its connections
+type countingConnector struct {
+	driver.Connector
+	queries *atomic.Int64
+}
+
+func (c *countingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &countingConn{Conn: conn, queries: c.queries}, nil
+}
+
+type countingConn struct {
+	driver.Conn
+	queries *atomic.Int64
+}
+
+func (c *countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.queries.Add(1)
+
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.queries.Add(1)
+
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *countingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *countingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *countingConn) CheckNamedValue(nv *driver.NamedValue) error {
+	return c.Conn.(driver.NamedValueChecker).CheckNamedValue(nv)
+}
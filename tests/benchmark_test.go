@@ -21,6 +21,7 @@ import (
 func BenchmarkCreateUser(b *testing.B) {
 	cfg := config.Load()
 	cfg.Set("LOG_LEVEL", "info")
+	cfg.SetDefault("REPOSITORY_DRIVER", container.DriverMemory) // REPOSITORY_DRIVER=mysql runs it against the database
 	logger.Setup(cfg)
 	ctn := container.New()
 	userHandler := ctn.Get("http-user").(*handlers.UserHTTPHandler)
@@ -1,34 +1,48 @@
 package tests
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/wojciechpawlinow/usermanagement/internal/config"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
 	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/container"
-	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/httpserver/handlers"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/hasher"
+	"github.com/wojciechpawlinow/usermanagement/internal/infrastructure/httpserver"
+	"github.com/wojciechpawlinow/usermanagement/pkg/jwt"
 	"github.com/wojciechpawlinow/usermanagement/pkg/logger"
+	"github.com/wojciechpawlinow/usermanagement/pkg/totp"
 )
 
+// hiddenInput matches the fields of the login page a browser posts back unchanged
+var hiddenInput = regexp.MustCompile(`<input type="hidden" name="([^"]+)" value="([^"]*)">`)
+
 func TestIntegration(t *testing.T) {
 	cfg := config.Load()
+	cfg.SetDefault("REPOSITORY_DRIVER", container.DriverMemory) // REPOSITORY_DRIVER=mysql runs it against the database
+	cfg.Set("AUTH_API_KEYS", "integration:integration-test-key")
+	cfg.Set("MAILER_DRIVER", container.MailerFile)
+	cfg.Set("MAILER_FILE_PATH", filepath.Join(t.TempDir(), "mail.log"))
 	logger.Setup(cfg)
 	ctn := container.New()
-	userHandler := ctn.Get("http-user").(*handlers.UserHTTPHandler)
-	router := gin.Default()
-	router.POST("/users", userHandler.CreateUser)
-	router.PUT("/users/:id", userHandler.UpdateUser)
-	router.DELETE("/users/:id", userHandler.DeleteUser)
-	router.GET("/users/:id", userHandler.GetUser)
-	router.GET("/users", userHandler.Get)
+	router := httpserver.NewRouter(ctn)
 
 	createReqBody := `{
 	  "email": "test999@myemailxx.com",
@@ -60,15 +74,98 @@ func TestIntegration(t *testing.T) {
 
 	userID := resp["uuid"]
 
+	unauthorizedReq, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s", userID), nil)
+	unauthorizedRec := httptest.NewRecorder()
+	router.ServeHTTP(unauthorizedRec, unauthorizedReq)
+
+	assert.Equal(t, http.StatusUnauthorized, unauthorizedRec.Code)
+
+	loginReqBody := `{"email": "test999@myemailxx.com", "password": "secure123"}`
+	loginReq, _ := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(loginReqBody)))
+	loginReq.Header.Set("Content-Type", "application/json")
+	loginRec := httptest.NewRecorder()
+	router.ServeHTTP(loginRec, loginReq)
+
+	assert.Equal(t, http.StatusOK, loginRec.Code)
+
+	var loginResp map[string]any
+	_ = json.Unmarshal([]byte(loginRec.Body.String()), &loginResp)
+
+	authorization := fmt.Sprintf("Bearer %v", loginResp["access_token"])
+
+	// the email given at creation is verified with the token mailed to it
+	verifyReq, _ := http.NewRequest(http.MethodPost, "/auth/verify-email", io.NopCloser(strings.NewReader(fmt.Sprintf(`{"token": %q}`, lastMailedToken(t, cfg)))))
+	verifyReq.Header.Set("Content-Type", "application/json")
+	verifyRec := httptest.NewRecorder()
+	router.ServeHTTP(verifyRec, verifyReq)
+
+	assert.Equal(t, http.StatusOK, verifyRec.Code)
+
+	// the first admin is created by an API key client, anonymous callers can not pick a role
+	adminReqBody := `{
+	  "email": %q,
+	  "password": "secure123",
+	  "first_name": "Admin",
+	  "last_name": "Admin",
+	  "phone_number": "1234567890",
+	  "role": "admin",
+	  "addresses": [{"type": 1, "street": "Test avenue", "city": "New York", "state": "NY", "postal_code": "55010", "country": "USA"}]
+	}`
+
+	for _, tc := range []struct {
+		apiKey       string
+		email        string
+		expectedCode int
+	}{
+		{"", "anonymous999@myemailxx.com", http.StatusUnauthorized},
+		{"unknown-key", "unknown999@myemailxx.com", http.StatusUnauthorized},
+		{"integration-test-key", "admin999@myemailxx.com", http.StatusCreated},
+	} {
+		adminReq, _ := http.NewRequest(http.MethodPost, "/users", io.NopCloser(strings.NewReader(fmt.Sprintf(adminReqBody, tc.email))))
+		adminReq.Header.Set("Content-Type", "application/json")
+		if tc.apiKey != "" {
+			adminReq.Header.Set("X-API-Key", tc.apiKey)
+		}
+		adminRec := httptest.NewRecorder()
+		router.ServeHTTP(adminRec, adminReq)
+
+		assert.Equal(t, tc.expectedCode, adminRec.Code, tc.email)
+	}
+
+	adminLoginReq, _ := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(`{"email": "admin999@myemailxx.com", "password": "secure123"}`)))
+	adminLoginReq.Header.Set("Content-Type", "application/json")
+	adminLoginRec := httptest.NewRecorder()
+	router.ServeHTTP(adminLoginRec, adminLoginReq)
+
+	assert.Equal(t, http.StatusOK, adminLoginRec.Code)
+
+	var adminLoginResp map[string]any
+	_ = json.Unmarshal([]byte(adminLoginRec.Body.String()), &adminLoginResp)
+
+	// only admins list users
+	adminListReq, _ := http.NewRequest(http.MethodGet, "/users", nil)
+	adminListReq.Header.Set("Authorization", fmt.Sprintf("Bearer %v", adminLoginResp["access_token"]))
+	adminListRec := httptest.NewRecorder()
+	router.ServeHTTP(adminListRec, adminListReq)
+
+	assert.Equal(t, http.StatusOK, adminListRec.Code)
+
 	getReq, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s", userID), nil)
+	getReq.Header.Set("Authorization", authorization)
 	getRec := httptest.NewRecorder()
 	router.ServeHTTP(getRec, getReq)
 
 	assert.Equal(t, http.StatusOK, getRec.Code)
 	assert.Contains(t, getRec.Body.String(), `"email":"test999@myemailxx.com"`)
+	assert.Contains(t, getRec.Body.String(), `"email_verified":true`)
+
+	etag := getRec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
 
 	updateReqBody := `{
 		"first_name": "New test name",
+		"last_name": "LastName",
+		"phone_number": "1234567890",
 		"addresses": [
 		  {
 	        "type": 3,
@@ -80,15 +177,534 @@ func TestIntegration(t *testing.T) {
 	}`
 	updateReq, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/users/%s", userID), io.NopCloser(strings.NewReader(updateReqBody)))
 	updateReq.Header.Set("Content-Type", "application/json")
+	updateReq.Header.Set("Authorization", authorization)
+	updateReq.Header.Set("If-Match", etag)
 
 	updateRec := httptest.NewRecorder()
 	router.ServeHTTP(updateRec, updateReq)
 
 	assert.Equal(t, http.StatusOK, updateRec.Code)
 
+	staleUpdateReq, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/users/%s", userID), io.NopCloser(strings.NewReader(updateReqBody)))
+	staleUpdateReq.Header.Set("Content-Type", "application/json")
+	staleUpdateReq.Header.Set("Authorization", authorization)
+	staleUpdateReq.Header.Set("If-Match", etag)
+
+	staleUpdateRec := httptest.NewRecorder()
+	router.ServeHTTP(staleUpdateRec, staleUpdateReq)
+
+	assert.Equal(t, http.StatusPreconditionFailed, staleUpdateRec.Code) // the version changed with the previous update
+
+	patchReq, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%s", userID), io.NopCloser(strings.NewReader(`{"phone_number": null}`)))
+	patchReq.Header.Set("Content-Type", "application/merge-patch+json")
+	patchReq.Header.Set("Authorization", authorization)
+	patchReq.Header.Set("X-Request-ID", "integration-patch")
+
+	patchRec := httptest.NewRecorder()
+	router.ServeHTTP(patchRec, patchReq)
+
+	assert.Equal(t, http.StatusOK, patchRec.Code)
+
+	patchedReq, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s", userID), nil)
+	patchedReq.Header.Set("Authorization", authorization)
+	patchedRec := httptest.NewRecorder()
+	router.ServeHTTP(patchedRec, patchedReq)
+
+	assert.Equal(t, http.StatusOK, patchedRec.Code)
+	assert.Contains(t, patchedRec.Body.String(), `"phone_number":""`)
+	assert.Contains(t, patchedRec.Body.String(), `"street":"New address type"`)
+	assert.NotContains(t, patchedRec.Body.String(), `"street":"Test avenue"`) // PUT replaced the addresses
+
+	listReq, _ := http.NewRequest(http.MethodGet, "/users", nil)
+	listReq.Header.Set("Authorization", authorization)
+	listRec := httptest.NewRecorder()
+	router.ServeHTTP(listRec, listReq)
+
+	assert.Equal(t, http.StatusForbidden, listRec.Code) // only admins and support can list users
+
+	emailChangeReq, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/email-change", userID), io.NopCloser(strings.NewReader(`{"email": "changed999@myemailxx.com"}`)))
+	emailChangeReq.Header.Set("Content-Type", "application/json")
+	emailChangeReq.Header.Set("Authorization", authorization)
+	emailChangeRec := httptest.NewRecorder()
+	router.ServeHTTP(emailChangeRec, emailChangeReq)
+
+	assert.Equal(t, http.StatusAccepted, emailChangeRec.Code)
+
+	token := lastMailedToken(t, cfg)
+
+	for _, expectedCode := range []int{http.StatusOK, http.StatusBadRequest} { // tokens are single-use
+		verifyChangeReq, _ := http.NewRequest(http.MethodPost, "/auth/verify-email", io.NopCloser(strings.NewReader(fmt.Sprintf(`{"token": %q}`, token))))
+		verifyChangeReq.Header.Set("Content-Type", "application/json")
+		verifyChangeRec := httptest.NewRecorder()
+		router.ServeHTTP(verifyChangeRec, verifyChangeReq)
+
+		assert.Equal(t, expectedCode, verifyChangeRec.Code)
+	}
+
+	changedReq, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s", userID), nil)
+	changedReq.Header.Set("Authorization", authorization)
+	changedRec := httptest.NewRecorder()
+	router.ServeHTTP(changedRec, changedReq)
+
+	assert.Equal(t, http.StatusOK, changedRec.Code)
+	assert.Contains(t, changedRec.Body.String(), `"email":"changed999@myemailxx.com"`)
+
+	// every change is recorded, the user reads the entries of their own record only
+	userAuditReq, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s/audit?size=50", userID), nil)
+	userAuditReq.Header.Set("Authorization", authorization)
+	userAuditRec := httptest.NewRecorder()
+	router.ServeHTTP(userAuditRec, userAuditReq)
+
+	assert.Equal(t, http.StatusOK, userAuditRec.Code)
+	assert.Contains(t, userAuditRec.Body.String(), `"action":"user.created"`)
+	assert.Contains(t, userAuditRec.Body.String(), `{"field":"password","value":"[redacted]"}`)
+	assert.Contains(t, userAuditRec.Body.String(), `{"field":"phone_number","value":null}],"request_id":"integration-patch"`)
+	assert.Contains(t, userAuditRec.Body.String(), `"action":"email.verified","user_id":"`+userID+`","changes":[{"field":"email","value":"changed999@myemailxx.com"}]`)
+	assert.NotContains(t, userAuditRec.Body.String(), "secure123")
+
+	allAuditReq, _ := http.NewRequest(http.MethodGet, "/audit", nil)
+	allAuditReq.Header.Set("Authorization", authorization)
+	allAuditRec := httptest.NewRecorder()
+	router.ServeHTTP(allAuditRec, allAuditReq)
+
+	assert.Equal(t, http.StatusForbidden, allAuditRec.Code)
+
+	for _, email := range []string{"changed999@myemailxx.com", "unknown999@myemailxx.com"} { // unknown emails are not revealed
+		resetReq, _ := http.NewRequest(http.MethodPost, "/auth/password-reset/request", io.NopCloser(strings.NewReader(fmt.Sprintf(`{"email": %q}`, email))))
+		resetReq.Header.Set("Content-Type", "application/json")
+		resetRec := httptest.NewRecorder()
+		router.ServeHTTP(resetRec, resetReq)
+
+		assert.Equal(t, http.StatusAccepted, resetRec.Code)
+	}
+
+	confirmReq, _ := http.NewRequest(http.MethodPost, "/auth/password-reset/confirm",
+		io.NopCloser(strings.NewReader(fmt.Sprintf(`{"token": %q, "password": "new-secure123"}`, lastMailedToken(t, cfg)))))
+	confirmReq.Header.Set("Content-Type", "application/json")
+	confirmRec := httptest.NewRecorder()
+	router.ServeHTTP(confirmRec, confirmReq)
+
+	assert.Equal(t, http.StatusOK, confirmRec.Code)
+
+	newLoginReq, _ := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(`{"email": "changed999@myemailxx.com", "password": "new-secure123"}`)))
+	newLoginReq.Header.Set("Content-Type", "application/json")
+	newLoginRec := httptest.NewRecorder()
+	router.ServeHTTP(newLoginRec, newLoginReq)
+
+	assert.Equal(t, http.StatusOK, newLoginRec.Code)
+
+	for _, tc := range []struct {
+		body         string
+		expectedCode int
+	}{
+		{`{"current_password": "secure123", "new_password": "changed-secure123"}`, http.StatusForbidden}, // the old password is not valid anymore
+		{`{"current_password": "new-secure123", "new_password": "password1"}`, http.StatusUnprocessableEntity},
+		{`{"current_password": "new-secure123", "new_password": "changed-secure123"}`, http.StatusOK},
+	} {
+		changePasswordReq, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/password", userID), io.NopCloser(strings.NewReader(tc.body)))
+		changePasswordReq.Header.Set("Content-Type", "application/json")
+		changePasswordReq.Header.Set("Authorization", authorization)
+		changePasswordRec := httptest.NewRecorder()
+		router.ServeHTTP(changePasswordRec, changePasswordReq)
+
+		assert.Equal(t, tc.expectedCode, changePasswordRec.Code)
+	}
+
+	changedLoginReq, _ := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(`{"email": "changed999@myemailxx.com", "password": "changed-secure123"}`)))
+	changedLoginReq.Header.Set("Content-Type", "application/json")
+	changedLoginRec := httptest.NewRecorder()
+	router.ServeHTTP(changedLoginRec, changedLoginReq)
+
+	assert.Equal(t, http.StatusOK, changedLoginRec.Code)
+
+	const clientAddr = "198.51.100.7:1234"
+
+	for _, expectedCode := range []int{
+		http.StatusUnauthorized,
+		http.StatusUnauthorized,
+		http.StatusUnauthorized,
+		http.StatusUnauthorized,
+		http.StatusUnauthorized, // the default threshold locks the user out
+		http.StatusTooManyRequests,
+	} {
+		failedLoginReq, _ := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(`{"email": "changed999@myemailxx.com", "password": "wrong-secure123"}`)))
+		failedLoginReq.Header.Set("Content-Type", "application/json")
+		failedLoginReq.RemoteAddr = clientAddr
+		failedLoginRec := httptest.NewRecorder()
+		router.ServeHTTP(failedLoginRec, failedLoginReq)
+
+		assert.Equal(t, expectedCode, failedLoginRec.Code)
+	}
+
+	lockoutReq, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s/lockout", userID), nil)
+	lockoutReq.Header.Set("X-API-Key", "integration-test-key")
+	lockoutRec := httptest.NewRecorder()
+	router.ServeHTTP(lockoutRec, lockoutReq)
+
+	assert.Equal(t, http.StatusOK, lockoutRec.Code)
+	assert.Contains(t, lockoutRec.Body.String(), `"failures":5`)
+
+	for _, path := range []string{fmt.Sprintf("/users/%s/lockout", userID), "/lockouts/ip/198.51.100.7"} {
+		unlockReq, _ := http.NewRequest(http.MethodDelete, path, nil)
+		unlockReq.Header.Set("X-API-Key", "integration-test-key")
+		unlockRec := httptest.NewRecorder()
+		router.ServeHTTP(unlockRec, unlockReq)
+
+		assert.Equal(t, http.StatusOK, unlockRec.Code)
+	}
+
+	unlockedLoginReq, _ := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(`{"email": "changed999@myemailxx.com", "password": "changed-secure123"}`)))
+	unlockedLoginReq.Header.Set("Content-Type", "application/json")
+	unlockedLoginReq.RemoteAddr = clientAddr
+	unlockedLoginRec := httptest.NewRecorder()
+	router.ServeHTTP(unlockedLoginRec, unlockedLoginReq)
+
+	assert.Equal(t, http.StatusOK, unlockedLoginRec.Code)
+
+	enrollReq, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/mfa", userID), nil)
+	enrollReq.Header.Set("Authorization", authorization)
+	enrollRec := httptest.NewRecorder()
+	router.ServeHTTP(enrollRec, enrollReq)
+
+	assert.Equal(t, http.StatusOK, enrollRec.Code)
+
+	var enrollResp map[string]string
+	_ = json.Unmarshal([]byte(enrollRec.Body.String()), &enrollResp)
+
+	secret, err := totp.Encoding.DecodeString(enrollResp["secret"])
+	assert.NoError(t, err)
+
+	confirmMFAReq, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%s/mfa/confirm", userID),
+		io.NopCloser(strings.NewReader(fmt.Sprintf(`{"code": %q}`, totp.Code(secret, totp.Step(time.Now()))))))
+	confirmMFAReq.Header.Set("Content-Type", "application/json")
+	confirmMFAReq.Header.Set("Authorization", authorization)
+	confirmMFARec := httptest.NewRecorder()
+	router.ServeHTTP(confirmMFARec, confirmMFAReq)
+
+	assert.Equal(t, http.StatusOK, confirmMFARec.Code)
+
+	var recoveryResp map[string][]string
+	_ = json.Unmarshal([]byte(confirmMFARec.Body.String()), &recoveryResp)
+	assert.NotEmpty(t, recoveryResp["recovery_codes"])
+
+	challengeReq, _ := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(`{"email": "changed999@myemailxx.com", "password": "changed-secure123"}`)))
+	challengeReq.Header.Set("Content-Type", "application/json")
+	challengeRec := httptest.NewRecorder()
+	router.ServeHTTP(challengeRec, challengeReq)
+
+	assert.Equal(t, http.StatusAccepted, challengeRec.Code)
+
+	var challengeResp map[string]any
+	_ = json.Unmarshal([]byte(challengeRec.Body.String()), &challengeResp)
+
+	// the code used to confirm can not be used again within its time step, a recovery code can
+	mfaLoginReq, _ := http.NewRequest(http.MethodPost, "/auth/login/mfa",
+		io.NopCloser(strings.NewReader(fmt.Sprintf(`{"mfa_token": "%v", "code": %q}`, challengeResp["mfa_token"], recoveryResp["recovery_codes"][0]))))
+	mfaLoginReq.Header.Set("Content-Type", "application/json")
+	mfaLoginRec := httptest.NewRecorder()
+	router.ServeHTTP(mfaLoginRec, mfaLoginReq)
+
+	assert.Equal(t, http.StatusOK, mfaLoginRec.Code)
+	assert.Contains(t, mfaLoginRec.Body.String(), `"access_token"`)
+
+	resetMFAReq, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s/mfa", userID), nil)
+	resetMFAReq.Header.Set("X-API-Key", "integration-test-key")
+	resetMFARec := httptest.NewRecorder()
+	router.ServeHTTP(resetMFARec, resetMFAReq)
+
+	assert.Equal(t, http.StatusOK, resetMFARec.Code)
+
+	resetLoginReq, _ := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(`{"email": "changed999@myemailxx.com", "password": "changed-secure123"}`)))
+	resetLoginReq.Header.Set("Content-Type", "application/json")
+	resetLoginRec := httptest.NewRecorder()
+	router.ServeHTTP(resetLoginRec, resetLoginReq)
+
+	assert.Equal(t, http.StatusOK, resetLoginRec.Code)
+
+	var sessionResp map[string]any
+	_ = json.Unmarshal([]byte(resetLoginRec.Body.String()), &sessionResp)
+	assert.NotEmpty(t, sessionResp["refresh_token"])
+
+	refresh := func(refreshToken any) *httptest.ResponseRecorder {
+		refreshReq, _ := http.NewRequest(http.MethodPost, "/auth/refresh", io.NopCloser(strings.NewReader(fmt.Sprintf(`{"refresh_token": "%v"}`, refreshToken))))
+		refreshReq.Header.Set("Content-Type", "application/json")
+		refreshRec := httptest.NewRecorder()
+		router.ServeHTTP(refreshRec, refreshReq)
+
+		return refreshRec
+	}
+
+	refreshRec := refresh(sessionResp["refresh_token"])
+	assert.Equal(t, http.StatusOK, refreshRec.Code)
+
+	var refreshResp map[string]any
+	_ = json.Unmarshal([]byte(refreshRec.Body.String()), &refreshResp)
+	assert.NotEqual(t, sessionResp["refresh_token"], refreshResp["refresh_token"])
+
+	// reusing a rotated token revokes the whole session, the token it was exchanged for included
+	assert.Equal(t, http.StatusUnauthorized, refresh(sessionResp["refresh_token"]).Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(refreshResp["refresh_token"]).Code)
+
+	logoutLoginReq, _ := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(`{"email": "changed999@myemailxx.com", "password": "changed-secure123"}`)))
+	logoutLoginReq.Header.Set("Content-Type", "application/json")
+	logoutLoginRec := httptest.NewRecorder()
+	router.ServeHTTP(logoutLoginRec, logoutLoginReq)
+
+	_ = json.Unmarshal([]byte(logoutLoginRec.Body.String()), &sessionResp)
+
+	logoutReq, _ := http.NewRequest(http.MethodPost, "/auth/logout", io.NopCloser(strings.NewReader(fmt.Sprintf(`{"refresh_token": "%v"}`, sessionResp["refresh_token"]))))
+	logoutReq.Header.Set("Content-Type", "application/json")
+	logoutRec := httptest.NewRecorder()
+	router.ServeHTTP(logoutRec, logoutReq)
+
+	assert.Equal(t, http.StatusOK, logoutRec.Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(sessionResp["refresh_token"]).Code)
+
+	revokeLoginReq, _ := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(`{"email": "changed999@myemailxx.com", "password": "changed-secure123"}`)))
+	revokeLoginReq.Header.Set("Content-Type", "application/json")
+	revokeLoginRec := httptest.NewRecorder()
+	router.ServeHTTP(revokeLoginRec, revokeLoginReq)
+
+	_ = json.Unmarshal([]byte(revokeLoginRec.Body.String()), &sessionResp)
+
+	revokeReq, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s/sessions", userID), nil)
+	revokeReq.Header.Set("X-API-Key", "integration-test-key")
+	revokeRec := httptest.NewRecorder()
+	router.ServeHTTP(revokeRec, revokeReq)
+
+	assert.Equal(t, http.StatusOK, revokeRec.Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(sessionResp["refresh_token"]).Code)
+
+	deletedLoginReq, _ := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(`{"email": "changed999@myemailxx.com", "password": "changed-secure123"}`)))
+	deletedLoginReq.Header.Set("Content-Type", "application/json")
+	deletedLoginRec := httptest.NewRecorder()
+	router.ServeHTTP(deletedLoginRec, deletedLoginReq)
+
+	_ = json.Unmarshal([]byte(deletedLoginRec.Body.String()), &sessionResp)
+
+	// an app delegating the login gets a code for the user signed in on the login page and exchanges it with the PKCE verifier
+	registerReq, _ := http.NewRequest(http.MethodPost, "/oauth/clients", io.NopCloser(strings.NewReader(`{"name": "integration", "redirect_uris": ["https://app.example.com/callback"]}`)))
+	registerReq.Header.Set("Content-Type", "application/json")
+	registerReq.Header.Set("X-API-Key", "integration-test-key")
+	registerRec := httptest.NewRecorder()
+	router.ServeHTTP(registerRec, registerReq)
+
+	assert.Equal(t, http.StatusCreated, registerRec.Code)
+
+	var clientResp map[string]any
+	_ = json.Unmarshal([]byte(registerRec.Body.String()), &clientResp)
+
+	clientID, clientSecret := fmt.Sprint(clientResp["client_id"]), fmt.Sprint(clientResp["client_secret"])
+	verifier := "integration-test-verifier-0123456789-abcdefghij"
+	challenge := sha256.Sum256([]byte(verifier))
+
+	// the browser is sent to the login page without any credentials, the user signs in there and allows the access
+	authorizeReq, _ := http.NewRequest(http.MethodGet, "/oauth/authorize?"+url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {"https://app.example.com/callback"},
+		"scope":                 {"openid email"},
+		"state":                 {"state"},
+		"nonce":                 {"nonce"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}.Encode(), nil)
+	authorizeRec := httptest.NewRecorder()
+	router.ServeHTTP(authorizeRec, authorizeReq)
+
+	assert.Equal(t, http.StatusOK, authorizeRec.Code)
+	assert.Contains(t, authorizeRec.Body.String(), "Sign in to integration")
+
+	consent := func(password string) *httptest.ResponseRecorder {
+		form := url.Values{"email": {"changed999@myemailxx.com"}, "password": {password}, "decision": {"allow"}}
+		for _, input := range hiddenInput.FindAllStringSubmatch(authorizeRec.Body.String(), -1) {
+			form.Set(input[1], html.UnescapeString(input[2]))
+		}
+
+		consentReq, _ := http.NewRequest(http.MethodPost, "/oauth/authorize", io.NopCloser(strings.NewReader(form.Encode())))
+		consentReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		consentRec := httptest.NewRecorder()
+		router.ServeHTTP(consentRec, consentReq)
+
+		return consentRec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, consent("wrong-password").Code)
+
+	consentRec := consent("changed-secure123")
+	assert.Equal(t, http.StatusFound, consentRec.Code)
+
+	location, err := url.Parse(consentRec.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "state", location.Query().Get("state"))
+
+	exchange := func() *httptest.ResponseRecorder {
+		tokenReq, _ := http.NewRequest(http.MethodPost, "/oauth/token", io.NopCloser(strings.NewReader(url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {location.Query().Get("code")},
+			"redirect_uri":  {"https://app.example.com/callback"},
+			"code_verifier": {verifier},
+		}.Encode())))
+		tokenReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		tokenReq.SetBasicAuth(clientID, clientSecret)
+		tokenRec := httptest.NewRecorder()
+		router.ServeHTTP(tokenRec, tokenReq)
+
+		return tokenRec
+	}
+
+	tokenRec := exchange()
+	assert.Equal(t, http.StatusOK, tokenRec.Code)
+
+	var tokenResp map[string]any
+	_ = json.Unmarshal([]byte(tokenRec.Body.String()), &tokenResp)
+
+	jwksReq, _ := http.NewRequest(http.MethodGet, "/oauth/jwks", nil)
+	jwksRec := httptest.NewRecorder()
+	router.ServeHTTP(jwksRec, jwksReq)
+
+	var keySet jwt.JWKS
+	_ = json.Unmarshal([]byte(jwksRec.Body.String()), &keySet)
+
+	idToken := fmt.Sprint(tokenResp["id_token"])
+	keyID, err := jwt.KeyID(idToken)
+	assert.NoError(t, err)
+
+	var idClaims map[string]any
+	for _, k := range keySet.Keys {
+		if k.KeyID == keyID {
+			publicKey, err := k.PublicKey()
+			assert.NoError(t, err)
+			assert.NoError(t, jwt.Verify(idToken, publicKey, &idClaims))
+		}
+	}
+
+	assert.Equal(t, userID, idClaims["sub"])
+	assert.Equal(t, clientID, idClaims["aud"])
+	assert.Equal(t, "nonce", idClaims["nonce"])
+	assert.Equal(t, "changed999@myemailxx.com", idClaims["email"])
+
+	userInfoReq, _ := http.NewRequest(http.MethodGet, "/oauth/userinfo", nil)
+	userInfoReq.Header.Set("Authorization", fmt.Sprintf("Bearer %v", tokenResp["access_token"]))
+	userInfoRec := httptest.NewRecorder()
+	router.ServeHTTP(userInfoRec, userInfoReq)
+
+	assert.Equal(t, http.StatusOK, userInfoRec.Code)
+	assert.Contains(t, userInfoRec.Body.String(), fmt.Sprintf(`"sub":%q`, userID))
+
+	assert.Equal(t, http.StatusBadRequest, exchange().Code) // a code is exchanged once
+
+	forbiddenDeleteReq, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s", userID), nil)
+	forbiddenDeleteReq.Header.Set("Authorization", authorization)
+	forbiddenDeleteRec := httptest.NewRecorder()
+	router.ServeHTTP(forbiddenDeleteRec, forbiddenDeleteReq)
+
+	assert.Equal(t, http.StatusForbidden, forbiddenDeleteRec.Code) // only admins can delete users
+
 	deleteReq, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s", userID), nil)
+	deleteReq.Header.Set("X-API-Key", "integration-test-key")
 	deleteRec := httptest.NewRecorder()
 	router.ServeHTTP(deleteRec, deleteReq)
 
 	assert.Equal(t, http.StatusOK, deleteRec.Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(sessionResp["refresh_token"]).Code) // deleting the user ends their sessions
+
+	// the entries outlive the user
+	deletedAuditReq, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/audit?user_id=%s&action=user.deleted", userID), nil)
+	deletedAuditReq.Header.Set("X-API-Key", "integration-test-key")
+	deletedAuditRec := httptest.NewRecorder()
+	router.ServeHTTP(deletedAuditRec, deletedAuditReq)
+
+	assert.Equal(t, http.StatusOK, deletedAuditRec.Code)
+	assert.Contains(t, deletedAuditRec.Body.String(), `"actor":{"type":"client","id":"integration"},"action":"user.deleted"`)
+}
+
+// TestRehashKeepsETag logs in a user whose password was hashed with an outdated algorithm,
+// the hash is replaced without invalidating the ETag clients have cached
+func TestRehashKeepsETag(t *testing.T) {
+	cfg := config.Load()
+	cfg.SetDefault("REPOSITORY_DRIVER", container.DriverMemory)
+	cfg.Set("AUTH_API_KEYS", "integration:integration-test-key")
+	cfg.Set("MAILER_DRIVER", container.MailerFile)
+	cfg.Set("MAILER_FILE_PATH", filepath.Join(t.TempDir(), "mail.log"))
+	cfg.Set("PASSWORD_HASH_ALGORITHM", hasher.AlgorithmArgon2id)
+	logger.Setup(cfg)
+	ctn := container.New()
+	router := httpserver.NewRouter(ctn)
+
+	createReqBody := `{
+	  "email": "rehash999@myemailxx.com",
+	  "password": "secure123",
+	  "first_name": "FirstName",
+	  "last_name": "LastName",
+	  "phone_number": "1234567890",
+	  "addresses": [{"type": 1, "street": "Test avenue", "city": "New York", "postal_code": "55010", "country": "USA"}]
+	}`
+	createReq, _ := http.NewRequest(http.MethodPost, "/users", io.NopCloser(strings.NewReader(createReqBody)))
+	createReq.Header.Set("Content-Type", "application/json")
+	createRec := httptest.NewRecorder()
+	router.ServeHTTP(createRec, createReq)
+
+	assert.Equal(t, http.StatusCreated, createRec.Code)
+
+	var resp map[string]string
+	_ = json.Unmarshal([]byte(createRec.Body.String()), &resp)
+
+	userID, err := domain.ParseID(resp["uuid"])
+	assert.NoError(t, err)
+
+	// the password was hashed before the algorithm was changed
+	bcrypt, err := hasher.NewBcrypt(cfg.GetInt("PASSWORD_BCRYPT_COST"))
+	assert.NoError(t, err)
+	outdatedHash, err := bcrypt.Hash("secure123")
+	assert.NoError(t, err)
+
+	userRepo := ctn.Get("repo-user").(user.Repository)
+	assert.NoError(t, userRepo.UpdateBasicFields(context.Background(), userID, []user.Change{user.Set(user.FieldPassword, outdatedHash)}))
+
+	getETag := func() string {
+		getReq, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s", userID), nil)
+		getReq.Header.Set("X-API-Key", "integration-test-key")
+		getRec := httptest.NewRecorder()
+		router.ServeHTTP(getRec, getReq)
+
+		assert.Equal(t, http.StatusOK, getRec.Code)
+
+		return getRec.Header().Get("ETag")
+	}
+
+	etag := getETag()
+
+	loginReq, _ := http.NewRequest(http.MethodPost, "/auth/login", io.NopCloser(strings.NewReader(`{"email": "rehash999@myemailxx.com", "password": "secure123"}`)))
+	loginReq.Header.Set("Content-Type", "application/json")
+	loginRec := httptest.NewRecorder()
+	router.ServeHTTP(loginRec, loginReq)
+
+	assert.Equal(t, http.StatusOK, loginRec.Code)
+
+	credentials, err := userRepo.GetCredentialsByUUID(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.NotEqual(t, outdatedHash, credentials.PasswordHash)
+	assert.False(t, bcrypt.Recognizes(credentials.PasswordHash))
+
+	assert.Equal(t, etag, getETag())
+
+	patchReq, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%s", userID), io.NopCloser(strings.NewReader(`{"first_name": "Rehashed"}`)))
+	patchReq.Header.Set("Content-Type", "application/merge-patch+json")
+	patchReq.Header.Set("X-API-Key", "integration-test-key")
+	patchReq.Header.Set("If-Match", etag)
+	patchRec := httptest.NewRecorder()
+	router.ServeHTTP(patchRec, patchReq)
+
+	assert.Equal(t, http.StatusOK, patchRec.Code)
+}
+
+// lastMailedToken reads the secret closing the last message written by the file mailer
+func lastMailedToken(t *testing.T, cfg config.Provider) string {
+	content, err := os.ReadFile(cfg.GetString("MAILER_FILE_PATH"))
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+
+	return lines[len(lines)-1]
 }
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+)
+
+type AuditServiceMock struct {
+	mock.Mock
+}
+
+var _ service.AuditPort = (*AuditServiceMock)(nil)
+
+func (m *AuditServiceMock) List(ctx context.Context, q *audit.Query) (*audit.Page, error) {
+	args := m.Called(ctx, q)
+
+	if val, ok := args.Get(0).(*audit.Page); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+)
+
+type AuthServiceMock struct {
+	mock.Mock
+}
+
+var _ service.AuthPort = (*AuthServiceMock)(nil)
+
+func (m *AuthServiceMock) Login(ctx context.Context, email, password, clientIP string) (*auth.Token, error) {
+	args := m.Called(ctx, email, password, clientIP)
+
+	if val, ok := args.Get(0).(*auth.Token); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *AuthServiceMock) LoginMFA(ctx context.Context, challenge, code, clientIP string) (*auth.Token, error) {
+	args := m.Called(ctx, challenge, code, clientIP)
+
+	if val, ok := args.Get(0).(*auth.Token); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *AuthServiceMock) Authenticate(ctx context.Context, email, password, clientIP string) (auth.Identity, error) {
+	args := m.Called(ctx, email, password, clientIP)
+
+	return args.Get(0).(auth.Identity), args.Error(1)
+}
+
+func (m *AuthServiceMock) AuthenticateMFA(ctx context.Context, challenge, code, clientIP string) (auth.Identity, error) {
+	args := m.Called(ctx, challenge, code, clientIP)
+
+	return args.Get(0).(auth.Identity), args.Error(1)
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+type EmailServiceMock struct {
+	mock.Mock
+}
+
+var (
+	_ service.EmailPort     = (*EmailServiceMock)(nil)
+	_ service.EmailVerifier = (*EmailServiceMock)(nil)
+)
+
+func (m *EmailServiceMock) RequestChange(ctx context.Context, userID, email string) error {
+	args := m.Called(ctx, userID, email)
+
+	return args.Error(0)
+}
+
+func (m *EmailServiceMock) Verify(ctx context.Context, secret string) error {
+	args := m.Called(ctx, secret)
+
+	return args.Error(0)
+}
+
+func (m *EmailServiceMock) SendVerification(ctx context.Context, id domain.ID, email string) error {
+	args := m.Called(ctx, id, email)
+
+	return args.Error(0)
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+)
+
+type LockoutServiceMock struct {
+	mock.Mock
+}
+
+var _ service.LockoutPort = (*LockoutServiceMock)(nil)
+
+func (m *LockoutServiceMock) GetUserLockout(ctx context.Context, userID string) (*lockout.State, error) {
+	args := m.Called(ctx, userID)
+
+	if val, ok := args.Get(0).(*lockout.State); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *LockoutServiceMock) UnlockUser(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+
+	return args.Error(0)
+}
+
+func (m *LockoutServiceMock) UnlockIP(ctx context.Context, address string) error {
+	args := m.Called(ctx, address)
+
+	return args.Error(0)
+}
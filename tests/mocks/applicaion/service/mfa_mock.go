@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+)
+
+type MFAServiceMock struct {
+	mock.Mock
+}
+
+var _ service.MFAPort = (*MFAServiceMock)(nil)
+
+func (m *MFAServiceMock) Enroll(ctx context.Context, userID string) (*service.MFAEnrollment, error) {
+	args := m.Called(ctx, userID)
+
+	if val, ok := args.Get(0).(*service.MFAEnrollment); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MFAServiceMock) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+
+	if val, ok := args.Get(0).([]string); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MFAServiceMock) Disable(ctx context.Context, userID, code string) error {
+	args := m.Called(ctx, userID, code)
+
+	return args.Error(0)
+}
+
+func (m *MFAServiceMock) Reset(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+
+	return args.Error(0)
+}
+
+func (m *MFAServiceMock) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+
+	if val, ok := args.Get(0).([]string); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+)
+
+type OIDCServiceMock struct {
+	mock.Mock
+}
+
+var _ service.OIDCPort = (*OIDCServiceMock)(nil)
+
+func (m *OIDCServiceMock) Authorize(ctx context.Context, req *service.AuthorizationRequest) (string, error) {
+	args := m.Called(ctx, req)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *OIDCServiceMock) Exchange(ctx context.Context, req *service.TokenRequest) (*auth.Token, error) {
+	args := m.Called(ctx, req)
+
+	if val, ok := args.Get(0).(*auth.Token); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *OIDCServiceMock) UserInfo(ctx context.Context, accessToken string) (*oidc.UserClaims, error) {
+	args := m.Called(ctx, accessToken)
+
+	if val, ok := args.Get(0).(*oidc.UserClaims); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *OIDCServiceMock) PublicKeys(ctx context.Context) ([]oidc.PublicKey, error) {
+	args := m.Called(ctx)
+
+	if val, ok := args.Get(0).([]oidc.PublicKey); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *OIDCServiceMock) RegisterClient(ctx context.Context, name string, redirectURIs []string, public bool) (*oidc.Client, string, error) {
+	args := m.Called(ctx, name, redirectURIs, public)
+
+	if val, ok := args.Get(0).(*oidc.Client); ok {
+		return val, args.String(1), args.Error(2)
+	}
+
+	return nil, args.String(1), args.Error(2)
+}
+
+func (m *OIDCServiceMock) DeleteClient(ctx context.Context, clientID string) error {
+	args := m.Called(ctx, clientID)
+
+	return args.Error(0)
+}
+
+func (m *OIDCServiceMock) Prepare(ctx context.Context, req *service.AuthorizationRequest) (*oidc.Client, []string, error) {
+	args := m.Called(ctx, req)
+
+	client, _ := args.Get(0).(*oidc.Client)
+	scopes, _ := args.Get(1).([]string)
+
+	return client, scopes, args.Error(2)
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+)
+
+type PasswordServiceMock struct {
+	mock.Mock
+}
+
+var _ service.PasswordPort = (*PasswordServiceMock)(nil)
+
+func (m *PasswordServiceMock) RequestReset(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+
+	return args.Error(0)
+}
+
+func (m *PasswordServiceMock) ConfirmReset(ctx context.Context, secret, password string) error {
+	args := m.Called(ctx, secret, password)
+
+	return args.Error(0)
+}
+
+func (m *PasswordServiceMock) Change(ctx context.Context, userID, currentPassword, newPassword, clientIP string) error {
+	args := m.Called(ctx, userID, currentPassword, newPassword, clientIP)
+
+	return args.Error(0)
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+)
+
+type PrivacyServiceMock struct {
+	mock.Mock
+}
+
+var _ service.PrivacyPort = (*PrivacyServiceMock)(nil)
+
+func (m *PrivacyServiceMock) Export(ctx context.Context, userID string) (*service.UserExport, error) {
+	args := m.Called(ctx, userID)
+
+	if val, ok := args.Get(0).(*service.UserExport); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *PrivacyServiceMock) Erase(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+
+	return args.Error(0)
+}
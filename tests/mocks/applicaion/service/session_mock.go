@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+)
+
+type SessionServiceMock struct {
+	mock.Mock
+}
+
+var _ service.SessionPort = (*SessionServiceMock)(nil)
+
+func (m *SessionServiceMock) Refresh(ctx context.Context, refreshToken string) (*auth.Token, error) {
+	args := m.Called(ctx, refreshToken)
+
+	if val, ok := args.Get(0).(*auth.Token); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *SessionServiceMock) Logout(ctx context.Context, refreshToken string) error {
+	args := m.Called(ctx, refreshToken)
+
+	return args.Error(0)
+}
+
+func (m *SessionServiceMock) RevokeAll(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+
+	return args.Error(0)
+}
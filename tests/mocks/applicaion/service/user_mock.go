@@ -21,22 +21,28 @@ func (m *UserServiceMock) Create(ctx context.Context, dto *service.CreateUserDTO
 	return args.Error(0)
 }
 
-func (m *UserServiceMock) Update(ctx context.Context, userID string, dto *service.UpdateUserDTO) error {
-	args := m.Called(ctx, userID, dto)
+func (m *UserServiceMock) Update(ctx context.Context, userID string, changes *user.ChangeSet, version *int64) error {
+	args := m.Called(ctx, userID, changes, version)
 
 	return args.Error(0)
 }
 
-func (m *UserServiceMock) Delete(ctx context.Context, userID string) error {
+func (m *UserServiceMock) Delete(ctx context.Context, userID string, version *int64) error {
+	args := m.Called(ctx, userID, version)
+
+	return args.Error(0)
+}
+
+func (m *UserServiceMock) Restore(ctx context.Context, userID string) error {
 	args := m.Called(ctx, userID)
 
 	return args.Error(0)
 }
 
-func (m *UserServiceMock) Get(ctx context.Context, page, pageSize int) ([]*user.User, error) {
-	args := m.Called(ctx, page, pageSize)
+func (m *UserServiceMock) Get(ctx context.Context, q *user.ListQuery) (*user.Page, error) {
+	args := m.Called(ctx, q)
 
-	if val, ok := (args.Get(0)).([]*user.User); ok {
+	if val, ok := (args.Get(0)).(*user.Page); ok {
 		return val, args.Error(1)
 	}
 
@@ -52,3 +58,41 @@ func (m *UserServiceMock) GetByUUID(ctx context.Context, userID string) (*user.U
 
 	return nil, args.Error(1)
 }
+
+func (m *UserServiceMock) ListAddresses(ctx context.Context, userID string) ([]*user.Address, error) {
+	args := m.Called(ctx, userID)
+
+	if val, ok := args.Get(0).([]*user.Address); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *UserServiceMock) GetAddress(ctx context.Context, userID string, addrType user.AddressType) (*user.Address, error) {
+	args := m.Called(ctx, userID, addrType)
+
+	if val, ok := args.Get(0).(*user.Address); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *UserServiceMock) AddAddress(ctx context.Context, userID string, addr *user.Address, version *int64) error {
+	args := m.Called(ctx, userID, addr, version)
+
+	return args.Error(0)
+}
+
+func (m *UserServiceMock) ReplaceAddress(ctx context.Context, userID string, addr *user.Address, version *int64) error {
+	args := m.Called(ctx, userID, addr, version)
+
+	return args.Error(0)
+}
+
+func (m *UserServiceMock) DeleteAddress(ctx context.Context, userID string, addrType user.AddressType, version *int64) error {
+	args := m.Called(ctx, userID, addrType, version)
+
+	return args.Error(0)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/application/service"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+)
+
+type WebhookServiceMock struct {
+	mock.Mock
+}
+
+var _ service.WebhookPort = (*WebhookServiceMock)(nil)
+
+func (m *WebhookServiceMock) Create(ctx context.Context, req *service.WebhookRequest) (*webhook.Subscription, string, error) {
+	args := m.Called(ctx, req)
+
+	if val, ok := args.Get(0).(*webhook.Subscription); ok {
+		return val, args.String(1), args.Error(2)
+	}
+
+	return nil, args.String(1), args.Error(2)
+}
+
+func (m *WebhookServiceMock) Get(ctx context.Context, subscriptionID string) (*webhook.Subscription, error) {
+	args := m.Called(ctx, subscriptionID)
+
+	if val, ok := args.Get(0).(*webhook.Subscription); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *WebhookServiceMock) List(ctx context.Context) ([]*webhook.Subscription, error) {
+	args := m.Called(ctx)
+
+	if val, ok := args.Get(0).([]*webhook.Subscription); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *WebhookServiceMock) Update(ctx context.Context, subscriptionID string, req *service.WebhookRequest) (*webhook.Subscription, error) {
+	args := m.Called(ctx, subscriptionID, req)
+
+	if val, ok := args.Get(0).(*webhook.Subscription); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *WebhookServiceMock) Delete(ctx context.Context, subscriptionID string) error {
+	args := m.Called(ctx, subscriptionID)
+
+	return args.Error(0)
+}
+
+func (m *WebhookServiceMock) ListDeliveries(ctx context.Context, q *webhook.DeliveryQuery) (*webhook.DeliveryPage, error) {
+	args := m.Called(ctx, q)
+
+	if val, ok := args.Get(0).(*webhook.DeliveryPage); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *WebhookServiceMock) Redeliver(ctx context.Context, subscriptionID, deliveryID string) error {
+	args := m.Called(ctx, subscriptionID, deliveryID)
+
+	return args.Error(0)
+}
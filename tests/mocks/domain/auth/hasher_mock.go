@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+)
+
+type PasswordHasherMock struct {
+	mock.Mock
+}
+
+var _ auth.PasswordHasher = (*PasswordHasherMock)(nil)
+
+func (m *PasswordHasherMock) Hash(password string) (string, error) {
+	args := m.Called(password)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *PasswordHasherMock) Verify(hash, password string) (bool, bool, error) {
+	args := m.Called(hash, password)
+
+	return args.Bool(0), args.Bool(1), args.Error(2)
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/auth"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/user"
+)
+
+type TokenProviderMock struct {
+	mock.Mock
+}
+
+var _ auth.TokenProvider = (*TokenProviderMock)(nil)
+
+func (m *TokenProviderMock) Issue(subject domain.ID, role user.Role) (*auth.Token, error) {
+	args := m.Called(subject, role)
+
+	if val, ok := args.Get(0).(*auth.Token); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *TokenProviderMock) Verify(accessToken string) (*auth.Claims, error) {
+	args := m.Called(accessToken)
+
+	if val, ok := args.Get(0).(*auth.Claims); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
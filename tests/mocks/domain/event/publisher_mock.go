@@ -0,0 +1,21 @@
+package event
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+)
+
+type PublisherMock struct {
+	mock.Mock
+}
+
+var _ event.Publisher = (*PublisherMock)(nil)
+
+func (m *PublisherMock) Publish(ctx context.Context, e *event.Event) error {
+	args := m.Called(ctx, e)
+
+	return args.Error(0)
+}
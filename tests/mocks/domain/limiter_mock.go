@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+type RateLimiterMock struct {
+	mock.Mock
+}
+
+var _ domain.RateLimiter = (*RateLimiterMock)(nil)
+
+func (m *RateLimiterMock) Allow(key string) bool {
+	args := m.Called(key)
+
+	return args.Bool(0)
+}
@@ -0,0 +1,21 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mail"
+)
+
+type MailerMock struct {
+	mock.Mock
+}
+
+var _ mail.Mailer = (*MailerMock)(nil)
+
+func (m *MailerMock) Send(ctx context.Context, msg *mail.Message) error {
+	args := m.Called(ctx, msg)
+
+	return args.Error(0)
+}
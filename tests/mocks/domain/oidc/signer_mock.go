@@ -0,0 +1,37 @@
+package oidc
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+)
+
+type SignerMock struct {
+	mock.Mock
+}
+
+var _ oidc.Signer = (*SignerMock)(nil)
+
+func (m *SignerMock) Sign(ctx context.Context, claims any) (string, error) {
+	args := m.Called(ctx, claims)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *SignerMock) PublicKeys(ctx context.Context) ([]oidc.PublicKey, error) {
+	args := m.Called(ctx)
+
+	if val, ok := args.Get(0).([]oidc.PublicKey); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *SignerMock) Verify(ctx context.Context, token string, dst any) error {
+	args := m.Called(ctx, token, dst)
+
+	return args.Error(0)
+}
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+)
+
+// UnitOfWorkMock runs the function in place, there is no transaction to commit or roll back
+type UnitOfWorkMock struct {
+	mock.Mock
+}
+
+var _ domain.UnitOfWork = (*UnitOfWorkMock)(nil)
+
+func (m *UnitOfWorkMock) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
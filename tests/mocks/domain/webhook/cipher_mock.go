@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+)
+
+type CipherMock struct {
+	mock.Mock
+}
+
+var _ webhook.Cipher = (*CipherMock)(nil)
+
+func (m *CipherMock) Encrypt(plaintext []byte) (string, error) {
+	args := m.Called(plaintext)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *CipherMock) Decrypt(ciphertext string) ([]byte, error) {
+	args := m.Called(ciphertext)
+
+	if val, ok := args.Get(0).([]byte); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
@@ -0,0 +1,21 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+)
+
+type SenderMock struct {
+	mock.Mock
+}
+
+var _ webhook.Sender = (*SenderMock)(nil)
+
+func (m *SenderMock) Send(ctx context.Context, url string, secret []byte, d *webhook.Delivery) (int, error) {
+	args := m.Called(ctx, url, secret, d)
+
+	return args.Int(0), args.Error(1)
+}
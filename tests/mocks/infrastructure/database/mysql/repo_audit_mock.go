@@ -0,0 +1,38 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/audit"
+)
+
+type AuditRepositoryMock struct {
+	mock.Mock
+}
+
+var _ audit.Repository = (*AuditRepositoryMock)(nil)
+
+func (m *AuditRepositoryMock) Create(ctx context.Context, e *audit.Entry) error {
+	args := m.Called(ctx, e)
+
+	return args.Error(0)
+}
+
+func (m *AuditRepositoryMock) List(ctx context.Context, q *audit.Query) (*audit.Page, error) {
+	args := m.Called(ctx, q)
+
+	if val, ok := args.Get(0).(*audit.Page); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *AuditRepositoryMock) Erase(ctx context.Context, userID domain.ID) error {
+	args := m.Called(ctx, userID)
+
+	return args.Error(0)
+}
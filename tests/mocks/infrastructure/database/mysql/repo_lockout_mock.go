@@ -0,0 +1,48 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/lockout"
+)
+
+type LockoutRepositoryMock struct {
+	mock.Mock
+}
+
+var _ lockout.Repository = (*LockoutRepositoryMock)(nil)
+
+func (m *LockoutRepositoryMock) Get(ctx context.Context, subject lockout.Subject) (*lockout.State, error) {
+	args := m.Called(ctx, subject)
+
+	if val, ok := args.Get(0).(*lockout.State); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *LockoutRepositoryMock) RecordFailure(ctx context.Context, subject lockout.Subject, at time.Time, window time.Duration) (*lockout.State, error) {
+	args := m.Called(ctx, subject, at, window)
+
+	if val, ok := args.Get(0).(*lockout.State); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *LockoutRepositoryMock) Lock(ctx context.Context, subject lockout.Subject, until time.Time) error {
+	args := m.Called(ctx, subject, until)
+
+	return args.Error(0)
+}
+
+func (m *LockoutRepositoryMock) Reset(ctx context.Context, subject lockout.Subject) error {
+	args := m.Called(ctx, subject)
+
+	return args.Error(0)
+}
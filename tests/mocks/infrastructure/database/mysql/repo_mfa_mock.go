@@ -0,0 +1,63 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/mfa"
+)
+
+type MFARepositoryMock struct {
+	mock.Mock
+}
+
+var _ mfa.Repository = (*MFARepositoryMock)(nil)
+
+func (m *MFARepositoryMock) Get(ctx context.Context, userID domain.ID) (*mfa.Factor, error) {
+	args := m.Called(ctx, userID)
+
+	if val, ok := args.Get(0).(*mfa.Factor); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MFARepositoryMock) Enroll(ctx context.Context, f *mfa.Factor, createdAt time.Time) error {
+	args := m.Called(ctx, f, createdAt)
+
+	return args.Error(0)
+}
+
+func (m *MFARepositoryMock) Confirm(ctx context.Context, userID domain.ID, step int64, confirmedAt time.Time, recoveryCodeHashes []string) error {
+	args := m.Called(ctx, userID, step, confirmedAt, recoveryCodeHashes)
+
+	return args.Error(0)
+}
+
+func (m *MFARepositoryMock) UseStep(ctx context.Context, userID domain.ID, step int64) error {
+	args := m.Called(ctx, userID, step)
+
+	return args.Error(0)
+}
+
+func (m *MFARepositoryMock) UseRecoveryCode(ctx context.Context, userID domain.ID, hash string, usedAt time.Time) error {
+	args := m.Called(ctx, userID, hash, usedAt)
+
+	return args.Error(0)
+}
+
+func (m *MFARepositoryMock) ReplaceRecoveryCodes(ctx context.Context, userID domain.ID, hashes []string) error {
+	args := m.Called(ctx, userID, hashes)
+
+	return args.Error(0)
+}
+
+func (m *MFARepositoryMock) Delete(ctx context.Context, userID domain.ID) error {
+	args := m.Called(ctx, userID)
+
+	return args.Error(0)
+}
@@ -0,0 +1,61 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/oidc"
+)
+
+type ClientRepositoryMock struct {
+	mock.Mock
+}
+
+var _ oidc.ClientRepository = (*ClientRepositoryMock)(nil)
+
+func (m *ClientRepositoryMock) Create(ctx context.Context, c *oidc.Client, createdAt time.Time) error {
+	args := m.Called(ctx, c, createdAt)
+
+	return args.Error(0)
+}
+
+func (m *ClientRepositoryMock) Get(ctx context.Context, id domain.ID) (*oidc.Client, error) {
+	args := m.Called(ctx, id)
+
+	if val, ok := args.Get(0).(*oidc.Client); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *ClientRepositoryMock) Delete(ctx context.Context, id domain.ID) error {
+	args := m.Called(ctx, id)
+
+	return args.Error(0)
+}
+
+type AuthorizationCodeRepositoryMock struct {
+	mock.Mock
+}
+
+var _ oidc.CodeRepository = (*AuthorizationCodeRepositoryMock)(nil)
+
+func (m *AuthorizationCodeRepositoryMock) Create(ctx context.Context, c *oidc.AuthorizationCode, createdAt time.Time) error {
+	args := m.Called(ctx, c, createdAt)
+
+	return args.Error(0)
+}
+
+func (m *AuthorizationCodeRepositoryMock) Use(ctx context.Context, hash string, usedAt time.Time) (*oidc.AuthorizationCode, error) {
+	args := m.Called(ctx, hash, usedAt)
+
+	if val, ok := args.Get(0).(*oidc.AuthorizationCode); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
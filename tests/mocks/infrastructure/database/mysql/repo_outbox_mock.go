@@ -0,0 +1,51 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/event"
+)
+
+type OutboxRepositoryMock struct {
+	mock.Mock
+}
+
+var _ event.Outbox = (*OutboxRepositoryMock)(nil)
+
+func (m *OutboxRepositoryMock) Add(ctx context.Context, e *event.Event) error {
+	args := m.Called(ctx, e)
+
+	return args.Error(0)
+}
+
+func (m *OutboxRepositoryMock) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*event.Event, error) {
+	args := m.Called(ctx, now, leaseUntil, limit)
+
+	if val, ok := args.Get(0).([]*event.Event); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *OutboxRepositoryMock) MarkPublished(ctx context.Context, ids []domain.ID, publishedAt time.Time) error {
+	args := m.Called(ctx, ids, publishedAt)
+
+	return args.Error(0)
+}
+
+func (m *OutboxRepositoryMock) Release(ctx context.Context, ids []domain.ID) error {
+	args := m.Called(ctx, ids)
+
+	return args.Error(0)
+}
+
+func (m *OutboxRepositoryMock) Erase(ctx context.Context, userID domain.ID) error {
+	args := m.Called(ctx, userID)
+
+	return args.Error(0)
+}
@@ -0,0 +1,61 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/session"
+)
+
+type SessionRepositoryMock struct {
+	mock.Mock
+}
+
+var _ session.Repository = (*SessionRepositoryMock)(nil)
+
+func (m *SessionRepositoryMock) Create(ctx context.Context, t *session.RefreshToken, createdAt time.Time) error {
+	args := m.Called(ctx, t, createdAt)
+
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) Get(ctx context.Context, hash string) (*session.RefreshToken, error) {
+	args := m.Called(ctx, hash)
+
+	if val, ok := args.Get(0).(*session.RefreshToken); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *SessionRepositoryMock) Use(ctx context.Context, hash string, usedAt time.Time) error {
+	args := m.Called(ctx, hash, usedAt)
+
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) RevokeFamily(ctx context.Context, familyID domain.ID, revokedAt time.Time) error {
+	args := m.Called(ctx, familyID, revokedAt)
+
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) ListByUser(ctx context.Context, userID domain.ID) ([]*session.Session, error) {
+	args := m.Called(ctx, userID)
+
+	if val, ok := args.Get(0).([]*session.Session); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *SessionRepositoryMock) RevokeUser(ctx context.Context, userID domain.ID, revokedAt time.Time) error {
+	args := m.Called(ctx, userID, revokedAt)
+
+	return args.Error(0)
+}
@@ -22,14 +22,14 @@ func (m *UserRepositoryMock) Create(ctx context.Context, u *user.User, createdAt
 	return args.Error(0)
 }
 
-func (m *UserRepositoryMock) UpdateBasicFields(ctx context.Context, id domain.ID, fields map[string]any) error {
-	args := m.Called(ctx, id, fields)
+func (m *UserRepositoryMock) UpdateBasicFields(ctx context.Context, id domain.ID, changes []user.Change) error {
+	args := m.Called(ctx, id, changes)
 
 	return args.Error(0)
 }
 
-func (m *UserRepositoryMock) UpdateAddress(ctx context.Context, id domain.ID, addrType int, fields map[string]any) error {
-	args := m.Called(ctx, id, addrType, fields)
+func (m *UserRepositoryMock) UpdateAddress(ctx context.Context, id domain.ID, addrType user.AddressType, changes []user.Change) error {
+	args := m.Called(ctx, id, addrType, changes)
 
 	return args.Error(0)
 }
@@ -40,12 +40,46 @@ func (m *UserRepositoryMock) InsertAddress(ctx context.Context, id domain.ID, ad
 	return args.Error(0)
 }
 
+func (m *UserRepositoryMock) DeleteAddress(ctx context.Context, id domain.ID, addrType user.AddressType) error {
+	args := m.Called(ctx, id, addrType)
+
+	return args.Error(0)
+}
+
+func (m *UserRepositoryMock) ListAddresses(ctx context.Context, id domain.ID) ([]*user.Address, error) {
+	args := m.Called(ctx, id)
+
+	if val, ok := args.Get(0).([]*user.Address); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
 func (m *UserRepositoryMock) Delete(ctx context.Context, id domain.ID) error {
 	args := m.Called(ctx, id)
 
 	return args.Error(0)
 }
 
+func (m *UserRepositoryMock) Restore(ctx context.Context, id domain.ID) error {
+	args := m.Called(ctx, id)
+
+	return args.Error(0)
+}
+
+func (m *UserRepositoryMock) Erase(ctx context.Context, id domain.ID, erasedAt time.Time) error {
+	args := m.Called(ctx, id, erasedAt)
+
+	return args.Error(0)
+}
+
+func (m *UserRepositoryMock) Purge(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	args := m.Called(ctx, deletedBefore, limit)
+
+	return args.Int(0), args.Error(1)
+}
+
 func (m *UserRepositoryMock) GetByUUID(ctx context.Context, id domain.ID) (*user.User, error) {
 	args := m.Called(ctx, id)
 
@@ -56,12 +90,60 @@ func (m *UserRepositoryMock) GetByUUID(ctx context.Context, id domain.ID) (*user
 	return nil, args.Error(1)
 }
 
-func (m *UserRepositoryMock) Get(ctx context.Context, page, pageSize int) ([]*user.User, error) {
-	args := m.Called(ctx, page, pageSize)
+func (m *UserRepositoryMock) GetByUUIDIncludingDeleted(ctx context.Context, id domain.ID) (*user.User, error) {
+	args := m.Called(ctx, id)
+
+	if val, ok := args.Get(0).(*user.User); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *UserRepositoryMock) Get(ctx context.Context, q *user.ListQuery) (*user.Page, error) {
+	args := m.Called(ctx, q)
+
+	if val, ok := args.Get(0).(*user.Page); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *UserRepositoryMock) GetCredentialsByEmail(ctx context.Context, email string) (*user.Credentials, error) {
+	args := m.Called(ctx, email)
+
+	if val, ok := args.Get(0).(*user.Credentials); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *UserRepositoryMock) GetCredentialsByUUID(ctx context.Context, id domain.ID) (*user.Credentials, error) {
+	args := m.Called(ctx, id)
 
-	if val, ok := args.Get(0).([]*user.User); ok {
+	if val, ok := args.Get(0).(*user.Credentials); ok {
 		return val, args.Error(1)
 	}
 
 	return nil, args.Error(1)
 }
+
+func (m *UserRepositoryMock) SetVerifiedEmail(ctx context.Context, id domain.ID, email string) error {
+	args := m.Called(ctx, id, email)
+
+	return args.Error(0)
+}
+
+func (m *UserRepositoryMock) ReplacePasswordHash(ctx context.Context, id domain.ID, current, replacement string) error {
+	args := m.Called(ctx, id, current, replacement)
+
+	return args.Error(0)
+}
+
+func (m *UserRepositoryMock) IncrementVersion(ctx context.Context, id domain.ID, expected *int64) error {
+	args := m.Called(ctx, id, expected)
+
+	return args.Error(0)
+}
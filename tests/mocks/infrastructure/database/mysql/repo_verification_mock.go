@@ -0,0 +1,39 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/verification"
+)
+
+type VerificationRepositoryMock struct {
+	mock.Mock
+}
+
+var _ verification.Repository = (*VerificationRepositoryMock)(nil)
+
+func (m *VerificationRepositoryMock) Create(ctx context.Context, t *verification.Token, createdAt time.Time) error {
+	args := m.Called(ctx, t, createdAt)
+
+	return args.Error(0)
+}
+
+func (m *VerificationRepositoryMock) Consume(ctx context.Context, hash string, purpose verification.Purpose, now time.Time) (*verification.Token, error) {
+	args := m.Called(ctx, hash, purpose, now)
+
+	if val, ok := args.Get(0).(*verification.Token); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *VerificationRepositoryMock) Revoke(ctx context.Context, userID domain.ID, purpose verification.Purpose) error {
+	args := m.Called(ctx, userID, purpose)
+
+	return args.Error(0)
+}
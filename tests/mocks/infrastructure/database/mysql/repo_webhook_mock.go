@@ -0,0 +1,109 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/wojciechpawlinow/usermanagement/internal/domain"
+	"github.com/wojciechpawlinow/usermanagement/internal/domain/webhook"
+)
+
+type SubscriptionRepositoryMock struct {
+	mock.Mock
+}
+
+var _ webhook.SubscriptionRepository = (*SubscriptionRepositoryMock)(nil)
+
+func (m *SubscriptionRepositoryMock) Create(ctx context.Context, s *webhook.Subscription) error {
+	args := m.Called(ctx, s)
+
+	return args.Error(0)
+}
+
+func (m *SubscriptionRepositoryMock) Get(ctx context.Context, id domain.ID) (*webhook.Subscription, error) {
+	args := m.Called(ctx, id)
+
+	if val, ok := args.Get(0).(*webhook.Subscription); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *SubscriptionRepositoryMock) List(ctx context.Context) ([]*webhook.Subscription, error) {
+	args := m.Called(ctx)
+
+	if val, ok := args.Get(0).([]*webhook.Subscription); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *SubscriptionRepositoryMock) Update(ctx context.Context, s *webhook.Subscription) error {
+	args := m.Called(ctx, s)
+
+	return args.Error(0)
+}
+
+func (m *SubscriptionRepositoryMock) Delete(ctx context.Context, id domain.ID) error {
+	args := m.Called(ctx, id)
+
+	return args.Error(0)
+}
+
+type DeliveryRepositoryMock struct {
+	mock.Mock
+}
+
+var _ webhook.DeliveryRepository = (*DeliveryRepositoryMock)(nil)
+
+func (m *DeliveryRepositoryMock) Create(ctx context.Context, d *webhook.Delivery) error {
+	args := m.Called(ctx, d)
+
+	return args.Error(0)
+}
+
+func (m *DeliveryRepositoryMock) Get(ctx context.Context, subscriptionID, id domain.ID) (*webhook.Delivery, error) {
+	args := m.Called(ctx, subscriptionID, id)
+
+	if val, ok := args.Get(0).(*webhook.Delivery); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *DeliveryRepositoryMock) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*webhook.Delivery, error) {
+	args := m.Called(ctx, now, leaseUntil, limit)
+
+	if val, ok := args.Get(0).([]*webhook.Delivery); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *DeliveryRepositoryMock) Update(ctx context.Context, d *webhook.Delivery) error {
+	args := m.Called(ctx, d)
+
+	return args.Error(0)
+}
+
+func (m *DeliveryRepositoryMock) List(ctx context.Context, q *webhook.DeliveryQuery) (*webhook.DeliveryPage, error) {
+	args := m.Called(ctx, q)
+
+	if val, ok := args.Get(0).(*webhook.DeliveryPage); ok {
+		return val, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *DeliveryRepositoryMock) Erase(ctx context.Context, userID domain.ID) error {
+	args := m.Called(ctx, userID)
+
+	return args.Error(0)
+}